@@ -57,6 +57,12 @@ func (a *apiKeyService) HashRaw(rawKey string) string {
 // GenerateApiKey generates a raw API key for a user (for one-time display).
 // The generated API key is stored in the cache and database.
 func (a *apiKeyService) GenerateApiKey(user portainer.User, description string) (string, *portainer.APIKey, error) {
+	return a.GenerateScopedApiKey(user, description, 0, nil)
+}
+
+// GenerateScopedApiKey generates a raw API key for a user, rejected after expiresAt (when not 0)
+// and restricted to scope (when not nil).
+func (a *apiKeyService) GenerateScopedApiKey(user portainer.User, description string, expiresAt int64, scope *portainer.APIKeyScope) (string, *portainer.APIKey, error) {
 	randKey := GenerateRandomKey(32)
 	encodedRawAPIKey := base64.StdEncoding.EncodeToString(randKey)
 	prefixedAPIKey := portainerAPIKeyPrefix + encodedRawAPIKey
@@ -68,6 +74,8 @@ func (a *apiKeyService) GenerateApiKey(user portainer.User, description string)
 		Prefix:      prefixedAPIKey[:7],
 		DateCreated: time.Now().Unix(),
 		Digest:      hashDigest,
+		ExpiresAt:   expiresAt,
+		Scope:       scope,
 	}
 
 	if err := a.apiKeyRepository.Create(apiKey); err != nil {
@@ -90,6 +98,11 @@ func (a *apiKeyService) GetAPIKeys(userID portainer.UserID) ([]portainer.APIKey,
 	return a.apiKeyRepository.GetAPIKeysByUserID(userID)
 }
 
+// GetAllAPIKeys returns the API keys of all the users.
+func (a *apiKeyService) GetAllAPIKeys() ([]portainer.APIKey, error) {
+	return a.apiKeyRepository.ReadAll()
+}
+
 // GetDigestUserAndKey returns the user and api-key associated to a specified hash digest.
 // A cache lookup is performed first; if the user/api-key is not found in the cache, respective database lookups are performed.
 func (a *apiKeyService) GetDigestUserAndKey(digest string) (portainer.User, portainer.APIKey, error) {
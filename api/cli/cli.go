@@ -21,6 +21,7 @@ var (
 	ErrSocketOrNamedPipeNotFound     = errors.New("Unable to locate Unix socket or named pipe")
 	ErrInvalidSnapshotInterval       = errors.New("Invalid snapshot interval")
 	ErrAdminPassExcludeAdminPassFile = errors.New("Cannot use --admin-password with --admin-password-file")
+	ErrRotateSecretsKeyWithoutKey    = errors.New("Cannot use --rotate-secrets-key without --secrets-key")
 )
 
 func CLIFlags() *portainer.CLIFlags {
@@ -57,11 +58,15 @@ func CLIFlags() *portainer.CLIFlags {
 		MaxBatchSize:              kingpin.Flag("max-batch-size", "Maximum size of a batch").Int(),
 		MaxBatchDelay:             kingpin.Flag("max-batch-delay", "Maximum delay before a batch starts").Duration(),
 		SecretKeyName:             kingpin.Flag("secret-key-name", "Secret key name for encryption and will be used as /run/secrets/<secret-key-name>.").Default(defaultSecretKeyName).String(),
+		SecretsKey:                kingpin.Flag("secrets-key", "Master key used to encrypt the secret fields such as the registry and git passwords, as <provider>:<config> such as file:/run/secrets/portainer-fields or exec:/usr/local/bin/portainer-kms").Envar(portainer.SecretsKeyEnvVar).String(),
+		SecretsPreviousKeys:       kingpin.Flag("secrets-previous-key", "Previous master key of the secret fields, kept to read the fields not yet encrypted with the current key. Can be repeated").Strings(),
+		RotateSecretsKey:          kingpin.Flag("rotate-secrets-key", "Encrypt every secret field with the current secrets key and exit").Bool(),
 		LogLevel:                  kingpin.Flag("log-level", "Set the minimum logging level to show").Default("INFO").Enum("DEBUG", "INFO", "WARN", "ERROR"),
 		LogMode:                   kingpin.Flag("log-mode", "Set the logging output mode").Default("PRETTY").Enum("NOCOLOR", "PRETTY", "JSON"),
 		KubectlShellImage:         kingpin.Flag("kubectl-shell-image", "Kubectl shell image").Envar(portainer.KubectlShellImageEnvVar).Default(portainer.DefaultKubectlShellImage).String(),
 		PullLimitCheckDisabled:    kingpin.Flag("pull-limit-check-disabled", "Pull limit check").Envar(portainer.PullLimitCheckDisabledEnvVar).Default(defaultPullLimitCheckDisabled).Bool(),
 		TrustedOrigins:            kingpin.Flag("trusted-origins", "List of trusted origins for CSRF protection. Separate multiple origins with a comma.").Envar(portainer.TrustedOriginsEnvVar).String(),
+		TrustedProxies:            kingpin.Flag("trusted-proxies", "List of IP addresses or CIDR ranges of the reverse proxies whose X-Forwarded-For header is trusted. Separate multiple entries with a comma.").Envar(portainer.TrustedProxiesEnvVar).String(),
 		CSP:                       kingpin.Flag("csp", "Content Security Policy (CSP) header").Envar(portainer.CSPEnvVar).Default("true").Bool(),
 	}
 }
@@ -102,6 +107,10 @@ func (*Service) ValidateFlags(flags *portainer.CLIFlags) error {
 		return ErrAdminPassExcludeAdminPassFile
 	}
 
+	if *flags.RotateSecretsKey && *flags.SecretsKey == "" {
+		return ErrRotateSecretsKeyWithoutKey
+	}
+
 	return nil
 }
 
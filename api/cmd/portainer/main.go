@@ -29,7 +29,9 @@ import (
 	"github.com/portainer/portainer/api/http"
 	"github.com/portainer/portainer/api/http/proxy"
 	kubeproxy "github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
+	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/edge"
 	"github.com/portainer/portainer/api/internal/edge/edgestacks"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/internal/snapshot"
@@ -45,13 +47,18 @@ import (
 	"github.com/portainer/portainer/api/pendingactions/actions"
 	"github.com/portainer/portainer/api/pendingactions/handlers"
 	"github.com/portainer/portainer/api/platform"
+	"github.com/portainer/portainer/api/saml"
 	"github.com/portainer/portainer/api/scheduler"
+	"github.com/portainer/portainer/api/secrets"
+	"github.com/portainer/portainer/api/stacks/buildlogs"
+	"github.com/portainer/portainer/api/stacks/deploymentgroups"
 	"github.com/portainer/portainer/api/stacks/deployments"
 	"github.com/portainer/portainer/pkg/build"
 	"github.com/portainer/portainer/pkg/featureflags"
 	"github.com/portainer/portainer/pkg/libhelm"
 	libhelmtypes "github.com/portainer/portainer/pkg/libhelm/types"
 	"github.com/portainer/portainer/pkg/libstack/compose"
+	"github.com/portainer/portainer/pkg/libstack/swarm"
 	"github.com/portainer/portainer/pkg/validate"
 
 	"github.com/gofrs/uuid"
@@ -92,6 +99,7 @@ func initDataStore(flags *portainer.CLIFlags, secretKey []byte, fileService port
 		bconn.MaxBatchSize = *flags.MaxBatchSize
 		bconn.MaxBatchDelay = *flags.MaxBatchDelay
 		bconn.InitialMmapSize = *flags.InitialMmapSize
+		bconn.Secrets = initSecretsService(flags)
 	} else {
 		log.Fatal().Msg("failed creating database connection: expecting a boltdb database type but a different one was received")
 	}
@@ -148,6 +156,15 @@ func initDataStore(flags *portainer.CLIFlags, secretKey []byte, fileService port
 		log.Fatal().Err(err).Msg("failed updating settings from flags")
 	}
 
+	if *flags.RotateSecretsKey {
+		if err := store.RotateSecrets(); err != nil {
+			log.Fatal().Err(err).Msg("failed rotating the secrets key")
+		}
+
+		log.Info().Msg("exiting secrets key rotation")
+		os.Exit(0)
+	}
+
 	// this is for the db restore functionality - needs more tests.
 	go func() {
 		<-shutdownCtx.Done()
@@ -306,6 +323,38 @@ func initKeyPair(fileService portainer.FileService, signatureService portainer.D
 	return generateAndStoreKeyPair(fileService, signatureService)
 }
 
+// initSecretsService returns the service encrypting the secret fields, or nil when no secrets key is set
+func initSecretsService(flags *portainer.CLIFlags) *secrets.Service {
+	if *flags.SecretsKey == "" {
+		return nil
+	}
+
+	current, err := secrets.NewKeyProvider(*flags.SecretsKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed loading the secrets key")
+	}
+
+	var previous []secrets.KeyProvider
+
+	for _, spec := range *flags.SecretsPreviousKeys {
+		provider, err := secrets.NewKeyProvider(spec)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed loading a previous secrets key")
+		}
+
+		previous = append(previous, provider)
+	}
+
+	service, err := secrets.NewService(current, previous...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed initializing the secrets encryption")
+	}
+
+	log.Info().Str("key_id", service.KeyID()).Msg("secret fields encryption enabled")
+
+	return service
+}
+
 func loadEncryptionSecretKey(keyfilename string) []byte {
 	content, err := os.ReadFile(path.Join("/run/secrets", keyfilename))
 	if err != nil {
@@ -343,6 +392,12 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		}
 	}
 
+	trustedProxies, err := security.ParseTrustedProxies(strings.Split(*flags.TrustedProxies, ","))
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid trusted proxy. Please check the trusted proxies flag.")
+	}
+	security.SetTrustedProxies(trustedProxies)
+
 	fileService := initFileService(*flags.Data)
 	encryptionKey := loadEncryptionSecretKey(*flags.SecretKeyName)
 	if encryptionKey == nil {
@@ -381,6 +436,8 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 
 	oauthService := oauth.NewService()
 
+	samlService := saml.NewService()
+
 	gitService := git.NewService(shutdownCtx)
 
 	// Setting insecureSkipVerify to true to preserve the old behaviour.
@@ -402,6 +459,11 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		log.Fatal().Err(err).Msg("failed to get SSL settings")
 	}
 
+	clientCertificateService, err := security.NewClientCertificateService(dataStore)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed initializing client certificate authentication")
+	}
+
 	if err := initKeyPair(fileService, signatureService); err != nil {
 		log.Fatal().Err(err).Msg("failed initializing key pair")
 	}
@@ -426,16 +488,13 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 
 	reverseTunnelService.ProxyManager = proxyManager
 
-	dockerConfigPath := fileService.GetDockerConfigPath()
-
 	composeDeployer := compose.NewComposeDeployer()
 
-	composeStackManager := exec.NewComposeStackManager(composeDeployer, proxyManager, dataStore)
+	stackBuildLogs := buildlogs.NewService()
 
-	swarmStackManager, err := exec.NewSwarmStackManager(*flags.Assets, dockerConfigPath, signatureService, fileService, reverseTunnelService, dataStore)
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed initializing swarm stack manager")
-	}
+	composeStackManager := exec.NewComposeStackManager(composeDeployer, proxyManager, dataStore, stackBuildLogs)
+
+	swarmStackManager := exec.NewSwarmStackManager(swarm.NewSwarmDeployer(), proxyManager, dataStore)
 
 	kubernetesDeployer := initKubernetesDeployer(kubernetesTokenCacheManager, kubernetesClientFactory, dataStore, reverseTunnelService, signatureService, proxyManager)
 
@@ -494,6 +553,7 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 				Username: "admin",
 				Role:     portainer.AdministratorRole,
 				Password: adminPasswordHash,
+				Origin:   portainer.UserOriginInternal,
 			}
 
 			if err := dataStore.User().Create(user); err != nil {
@@ -514,6 +574,17 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 	scheduler := scheduler.NewScheduler(shutdownCtx)
 	stackDeployer := deployments.NewStackDeployer(swarmStackManager, composeStackManager, kubernetesDeployer, dockerClientFactory, dataStore)
 	deployments.StartStackSchedules(scheduler, stackDeployer, dataStore, gitService)
+	sslService.StartACMERenewal(scheduler)
+	edge.StartCheckInAgeRefresh(dataStore, scheduler)
+
+	if err := deploymentgroups.ResetUnfinishedRuns(dataStore); err != nil {
+		log.Error().Err(err).Msg("unable to reset the interrupted deployment group runs")
+	}
+
+	ldapSyncService, err := ldap.NewSyncService(dataStore, ldapService, scheduler)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed initializing LDAP synchronization service")
+	}
 
 	sslDBSettings, err := dataStore.SSLSettings().Settings()
 	if err != nil {
@@ -566,6 +637,7 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		EdgeStacksService:           edgeStacksService,
 		SwarmStackManager:           swarmStackManager,
 		ComposeStackManager:         composeStackManager,
+		StackBuildLogs:              stackBuildLogs,
 		KubernetesDeployer:          kubernetesDeployer,
 		HelmPackageManager:          helmPackageManager,
 		APIKeyService:               apiKeyService,
@@ -573,7 +645,9 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		JWTService:                  jwtService,
 		FileService:                 fileService,
 		LDAPService:                 ldapService,
+		LDAPSyncService:             ldapSyncService,
 		OAuthService:                oauthService,
+		SAMLService:                 samlService,
 		GitService:                  gitService,
 		OpenAMTService:              openAMTService,
 		ProxyManager:                proxyManager,
@@ -582,6 +656,7 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		SignatureService:            signatureService,
 		SnapshotService:             snapshotService,
 		SSLService:                  sslService,
+		ClientCertificateService:    clientCertificateService,
 		DockerClientFactory:         dockerClientFactory,
 		KubernetesClientFactory:     kubernetesClientFactory,
 		Scheduler:                   scheduler,
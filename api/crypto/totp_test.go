@@ -0,0 +1,82 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHOTPCode(t *testing.T) {
+	// Test vectors from RFC 4226 appendix D
+	key := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range expected {
+		require.Equal(t, code, hotpCode(key, uint64(counter), 6))
+	}
+}
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B for SHA1, truncated to 8 digits
+	key := []byte("12345678901234567890")
+
+	tests := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+
+	for unix, code := range tests {
+		require.Equal(t, code, hotpCode(key, uint64(TOTPStep(time.Unix(unix, 0))), 8))
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+
+	code, err := GenerateTOTPCode(secret, TOTPStep(now))
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTPCode(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, TOTPStep(now), step)
+
+	// Clock drift of one period is tolerated
+	_, ok = ValidateTOTPCode(secret, code, now.Add(TOTPPeriod))
+	require.True(t, ok)
+
+	_, ok = ValidateTOTPCode(secret, code, now.Add(3*TOTPPeriod))
+	require.False(t, ok)
+
+	_, ok = ValidateTOTPCode(secret, "12345", now)
+	require.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Portainer", "alice", "JBSWY3DPEHPK3PXP")
+
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/Portainer:alice?"))
+	require.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	require.Contains(t, uri, "issuer=Portainer")
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		require.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+}
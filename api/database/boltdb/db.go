@@ -13,6 +13,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	dserrors "github.com/portainer/portainer/api/dataservices/errors"
+	"github.com/portainer/portainer/api/secrets"
 
 	"github.com/rs/zerolog/log"
 	bolt "go.etcd.io/bbolt"
@@ -34,6 +35,7 @@ type DbConnection struct {
 	MaxBatchDelay   time.Duration
 	InitialMmapSize int
 	EncryptionKey   []byte
+	Secrets         *secrets.Service
 	isEncrypted     bool
 
 	*bolt.DB
@@ -7,6 +7,8 @@ import (
 	"crypto/rand"
 	"io"
 
+	"github.com/portainer/portainer/api/secrets"
+
 	"github.com/pkg/errors"
 	"github.com/segmentio/encoding/json"
 )
@@ -21,6 +23,13 @@ func (connection *DbConnection) MarshalObject(object any) ([]byte, error) {
 	if v, ok := object.(string); ok {
 		buf.WriteString(v)
 	} else {
+		if connection.Secrets != nil && secrets.HasSecretFields(object) {
+			var err error
+			if object, err = connection.Secrets.EncryptedCopy(object); err != nil {
+				return nil, errors.Wrap(err, "Failed encrypting the secret fields")
+			}
+		}
+
 		enc := json.NewEncoder(buf)
 		enc.SetSortMapKeys(false)
 		enc.SetAppendNewline(false)
@@ -56,6 +65,10 @@ func (connection *DbConnection) UnmarshalObject(data []byte, object any) error {
 		}
 
 		*s = string(data)
+	} else if secrets.HasSecretFields(object) {
+		if err := connection.Secrets.DecryptFields(object); err != nil {
+			return errors.Wrap(err, "Failed decrypting the secret fields")
+		}
 	}
 
 	return err
@@ -3,8 +3,13 @@ package boltdb
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/secrets"
+
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
 )
@@ -175,3 +180,40 @@ func Test_ObjectMarshallingEncrypted(t *testing.T) {
 		})
 	}
 }
+
+func Test_MarshalObjectSecretFields(t *testing.T) {
+	is := assert.New(t)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	is.NoError(os.WriteFile(keyFile, []byte(passphrase), 0600))
+
+	provider, err := secrets.NewKeyProvider(keyFile)
+	is.NoError(err)
+
+	service, err := secrets.NewService(provider)
+	is.NoError(err)
+
+	conn := DbConnection{Secrets: service}
+
+	registry := &portainer.Registry{
+		Username:                "user",
+		Password:                "registry password",
+		ManagementConfiguration: &portainer.RegistryManagementConfiguration{Password: "management password"},
+	}
+
+	data, err := conn.MarshalObject(registry)
+	is.NoError(err)
+	is.NotContains(string(data), "password")
+	is.Contains(string(data), `"Username":"user"`)
+
+	// the object of the caller is not modified
+	is.Equal("registry password", registry.Password)
+
+	var object portainer.Registry
+	is.NoError(conn.UnmarshalObject(data, &object))
+	is.Equal(registry, &object)
+
+	// the fields cannot be read without the secrets key
+	conn = DbConnection{}
+	is.ErrorIs(conn.UnmarshalObject(data, &object), secrets.ErrNoSecretsKey)
+}
@@ -0,0 +1,50 @@
+package deploymentgroup
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+)
+
+// BucketName represents the name of the bucket where this service stores data.
+const BucketName = "deployment_group"
+
+// Service represents a service for managing deployment group data.
+type Service struct {
+	dataservices.BaseDataService[portainer.DeploymentGroup, portainer.DeploymentGroupID]
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection portainer.Connection) (*Service, error) {
+	if err := connection.SetServiceName(BucketName); err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		BaseDataService: dataservices.BaseDataService[portainer.DeploymentGroup, portainer.DeploymentGroupID]{
+			Bucket:     BucketName,
+			Connection: connection,
+		},
+	}, nil
+}
+
+func (service *Service) Tx(tx portainer.Transaction) ServiceTx {
+	return ServiceTx{
+		BaseDataServiceTx: dataservices.BaseDataServiceTx[portainer.DeploymentGroup, portainer.DeploymentGroupID]{
+			Bucket:     BucketName,
+			Connection: service.Connection,
+			Tx:         tx,
+		},
+	}
+}
+
+// Create creates a new deployment group.
+func (service *Service) Create(deploymentGroup *portainer.DeploymentGroup) error {
+	return service.Connection.CreateObject(
+		BucketName,
+		func(id uint64) (int, any) {
+			deploymentGroup.ID = portainer.DeploymentGroupID(id)
+
+			return int(deploymentGroup.ID), deploymentGroup
+		},
+	)
+}
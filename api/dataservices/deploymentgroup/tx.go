@@ -0,0 +1,22 @@
+package deploymentgroup
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+)
+
+type ServiceTx struct {
+	dataservices.BaseDataServiceTx[portainer.DeploymentGroup, portainer.DeploymentGroupID]
+}
+
+// Create creates a new deployment group.
+func (service ServiceTx) Create(deploymentGroup *portainer.DeploymentGroup) error {
+	return service.Tx.CreateObject(
+		BucketName,
+		func(id uint64) (int, any) {
+			deploymentGroup.ID = portainer.DeploymentGroupID(id)
+
+			return int(deploymentGroup.ID), deploymentGroup
+		},
+	)
+}
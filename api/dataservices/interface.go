@@ -33,6 +33,9 @@ type (
 		Version() VersionService
 		Webhook() WebhookService
 		PendingActions() PendingActionsService
+		UserSession() UserSessionService
+		VariableSet() VariableSetService
+		DeploymentGroup() DeploymentGroupService
 	}
 
 	DataStore interface {
@@ -161,6 +164,21 @@ type (
 		GetAPIKeyByDigest(digest string) (*portainer.APIKey, error)
 	}
 
+	// UserSessionService represents a service for managing user sessions
+	UserSessionService interface {
+		BaseCRUD[portainer.UserSession, portainer.UserSessionID]
+	}
+
+	// DeploymentGroupService represents a service for managing stack deployment groups
+	DeploymentGroupService interface {
+		BaseCRUD[portainer.DeploymentGroup, portainer.DeploymentGroupID]
+	}
+
+	// VariableSetService represents a service for managing stack variable sets
+	VariableSetService interface {
+		BaseCRUD[portainer.VariableSet, portainer.VariableSetID]
+	}
+
 	// SettingsService represents a service for managing application settings
 	SettingsService interface {
 		Settings() (*portainer.Settings, error)
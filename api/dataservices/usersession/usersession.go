@@ -0,0 +1,40 @@
+package usersession
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+)
+
+// BucketName represents the name of the bucket where this service stores data.
+const BucketName = "user_session"
+
+// Service represents a service for managing user session data.
+type Service struct {
+	dataservices.BaseDataService[portainer.UserSession, portainer.UserSessionID]
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection portainer.Connection) (*Service, error) {
+	if err := connection.SetServiceName(BucketName); err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		BaseDataService: dataservices.BaseDataService[portainer.UserSession, portainer.UserSessionID]{
+			Bucket:     BucketName,
+			Connection: connection,
+		},
+	}, nil
+}
+
+// Create creates a new user session.
+func (service *Service) Create(session *portainer.UserSession) error {
+	return service.Connection.CreateObject(
+		BucketName,
+		func(id uint64) (int, any) {
+			session.ID = portainer.UserSessionID(id)
+
+			return int(session.ID), session
+		},
+	)
+}
@@ -0,0 +1,22 @@
+package variableset
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+)
+
+type ServiceTx struct {
+	dataservices.BaseDataServiceTx[portainer.VariableSet, portainer.VariableSetID]
+}
+
+// Create creates a new variable set.
+func (service ServiceTx) Create(variableSet *portainer.VariableSet) error {
+	return service.Tx.CreateObject(
+		BucketName,
+		func(id uint64) (int, any) {
+			variableSet.ID = portainer.VariableSetID(id)
+
+			return int(variableSet.ID), variableSet
+		},
+	)
+}
@@ -0,0 +1,50 @@
+package variableset
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+)
+
+// BucketName represents the name of the bucket where this service stores data.
+const BucketName = "variable_set"
+
+// Service represents a service for managing variable set data.
+type Service struct {
+	dataservices.BaseDataService[portainer.VariableSet, portainer.VariableSetID]
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection portainer.Connection) (*Service, error) {
+	if err := connection.SetServiceName(BucketName); err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		BaseDataService: dataservices.BaseDataService[portainer.VariableSet, portainer.VariableSetID]{
+			Bucket:     BucketName,
+			Connection: connection,
+		},
+	}, nil
+}
+
+func (service *Service) Tx(tx portainer.Transaction) ServiceTx {
+	return ServiceTx{
+		BaseDataServiceTx: dataservices.BaseDataServiceTx[portainer.VariableSet, portainer.VariableSetID]{
+			Bucket:     BucketName,
+			Connection: service.Connection,
+			Tx:         tx,
+		},
+	}
+}
+
+// Create creates a new variable set.
+func (service *Service) Create(variableSet *portainer.VariableSet) error {
+	return service.Connection.CreateObject(
+		BucketName,
+		func(id uint64) (int, any) {
+			variableSet.ID = portainer.VariableSetID(id)
+
+			return int(variableSet.ID), variableSet
+		},
+	)
+}
@@ -36,6 +36,9 @@ func (store *Store) checkOrCreateDefaultSettings() error {
 			BlackListedLabels:    make([]portainer.Pair, 0),
 			InternalAuthSettings: portainer.InternalAuthSettings{
 				RequiredPasswordLength: 12,
+				PasswordPolicy: portainer.PasswordPolicy{
+					DenyCommonPasswords: true,
+				},
 			},
 			LDAPSettings: portainer.LDAPSettings{
 				AnonymousMode:   true,
@@ -0,0 +1,118 @@
+package datastore
+
+import (
+	"github.com/portainer/portainer/api/dataservices"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RotateSecrets saves again every object holding secret fields so that they are all encrypted with the current
+// secrets key, including the fields stored before the field encryption was enabled
+func (store *Store) RotateSecrets() error {
+	// the SSL settings and the deprecated DockerHub bucket are not part of the transactions
+	sslSettings, err := store.SSLSettings().Settings()
+	if err == nil {
+		err = store.SSLSettings().UpdateSettings(sslSettings)
+	}
+
+	if err != nil && !store.IsErrObjectNotFound(err) {
+		return err
+	}
+
+	dockerHub, err := store.DockerHubService.DockerHub()
+	if err == nil {
+		err = store.DockerHubService.UpdateDockerHub(dockerHub)
+	}
+
+	if err != nil && !store.IsErrObjectNotFound(err) {
+		return err
+	}
+
+	return store.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		settings, err := tx.Settings().Settings()
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Settings().UpdateSettings(settings); err != nil {
+			return err
+		}
+
+		registries, err := tx.Registry().ReadAll()
+		if err != nil {
+			return err
+		}
+
+		for _, registry := range registries {
+			if err := tx.Registry().Update(registry.ID, &registry); err != nil {
+				return err
+			}
+		}
+
+		endpoints, err := tx.Endpoint().Endpoints()
+		if err != nil {
+			return err
+		}
+
+		for _, endpoint := range endpoints {
+			if err := tx.Endpoint().UpdateEndpoint(endpoint.ID, &endpoint); err != nil {
+				return err
+			}
+		}
+
+		stacks, err := tx.Stack().ReadAll()
+		if err != nil {
+			return err
+		}
+
+		for _, stack := range stacks {
+			if err := tx.Stack().Update(stack.ID, &stack); err != nil {
+				return err
+			}
+		}
+
+		customTemplates, err := tx.CustomTemplate().ReadAll()
+		if err != nil {
+			return err
+		}
+
+		for _, customTemplate := range customTemplates {
+			if err := tx.CustomTemplate().Update(customTemplate.ID, &customTemplate); err != nil {
+				return err
+			}
+		}
+
+		variableSets, err := tx.VariableSet().ReadAll()
+		if err != nil {
+			return err
+		}
+
+		for _, variableSet := range variableSets {
+			if err := tx.VariableSet().Update(variableSet.ID, &variableSet); err != nil {
+				return err
+			}
+		}
+
+		users, err := tx.User().ReadAll()
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := tx.User().Update(user.ID, &user); err != nil {
+				return err
+			}
+		}
+
+		log.Info().
+			Int("users", len(users)).
+			Int("registries", len(registries)).
+			Int("environments", len(endpoints)).
+			Int("stacks", len(stacks)).
+			Int("custom_templates", len(customTemplates)).
+			Int("variable_sets", len(variableSets)).
+			Msg("secret fields encrypted with the current secrets key")
+
+		return nil
+	})
+}
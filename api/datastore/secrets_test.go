@@ -0,0 +1,129 @@
+package datastore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/database/boltdb"
+	registryservice "github.com/portainer/portainer/api/dataservices/registry"
+	userservice "github.com/portainer/portainer/api/dataservices/user"
+	"github.com/portainer/portainer/api/secrets"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSecretsService(t *testing.T, key string, previousKeys ...string) *secrets.Service {
+	newProvider := func(key string) secrets.KeyProvider {
+		path := filepath.Join(t.TempDir(), "key")
+		require.NoError(t, os.WriteFile(path, []byte(key), 0600))
+
+		provider, err := secrets.NewKeyProvider(path)
+		require.NoError(t, err)
+
+		return provider
+	}
+
+	var previous []secrets.KeyProvider
+	for _, key := range previousKeys {
+		previous = append(previous, newProvider(key))
+	}
+
+	service, err := secrets.NewService(newProvider(key), previous...)
+	require.NoError(t, err)
+
+	return service
+}
+
+func TestRotateSecrets(t *testing.T) {
+	_, store := MustNewTestStore(t, true, false)
+
+	conn := store.GetConnection().(*boltdb.DbConnection)
+
+	registry := &portainer.Registry{Name: "registry", Password: "passwd"}
+	require.NoError(t, store.Registry().Create(registry))
+
+	storedPassword := func() string {
+		data, err := conn.GetRawBytes(registryservice.BucketName, conn.ConvertToKey(int(registry.ID)))
+		require.NoError(t, err)
+
+		var raw struct{ Password string }
+		require.NoError(t, conn.UnmarshalObject(data, &raw))
+
+		return raw.Password
+	}
+
+	require.Equal(t, "passwd", storedPassword())
+
+	require.NoError(t, store.SSLSettings().UpdateSettings(&portainer.SSLSettings{
+		ACME: &portainer.ACMESettings{DNSProvider: "cloudflare", DNSProviderConfig: map[string]string{"apiToken": "token"}},
+	}))
+	require.NoError(t, store.DockerHubService.UpdateDockerHub(&portainer.DockerHub{Authentication: true, Password: "hub password"}))
+
+	// the fields stored as plain text are encrypted by the rotation
+	conn.Secrets = newTestSecretsService(t, "first key")
+	require.NoError(t, store.RotateSecrets())
+
+	firstKeyID, ok := secrets.KeyIDOf(storedPassword())
+	require.True(t, ok)
+	require.Equal(t, conn.Secrets.KeyID(), firstKeyID)
+
+	// the fields encrypted with the previous key are encrypted with the new key
+	conn.Secrets = newTestSecretsService(t, "second key", "first key")
+	require.NoError(t, store.RotateSecrets())
+
+	secondKeyID, ok := secrets.KeyIDOf(storedPassword())
+	require.True(t, ok)
+	require.Equal(t, conn.Secrets.KeyID(), secondKeyID)
+	require.NotEqual(t, firstKeyID, secondKeyID)
+
+	// the previous key is no longer needed
+	conn.Secrets = newTestSecretsService(t, "second key")
+
+	stored, err := store.Registry().Read(registry.ID)
+	require.NoError(t, err)
+	require.Equal(t, "passwd", stored.Password)
+
+	sslSettings, err := store.SSLSettings().Settings()
+	require.NoError(t, err)
+	require.Equal(t, "token", sslSettings.ACME.DNSProviderConfig["apiToken"])
+
+	dockerHub, err := store.DockerHubService.DockerHub()
+	require.NoError(t, err)
+	require.Equal(t, "hub password", dockerHub.Password)
+}
+
+func TestUserTwoFactorSecretEncryption(t *testing.T) {
+	_, store := MustNewTestStore(t, true, false)
+
+	conn := store.GetConnection().(*boltdb.DbConnection)
+
+	user := &portainer.User{Username: "admin", TwoFactor: portainer.UserTwoFactor{Enabled: true, Secret: "JBSWY3DPEHPK3PXP"}}
+	require.NoError(t, store.User().Create(user))
+
+	storedSecret := func() string {
+		data, err := conn.GetRawBytes(userservice.BucketName, conn.ConvertToKey(int(user.ID)))
+		require.NoError(t, err)
+
+		var raw struct{ TwoFactor struct{ Secret string } }
+		require.NoError(t, conn.UnmarshalObject(data, &raw))
+
+		return raw.TwoFactor.Secret
+	}
+
+	require.Equal(t, "JBSWY3DPEHPK3PXP", storedSecret())
+
+	// the secret stored as plain text is encrypted by the rotation
+	conn.Secrets = newTestSecretsService(t, "first key")
+	require.NoError(t, store.RotateSecrets())
+	require.True(t, secrets.IsEncrypted(storedSecret()))
+
+	user.TwoFactor.Secret = "KRSXG5CTMVRXEZLU"
+	require.NoError(t, store.User().Update(user.ID, user))
+	require.True(t, secrets.IsEncrypted(storedSecret()))
+
+	stored, err := store.User().Read(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "KRSXG5CTMVRXEZLU", stored.TwoFactor.Secret)
+}
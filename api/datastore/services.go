@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/dataservices/apikeyrepository"
 	"github.com/portainer/portainer/api/dataservices/customtemplate"
+	"github.com/portainer/portainer/api/dataservices/deploymentgroup"
 	"github.com/portainer/portainer/api/dataservices/dockerhub"
 	"github.com/portainer/portainer/api/dataservices/edgegroup"
 	"github.com/portainer/portainer/api/dataservices/edgejob"
@@ -33,6 +34,8 @@ import (
 	"github.com/portainer/portainer/api/dataservices/teammembership"
 	"github.com/portainer/portainer/api/dataservices/tunnelserver"
 	"github.com/portainer/portainer/api/dataservices/user"
+	"github.com/portainer/portainer/api/dataservices/usersession"
+	"github.com/portainer/portainer/api/dataservices/variableset"
 	"github.com/portainer/portainer/api/dataservices/version"
 	"github.com/portainer/portainer/api/dataservices/webhook"
 
@@ -77,6 +80,9 @@ type Store struct {
 	VersionService            *version.Service
 	WebhookService            *webhook.Service
 	PendingActionsService     *pendingactions.Service
+	UserSessionService        *usersession.Service
+	VariableSetService        *variableset.Service
+	DeploymentGroupService    *deploymentgroup.Service
 }
 
 func (store *Store) initServices() error {
@@ -227,6 +233,24 @@ func (store *Store) initServices() error {
 	}
 	store.APIKeyRepositoryService = apiKeyService
 
+	userSessionService, err := usersession.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.UserSessionService = userSessionService
+
+	variableSetService, err := variableset.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.VariableSetService = variableSetService
+
+	deploymentGroupService, err := deploymentgroup.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.DeploymentGroupService = deploymentGroupService
+
 	versionService, err := version.NewService(store.connection)
 	if err != nil {
 		return err
@@ -264,6 +288,11 @@ func (store *Store) CustomTemplate() dataservices.CustomTemplateService {
 	return store.CustomTemplateService
 }
 
+// DeploymentGroup gives access to the DeploymentGroup data management layer
+func (store *Store) DeploymentGroup() dataservices.DeploymentGroupService {
+	return store.DeploymentGroupService
+}
+
 // EdgeGroup gives access to the EdgeGroup data management layer
 func (store *Store) EdgeGroup() dataservices.EdgeGroupService {
 	return store.EdgeGroupService
@@ -323,6 +352,11 @@ func (store *Store) APIKeyRepository() dataservices.APIKeyRepository {
 	return store.APIKeyRepositoryService
 }
 
+// UserSession gives access to the UserSession data management layer
+func (store *Store) UserSession() dataservices.UserSessionService {
+	return store.UserSessionService
+}
+
 // Settings gives access to the Settings data management layer
 func (store *Store) Settings() dataservices.SettingsService {
 	return store.SettingsService
@@ -372,6 +406,11 @@ func (store *Store) Version() dataservices.VersionService {
 	return store.VersionService
 }
 
+// VariableSet gives access to the VariableSet data management layer
+func (store *Store) VariableSet() dataservices.VariableSetService {
+	return store.VariableSetService
+}
+
 // Webhook gives access to the Webhook data management layer
 func (store *Store) Webhook() dataservices.WebhookService {
 	return store.WebhookService
@@ -379,6 +418,7 @@ func (store *Store) Webhook() dataservices.WebhookService {
 
 type storeExport struct {
 	CustomTemplate     []portainer.CustomTemplate     `json:"customtemplates,omitempty"`
+	DeploymentGroup    []portainer.DeploymentGroup    `json:"deployment_group,omitempty"`
 	EdgeGroup          []portainer.EdgeGroup          `json:"edgegroups,omitempty"`
 	EdgeJob            []portainer.EdgeJob            `json:"edgejobs,omitempty"`
 	EdgeStack          []portainer.EdgeStack          `json:"edge_stack,omitempty"`
@@ -400,6 +440,7 @@ type storeExport struct {
 	Team               []portainer.Team               `json:"teams,omitempty"`
 	TunnelServer       portainer.TunnelServerInfo     `json:"tunnel_server,omitempty"`
 	User               []portainer.User               `json:"users,omitempty"`
+	VariableSet        []portainer.VariableSet        `json:"variable_set,omitempty"`
 	Version            models.Version                 `json:"version,omitempty"`
 	Webhook            []portainer.Webhook            `json:"webhooks,omitempty"`
 	Metadata           map[string]any                 `json:"metadata,omitempty"`
@@ -416,6 +457,14 @@ func (store *Store) Export(filename string) (err error) {
 		backup.CustomTemplate = c
 	}
 
+	if deploymentGroups, err := store.DeploymentGroup().ReadAll(); err != nil {
+		if !store.IsErrObjectNotFound(err) {
+			log.Error().Err(err).Msg("exporting Deployment Groups")
+		}
+	} else {
+		backup.DeploymentGroup = deploymentGroups
+	}
+
 	if e, err := store.EdgeGroup().ReadAll(); err != nil {
 		if !store.IsErrObjectNotFound(err) {
 			log.Error().Err(err).Msg("exporting Edge Groups")
@@ -584,6 +633,14 @@ func (store *Store) Export(filename string) (err error) {
 		backup.User = users
 	}
 
+	if variableSets, err := store.VariableSet().ReadAll(); err != nil {
+		if !store.IsErrObjectNotFound(err) {
+			log.Error().Err(err).Msg("exporting Variable Sets")
+		}
+	} else {
+		backup.VariableSet = variableSets
+	}
+
 	if webhooks, err := store.Webhook().ReadAll(); err != nil {
 		if !store.IsErrObjectNotFound(err) {
 			log.Error().Err(err).Msg("exporting Webhooks")
@@ -631,6 +688,10 @@ func (store *Store) Import(filename string) (err error) {
 		store.CustomTemplate().Update(v.ID, &v)
 	}
 
+	for _, v := range backup.DeploymentGroup {
+		store.DeploymentGroup().Update(v.ID, &v)
+	}
+
 	for _, v := range backup.EdgeGroup {
 		store.EdgeGroup().Update(v.ID, &v)
 	}
@@ -702,6 +763,10 @@ func (store *Store) Import(filename string) (err error) {
 		}
 	}
 
+	for _, v := range backup.VariableSet {
+		store.VariableSet().Update(v.ID, &v)
+	}
+
 	for _, v := range backup.Webhook {
 		store.Webhook().Update(v.ID, &v)
 	}
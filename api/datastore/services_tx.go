@@ -14,7 +14,13 @@ func (tx *StoreTx) IsErrObjectNotFound(err error) bool {
 	return tx.store.IsErrObjectNotFound(err)
 }
 
-func (tx *StoreTx) CustomTemplate() dataservices.CustomTemplateService { return nil }
+func (tx *StoreTx) CustomTemplate() dataservices.CustomTemplateService {
+	return tx.store.CustomTemplateService.Tx(tx.tx)
+}
+
+func (tx *StoreTx) DeploymentGroup() dataservices.DeploymentGroupService {
+	return tx.store.DeploymentGroupService.Tx(tx.tx)
+}
 
 func (tx *StoreTx) PendingActions() dataservices.PendingActionsService {
 	return tx.store.PendingActionsService.Tx(tx.tx)
@@ -64,6 +70,8 @@ func (tx *StoreTx) Role() dataservices.RoleService {
 
 func (tx *StoreTx) APIKeyRepository() dataservices.APIKeyRepository { return nil }
 
+func (tx *StoreTx) UserSession() dataservices.UserSessionService { return nil }
+
 func (tx *StoreTx) Settings() dataservices.SettingsService {
 	return tx.store.SettingsService.Tx(tx.tx)
 }
@@ -96,5 +104,9 @@ func (tx *StoreTx) User() dataservices.UserService {
 	return tx.store.UserService.Tx(tx.tx)
 }
 
+func (tx *StoreTx) VariableSet() dataservices.VariableSetService {
+	return tx.store.VariableSetService.Tx(tx.tx)
+}
+
 func (tx *StoreTx) Version() dataservices.VersionService { return nil }
 func (tx *StoreTx) Webhook() dataservices.WebhookService { return nil }
@@ -1,5 +1,33 @@
 package exec
 
-import "regexp"
+import (
+	"regexp"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/pkg/libstack"
+)
 
 var stackNameNormalizeRegex = regexp.MustCompile("[^-_a-z0-9]+")
+
+// stackHealth converts the status computed by a libstack deployer
+func stackHealth(status libstack.StackStatus) *portainer.StackHealth {
+	health := &portainer.StackHealth{
+		Status:   string(status.Status),
+		Services: make([]portainer.StackServiceHealth, 0, len(status.Services)),
+	}
+
+	for _, service := range status.Services {
+		health.Services = append(health.Services, portainer.StackServiceHealth{
+			Name:            service.Name,
+			Status:          string(service.Status),
+			DesiredReplicas: service.DesiredReplicas,
+			RunningReplicas: service.RunningReplicas,
+			Health:          service.Health,
+			RestartCount:    service.RestartCount,
+			ExitCode:        service.ExitCode,
+			LogsTail:        service.LogsTail,
+		})
+	}
+
+	return health
+}
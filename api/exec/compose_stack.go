@@ -7,12 +7,15 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/proxy/factory"
+	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/internal/registryutils"
+	"github.com/portainer/portainer/api/stacks/buildlogs"
 	"github.com/portainer/portainer/api/stacks/stackutils"
 	"github.com/portainer/portainer/pkg/libstack"
 
@@ -26,14 +29,16 @@ type ComposeStackManager struct {
 	deployer     libstack.Deployer
 	proxyManager *proxy.Manager
 	dataStore    dataservices.DataStore
+	buildLogs    *buildlogs.Service
 }
 
 // NewComposeStackManager returns a Compose stack manager
-func NewComposeStackManager(deployer libstack.Deployer, proxyManager *proxy.Manager, dataStore dataservices.DataStore) *ComposeStackManager {
+func NewComposeStackManager(deployer libstack.Deployer, proxyManager *proxy.Manager, dataStore dataservices.DataStore, buildLogs *buildlogs.Service) *ComposeStackManager {
 	return &ComposeStackManager{
 		deployer:     deployer,
 		proxyManager: proxyManager,
 		dataStore:    dataStore,
+		buildLogs:    buildLogs,
 	}
 }
 
@@ -44,7 +49,7 @@ func (manager *ComposeStackManager) ComposeSyntaxMaxVersion() string {
 
 // Up builds, (re)creates and starts containers in the background. Wraps `docker-compose up -d` command
 func (manager *ComposeStackManager) Up(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, options portainer.ComposeUpOptions) error {
-	url, proxy, err := manager.fetchEndpointProxy(endpoint)
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
 	if err != nil {
 		return errors.Wrap(err, "failed to fetch environment proxy")
 	}
@@ -53,11 +58,15 @@ func (manager *ComposeStackManager) Up(ctx context.Context, stack *portainer.Sta
 		defer proxy.Close()
 	}
 
-	envFilePath, err := createEnvFile(stack)
+	env := inheritedEnv(manager.dataStore, stack, endpoint)
+
+	envFilePath, err := createEnvFile(stack, env)
 	if err != nil {
 		return errors.Wrap(err, "failed to create env file")
 	}
 
+	build := manager.buildLogs.Start(stack.ID)
+
 	filePaths := stackutils.GetStackFilePaths(stack, true)
 	err = manager.deployer.Deploy(ctx, filePaths, libstack.DeployOptions{
 		Options: libstack.Options{
@@ -69,13 +78,53 @@ func (manager *ComposeStackManager) Up(ctx context.Context, stack *portainer.Sta
 		},
 		ForceRecreate:        options.ForceRecreate,
 		AbortOnContainerExit: options.AbortOnContainerExit,
+		BuildArgs:            stackBuildArgs(manager.dataStore, stack),
+		BuildOutput:          build,
 	})
+
+	manager.buildLogs.Finish(stack.ID, build)
+	manager.storeBuildLog(stack, build, err == nil)
+
 	return errors.Wrap(err, "failed to deploy a stack")
 }
 
+// storeBuildLog attaches the output of the build to the stack, the stacks whose services have no build section are
+// left untouched
+func (manager *ComposeStackManager) storeBuildLog(stack *portainer.Stack, build *buildlogs.Build, success bool) {
+	output := build.Output()
+	if output == "" {
+		return
+	}
+
+	stack.BuildLog = &portainer.StackBuildLog{
+		Date:    time.Now().Unix(),
+		Success: success,
+		Output:  output,
+	}
+
+	// the stacks that are being created are not stored yet, their build log is persisted along with them
+	if err := manager.dataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		storedStack, err := tx.Stack().Read(stack.ID)
+		if tx.IsErrObjectNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		storedStack.BuildLog = stack.BuildLog
+
+		return tx.Stack().Update(stack.ID, storedStack)
+	}); err != nil {
+		log.Warn().
+			Err(err).
+			Int("stack_id", int(stack.ID)).
+			Msg("unable to persist the build log of the stack")
+	}
+}
+
 // Run runs a one-off command on a service. Wraps `docker-compose run` command
 func (manager *ComposeStackManager) Run(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string, options portainer.ComposeRunOptions) error {
-	url, proxy, err := manager.fetchEndpointProxy(endpoint)
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
 	if err != nil {
 		return errors.Wrap(err, "failed to fetch environment proxy")
 	}
@@ -84,7 +133,7 @@ func (manager *ComposeStackManager) Run(ctx context.Context, stack *portainer.St
 		defer proxy.Close()
 	}
 
-	envFilePath, err := createEnvFile(stack)
+	envFilePath, err := createEnvFile(stack, inheritedEnv(manager.dataStore, stack, endpoint))
 	if err != nil {
 		return errors.Wrap(err, "failed to create env file")
 	}
@@ -107,7 +156,7 @@ func (manager *ComposeStackManager) Run(ctx context.Context, stack *portainer.St
 
 // Down stops and removes containers, networks, images, and volumes
 func (manager *ComposeStackManager) Down(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) error {
-	url, proxy, err := manager.fetchEndpointProxy(endpoint)
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
 	if err != nil {
 		return err
 	} else if proxy != nil {
@@ -127,14 +176,14 @@ func (manager *ComposeStackManager) Down(ctx context.Context, stack *portainer.S
 // Pull an image associated with a service defined in a docker-compose.yml or docker-stack.yml file,
 // but does not start containers based on those images.
 func (manager *ComposeStackManager) Pull(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, options portainer.ComposeOptions) error {
-	url, proxy, err := manager.fetchEndpointProxy(endpoint)
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
 	if err != nil {
 		return err
 	} else if proxy != nil {
 		defer proxy.Close()
 	}
 
-	envFilePath, err := createEnvFile(stack)
+	envFilePath, err := createEnvFile(stack, inheritedEnv(manager.dataStore, stack, endpoint))
 	if err != nil {
 		return errors.Wrap(err, "failed to create env file")
 	}
@@ -150,28 +199,127 @@ func (manager *ComposeStackManager) Pull(ctx context.Context, stack *portainer.S
 	return errors.Wrap(err, "failed to pull images of the stack")
 }
 
+// StartService starts the existing containers of a service of the stack
+func (manager *ComposeStackManager) StartService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return manager.withServiceOptions(stack, endpoint, nil, func(options libstack.Options) error {
+		return errors.Wrap(manager.deployer.StartService(ctx, stackutils.GetStackFilePaths(stack, true), serviceName, options), "failed to start the stack service")
+	})
+}
+
+// StopService stops the containers of a service of the stack without removing them
+func (manager *ComposeStackManager) StopService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return manager.withServiceOptions(stack, endpoint, nil, func(options libstack.Options) error {
+		return errors.Wrap(manager.deployer.StopService(ctx, stackutils.GetStackFilePaths(stack, true), serviceName, options), "failed to stop the stack service")
+	})
+}
+
+// RestartService restarts the containers of a service of the stack
+func (manager *ComposeStackManager) RestartService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return manager.withServiceOptions(stack, endpoint, nil, func(options libstack.Options) error {
+		return errors.Wrap(manager.deployer.RestartService(ctx, stackutils.GetStackFilePaths(stack, true), serviceName, options), "failed to restart the stack service")
+	})
+}
+
+// ScaleService scales a service of the stack to the given number of replicas
+func (manager *ComposeStackManager) ScaleService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string, replicas int) error {
+	return manager.withServiceOptions(stack, endpoint, nil, func(options libstack.Options) error {
+		return errors.Wrap(manager.deployer.ScaleService(ctx, stackutils.GetStackFilePaths(stack, true), serviceName, replicas, options), "failed to scale the stack service")
+	})
+}
+
+// RecreateService recreates the containers of a service of the stack, optionally pulling its image first
+func (manager *ComposeStackManager) RecreateService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string, options portainer.ComposeRecreateServiceOptions) error {
+	return manager.withServiceOptions(stack, endpoint, options.Registries, func(libstackOptions libstack.Options) error {
+		err := manager.deployer.RecreateService(ctx, stackutils.GetStackFilePaths(stack, true), serviceName, libstack.RecreateServiceOptions{
+			Options:   libstackOptions,
+			PullImage: options.PullImage,
+		})
+
+		return errors.Wrap(err, "failed to recreate the stack service")
+	})
+}
+
+// Status returns the status of the services of the stack
+func (manager *ComposeStackManager) Status(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) (*portainer.StackHealth, error) {
+	var health *portainer.StackHealth
+
+	err := manager.withServiceOptions(stack, endpoint, nil, func(options libstack.Options) error {
+		status, err := manager.deployer.GetStatus(ctx, stackutils.GetStackFilePaths(stack, true), options)
+		if err != nil {
+			return errors.Wrap(err, "failed to retrieve the stack status")
+		}
+
+		health = stackHealth(status)
+
+		return nil
+	})
+
+	return health, err
+}
+
+// WaitForRunning waits for the services of the stack to run with their health checks passing
+func (manager *ComposeStackManager) WaitForRunning(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	return manager.withServiceOptions(stack, endpoint, nil, func(options libstack.Options) error {
+		result := manager.deployer.WaitForStatusWithOptions(ctx, libstack.StatusRunning, libstack.WaitOptions{
+			Options:        options,
+			RequireHealthy: true,
+		})
+		if result.ErrorMsg != "" {
+			return errors.Errorf("the stack %s failed to start: %s", stack.Name, result.ErrorMsg)
+		}
+
+		return nil
+	})
+}
+
+// withServiceOptions runs fn with the libstack options targeting the environment of the stack
+func (manager *ComposeStackManager) withServiceOptions(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, fn func(libstack.Options) error) error {
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch environment proxy")
+	}
+
+	if proxy != nil {
+		defer proxy.Close()
+	}
+
+	envFilePath, err := createEnvFile(stack, inheritedEnv(manager.dataStore, stack, endpoint))
+	if err != nil {
+		return errors.Wrap(err, "failed to create env file")
+	}
+
+	return fn(libstack.Options{
+		WorkingDir:  stack.ProjectPath,
+		EnvFilePath: envFilePath,
+		Host:        url,
+		ProjectName: stack.Name,
+		Registries:  portainerRegistriesToAuthConfigs(manager.dataStore, registries),
+	})
+}
+
 // NormalizeStackName returns a new stack name with unsupported characters replaced
 func (manager *ComposeStackManager) NormalizeStackName(name string) string {
 	return stackNameNormalizeRegex.ReplaceAllString(strings.ToLower(name), "")
 }
 
-func (manager *ComposeStackManager) fetchEndpointProxy(endpoint *portainer.Endpoint) (string, *factory.ProxyServer, error) {
+// fetchEndpointProxy returns the address of a local proxy to the environment, the proxy must be closed once used
+func fetchEndpointProxy(proxyManager *proxy.Manager, endpoint *portainer.Endpoint) (string, *factory.ProxyServer, error) {
 	if strings.HasPrefix(endpoint.URL, "unix://") || strings.HasPrefix(endpoint.URL, "npipe://") {
 		return "", nil, nil
 	}
 
-	proxy, err := manager.proxyManager.CreateAgentProxyServer(endpoint)
+	proxyServer, err := proxyManager.CreateAgentProxyServer(endpoint)
 	if err != nil {
 		return "", nil, err
 	}
 
-	return fmt.Sprintf("tcp://127.0.0.1:%d", proxy.Port), proxy, nil
+	return fmt.Sprintf("tcp://127.0.0.1:%d", proxyServer.Port), proxyServer, nil
 }
 
-// createEnvFile creates a file that would hold both "in-place" and default environment variables.
-// It will return the name of the file if the stack has "in-place" env vars, otherwise empty string.
-func createEnvFile(stack *portainer.Stack) (string, error) {
-	if len(stack.Env) == 0 {
+// createEnvFile creates a file that would hold the "in-place", the inherited and the default environment variables.
+// It will return the name of the file if the stack has "in-place" or inherited env vars, otherwise empty string.
+func createEnvFile(stack *portainer.Stack, inheritedEnv []portainer.Pair) (string, error) {
+	if len(stack.Env) == 0 && len(inheritedEnv) == 0 {
 		return "", nil
 	}
 
@@ -188,6 +336,11 @@ func createEnvFile(stack *portainer.Stack) (string, error) {
 		return "", err
 	}
 
+	// Copy from environment labels and variable sets, before the stack env vars so that they can be overridden
+	if err := copyConfigEnvVars(envfile, inheritedEnv); err != nil {
+		return "", err
+	}
+
 	// Copy from stack env vars
 	if err := copyConfigEnvVars(envfile, stack.Env); err != nil {
 		return "", err
@@ -226,6 +379,42 @@ func copyConfigEnvVars(w io.Writer, envs []portainer.Pair) error {
 	return nil
 }
 
+// environmentLabelsEnv returns the labels of the environment, including the ones inherited from its group, as env vars
+func environmentLabelsEnv(tx dataservices.DataStoreTx, endpoint *portainer.Endpoint) []portainer.Pair {
+	endpointGroup, err := tx.EndpointGroup().Read(endpoint.GroupID)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Int("endpoint_id", int(endpoint.ID)).
+			Msg("Unable to retrieve the environment group, its labels won't be exposed to the stack")
+
+		endpointGroup = nil
+	}
+
+	return endpointutils.LabelsEnv(endpointutils.EndpointLabels(endpoint, endpointGroup))
+}
+
+// inheritedEnv returns the env vars inherited by the stack, the environment labels come first so that the variable
+// sets override them
+// stackBuildArgs returns the env vars of the stack passed to its builds. The build args end up in the image history and
+// in the build log, so the inherited variables and the env vars overriding a secret variable are left out.
+func stackBuildArgs(tx dataservices.DataStoreTx, stack *portainer.Stack) []string {
+	secretNames := stackutils.SecretVariableNames(tx, stack)
+
+	buildArgs := make([]string, 0, len(stack.Env))
+	for _, envvar := range stack.Env {
+		if !secretNames[envvar.Name] {
+			buildArgs = append(buildArgs, envvar.Name+"="+envvar.Value)
+		}
+	}
+
+	return buildArgs
+}
+
+func inheritedEnv(tx dataservices.DataStoreTx, stack *portainer.Stack, endpoint *portainer.Endpoint) []portainer.Pair {
+	return append(environmentLabelsEnv(tx, endpoint), stackutils.VariableSetsEnv(tx, stack, endpoint)...)
+}
+
 func portainerRegistriesToAuthConfigs(tx dataservices.DataStoreTx, registries []portainer.Registry) []types.AuthConfig {
 	var authConfigs []types.AuthConfig
 
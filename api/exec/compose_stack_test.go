@@ -1,6 +1,9 @@
 package exec
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path"
@@ -8,7 +11,12 @@ import (
 	"testing"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/stacks/buildlogs"
+	"github.com/portainer/portainer/pkg/libstack"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_createEnvFile(t *testing.T) {
@@ -51,7 +59,7 @@ func Test_createEnvFile(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, _ := createEnvFile(tt.stack)
+			result, _ := createEnvFile(tt.stack, nil)
 
 			if tt.expected != "" {
 				assert.Equal(t, filepath.Join(tt.stack.ProjectPath, "stack.env"), result)
@@ -77,7 +85,7 @@ func Test_createEnvFile_mergesDefultAndInplaceEnvVars(t *testing.T) {
 			{Name: "VAR3", Value: "VAL3"},
 		},
 	}
-	result, err := createEnvFile(stack)
+	result, err := createEnvFile(stack, nil)
 	assert.Equal(t, filepath.Join(stack.ProjectPath, "stack.env"), result)
 	assert.NoError(t, err)
 	assert.FileExists(t, path.Join(dir, "stack.env"))
@@ -86,3 +94,89 @@ func Test_createEnvFile_mergesDefultAndInplaceEnvVars(t *testing.T) {
 
 	assert.Equal(t, []byte("VAR1=VAL1\nVAR2=VAL2\n\nVAR1=NEW_VAL1\nVAR3=VAL3\n"), content)
 }
+
+func Test_createEnvFile_withEnvironmentLabels(t *testing.T) {
+	dir := t.TempDir()
+	stack := &portainer.Stack{
+		ProjectPath: dir,
+		Env: []portainer.Pair{
+			{Name: "PORTAINER_LABEL_OWNER", Value: "team-y"},
+		},
+	}
+
+	labelsEnv := []portainer.Pair{
+		{Name: "PORTAINER_LABEL_SITE", Value: "paris"},
+		{Name: "PORTAINER_LABEL_OWNER", Value: "team-x"},
+	}
+
+	result, err := createEnvFile(stack, labelsEnv)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(stack.ProjectPath, "stack.env"), result)
+
+	content, err := os.ReadFile(result)
+	assert.NoError(t, err)
+	assert.Equal(t, "PORTAINER_LABEL_SITE=paris\nPORTAINER_LABEL_OWNER=team-x\nPORTAINER_LABEL_OWNER=team-y\n", string(content))
+}
+
+type buildingComposeDeployer struct {
+	libstack.Deployer
+	deployOptions libstack.DeployOptions
+	output        string
+	err           error
+}
+
+func (d *buildingComposeDeployer) Deploy(ctx context.Context, filePaths []string, options libstack.DeployOptions) error {
+	d.deployOptions = options
+
+	if d.output != "" {
+		fmt.Fprint(options.BuildOutput, d.output)
+	}
+
+	return d.err
+}
+
+func TestComposeStackManager_UpBuildLog(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	endpoint := &portainer.Endpoint{ID: 1, URL: "unix:///var/run/docker.sock"}
+
+	require.NoError(t, store.VariableSet().Create(&portainer.VariableSet{
+		Name:      "production",
+		ScopeType: portainer.EndpointVariableSetScope,
+		ScopeID:   1,
+		Variables: []portainer.StackVariable{
+			{Name: "DB_HOST", Value: "db.internal"},
+			{Name: "DB_PASSWORD", Value: "secret", Secret: true},
+		},
+	}))
+
+	stack := &portainer.Stack{
+		ID:             1,
+		Name:           "app",
+		ProjectPath:    t.TempDir(),
+		EntryPoint:     "docker-compose.yml",
+		Env:            []portainer.Pair{{Name: "VERSION", Value: "1.0"}, {Name: "DB_PASSWORD", Value: "override"}},
+		VariableSetIDs: []portainer.VariableSetID{1},
+	}
+	require.NoError(t, store.Stack().Create(stack))
+
+	deployer := &buildingComposeDeployer{}
+	manager := NewComposeStackManager(deployer, nil, store, buildlogs.NewService())
+
+	// the stacks without build section keep their previous build log, only the env vars of the stack that do not
+	// override a secret variable are passed to the builds
+	require.NoError(t, manager.Up(context.Background(), stack, endpoint, portainer.ComposeUpOptions{}))
+	require.Equal(t, []string{"VERSION=1.0"}, deployer.deployOptions.BuildArgs)
+	require.Nil(t, stack.BuildLog)
+
+	deployer.output = "Step 1/2 : FROM alpine\n"
+	deployer.err = errors.New("build failed")
+	require.Error(t, manager.Up(context.Background(), stack, endpoint, portainer.ComposeUpOptions{}))
+
+	storedStack, err := store.Stack().Read(stack.ID)
+	require.NoError(t, err)
+	require.NotNil(t, storedStack.BuildLog)
+	require.False(t, storedStack.BuildLog.Success)
+	require.Equal(t, "Step 1/2 : FROM alpine\n", storedStack.BuildLog.Output)
+	require.Equal(t, storedStack.BuildLog, stack.BuildLog)
+}
@@ -1,247 +1,151 @@
 package exec
 
 import (
-	"bytes"
-	"errors"
-	"os"
-	"os/exec"
-	"path"
-	"runtime"
+	"context"
 	"strings"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/stacks/stackutils"
+	"github.com/portainer/portainer/pkg/libstack"
 
-	"github.com/rs/zerolog/log"
-	"github.com/segmentio/encoding/json"
+	"github.com/pkg/errors"
 )
 
 // SwarmStackManager represents a service for managing stacks.
 type SwarmStackManager struct {
-	binaryPath           string
-	configPath           string
-	signatureService     portainer.DigitalSignatureService
-	fileService          portainer.FileService
-	reverseTunnelService portainer.ReverseTunnelService
-	dataStore            dataservices.DataStore
+	deployer     libstack.Deployer
+	proxyManager *proxy.Manager
+	dataStore    dataservices.DataStore
 }
 
 // NewSwarmStackManager initializes a new SwarmStackManager service.
-// It also updates the configuration of the Docker CLI binary.
-func NewSwarmStackManager(
-	binaryPath, configPath string,
-	signatureService portainer.DigitalSignatureService,
-	fileService portainer.FileService,
-	reverseTunnelService portainer.ReverseTunnelService,
-	datastore dataservices.DataStore,
-) (*SwarmStackManager, error) {
-	manager := &SwarmStackManager{
-		binaryPath:           binaryPath,
-		configPath:           configPath,
-		signatureService:     signatureService,
-		fileService:          fileService,
-		reverseTunnelService: reverseTunnelService,
-		dataStore:            datastore,
+func NewSwarmStackManager(deployer libstack.Deployer, proxyManager *proxy.Manager, dataStore dataservices.DataStore) *SwarmStackManager {
+	return &SwarmStackManager{
+		deployer:     deployer,
+		proxyManager: proxyManager,
+		dataStore:    dataStore,
 	}
-
-	if err := manager.updateDockerCLIConfiguration(manager.configPath); err != nil {
-		return nil, err
-	}
-
-	return manager, nil
 }
 
-// Login executes the docker login command against a list of registries (including DockerHub).
-func (manager *SwarmStackManager) Login(registries []portainer.Registry, endpoint *portainer.Endpoint) error {
-	command, args, err := manager.prepareDockerCommandAndArgs(manager.binaryPath, manager.configPath, endpoint)
+// Deploy creates or updates the services of the stack, the images are resolved against the registries when pullImage is set
+func (manager *SwarmStackManager) Deploy(stack *portainer.Stack, prune bool, pullImage bool, endpoint *portainer.Endpoint, registries []portainer.Registry) error {
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "failed to fetch environment proxy")
 	}
 
-	for _, registry := range registries {
-		if registry.Authentication {
-			username, password, err := getEffectiveRegUsernamePassword(manager.dataStore, &registry)
-			if err != nil {
-				continue
-			}
-
-			registryArgs := append(args, "login", "--username", username, "--password", password, registry.URL)
-			if err := runCommandAndCaptureStdErr(command, registryArgs, nil, ""); err != nil {
-				log.Warn().
-					Err(err).
-					Str("RegistryName", registry.Name).
-					Msg("Failed to login.")
-			}
-		}
-	}
-
-	return nil
-}
-
-// Logout executes the docker logout command.
-func (manager *SwarmStackManager) Logout(endpoint *portainer.Endpoint) error {
-	command, args, err := manager.prepareDockerCommandAndArgs(manager.binaryPath, manager.configPath, endpoint)
-	if err != nil {
-		return err
+	if proxy != nil {
+		defer proxy.Close()
 	}
 
-	args = append(args, "logout")
-
-	return runCommandAndCaptureStdErr(command, args, nil, "")
-}
-
-// Deploy executes the docker stack deploy command.
-func (manager *SwarmStackManager) Deploy(stack *portainer.Stack, prune bool, pullImage bool, endpoint *portainer.Endpoint) error {
-	filePaths := stackutils.GetStackFilePaths(stack, true)
-	command, args, err := manager.prepareDockerCommandAndArgs(manager.binaryPath, manager.configPath, endpoint)
-	if err != nil {
-		return err
+	env := make([]string, 0)
+	for _, envvar := range inheritedEnv(manager.dataStore, stack, endpoint) {
+		env = append(env, envvar.Name+"="+envvar.Value)
 	}
 
-	if prune {
-		args = append(args, "stack", "deploy", "--prune", "--with-registry-auth")
-	} else {
-		args = append(args, "stack", "deploy", "--with-registry-auth")
+	for _, envvar := range stack.Env {
+		env = append(env, envvar.Name+"="+envvar.Value)
 	}
 
+	resolveImage := libstack.ResolveImageAlways
 	if !pullImage {
-		args = append(args, "--resolve-image=never")
-	}
-
-	args = configureFilePaths(args, filePaths)
-	args = append(args, stack.Name)
-
-	env := make([]string, 0)
-	for _, envvar := range stack.Env {
-		env = append(env, envvar.Name+"="+envvar.Value)
+		resolveImage = libstack.ResolveImageNever
 	}
 
-	return runCommandAndCaptureStdErr(command, args, env, stack.ProjectPath)
+	filePaths := stackutils.GetStackFilePaths(stack, true)
+	err = manager.deployer.Deploy(context.TODO(), filePaths, libstack.DeployOptions{
+		Options: libstack.Options{
+			WorkingDir:  stack.ProjectPath,
+			Host:        url,
+			ProjectName: stack.Name,
+			Env:         env,
+			Registries:  portainerRegistriesToAuthConfigs(manager.dataStore, registries),
+			HTTPHeaders: managerOperationHeaders,
+		},
+		RemoveOrphans: prune,
+		ResolveImage:  resolveImage,
+	})
+
+	return errors.Wrap(err, "failed to deploy a stack")
 }
 
-// Remove executes the docker stack rm command.
+// Remove removes the services, networks, secrets and configs of the stack
 func (manager *SwarmStackManager) Remove(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
-	command, args, err := manager.prepareDockerCommandAndArgs(manager.binaryPath, manager.configPath, endpoint)
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
 	if err != nil {
-		return err
-	}
-
-	args = append(args, "stack", "rm", "--detach=false", stack.Name)
-
-	return runCommandAndCaptureStdErr(command, args, nil, "")
-}
-
-func runCommandAndCaptureStdErr(command string, args []string, env []string, workingDir string) error {
-	var stderr bytes.Buffer
-
-	cmd := exec.Command(command, args...)
-	cmd.Stderr = &stderr
-
-	if workingDir != "" {
-		cmd.Dir = workingDir
+		return errors.Wrap(err, "failed to fetch environment proxy")
 	}
 
-	if env != nil {
-		cmd.Env = os.Environ()
-		cmd.Env = append(cmd.Env, env...)
+	if proxy != nil {
+		defer proxy.Close()
 	}
 
-	if err := cmd.Run(); err != nil {
-		return errors.New(stderr.String())
-	}
+	err = manager.deployer.Remove(context.TODO(), stack.Name, nil, libstack.RemoveOptions{
+		Options: libstack.Options{
+			Host:        url,
+			HTTPHeaders: managerOperationHeaders,
+		},
+	})
 
-	return nil
+	return errors.Wrap(err, "failed to remove a stack")
 }
 
-func (manager *SwarmStackManager) prepareDockerCommandAndArgs(binaryPath, configPath string, endpoint *portainer.Endpoint) (string, []string, error) {
-	// Assume Linux as a default
-	command := path.Join(binaryPath, "docker")
-
-	if runtime.GOOS == "windows" {
-		command = path.Join(binaryPath, "docker.exe")
+// Status returns the status of the services of the stack
+func (manager *SwarmStackManager) Status(stack *portainer.Stack, endpoint *portainer.Endpoint) (*portainer.StackHealth, error) {
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch environment proxy")
 	}
 
-	args := make([]string, 0)
-	args = append(args, "--config", configPath)
-
-	endpointURL := endpoint.URL
-	if endpoint.Type == portainer.EdgeAgentOnDockerEnvironment {
-		tunnelAddr, err := manager.reverseTunnelService.TunnelAddr(endpoint)
-		if err != nil {
-			return "", nil, err
-		}
-
-		endpointURL = "tcp://" + tunnelAddr
+	if proxy != nil {
+		defer proxy.Close()
 	}
 
-	args = append(args, "-H", endpointURL)
-
-	if endpoint.TLSConfig.TLS {
-		args = append(args, "--tls")
-
-		if !endpoint.TLSConfig.TLSSkipVerify {
-			args = append(args, "--tlsverify", "--tlscacert", endpoint.TLSConfig.TLSCACertPath)
-		} else {
-			args = append(args, "--tlscacert", "''")
-		}
-
-		if endpoint.TLSConfig.TLSCertPath != "" && endpoint.TLSConfig.TLSKeyPath != "" {
-			args = append(args, "--tlscert", endpoint.TLSConfig.TLSCertPath, "--tlskey", endpoint.TLSConfig.TLSKeyPath)
-		}
+	status, err := manager.deployer.GetStatus(context.TODO(), nil, libstack.Options{
+		Host:        url,
+		ProjectName: stack.Name,
+		HTTPHeaders: managerOperationHeaders,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve the stack status")
 	}
 
-	return command, args, nil
+	return stackHealth(status), nil
 }
 
-func (manager *SwarmStackManager) updateDockerCLIConfiguration(configPath string) error {
-	configFilePath := path.Join(configPath, "config.json")
-
-	config, err := manager.retrieveConfigurationFromDisk(configFilePath)
-	if err != nil {
-		log.Warn().Err(err).Msg("unable to retrieve the Swarm configuration from disk, proceeding without it")
-	}
-
-	signature, err := manager.signatureService.CreateSignature(portainer.PortainerAgentSignatureMessage)
+// WaitForRunning waits for the services of the stack to converge to their running replicas
+func (manager *SwarmStackManager) WaitForRunning(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	url, proxy, err := fetchEndpointProxy(manager.proxyManager, endpoint)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "failed to fetch environment proxy")
 	}
 
-	if config["HttpHeaders"] == nil {
-		config["HttpHeaders"] = make(map[string]any)
-	}
-
-	headersObject := config["HttpHeaders"].(map[string]any)
-	headersObject["X-PortainerAgent-ManagerOperation"] = "1"
-	headersObject["X-PortainerAgent-Signature"] = signature
-	headersObject["X-PortainerAgent-PublicKey"] = manager.signatureService.EncodedPublicKey()
-
-	return manager.fileService.WriteJSONToFile(configFilePath, config)
-}
-
-func (manager *SwarmStackManager) retrieveConfigurationFromDisk(path string) (map[string]any, error) {
-	var config map[string]any
-
-	raw, err := manager.fileService.GetFileContent(path, "")
-	if err != nil {
-		return make(map[string]any), nil
+	if proxy != nil {
+		defer proxy.Close()
 	}
 
-	if err := json.Unmarshal(raw, &config); err != nil {
-		return nil, err
+	result := manager.deployer.WaitForStatusWithOptions(ctx, libstack.StatusRunning, libstack.WaitOptions{
+		Options: libstack.Options{
+			Host:        url,
+			ProjectName: stack.Name,
+			HTTPHeaders: managerOperationHeaders,
+		},
+	})
+	if result.ErrorMsg != "" {
+		return errors.Errorf("the stack %s failed to start: %s", stack.Name, result.ErrorMsg)
 	}
 
-	return config, nil
+	return nil
 }
 
+// NormalizeStackName returns a new stack name with unsupported characters replaced
 func (manager *SwarmStackManager) NormalizeStackName(name string) string {
 	return stackNameNormalizeRegex.ReplaceAllString(strings.ToLower(name), "")
 }
 
-func configureFilePaths(args []string, filePaths []string) []string {
-	for _, path := range filePaths {
-		args = append(args, "--compose-file", path)
-	}
-
-	return args
+// managerOperationHeaders make the agents forward the requests to a manager node of the cluster
+var managerOperationHeaders = map[string]string{
+	portainer.PortainerAgentManagerOperationHeader: "1",
 }
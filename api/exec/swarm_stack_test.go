@@ -1,15 +1,76 @@
 package exec
 
 import (
+	"context"
 	"testing"
 
-	"github.com/stretchr/testify/assert"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/pkg/libstack"
+
+	"github.com/stretchr/testify/require"
 )
 
-func TestConfigFilePaths(t *testing.T) {
-	args := []string{"stack", "deploy", "--with-registry-auth"}
-	filePaths := []string{"dir/file", "dir/file-two", "dir/file-three"}
-	expected := []string{"stack", "deploy", "--with-registry-auth", "--compose-file", "dir/file", "--compose-file", "dir/file-two", "--compose-file", "dir/file-three"}
-	output := configureFilePaths(args, filePaths)
-	assert.ElementsMatch(t, expected, output, "wrong output file paths")
+type recordingSwarmDeployer struct {
+	libstack.Deployer
+	filePaths     []string
+	deployOptions libstack.DeployOptions
+	removedName   string
+}
+
+func (d *recordingSwarmDeployer) Deploy(ctx context.Context, filePaths []string, options libstack.DeployOptions) error {
+	d.filePaths = filePaths
+	d.deployOptions = options
+
+	return nil
+}
+
+func (d *recordingSwarmDeployer) Remove(ctx context.Context, projectName string, filePaths []string, options libstack.RemoveOptions) error {
+	d.removedName = projectName
+
+	return nil
+}
+
+func TestSwarmStackManager(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	endpoint := &portainer.Endpoint{ID: 1, URL: "unix:///var/run/docker.sock"}
+
+	require.NoError(t, store.VariableSet().Create(&portainer.VariableSet{
+		Name:      "team",
+		ScopeType: portainer.TeamVariableSetScope,
+		ScopeID:   1,
+		Variables: []portainer.StackVariable{{Name: "TAG", Value: "0.9"}, {Name: "REGION", Value: "eu"}},
+	}))
+
+	stack := &portainer.Stack{
+		Name:        "app",
+		ProjectPath: "/data/compose/1",
+		EntryPoint:  "docker-compose.yml",
+		Env:         []portainer.Pair{{Name: "TAG", Value: "1.0"}},
+		// the variables of the stack override the ones of its variable sets
+		VariableSetIDs: []portainer.VariableSetID{1},
+	}
+
+	registries := []portainer.Registry{{URL: "registry.example.com", Username: "user", Password: "pass"}}
+
+	deployer := &recordingSwarmDeployer{}
+	manager := NewSwarmStackManager(deployer, nil, store)
+
+	require.NoError(t, manager.Deploy(stack, true, false, endpoint, registries))
+	require.Equal(t, []string{"/data/compose/1/docker-compose.yml"}, deployer.filePaths)
+	require.Equal(t, "app", deployer.deployOptions.ProjectName)
+	require.Equal(t, []string{"TAG=0.9", "REGION=eu", "TAG=1.0"}, deployer.deployOptions.Env)
+	require.True(t, deployer.deployOptions.RemoveOrphans)
+	require.Equal(t, libstack.ResolveImageNever, deployer.deployOptions.ResolveImage)
+	require.Equal(t, "1", deployer.deployOptions.HTTPHeaders[portainer.PortainerAgentManagerOperationHeader])
+	require.Len(t, deployer.deployOptions.Registries, 1)
+	require.Equal(t, "registry.example.com", deployer.deployOptions.Registries[0].ServerAddress)
+
+	require.NoError(t, manager.Deploy(stack, false, true, endpoint, nil))
+	require.False(t, deployer.deployOptions.RemoveOrphans)
+	require.Equal(t, libstack.ResolveImageAlways, deployer.deployOptions.ResolveImage)
+
+	require.NoError(t, manager.Remove(stack, endpoint))
+	require.Equal(t, "app", deployer.removedName)
 }
@@ -71,6 +71,8 @@ const (
 	ChiselPath = "chisel"
 	// ChiselPrivateKeyFilename represents the chisel private key file name
 	ChiselPrivateKeyFilename = "private-key.pem"
+	// ACMEAccountKeyFilename represents the ACME account private key file name
+	ACMEAccountKeyFilename = "acme-account-key.pem"
 )
 
 // ErrUndefinedTLSFileType represents an error returned on undefined TLS file type
@@ -241,12 +243,14 @@ func (service *Service) Copy(fromFilePath string, toFilePath string, deleteIfExi
 // It returns the path to the folder where the file is stored.
 func (service *Service) StoreStackFileFromBytes(stackIdentifier, fileName string, data []byte) (string, error) {
 	stackStorePath := JoinPaths(ComposeStorePath, stackIdentifier)
-	err := service.createDirectoryInStore(stackStorePath)
+	composeFilePath := JoinPaths(stackStorePath, fileName)
+
+	// the file can be stored in a subfolder of the stack folder
+	err := service.createDirectoryInStore(filepath.Dir(composeFilePath))
 	if err != nil {
 		return "", err
 	}
 
-	composeFilePath := JoinPaths(stackStorePath, fileName)
 	r := bytes.NewReader(data)
 
 	err = service.createFileInStore(composeFilePath, r)
@@ -870,6 +874,18 @@ func (service *Service) StoreChiselPrivateKey(privateKey []byte) error {
 	return service.createFileInStore(privateKeyPath, r)
 }
 
+// GetDefaultACMEAccountKeyPath returns the ACME account private key path
+func (service *Service) GetDefaultACMEAccountKeyPath() string {
+	return service.wrapFileStore(JoinPaths(SSLCertPath, ACMEAccountKeyFilename))
+}
+
+// StoreACMEAccountKey stores the specified ACME account private key content on disk.
+func (service *Service) StoreACMEAccountKey(privateKey []byte) error {
+	r := bytes.NewReader(privateKey)
+
+	return service.createFileInStore(JoinPaths(SSLCertPath, ACMEAccountKeyFilename), r)
+}
+
 // StoreSSLCertPair stores a ssl certificate pair
 func (service *Service) StoreSSLCertPair(cert, key []byte) (string, string, error) {
 	certPath, keyPath := defaultCertPathUnderFileStore()
@@ -14,6 +14,7 @@ import (
 	"github.com/portainer/portainer/api/crypto"
 	gittypes "github.com/portainer/portainer/api/git/types"
 
+	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/pkg/errors"
 	"github.com/segmentio/encoding/json"
@@ -24,6 +25,8 @@ const (
 	visualStudioHostSuffix = ".visualstudio.com"
 )
 
+var errAzureHistoryUnsupported = errors.New("the commit history of Azure DevOps repositories cannot be inspected")
+
 func isAzureUrl(s string) bool {
 	return strings.Contains(s, azureDevOpsHost) ||
 		strings.Contains(s, visualStudioHostSuffix)
@@ -78,6 +81,11 @@ func newHttpClientForAzure(insecureSkipVerify bool) *http.Client {
 }
 
 func (a *azureClient) download(ctx context.Context, destination string, opt cloneOption) error {
+	// the archive of the exact commit is downloaded
+	if opt.commitID != "" {
+		opt.referenceName = opt.commitID
+	}
+
 	zipFilepath, err := a.downloadZipFromAzureDevOps(ctx, opt)
 	if err != nil {
 		return errors.Wrap(err, "failed to download a zip file from Azure DevOps")
@@ -152,6 +160,10 @@ func (a *azureClient) latestCommitID(ctx context.Context, opt fetchOption) (stri
 	return rootItem.CommitId, nil
 }
 
+func (a *azureClient) fetchHistory(ctx context.Context, opt cloneOption) (*git.Repository, error) {
+	return nil, errAzureHistoryUnsupported
+}
+
 func (a *azureClient) getRootItem(ctx context.Context, opt fetchOption) (*azureItem, error) {
 	config, err := parseUrl(opt.repositoryUrl)
 	if err != nil {
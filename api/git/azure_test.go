@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	gittypes "github.com/portainer/portainer/api/git/types"
+
+	"github.com/go-git/go-git/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -382,6 +384,10 @@ func (t *testRepoManager) listRefs(_ context.Context, _ baseOption) ([]string, e
 func (t *testRepoManager) listFiles(_ context.Context, _ fetchOption) ([]string, error) {
 	return nil, nil
 }
+
+func (t *testRepoManager) fetchHistory(_ context.Context, _ cloneOption) (*git.Repository, error) {
+	return nil, nil
+}
 func Test_cloneRepository_azure(t *testing.T) {
 	tests := []struct {
 		name   string
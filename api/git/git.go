@@ -4,8 +4,10 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/portainer/portainer/api/filesystem"
 	gittypes "github.com/portainer/portainer/api/git/types"
 
 	"github.com/go-git/go-git/v5"
@@ -18,6 +20,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ErrReferenceMoved is returned when a reference does not point anymore to the commit that was checked
+var ErrReferenceMoved = errors.New("the reference does not point to the expected commit")
+
 type gitClient struct {
 	preserveGitDirectory bool
 }
@@ -29,6 +34,56 @@ func NewGitClient(preserveGitDir bool) *gitClient {
 }
 
 func (c *gitClient) download(ctx context.Context, dst string, opt cloneOption) error {
+	if opt.commitID != "" {
+		return c.downloadCommit(ctx, dst, opt)
+	}
+
+	if _, err := c.clone(ctx, dst, opt); err != nil {
+		return err
+	}
+
+	if !c.preserveGitDirectory {
+		os.RemoveAll(filepath.Join(dst, ".git"))
+	}
+
+	return nil
+}
+
+// downloadCommit clones the reference next to the destination and only moves it there once the commit it points to
+// is checked, so that the destination never holds the files of another commit
+func (c *gitClient) downloadCommit(ctx context.Context, dst string, opt cloneOption) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create the parent directory of the git repository")
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(dst), filepath.Base(dst)+"-clone-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a temporary directory for the git repository")
+	}
+	defer os.RemoveAll(tmp)
+
+	repo, err := c.clone(ctx, tmp, opt)
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return errors.Wrap(err, "failed to retrieve the cloned commit")
+	}
+
+	if !strings.EqualFold(head.Hash().String(), opt.commitID) {
+		return errors.Wrapf(ErrReferenceMoved, "%s points to %s instead of %s", opt.referenceName, head.Hash(), opt.commitID)
+	}
+
+	if !c.preserveGitDirectory {
+		os.RemoveAll(filepath.Join(tmp, ".git"))
+	}
+
+	return filesystem.MoveDirectory(tmp, dst, true)
+}
+
+func (c *gitClient) clone(ctx context.Context, dst string, opt cloneOption) (*git.Repository, error) {
 	gitOptions := git.CloneOptions{
 		URL:             opt.repositoryUrl,
 		Depth:           opt.depth,
@@ -41,20 +96,15 @@ func (c *gitClient) download(ctx context.Context, dst string, opt cloneOption) e
 		gitOptions.ReferenceName = plumbing.ReferenceName(opt.referenceName)
 	}
 
-	_, err := git.PlainCloneContext(ctx, dst, false, &gitOptions)
-
+	repo, err := git.PlainCloneContext(ctx, dst, false, &gitOptions)
 	if err != nil {
 		if err.Error() == "authentication required" {
-			return gittypes.ErrAuthenticationFailure
+			return nil, gittypes.ErrAuthenticationFailure
 		}
-		return errors.Wrap(err, "failed to clone git repository")
+		return nil, errors.Wrap(err, "failed to clone git repository")
 	}
 
-	if !c.preserveGitDirectory {
-		os.RemoveAll(filepath.Join(dst, ".git"))
-	}
-
-	return nil
+	return repo, nil
 }
 
 func (c *gitClient) latestCommitID(ctx context.Context, opt fetchOption) (string, error) {
@@ -188,6 +238,82 @@ func (c *gitClient) listFiles(ctx context.Context, opt fetchOption) ([]string, e
 	return allPaths, nil
 }
 
+// fetchHistory fetches the last commits of the reference without checking out any file
+func (c *gitClient) fetchHistory(ctx context.Context, opt cloneOption) (*git.Repository, error) {
+	cloneOption := &git.CloneOptions{
+		URL:             opt.repositoryUrl,
+		NoCheckout:      true,
+		Depth:           opt.depth,
+		SingleBranch:    true,
+		ReferenceName:   plumbing.ReferenceName(opt.referenceName),
+		Auth:            getAuth(opt.username, opt.password),
+		InsecureSkipTLS: opt.tlsSkipVerify,
+		Tags:            git.NoTags,
+	}
+
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, cloneOption)
+	if err != nil {
+		return nil, checkGitError(err)
+	}
+
+	return repo, nil
+}
+
+// history is the history of a reference fetched once to inspect its commits
+type history struct {
+	repo *git.Repository
+}
+
+// ChangedFiles returns the paths of the files added, modified, renamed or removed between two commits of the history
+func (h *history) ChangedFiles(fromCommitID, toCommitID string) ([]string, error) {
+	fromTree, err := commitTree(h.repo, fromCommitID)
+	if err != nil {
+		return nil, err
+	}
+
+	toTree, err := commitTree(h.repo, toCommitID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.TODO(), fromTree, toTree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to diff the commits")
+	}
+
+	var paths []string
+	for _, change := range changes {
+		// a renamed file is reported under both its previous and its new path
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && !slices.Contains(paths, name) {
+				paths = append(paths, name)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// VerifyCommitSignature checks that a commit of the history is signed by one of the trusted keys, which are either
+// armored GPG public keys or SSH public keys in the authorized_keys format
+func (h *history) VerifyCommitSignature(commitID string, trustedKeys []string) error {
+	commit, err := h.repo.CommitObject(plumbing.NewHash(commitID))
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the commit %s", commitID)
+	}
+
+	return verifyCommitSignature(commit, trustedKeys)
+}
+
+func commitTree(repo *git.Repository, commitID string) (*object.Tree, error) {
+	commit, err := repo.CommitObject(plumbing.NewHash(commitID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the commit %s", commitID)
+	}
+
+	return commit.Tree()
+}
+
 func checkGitError(err error) error {
 	errMsg := err.Error()
 	if errMsg == "repository not found" {
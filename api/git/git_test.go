@@ -13,6 +13,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setup(t *testing.T) string {
@@ -329,3 +330,71 @@ func Test_listFilesPrivateRepository(t *testing.T) {
 		})
 	}
 }
+
+func Test_ChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	first := commitFiles(t, repo, dir, map[string]string{"services/api/docker-compose.yml": "api", "services/web/docker-compose.yml": "web"}, git.CommitOptions{})
+	second := commitFiles(t, repo, dir, map[string]string{"services/web/docker-compose.yml": "web v2"}, git.CommitOptions{})
+	third := commitFiles(t, repo, dir, map[string]string{"README.md": "readme"}, git.CommitOptions{})
+
+	service := Service{git: NewGitClient(false)}
+
+	history, err := service.FetchHistory(dir, "refs/heads/master", "", "", false, 10)
+	require.NoError(t, err)
+
+	files, err := history.ChangedFiles(first, third)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"services/web/docker-compose.yml", "README.md"}, files)
+
+	files, err = history.ChangedFiles(second, third)
+	require.NoError(t, err)
+	require.Equal(t, []string{"README.md"}, files)
+
+	_, err = history.ChangedFiles("0123456789012345678901234567890123456789", third)
+	require.Error(t, err)
+
+	// the commits older than the fetched history are missing
+	history, err = service.FetchHistory(dir, "refs/heads/master", "", "", false, 2)
+	require.NoError(t, err)
+
+	_, err = history.ChangedFiles(second, third)
+	require.NoError(t, err)
+
+	_, err = history.ChangedFiles(first, third)
+	require.Error(t, err)
+}
+
+func Test_CloneCommit(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	first := commitFiles(t, repo, dir, map[string]string{"docker-compose.yml": "v1"}, git.CommitOptions{})
+
+	service := Service{git: NewGitClient(false)}
+
+	dst := filepath.Join(t.TempDir(), "stack")
+	require.NoError(t, service.CloneCommit(dst, dir, "refs/heads/master", first, "", "", false))
+
+	content, err := os.ReadFile(filepath.Join(dst, "docker-compose.yml"))
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(content))
+	require.NoDirExists(t, filepath.Join(dst, ".git"))
+
+	// the reference moved after the commit was checked, the deployed files are left untouched
+	commitFiles(t, repo, dir, map[string]string{"docker-compose.yml": "v2"}, git.CommitOptions{})
+
+	err = service.CloneCommit(dst, dir, "refs/heads/master", first, "", "", false)
+	require.ErrorIs(t, err, ErrReferenceMoved)
+
+	content, err = os.ReadFile(filepath.Join(dst, "docker-compose.yml"))
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(content))
+
+	entries, err := os.ReadDir(filepath.Dir(dst))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
@@ -7,6 +7,9 @@ import (
 	"sync"
 	"time"
 
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/go-git/go-git/v5"
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/singleflight"
@@ -36,6 +39,8 @@ type fetchOption struct {
 type cloneOption struct {
 	fetchOption
 	depth int
+	// commitID is the commit the reference must point to, the clone fails otherwise
+	commitID string
 }
 
 type repoManager interface {
@@ -43,6 +48,7 @@ type repoManager interface {
 	latestCommitID(ctx context.Context, opt fetchOption) (string, error)
 	listRefs(ctx context.Context, opt baseOption) ([]string, error)
 	listFiles(ctx context.Context, opt fetchOption) ([]string, error)
+	fetchHistory(ctx context.Context, opt cloneOption) (*git.Repository, error)
 }
 
 // Service represents a service for managing Git.
@@ -140,6 +146,26 @@ func (service *Service) CloneRepository(destination, repositoryURL, referenceNam
 	return service.cloneRepository(destination, options)
 }
 
+// CloneCommit clones the specified reference of a git repository in the specified destination folder, the clone fails
+// without touching the destination folder when the reference does not point to the specified commit anymore
+func (service *Service) CloneCommit(destination, repositoryURL, referenceName, commitID, username, password string, tlsSkipVerify bool) error {
+	options := cloneOption{
+		fetchOption: fetchOption{
+			baseOption: baseOption{
+				repositoryUrl: repositoryURL,
+				username:      username,
+				password:      password,
+				tlsSkipVerify: tlsSkipVerify,
+			},
+			referenceName: referenceName,
+		},
+		depth:    1,
+		commitID: commitID,
+	}
+
+	return service.cloneRepository(destination, options)
+}
+
 func (service *Service) repoManager(options baseOption) repoManager {
 	repoManager := service.git
 
@@ -265,6 +291,30 @@ func (service *Service) listFiles(repositoryURL, referenceName, username, passwo
 	return files, nil
 }
 
+// FetchHistory fetches the last depth commits of the specified reference, without checking out any file, so that
+// they can be inspected
+func (service *Service) FetchHistory(repositoryURL, referenceName, username, password string, tlsSkipVerify bool, depth int) (portainer.GitHistory, error) {
+	options := cloneOption{
+		fetchOption: fetchOption{
+			baseOption: baseOption{
+				repositoryUrl: repositoryURL,
+				username:      username,
+				password:      password,
+				tlsSkipVerify: tlsSkipVerify,
+			},
+			referenceName: referenceName,
+		},
+		depth: depth,
+	}
+
+	repo, err := service.repoManager(options.baseOption).fetchHistory(context.TODO(), options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &history{repo: repo}, nil
+}
+
 func (service *Service) purgeCache() {
 	if service.repoRefCache != nil {
 		service.repoRefCache.Purge()
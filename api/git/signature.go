@@ -0,0 +1,149 @@
+package git
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshSignatureMagic     = "SSHSIG"
+	sshSignatureNamespace = "git"
+	sshSignaturePEMType   = "SSH SIGNATURE"
+)
+
+var (
+	ErrUnsignedCommit   = errors.New("the commit is not signed")
+	ErrUntrustedCommit  = errors.New("the commit is not signed by a trusted key")
+	errInvalidSignature = errors.New("invalid SSH signature")
+)
+
+// sshSignature is the blob of an SSH signature, see https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is the data signed by an SSH signature, it is preceded by the magic preamble
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func verifyCommitSignature(commit *object.Commit, trustedKeys []string) error {
+	if commit.PGPSignature == "" {
+		return errors.Wrapf(ErrUnsignedCommit, "commit %s", commit.Hash)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(commit.PGPSignature), "-----BEGIN "+sshSignaturePEMType) {
+		payload, err := commitPayload(commit)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range trustedKeys {
+			publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+			if err != nil {
+				// not an SSH key
+				continue
+			}
+
+			if verifySSHSignature(payload, commit.PGPSignature, publicKey) == nil {
+				return nil
+			}
+		}
+
+		return errors.Wrapf(ErrUntrustedCommit, "commit %s", commit.Hash)
+	}
+
+	for _, key := range trustedKeys {
+		if _, err := commit.Verify(key); err == nil {
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrUntrustedCommit, "commit %s", commit.Hash)
+}
+
+// commitPayload returns the content of the commit signed by its author
+func commitPayload(commit *object.Commit) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return nil, errors.Wrap(err, "failed to encode the commit")
+	}
+
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// verifySSHSignature checks that the armored SSH signature of the payload was made by the public key
+func verifySSHSignature(payload []byte, armoredSignature string, publicKey ssh.PublicKey) error {
+	block, _ := pem.Decode([]byte(armoredSignature))
+	if block == nil || block.Type != sshSignaturePEMType {
+		return errInvalidSignature
+	}
+
+	blob, ok := bytes.CutPrefix(block.Bytes, []byte(sshSignatureMagic))
+	if !ok {
+		return errInvalidSignature
+	}
+
+	var signature sshSignature
+	if err := ssh.Unmarshal(blob, &signature); err != nil {
+		return errors.Wrap(err, "failed to decode the SSH signature")
+	}
+
+	if signature.Version != 1 || signature.Namespace != sshSignatureNamespace {
+		return errInvalidSignature
+	}
+
+	if !bytes.Equal(signature.PublicKey, publicKey.Marshal()) {
+		return ErrUntrustedCommit
+	}
+
+	var h hash.Hash
+	switch signature.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return errors.Errorf("unsupported SSH signature hash algorithm %q", signature.HashAlgorithm)
+	}
+
+	h.Write(payload)
+
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(signature.Signature, &sig); err != nil {
+		return errors.Wrap(err, "failed to decode the SSH signature")
+	}
+
+	signedData := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     signature.Namespace,
+		Reserved:      signature.Reserved,
+		HashAlgorithm: signature.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...)
+
+	return publicKey.Verify(signedData, &sig)
+}
@@ -0,0 +1,129 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshSigner signs the commits the way git does with gpg.format set to ssh
+type sshSigner struct {
+	signer ssh.Signer
+}
+
+func (s *sshSigner) Sign(message io.Reader) ([]byte, error) {
+	payload, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha512.Sum512(payload)
+	signedData := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sshSignatureNamespace,
+		HashAlgorithm: "sha512",
+		Hash:          h[:],
+	})...)
+
+	signature, err := s.signer.Sign(rand.Reader, signedData)
+	if err != nil {
+		return nil, err
+	}
+
+	blob := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignature{
+		Version:       1,
+		PublicKey:     s.signer.PublicKey().Marshal(),
+		Namespace:     sshSignatureNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})...)
+
+	return pem.EncodeToMemory(&pem.Block{Type: sshSignaturePEMType, Bytes: blob}), nil
+}
+
+func newSSHKey(t *testing.T) (*sshSigner, string) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+
+	return &sshSigner{signer: signer}, string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+}
+
+func newGPGKey(t *testing.T) (*openpgp.Entity, string) {
+	entity, err := openpgp.NewEntity("portainer", "", "portainer@example.com", nil)
+	require.NoError(t, err)
+
+	var armored bytes.Buffer
+	writer, err := armor.Encode(&armored, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(writer))
+	require.NoError(t, writer.Close())
+
+	return entity, armored.String()
+}
+
+// commitFiles writes the files in the repository and commits them with the given options
+func commitFiles(t *testing.T, repo *git.Repository, dir string, files map[string]string, options git.CommitOptions) string {
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+		_, err := worktree.Add(name)
+		require.NoError(t, err)
+	}
+
+	options.Author = &object.Signature{Name: "portainer", Email: "portainer@example.com", When: time.Now()}
+
+	hash, err := worktree.Commit("update", &options)
+	require.NoError(t, err)
+
+	return hash.String()
+}
+
+func Test_VerifyCommitSignature(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	sshKey, sshPublicKey := newSSHKey(t)
+	_, otherSSHPublicKey := newSSHKey(t)
+	gpgKey, gpgPublicKey := newGPGKey(t)
+	_, otherGPGPublicKey := newGPGKey(t)
+
+	unsigned := commitFiles(t, repo, dir, map[string]string{"docker-compose.yml": "v1"}, git.CommitOptions{})
+	sshSigned := commitFiles(t, repo, dir, map[string]string{"docker-compose.yml": "v2"}, git.CommitOptions{Signer: sshKey})
+	gpgSigned := commitFiles(t, repo, dir, map[string]string{"docker-compose.yml": "v3"}, git.CommitOptions{SignKey: gpgKey})
+
+	service := Service{git: NewGitClient(false)}
+	history, err := service.FetchHistory(dir, "refs/heads/master", "", "", false, 0)
+	require.NoError(t, err)
+
+	verify := func(commitID string, trustedKeys ...string) error {
+		return history.VerifyCommitSignature(commitID, trustedKeys)
+	}
+
+	require.ErrorIs(t, verify(unsigned, sshPublicKey, gpgPublicKey), ErrUnsignedCommit)
+
+	require.NoError(t, verify(sshSigned, gpgPublicKey, sshPublicKey))
+	require.ErrorIs(t, verify(sshSigned, otherSSHPublicKey, gpgPublicKey), ErrUntrustedCommit)
+
+	require.NoError(t, verify(gpgSigned, sshPublicKey, gpgPublicKey))
+	require.ErrorIs(t, verify(gpgSigned, otherGPGPublicKey, sshPublicKey), ErrUntrustedCommit)
+}
@@ -0,0 +1,80 @@
+package git
+
+import (
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	gittypes "github.com/portainer/portainer/api/git/types"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+const tagReferencePrefix = "refs/tags/"
+
+// ErrNoMatchingTag is returned when no tag of the repository matches the semver constraint of a repository config
+var ErrNoMatchingTag = errors.New("no tag of the repository matches the version constraint")
+
+// ValidateTagConstraint checks that the constraint is a valid semver constraint, for example ~1.4 or >=2.0.0 <3
+func ValidateTagConstraint(constraint string) error {
+	_, err := semver.NewConstraint(constraint)
+
+	return err
+}
+
+// LatestMatchingTag returns the reference of the highest semver tag matching the constraint, the tags can be prefixed by v
+func LatestMatchingTag(refs []string, constraint string) (string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid version constraint %q", constraint)
+	}
+
+	var latestRef string
+	var latest *semver.Version
+
+	for _, ref := range refs {
+		tag, ok := strings.CutPrefix(ref, tagReferencePrefix)
+		if !ok {
+			continue
+		}
+
+		version, err := semver.NewVersion(tag)
+		if err != nil || !c.Check(version) {
+			continue
+		}
+
+		if latest == nil || version.GreaterThan(latest) {
+			latest = version
+			latestRef = ref
+		}
+	}
+
+	if latest == nil {
+		return "", errors.Wrapf(ErrNoMatchingTag, "constraint %q", constraint)
+	}
+
+	return latestRef, nil
+}
+
+// ResolveTagConstraint points the reference of a repository config tracking a semver constraint to the highest
+// matching tag of the repository. It returns true when the reference changed.
+func ResolveTagConstraint(gitService portainer.GitService, config *gittypes.RepoConfig, username, password string) (bool, error) {
+	if config == nil || config.TagConstraint == "" {
+		return false, nil
+	}
+
+	refs, err := gitService.ListRefs(config.URL, username, password, true, config.TLSSkipVerify)
+	if err != nil {
+		return false, errors.WithMessage(err, "failed to list the tags of the repository")
+	}
+
+	ref, err := LatestMatchingTag(refs, config.TagConstraint)
+	if err != nil {
+		return false, err
+	}
+
+	changed := ref != config.ReferenceName
+	config.ReferenceName = ref
+
+	return changed, nil
+}
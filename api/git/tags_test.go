@@ -0,0 +1,89 @@
+package git
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	gittypes "github.com/portainer/portainer/api/git/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testRefs = []string{
+	"HEAD",
+	"refs/heads/main",
+	"refs/heads/release/2.0",
+	"refs/tags/v1.3.9",
+	"refs/tags/v1.4.0",
+	"refs/tags/v1.4.2",
+	"refs/tags/1.4.10",
+	"refs/tags/v1.5.0",
+	"refs/tags/v2.0.0-rc.1",
+	"refs/tags/v2.0.0",
+	"refs/tags/v2.3.1",
+	"refs/tags/v3.0.0",
+	"refs/tags/latest",
+}
+
+func Test_LatestMatchingTag(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       string
+		wantErr    bool
+	}{
+		{constraint: "~1.4", want: "refs/tags/1.4.10"},
+		{constraint: ">=2.0.0 <3", want: "refs/tags/v2.3.1"},
+		{constraint: "^1", want: "refs/tags/v1.5.0"},
+		{constraint: "<2.0.0", want: "refs/tags/v1.5.0"},
+		{constraint: "*", want: "refs/tags/v3.0.0"},
+		{constraint: "~4", wantErr: true},
+		{constraint: "not a constraint", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			ref, err := LatestMatchingTag(testRefs, tt.constraint)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ref)
+		})
+	}
+}
+
+type refsGitService struct {
+	portainer.GitService
+	refs []string
+}
+
+func (g *refsGitService) ListRefs(repositoryURL, username, password string, hardRefresh bool, tlsSkipVerify bool) ([]string, error) {
+	return g.refs, nil
+}
+
+func Test_ResolveTagConstraint(t *testing.T) {
+	gitService := &refsGitService{refs: testRefs}
+
+	config := &gittypes.RepoConfig{URL: "https://github.com/portainer/app", ReferenceName: "refs/heads/main"}
+	changed, err := ResolveTagConstraint(gitService, config, "", "")
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, "refs/heads/main", config.ReferenceName)
+
+	config.TagConstraint = "~1.4"
+	changed, err = ResolveTagConstraint(gitService, config, "", "")
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, "refs/tags/1.4.10", config.ReferenceName)
+
+	changed, err = ResolveTagConstraint(gitService, config, "", "")
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	config.TagConstraint = "~4"
+	_, err = ResolveTagConstraint(gitService, config, "", "")
+	require.ErrorIs(t, err, ErrNoMatchingTag)
+	require.Equal(t, "refs/tags/1.4.10", config.ReferenceName)
+}
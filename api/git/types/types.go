@@ -11,8 +11,10 @@ var (
 type RepoConfig struct {
 	// The repo url
 	URL string `example:"https://github.com/portainer/portainer.git"`
-	// The reference name
+	// The reference name, when a tag constraint is set it is the reference of the deployed tag
 	ReferenceName string `example:"refs/heads/branch_name"`
+	// Semver constraint of the tags to track, the reference follows the highest matching tag when it is set
+	TagConstraint string `json:",omitempty" example:"~1.4"`
 	// Path to where the config file is in this url/refName
 	ConfigFilePath string `example:"docker-compose.yml"`
 	// Git credentials
@@ -25,7 +27,7 @@ type RepoConfig struct {
 
 type GitAuthentication struct {
 	Username string
-	Password string
+	Password string `secret:"true"`
 	// Git credentials identifier when the value is not 0
 	// When the value is 0, Username and Password are set without using saved credential
 	// This is introduced since 2.15.0
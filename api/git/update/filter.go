@@ -0,0 +1,59 @@
+package update
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchPathFilters returns true when one of the paths matches one of the filters. The filters are globs relative to the
+// root of the repository where ** matches any number of folders, for example services/api/** or **/*.env
+func MatchPathFilters(paths, filters []string) bool {
+	for _, name := range paths {
+		for _, filter := range filters {
+			if matchPathFilter(filter, name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchPathFilter(filter, name string) bool {
+	return matchSegments(splitPath(filter), splitPath(name))
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.Trim(path.Clean("/"+p), "/"), "/")
+}
+
+func matchSegments(filter, name []string) bool {
+	for len(filter) > 0 {
+		if filter[0] == "**" {
+			filter = filter[1:]
+			if len(filter) == 0 {
+				return true
+			}
+
+			for i := range name {
+				if matchSegments(filter, name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(filter[0], name[0]); err != nil || !ok {
+			return false
+		}
+
+		filter, name = filter[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
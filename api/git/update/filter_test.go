@@ -0,0 +1,33 @@
+package update
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MatchPathFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		filters []string
+		want    bool
+	}{
+		{name: "exact file", paths: []string{"services/api/docker-compose.yml"}, filters: []string{"services/api/docker-compose.yml"}, want: true},
+		{name: "folder content", paths: []string{"services/api/src/main.go"}, filters: []string{"services/api/**"}, want: true},
+		{name: "other folder", paths: []string{"services/web/src/main.go"}, filters: []string{"services/api/**"}, want: false},
+		{name: "any depth", paths: []string{"services/api/.env"}, filters: []string{"**/*.env"}, want: true},
+		{name: "root file with any depth", paths: []string{"prod.env"}, filters: []string{"**/*.env"}, want: true},
+		{name: "single segment glob", paths: []string{"services/api/docker-compose.yml"}, filters: []string{"services/*/docker-compose.yml"}, want: true},
+		{name: "single segment glob does not cross folders", paths: []string{"services/api/v2/docker-compose.yml"}, filters: []string{"services/*/docker-compose.yml"}, want: false},
+		{name: "leading slash", paths: []string{"docker-compose.yml"}, filters: []string{"/docker-compose.yml"}, want: true},
+		{name: "one of several", paths: []string{"README.md", "stack/docker-compose.yml"}, filters: []string{"docs/**", "stack/**"}, want: true},
+		{name: "no filter", paths: []string{"README.md"}, filters: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchPathFilters(tt.paths, tt.filters))
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package update
 
 import (
+	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -12,8 +14,24 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// UpdateGitObject updates a git object based on its config
-func UpdateGitObject(gitService portainer.GitService, objId string, gitConfig *gittypes.RepoConfig, forceUpdate, enableVersionFolder bool, projectPath string) (bool, string, error) {
+// historyDepth bounds the number of commits fetched to filter the changes, the path filters are ignored when the
+// deployed commit is older
+const historyDepth = 100
+
+// checkedCommit is the result of the last trigger check of an object, so that the history is only fetched again
+// once its reference moved or its auto update settings changed
+type checkedCommit struct {
+	fromHash string
+	toHash   string
+	settings string
+	err      error
+}
+
+var checkedCommits sync.Map
+
+// UpdateGitObject updates a git object based on its config, when auto update settings are given the object is only
+// updated when the new commits match their path filters and signature requirement
+func UpdateGitObject(gitService portainer.GitService, objId string, gitConfig *gittypes.RepoConfig, autoUpdate *portainer.AutoUpdateSettings, forceUpdate, enableVersionFolder bool, projectPath string) (bool, string, error) {
 	if gitConfig == nil {
 		return false, "", nil
 	}
@@ -29,12 +47,19 @@ func UpdateGitObject(gitService portainer.GitService, objId string, gitConfig *g
 		return false, "", errors.WithMessagef(err, "failed to get credentials for %v", objId)
 	}
 
+	// a config tracking a tag constraint is pointed to the latest matching tag, a new tag is an update even when it
+	// points to the deployed commit so that the deployed tag is recorded
+	refChanged, err := git.ResolveTagConstraint(gitService, gitConfig, username, password)
+	if err != nil {
+		return false, "", errors.WithMessagef(err, "failed to resolve the tag constraint of %v", objId)
+	}
+
 	newHash, err := gitService.LatestCommitID(gitConfig.URL, gitConfig.ReferenceName, username, password, gitConfig.TLSSkipVerify)
 	if err != nil {
 		return false, "", errors.WithMessagef(err, "failed to fetch latest commit id of %v", objId)
 	}
 
-	hashChanged := !strings.EqualFold(newHash, gitConfig.ConfigHash)
+	hashChanged := refChanged || !strings.EqualFold(newHash, gitConfig.ConfigHash)
 
 	if !hashChanged && !forceUpdate {
 		log.Debug().
@@ -47,6 +72,13 @@ func UpdateGitObject(gitService portainer.GitService, objId string, gitConfig *g
 		return false, newHash, nil
 	}
 
+	if !forceUpdate && autoUpdate != nil {
+		triggered, err := checkTriggers(gitService, objId, gitConfig, autoUpdate, newHash, username, password)
+		if err != nil || !triggered {
+			return false, newHash, err
+		}
+	}
+
 	toDir := projectPath
 	if enableVersionFolder {
 		toDir = filesystem.JoinPaths(projectPath, newHash)
@@ -55,6 +87,7 @@ func UpdateGitObject(gitService portainer.GitService, objId string, gitConfig *g
 	cloneParams := &cloneRepositoryParameters{
 		url:           gitConfig.URL,
 		ref:           gitConfig.ReferenceName,
+		commitID:      newHash,
 		toDir:         toDir,
 		tlsSkipVerify: gitConfig.TLSSkipVerify,
 	}
@@ -80,10 +113,12 @@ func UpdateGitObject(gitService portainer.GitService, objId string, gitConfig *g
 }
 
 type cloneRepositoryParameters struct {
-	url   string
-	ref   string
-	toDir string
-	auth  *gitAuth
+	url string
+	ref string
+	// commitID is the commit the reference must point to, so that the checked commit is the one deployed
+	commitID string
+	toDir    string
+	auth     *gitAuth
 	// tlsSkipVerify skips SSL verification when cloning the Git repository
 	tlsSkipVerify bool `example:"false"`
 }
@@ -95,8 +130,100 @@ type gitAuth struct {
 
 func cloneGitRepository(gitService portainer.GitService, cloneParams *cloneRepositoryParameters) error {
 	if cloneParams.auth != nil {
-		return gitService.CloneRepository(cloneParams.toDir, cloneParams.url, cloneParams.ref, cloneParams.auth.username, cloneParams.auth.password, cloneParams.tlsSkipVerify)
+		return gitService.CloneCommit(cloneParams.toDir, cloneParams.url, cloneParams.ref, cloneParams.commitID, cloneParams.auth.username, cloneParams.auth.password, cloneParams.tlsSkipVerify)
+	}
+
+	return gitService.CloneCommit(cloneParams.toDir, cloneParams.url, cloneParams.ref, cloneParams.commitID, "", "", cloneParams.tlsSkipVerify)
+}
+
+// checkTriggers returns true when the new commit should trigger an update according to the auto update settings, a
+// commit that was already checked is not fetched again
+func checkTriggers(gitService portainer.GitService, objId string, gitConfig *gittypes.RepoConfig, autoUpdate *portainer.AutoUpdateSettings, newHash, username, password string) (bool, error) {
+	filterChanges := len(autoUpdate.PathFilters) > 0 && gitConfig.ConfigHash != ""
+	if !autoUpdate.RequireSignedCommits && !filterChanges {
+		return true, nil
+	}
+
+	checked := checkedCommit{
+		fromHash: gitConfig.ConfigHash,
+		toHash:   newHash,
+		settings: fmt.Sprint(autoUpdate.PathFilters, autoUpdate.RequireSignedCommits, autoUpdate.TrustedSigningKeys),
+	}
+	if previous, ok := checkedCommits.Load(objId); ok {
+		if previous := previous.(checkedCommit); previous.fromHash == checked.fromHash && previous.toHash == checked.toHash && previous.settings == checked.settings {
+			return false, previous.err
+		}
+	}
+
+	triggered, err := fetchTriggers(gitService, objId, gitConfig, autoUpdate, filterChanges, newHash, username, password)
+	if triggered {
+		checkedCommits.Delete(objId)
+	} else {
+		checked.err = err
+		checkedCommits.Store(objId, checked)
+	}
+
+	return triggered, err
+}
+
+// fetchTriggers fetches the history of the reference once to check the signature of the new commit and the files
+// changed since the deployed one
+func fetchTriggers(gitService portainer.GitService, objId string, gitConfig *gittypes.RepoConfig, autoUpdate *portainer.AutoUpdateSettings, filterChanges bool, newHash, username, password string) (bool, error) {
+	depth := 1
+	if filterChanges {
+		depth = historyDepth
+	}
+
+	history, err := gitService.FetchHistory(gitConfig.URL, gitConfig.ReferenceName, username, password, gitConfig.TLSSkipVerify, depth)
+	if err != nil {
+		if autoUpdate.RequireSignedCommits {
+			return false, errors.WithMessagef(err, "failed to fetch the history of %v", objId)
+		}
+
+		log.Warn().
+			Err(err).
+			Str("hash", newHash).
+			Str("url", gitConfig.URL).
+			Str("object", objId).
+			Msg("unable to fetch the history of the repository, ignoring the path filters")
+
+		return true, nil
+	}
+
+	if autoUpdate.RequireSignedCommits {
+		if err := history.VerifyCommitSignature(newHash, autoUpdate.TrustedSigningKeys); err != nil {
+			return false, errors.WithMessagef(err, "refusing to update %v", objId)
+		}
+	}
+
+	if !filterChanges {
+		return true, nil
+	}
+
+	changedFiles, err := history.ChangedFiles(gitConfig.ConfigHash, newHash)
+	if err != nil {
+		// the previous commit can be missing when the history was rewritten or is deeper than the fetched history, the
+		// changes cannot be filtered then
+		log.Warn().
+			Err(err).
+			Str("hash", newHash).
+			Str("url", gitConfig.URL).
+			Str("object", objId).
+			Msg("unable to retrieve the files changed since the last update, ignoring the path filters")
+
+		return true, nil
+	}
+
+	if !MatchPathFilters(changedFiles, autoUpdate.PathFilters) {
+		log.Debug().
+			Str("hash", newHash).
+			Str("url", gitConfig.URL).
+			Str("ref", gitConfig.ReferenceName).
+			Str("object", objId).
+			Msg("no changed file matches the path filters")
+
+		return false, nil
 	}
 
-	return gitService.CloneRepository(cloneParams.toDir, cloneParams.url, cloneParams.ref, "", "", cloneParams.tlsSkipVerify)
+	return true, nil
 }
@@ -0,0 +1,196 @@
+package update
+
+import (
+	"errors"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/require"
+)
+
+type triggerGitService struct {
+	portainer.GitService
+	changedFiles []string
+	signatureErr error
+	cloned       string
+	fetched      int
+	refs         map[string]string
+}
+
+func (g *triggerGitService) ListRefs(repositoryURL, username, password string, hardRefresh bool, tlsSkipVerify bool) ([]string, error) {
+	refs := make([]string, 0, len(g.refs))
+	for ref := range g.refs {
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+func (g *triggerGitService) LatestCommitID(repositoryURL, referenceName, username, password string, tlsSkipVerify bool) (string, error) {
+	if hash, ok := g.refs[referenceName]; ok {
+		return hash, nil
+	}
+
+	return g.GitService.LatestCommitID(repositoryURL, referenceName, username, password, tlsSkipVerify)
+}
+
+func (g *triggerGitService) CloneCommit(destination, repositoryURL, referenceName, commitID, username, password string, tlsSkipVerify bool) error {
+	g.cloned = commitID
+
+	return nil
+}
+
+func (g *triggerGitService) FetchHistory(repositoryURL, referenceName, username, password string, tlsSkipVerify bool, depth int) (portainer.GitHistory, error) {
+	g.fetched++
+
+	return g, nil
+}
+
+func (g *triggerGitService) ChangedFiles(fromCommitID, toCommitID string) ([]string, error) {
+	return g.changedFiles, nil
+}
+
+func (g *triggerGitService) VerifyCommitSignature(commitID string, trustedKeys []string) error {
+	return g.signatureErr
+}
+
+func Test_UpdateGitObject_Triggers(t *testing.T) {
+	errUntrusted := errors.New("the commit is not signed by a trusted key")
+
+	tests := []struct {
+		name         string
+		autoUpdate   *portainer.AutoUpdateSettings
+		changedFiles []string
+		signatureErr error
+		wantUpdated  bool
+		wantErr      error
+	}{
+		{
+			name:        "no trigger",
+			wantUpdated: true,
+		},
+		{
+			name:         "matching path filter",
+			autoUpdate:   &portainer.AutoUpdateSettings{PathFilters: []string{"services/api/**"}},
+			changedFiles: []string{"README.md", "services/api/docker-compose.yml"},
+			wantUpdated:  true,
+		},
+		{
+			name:         "unrelated changes",
+			autoUpdate:   &portainer.AutoUpdateSettings{PathFilters: []string{"services/api/**"}},
+			changedFiles: []string{"services/web/docker-compose.yml"},
+			wantUpdated:  false,
+		},
+		{
+			name:        "trusted signature",
+			autoUpdate:  &portainer.AutoUpdateSettings{RequireSignedCommits: true, TrustedSigningKeys: []string{"key"}},
+			wantUpdated: true,
+		},
+		{
+			name:         "untrusted signature",
+			autoUpdate:   &portainer.AutoUpdateSettings{RequireSignedCommits: true, TrustedSigningKeys: []string{"key"}},
+			signatureErr: errUntrusted,
+			wantErr:      errUntrusted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitService := &triggerGitService{
+				GitService:   testhelpers.NewGitService(nil, "newHash"),
+				changedFiles: tt.changedFiles,
+				signatureErr: tt.signatureErr,
+			}
+
+			gitConfig := &gittypes.RepoConfig{URL: "url", ReferenceName: "ref", ConfigHash: "oldHash"}
+
+			updated, newHash, err := UpdateGitObject(gitService, t.Name(), gitConfig, tt.autoUpdate, false, false, t.TempDir())
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantUpdated, updated)
+			require.Equal(t, tt.wantUpdated, gitService.cloned == "newHash")
+			require.Equal(t, "newHash", newHash)
+		})
+	}
+}
+
+func Test_UpdateGitObject_CheckedCommits(t *testing.T) {
+	errUntrusted := errors.New("the commit is not signed by a trusted key")
+
+	gitService := &triggerGitService{
+		GitService:   testhelpers.NewGitService(nil, "newHash"),
+		changedFiles: []string{"services/web/docker-compose.yml"},
+		signatureErr: errUntrusted,
+	}
+
+	gitConfig := &gittypes.RepoConfig{URL: "url", ReferenceName: "ref", ConfigHash: "oldHash"}
+	autoUpdate := &portainer.AutoUpdateSettings{PathFilters: []string{"services/api/**"}}
+
+	// the history is fetched once as long as the reference does not move
+	for range 2 {
+		updated, _, err := UpdateGitObject(gitService, t.Name(), gitConfig, autoUpdate, false, false, t.TempDir())
+		require.NoError(t, err)
+		require.False(t, updated)
+	}
+	require.Equal(t, 1, gitService.fetched)
+
+	// the signature check is kept along with the trigger check
+	autoUpdate.RequireSignedCommits = true
+
+	for range 2 {
+		_, _, err := UpdateGitObject(gitService, t.Name(), gitConfig, autoUpdate, false, false, t.TempDir())
+		require.ErrorIs(t, err, errUntrusted)
+	}
+	require.Equal(t, 2, gitService.fetched)
+
+	gitService.signatureErr = nil
+	gitService.changedFiles = []string{"services/api/docker-compose.yml"}
+	gitService.GitService = testhelpers.NewGitService(nil, "nextHash")
+
+	updated, newHash, err := UpdateGitObject(gitService, t.Name(), gitConfig, autoUpdate, false, false, t.TempDir())
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, "nextHash", newHash)
+	require.Equal(t, "nextHash", gitService.cloned)
+	require.Equal(t, 3, gitService.fetched)
+}
+
+func Test_UpdateGitObject_TagConstraint(t *testing.T) {
+	gitService := &triggerGitService{
+		GitService: testhelpers.NewGitService(nil, "newHash"),
+		refs: map[string]string{
+			"refs/heads/main":  "mainHash",
+			"refs/tags/v1.4.0": "hash140",
+			"refs/tags/v1.4.1": "hash141",
+			"refs/tags/v1.5.0": "hash150",
+		},
+	}
+
+	gitConfig := &gittypes.RepoConfig{URL: "url", ReferenceName: "refs/tags/v1.4.0", ConfigHash: "hash140", TagConstraint: "~1.4"}
+
+	updated, newHash, err := UpdateGitObject(gitService, "stack:1", gitConfig, nil, false, false, t.TempDir())
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, "hash141", newHash)
+	require.Equal(t, "refs/tags/v1.4.1", gitConfig.ReferenceName)
+
+	require.Equal(t, "hash141", gitService.cloned)
+
+	gitService.cloned = ""
+	gitConfig.ConfigHash = newHash
+
+	updated, _, err = UpdateGitObject(gitService, "stack:1", gitConfig, nil, false, false, t.TempDir())
+	require.NoError(t, err)
+	require.False(t, updated)
+	require.Empty(t, gitService.cloned)
+
+	// a new tag pointing to the deployed commit is recorded
+	gitService.refs["refs/tags/v1.4.2"] = "hash141"
+
+	updated, _, err = UpdateGitObject(gitService, "stack:1", gitConfig, nil, false, false, t.TempDir())
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, "refs/tags/v1.4.2", gitConfig.ReferenceName)
+}
@@ -1,14 +1,30 @@
 package update
 
 import (
+	"path"
+	"strings"
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/git/webhooks"
 	httperrors "github.com/portainer/portainer/api/http/errors"
 	"github.com/portainer/portainer/pkg/validate"
+
+	"golang.org/x/crypto/ssh"
 )
 
+// ValidateAutoUpdateSettings validates the auto update settings of a new object
 func ValidateAutoUpdateSettings(autoUpdate *portainer.AutoUpdateSettings) error {
+	if err := ValidateAutoUpdateSettingsUpdate(autoUpdate); err != nil {
+		return err
+	}
+
+	return ValidateWebhookSecret(autoUpdate)
+}
+
+// ValidateAutoUpdateSettingsUpdate validates the auto update settings sent to update an object, their webhook secret
+// can be left empty to keep the saved one so it is validated with ValidateWebhookSecret once it is kept
+func ValidateAutoUpdateSettingsUpdate(autoUpdate *portainer.AutoUpdateSettings) error {
 	if autoUpdate == nil {
 		return nil
 	}
@@ -27,5 +43,51 @@ func ValidateAutoUpdateSettings(autoUpdate *portainer.AutoUpdateSettings) error
 		}
 	}
 
+	for _, filter := range autoUpdate.PathFilters {
+		if _, err := path.Match(filter, ""); err != nil || strings.TrimSpace(filter) == "" {
+			return httperrors.NewInvalidPayloadError("invalid PathFilters format")
+		}
+	}
+
+	if autoUpdate.RequireSignedCommits && len(autoUpdate.TrustedSigningKeys) == 0 {
+		return httperrors.NewInvalidPayloadError("TrustedSigningKeys must be provided to require signed commits")
+	}
+
+	for _, key := range autoUpdate.TrustedSigningKeys {
+		if !isSigningKey(key) {
+			return httperrors.NewInvalidPayloadError("TrustedSigningKeys must be armored GPG public keys or SSH public keys")
+		}
+	}
+
+	if autoUpdate.WebhookProvider != "" {
+		if !webhooks.IsValidProvider(autoUpdate.WebhookProvider) {
+			return httperrors.NewInvalidPayloadError("WebhookProvider must be one of github, gitlab, gitea or bitbucket")
+		}
+
+		if autoUpdate.Webhook == "" {
+			return httperrors.NewInvalidPayloadError("Webhook must be provided with a WebhookProvider")
+		}
+	}
+
+	return nil
+}
+
+// ValidateWebhookSecret checks that the push events sent by the webhook provider can be verified, they are all
+// rejected without a secret
+func ValidateWebhookSecret(autoUpdate *portainer.AutoUpdateSettings) error {
+	if autoUpdate != nil && autoUpdate.WebhookProvider != "" && autoUpdate.WebhookSecret == "" {
+		return httperrors.NewInvalidPayloadError("WebhookSecret must be provided with a WebhookProvider")
+	}
+
 	return nil
 }
+
+func isSigningKey(key string) bool {
+	if strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+		return true
+	}
+
+	_, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+
+	return err == nil
+}
@@ -23,6 +23,55 @@ func Test_ValidateAutoUpdate(t *testing.T) {
 			value:   &portainer.AutoUpdateSettings{Interval: "1dd2hh3mm"},
 			wantErr: true,
 		},
+		{
+			name:    "invalid path filter",
+			value:   &portainer.AutoUpdateSettings{Interval: "5m", PathFilters: []string{"services/[api/**"}},
+			wantErr: true,
+		},
+		{
+			name:    "signed commits without trusted keys",
+			value:   &portainer.AutoUpdateSettings{Interval: "5m", RequireSignedCommits: true},
+			wantErr: true,
+		},
+		{
+			name:    "invalid trusted key",
+			value:   &portainer.AutoUpdateSettings{Interval: "5m", RequireSignedCommits: true, TrustedSigningKeys: []string{"not a key"}},
+			wantErr: true,
+		},
+		{
+			name: "valid commit triggers",
+			value: &portainer.AutoUpdateSettings{
+				Interval:             "5m",
+				PathFilters:          []string{"services/api/**"},
+				RequireSignedCommits: true,
+				TrustedSigningKeys:   []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl portainer@example.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown webhook provider",
+			value:   &portainer.AutoUpdateSettings{Webhook: "8dce8c2f-9ca1-482b-ad20-271e86536ada", WebhookProvider: "svn", WebhookSecret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:    "webhook provider without webhook",
+			value:   &portainer.AutoUpdateSettings{Interval: "5m", WebhookProvider: portainer.GitWebhookProviderGitHub, WebhookSecret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:    "webhook provider without secret",
+			value:   &portainer.AutoUpdateSettings{Webhook: "8dce8c2f-9ca1-482b-ad20-271e86536ada", WebhookProvider: portainer.GitWebhookProviderGitHub},
+			wantErr: true,
+		},
+		{
+			name: "valid webhook provider",
+			value: &portainer.AutoUpdateSettings{
+				Webhook:         "8dce8c2f-9ca1-482b-ad20-271e86536ada",
+				WebhookProvider: portainer.GitWebhookProviderGitLab,
+				WebhookSecret:   "secret",
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid auto update",
 			value: &portainer.AutoUpdateSettings{
@@ -40,3 +89,13 @@ func Test_ValidateAutoUpdate(t *testing.T) {
 		})
 	}
 }
+
+func Test_ValidateAutoUpdateSettingsUpdate(t *testing.T) {
+	// the saved secret is kept when it is left empty
+	autoUpdate := &portainer.AutoUpdateSettings{Webhook: "8dce8c2f-9ca1-482b-ad20-271e86536ada", WebhookProvider: portainer.GitWebhookProviderGitHub}
+	assert.NoError(t, ValidateAutoUpdateSettingsUpdate(autoUpdate))
+	assert.Error(t, ValidateWebhookSecret(autoUpdate))
+
+	autoUpdate.WebhookSecret = "secret"
+	assert.NoError(t, ValidateWebhookSecret(autoUpdate))
+}
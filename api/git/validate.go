@@ -11,6 +11,12 @@ func ValidateRepoConfig(repoConfig *gittypes.RepoConfig) error {
 		return httperrors.NewInvalidPayloadError("Invalid repository URL. Must correspond to a valid URL format")
 	}
 
+	if repoConfig.TagConstraint != "" {
+		if err := ValidateTagConstraint(repoConfig.TagConstraint); err != nil {
+			return httperrors.NewInvalidPayloadError("Invalid repository tag constraint. Must correspond to a valid semver constraint")
+		}
+	}
+
 	return ValidateRepoAuthentication(repoConfig.Authentication)
 }
 
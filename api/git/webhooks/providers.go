@@ -0,0 +1,182 @@
+package webhooks
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/pkg/errors"
+)
+
+type parser struct {
+	verify func(header http.Header, body []byte, secret string) bool
+	parse  func(header http.Header, body []byte) (*PushEvent, error)
+}
+
+var parsers = map[portainer.GitWebhookProvider]parser{
+	portainer.GitWebhookProviderGitHub: {
+		// https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+		verify: func(header http.Header, body []byte, secret string) bool {
+			signature, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+
+			return ok && verifyHMAC(signature, body, secret)
+		},
+		parse: func(header http.Header, body []byte) (*PushEvent, error) {
+			if header.Get("X-GitHub-Event") != "push" {
+				return nil, nil
+			}
+
+			return parseCommitPush(header.Get("X-GitHub-Delivery"), body)
+		},
+	},
+	portainer.GitWebhookProviderGitLab: {
+		// https://docs.gitlab.com/ee/user/project/integrations/webhooks.html#validate-payloads-by-using-a-secret-token
+		verify: func(header http.Header, body []byte, secret string) bool {
+			return verifyToken(header.Get("X-Gitlab-Token"), secret)
+		},
+		parse: func(header http.Header, body []byte) (*PushEvent, error) {
+			if event := header.Get("X-Gitlab-Event"); event != "Push Hook" && event != "Tag Push Hook" {
+				return nil, nil
+			}
+
+			return parseCommitPush(header.Get("X-Gitlab-Event-UUID"), body)
+		},
+	},
+	portainer.GitWebhookProviderGitea: {
+		// https://docs.gitea.com/usage/webhooks
+		verify: func(header http.Header, body []byte, secret string) bool {
+			return verifyHMAC(header.Get("X-Gitea-Signature"), body, secret)
+		},
+		parse: func(header http.Header, body []byte) (*PushEvent, error) {
+			if header.Get("X-Gitea-Event") != "push" {
+				return nil, nil
+			}
+
+			return parseCommitPush(header.Get("X-Gitea-Delivery"), body)
+		},
+	},
+	portainer.GitWebhookProviderBitbucket: {
+		// https://support.atlassian.com/bitbucket-cloud/docs/manage-webhooks/#Secure-webhooks
+		verify: func(header http.Header, body []byte, secret string) bool {
+			signature, ok := strings.CutPrefix(header.Get("X-Hub-Signature"), "sha256=")
+
+			return ok && verifyHMAC(signature, body, secret)
+		},
+		parse: func(header http.Header, body []byte) (*PushEvent, error) {
+			// Bitbucket Cloud identifies its deliveries with X-Request-UUID and Bitbucket Data Center with X-Request-Id
+			deliveryID := cmp.Or(header.Get("X-Request-UUID"), header.Get("X-Request-Id"))
+
+			switch header.Get("X-Event-Key") {
+			case "repo:push":
+				return parseBitbucketCloudPush(deliveryID, body)
+			case "repo:refs_changed":
+				return parseBitbucketServerPush(deliveryID, body)
+			}
+
+			return nil, nil
+		},
+	},
+}
+
+// commitPush is the push event shared by GitHub, GitLab and Gitea
+type commitPush struct {
+	Ref         string `json:"ref"`
+	After       string `json:"after"`
+	CheckoutSHA string `json:"checkout_sha"`
+	Repository  struct {
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Project struct {
+		DefaultBranch string `json:"default_branch"`
+	} `json:"project"`
+}
+
+func parseCommitPush(deliveryID string, body []byte) (*PushEvent, error) {
+	var push commitPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the push event")
+	}
+
+	return &PushEvent{
+		DeliveryID: deliveryID,
+		// the checkout SHA of GitLab is the commit of an annotated tag, not the tag object
+		Refs:          []PushedRef{{Name: push.Ref, Commit: cmp.Or(push.CheckoutSHA, push.After)}},
+		DefaultBranch: cmp.Or(push.Repository.DefaultBranch, push.Project.DefaultBranch),
+	}, nil
+}
+
+type bitbucketCloudPush struct {
+	Push struct {
+		Changes []struct {
+			New *struct {
+				Type   string `json:"type"`
+				Name   string `json:"name"`
+				Target struct {
+					Hash string `json:"hash"`
+				} `json:"target"`
+			} `json:"new"`
+		} `json:"changes"`
+	} `json:"push"`
+	Repository struct {
+		MainBranch struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+	} `json:"repository"`
+}
+
+func parseBitbucketCloudPush(deliveryID string, body []byte) (*PushEvent, error) {
+	var push bitbucketCloudPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the push event")
+	}
+
+	event := &PushEvent{DeliveryID: deliveryID, DefaultBranch: push.Repository.MainBranch.Name}
+
+	for _, change := range push.Push.Changes {
+		// the new state of a deleted reference is null
+		if change.New == nil {
+			continue
+		}
+
+		prefix := branchReferencePrefix
+		if change.New.Type == "tag" || change.New.Type == "annotated_tag" {
+			prefix = tagReferencePrefix
+		}
+
+		event.Refs = append(event.Refs, PushedRef{Name: prefix + change.New.Name, Commit: change.New.Target.Hash})
+	}
+
+	return event, nil
+}
+
+type bitbucketServerPush struct {
+	Changes []struct {
+		Ref struct {
+			ID string `json:"id"`
+		} `json:"ref"`
+		ToHash string `json:"toHash"`
+		Type   string `json:"type"`
+	} `json:"changes"`
+}
+
+func parseBitbucketServerPush(deliveryID string, body []byte) (*PushEvent, error) {
+	var push bitbucketServerPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the push event")
+	}
+
+	event := &PushEvent{DeliveryID: deliveryID}
+
+	for _, change := range push.Changes {
+		if change.Type == "DELETE" {
+			continue
+		}
+
+		event.Refs = append(event.Refs, PushedRef{Name: change.Ref.ID, Commit: change.ToHash})
+	}
+
+	return event, nil
+}
@@ -0,0 +1,139 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/git"
+	gittypes "github.com/portainer/portainer/api/git/types"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	branchReferencePrefix = "refs/heads/"
+	tagReferencePrefix    = "refs/tags/"
+
+	// maxPayloadSize is the size limit of the push events, GitHub caps its payloads to 25MB
+	maxPayloadSize = 25 << 20
+)
+
+var (
+	ErrInvalidSignature = errors.New("the push event is not signed with the webhook secret")
+	ErrUnknownProvider  = errors.New("unknown git webhook provider")
+)
+
+// PushEvent is a push to a git repository sent by a provider webhook
+type PushEvent struct {
+	// Identifier of the delivery given by the provider
+	DeliveryID string
+	// Pushed references, the deleted references are left out
+	Refs []PushedRef
+	// Default branch of the repository when the provider sends it
+	DefaultBranch string
+}
+
+// PushedRef is a reference updated by a push
+type PushedRef struct {
+	// Full name of the reference, such as refs/heads/main
+	Name string
+	// Commit the reference points to after the push
+	Commit string
+}
+
+// IsValidProvider returns true when the provider is supported by the webhooks
+func IsValidProvider(provider portainer.GitWebhookProvider) bool {
+	_, ok := parsers[provider]
+
+	return ok
+}
+
+// Parse checks the signature of the request sent by the provider and decodes its push event.
+// It returns a nil event for the other events sent by the provider, such as the pings.
+func Parse(provider portainer.GitWebhookProvider, secret string, r *http.Request) (*PushEvent, error) {
+	parser, ok := parsers[provider]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownProvider, "provider %q", provider)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the push event")
+	} else if len(body) > maxPayloadSize {
+		return nil, errors.New("the push event is too large")
+	}
+
+	// an empty secret would let anyone sign the events
+	if secret == "" || !parser.verify(r.Header, body, secret) {
+		return nil, ErrInvalidSignature
+	}
+
+	event, err := parser.parse(r.Header, body)
+	if err != nil || event == nil {
+		return nil, err
+	}
+
+	// the deleted references point to the zero commit
+	refs := make([]PushedRef, 0, len(event.Refs))
+	for _, ref := range event.Refs {
+		if ref.Name != "" && strings.Trim(ref.Commit, "0") != "" {
+			refs = append(refs, ref)
+		}
+	}
+	event.Refs = refs
+
+	return event, nil
+}
+
+// MatchingRef returns the pushed reference deployed from the repository config. The tags matching the tag
+// constraint of the config are deployed, otherwise its reference or the default branch when it is left empty.
+func (event *PushEvent) MatchingRef(config *gittypes.RepoConfig) (PushedRef, bool) {
+	for _, ref := range event.Refs {
+		if config.TagConstraint != "" {
+			if _, err := git.LatestMatchingTag([]string{ref.Name}, config.TagConstraint); err == nil {
+				return ref, true
+			}
+
+			continue
+		}
+
+		if config.ReferenceName == "" {
+			// the default branch is not sent by every provider, a push cannot be matched to it then
+			if event.DefaultBranch != "" && ref.Name == branchReferencePrefix+event.DefaultBranch {
+				return ref, true
+			}
+
+			continue
+		}
+
+		if ref.Name == config.ReferenceName || ref.Name == branchReferencePrefix+config.ReferenceName || ref.Name == tagReferencePrefix+config.ReferenceName {
+			return ref, true
+		}
+	}
+
+	return PushedRef{}, false
+}
+
+// verifyHMAC checks that the signature is the hex encoded HMAC-SHA256 of the body
+func verifyHMAC(signature string, body []byte, secret string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// verifyToken checks that the token sent along with the event is the secret
+func verifyToken(token, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
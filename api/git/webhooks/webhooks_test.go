@@ -0,0 +1,161 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	gittypes "github.com/portainer/portainer/api/git/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	secret = "s3cr3t"
+	commit = "a5c9e5d3f2e7c3b04d8d6a7d5e4f3c2b1a0f9e8d"
+)
+
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newRequest(body string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+
+	return r
+}
+
+func Test_Parse(t *testing.T) {
+	githubBody := `{"ref":"refs/heads/main","after":"` + commit + `","repository":{"default_branch":"main"}}`
+	gitlabBody := `{"ref":"refs/tags/v1.2.0","after":"1111111111111111111111111111111111111111","checkout_sha":"` + commit + `","project":{"default_branch":"main"}}`
+	bitbucketCloudBody := `{"push":{"changes":[{"new":null},{"new":{"type":"branch","name":"main","target":{"hash":"` + commit + `"}}}]}}`
+	bitbucketServerBody := `{"changes":[{"ref":{"id":"refs/heads/old"},"toHash":"0000000000000000000000000000000000000000","type":"DELETE"},{"ref":{"id":"refs/heads/main"},"toHash":"` + commit + `","type":"UPDATE"}]}`
+
+	tests := []struct {
+		name     string
+		provider portainer.GitWebhookProvider
+		request  *http.Request
+		expected *PushEvent
+		err      error
+	}{
+		{
+			name:     "github push",
+			provider: portainer.GitWebhookProviderGitHub,
+			request:  newRequest(githubBody, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(githubBody), "X-GitHub-Event": "push", "X-GitHub-Delivery": "1"}),
+			expected: &PushEvent{DeliveryID: "1", Refs: []PushedRef{{Name: "refs/heads/main", Commit: commit}}, DefaultBranch: "main"},
+		},
+		{
+			name:     "github ping is ignored",
+			provider: portainer.GitWebhookProviderGitHub,
+			request:  newRequest(`{}`, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(`{}`), "X-GitHub-Event": "ping"}),
+		},
+		{
+			name:     "github wrong signature",
+			provider: portainer.GitWebhookProviderGitHub,
+			request:  newRequest(githubBody, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(`{}`), "X-GitHub-Event": "push"}),
+			err:      ErrInvalidSignature,
+		},
+		{
+			name:     "github missing signature",
+			provider: portainer.GitWebhookProviderGitHub,
+			request:  newRequest(githubBody, map[string]string{"X-GitHub-Event": "push"}),
+			err:      ErrInvalidSignature,
+		},
+		{
+			name:     "gitlab tag push",
+			provider: portainer.GitWebhookProviderGitLab,
+			request:  newRequest(gitlabBody, map[string]string{"X-Gitlab-Token": secret, "X-Gitlab-Event": "Tag Push Hook", "X-Gitlab-Event-UUID": "2"}),
+			expected: &PushEvent{DeliveryID: "2", Refs: []PushedRef{{Name: "refs/tags/v1.2.0", Commit: commit}}, DefaultBranch: "main"},
+		},
+		{
+			name:     "gitlab wrong token",
+			provider: portainer.GitWebhookProviderGitLab,
+			request:  newRequest(gitlabBody, map[string]string{"X-Gitlab-Token": "guess", "X-Gitlab-Event": "Push Hook"}),
+			err:      ErrInvalidSignature,
+		},
+		{
+			name:     "gitea push",
+			provider: portainer.GitWebhookProviderGitea,
+			request:  newRequest(githubBody, map[string]string{"X-Gitea-Signature": sign(githubBody), "X-Gitea-Event": "push", "X-Gitea-Delivery": "3"}),
+			expected: &PushEvent{DeliveryID: "3", Refs: []PushedRef{{Name: "refs/heads/main", Commit: commit}}, DefaultBranch: "main"},
+		},
+		{
+			name:     "bitbucket cloud push",
+			provider: portainer.GitWebhookProviderBitbucket,
+			request:  newRequest(bitbucketCloudBody, map[string]string{"X-Hub-Signature": "sha256=" + sign(bitbucketCloudBody), "X-Event-Key": "repo:push", "X-Request-UUID": "4"}),
+			expected: &PushEvent{DeliveryID: "4", Refs: []PushedRef{{Name: "refs/heads/main", Commit: commit}}},
+		},
+		{
+			name:     "bitbucket data center push",
+			provider: portainer.GitWebhookProviderBitbucket,
+			request:  newRequest(bitbucketServerBody, map[string]string{"X-Hub-Signature": "sha256=" + sign(bitbucketServerBody), "X-Event-Key": "repo:refs_changed", "X-Request-Id": "5"}),
+			expected: &PushEvent{DeliveryID: "5", Refs: []PushedRef{{Name: "refs/heads/main", Commit: commit}}},
+		},
+		{
+			name:     "unknown provider",
+			provider: "svn",
+			request:  newRequest(githubBody, nil),
+			err:      ErrUnknownProvider,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := Parse(tt.provider, secret, tt.request)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.expected, event)
+		})
+	}
+
+	t.Run("empty secret is refused", func(t *testing.T) {
+		body := `{"ref":"refs/heads/main"}`
+		_, err := Parse(portainer.GitWebhookProviderGitLab, "", newRequest(body, map[string]string{"X-Gitlab-Event": "Push Hook"}))
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
+
+func Test_MatchingRef(t *testing.T) {
+	event := &PushEvent{
+		Refs: []PushedRef{
+			{Name: "refs/heads/feature", Commit: "1"},
+			{Name: "refs/heads/main", Commit: "2"},
+			{Name: "refs/tags/v1.4.2", Commit: "3"},
+		},
+		DefaultBranch: "main",
+	}
+
+	tests := []struct {
+		name     string
+		event    *PushEvent
+		config   gittypes.RepoConfig
+		expected string
+		match    bool
+	}{
+		{name: "full branch reference", event: event, config: gittypes.RepoConfig{ReferenceName: "refs/heads/feature"}, expected: "1", match: true},
+		{name: "short branch name", event: event, config: gittypes.RepoConfig{ReferenceName: "main"}, expected: "2", match: true},
+		{name: "other branch", event: event, config: gittypes.RepoConfig{ReferenceName: "refs/heads/release"}},
+		{name: "default branch", event: event, config: gittypes.RepoConfig{}, expected: "2", match: true},
+		{name: "unknown default branch", event: &PushEvent{Refs: event.Refs[:1]}, config: gittypes.RepoConfig{}},
+		{name: "tag matching the constraint", event: event, config: gittypes.RepoConfig{ReferenceName: "refs/tags/v1.4.0", TagConstraint: "~1.4"}, expected: "3", match: true},
+		{name: "tag outside the constraint", event: event, config: gittypes.RepoConfig{ReferenceName: "refs/heads/main", TagConstraint: "^2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, ok := tt.event.MatchingRef(&tt.config)
+			require.Equal(t, tt.match, ok)
+			require.Equal(t, tt.expected, ref.Commit)
+		})
+	}
+}
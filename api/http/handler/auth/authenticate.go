@@ -4,11 +4,13 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	httperrors "github.com/portainer/portainer/api/http/errors"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/twofactor"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
 	"github.com/portainer/portainer/pkg/libhttp/response"
@@ -26,7 +28,13 @@ type authenticatePayload struct {
 
 type authenticateResponse struct {
 	// JWT token used to authenticate against the API
-	JWT string `json:"jwt" example:"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789abcdefghijklmnopqrstuvwxyzAB"`
+	JWT string `json:"jwt,omitempty" example:"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789abcdefghijklmnopqrstuvwxyzAB"`
+	// Set instead of the JWT when a second factor is required, to be sent to /auth/2fa along with the code
+	TwoFactorToken string `json:"twoFactorToken,omitempty" example:"c2VjcmV0"`
+	// Set when the user must enrol a second factor to log in, the code sent to /auth/2fa confirms the enrolment
+	TwoFactorEnrolment *twofactor.Enrolment `json:"twoFactorEnrolment,omitempty"`
+	// One-time recovery codes, only returned when the enrolment is confirmed
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
 }
 
 func (payload *authenticatePayload) Validate(r *http.Request) error {
@@ -45,6 +53,7 @@ func (payload *authenticatePayload) Validate(r *http.Request) error {
 // @summary Authenticate
 // @description **Access policy**: public
 // @description Use this environment(endpoint) to authenticate against Portainer using a username and password.
+// @description When the user has a second factor, or must enrol one, no JWT is returned and the login is completed through /auth/2fa.
 // @tags auth
 // @accept json
 // @produce json
@@ -52,6 +61,7 @@ func (payload *authenticatePayload) Validate(r *http.Request) error {
 // @success 200 {object} authenticateResponse "Success"
 // @failure 400 "Invalid request"
 // @failure 422 "Invalid Credentials"
+// @failure 429 "Too many failed login attempts for this username"
 // @failure 500 "Server error"
 // @router /auth [post]
 func (handler *Handler) authenticate(rw http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -60,6 +70,21 @@ func (handler *Handler) authenticate(rw http.ResponseWriter, r *http.Request) *h
 		return httperror.BadRequest("Invalid request payload", err)
 	}
 
+	release, httpErr := handler.reserveLogin(rw, payload.Username)
+	if httpErr != nil {
+		return httpErr
+	}
+	defer release()
+
+	httpErr = handler.authenticateWithCredentials(rw, &payload)
+	if httpErr != nil && errors.Is(httpErr.Err, httperrors.ErrUnauthorized) {
+		handler.loginGuard.Fail(payload.Username, security.RealClientIP(r))
+	}
+
+	return httpErr
+}
+
+func (handler *Handler) authenticateWithCredentials(rw http.ResponseWriter, payload *authenticatePayload) *httperror.HandlerError {
 	settings, err := handler.DataStore.Settings().Settings()
 	if err != nil {
 		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
@@ -73,6 +98,7 @@ func (handler *Handler) authenticate(rw http.ResponseWriter, r *http.Request) *h
 
 		if settings.AuthenticationMethod == portainer.AuthenticationInternal ||
 			settings.AuthenticationMethod == portainer.AuthenticationOAuth ||
+			settings.AuthenticationMethod == portainer.AuthenticationSAML ||
 			(settings.AuthenticationMethod == portainer.AuthenticationLDAP && !settings.LDAPSettings.AutoCreateUsers) {
 			// avoid username enumeration timing attack by creating a fake user
 			// https://en.wikipedia.org/wiki/Timing_attack
@@ -83,19 +109,28 @@ func (handler *Handler) authenticate(rw http.ResponseWriter, r *http.Request) *h
 		}
 	}
 
+	// disabled LDAP users are enabled again once the directory authenticates them
+	if user != nil && user.Disabled && settings.AuthenticationMethod != portainer.AuthenticationLDAP {
+		return httperror.NewError(http.StatusUnprocessableEntity, "User account is disabled", httperrors.ErrUnauthorized)
+	}
+
 	// Clear any existing user caches
 	if user != nil {
 		handler.KubernetesClientFactory.ClearUserClientCache(strconv.Itoa(int(user.ID)))
 	}
 
 	if user != nil && isUserInitialAdmin(user) || settings.AuthenticationMethod == portainer.AuthenticationInternal {
-		return handler.authenticateInternal(rw, user, payload.Password)
+		return handler.authenticateInternal(rw, user, payload.Password, &settings.InternalAuthSettings)
 	}
 
 	if settings.AuthenticationMethod == portainer.AuthenticationOAuth {
 		return httperror.NewError(http.StatusUnprocessableEntity, "Only initial admin is allowed to login without oauth", httperrors.ErrUnauthorized)
 	}
 
+	if settings.AuthenticationMethod == portainer.AuthenticationSAML {
+		return httperror.NewError(http.StatusUnprocessableEntity, "Only initial admin is allowed to login without SAML", httperrors.ErrUnauthorized)
+	}
+
 	if settings.AuthenticationMethod == portainer.AuthenticationLDAP {
 		return handler.authenticateLDAP(rw, user, payload.Username, payload.Password, &settings.LDAPSettings)
 	}
@@ -107,12 +142,25 @@ func isUserInitialAdmin(user *portainer.User) bool {
 	return int(user.ID) == 1
 }
 
-func (handler *Handler) authenticateInternal(w http.ResponseWriter, user *portainer.User, password string) *httperror.HandlerError {
+func (handler *Handler) authenticateInternal(w http.ResponseWriter, user *portainer.User, password string, authSettings *portainer.InternalAuthSettings) *httperror.HandlerError {
 	if err := handler.CryptoService.CompareHashAndData(user.Password, password); err != nil {
 		return httperror.NewError(http.StatusUnprocessableEntity, "Invalid credentials", httperrors.ErrUnauthorized)
 	}
 
-	forceChangePassword := !handler.passwordStrengthChecker.Check(password)
+	maxAgeDays := authSettings.PasswordPolicy.MaxAgeDays
+	if maxAgeDays > 0 && user.PasswordChangedAt == 0 {
+		// the age of the passwords set before the policy is unknown, it is counted from the first login
+		user.PasswordChangedAt = time.Now().Unix()
+		if err := handler.DataStore.User().Update(user.ID, user); err != nil {
+			return httperror.InternalServerError("Unable to persist user changes inside the database", err)
+		}
+	}
+
+	forceChangePassword := !handler.passwordStrengthChecker.Check(password) || security.PasswordExpired(user, maxAgeDays, time.Now())
+
+	if user.TwoFactor.Enabled || authSettings.EnforceTwoFactor {
+		return handler.startTwoFactorLogin(w, user, forceChangePassword)
+	}
 
 	return handler.writeToken(w, user, forceChangePassword)
 }
@@ -131,6 +179,7 @@ func (handler *Handler) authenticateLDAP(w http.ResponseWriter, user *portainer.
 			Username:                username,
 			Role:                    portainer.StandardUserRole,
 			PortainerAuthorizations: authorization.DefaultPortainerAuthorizations(),
+			Origin:                  portainer.UserOriginLDAP,
 		}
 
 		if err := handler.DataStore.User().Create(user); err != nil {
@@ -138,6 +187,16 @@ func (handler *Handler) authenticateLDAP(w http.ResponseWriter, user *portainer.
 		}
 	}
 
+	// the users created before their origin was recorded are managed by the LDAP synchronization once they log in
+	if user.Disabled || user.Origin != portainer.UserOriginLDAP {
+		user.Disabled = false
+		user.Origin = portainer.UserOriginLDAP
+
+		if err := handler.DataStore.User().Update(user.ID, user); err != nil {
+			return httperror.InternalServerError("Unable to persist user changes inside the database", err)
+		}
+	}
+
 	if err := handler.syncUserTeamsWithLDAPGroups(user, ldapSettings); err != nil {
 		log.Warn().Err(err).Msg("unable to automatically sync user teams with ldap")
 	}
@@ -146,6 +205,8 @@ func (handler *Handler) authenticateLDAP(w http.ResponseWriter, user *portainer.
 }
 
 func (handler *Handler) writeToken(w http.ResponseWriter, user *portainer.User, forceChangePassword bool) *httperror.HandlerError {
+	handler.loginGuard.Succeed(user.Username)
+
 	tokenData := composeTokenData(user, forceChangePassword)
 
 	return handler.persistAndWriteToken(w, tokenData)
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/twofactor"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// twoFactorLoginTTL is the time a user has to provide the code once the password is verified
+	twoFactorLoginTTL = 5 * time.Minute
+	// twoFactorLoginMaxAttempts is the number of codes that can be tried for a single password verification
+	twoFactorLoginMaxAttempts = 5
+)
+
+// pendingTwoFactorLogin is a login whose password was verified and that waits for the second factor
+type pendingTwoFactorLogin struct {
+	userID              portainer.UserID
+	forceChangePassword bool
+	// secret generated for a user that must enrol before being able to log in
+	enrolmentSecret string
+	attempts        int
+	expiresAt       time.Time
+}
+
+type pendingTwoFactorLogins struct {
+	mu     sync.Mutex
+	logins map[string]*pendingTwoFactorLogin
+}
+
+func newPendingTwoFactorLogins() *pendingTwoFactorLogins {
+	return &pendingTwoFactorLogins{logins: make(map[string]*pendingTwoFactorLogin)}
+}
+
+func (p *pendingTwoFactorLogins) add(login *pendingTwoFactorLogin) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for t, l := range p.logins {
+		if now.After(l.expiresAt) {
+			delete(p.logins, t)
+		}
+	}
+
+	login.expiresAt = now.Add(twoFactorLoginTTL)
+	p.logins[token] = login
+
+	return token, nil
+}
+
+// attempt returns a copy of the pending login matching token and counts the attempt,
+// the login is dropped once expired or out of attempts
+func (p *pendingTwoFactorLogins) attempt(token string) (pendingTwoFactorLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	login, ok := p.logins[token]
+	if !ok {
+		return pendingTwoFactorLogin{}, false
+	}
+
+	login.attempts++
+	if time.Now().After(login.expiresAt) || login.attempts > twoFactorLoginMaxAttempts {
+		delete(p.logins, token)
+		return pendingTwoFactorLogin{}, false
+	}
+
+	return *login, true
+}
+
+func (p *pendingTwoFactorLogins) remove(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.logins, token)
+}
+
+type authenticateTwoFactorPayload struct {
+	// Token returned by the password authentication
+	Token string `example:"c2VjcmV0" validate:"required"`
+	// TOTP code from the authenticator application
+	Code string `example:"123456"`
+	// One of the recovery codes, used when the authenticator application is not available
+	RecoveryCode string `example:"abcde-fghij"`
+}
+
+func (payload *authenticateTwoFactorPayload) Validate(r *http.Request) error {
+	if len(payload.Token) == 0 {
+		return errors.New("Invalid token")
+	}
+
+	if len(payload.Code) == 0 && len(payload.RecoveryCode) == 0 {
+		return errors.New("A code or a recovery code is required")
+	}
+
+	return nil
+}
+
+// startTwoFactorLogin answers a verified password with a token to exchange against a JWT once the second
+// factor is verified, along with a new secret when the user has to enrol first
+func (handler *Handler) startTwoFactorLogin(w http.ResponseWriter, user *portainer.User, forceChangePassword bool) *httperror.HandlerError {
+	login := &pendingTwoFactorLogin{
+		userID:              user.ID,
+		forceChangePassword: forceChangePassword,
+	}
+
+	resp := &authenticateResponse{}
+
+	if !user.TwoFactor.Enabled {
+		enrolment, err := twofactor.NewEnrolment(user)
+		if err != nil {
+			return httperror.InternalServerError("Unable to generate a two-factor authentication secret", err)
+		}
+
+		login.enrolmentSecret = enrolment.Secret
+		resp.TwoFactorEnrolment = enrolment
+	}
+
+	token, err := handler.pendingLogins.add(login)
+	if err != nil {
+		return httperror.InternalServerError("Unable to generate a two-factor authentication token", err)
+	}
+
+	resp.TwoFactorToken = token
+
+	return response.JSON(w, resp)
+}
+
+// @id AuthenticateTwoFactor
+// @summary Complete an authentication with a second factor
+// @description **Access policy**: public
+// @description Exchange the token returned by /auth for a JWT, using a TOTP code or a recovery code.
+// @description When the user had to enrol, the code validates the new secret and the recovery codes are returned once.
+// @tags auth
+// @accept json
+// @produce json
+// @param body body authenticateTwoFactorPayload true "Token and code"
+// @success 200 {object} authenticateResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 422 "Invalid code or expired token"
+// @failure 429 "Too many failed login attempts for this username"
+// @failure 500 "Server error"
+// @router /auth/2fa [post]
+func (handler *Handler) authenticateTwoFactor(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload authenticateTwoFactorPayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	login, ok := handler.pendingLogins.attempt(payload.Token)
+	if !ok {
+		return httperror.NewError(http.StatusUnprocessableEntity, "Invalid or expired two-factor authentication token", httperrors.ErrUnauthorized)
+	}
+
+	user, err := handler.DataStore.User().Read(login.userID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the user from the database", err)
+	}
+
+	release, httpErr := handler.reserveLogin(w, user.Username)
+	if httpErr != nil {
+		return httpErr
+	}
+	defer release()
+
+	var recoveryCodes []string
+	if login.enrolmentSecret != "" {
+		recoveryCodes, err = twofactor.Enable(user, login.enrolmentSecret, payload.Code, handler.CryptoService, time.Now())
+	} else {
+		err = twofactor.Verify(user, payload.Code, payload.RecoveryCode, handler.CryptoService, time.Now())
+	}
+
+	if errors.Is(err, twofactor.ErrInvalidCode) || errors.Is(err, twofactor.ErrNotEnrolled) || errors.Is(err, twofactor.ErrNoPendingSetup) {
+		handler.loginGuard.Fail(user.Username, security.RealClientIP(r))
+
+		return httperror.NewError(http.StatusUnprocessableEntity, "Invalid two-factor authentication code", httperrors.ErrUnauthorized)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to verify the two-factor authentication code", err)
+	}
+
+	if err := handler.DataStore.User().Update(user.ID, user); err != nil {
+		return httperror.InternalServerError("Unable to persist user changes inside the database", err)
+	}
+
+	handler.pendingLogins.remove(payload.Token)
+	handler.loginGuard.Succeed(user.Username)
+
+	token, expirationTime, err := handler.JWTService.GenerateToken(composeTokenData(user, login.forceChangePassword))
+	if err != nil {
+		return httperror.InternalServerError("Unable to generate JWT token", err)
+	}
+
+	security.AddAuthCookie(w, token, expirationTime)
+
+	return response.JSON(w, &authenticateResponse{JWT: token, RecoveryCodes: recoveryCodes})
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/portainer/portainer/api/kubernetes/cli"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPassword = "a-strong-enough-password"
+
+func setupTwoFactorHandler(t *testing.T, enforce bool) *Handler {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	cryptoService := &crypto.Service{}
+
+	hash, err := cryptoService.Hash(testPassword)
+	require.NoError(t, err)
+
+	err = store.User().Create(&portainer.User{Username: "alice", Password: hash, Role: portainer.StandardUserRole})
+	require.NoError(t, err)
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+	settings.AuthenticationMethod = portainer.AuthenticationInternal
+	settings.InternalAuthSettings.EnforceTwoFactor = enforce
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	kubernetesClientFactory, err := cli.NewClientFactory(nil, nil, store, "", "", "")
+	require.NoError(t, err)
+
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	bouncer := security.NewRequestBouncer(store, jwtService, apiKeyService)
+	rateLimiter := security.NewRateLimiter(100, time.Second, time.Hour)
+
+	h := NewHandler(bouncer, rateLimiter, security.NewPasswordStrengthChecker(store.SettingsService), kubernetesClientFactory)
+	h.DataStore = store
+	h.CryptoService = cryptoService
+	h.JWTService = jwtService
+
+	return h
+}
+
+func postJSON(t *testing.T, h *Handler, path string, payload any) (*httptest.ResponseRecorder, authenticateResponse) {
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+
+	var resp authenticateResponse
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	}
+
+	return rec, resp
+}
+
+func TestAuthenticateWithoutTwoFactor(t *testing.T) {
+	h := setupTwoFactorHandler(t, false)
+
+	rec, resp := postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: testPassword})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, resp.JWT)
+	require.Empty(t, resp.TwoFactorToken)
+}
+
+func TestAuthenticateEnforcedTwoFactorEnrolment(t *testing.T) {
+	h := setupTwoFactorHandler(t, true)
+
+	rec, resp := postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: testPassword})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, resp.JWT)
+	require.Empty(t, rec.Result().Cookies())
+	require.NotEmpty(t, resp.TwoFactorToken)
+	require.NotNil(t, resp.TwoFactorEnrolment)
+
+	rec, _ = postJSON(t, h, "/auth/2fa", authenticateTwoFactorPayload{Token: resp.TwoFactorToken, Code: "000000"})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	code, err := crypto.GenerateTOTPCode(resp.TwoFactorEnrolment.Secret, crypto.TOTPStep(time.Now()))
+	require.NoError(t, err)
+
+	rec, completed := postJSON(t, h, "/auth/2fa", authenticateTwoFactorPayload{Token: resp.TwoFactorToken, Code: code})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, completed.JWT)
+	require.NotEmpty(t, completed.RecoveryCodes)
+
+	user, err := h.DataStore.User().UserByUsername("alice")
+	require.NoError(t, err)
+	require.True(t, user.TwoFactor.Enabled)
+
+	// The token cannot be used twice
+	rec, _ = postJSON(t, h, "/auth/2fa", authenticateTwoFactorPayload{Token: resp.TwoFactorToken, Code: code})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Later logins require a code, a recovery code works as well
+	rec, resp = postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: testPassword})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, resp.JWT)
+	require.Nil(t, resp.TwoFactorEnrolment)
+
+	rec, resp = postJSON(t, h, "/auth/2fa", authenticateTwoFactorPayload{Token: resp.TwoFactorToken, RecoveryCode: completed.RecoveryCodes[0]})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, resp.JWT)
+}
+
+func TestAuthenticateTwoFactorMaxAttempts(t *testing.T) {
+	h := setupTwoFactorHandler(t, true)
+
+	_, resp := postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: testPassword})
+
+	code, err := crypto.GenerateTOTPCode(resp.TwoFactorEnrolment.Secret, crypto.TOTPStep(time.Now()))
+	require.NoError(t, err)
+
+	for range twoFactorLoginMaxAttempts {
+		rec, _ := postJSON(t, h, "/auth/2fa", authenticateTwoFactorPayload{Token: resp.TwoFactorToken, Code: "000000"})
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+		// only exercise the attempt limit of the pending login, not the per-username delays
+		h.loginGuard.Succeed("alice")
+	}
+
+	rec, _ := postJSON(t, h, "/auth/2fa", authenticateTwoFactorPayload{Token: resp.TwoFactorToken, Code: code})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/internal/authorization"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+
+	"github.com/rs/zerolog/log"
+)
+
+// @id AuthenticateCertificate
+// @summary Authenticate with a TLS client certificate
+// @description Authenticate with the client certificate presented during the TLS handshake, the certificate must be issued by one of the configured authorities.
+// @description Users unknown to Portainer are created when automatic user provisioning is enabled and their username matches the configured pattern.
+// @description API requests can also be authenticated with the client certificate directly, by setting the X-Client-Certificate-Auth header to true.
+// @description **Access policy**: public
+// @tags auth
+// @produce json
+// @success 200 {object} authenticateResponse "Success"
+// @failure 403 "Client certificate authentication is not enabled or the user cannot be created"
+// @failure 422 "Invalid client certificate"
+// @failure 500 "Server error"
+// @router /auth/certificate [post]
+func (handler *Handler) authenticateCertificate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
+	}
+
+	certificateSettings := &settings.ClientCertificateSettings
+	if !certificateSettings.Enabled {
+		return httperror.Forbidden("Client certificate authentication is not enabled", errors.New("client certificate authentication is not enabled"))
+	}
+
+	username, err := handler.ClientCertificateService.Username(r)
+	if err != nil {
+		log.Debug().Err(err).Msg("client certificate authentication error")
+
+		return httperror.NewError(http.StatusUnprocessableEntity, "Invalid client certificate", httperrors.ErrUnauthorized)
+	}
+
+	user, err := handler.DataStore.User().UserByUsername(username)
+	if err != nil && !handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.InternalServerError("Unable to retrieve a user with the specified username from the database", err)
+	}
+
+	if user == nil {
+		if !handler.ClientCertificateService.CanCreateUser(username) {
+			return httperror.Forbidden("Account not created beforehand in Portainer and automatic user provisioning not enabled", httperrors.ErrUnauthorized)
+		}
+
+		user = &portainer.User{
+			Username:                username,
+			Role:                    portainer.StandardUserRole,
+			PortainerAuthorizations: authorization.DefaultPortainerAuthorizations(),
+			Origin:                  portainer.UserOriginCertificate,
+		}
+
+		if err := handler.DataStore.User().Create(user); err != nil {
+			return httperror.InternalServerError("Unable to persist user inside the database", err)
+		}
+
+		if certificateSettings.DefaultTeamID != 0 {
+			membership := &portainer.TeamMembership{
+				UserID: user.ID,
+				TeamID: certificateSettings.DefaultTeamID,
+				Role:   portainer.TeamMember,
+			}
+
+			if err := handler.DataStore.TeamMembership().Create(membership); err != nil {
+				return httperror.InternalServerError("Unable to persist team membership inside the database", err)
+			}
+		}
+	}
+
+	if user.Disabled {
+		return httperror.NewError(http.StatusUnprocessableEntity, "User account is disabled", httperrors.ErrUnauthorized)
+	}
+
+	return handler.writeToken(w, user, false)
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testClientCertificateService struct {
+	username        string
+	usernamePattern *regexp.Regexp
+}
+
+func (service *testClientCertificateService) SetSettings(settings *portainer.ClientCertificateSettings) error {
+	usernamePattern, err := security.CompileAutoCreateUsernamePattern(settings)
+	service.usernamePattern = usernamePattern
+
+	return err
+}
+
+func (service *testClientCertificateService) CanCreateUser(username string) bool {
+	return service.usernamePattern != nil && service.usernamePattern.MatchString(username)
+}
+
+func (service *testClientCertificateService) Username(r *http.Request) (string, error) {
+	if service.username == "" {
+		return "", security.ErrInvalidClientCertificate
+	}
+
+	return service.username, nil
+}
+
+func setupCertificateHandler(t *testing.T, certificateSettings portainer.ClientCertificateSettings) (*Handler, *testClientCertificateService) {
+	h := setupTwoFactorHandler(t, false)
+
+	require.NoError(t, h.DataStore.Team().Create(&portainer.Team{Name: "smartcards"}))
+
+	settings, err := h.DataStore.Settings().Settings()
+	require.NoError(t, err)
+
+	settings.ClientCertificateSettings = certificateSettings
+	require.NoError(t, h.DataStore.Settings().UpdateSettings(settings))
+
+	service := &testClientCertificateService{}
+	require.NoError(t, service.SetSettings(&certificateSettings))
+	h.ClientCertificateService = service
+
+	return h, service
+}
+
+func postCertificate(h *Handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/certificate", nil))
+
+	return rec
+}
+
+func TestAuthenticateCertificate(t *testing.T) {
+	h, service := setupCertificateHandler(t, portainer.ClientCertificateSettings{
+		Enabled:                   true,
+		AutoCreateUsers:           true,
+		AutoCreateUsernamePattern: `.+@example\.com`,
+		DefaultTeamID:             1,
+	})
+
+	// existing user
+	service.username = "alice"
+	rec, resp := postJSON(t, h, "/auth/certificate", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, resp.JWT)
+
+	// invalid certificate
+	service.username = ""
+	require.Equal(t, http.StatusUnprocessableEntity, postCertificate(h).Code)
+
+	// the username does not match the pattern of the users created automatically
+	service.username = "mallory@example.org"
+	require.Equal(t, http.StatusForbidden, postCertificate(h).Code)
+
+	// the pattern must match the whole username
+	service.username = "mallory@example.com.evil.org"
+	require.Equal(t, http.StatusForbidden, postCertificate(h).Code)
+
+	service.username = "bob@example.com"
+	require.Equal(t, http.StatusOK, postCertificate(h).Code)
+
+	bob, err := h.DataStore.User().UserByUsername("bob@example.com")
+	require.NoError(t, err)
+	require.Equal(t, portainer.StandardUserRole, bob.Role)
+
+	memberships, err := h.DataStore.TeamMembership().TeamMembershipsByUserID(bob.ID)
+	require.NoError(t, err)
+	require.Len(t, memberships, 1)
+	require.Equal(t, portainer.TeamID(1), memberships[0].TeamID)
+
+	// disabled user
+	bob.Disabled = true
+	require.NoError(t, h.DataStore.User().Update(bob.ID, bob))
+	require.Equal(t, http.StatusUnprocessableEntity, postCertificate(h).Code)
+}
+
+func TestAuthenticateCertificateDisabled(t *testing.T) {
+	h, service := setupCertificateHandler(t, portainer.ClientCertificateSettings{})
+
+	service.username = "alice"
+	require.Equal(t, http.StatusForbidden, postCertificate(h).Code)
+}
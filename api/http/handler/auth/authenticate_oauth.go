@@ -90,6 +90,7 @@ func (handler *Handler) validateOAuth(w http.ResponseWriter, r *http.Request) *h
 		user = &portainer.User{
 			Username: username,
 			Role:     portainer.StandardUserRole,
+			Origin:   portainer.UserOriginOAuth,
 		}
 
 		err = handler.DataStore.User().Create(user)
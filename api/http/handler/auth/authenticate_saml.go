@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+
+	"github.com/rs/zerolog/log"
+)
+
+// @id SAMLMetadata
+// @summary Retrieve the SAML service provider metadata
+// @description Retrieve the metadata describing Portainer to a SAML identity provider.
+// @description **Access policy**: public
+// @tags auth
+// @produce xml
+// @success 200 "Success"
+// @failure 400 "SAML authentication is not configured"
+// @failure 500 "Server error"
+// @router /auth/saml/metadata [get]
+func (handler *Handler) samlMetadata(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
+	}
+
+	metadata, err := handler.SAMLService.Metadata(&settings.SAMLSettings)
+	if err != nil {
+		return httperror.BadRequest("Unable to generate the SAML metadata", err)
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	_, _ = w.Write(metadata)
+
+	return nil
+}
+
+// @id SAMLLogin
+// @summary Start a SAML login
+// @description Redirect the user to the SAML identity provider with an authentication request.
+// @description **Access policy**: public
+// @tags auth
+// @success 302 "Redirect to the identity provider"
+// @failure 403 "SAML authentication is not enabled"
+// @failure 500 "Server error"
+// @router /auth/saml/login [get]
+func (handler *Handler) samlLogin(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationSAML {
+		return httperror.Forbidden("SAML authentication is not enabled", errors.New("SAML authentication is not enabled"))
+	}
+
+	location, err := handler.SAMLService.AuthnRequestURL(&settings.SAMLSettings)
+	if err != nil {
+		return httperror.InternalServerError("Unable to create the SAML authentication request", err)
+	}
+
+	http.Redirect(w, r, location, http.StatusFound)
+
+	return nil
+}
+
+// @id SAMLAssertionConsumerService
+// @summary Authenticate with SAML
+// @description Validate the SAML response posted by the identity provider, set the authentication cookie and redirect to Portainer.
+// @description Users unknown to Portainer are created when automatic user provisioning is enabled, and are added to the teams mapped to their groups.
+// @description **Access policy**: public
+// @tags auth
+// @accept x-www-form-urlencoded
+// @param SAMLResponse formData string true "Base64 encoded SAML response"
+// @success 303 "Redirect to Portainer"
+// @failure 400 "Invalid request"
+// @failure 403 "SAML authentication is not enabled or the user cannot be created"
+// @failure 422 "Invalid SAML response"
+// @failure 500 "Server error"
+// @router /auth/saml/acs [post]
+func (handler *Handler) samlAssertionConsumerService(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	samlResponse := r.PostFormValue("SAMLResponse")
+	if samlResponse == "" {
+		return httperror.BadRequest("Invalid request payload", errors.New("missing SAMLResponse"))
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationSAML {
+		return httperror.Forbidden("SAML authentication is not enabled", errors.New("SAML authentication is not enabled"))
+	}
+
+	identity, err := handler.SAMLService.Authenticate(samlResponse, &settings.SAMLSettings)
+	if err != nil {
+		log.Debug().Err(err).Msg("SAML authentication error")
+
+		return httperror.NewError(http.StatusUnprocessableEntity, "Unable to authenticate through SAML", httperrors.ErrUnauthorized)
+	}
+
+	user, err := handler.DataStore.User().UserByUsername(identity.Username)
+	if err != nil && !handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.InternalServerError("Unable to retrieve a user with the specified username from the database", err)
+	}
+
+	if user == nil && !settings.SAMLSettings.AutoCreateUsers {
+		return httperror.Forbidden("Account not created beforehand in Portainer and automatic user provisioning not enabled", httperrors.ErrUnauthorized)
+	}
+
+	if user == nil {
+		user = &portainer.User{
+			Username:                identity.Username,
+			Role:                    portainer.StandardUserRole,
+			PortainerAuthorizations: authorization.DefaultPortainerAuthorizations(),
+			Origin:                  portainer.UserOriginSAML,
+		}
+
+		if err := handler.DataStore.User().Create(user); err != nil {
+			return httperror.InternalServerError("Unable to persist user inside the database", err)
+		}
+
+		if settings.SAMLSettings.DefaultTeamID != 0 {
+			membership := &portainer.TeamMembership{
+				UserID: user.ID,
+				TeamID: settings.SAMLSettings.DefaultTeamID,
+				Role:   portainer.TeamMember,
+			}
+
+			if err := handler.DataStore.TeamMembership().Create(membership); err != nil {
+				return httperror.InternalServerError("Unable to persist team membership inside the database", err)
+			}
+		}
+	}
+
+	if err := handler.syncUserTeamsWithSAMLGroups(user, identity.Groups, &settings.SAMLSettings); err != nil {
+		log.Warn().Err(err).Msg("unable to automatically sync user teams with SAML groups")
+	}
+
+	handler.loginGuard.Succeed(user.Username)
+
+	token, expirationTime, err := handler.JWTService.GenerateToken(composeTokenData(user, false))
+	if err != nil {
+		return httperror.InternalServerError("Unable to generate JWT token", err)
+	}
+
+	security.AddAuthCookie(w, token, expirationTime)
+
+	http.Redirect(w, r, strings.TrimSuffix(settings.SAMLSettings.ServiceProviderURL, "/")+"/", http.StatusSeeOther)
+
+	return nil
+}
+
+// syncUserTeamsWithSAMLGroups adds the user to the teams mapped to its groups, the existing memberships are kept
+func (handler *Handler) syncUserTeamsWithSAMLGroups(user *portainer.User, groups []string, settings *portainer.SAMLSettings) error {
+	if len(settings.GroupTeamMappings) == 0 || len(groups) == 0 {
+		return nil
+	}
+
+	teams, err := handler.DataStore.Team().ReadAll()
+	if err != nil {
+		return err
+	}
+
+	userMemberships, err := handler.DataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, mapping := range settings.GroupTeamMappings {
+		if !teamExists(mapping.Group, groups) || !teamIDExists(mapping.TeamID, teams) || teamMembershipExists(mapping.TeamID, userMemberships) {
+			continue
+		}
+
+		membership := &portainer.TeamMembership{
+			UserID: user.ID,
+			TeamID: mapping.TeamID,
+			Role:   portainer.TeamMember,
+		}
+
+		if err := handler.DataStore.TeamMembership().Create(membership); err != nil {
+			return err
+		}
+
+		userMemberships = append(userMemberships, *membership)
+	}
+
+	return nil
+}
+
+func teamIDExists(teamID portainer.TeamID, teams []portainer.Team) bool {
+	for _, team := range teams {
+		if team.ID == teamID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testSAMLService struct {
+	identity *portainer.SAMLIdentity
+}
+
+func (service *testSAMLService) AuthnRequestURL(settings *portainer.SAMLSettings) (string, error) {
+	return settings.IdPSSOURL + "?SAMLRequest=request", nil
+}
+
+func (service *testSAMLService) Authenticate(samlResponse string, settings *portainer.SAMLSettings) (*portainer.SAMLIdentity, error) {
+	if samlResponse != "valid" {
+		return nil, errors.New("invalid SAML response")
+	}
+
+	return service.identity, nil
+}
+
+func (service *testSAMLService) Metadata(settings *portainer.SAMLSettings) ([]byte, error) {
+	return []byte("<EntityDescriptor/>"), nil
+}
+
+func setupSAMLHandler(t *testing.T, autoCreateUsers bool) (*Handler, *testSAMLService) {
+	h := setupTwoFactorHandler(t, false)
+
+	for _, name := range []string{"default", "developers", "operators"} {
+		require.NoError(t, h.DataStore.Team().Create(&portainer.Team{Name: name}))
+	}
+
+	settings, err := h.DataStore.Settings().Settings()
+	require.NoError(t, err)
+
+	settings.AuthenticationMethod = portainer.AuthenticationSAML
+	settings.SAMLSettings = portainer.SAMLSettings{
+		IdPEntityID:        "https://idp.example.com/saml",
+		IdPSSOURL:          "https://idp.example.com/saml/sso",
+		ServiceProviderURL: "https://portainer.example.com",
+		AutoCreateUsers:    autoCreateUsers,
+		DefaultTeamID:      1,
+		GroupTeamMappings: []portainer.SAMLGroupTeamMapping{
+			{Group: "devs", TeamID: 2},
+			{Group: "ops", TeamID: 3},
+			{Group: "devs", TeamID: 42},
+		},
+	}
+	require.NoError(t, h.DataStore.Settings().UpdateSettings(settings))
+
+	samlService := &testSAMLService{identity: &portainer.SAMLIdentity{Username: "bob", Groups: []string{"DEVS", "other"}}}
+	h.SAMLService = samlService
+
+	return h, samlService
+}
+
+func postSAMLResponse(h *Handler, samlResponse string) *httptest.ResponseRecorder {
+	form := url.Values{"SAMLResponse": {samlResponse}}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/saml/acs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestSAMLAssertionConsumerService(t *testing.T) {
+	h, _ := setupSAMLHandler(t, true)
+
+	rec := postSAMLResponse(h, "valid")
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	require.Equal(t, "https://portainer.example.com/", rec.Header().Get("Location"))
+
+	var token string
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == portainer.AuthCookieKey {
+			token = cookie.Value
+		}
+	}
+	require.NotEmpty(t, token)
+
+	tokenData, _, _, err := h.JWTService.ParseAndVerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, "bob", tokenData.Username)
+
+	user, err := h.DataStore.User().UserByUsername("bob")
+	require.NoError(t, err)
+	require.Equal(t, portainer.StandardUserRole, user.Role)
+
+	memberships, err := h.DataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	require.NoError(t, err)
+
+	teamIDs := make([]portainer.TeamID, 0, len(memberships))
+	for _, membership := range memberships {
+		teamIDs = append(teamIDs, membership.TeamID)
+	}
+	require.ElementsMatch(t, []portainer.TeamID{1, 2}, teamIDs)
+
+	// a second login does not duplicate the memberships
+	require.Equal(t, http.StatusSeeOther, postSAMLResponse(h, "valid").Code)
+
+	memberships, err = h.DataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	require.NoError(t, err)
+	require.Len(t, memberships, 2)
+}
+
+func TestSAMLAssertionConsumerServiceRejectsInvalidResponse(t *testing.T) {
+	h, _ := setupSAMLHandler(t, true)
+
+	require.Equal(t, http.StatusUnprocessableEntity, postSAMLResponse(h, "invalid").Code)
+	require.Equal(t, http.StatusBadRequest, postSAMLResponse(h, "").Code)
+}
+
+func TestSAMLAssertionConsumerServiceWithoutAutoCreateUsers(t *testing.T) {
+	h, samlService := setupSAMLHandler(t, false)
+
+	require.Equal(t, http.StatusForbidden, postSAMLResponse(h, "valid").Code)
+
+	samlService.identity = &portainer.SAMLIdentity{Username: "alice"}
+	require.Equal(t, http.StatusSeeOther, postSAMLResponse(h, "valid").Code)
+}
+
+func TestSAMLLogin(t *testing.T) {
+	h, _ := setupSAMLHandler(t, true)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/saml/login", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "https://idp.example.com/saml/sso?SAMLRequest=request", rec.Header().Get("Location"))
+
+	// only the initial administrator can log in with a password
+	alice, err := h.DataStore.User().UserByUsername("alice")
+	require.NoError(t, err)
+	require.NoError(t, h.DataStore.User().Create(&portainer.User{Username: "carol", Password: alice.Password, Role: portainer.StandardUserRole}))
+
+	rec, _ = postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: testPassword})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec, _ = postJSON(t, h, "/auth", authenticatePayload{Username: "carol", Password: testPassword})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticateExpiredPassword(t *testing.T) {
+	h := setupTwoFactorHandler(t, false)
+
+	settings, err := h.DataStore.Settings().Settings()
+	require.NoError(t, err)
+	settings.InternalAuthSettings.PasswordPolicy.MaxAgeDays = 30
+	require.NoError(t, h.DataStore.Settings().UpdateSettings(settings))
+
+	login := func() bool {
+		rec, resp := postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: testPassword})
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		tokenData, _, _, err := h.JWTService.ParseAndVerifyToken(resp.JWT)
+		require.NoError(t, err)
+
+		return tokenData.ForceChangePassword
+	}
+
+	// The age of a password set before the policy is counted from the first login
+	require.False(t, login())
+
+	user, err := h.DataStore.User().UserByUsername("alice")
+	require.NoError(t, err)
+	require.NotZero(t, user.PasswordChangedAt)
+
+	user.PasswordChangedAt = time.Now().AddDate(0, 0, -31).Unix()
+	require.NoError(t, h.DataStore.User().Update(user.ID, user))
+
+	require.True(t, login())
+}
@@ -22,11 +22,15 @@ type Handler struct {
 	JWTService                  portainer.JWTService
 	LDAPService                 portainer.LDAPService
 	OAuthService                portainer.OAuthService
+	SAMLService                 portainer.SAMLService
+	ClientCertificateService    portainer.ClientCertificateService
 	ProxyManager                *proxy.Manager
 	KubernetesTokenCacheManager *kubernetes.TokenCacheManager
 	KubernetesClientFactory     *cli.ClientFactory
 	passwordStrengthChecker     security.PasswordStrengthChecker
 	bouncer                     security.BouncerService
+	pendingLogins               *pendingTwoFactorLogins
+	loginGuard                  *security.LoginGuard
 }
 
 // NewHandler creates a handler to manage authentication operations.
@@ -36,14 +40,33 @@ func NewHandler(bouncer security.BouncerService, rateLimiter *security.RateLimit
 		passwordStrengthChecker: passwordStrengthChecker,
 		bouncer:                 bouncer,
 		KubernetesClientFactory: kubernetesClientFactory,
+		pendingLogins:           newPendingTwoFactorLogins(),
+		loginGuard:              security.NewLoginGuard(),
 	}
 
 	h.Handle("/auth/oauth/validate",
 		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.validateOAuth)))).Methods(http.MethodPost)
+	h.Handle("/auth/saml/metadata",
+		bouncer.PublicAccess(httperror.LoggerHandler(h.samlMetadata))).Methods(http.MethodGet)
+	h.Handle("/auth/saml/login",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.samlLogin)))).Methods(http.MethodGet)
+	h.Handle("/auth/saml/acs",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.samlAssertionConsumerService)))).Methods(http.MethodPost)
+	h.Handle("/auth/certificate",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticateCertificate)))).Methods(http.MethodPost)
 	h.Handle("/auth",
 		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticate)))).Methods(http.MethodPost)
+	h.Handle("/auth/2fa",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticateTwoFactor)))).Methods(http.MethodPost)
 	h.Handle("/auth/logout",
 		bouncer.PublicAccess(httperror.LoggerHandler(h.logout))).Methods(http.MethodPost)
 
+	adminRouter := h.NewRoute().Subrouter()
+	adminRouter.Use(bouncer.AdminAccess)
+
+	adminRouter.Handle("/auth/lockouts", httperror.LoggerHandler(h.lockoutList)).Methods(http.MethodGet)
+	adminRouter.Handle("/auth/lockouts/events", httperror.LoggerHandler(h.lockoutEvents)).Methods(http.MethodGet)
+	adminRouter.Handle("/auth/lockouts/{username}", httperror.LoggerHandler(h.lockoutUnlock)).Methods(http.MethodDelete)
+
 	return h
 }
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/portainer/portainer/api/http/security"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+
+	"github.com/pkg/errors"
+)
+
+// reserveLogin rejects the login when the username is locked or must wait before its next attempt, otherwise the
+// returned function must be called once the attempt is over
+func (handler *Handler) reserveLogin(w http.ResponseWriter, username string) (func(), *httperror.HandlerError) {
+	release, retryAfter, err := handler.loginGuard.Reserve(username)
+	if err == nil {
+		return release, nil
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	return nil, httperror.NewError(http.StatusTooManyRequests, "Too many failed login attempts", err)
+}
+
+// @id AuthLockoutList
+// @summary List the locked accounts
+// @description List the accounts locked after too many failed login attempts.
+// @description **Access policy**: administrator
+// @tags auth
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {array} security.LoginLockout "Success"
+// @failure 403 "Permission denied"
+// @router /auth/lockouts [get]
+func (handler *Handler) lockoutList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.loginGuard.Lockouts())
+}
+
+// @id AuthLockoutEvents
+// @summary List the lockout events
+// @description List the most recent account lockouts and unlocks, newest first.
+// @description **Access policy**: administrator
+// @tags auth
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {array} security.LoginEvent "Success"
+// @failure 403 "Permission denied"
+// @router /auth/lockouts/events [get]
+func (handler *Handler) lockoutEvents(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.loginGuard.Events())
+}
+
+// @id AuthLockoutUnlock
+// @summary Unlock an account
+// @description Unlock an account locked after too many failed login attempts.
+// @description **Access policy**: administrator
+// @tags auth
+// @security ApiKeyAuth
+// @security jwt
+// @param username path string true "Username of the locked account"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "The account is not locked"
+// @failure 500 "Server error"
+// @router /auth/lockouts/{username} [delete]
+func (handler *Handler) lockoutUnlock(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	username, err := request.RetrieveRouteVariableValue(r, "username")
+	if err != nil {
+		return httperror.BadRequest("Invalid username route variable", err)
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve user authentication token", err)
+	}
+
+	if !handler.loginGuard.Unlock(username, tokenData.Username) {
+		return httperror.NotFound("Unable to find a locked account with the specified username", errors.New("the account is not locked"))
+	}
+
+	return response.Empty(w)
+}
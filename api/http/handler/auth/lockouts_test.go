@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticateProgressiveDelay(t *testing.T) {
+	h := setupTwoFactorHandler(t, false)
+
+	for range 3 {
+		rec, _ := postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: "wrong-password"})
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	// Even the right password is rejected until the delay is over
+	rec, _ := postJSON(t, h, "/auth", authenticatePayload{Username: "Alice", Password: testPassword})
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Other usernames are not affected
+	rec, _ = postJSON(t, h, "/auth", authenticatePayload{Username: "bob", Password: "wrong-password"})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestAuthenticateLockoutAndUnlock(t *testing.T) {
+	h := setupTwoFactorHandler(t, false)
+
+	admin := &portainer.User{Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, h.DataStore.User().Create(admin))
+
+	adminJWT, _, err := h.JWTService.GenerateToken(&portainer.TokenData{ID: admin.ID, Username: admin.Username, Role: admin.Role})
+	require.NoError(t, err)
+
+	for range 10 {
+		h.loginGuard.Fail("alice", "203.0.113.5")
+	}
+
+	rec, _ := postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: testPassword})
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		testhelpers.AddTestSecurityCookie(req, adminJWT)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := do(http.MethodGet, "/auth/lockouts")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var lockouts []security.LoginLockout
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&lockouts))
+	require.Len(t, lockouts, 1)
+	require.Equal(t, "alice", lockouts[0].Username)
+	require.Equal(t, "203.0.113.5", lockouts[0].LastClientIP)
+
+	rr = do(http.MethodDelete, "/auth/lockouts/alice")
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = do(http.MethodDelete, "/auth/lockouts/alice")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = do(http.MethodGet, "/auth/lockouts/events")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var events []security.LoginEvent
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&events))
+	require.Len(t, events, 2)
+	require.Equal(t, security.LoginEventUnlocked, events[0].Type)
+	require.Equal(t, "admin", events[0].UnlockedBy)
+
+	rec, resp := postJSON(t, h, "/auth", authenticatePayload{Username: "alice", Password: testPassword})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, resp.JWT)
+}
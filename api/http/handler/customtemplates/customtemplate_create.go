@@ -9,6 +9,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/git"
 	gittypes "github.com/portainer/portainer/api/git/types"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
@@ -203,6 +204,8 @@ type customTemplateFromGitRepositoryPayload struct {
 	RepositoryURL string `example:"https://github.com/openfaas/faas" validate:"required"`
 	// Reference name of a Git repository hosting the Stack file
 	RepositoryReferenceName string `example:"refs/heads/master"`
+	// Semver constraint of the tags to track instead of the reference name, the highest matching tag is used
+	RepositoryTagConstraint string `example:"~1.4"`
 	// Use basic authentication to clone the Git repository
 	RepositoryAuthentication bool `example:"true"`
 	// Username used in basic authentication. Required when RepositoryAuthentication is true.
@@ -234,6 +237,9 @@ func (payload *customTemplateFromGitRepositoryPayload) Validate(r *http.Request)
 	if payload.RepositoryAuthentication && (len(payload.RepositoryUsername) == 0 || len(payload.RepositoryPassword) == 0) {
 		return errors.New("Invalid repository credentials. Username and password must be specified when authentication is enabled")
 	}
+	if payload.RepositoryTagConstraint != "" && git.ValidateTagConstraint(payload.RepositoryTagConstraint) != nil {
+		return errors.New("Invalid repository tag constraint. Must correspond to a valid semver constraint")
+	}
 	if len(payload.ComposeFilePathInRepository) == 0 {
 		payload.ComposeFilePathInRepository = filesystem.ComposeFileDefaultName
 	}
@@ -295,6 +301,7 @@ func (handler *Handler) createCustomTemplateFromGitRepository(r *http.Request) (
 	gitConfig := &gittypes.RepoConfig{
 		URL:            payload.RepositoryURL,
 		ReferenceName:  payload.RepositoryReferenceName,
+		TagConstraint:  payload.RepositoryTagConstraint,
 		ConfigFilePath: payload.ComposeFilePathInRepository,
 		TLSSkipVerify:  payload.TLSSkipVerify,
 	}
@@ -306,7 +313,7 @@ func (handler *Handler) createCustomTemplateFromGitRepository(r *http.Request) (
 		}
 	}
 
-	commitHash, err := stackutils.DownloadGitRepository(*gitConfig, handler.GitService, getProjectPath)
+	commitHash, err := stackutils.DownloadGitRepository(gitConfig, handler.GitService, getProjectPath)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +63,9 @@ func (handler *Handler) customTemplateGitFetch(w http.ResponseWriter, r *http.Re
 	// remove backup custom template folder
 	defer cleanUpBackupCustomTemplate(backupPath)
 
-	commitHash, err := stackutils.DownloadGitRepository(*customTemplate.GitConfig, handler.GitService, func() string {
+	referenceName := customTemplate.GitConfig.ReferenceName
+
+	commitHash, err := stackutils.DownloadGitRepository(customTemplate.GitConfig, handler.GitService, func() string {
 		return customTemplate.ProjectPath
 	})
 	if err != nil {
@@ -76,7 +78,7 @@ func (handler *Handler) customTemplateGitFetch(w http.ResponseWriter, r *http.Re
 		return httperror.InternalServerError("Failed to download git repository", err)
 	}
 
-	if customTemplate.GitConfig.ConfigHash != commitHash {
+	if customTemplate.GitConfig.ConfigHash != commitHash || customTemplate.GitConfig.ReferenceName != referenceName {
 		customTemplate.GitConfig.ConfigHash = commitHash
 
 		if err := handler.DataStore.CustomTemplate().Update(customTemplate.ID, customTemplate); err != nil {
@@ -37,6 +37,8 @@ type customTemplateUpdatePayload struct {
 	RepositoryURL string `example:"https://github.com/openfaas/faas" validate:"required"`
 	// Reference name of a Git repository hosting the Stack file
 	RepositoryReferenceName string `example:"refs/heads/master"`
+	// Semver constraint of the tags to track instead of the reference name, the highest matching tag is used
+	RepositoryTagConstraint string `example:"~1.4"`
 	// Use basic authentication to clone the Git repository
 	RepositoryAuthentication bool `example:"true"`
 	// Username used in basic authentication. Required when RepositoryAuthentication is true
@@ -79,6 +81,10 @@ func (payload *customTemplateUpdatePayload) Validate(r *http.Request) error {
 		return errors.New("Invalid custom template type")
 	}
 
+	if payload.RepositoryTagConstraint != "" && git.ValidateTagConstraint(payload.RepositoryTagConstraint) != nil {
+		return errors.New("Invalid repository tag constraint. Must correspond to a valid semver constraint")
+	}
+
 	if len(payload.Description) == 0 {
 		return errors.New("Invalid custom template description")
 	}
@@ -176,6 +182,7 @@ func (handler *Handler) customTemplateUpdate(w http.ResponseWriter, r *http.Requ
 		gitConfig := &gittypes.RepoConfig{
 			URL:            payload.RepositoryURL,
 			ReferenceName:  payload.RepositoryReferenceName,
+			TagConstraint:  payload.RepositoryTagConstraint,
 			ConfigFilePath: payload.ComposeFilePathInRepository,
 			TLSSkipVerify:  payload.TLSSkipVerify,
 		}
@@ -191,6 +198,10 @@ func (handler *Handler) customTemplateUpdate(w http.ResponseWriter, r *http.Requ
 			}
 		}
 
+		if _, err := git.ResolveTagConstraint(handler.GitService, gitConfig, repositoryUsername, repositoryPassword); err != nil {
+			return httperror.InternalServerError("Unable to resolve the repository tag constraint", err)
+		}
+
 		cleanBackup, err := git.CloneWithBackup(handler.GitService, handler.FileService, git.CloneOptions{
 			ProjectPath:   customTemplate.ProjectPath,
 			URL:           gitConfig.URL,
@@ -0,0 +1,116 @@
+package deploymentgroups
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/stacks/deploymentgroups"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+type deploymentGroupCreatePayload struct {
+	// Deployment group name
+	Name string `validate:"required" example:"shop"`
+	// Stacks of the group along with the stacks of the group they depend on
+	Stacks []portainer.DeploymentGroupStack `validate:"required"`
+}
+
+func (payload *deploymentGroupCreatePayload) Validate(r *http.Request) error {
+	if len(payload.Name) == 0 {
+		return errors.New("invalid deployment group name")
+	}
+
+	return validateStacks(payload.Stacks)
+}
+
+func validateStacks(stacks []portainer.DeploymentGroupStack) error {
+	if len(stacks) == 0 {
+		return errors.New("a deployment group requires at least one stack")
+	}
+
+	_, err := deploymentgroups.Order(stacks)
+
+	return err
+}
+
+// @id DeploymentGroupCreate
+// @summary Create a deployment group
+// @description Create a group of Compose and Swarm stacks deployed in the order of their dependencies.
+// @description **Access policy**: administrator
+// @tags deployment_groups
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param body body deploymentGroupCreatePayload true "Deployment group details"
+// @success 200 {object} portainer.DeploymentGroup "Success"
+// @failure 400 "Invalid request"
+// @failure 409 "This name is already associated to a deployment group"
+// @failure 500 "Server error"
+// @router /deployment_groups [post]
+func (handler *Handler) deploymentGroupCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload deploymentGroupCreatePayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	group := &portainer.DeploymentGroup{
+		Name:   payload.Name,
+		Stacks: payload.Stacks,
+	}
+
+	err := handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		if err := checkUniqueName(tx, group); err != nil {
+			return err
+		}
+
+		if err := checkStacks(tx, group.Stacks); err != nil {
+			return err
+		}
+
+		if err := tx.DeploymentGroup().Create(group); err != nil {
+			return httperror.InternalServerError("Unable to persist the deployment group inside the database", err)
+		}
+
+		return nil
+	})
+
+	return txResponse(w, group, err)
+}
+
+func checkUniqueName(tx dataservices.DataStoreTx, group *portainer.DeploymentGroup) error {
+	groups, err := tx.DeploymentGroup().ReadAll()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the deployment groups from the database", err)
+	}
+
+	for _, existing := range groups {
+		if existing.ID != group.ID && existing.Name == group.Name {
+			return httperror.Conflict("This name is already associated to a deployment group", errors.New("a deployment group already exists with this name"))
+		}
+	}
+
+	return nil
+}
+
+// checkStacks verifies that the stacks of the group exist and are Compose or Swarm stacks
+func checkStacks(tx dataservices.DataStoreTx, stacks []portainer.DeploymentGroupStack) error {
+	for _, groupStack := range stacks {
+		stack, err := tx.Stack().Read(groupStack.StackID)
+		if tx.IsErrObjectNotFound(err) {
+			return httperror.BadRequest("Unable to find a stack of the deployment group", fmt.Errorf("the stack %d does not exist", groupStack.StackID))
+		} else if err != nil {
+			return httperror.InternalServerError("Unable to find a stack of the deployment group", err)
+		}
+
+		if stack.Type != portainer.DockerComposeStack && stack.Type != portainer.DockerSwarmStack {
+			return httperror.BadRequest("Only Compose and Swarm stacks can be part of a deployment group", fmt.Errorf("the stack %s is neither a Compose nor a Swarm stack", stack.Name))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package deploymentgroups
+
+import (
+	"errors"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+// @id DeploymentGroupDelete
+// @summary Remove a deployment group
+// @description Remove a deployment group, its stacks are left untouched.
+// @description **Access policy**: administrator
+// @tags deployment_groups
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Deployment group identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Deployment group not found"
+// @failure 500 "Server error"
+// @router /deployment_groups/{id} [delete]
+func (handler *Handler) deploymentGroupDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	id, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid deployment group identifier route variable", err)
+	}
+
+	err = handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		if _, err := readDeploymentGroup(tx, portainer.DeploymentGroupID(id)); err != nil {
+			return err
+		}
+
+		if err := tx.DeploymentGroup().Delete(portainer.DeploymentGroupID(id)); err != nil {
+			return httperror.InternalServerError("Unable to remove the deployment group from the database", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		var handlerError *httperror.HandlerError
+		if errors.As(err, &handlerError) {
+			return handlerError
+		}
+
+		return httperror.InternalServerError("Unexpected error", err)
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,114 @@
+package deploymentgroups
+
+import (
+	"errors"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/deploymentgroups"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+// @id DeploymentGroupDeploy
+// @summary Deploy a deployment group
+// @description Start the deployment of the stacks of a deployment group in the order of their dependencies. Each stack
+// @description must run with its health checks passing before the stacks depending on it are deployed, the deployment
+// @description is aborted at the first failure. The deployment runs in the background, the run in progress is returned
+// @description and stored as the last run of the group, where it is replaced by the result of the deployment once it ends.
+// @description **Access policy**: administrator
+// @tags deployment_groups
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Deployment group identifier"
+// @success 202 {object} portainer.DeploymentGroupRun "Deployment started"
+// @failure 400 "Invalid request"
+// @failure 404 "Deployment group not found"
+// @failure 409 "The deployment group is already being deployed or torn down"
+// @failure 500 "Server error"
+// @router /deployment_groups/{id}/deploy [post]
+func (handler *Handler) deploymentGroupDeploy(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	group, httpErr := handler.retrieveDeploymentGroup(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	// the deployment waits for every stack to run, it outlives the request
+	run, err := handler.DeploymentGroupService.StartDeploy(group, securityContext)
+	if err != nil {
+		return runResponse(w, nil, err)
+	}
+
+	return response.JSONWithStatus(w, run, http.StatusAccepted)
+}
+
+// @id DeploymentGroupTeardown
+// @summary Tear down a deployment group
+// @description Stop the stacks of a deployment group in the reverse order of their dependencies, the stacks and their
+// @description files are kept so that the group can be deployed again. The teardown is aborted at the first failure.
+// @description **Access policy**: administrator
+// @tags deployment_groups
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Deployment group identifier"
+// @success 200 {object} portainer.DeploymentGroupRun "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Deployment group not found"
+// @failure 409 "The deployment group is already being deployed or torn down"
+// @failure 500 "Server error"
+// @router /deployment_groups/{id}/teardown [post]
+func (handler *Handler) deploymentGroupTeardown(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	group, httpErr := handler.retrieveDeploymentGroup(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	run, err := handler.DeploymentGroupService.Teardown(group)
+
+	return runResponse(w, run, err)
+}
+
+func (handler *Handler) retrieveDeploymentGroup(r *http.Request) (*portainer.DeploymentGroup, *httperror.HandlerError) {
+	id, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, httperror.BadRequest("Invalid deployment group identifier route variable", err)
+	}
+
+	var group *portainer.DeploymentGroup
+	err = handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		group, err = readDeploymentGroup(tx, portainer.DeploymentGroupID(id))
+
+		return err
+	})
+	if err != nil {
+		var handlerError *httperror.HandlerError
+		if errors.As(err, &handlerError) {
+			return nil, handlerError
+		}
+
+		return nil, httperror.InternalServerError("Unexpected error", err)
+	}
+
+	return group, nil
+}
+
+func runResponse(w http.ResponseWriter, run *portainer.DeploymentGroupRun, err error) *httperror.HandlerError {
+	switch {
+	case errors.Is(err, deploymentgroups.ErrGroupRunning):
+		return httperror.Conflict("The deployment group is already being deployed or torn down", err)
+	case err != nil:
+		return httperror.InternalServerError("Unable to run the deployment group", err)
+	}
+
+	return response.JSON(w, run)
+}
@@ -0,0 +1,51 @@
+package deploymentgroups
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+// @id DeploymentGroupInspect
+// @summary Inspect a deployment group
+// @description Retrieve details about a deployment group along with the result of its last deployment or teardown.
+// @description **Access policy**: administrator
+// @tags deployment_groups
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Deployment group identifier"
+// @success 200 {object} portainer.DeploymentGroup "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Deployment group not found"
+// @failure 500 "Server error"
+// @router /deployment_groups/{id} [get]
+func (handler *Handler) deploymentGroupInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	id, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid deployment group identifier route variable", err)
+	}
+
+	var group *portainer.DeploymentGroup
+	err = handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		group, err = readDeploymentGroup(tx, portainer.DeploymentGroupID(id))
+
+		return err
+	})
+
+	return txResponse(w, group, err)
+}
+
+func readDeploymentGroup(tx dataservices.DataStoreTx, id portainer.DeploymentGroupID) (*portainer.DeploymentGroup, error) {
+	group, err := tx.DeploymentGroup().Read(id)
+	if tx.IsErrObjectNotFound(err) {
+		return nil, httperror.NotFound("Unable to find a deployment group with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, httperror.InternalServerError("Unable to find a deployment group with the specified identifier inside the database", err)
+	}
+
+	return group, nil
+}
@@ -0,0 +1,35 @@
+package deploymentgroups
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+)
+
+// @id DeploymentGroupList
+// @summary List deployment groups
+// @description List the deployment groups.
+// @description **Access policy**: administrator
+// @tags deployment_groups
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {array} portainer.DeploymentGroup "Success"
+// @failure 500 "Server error"
+// @router /deployment_groups [get]
+func (handler *Handler) deploymentGroupList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var groups []portainer.DeploymentGroup
+	err := handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		var err error
+		groups, err = tx.DeploymentGroup().ReadAll()
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve the deployment groups from the database", err)
+		}
+
+		return nil
+	})
+
+	return txResponse(w, groups, err)
+}
@@ -0,0 +1,84 @@
+package deploymentgroups
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeploymentGroupOperations(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 1, Name: "database", Type: portainer.DockerComposeStack}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 2, Name: "backend", Type: portainer.DockerSwarmStack}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 3, Name: "monitoring", Type: portainer.KubernetesStack}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+
+	do := func(method, path string, body any, result any) int {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(method, path, bytes.NewReader(data))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		if result != nil && rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(result))
+		}
+
+		return rec.Code
+	}
+
+	var group portainer.DeploymentGroup
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/deployment_groups", deploymentGroupCreatePayload{
+		Name:   "shop",
+		Stacks: []portainer.DeploymentGroupStack{{StackID: 2, DependsOn: []portainer.StackID{1}}, {StackID: 1}},
+	}, &group))
+	require.Equal(t, "shop", group.Name)
+
+	require.Equal(t, http.StatusConflict, do(http.MethodPost, "/deployment_groups", deploymentGroupCreatePayload{
+		Name:   "shop",
+		Stacks: []portainer.DeploymentGroupStack{{StackID: 1}},
+	}, nil))
+
+	for name, stacks := range map[string][]portainer.DeploymentGroupStack{
+		"no stack":            nil,
+		"cycle":               {{StackID: 1, DependsOn: []portainer.StackID{2}}, {StackID: 2, DependsOn: []portainer.StackID{1}}},
+		"unknown dependency":  {{StackID: 1, DependsOn: []portainer.StackID{2}}},
+		"missing stack":       {{StackID: 4}},
+		"not a compose stack": {{StackID: 3}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/deployment_groups", deploymentGroupCreatePayload{
+				Name:   "other",
+				Stacks: stacks,
+			}, nil))
+		})
+	}
+
+	name := "store"
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/deployment_groups/1", deploymentGroupUpdatePayload{
+		Name:   &name,
+		Stacks: []portainer.DeploymentGroupStack{{StackID: 1}},
+	}, &group))
+	require.Equal(t, "store", group.Name)
+	require.Equal(t, []portainer.DeploymentGroupStack{{StackID: 1}}, group.Stacks)
+
+	var groups []portainer.DeploymentGroup
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/deployment_groups", nil, &groups))
+	require.Len(t, groups, 1)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/deployment_groups/1", nil, nil))
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/deployment_groups/1", nil, nil))
+}
@@ -0,0 +1,86 @@
+package deploymentgroups
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+type deploymentGroupUpdatePayload struct {
+	// Deployment group name
+	Name *string `example:"shop"`
+	// Stacks of the group, replacing the current ones
+	Stacks []portainer.DeploymentGroupStack
+}
+
+func (payload *deploymentGroupUpdatePayload) Validate(r *http.Request) error {
+	if payload.Stacks == nil {
+		return nil
+	}
+
+	return validateStacks(payload.Stacks)
+}
+
+// @id DeploymentGroupUpdate
+// @summary Update a deployment group
+// @description Update the name or the stacks of a deployment group.
+// @description **Access policy**: administrator
+// @tags deployment_groups
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Deployment group identifier"
+// @param body body deploymentGroupUpdatePayload true "Deployment group details"
+// @success 200 {object} portainer.DeploymentGroup "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Deployment group not found"
+// @failure 409 "This name is already associated to a deployment group"
+// @failure 500 "Server error"
+// @router /deployment_groups/{id} [put]
+func (handler *Handler) deploymentGroupUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	id, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid deployment group identifier route variable", err)
+	}
+
+	var payload deploymentGroupUpdatePayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	var group *portainer.DeploymentGroup
+	err = handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		group, err = readDeploymentGroup(tx, portainer.DeploymentGroupID(id))
+		if err != nil {
+			return err
+		}
+
+		if payload.Name != nil && *payload.Name != "" {
+			group.Name = *payload.Name
+
+			if err := checkUniqueName(tx, group); err != nil {
+				return err
+			}
+		}
+
+		if payload.Stacks != nil {
+			if err := checkStacks(tx, payload.Stacks); err != nil {
+				return err
+			}
+
+			group.Stacks = payload.Stacks
+		}
+
+		if err := tx.DeploymentGroup().Update(group.ID, group); err != nil {
+			return httperror.InternalServerError("Unable to persist the deployment group changes inside the database", err)
+		}
+
+		return nil
+	})
+
+	return txResponse(w, group, err)
+}
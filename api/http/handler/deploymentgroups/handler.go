@@ -0,0 +1,58 @@
+package deploymentgroups
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/deploymentgroups"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+
+	"github.com/gorilla/mux"
+)
+
+// Handler is the HTTP handler used to handle deployment group operations.
+type Handler struct {
+	*mux.Router
+	DataStore              dataservices.DataStore
+	DeploymentGroupService *deploymentgroups.Service
+}
+
+// NewHandler creates a handler to manage deployment group operations.
+func NewHandler(bouncer security.BouncerService) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+
+	h.Handle("/deployment_groups",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.deploymentGroupCreate))).Methods(http.MethodPost)
+	h.Handle("/deployment_groups",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.deploymentGroupList))).Methods(http.MethodGet)
+	h.Handle("/deployment_groups/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.deploymentGroupInspect))).Methods(http.MethodGet)
+	h.Handle("/deployment_groups/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.deploymentGroupUpdate))).Methods(http.MethodPut)
+	h.Handle("/deployment_groups/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.deploymentGroupDelete))).Methods(http.MethodDelete)
+	h.Handle("/deployment_groups/{id}/deploy",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.deploymentGroupDeploy))).Methods(http.MethodPost)
+	h.Handle("/deployment_groups/{id}/teardown",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.deploymentGroupTeardown))).Methods(http.MethodPost)
+
+	return h
+}
+
+func txResponse(w http.ResponseWriter, r any, err error) *httperror.HandlerError {
+	if err != nil {
+		var handlerError *httperror.HandlerError
+		if errors.As(err, &handlerError) {
+			return handlerError
+		}
+
+		return httperror.InternalServerError("Unexpected error", err)
+	}
+
+	return response.JSON(w, r)
+}
@@ -3,6 +3,7 @@ package edgegroups
 import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/edge"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 )
 
@@ -49,6 +50,34 @@ func GetEndpointsByTags(tx dataservices.DataStoreTx, tagIDs []portainer.TagID, p
 	return results, nil
 }
 
+// GetDynamicEdgeGroupEndpoints returns the trusted Edge environments matched by the tags and the selector of a dynamic Edge group
+func GetDynamicEdgeGroupEndpoints(tx dataservices.DataStoreTx, edgeGroup *portainer.EdgeGroup) ([]portainer.EndpointID, error) {
+	if edgeGroup.Selector == nil {
+		return GetEndpointsByTags(tx, edgeGroup.TagIDs, edgeGroup.PartialMatch)
+	}
+
+	endpoints, err := tx.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	endpointGroups, err := tx.EndpointGroup().ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	relatedEndpoints := endpointutils.EndpointSet(edge.EdgeGroupRelatedEndpoints(edgeGroup, endpoints, endpointGroups))
+
+	results := []portainer.EndpointID{}
+	for _, endpoint := range endpoints {
+		if relatedEndpoints[endpoint.ID] && endpoint.UserTrusted {
+			results = append(results, endpoint.ID)
+		}
+	}
+
+	return results, nil
+}
+
 func getTrustedEndpoints(tx dataservices.DataStoreTx, endpointIDs []portainer.EndpointID) ([]portainer.EndpointID, error) {
 	results := []portainer.EndpointID{}
 	for _, endpointID := range endpointIDs {
@@ -6,6 +6,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/edge"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
@@ -17,6 +18,8 @@ type edgeGroupCreatePayload struct {
 	TagIDs       []portainer.TagID
 	Endpoints    []portainer.EndpointID
 	PartialMatch bool
+	// Selector matching the environments of a dynamic Edge group, combined with the tags when both are provided
+	Selector *portainer.EdgeGroupSelector
 }
 
 func (payload *edgeGroupCreatePayload) Validate(r *http.Request) error {
@@ -24,20 +27,39 @@ func (payload *edgeGroupCreatePayload) Validate(r *http.Request) error {
 		return errors.New("invalid Edge group name")
 	}
 
-	if payload.Dynamic && len(payload.TagIDs) == 0 {
-		return errors.New("tagIDs is mandatory for a dynamic Edge group")
+	return validateDynamicMembership(payload.Dynamic, payload.TagIDs, payload.Selector)
+}
+
+func validateDynamicMembership(dynamic bool, tagIDs []portainer.TagID, selector *portainer.EdgeGroupSelector) error {
+	if !dynamic {
+		return nil
+	}
+
+	if len(tagIDs) == 0 && selector == nil {
+		return errors.New("tagIDs or selector is mandatory for a dynamic Edge group")
+	}
+
+	if selector != nil {
+		return edge.ValidateEdgeGroupSelector(selector)
 	}
 
 	return nil
 }
 
-func calculateEndpointsOrTags(tx dataservices.DataStoreTx, edgeGroup *portainer.EdgeGroup, endpoints []portainer.EndpointID, tagIDs []portainer.TagID) error {
+func calculateEndpointsOrTags(tx dataservices.DataStoreTx, edgeGroup *portainer.EdgeGroup, endpoints []portainer.EndpointID, tagIDs []portainer.TagID, selector *portainer.EdgeGroupSelector) error {
 	if edgeGroup.Dynamic {
 		edgeGroup.TagIDs = tagIDs
+		if edgeGroup.TagIDs == nil {
+			edgeGroup.TagIDs = []portainer.TagID{}
+		}
+
+		edgeGroup.Selector = selector
 
 		return nil
 	}
 
+	edgeGroup.Selector = nil
+
 	endpointIDs := []portainer.EndpointID{}
 
 	for _, endpointID := range endpoints {
@@ -97,7 +119,7 @@ func (handler *Handler) edgeGroupCreate(w http.ResponseWriter, r *http.Request)
 			PartialMatch: payload.PartialMatch,
 		}
 
-		if err := calculateEndpointsOrTags(tx, edgeGroup, payload.Endpoints, payload.TagIDs); err != nil {
+		if err := calculateEndpointsOrTags(tx, edgeGroup, payload.Endpoints, payload.TagIDs, payload.Selector); err != nil {
 			return err
 		}
 
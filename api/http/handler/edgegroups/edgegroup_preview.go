@@ -0,0 +1,83 @@
+package edgegroups
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+type edgeGroupPreviewPayload struct {
+	TagIDs       []portainer.TagID
+	PartialMatch bool
+	Selector     *portainer.EdgeGroupSelector
+}
+
+func (payload *edgeGroupPreviewPayload) Validate(r *http.Request) error {
+	return validateDynamicMembership(true, payload.TagIDs, payload.Selector)
+}
+
+type edgeGroupPreviewEnvironment struct {
+	ID      portainer.EndpointID      `json:"Id" example:"1"`
+	Name    string                    `json:"Name" example:"my-environment"`
+	Type    portainer.EndpointType    `json:"Type" example:"4"`
+	GroupID portainer.EndpointGroupID `json:"GroupId" example:"1"`
+}
+
+// @id EdgeGroupPreview
+// @summary Preview the environments matched by a dynamic EdgeGroup
+// @description Returns the environments that would be members of a dynamic Edge group with the given tags and selector, without saving anything.
+// @description **Access policy**: administrator
+// @tags edge_groups
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param body body edgeGroupPreviewPayload true "Dynamic EdgeGroup membership"
+// @success 200 {array} edgeGroupPreviewEnvironment
+// @failure 400
+// @failure 503 "Edge compute features are disabled"
+// @failure 500
+// @router /edge_groups/preview [post]
+func (handler *Handler) edgeGroupPreview(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload edgeGroupPreviewPayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	edgeGroup := &portainer.EdgeGroup{
+		Dynamic:      true,
+		TagIDs:       payload.TagIDs,
+		PartialMatch: payload.PartialMatch,
+		Selector:     payload.Selector,
+	}
+
+	var environments []edgeGroupPreviewEnvironment
+	err := handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		endpointIDs, err := GetDynamicEdgeGroupEndpoints(tx, edgeGroup)
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve environments matching the Edge group", err)
+		}
+
+		environments = make([]edgeGroupPreviewEnvironment, 0, len(endpointIDs))
+		for _, endpointID := range endpointIDs {
+			endpoint, err := tx.Endpoint().Endpoint(endpointID)
+			if err != nil {
+				return httperror.InternalServerError("Unable to retrieve environment from the database", err)
+			}
+
+			environments = append(environments, edgeGroupPreviewEnvironment{
+				ID:      endpoint.ID,
+				Name:    endpoint.Name,
+				Type:    endpoint.Type,
+				GroupID: endpoint.GroupID,
+			})
+		}
+
+		return nil
+	})
+
+	return txResponse(w, environments, err)
+}
@@ -21,14 +21,12 @@ type edgeGroupUpdatePayload struct {
 	TagIDs       []portainer.TagID
 	Endpoints    []portainer.EndpointID
 	PartialMatch *bool
+	// Selector matching the environments of a dynamic Edge group, combined with the tags when both are provided
+	Selector *portainer.EdgeGroupSelector
 }
 
 func (payload *edgeGroupUpdatePayload) Validate(r *http.Request) error {
-	if payload.Dynamic && len(payload.TagIDs) == 0 {
-		return errors.New("tagIDs is mandatory for a dynamic Edge group")
-	}
-
-	return nil
+	return validateDynamicMembership(payload.Dynamic, payload.TagIDs, payload.Selector)
 }
 
 // @id EdgeGroupUpdate
@@ -93,7 +91,7 @@ func (handler *Handler) edgeGroupUpdate(w http.ResponseWriter, r *http.Request)
 		oldRelatedEndpoints := edge.EdgeGroupRelatedEndpoints(edgeGroup, endpoints, endpointGroups)
 
 		edgeGroup.Dynamic = payload.Dynamic
-		if err := calculateEndpointsOrTags(tx, edgeGroup, payload.Endpoints, payload.TagIDs); err != nil {
+		if err := calculateEndpointsOrTags(tx, edgeGroup, payload.Endpoints, payload.TagIDs, payload.Selector); err != nil {
 			return err
 		}
 
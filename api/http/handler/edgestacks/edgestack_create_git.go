@@ -7,6 +7,7 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/git"
 	gittypes "github.com/portainer/portainer/api/git/types"
 	httperrors "github.com/portainer/portainer/api/http/errors"
 	"github.com/portainer/portainer/pkg/edge"
@@ -27,6 +28,8 @@ type edgeStackFromGitRepositoryPayload struct {
 	RepositoryURL string `example:"https://github.com/openfaas/faas" validate:"required"`
 	// Reference name of a Git repository hosting the Stack file
 	RepositoryReferenceName string `example:"refs/heads/master"`
+	// Semver constraint of the tags to deploy instead of the reference name, the highest matching tag is deployed
+	RepositoryTagConstraint string `example:"~1.4"`
 	// Use basic authentication to clone the Git repository
 	RepositoryAuthentication bool `example:"true"`
 	// Username used in basic authentication. Required when RepositoryAuthentication is true.
@@ -67,6 +70,10 @@ func (payload *edgeStackFromGitRepositoryPayload) Validate(r *http.Request) erro
 		return httperrors.NewInvalidPayloadError("Invalid repository credentials. Password must be specified when authentication is enabled")
 	}
 
+	if payload.RepositoryTagConstraint != "" && git.ValidateTagConstraint(payload.RepositoryTagConstraint) != nil {
+		return httperrors.NewInvalidPayloadError("Invalid repository tag constraint. Must correspond to a valid semver constraint")
+	}
+
 	if payload.DeploymentType != portainer.EdgeStackDeploymentCompose && payload.DeploymentType != portainer.EdgeStackDeploymentKubernetes {
 		return httperrors.NewInvalidPayloadError("Invalid deployment type")
 	}
@@ -119,6 +126,7 @@ func (handler *Handler) createEdgeStackFromGitRepository(r *http.Request, tx dat
 	repoConfig := gittypes.RepoConfig{
 		URL:            payload.RepositoryURL,
 		ReferenceName:  payload.RepositoryReferenceName,
+		TagConstraint:  payload.RepositoryTagConstraint,
 		ConfigFilePath: payload.FilePathInRepository,
 		TLSSkipVerify:  payload.TLSSkipVerify,
 	}
@@ -130,6 +138,17 @@ func (handler *Handler) createEdgeStackFromGitRepository(r *http.Request, tx dat
 		}
 	}
 
+	username, password, err := git.GetCredentials(repoConfig.Authentication)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := git.ResolveTagConstraint(handler.GitService, &repoConfig, username, password); err != nil {
+		return nil, errors.WithMessage(err, "unable to resolve the repository tag constraint")
+	}
+
+	stack.GitReferenceName = repoConfig.ReferenceName
+
 	return handler.edgeStacksService.PersistEdgeStack(tx, stack, func(stackFolder string, relatedEndpointIds []portainer.EndpointID) (composePath string, manifestPath string, projectPath string, err error) {
 		return handler.storeManifestFromGitRepository(tx, stackFolder, relatedEndpointIds, payload.DeploymentType, userID, repoConfig)
 	})
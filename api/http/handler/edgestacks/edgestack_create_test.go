@@ -211,3 +211,56 @@ func TestCreateWithInvalidPayload(t *testing.T) {
 		})
 	}
 }
+
+type tagGitService struct {
+	portainer.GitService
+	refs       []string
+	clonedRefs []string
+}
+
+func (g *tagGitService) ListRefs(repositoryURL, username, password string, hardRefresh bool, tlsSkipVerify bool) ([]string, error) {
+	return g.refs, nil
+}
+
+func (g *tagGitService) CloneRepository(destination, repositoryURL, referenceName, username, password string, tlsSkipVerify bool) error {
+	g.clonedRefs = append(g.clonedRefs, referenceName)
+
+	return nil
+}
+
+func TestCreateFromRepositoryWithTagConstraint(t *testing.T) {
+	handler, rawAPIKey := setupHandler(t)
+
+	gitService := &tagGitService{refs: []string{"refs/heads/main", "refs/tags/v1.4.0", "refs/tags/v1.4.2", "refs/tags/v1.5.0"}}
+	handler.GitService = gitService
+
+	endpoint := createEndpoint(t, handler.DataStore)
+	err := handler.DataStore.EdgeGroup().Create(&portainer.EdgeGroup{ID: 1, Name: "EdgeGroup 1", Endpoints: []portainer.EndpointID{endpoint.ID}})
+	require.NoError(t, err)
+
+	err = handler.DataStore.EndpointRelation().Create(&portainer.EndpointRelation{EndpointID: endpoint.ID, EdgeStacks: map[portainer.EdgeStackID]bool{}})
+	require.NoError(t, err)
+
+	jsonPayload, err := json.Marshal(edgeStackFromGitRepositoryPayload{
+		Name:                    "test-stack",
+		RepositoryURL:           "https://github.com/portainer/portainer",
+		RepositoryTagConstraint: "~1.4",
+		FilePathInRepository:    "docker-compose.yml",
+		EdgeGroups:              []portainer.EdgeGroupID{1},
+		DeploymentType:          portainer.EdgeStackDeploymentCompose,
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/edge_stacks/create/repository", bytes.NewBuffer(jsonPayload))
+	require.NoError(t, err)
+
+	req.Header.Add("x-api-key", rawAPIKey)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var edgeStack portainer.EdgeStack
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&edgeStack))
+	require.Equal(t, "refs/tags/v1.4.2", edgeStack.GitReferenceName)
+	require.Equal(t, []string{"refs/tags/v1.4.2"}, gitService.clonedRefs)
+}
@@ -10,6 +10,8 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/edge/cache"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
 	"github.com/portainer/portainer/pkg/libhttp/response"
@@ -116,10 +118,6 @@ func (handler *Handler) edgeStackStatusUpdate(w http.ResponseWriter, r *http.Req
 }
 
 func (handler *Handler) updateEdgeStackStatus(tx dataservices.DataStoreTx, stack *portainer.EdgeStack, stackID portainer.EdgeStackID, payload updateStatusPayload) error {
-	if payload.Version > 0 && payload.Version < stack.Version {
-		return nil
-	}
-
 	status := *payload.Status
 
 	deploymentStatus := portainer.EdgeStackDeploymentStatus{
@@ -129,6 +127,10 @@ func (handler *Handler) updateEdgeStackStatus(tx dataservices.DataStoreTx, stack
 	}
 
 	if deploymentStatus.Type == portainer.EdgeStackStatusRemoved {
+		if payload.Version > 0 && payload.Version < stack.Version {
+			return nil
+		}
+
 		return tx.EdgeStackStatus().Delete(stackID, payload.EndpointID)
 	}
 
@@ -142,11 +144,48 @@ func (handler *Handler) updateEdgeStackStatus(tx dataservices.DataStoreTx, stack
 		}
 	}
 
+	rollbackTo := edge.EdgeStackRollbackTarget(environmentStatus)
+	isRollbackStatus := rollbackTo != nil && payload.Version == *rollbackTo
+
+	if payload.Version > 0 && payload.Version < stack.Version && !isRollbackStatus {
+		return nil
+	}
+
+	if isRollbackStatus && deploymentStatus.Type == portainer.EdgeStackStatusRunning {
+		deploymentStatus.Type = portainer.EdgeStackStatusRolledBack
+		deploymentStatus.RollbackTo = rollbackTo
+		deploymentStatus.Version = stack.Version
+	}
+
+	appendDeploymentStatus(environmentStatus, deploymentStatus)
+
+	if deploymentStatus.Type == portainer.EdgeStackStatusError && rollbackTo == nil && canRollback(stack) {
+		previousVersion := stack.PreviousVersion
+
+		appendDeploymentStatus(environmentStatus, portainer.EdgeStackDeploymentStatus{
+			Type:       portainer.EdgeStackStatusRollingBack,
+			Time:       payload.Time,
+			RollbackTo: &previousVersion,
+			Version:    stack.Version,
+		})
+
+		// the environment needs to be told about the rollback on its next poll
+		cache.Del(payload.EndpointID)
+	}
+
+	return tx.EdgeStackStatus().Update(stackID, payload.EndpointID, environmentStatus)
+}
+
+func appendDeploymentStatus(environmentStatus *portainer.EdgeStackStatusForEnv, deploymentStatus portainer.EdgeStackDeploymentStatus) {
 	if containsStatus := slices.ContainsFunc(environmentStatus.Status, func(e portainer.EdgeStackDeploymentStatus) bool {
 		return e.Type == deploymentStatus.Type
 	}); !containsStatus {
 		environmentStatus.Status = append(environmentStatus.Status, deploymentStatus)
 	}
+}
 
-	return tx.EdgeStackStatus().Update(stackID, payload.EndpointID, environmentStatus)
+// canRollback returns true when the rollback policy of the stack is enabled and the files
+// of a previous version are available
+func canRollback(stack *portainer.EdgeStack) bool {
+	return stack.RollbackPolicy.Enabled && stack.PreviousVersion > 0 && stack.PreviousVersion < stack.Version
 }
@@ -143,3 +143,102 @@ func TestUpdateStatusWithInvalidPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateStatusWithRollbackPolicy(t *testing.T) {
+	handler, _ := setupHandler(t)
+
+	endpoint := createEndpoint(t, handler.DataStore)
+	edgeStack := createEdgeStack(t, handler.DataStore, endpoint.ID)
+
+	edgeStack.RollbackPolicy.Enabled = true
+	edgeStack.PreviousVersion = edgeStack.Version - 1
+	err := handler.DataStore.EdgeStack().UpdateEdgeStack(edgeStack.ID, &edgeStack)
+	require.NoError(t, err)
+
+	updateStatus := func(status portainer.EdgeStackStatusType, version int) {
+		payload := updateStatusPayload{
+			Status:     &status,
+			EndpointID: endpoint.ID,
+			Version:    version,
+		}
+
+		if status == portainer.EdgeStackStatusError {
+			payload.Error = "test-error"
+		}
+
+		jsonPayload, err := json.Marshal(payload)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/edge_stacks/%d/status", edgeStack.ID), bytes.NewBuffer(jsonPayload))
+		require.NoError(t, err)
+
+		req.Header.Set(portainer.PortainerAgentEdgeIDHeader, endpoint.EdgeID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	statusTypes := func() []portainer.EdgeStackStatusType {
+		status, err := handler.DataStore.EdgeStackStatus().Read(edgeStack.ID, endpoint.ID)
+		require.NoError(t, err)
+
+		types := []portainer.EdgeStackStatusType{}
+		for _, s := range status.Status {
+			types = append(types, s.Type)
+		}
+
+		return types
+	}
+
+	updateStatus(portainer.EdgeStackStatusError, edgeStack.Version)
+	require.Equal(t, []portainer.EdgeStackStatusType{
+		portainer.EdgeStackStatusError,
+		portainer.EdgeStackStatusRollingBack,
+	}, statusTypes())
+
+	updateStatus(portainer.EdgeStackStatusRunning, edgeStack.PreviousVersion)
+	require.Equal(t, []portainer.EdgeStackStatusType{
+		portainer.EdgeStackStatusError,
+		portainer.EdgeStackStatusRollingBack,
+		portainer.EdgeStackStatusRolledBack,
+	}, statusTypes())
+
+	status, err := handler.DataStore.EdgeStackStatus().Read(edgeStack.ID, endpoint.ID)
+	require.NoError(t, err)
+
+	rolledBack := status.Status[len(status.Status)-1]
+	require.NotNil(t, rolledBack.RollbackTo)
+	require.Equal(t, edgeStack.PreviousVersion, *rolledBack.RollbackTo)
+	require.Equal(t, edgeStack.Version, rolledBack.Version)
+}
+
+func TestUpdateStatusWithoutRollbackPolicy(t *testing.T) {
+	handler, _ := setupHandler(t)
+
+	endpoint := createEndpoint(t, handler.DataStore)
+	edgeStack := createEdgeStack(t, handler.DataStore, endpoint.ID)
+
+	status := portainer.EdgeStackStatusError
+	payload := updateStatusPayload{
+		Error:      "test-error",
+		Status:     &status,
+		EndpointID: endpoint.ID,
+		Version:    edgeStack.Version,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/edge_stacks/%d/status", edgeStack.ID), bytes.NewBuffer(jsonPayload))
+	require.NoError(t, err)
+
+	req.Header.Set(portainer.PortainerAgentEdgeIDHeader, endpoint.EdgeID)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	envStatus, err := handler.DataStore.EdgeStackStatus().Read(edgeStack.ID, endpoint.ID)
+	require.NoError(t, err)
+	require.Len(t, envStatus.Status, 1)
+	require.Equal(t, portainer.EdgeStackStatusError, envStatus.Status[0].Type)
+}
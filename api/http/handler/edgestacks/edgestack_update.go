@@ -21,6 +21,8 @@ type updateEdgeStackPayload struct {
 	DeploymentType   portainer.EdgeStackDeploymentType
 	// Uses the manifest's namespaces instead of the default one
 	UseManifestNamespaces bool
+	// Automatic rollback policy, left unchanged when omitted
+	RollbackPolicy *portainer.EdgeStackRollbackPolicy
 }
 
 func (payload *updateEdgeStackPayload) Validate(r *http.Request) error {
@@ -123,6 +125,10 @@ func (handler *Handler) updateEdgeStack(tx dataservices.DataStoreTx, stackID por
 
 	stack.EdgeGroups = groupsIds
 
+	if payload.RollbackPolicy != nil {
+		stack.RollbackPolicy = *payload.RollbackPolicy
+	}
+
 	if payload.UpdateVersion {
 		if err := handler.updateStackVersion(tx, stack, payload.DeploymentType, []byte(payload.StackFileContent), "", relatedEndpointIds); err != nil {
 			return nil, httperror.InternalServerError("Unable to update stack version", err)
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"testing"
 
 	portainer "github.com/portainer/portainer/api"
@@ -232,3 +235,49 @@ func TestUpdateWithInvalidPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestKeepStackFileVersion(t *testing.T) {
+	handler, _ := setupHandler(t)
+
+	stack := &portainer.EdgeStack{
+		ID:         1,
+		Version:    2,
+		EntryPoint: "docker-compose.yml",
+	}
+
+	stackFolder := strconv.Itoa(int(stack.ID))
+	stack.ProjectPath = handler.FileService.GetEdgeStackProjectPath(stackFolder)
+	stack.PreviousVersion = 1
+
+	for name, content := range map[string]string{
+		"docker-compose.yml":    "services: {}",
+		"docker-compose.db.yml": "services: {db: {}}",
+		".env":                  "TAG=2",
+		"config/app.conf":       "port=80",
+		"v1/docker-compose.yml": "services: {old: {}}",
+	} {
+		path := filepath.Join(stack.ProjectPath, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	err := handler.keepStackFileVersion(stack)
+	require.NoError(t, err)
+	require.Equal(t, 2, stack.PreviousVersion)
+
+	versionPath := handler.FileService.GetEdgeStackProjectPathByVersion(stackFolder, 2, "")
+	for name, content := range map[string]string{
+		"docker-compose.yml":    "services: {}",
+		"docker-compose.db.yml": "services: {db: {}}",
+		".env":                  "TAG=2",
+		"config/app.conf":       "port=80",
+	} {
+		b, err := handler.FileService.GetFileContent(versionPath, name)
+		require.NoError(t, err)
+		require.Equal(t, content, string(b))
+	}
+
+	// the other versions are neither nested in the kept version nor kept
+	require.NoDirExists(t, filepath.Join(versionPath, "v1"))
+	require.NoDirExists(t, handler.FileService.GetEdgeStackProjectPathByVersion(stackFolder, 1, ""))
+}
@@ -2,6 +2,9 @@ package edgestacks
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 
 	portainer "github.com/portainer/portainer/api"
@@ -11,7 +14,18 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// versionFolderRegex matches the folders where the previous versions of the stack files are kept
+var versionFolderRegex = regexp.MustCompile(`^v\d+$`)
+
 func (handler *Handler) updateStackVersion(tx dataservices.DataStoreTx, stack *portainer.EdgeStack, deploymentType portainer.EdgeStackDeploymentType, config []byte, oldGitHash string, relatedEnvironmentsIDs []portainer.EndpointID) error {
+	if stack.RollbackPolicy.Enabled && deploymentType == stack.DeploymentType {
+		if err := handler.keepStackFileVersion(stack); err != nil {
+			return err
+		}
+	} else {
+		stack.PreviousVersion = 0
+	}
+
 	stack.Version++
 
 	if err := tx.EdgeStackStatus().Clear(stack.ID, relatedEnvironmentsIDs); err != nil {
@@ -21,6 +35,43 @@ func (handler *Handler) updateStackVersion(tx dataservices.DataStoreTx, stack *p
 	return handler.storeStackFile(stack, deploymentType, config)
 }
 
+// keepStackFileVersion stores a copy of the current stack files under their version so that
+// environments failing to deploy the next version can roll back to them
+func (handler *Handler) keepStackFileVersion(stack *portainer.EdgeStack) error {
+	stackFolder := strconv.Itoa(int(stack.ID))
+	versionPath := handler.FileService.GetEdgeStackProjectPathByVersion(stackFolder, stack.Version, "")
+
+	// the entry point is stored along with the additional files, the .env file and the folders it refers to
+	entries, err := os.ReadDir(stack.ProjectPath)
+	if err != nil {
+		return fmt.Errorf("unable to read the current stack files: %w", err)
+	}
+
+	if err := handler.FileService.RemoveDirectory(versionPath); err != nil {
+		return fmt.Errorf("unable to clear the stack files of version %d: %w", stack.Version, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && versionFolderRegex.MatchString(entry.Name()) {
+			continue
+		}
+
+		if err := filesystem.CopyPath(filepath.Join(stack.ProjectPath, entry.Name()), versionPath); err != nil {
+			return fmt.Errorf("unable to keep the stack files of version %d: %w", stack.Version, err)
+		}
+	}
+
+	if stack.PreviousVersion != 0 && stack.PreviousVersion != stack.Version {
+		if err := handler.FileService.RemoveDirectory(handler.FileService.GetEdgeStackProjectPathByVersion(stackFolder, stack.PreviousVersion, "")); err != nil {
+			log.Warn().Err(err).Int("version", stack.PreviousVersion).Msg("Unable to remove the files of an old edge stack version")
+		}
+	}
+
+	stack.PreviousVersion = stack.Version
+
+	return nil
+}
+
 func (handler *Handler) storeStackFile(stack *portainer.EdgeStack, deploymentType portainer.EdgeStackDeploymentType, config []byte) error {
 	if deploymentType != stack.DeploymentType {
 		// deployment type was changed - need to delete all old files
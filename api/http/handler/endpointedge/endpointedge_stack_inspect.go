@@ -10,6 +10,7 @@ import (
 	"github.com/portainer/portainer/api/edge"
 	"github.com/portainer/portainer/api/filesystem"
 	"github.com/portainer/portainer/api/http/middlewares"
+	internaledge "github.com/portainer/portainer/api/internal/edge"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/kubernetes"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
@@ -88,7 +89,19 @@ func (handler *Handler) endpointEdgeStackInspect(w http.ResponseWriter, r *http.
 		}
 	}
 
-	dirEntries, err := filesystem.LoadDir(edgeStack.ProjectPath)
+	projectPath := edgeStack.ProjectPath
+
+	var rollbackTo *int
+	status, err := handler.DataStore.EdgeStackStatus().Read(edgeStack.ID, endpoint.ID)
+	if err != nil && !handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.InternalServerError("Unable to retrieve edge stack status from the database", fmt.Errorf("failed to find Edge stack status from the database: %w. Environment name: %s", err, endpoint.Name))
+	}
+
+	if rollbackTo = internaledge.EdgeStackRollbackTarget(status); rollbackTo != nil {
+		projectPath = handler.FileService.GetEdgeStackProjectPathByVersion(strconv.Itoa(int(edgeStack.ID)), *rollbackTo, "")
+	}
+
+	dirEntries, err := filesystem.LoadDir(projectPath)
 	if err != nil {
 		return httperror.InternalServerError("Unable to load repository", fmt.Errorf("failed to load project directory: %w. Environment name: %s", err, endpoint.Name))
 	}
@@ -106,5 +119,7 @@ func (handler *Handler) endpointEdgeStackInspect(w http.ResponseWriter, r *http.
 		StackFileContent: fileContent,
 		Name:             edgeStack.Name,
 		Namespace:        namespace,
+		Version:          edgeStack.Version,
+		RollbackTo:       rollbackTo,
 	})
 }
@@ -29,6 +29,8 @@ type stackStatusResponse struct {
 	ID portainer.EdgeStackID `example:"1"`
 	// Version of this stack
 	Version int `example:"3"`
+	// Version of the stack to redeploy when the environment is rolling back a failed deployment
+	RollbackTo *int `json:",omitempty" example:"2"`
 }
 
 type edgeJobResponse struct {
@@ -144,6 +146,8 @@ func (handler *Handler) inspectStatus(tx dataservices.DataStoreTx, r *http.Reque
 		return nil, err
 	}
 
+	previousType, previousVersion, previousEngine := endpoint.Type, endpoint.Agent.Version, endpoint.ContainerEngine
+
 	if err := handler.parseHeaders(r, endpoint); err != nil {
 		return nil, err
 	}
@@ -161,6 +165,13 @@ func (handler *Handler) inspectStatus(tx dataservices.DataStoreTx, r *http.Reque
 		return nil, httperror.InternalServerError("Unable to persist environment changes inside the database", err)
 	}
 
+	// the edge groups can select the environments by platform, agent version and container engine
+	if endpoint.Type != previousType || endpoint.Agent.Version != previousVersion || endpoint.ContainerEngine != previousEngine {
+		if err := edge.UpdateEndpointRelatedEdgeStacks(tx, endpoint); err != nil {
+			return nil, httperror.InternalServerError("Unable to update environment relations", err)
+		}
+	}
+
 	tunnel := handler.ReverseTunnelService.Config(endpoint.ID)
 
 	statusResponse := endpointEdgeStatusInspectResponse{
@@ -279,6 +290,13 @@ func (handler *Handler) buildEdgeStacks(tx dataservices.DataStoreTx, endpointID
 			Version: version,
 		}
 
+		status, err := tx.EdgeStackStatus().Read(stackID, endpointID)
+		if err != nil && !tx.IsErrObjectNotFound(err) {
+			return nil, httperror.InternalServerError("Unable to retrieve edge stack status from the database", err)
+		}
+
+		stackStatus.RollbackTo = edge.EdgeStackRollbackTarget(status)
+
 		edgeStacksStatus = append(edgeStacksStatus, stackStatus)
 	}
 
@@ -431,3 +431,47 @@ func TestEdgeJobsResponse(t *testing.T) {
 	f(endpointFromDynamicEdgeGroup, 1)
 	f(unrelatedEndpoint, 0)
 }
+
+func TestEdgeStackRelationsFollowAgentVersion(t *testing.T) {
+	handler := mustSetupHandler(t)
+
+	endpoint := portainer.Endpoint{
+		ID:      8,
+		Name:    "test-endpoint-8",
+		Type:    portainer.EdgeAgentOnDockerEnvironment,
+		GroupID: 1,
+		URL:     "https://portainer.io:9443",
+		EdgeID:  "edge-id",
+	}
+	endpoint.Agent.Version = "2.19.0"
+
+	err := handler.DataStore.EdgeGroup().Create(&portainer.EdgeGroup{
+		ID:       1,
+		Name:     "recent-agents",
+		Dynamic:  true,
+		Selector: &portainer.EdgeGroupSelector{Field: portainer.EdgeGroupSelectorAgentVersion, Value: ">=2.20.0"},
+	})
+	require.NoError(t, err)
+
+	edgeStack := portainer.EdgeStack{ID: 18, Name: "test-edge-stack-18", EdgeGroups: []portainer.EdgeGroupID{1}}
+	err = handler.DataStore.EdgeStack().Create(edgeStack.ID, &edgeStack)
+	require.NoError(t, err)
+
+	err = createEndpoint(handler, endpoint, portainer.EndpointRelation{EndpointID: endpoint.ID})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/endpoints/%d/edge/status", endpoint.ID), nil)
+	require.NoError(t, err)
+
+	req.Header.Set(portainer.PortainerAgentEdgeIDHeader, "edge-id")
+	req.Header.Set(portainer.HTTPResponseAgentPlatform, "1")
+	req.Header.Set(portainer.PortainerAgentHeader, "2.21.0")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	relation, err := handler.DataStore.EndpointRelation().EndpointRelation(endpoint.ID)
+	require.NoError(t, err)
+	assert.True(t, relation.EdgeStacks[edgeStack.ID])
+}
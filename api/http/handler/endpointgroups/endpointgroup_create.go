@@ -6,6 +6,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/endpointutils"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
 	"github.com/portainer/portainer/pkg/libhttp/response"
@@ -20,6 +21,8 @@ type endpointGroupCreatePayload struct {
 	AssociatedEndpoints []portainer.EndpointID `example:"1,3"`
 	// List of tag identifiers to which this environment(endpoint) group is associated
 	TagIDs []portainer.TagID `example:"1,2"`
+	// List of key/value labels inherited by the environments(endpoints) of this group
+	EnvironmentLabels []portainer.Pair
 }
 
 func (payload *endpointGroupCreatePayload) Validate(r *http.Request) error {
@@ -31,7 +34,11 @@ func (payload *endpointGroupCreatePayload) Validate(r *http.Request) error {
 		payload.TagIDs = []portainer.TagID{}
 	}
 
-	return nil
+	if payload.EnvironmentLabels == nil {
+		payload.EnvironmentLabels = []portainer.Pair{}
+	}
+
+	return endpointutils.ValidateLabels(payload.EnvironmentLabels)
 }
 
 // @summary Create an Environment(Endpoint) Group
@@ -78,6 +85,7 @@ func (handler *Handler) createEndpointGroup(tx dataservices.DataStoreTx, payload
 		UserAccessPolicies: portainer.UserAccessPolicies{},
 		TeamAccessPolicies: portainer.TeamAccessPolicies{},
 		TagIDs:             payload.TagIDs,
+		EnvironmentLabels:  payload.EnvironmentLabels,
 	}
 
 	err := tx.EndpointGroup().Create(endpointGroup)
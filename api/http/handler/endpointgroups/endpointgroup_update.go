@@ -23,13 +23,15 @@ type endpointGroupUpdatePayload struct {
 	// Environment(Endpoint) group description
 	Description string `example:"description"`
 	// List of tag identifiers associated to the environment(endpoint) group
-	TagIDs             []portainer.TagID `example:"3,4"`
+	TagIDs []portainer.TagID `example:"3,4"`
+	// List of key/value labels inherited by the environments(endpoints) of this group
+	EnvironmentLabels  []portainer.Pair
 	UserAccessPolicies portainer.UserAccessPolicies
 	TeamAccessPolicies portainer.TeamAccessPolicies
 }
 
 func (payload *endpointGroupUpdatePayload) Validate(r *http.Request) error {
-	return nil
+	return endpointutils.ValidateLabels(payload.EnvironmentLabels)
 }
 
 // @id EndpointGroupUpdate
@@ -135,6 +137,12 @@ func (handler *Handler) updateEndpointGroup(tx dataservices.DataStoreTx, endpoin
 		}
 	}
 
+	labelsChanged := false
+	if payload.EnvironmentLabels != nil {
+		labelsChanged = !reflect.DeepEqual(payload.EnvironmentLabels, endpointGroup.EnvironmentLabels)
+		endpointGroup.EnvironmentLabels = payload.EnvironmentLabels
+	}
+
 	updateAuthorizations := false
 	if payload.UserAccessPolicies != nil && !reflect.DeepEqual(payload.UserAccessPolicies, endpointGroup.UserAccessPolicies) {
 		endpointGroup.UserAccessPolicies = payload.UserAccessPolicies
@@ -170,7 +178,7 @@ func (handler *Handler) updateEndpointGroup(tx dataservices.DataStoreTx, endpoin
 		return nil, httperror.InternalServerError("Unable to persist environment group changes inside the database", err)
 	}
 
-	if tagsChanged {
+	if tagsChanged || labelsChanged {
 		endpoints, err := tx.Endpoint().Endpoints()
 		if err != nil {
 			return nil, httperror.InternalServerError("Unable to retrieve environments from the database", err)
@@ -546,6 +546,23 @@ func (handler *Handler) snapshotAndPersistEndpoint(tx dataservices.DataStoreTx,
 }
 
 func (handler *Handler) saveEndpointAndUpdateAuthorizations(tx dataservices.DataStoreTx, endpoint *portainer.Endpoint) error {
+	if err := handler.saveEndpoint(tx, endpoint); err != nil {
+		return err
+	}
+
+	for _, tagID := range endpoint.TagIDs {
+		if err := tx.Tag().UpdateTagFunc(tagID, func(tag *portainer.Tag) {
+			tag.Endpoints[endpoint.ID] = true
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveEndpoint persists a new environment with the default security settings, without updating its tags
+func (handler *Handler) saveEndpoint(tx dataservices.DataStoreTx, endpoint *portainer.Endpoint) error {
 	endpoint.SecuritySettings = portainer.EndpointSecuritySettings{
 		AllowVolumeBrowserForRegularUsers: false,
 		EnableHostManagementFeatures:      false,
@@ -563,19 +580,7 @@ func (handler *Handler) saveEndpointAndUpdateAuthorizations(tx dataservices.Data
 		return err
 	}
 
-	if err := endpointutils.InitializeEdgeEndpointRelation(endpoint, tx); err != nil {
-		return err
-	}
-
-	for _, tagID := range endpoint.TagIDs {
-		if err := tx.Tag().UpdateTagFunc(tagID, func(tag *portainer.Tag) {
-			tag.Endpoints[endpoint.ID] = true
-		}); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return endpointutils.InitializeEdgeEndpointRelation(endpoint, tx)
 }
 
 func (handler *Handler) storeTLSFiles(endpoint *portainer.Endpoint, payload *endpointCreatePayload) *httperror.HandlerError {
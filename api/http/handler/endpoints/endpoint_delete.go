@@ -9,6 +9,7 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/internal/endpointutils"
+	"github.com/portainer/portainer/api/stacks/deploymentgroups"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
 	"github.com/portainer/portainer/pkg/libhttp/response"
@@ -220,6 +221,23 @@ func (handler *Handler) deleteEndpoint(tx dataservices.DataStoreTx, endpointID p
 		}
 	}
 
+	// the stacks of the environment can no longer be deployed by their deployment groups
+	stacks, err := tx.Stack().ReadAll(func(stack portainer.Stack) bool { return stack.EndpointID == endpoint.ID })
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to retrieve stacks from the database")
+	}
+
+	if len(stacks) > 0 {
+		stackIDs := make([]portainer.StackID, 0, len(stacks))
+		for _, stack := range stacks {
+			stackIDs = append(stackIDs, stack.ID)
+		}
+
+		if err := deploymentgroups.RemoveStacks(tx, stackIDs...); err != nil {
+			log.Warn().Err(err).Msg("Unable to remove the stacks from the deployment groups")
+		}
+	}
+
 	registries, err := tx.Registry().ReadAll()
 	if err != nil {
 		log.Warn().Err(err).Msg("Unable to retrieve registries from the database")
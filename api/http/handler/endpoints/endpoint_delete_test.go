@@ -11,6 +11,8 @@ import (
 	"github.com/portainer/portainer/api/datastore"
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestEndpointDeleteEdgeGroupsConcurrently(t *testing.T) {
@@ -82,3 +84,31 @@ func TestEndpointDeleteEdgeGroupsConcurrently(t *testing.T) {
 		t.Fatal("the edge group is not consistent")
 	}
 }
+
+func TestEndpointDeleteDeploymentGroupStacks(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	handler := NewHandler(testhelpers.NewTestRequestBouncer())
+	handler.DataStore = store
+	handler.ProxyManager = proxy.NewManager(nil)
+	handler.ProxyManager.NewProxyFactory(nil, nil, nil, nil, nil, nil, nil, nil)
+
+	for _, endpointID := range []portainer.EndpointID{1, 2} {
+		require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: endpointID, Name: "env-" + strconv.Itoa(int(endpointID)), Type: portainer.DockerEnvironment}))
+		require.NoError(t, store.Stack().Create(&portainer.Stack{ID: portainer.StackID(endpointID), Name: "stack-" + strconv.Itoa(int(endpointID)), EndpointID: endpointID}))
+	}
+
+	require.NoError(t, store.DeploymentGroup().Create(&portainer.DeploymentGroup{
+		Name:   "shop",
+		Stacks: []portainer.DeploymentGroupStack{{StackID: 2, DependsOn: []portainer.StackID{1}}, {StackID: 1}},
+	}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/endpoints/1", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	group, err := store.DeploymentGroup().Read(1)
+	require.NoError(t, err)
+	require.Equal(t, []portainer.DeploymentGroupStack{{StackID: 2, DependsOn: []portainer.StackID{}}}, group.Stacks)
+}
@@ -0,0 +1,420 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/agent"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	"github.com/portainer/portainer/api/url"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+	"github.com/portainer/portainer/pkg/networking"
+)
+
+const (
+	diagnosticsCheckTimeout = 5 * time.Second
+	diagnosticsTimeout      = 2 * time.Minute
+)
+
+type endpointDiagnosticsReport struct {
+	EndpointID portainer.EndpointID `json:"EndpointId" example:"1"`
+	// Worst status of the checks that were run
+	Status networking.CheckStatus `json:"Status" example:"passed"`
+	// Unix timestamp of the start of the diagnostics
+	StartedAt int64 `json:"StartedAt" example:"1700000000"`
+	// Time spent running all the checks, in milliseconds
+	DurationMs int64                    `json:"DurationMs" example:"250"`
+	Checks     []networking.CheckResult `json:"Checks"`
+	// Diagnostics collected by the agent itself during the last snapshot
+	AgentDiagnostics *portainer.DiagnosticsData `json:"AgentDiagnostics,omitempty"`
+}
+
+// endpointDiagnostics runs checks in order, a failed check skips all the following ones
+// since they depend on it
+type endpointDiagnostics struct {
+	checks []networking.CheckResult
+	failed string
+}
+
+func (d *endpointDiagnostics) run(name string, check func() networking.CheckResult) {
+	if d.failed != "" {
+		d.skip(name, "skipped because the "+d.failed+" check failed")
+		return
+	}
+
+	result := check()
+	if result.Status == networking.CheckStatusFailed {
+		d.failed = result.Name
+	}
+
+	d.checks = append(d.checks, result)
+}
+
+func (d *endpointDiagnostics) skip(name, reason string) {
+	d.checks = append(d.checks, networking.SkippedCheck(name, reason))
+}
+
+// @id EndpointDiagnostics
+// @summary Run connectivity diagnostics against an environment(endpoint)
+// @description Run DNS, TCP, TLS, proxy, agent and Docker/Kubernetes API checks against an environment(endpoint) and return a report with the timing of each check and remediation hints for the failed ones.
+// @description Edge environments are checked through the reverse tunnel.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Environment(Endpoint) identifier"
+// @success 200 {object} endpointDiagnosticsReport "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Environment(Endpoint) not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/diagnostics [post]
+func (handler *Handler) endpointDiagnostics(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid environment identifier route variable", err)
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an environment with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), diagnosticsTimeout)
+	defer cancel()
+
+	start := time.Now()
+	diagnostics := &endpointDiagnostics{}
+
+	if endpointutils.IsEdgeEndpoint(endpoint) {
+		handler.diagnoseEdgeEndpoint(ctx, diagnostics, endpoint)
+	} else {
+		handler.diagnoseEndpoint(ctx, diagnostics, endpoint)
+	}
+
+	report := endpointDiagnosticsReport{
+		EndpointID:       endpoint.ID,
+		Status:           networking.OverallStatus(diagnostics.checks),
+		StartedAt:        start.Unix(),
+		DurationMs:       time.Since(start).Milliseconds(),
+		Checks:           diagnostics.checks,
+		AgentDiagnostics: snapshotDiagnosticsData(endpoint),
+	}
+
+	return response.JSON(w, report)
+}
+
+func (handler *Handler) diagnoseEndpoint(ctx context.Context, diagnostics *endpointDiagnostics, endpoint *portainer.Endpoint) {
+	endpointURL, err := url.ParseURL(endpoint.URL)
+	if err != nil {
+		diagnostics.run("url", func() networking.CheckResult {
+			return networking.RunCheck("url", "Update the environment with a valid URL", func() (string, map[string]any, error) {
+				return "", nil, fmt.Errorf("invalid environment URL %q: %w", endpoint.URL, err)
+			})
+		})
+
+		handler.diagnoseAPI(ctx, diagnostics, endpoint)
+
+		return
+	}
+
+	switch endpointURL.Scheme {
+	case "unix":
+		diagnostics.skip("dns", "the environment is reached through a local socket")
+		diagnostics.run("socket", func() networking.CheckResult {
+			result := networking.CheckTCP(endpoint.URL)
+			result.Name = "socket"
+			if result.Status == networking.CheckStatusFailed {
+				result.Remediation = "Make sure the Docker socket is mounted inside the Portainer container and readable"
+			}
+
+			return result
+		})
+		diagnostics.skip("tls", "the environment is reached through a local socket")
+		diagnostics.skip("proxy", "the environment is reached through a local socket")
+
+	case "npipe":
+		diagnostics.skip("dns", "the environment is reached through a named pipe")
+		diagnostics.skip("tcp", "the environment is reached through a named pipe")
+		diagnostics.skip("tls", "the environment is reached through a named pipe")
+		diagnostics.skip("proxy", "the environment is reached through a named pipe")
+
+	default:
+		host := endpointURL.Hostname()
+		address := net.JoinHostPort(host, diagnosticsPort(endpoint, endpointURL.Port(), endpointURL.Scheme))
+
+		diagnostics.run("dns", func() networking.CheckResult {
+			return networking.CheckDNS("tcp://" + address)
+		})
+
+		diagnostics.run("tcp", func() networking.CheckResult {
+			return networking.CheckTCP("tcp://" + address)
+		})
+
+		// the TLS and proxy checks share the request sent to the environment
+		var proxyProbe *networking.ProxyProbe
+		probe := func(scheme string, tlsConfig *tls.Config) networking.ProxyProbe {
+			if proxyProbe == nil {
+				probe := networking.ProbeProxy(scheme+"://"+address, tlsConfig)
+				proxyProbe = &probe
+			}
+
+			return *proxyProbe
+		}
+
+		tlsConfig, err := diagnosticsTLSConfig(endpoint, endpointURL.Scheme)
+		switch {
+		case err != nil:
+			diagnostics.run("tls", func() networking.CheckResult {
+				return networking.RunCheck("tls", "Upload the TLS files of the environment again", func() (string, map[string]any, error) {
+					return "", nil, fmt.Errorf("unable to load the TLS configuration: %w", err)
+				})
+			})
+		case tlsConfig == nil:
+			diagnostics.skip("tls", "TLS is not enabled for this environment")
+			diagnostics.run("proxy", func() networking.CheckResult {
+				return networking.CheckProxy(probe("http", nil))
+			})
+		default:
+			tlsConfig.ServerName = host
+			diagnostics.run("tls", func() networking.CheckResult {
+				return networking.CheckTLS(probe("https", tlsConfig))
+			})
+			diagnostics.run("proxy", func() networking.CheckResult {
+				return networking.CheckProxy(probe("https", tlsConfig))
+			})
+		}
+	}
+
+	if endpoint.Type == portainer.AgentOnDockerEnvironment || endpoint.Type == portainer.AgentOnKubernetesEnvironment {
+		diagnostics.run("agent", func() networking.CheckResult {
+			return networking.RunCheck("agent", "Make sure the Portainer agent is running and reachable on the environment URL", func() (string, map[string]any, error) {
+				platform, version, err := agent.GetAgentVersionAndPlatform(endpoint.URL, &tls.Config{InsecureSkipVerify: true})
+				if err != nil {
+					return "", nil, fmt.Errorf("unable to ping the agent: %w", err)
+				}
+
+				return "agent " + version + " answered", map[string]any{"Version": version, "Platform": agentPlatformName(platform)}, nil
+			})
+		})
+	}
+
+	handler.diagnoseAPI(ctx, diagnostics, endpoint)
+}
+
+func (handler *Handler) diagnoseEdgeEndpoint(ctx context.Context, diagnostics *endpointDiagnostics, endpoint *portainer.Endpoint) {
+	diagnostics.skip("dns", "Edge agents connect to Portainer")
+	diagnostics.skip("tcp", "Edge agents connect to Portainer")
+	diagnostics.skip("tls", "Edge agents connect to Portainer")
+	diagnostics.skip("proxy", "Edge agents connect to Portainer")
+
+	diagnostics.run("checkin", func() networking.CheckResult {
+		return networking.RunCheck("checkin", "Make sure the Edge agent is running and can reach the Portainer URL and tunnel server address found in its Edge key", func() (string, map[string]any, error) {
+			lastCheckIn, ok := handler.DataStore.Endpoint().Heartbeat(endpoint.ID)
+			if !ok || lastCheckIn == 0 {
+				return "", nil, errors.New("the Edge agent never checked in")
+			}
+
+			var checkinInterval int
+			if err := handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+				checkinInterval = edge.EffectiveCheckinInterval(tx, endpoint)
+				return nil
+			}); err != nil {
+				return "", nil, err
+			}
+
+			age := time.Since(time.Unix(lastCheckIn, 0)).Round(time.Second)
+			details := map[string]any{
+				"LastCheckInDate": lastCheckIn,
+				"CheckinInterval": checkinInterval,
+			}
+
+			// Missing a couple of check-ins is tolerated, the agent is considered down past that point
+			if age > 3*time.Duration(checkinInterval)*time.Second {
+				return "", details, fmt.Errorf("the Edge agent last checked in %s ago, for a check-in interval of %ds", age, checkinInterval)
+			}
+
+			return fmt.Sprintf("the Edge agent last checked in %s ago", age), details, nil
+		})
+	})
+
+	if endpoint.Edge.AsyncMode {
+		diagnostics.skip("tunnel", "Edge agents in async mode do not use the tunnel")
+		diagnostics.skip("agent", "Edge agents in async mode do not use the tunnel")
+
+		return
+	}
+
+	var tunnelAddr string
+	diagnostics.run("tunnel", func() networking.CheckResult {
+		return networking.RunCheck("tunnel", "Make sure the Edge agent can reach the tunnel server address found in its Edge key", func() (string, map[string]any, error) {
+			addr, err := handler.ReverseTunnelService.TunnelAddr(endpoint)
+			if err != nil {
+				return "", nil, fmt.Errorf("unable to open the tunnel: %w", err)
+			}
+
+			tunnelAddr = addr
+
+			return "tunnel opened on " + addr, map[string]any{"Address": addr}, nil
+		})
+	})
+
+	diagnostics.run("agent", func() networking.CheckResult {
+		return networking.RunCheck("agent", "Make sure the Edge agent is running and healthy", func() (string, map[string]any, error) {
+			version, err := pingAgentThroughTunnel(ctx, tunnelAddr)
+			if err != nil {
+				return "", nil, fmt.Errorf("unable to ping the agent through the tunnel: %w", err)
+			}
+
+			return "agent " + version + " answered", map[string]any{"Version": version}, nil
+		})
+	})
+
+	handler.diagnoseAPI(ctx, diagnostics, endpoint)
+}
+
+// diagnoseAPI queries the version of the Docker or Kubernetes API of the environment
+func (handler *Handler) diagnoseAPI(ctx context.Context, diagnostics *endpointDiagnostics, endpoint *portainer.Endpoint) {
+	switch {
+	case endpointutils.IsDockerEndpoint(endpoint):
+		diagnostics.run("docker", func() networking.CheckResult {
+			return networking.RunCheck("docker", "Make sure the Docker daemon is running and that its API is exposed on the environment URL", func() (string, map[string]any, error) {
+				timeout := diagnosticsCheckTimeout
+				cli, err := handler.DockerClientFactory.CreateClient(endpoint, "", &timeout)
+				if err != nil {
+					return "", nil, fmt.Errorf("unable to create the Docker client: %w", err)
+				}
+				defer cli.Close()
+
+				version, err := cli.ServerVersion(ctx)
+				if err != nil {
+					return "", nil, fmt.Errorf("unable to query the Docker API: %w", err)
+				}
+
+				return "Docker " + version.Version + " answered", map[string]any{
+					"Version":    version.Version,
+					"APIVersion": version.APIVersion,
+					"Os":         version.Os,
+					"Arch":       version.Arch,
+				}, nil
+			})
+		})
+
+	case endpointutils.IsKubernetesEndpoint(endpoint):
+		diagnostics.run("kubernetes", func() networking.CheckResult {
+			return networking.RunCheck("kubernetes", "Make sure the Kubernetes API server is running and that Portainer is allowed to query it", func() (string, map[string]any, error) {
+				cli, err := handler.K8sClientFactory.CreateClient(endpoint)
+				if err != nil {
+					return "", nil, fmt.Errorf("unable to create the Kubernetes client: %w", err)
+				}
+
+				version, err := cli.Discovery().ServerVersion()
+				if err != nil {
+					return "", nil, fmt.Errorf("unable to query the Kubernetes API: %w", err)
+				}
+
+				return "Kubernetes " + version.GitVersion + " answered", map[string]any{
+					"Version":  version.GitVersion,
+					"Platform": version.Platform,
+				}, nil
+			})
+		})
+	}
+}
+
+// diagnosticsPort returns the port to check when the environment URL does not specify one
+func diagnosticsPort(endpoint *portainer.Endpoint, port, scheme string) string {
+	if port != "" {
+		return port
+	}
+
+	switch {
+	case endpoint.Type == portainer.AgentOnDockerEnvironment || endpoint.Type == portainer.AgentOnKubernetesEnvironment:
+		return "9001"
+	case scheme == "https" || endpoint.Type == portainer.KubernetesLocalEnvironment || endpoint.Type == portainer.AzureEnvironment:
+		return "443"
+	case endpoint.TLSConfig.TLS:
+		return "2376"
+	}
+
+	return "2375"
+}
+
+// diagnosticsTLSConfig returns the TLS configuration used to connect to the environment, nil when it does not use TLS
+func diagnosticsTLSConfig(endpoint *portainer.Endpoint, scheme string) (*tls.Config, error) {
+	switch endpoint.Type {
+	case portainer.AgentOnDockerEnvironment, portainer.AgentOnKubernetesEnvironment:
+		// Agents use a self-signed certificate, requests are authenticated with a signature instead
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	case portainer.AzureEnvironment:
+		return crypto.CreateTLSConfiguration(), nil
+	case portainer.KubernetesLocalEnvironment:
+		// The in-cluster CA is not known to Portainer
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	if !endpoint.TLSConfig.TLS && scheme != "https" {
+		return nil, nil
+	}
+
+	return crypto.CreateTLSConfigurationFromDisk(endpoint.TLSConfig.TLSCACertPath, endpoint.TLSConfig.TLSCertPath, endpoint.TLSConfig.TLSKeyPath, endpoint.TLSConfig.TLSSkipVerify)
+}
+
+func pingAgentThroughTunnel(ctx context.Context, tunnelAddr string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+tunnelAddr+"/ping", nil)
+	if err != nil {
+		return "", err
+	}
+
+	httpCli := &http.Client{Timeout: diagnosticsCheckTimeout}
+
+	resp, err := httpCli.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.Header.Get(portainer.PortainerAgentHeader), nil
+}
+
+func agentPlatformName(platform portainer.AgentPlatform) string {
+	switch platform {
+	case portainer.AgentPlatformDocker:
+		return "docker"
+	case portainer.AgentPlatformKubernetes:
+		return "kubernetes"
+	}
+
+	return strconv.Itoa(int(platform))
+}
+
+// snapshotDiagnosticsData returns the diagnostics collected by the agent during the last snapshot
+func snapshotDiagnosticsData(endpoint *portainer.Endpoint) *portainer.DiagnosticsData {
+	if len(endpoint.Snapshots) > 0 && endpoint.Snapshots[0].DiagnosticsData != nil {
+		return endpoint.Snapshots[0].DiagnosticsData
+	}
+
+	if len(endpoint.Kubernetes.Snapshots) > 0 {
+		return endpoint.Kubernetes.Snapshots[0].DiagnosticsData
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/pkg/networking"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointDiagnosticsUnreachable(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	err = store.Endpoint().Create(&portainer.Endpoint{
+		ID:   1,
+		Name: "unreachable",
+		Type: portainer.DockerEnvironment,
+		URL:  "tcp://" + address,
+	})
+	require.NoError(t, err)
+
+	handler := NewHandler(testhelpers.NewTestRequestBouncer())
+	handler.DataStore = store
+
+	req := httptest.NewRequest(http.MethodPost, "/endpoints/1/diagnostics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var report endpointDiagnosticsReport
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+
+	require.Equal(t, networking.CheckStatusFailed, report.Status)
+
+	statuses := map[string]networking.CheckStatus{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+
+		if check.Status == networking.CheckStatusFailed {
+			require.NotEmpty(t, check.Remediation)
+		}
+	}
+
+	require.Equal(t, map[string]networking.CheckStatus{
+		"dns":    networking.CheckStatusSkipped,
+		"tcp":    networking.CheckStatusFailed,
+		"tls":    networking.CheckStatusSkipped,
+		"proxy":  networking.CheckStatusSkipped,
+		"docker": networking.CheckStatusSkipped,
+	}, statuses)
+}
+
+func TestEndpointDiagnosticsNotFound(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	handler := NewHandler(testhelpers.NewTestRequestBouncer())
+	handler.DataStore = store
+
+	req := httptest.NewRequest(http.MethodPost, "/endpoints/1/diagnostics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
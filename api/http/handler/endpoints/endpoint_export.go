@@ -0,0 +1,185 @@
+package endpoints
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+// @id EndpointExport
+// @summary Export environments(endpoints) to a file
+// @description Export the environments(endpoints) inventory to a CSV or YAML file that can be used with the import.
+// @description Secrets such as Edge keys, Azure credentials or the TLS keys are never exported, the TLS certificates are exported inline as PEM content.
+// @description The TLS key of an environment is referenced by the name of the file to upload in the TLSFiles field of the import, environment-<id>-key.pem.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security ApiKeyAuth
+// @security jwt
+// @produce text/csv,text/yaml
+// @param format query string false "File format, defaults to yaml" Enum("csv", "yaml")
+// @success 200 {string} string "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /endpoints/export [get]
+func (handler *Handler) endpointExport(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	format, _ := request.RetrieveQueryParameter(r, "format", true)
+	switch format {
+	case "", environmentFileFormatYAML, "yml":
+		format = environmentFileFormatYAML
+	case environmentFileFormatCSV:
+	default:
+		return httperror.BadRequest("Invalid query parameter: format", errors.New("value must be one of: csv or yaml"))
+	}
+
+	var records []environmentRecord
+	if err := handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		var err error
+		records, err = exportEnvironmentRecords(tx, handler.FileService)
+		return err
+	}); err != nil {
+		return httperror.InternalServerError("Unable to retrieve the environments from the database", err)
+	}
+
+	content, err := writeEnvironmentRecords(format, records)
+	if err != nil {
+		return httperror.InternalServerError("Unable to write the environments file", err)
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=portainer-environments."+format)
+
+	if format == environmentFileFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		_, _ = w.Write([]byte(content))
+
+		return nil
+	}
+
+	return response.YAML(w, content)
+}
+
+func exportEnvironmentRecords(tx dataservices.DataStoreTx, fileService portainer.FileService) ([]environmentRecord, error) {
+	endpoints, err := tx.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	endpointGroups, err := tx.EndpointGroup().ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	groupNames := make(map[portainer.EndpointGroupID]string, len(endpointGroups))
+	for _, group := range endpointGroups {
+		groupNames[group.ID] = group.Name
+	}
+
+	tags, err := tx.Tag().ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	tagNames := make(map[portainer.TagID]string, len(tags))
+	for _, tag := range tags {
+		tagNames[tag.ID] = tag.Name
+	}
+
+	slices.SortFunc(endpoints, func(a, b portainer.Endpoint) int {
+		return int(a.ID) - int(b.ID)
+	})
+
+	records := make([]environmentRecord, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		record := environmentRecord{
+			Name:            endpoint.Name,
+			Type:            environmentRecordType(endpoint.Type),
+			URL:             endpoint.URL,
+			PublicURL:       endpoint.PublicURL,
+			ContainerEngine: endpoint.ContainerEngine,
+			TLS:             endpoint.TLSConfig.TLS,
+		}
+
+		// The unassigned group is the default one on import
+		if endpoint.GroupID != 1 {
+			record.Group = groupNames[endpoint.GroupID]
+		}
+
+		for _, tagID := range endpoint.TagIDs {
+			if name, ok := tagNames[tagID]; ok {
+				record.Tags = append(record.Tags, name)
+			}
+		}
+
+		if len(endpoint.Labels) > 0 {
+			record.Labels = make(map[string]string, len(endpoint.Labels))
+			for _, label := range endpoint.Labels {
+				record.Labels[label.Name] = label.Value
+			}
+		}
+
+		if endpointutils.IsEdgeEndpoint(&endpoint) {
+			record.URL = edgeKeyPortainerURL(endpoint.EdgeKey, endpoint.URL)
+			record.EdgeCheckinInterval = endpoint.EdgeCheckinInterval
+		}
+
+		if record.TLS {
+			record.TLSSkipVerify = endpoint.TLSConfig.TLSSkipVerify
+			record.TLSSkipClientVerify = endpoint.TLSConfig.TLSCertPath == "" && endpoint.TLSConfig.TLSKeyPath == ""
+
+			// the key is never exported, it is referenced by a file name to upload along with the file on import
+			if endpoint.TLSConfig.TLSKeyPath != "" {
+				record.TLSKey = tlsKeyFileName(endpoint.ID)
+			}
+
+			for path, content := range map[string]*string{
+				endpoint.TLSConfig.TLSCACertPath: &record.TLSCACert,
+				endpoint.TLSConfig.TLSCertPath:   &record.TLSCert,
+			} {
+				if path == "" {
+					continue
+				}
+
+				file, err := fileService.GetFileContent(path, "")
+				if err != nil {
+					return nil, err
+				}
+
+				*content = string(file)
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// tlsKeyFileName returns the name of the file the TLS key of an environment is referenced by in the export
+func tlsKeyFileName(endpointID portainer.EndpointID) string {
+	return fmt.Sprintf("environment-%d-key.pem", endpointID)
+}
+
+// edgeKeyPortainerURL extracts the Portainer URL the Edge agent was registered with from the Edge key,
+// falling back to the environment URL when the key cannot be decoded
+func edgeKeyPortainerURL(edgeKey, fallback string) string {
+	decoded, err := base64.RawStdEncoding.DecodeString(edgeKey)
+	if err != nil {
+		return fallback
+	}
+
+	portainerURL, _, found := strings.Cut(string(decoded), "|")
+	if !found || portainerURL == "" {
+		return fallback
+	}
+
+	return portainerURL
+}
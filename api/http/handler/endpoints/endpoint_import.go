@@ -0,0 +1,504 @@
+package endpoints
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+
+	"github.com/gofrs/uuid"
+)
+
+type endpointImportPayload struct {
+	// Content of the CSV or YAML file describing the environments
+	File []byte
+	// Format of the file, either csv or yaml. Deduced from the file extension when omitted
+	Format string
+	// TLS files uploaded along with the environments file, by file name
+	TLSFiles map[string][]byte
+}
+
+func (payload *endpointImportPayload) Validate(r *http.Request) error {
+	file, filename, err := request.RetrieveMultiPartFormFile(r, "file")
+	if err != nil {
+		return errors.New("invalid environments file. Ensure that the file is uploaded correctly")
+	}
+	payload.File = file
+
+	format, _ := request.RetrieveMultiPartFormValue(r, "Format", true)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+
+	switch format {
+	case environmentFileFormatCSV:
+		payload.Format = environmentFileFormatCSV
+	case environmentFileFormatYAML, "yml":
+		payload.Format = environmentFileFormatYAML
+	default:
+		return errors.New("invalid file format. Value must be one of: csv or yaml")
+	}
+
+	payload.TLSFiles = map[string][]byte{}
+	if r.MultipartForm != nil {
+		for _, header := range r.MultipartForm.File["TLSFiles"] {
+			file, err := header.Open()
+			if err != nil {
+				return fmt.Errorf("invalid TLS file %q: %w", header.Filename, err)
+			}
+
+			content, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return fmt.Errorf("invalid TLS file %q: %w", header.Filename, err)
+			}
+
+			payload.TLSFiles[header.Filename] = content
+		}
+	}
+
+	return nil
+}
+
+// importedEnvironment is an environment built from a validated record, ready to be persisted
+type importedEnvironment struct {
+	endpoint     *portainer.Endpoint
+	portainerURL string
+	tlsFiles     *endpointCreatePayload
+}
+
+// @id EndpointImport
+// @summary Import environments(endpoints) from a file
+// @description Create environments(endpoints) in bulk from a CSV or YAML file, in the format produced by the export.
+// @description Every entry is validated before anything is created, and the import is rejected with the list of invalid entries if any fails.
+// @description Environments are created in a single transaction. They are not contacted during the import, snapshots are taken by the next scheduled run.
+// @description The TLS files of an entry are either given inline as PEM content, or reference by name a file uploaded in the TLSFiles field.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security ApiKeyAuth
+// @security jwt
+// @accept multipart/form-data
+// @produce json
+// @param file formData file true "CSV or YAML file describing the environments"
+// @param Format formData string false "File format, deduced from the file extension if not specified" Enum("csv", "yaml")
+// @param TLSFiles formData file false "TLS files referenced by name by the entries, the field can be repeated"
+// @success 200 {array} portainer.Endpoint "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /endpoints/import [post]
+func (handler *Handler) endpointImport(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	payload := &endpointImportPayload{}
+	if err := payload.Validate(r); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	records, err := parseEnvironmentRecords(payload.Format, payload.File)
+	if err != nil {
+		return httperror.BadRequest("Unable to parse the environments file", err)
+	}
+
+	if len(records) == 0 {
+		return httperror.BadRequest("Invalid environments file", errors.New("the file does not contain any environment"))
+	}
+
+	var environments []importedEnvironment
+	if err := handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		environments, err = handler.validateEnvironmentRecords(tx, payload.Format, records, payload.TLSFiles)
+		return err
+	}); err != nil {
+		var validationErr *environmentRecordsValidationError
+		if errors.As(err, &validationErr) {
+			return httperror.BadRequest("Invalid environments file", validationErr.err)
+		}
+
+		return httperror.InternalServerError("Unable to validate the environments file", err)
+	}
+
+	var tlsFolders []string
+	if err := handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		for _, environment := range environments {
+			err := handler.createImportedEndpoint(tx, &environment)
+
+			if environment.tlsFiles != nil {
+				tlsFolders = append(tlsFolders, strconv.Itoa(int(environment.endpoint.ID)))
+			}
+
+			if err != nil {
+				return fmt.Errorf("unable to create environment %q: %w", environment.endpoint.Name, err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		for _, folder := range tlsFolders {
+			_ = handler.FileService.DeleteTLSFiles(folder)
+		}
+
+		return httperror.InternalServerError("Unable to import the environments", err)
+	}
+
+	endpoints := make([]portainer.Endpoint, 0, len(environments))
+	for _, environment := range environments {
+		hideFields(environment.endpoint)
+		endpoints = append(endpoints, *environment.endpoint)
+	}
+
+	return response.JSON(w, endpoints)
+}
+
+type environmentRecordsValidationError struct {
+	err error
+}
+
+func (e *environmentRecordsValidationError) Error() string {
+	return e.err.Error()
+}
+
+// validateEnvironmentRecords validates every record and returns the environments to create,
+// or an environmentRecordsValidationError listing the invalid records
+func (handler *Handler) validateEnvironmentRecords(tx dataservices.DataStoreTx, format string, records []environmentRecord, tlsFiles map[string][]byte) ([]importedEnvironment, error) {
+	existingEndpoints, err := tx.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(existingEndpoints)+len(records))
+	for _, endpoint := range existingEndpoints {
+		names[endpoint.Name] = true
+	}
+
+	endpointGroups, err := tx.EndpointGroup().ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	groupIDs := make(map[string]portainer.EndpointGroupID, len(endpointGroups))
+	for _, group := range endpointGroups {
+		groupIDs[group.Name] = group.ID
+	}
+
+	tags, err := tx.Tag().ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	tagIDs := make(map[string]portainer.TagID, len(tags))
+	for _, tag := range tags {
+		tagIDs[tag.Name] = tag.ID
+	}
+
+	var errs []error
+	environments := make([]importedEnvironment, 0, len(records))
+
+	for i, record := range records {
+		environment, err := handler.validateEnvironmentRecord(record, groupIDs, tagIDs, tlsFiles)
+		if err == nil && names[record.Name] {
+			err = fmt.Errorf("an environment named %q already exists", record.Name)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", environmentRecordPosition(format, i, record), err))
+			continue
+		}
+
+		names[record.Name] = true
+		environments = append(environments, environment)
+	}
+
+	if len(errs) > 0 {
+		return nil, &environmentRecordsValidationError{err: errors.Join(errs...)}
+	}
+
+	return environments, nil
+}
+
+func environmentRecordPosition(format string, index int, record environmentRecord) string {
+	position := fmt.Sprintf("environment %d", index+1)
+	if format == environmentFileFormatCSV {
+		// The header is line 1, records start at line 2
+		position = fmt.Sprintf("row %d", index+2)
+	}
+
+	if record.Name != "" {
+		position += fmt.Sprintf(" (%s)", record.Name)
+	}
+
+	return position
+}
+
+func (handler *Handler) validateEnvironmentRecord(record environmentRecord, groupIDs map[string]portainer.EndpointGroupID, tagIDs map[string]portainer.TagID, tlsFiles map[string][]byte) (importedEnvironment, error) {
+	environment := importedEnvironment{}
+
+	if strings.TrimSpace(record.Name) == "" {
+		return environment, errors.New("name cannot be empty")
+	}
+
+	endpointType, ok := environmentRecordTypes[record.Type]
+	if !ok {
+		return environment, fmt.Errorf("invalid type %q. Value must be one of: %s", record.Type, strings.Join(slices.Sorted(maps.Keys(environmentRecordTypes)), ", "))
+	}
+
+	if endpointType == portainer.AzureEnvironment {
+		return environment, errors.New("azure environments cannot be imported as their credentials are not part of the file")
+	}
+
+	if record.ContainerEngine != "" && record.ContainerEngine != portainer.ContainerEngineDocker && record.ContainerEngine != portainer.ContainerEnginePodman {
+		return environment, errors.New("invalid container engine value. Value must be one of: 'docker' or 'podman'")
+	}
+
+	if record.EdgeCheckinInterval < 0 {
+		return environment, errors.New("edge checkin interval cannot be negative")
+	}
+
+	groupID := portainer.EndpointGroupID(1)
+	if record.Group != "" {
+		if groupID, ok = groupIDs[record.Group]; !ok {
+			return environment, fmt.Errorf("environment group %q does not exist", record.Group)
+		}
+	}
+
+	endpointTagIDs := make([]portainer.TagID, 0, len(record.Tags))
+	for _, tagName := range record.Tags {
+		tagID, ok := tagIDs[tagName]
+		if !ok {
+			return environment, fmt.Errorf("tag %q does not exist", tagName)
+		}
+
+		if !slices.Contains(endpointTagIDs, tagID) {
+			endpointTagIDs = append(endpointTagIDs, tagID)
+		}
+	}
+
+	var labels []portainer.Pair
+	for _, key := range slices.Sorted(maps.Keys(record.Labels)) {
+		labels = append(labels, portainer.Pair{Name: key, Value: record.Labels[key]})
+	}
+
+	if err := endpointutils.ValidateLabels(labels); err != nil {
+		return environment, err
+	}
+
+	endpoint := &portainer.Endpoint{
+		Name:               record.Name,
+		URL:                record.URL,
+		Type:               endpointType,
+		ContainerEngine:    record.ContainerEngine,
+		GroupID:            groupID,
+		PublicURL:          record.PublicURL,
+		Gpus:               []portainer.Pair{},
+		UserAccessPolicies: portainer.UserAccessPolicies{},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{},
+		TagIDs:             endpointTagIDs,
+		Labels:             labels,
+		Status:             portainer.EndpointStatusUp,
+		Snapshots:          []portainer.DockerSnapshot{},
+		Kubernetes:         portainer.KubernetesDefault(),
+	}
+
+	switch endpointType {
+	case portainer.EdgeAgentOnDockerEnvironment, portainer.EdgeAgentOnKubernetesEnvironment:
+		if record.URL == "" {
+			return environment, errors.New("URL cannot be empty for Edge environments")
+		}
+
+		portainerHost, err := edge.ParseHostForEdge(record.URL)
+		if err != nil {
+			return environment, err
+		}
+
+		if record.TLS {
+			return environment, errors.New("TLS cannot be enabled for Edge environments")
+		}
+
+		environment.portainerURL = record.URL
+		endpoint.URL = portainerHost
+		endpoint.EdgeCheckinInterval = record.EdgeCheckinInterval
+		endpoint.UserTrusted = true
+
+	case portainer.KubernetesLocalEnvironment:
+		if endpoint.URL == "" {
+			endpoint.URL = "https://kubernetes.default.svc"
+		}
+
+		endpoint.TLSConfig = portainer.TLSConfiguration{
+			TLS:           record.TLS,
+			TLSSkipVerify: record.TLSSkipVerify,
+		}
+
+	default:
+		if record.URL == "" {
+			return environment, errors.New("URL cannot be empty")
+		}
+
+		if endpointType == portainer.AgentOnKubernetesEnvironment {
+			endpoint.URL = strings.TrimPrefix(endpoint.URL, "tcp://")
+		}
+
+		if record.TLS {
+			recordTLSFiles, err := readEnvironmentRecordTLSFiles(record, tlsFiles)
+			if err != nil {
+				return environment, err
+			}
+
+			environment.tlsFiles = recordTLSFiles
+			endpoint.TLSConfig = portainer.TLSConfiguration{
+				TLS:           true,
+				TLSSkipVerify: record.TLSSkipVerify,
+			}
+		}
+	}
+
+	environment.endpoint = endpoint
+
+	return environment, nil
+}
+
+// readEnvironmentRecordTLSFiles loads the TLS files of a record and checks that they are usable. The files are
+// given inline as PEM content or reference an uploaded file, they are never read from the Portainer server.
+func readEnvironmentRecordTLSFiles(record environmentRecord, uploadedFiles map[string][]byte) (*endpointCreatePayload, error) {
+	tlsFiles := &endpointCreatePayload{
+		TLS:                 true,
+		TLSSkipVerify:       record.TLSSkipVerify,
+		TLSSkipClientVerify: record.TLSSkipClientVerify,
+	}
+
+	readFile := func(value, description string) ([]byte, error) {
+		if value == "" {
+			return nil, fmt.Errorf("%s cannot be empty when TLS is enabled", description)
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN ") {
+			return []byte(value), nil
+		}
+
+		content, ok := uploadedFiles[value]
+		if !ok {
+			return nil, fmt.Errorf("%s %q is neither a PEM content nor an uploaded TLS file", description, value)
+		}
+
+		return content, nil
+	}
+
+	var err error
+	if !record.TLSSkipVerify {
+		if tlsFiles.TLSCACertFile, err = readFile(record.TLSCACert, "TLS CA certificate"); err != nil {
+			return nil, err
+		}
+
+		if !x509.NewCertPool().AppendCertsFromPEM(tlsFiles.TLSCACertFile) {
+			return nil, errors.New("invalid TLS CA certificate")
+		}
+	}
+
+	if !record.TLSSkipClientVerify {
+		if tlsFiles.TLSCertFile, err = readFile(record.TLSCert, "TLS certificate"); err != nil {
+			return nil, err
+		}
+
+		if tlsFiles.TLSKeyFile, err = readFile(record.TLSKey, "TLS key"); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := crypto.CreateTLSConfigurationFromBytes(tlsFiles.TLSCACertFile, tlsFiles.TLSCertFile, tlsFiles.TLSKeyFile, record.TLSSkipClientVerify, record.TLSSkipVerify); err != nil {
+		return nil, fmt.Errorf("invalid TLS files: %w", err)
+	}
+
+	return tlsFiles, nil
+}
+
+func (handler *Handler) createImportedEndpoint(tx dataservices.DataStoreTx, environment *importedEnvironment) error {
+	endpoint := environment.endpoint
+	endpoint.ID = portainer.EndpointID(tx.Endpoint().GetNextIdentifier())
+
+	isEdge := endpointutils.IsEdgeEndpoint(endpoint)
+	if isEdge {
+		endpoint.EdgeKey = handler.ReverseTunnelService.GenerateEdgeKey(environment.portainerURL, endpoint.URL, int(endpoint.ID))
+
+		settings, err := tx.Settings().Settings()
+		if err != nil {
+			return err
+		}
+
+		if settings.EnforceEdgeID {
+			edgeID, err := uuid.NewV4()
+			if err != nil {
+				return err
+			}
+
+			endpoint.EdgeID = edgeID.String()
+		}
+	}
+
+	if environment.tlsFiles != nil {
+		if err := handler.storeTLSFiles(endpoint, environment.tlsFiles); err != nil {
+			return err.Err
+		}
+	}
+
+	if err := handler.saveEndpoint(tx, endpoint); err != nil {
+		return err
+	}
+
+	// UpdateTagFunc cannot be used inside a transaction
+	for _, tagID := range endpoint.TagIDs {
+		tag, err := tx.Tag().Read(tagID)
+		if err != nil {
+			return err
+		}
+
+		tag.Endpoints[endpoint.ID] = true
+
+		if err := tx.Tag().Update(tagID, tag); err != nil {
+			return err
+		}
+	}
+
+	if !isEdge {
+		return tx.EndpointRelation().Create(&portainer.EndpointRelation{
+			EndpointID: endpoint.ID,
+			EdgeStacks: map[portainer.EdgeStackID]bool{},
+		})
+	}
+
+	endpointGroup, err := tx.EndpointGroup().Read(endpoint.GroupID)
+	if err != nil {
+		return err
+	}
+
+	edgeGroups, err := tx.EdgeGroup().ReadAll()
+	if err != nil {
+		return err
+	}
+
+	edgeStacks, err := tx.EdgeStack().EdgeStacks()
+	if err != nil {
+		return err
+	}
+
+	relation, err := tx.EndpointRelation().EndpointRelation(endpoint.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, stackID := range edge.EndpointRelatedEdgeStacks(endpoint, endpointGroup, edgeGroups, edgeStacks) {
+		relation.EdgeStacks[stackID] = true
+	}
+
+	return tx.EndpointRelation().UpdateEndpointRelation(endpoint.ID, relation)
+}
@@ -0,0 +1,430 @@
+package endpoints
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newImportRequest(t *testing.T, filename, content string) *http.Request {
+	return newImportRequestWithTLSFiles(t, filename, content, nil)
+}
+
+func newImportRequestWithTLSFiles(t *testing.T, filename, content string, tlsFiles map[string]string) *http.Request {
+	var body bytes.Buffer
+
+	w := multipart.NewWriter(&body)
+
+	fw, err := w.CreateFormFile("file", filename)
+	require.NoError(t, err)
+
+	_, err = fw.Write([]byte(content))
+	require.NoError(t, err)
+
+	for name, content := range tlsFiles {
+		fw, err := w.CreateFormFile("TLSFiles", name)
+		require.NoError(t, err)
+
+		_, err = fw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/endpoints/import", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	return req
+}
+
+func setupImportHandler(t *testing.T) *Handler {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	err := store.EndpointGroup().Create(&portainer.EndpointGroup{ID: 2, Name: "production"})
+	require.NoError(t, err)
+
+	err = store.Tag().Create(&portainer.Tag{ID: 1, Name: "linux", Endpoints: map[portainer.EndpointID]bool{}})
+	require.NoError(t, err)
+
+	err = store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "existing", Type: portainer.DockerEnvironment})
+	require.NoError(t, err)
+
+	handler := NewHandler(testhelpers.NewTestRequestBouncer())
+	handler.DataStore = store
+
+	return handler
+}
+
+func TestEnvironmentRecordsRoundTrip(t *testing.T) {
+	records := []environmentRecord{
+		{
+			Name:          "docker-1",
+			Type:          environmentRecordTypeDockerAgent,
+			URL:           "10.0.0.1:9001",
+			Group:         "production",
+			Tags:          []string{"linux", "amd64"},
+			Labels:        map[string]string{"site": "paris", "owner": "team-a"},
+			TLS:           true,
+			TLSSkipVerify: true,
+			TLSCert:       "/certs/cert.pem",
+			TLSKey:        "/certs/key.pem",
+		},
+		{
+			Name:                "edge-1",
+			Type:                environmentRecordTypeDockerEdge,
+			URL:                 "https://portainer.example.com",
+			EdgeCheckinInterval: 30,
+		},
+	}
+
+	for _, format := range []string{environmentFileFormatCSV, environmentFileFormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			content, err := writeEnvironmentRecords(format, records)
+			require.NoError(t, err)
+
+			parsed, err := parseEnvironmentRecords(format, []byte(content))
+			require.NoError(t, err)
+			require.Equal(t, records, parsed)
+		})
+	}
+}
+
+func TestParseEnvironmentRecordsCSVErrors(t *testing.T) {
+	_, err := parseEnvironmentRecordsCSV(strings.NewReader("name,kind\nenv,docker\n"))
+	require.ErrorContains(t, err, `unknown CSV column "kind"`)
+
+	_, err = parseEnvironmentRecordsCSV(strings.NewReader("name,url\nenv,tcp://10.0.0.1:2375\n"))
+	require.ErrorContains(t, err, `missing required CSV column "type"`)
+
+	_, err = parseEnvironmentRecordsCSV(strings.NewReader("name,type,tls\nenv,docker,maybe\n"))
+	require.ErrorContains(t, err, "row 2")
+}
+
+func TestEndpointImport(t *testing.T) {
+	handler := setupImportHandler(t)
+
+	content := `name,type,url,group,tags,labels
+docker-1,docker,tcp://10.0.0.1:2375,production,linux,site=paris
+docker-2,docker,tcp://10.0.0.2:2375,,,
+`
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newImportRequest(t, "environments.csv", content))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var created []portainer.Endpoint
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	require.Len(t, created, 2)
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(created[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, "docker-1", endpoint.Name)
+	require.Equal(t, portainer.EndpointGroupID(2), endpoint.GroupID)
+	require.Equal(t, []portainer.TagID{1}, endpoint.TagIDs)
+	require.Equal(t, []portainer.Pair{{Name: "site", Value: "paris"}}, endpoint.Labels)
+
+	tag, err := handler.DataStore.Tag().Read(1)
+	require.NoError(t, err)
+	require.True(t, tag.Endpoints[endpoint.ID])
+
+	_, err = handler.DataStore.EndpointRelation().EndpointRelation(endpoint.ID)
+	require.NoError(t, err)
+
+	endpoint, err = handler.DataStore.Endpoint().Endpoint(created[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, portainer.EndpointGroupID(1), endpoint.GroupID)
+}
+
+// newTLSFiles returns a self-signed certificate and its key in the PEM format
+func newTLSFiles(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "docker"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return string(certificate), string(privateKey)
+}
+
+func TestEndpointImportTLSFiles(t *testing.T) {
+	handler := setupImportHandler(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+	handler.FileService = fileService
+
+	certificate, key := newTLSFiles(t)
+	indentedCertificate := "      " + strings.ReplaceAll(strings.TrimSpace(certificate), "\n", "\n      ")
+
+	content := `environments:
+  - name: tls
+    type: docker
+    url: tcp://10.0.0.1:2376
+    tls: true
+    tlsCACert: |
+` + indentedCertificate + `
+    tlsCert: client.pem
+    tlsKey: client-key.pem
+`
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newImportRequestWithTLSFiles(t, "environments.yaml", content, map[string]string{
+		"client.pem":     certificate,
+		"client-key.pem": key,
+	}))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var created []portainer.Endpoint
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	require.Len(t, created, 1)
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(created[0].ID)
+	require.NoError(t, err)
+
+	stored, err := fileService.GetFileContent(endpoint.TLSConfig.TLSKeyPath, "")
+	require.NoError(t, err)
+	require.Equal(t, key, string(stored))
+
+	// the certificates are exported inline, the key is referenced by a file name
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/endpoints/export?format=csv", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	records, err := parseEnvironmentRecordsCSV(rec.Body)
+	require.NoError(t, err)
+	i := slices.IndexFunc(records, func(record environmentRecord) bool { return record.Name == "tls" })
+	require.GreaterOrEqual(t, i, 0)
+	require.Equal(t, strings.TrimSpace(certificate), strings.TrimSpace(records[i].TLSCACert))
+	require.Equal(t, strings.TrimSpace(certificate), strings.TrimSpace(records[i].TLSCert))
+	require.Equal(t, tlsKeyFileName(endpoint.ID), records[i].TLSKey)
+}
+
+func TestEndpointExportImportTLSRoundTrip(t *testing.T) {
+	certificate, key := newTLSFiles(t)
+
+	for _, format := range []string{environmentFileFormatYAML, environmentFileFormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			source := setupImportHandler(t)
+
+			err := source.DataStore.Endpoint().DeleteEndpoint(1)
+			require.NoError(t, err)
+
+			fileService, err := filesystem.NewService(t.TempDir(), "")
+			require.NoError(t, err)
+			source.FileService = fileService
+
+			endpoint := &portainer.Endpoint{
+				ID:      2,
+				Name:    "tls",
+				Type:    portainer.DockerEnvironment,
+				URL:     "tcp://10.0.0.1:2376",
+				GroupID: 2,
+				TagIDs:  []portainer.TagID{1},
+			}
+
+			caCertPath, err := fileService.StoreTLSFileFromBytes("2", portainer.TLSFileCA, []byte(certificate))
+			require.NoError(t, err)
+			certPath, err := fileService.StoreTLSFileFromBytes("2", portainer.TLSFileCert, []byte(certificate))
+			require.NoError(t, err)
+			keyPath, err := fileService.StoreTLSFileFromBytes("2", portainer.TLSFileKey, []byte(key))
+			require.NoError(t, err)
+
+			endpoint.TLSConfig = portainer.TLSConfiguration{
+				TLS:           true,
+				TLSCACertPath: caCertPath,
+				TLSCertPath:   certPath,
+				TLSKeyPath:    keyPath,
+			}
+
+			err = source.DataStore.Endpoint().Create(endpoint)
+			require.NoError(t, err)
+
+			export := func(handler *Handler) string {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/endpoints/export?format="+format, nil))
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+				return rec.Body.String()
+			}
+
+			exported := export(source)
+
+			// the file is imported as is in another instance, the key is uploaded under the name it is referenced by
+			target := setupImportHandler(t)
+
+			targetFileService, err := filesystem.NewService(t.TempDir(), "")
+			require.NoError(t, err)
+			target.FileService = targetFileService
+
+			err = target.DataStore.Endpoint().DeleteEndpoint(1)
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			target.ServeHTTP(rec, newImportRequestWithTLSFiles(t, "environments."+format, exported, map[string]string{
+				tlsKeyFileName(endpoint.ID): key,
+			}))
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			var created []portainer.Endpoint
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+			require.Len(t, created, 1)
+
+			imported, err := target.DataStore.Endpoint().Endpoint(created[0].ID)
+			require.NoError(t, err)
+			require.Equal(t, "tls", imported.Name)
+			require.True(t, imported.TLSConfig.TLS)
+			require.Equal(t, portainer.EndpointGroupID(2), imported.GroupID)
+			require.Equal(t, []portainer.TagID{1}, imported.TagIDs)
+
+			stored, err := targetFileService.GetFileContent(imported.TLSConfig.TLSKeyPath, "")
+			require.NoError(t, err)
+			require.Equal(t, key, string(stored))
+
+			// the file exported by the target describes the same environments
+			sourceRecords, err := parseEnvironmentRecords(format, []byte(exported))
+			require.NoError(t, err)
+			targetRecords, err := parseEnvironmentRecords(format, []byte(export(target)))
+			require.NoError(t, err)
+			require.Len(t, targetRecords, len(sourceRecords))
+
+			// only the reference to the key differs, the environment has a new identifier
+			require.Equal(t, tlsKeyFileName(imported.ID), targetRecords[0].TLSKey)
+			targetRecords[0].TLSKey = sourceRecords[0].TLSKey
+			require.Equal(t, sourceRecords, targetRecords)
+		})
+	}
+}
+
+func TestEndpointImportRejectsServerPaths(t *testing.T) {
+	handler := setupImportHandler(t)
+
+	dir := t.TempDir()
+	certificate, key := newTLSFiles(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"), []byte(certificate), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), []byte(key), 0o600))
+
+	content := `environments:
+  - name: tls
+    type: docker
+    url: tcp://10.0.0.1:2376
+    tls: true
+    tlsSkipVerify: true
+    tlsCert: ` + filepath.Join(dir, "cert.pem") + `
+    tlsKey: ` + filepath.Join(dir, "key.pem") + `
+`
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newImportRequest(t, "environments.yaml", content))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "is neither a PEM content nor an uploaded TLS file")
+}
+
+func TestEndpointImportValidation(t *testing.T) {
+	handler := setupImportHandler(t)
+
+	content := `environments:
+  - name: existing
+    type: docker
+    url: tcp://10.0.0.1:2375
+  - name: valid
+    type: docker
+    url: tcp://10.0.0.2:2375
+  - name: unknown-group
+    type: docker
+    url: tcp://10.0.0.3:2375
+    group: staging
+  - name: azure
+    type: azure
+  - name: valid
+    type: docker
+    url: tcp://10.0.0.4:2375
+`
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newImportRequest(t, "environments.yaml", content))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	body := rec.Body.String()
+	require.Contains(t, body, `Environment 1 (existing): an environment named \"existing\" already exists`)
+	require.NotContains(t, body, "environment 2 ")
+	require.Contains(t, body, `environment 3 (unknown-group): environment group \"staging\" does not exist`)
+	require.Contains(t, body, "environment 4 (azure)")
+	require.Contains(t, body, `environment 5 (valid): an environment named \"valid\" already exists`)
+
+	// Nothing is created when an entry is invalid
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+}
+
+func TestEndpointExport(t *testing.T) {
+	handler := setupImportHandler(t)
+
+	err := handler.DataStore.Endpoint().Create(&portainer.Endpoint{
+		ID:      2,
+		Name:    "edge",
+		Type:    portainer.EdgeAgentOnDockerEnvironment,
+		URL:     "portainer.example.com",
+		GroupID: 2,
+		TagIDs:  []portainer.TagID{1},
+		EdgeKey: "aHR0cHM6Ly9wb3J0YWluZXIuZXhhbXBsZS5jb218cG9ydGFpbmVyLmV4YW1wbGUuY29tOjgwMDB8ZmluZ2VycHJpbnR8Mg",
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/endpoints/export?format=csv", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+
+	records, err := parseEnvironmentRecordsCSV(rec.Body)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, environmentRecord{Name: "existing", Type: environmentRecordTypeDocker}, records[0])
+	require.Equal(t, environmentRecord{
+		Name:  "edge",
+		Type:  environmentRecordTypeDockerEdge,
+		URL:   "https://portainer.example.com",
+		Group: "production",
+		Tags:  []string{"linux"},
+	}, records[1])
+}
@@ -29,7 +29,7 @@ const (
 // @produce json
 // @param start query int false "Start searching from"
 // @param limit query int false "Limit results to this value"
-// @param sort query sortKey false "Sort results by this value" Enum("Name", "Group", "Status", "LastCheckIn", "EdgeID")
+// @param sort query sortKey false "Sort results by this value, Label:<key> sorts by the value of the label" Enum("Name", "Group", "Status", "LastCheckIn", "EdgeID", "Label:<key>")
 // @param order query int false "Order sorted results by desc/asc" Enum("asc", "desc")
 // @param search query string false "Search query"
 // @param groupIds query []int false "List environments(endpoints) of these groups"
@@ -51,6 +51,7 @@ const (
 // @param edgeStackStatus query string false "only applied when edgeStackId exists. Filter the returned environments based on their deployment status in the stack (not the environment status!)" Enum("Pending", "Ok", "Error", "Acknowledged", "Remove", "RemoteUpdateSuccess", "ImagesPulled")
 // @param edgeGroupIds query []int false "List environments(endpoints) of these edge groups"
 // @param excludeEdgeGroupIds query []int false "Exclude environments(endpoints) of these edge groups"
+// @param labels query []string false "will return only environments(endpoints) having all these labels, including the ones inherited from their group, formatted as key:value or key"
 // @success 200 {array} portainer.Endpoint "Endpoints"
 // @failure 500 "Server error"
 // @router /endpoints [get]
@@ -46,7 +46,9 @@ type endpointUpdatePayload struct {
 	// Azure authentication key
 	AzureAuthenticationKey *string `example:"cOrXoK/1D35w8YQ8nH1/8ZGwzz45JIYD5jxHKXEQknk="`
 	// List of tag identifiers to which this environment(endpoint) is associated
-	TagIDs             []portainer.TagID `example:"1,2"`
+	TagIDs []portainer.TagID `example:"1,2"`
+	// List of key/value labels associated to this environment(endpoint)
+	Labels             []portainer.Pair
 	UserAccessPolicies portainer.UserAccessPolicies
 	TeamAccessPolicies portainer.TeamAccessPolicies
 	// The check in interval for edge agent (in seconds)
@@ -56,7 +58,7 @@ type endpointUpdatePayload struct {
 }
 
 func (payload *endpointUpdatePayload) Validate(r *http.Request) error {
-	return nil
+	return endpointutils.ValidateLabels(payload.Labels)
 }
 
 // @id EndpointUpdate
@@ -95,6 +97,7 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 	}
 
 	updateEndpointProxy := shouldReloadTLSConfiguration(endpoint, &payload)
+	updateRelations := false
 
 	if payload.Name != nil {
 		name := *payload.Name
@@ -104,6 +107,8 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 			return httperror.Conflict("Name is not unique", nil)
 		}
 
+		// the edge groups can select the environments by name
+		updateRelations = name != endpoint.Name
 		endpoint.Name = name
 	}
 
@@ -119,8 +124,6 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 	endpoint.PublicURL = *cmp.Or(payload.PublicURL, &endpoint.PublicURL)
 	endpoint.EdgeCheckinInterval = *cmp.Or(payload.EdgeCheckinInterval, &endpoint.EdgeCheckinInterval)
 
-	updateRelations := false
-
 	if payload.GroupID != nil {
 		groupID := portainer.EndpointGroupID(*payload.GroupID)
 
@@ -144,6 +147,11 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 		}
 	}
 
+	if payload.Labels != nil {
+		updateRelations = updateRelations || !reflect.DeepEqual(payload.Labels, endpoint.Labels)
+		endpoint.Labels = payload.Labels
+	}
+
 	updateAuthorizations := false
 
 	if payload.Kubernetes != nil {
@@ -2,9 +2,11 @@ package endpoints
 
 import (
 	"net/http"
+	"reflect"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/endpointutils"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
 	"github.com/portainer/portainer/pkg/libhttp/response"
@@ -17,14 +19,19 @@ type endpointUpdateRelationsPayload struct {
 		EdgeGroups []portainer.EdgeGroupID
 		Tags       []portainer.TagID
 		Group      portainer.EndpointGroupID
+		Labels     []portainer.Pair
 	}
 }
 
 func (payload *endpointUpdateRelationsPayload) Validate(r *http.Request) error {
-	for eID := range payload.Relations {
+	for eID, relation := range payload.Relations {
 		if eID == 0 {
 			return errors.New("Missing environment identifier")
 		}
+
+		if err := endpointutils.ValidateLabels(relation.Labels); err != nil {
+			return errors.WithMessagef(err, "Invalid labels for environment %d", eID)
+		}
 	}
 
 	return nil
@@ -33,7 +40,7 @@ func (payload *endpointUpdateRelationsPayload) Validate(r *http.Request) error {
 // @id EndpointUpdateRelations
 // @summary Update relations for a list of environments
 // @description Update relations for a list of environments
-// @description Edge groups, tags and environment group can be updated.
+// @description Edge groups, tags, labels and environment group can be updated.
 // @description
 // @description **Access policy**: administrator
 // @tags endpoints
@@ -78,6 +85,12 @@ func (handler *Handler) updateRelations(w http.ResponseWriter, r *http.Request)
 				updateRelations = updateRelations || tagsChanged
 			}
 
+			if relationPayload.Labels != nil {
+				labelsChanged := !reflect.DeepEqual(relationPayload.Labels, endpoint.Labels)
+				endpoint.Labels = relationPayload.Labels
+				updateRelations = updateRelations || labelsChanged
+			}
+
 			if relationPayload.EdgeGroups != nil {
 				edgeGroupsChanged, err := updateEnvironmentEdgeGroups(tx, relationPayload.EdgeGroups, endpoint.ID)
 				if err != nil {
@@ -0,0 +1,334 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	environmentFileFormatCSV  = "csv"
+	environmentFileFormatYAML = "yaml"
+)
+
+// Environment types as they are written in the import/export files
+const (
+	environmentRecordTypeDocker          = "docker"
+	environmentRecordTypeDockerAgent     = "docker-agent"
+	environmentRecordTypeDockerEdge      = "docker-edge"
+	environmentRecordTypeKubernetesLocal = "kubernetes-local"
+	environmentRecordTypeKubernetesAgent = "kubernetes-agent"
+	environmentRecordTypeKubernetesEdge  = "kubernetes-edge"
+	environmentRecordTypeAzure           = "azure"
+)
+
+var environmentRecordTypes = map[string]portainer.EndpointType{
+	environmentRecordTypeDocker:          portainer.DockerEnvironment,
+	environmentRecordTypeDockerAgent:     portainer.AgentOnDockerEnvironment,
+	environmentRecordTypeDockerEdge:      portainer.EdgeAgentOnDockerEnvironment,
+	environmentRecordTypeKubernetesLocal: portainer.KubernetesLocalEnvironment,
+	environmentRecordTypeKubernetesAgent: portainer.AgentOnKubernetesEnvironment,
+	environmentRecordTypeKubernetesEdge:  portainer.EdgeAgentOnKubernetesEnvironment,
+	environmentRecordTypeAzure:           portainer.AzureEnvironment,
+}
+
+// environmentRecord is the representation of an environment inside an import/export file.
+// TLS files are given inline as PEM content or reference by name a file uploaded with the import, the keys are never exported.
+type environmentRecord struct {
+	Name                string            `yaml:"name"`
+	Type                string            `yaml:"type"`
+	URL                 string            `yaml:"url,omitempty"`
+	PublicURL           string            `yaml:"publicUrl,omitempty"`
+	Group               string            `yaml:"group,omitempty"`
+	Tags                []string          `yaml:"tags,omitempty"`
+	Labels              map[string]string `yaml:"labels,omitempty"`
+	ContainerEngine     string            `yaml:"containerEngine,omitempty"`
+	TLS                 bool              `yaml:"tls,omitempty"`
+	TLSSkipVerify       bool              `yaml:"tlsSkipVerify,omitempty"`
+	TLSSkipClientVerify bool              `yaml:"tlsSkipClientVerify,omitempty"`
+	TLSCACert           string            `yaml:"tlsCACert,omitempty"`
+	TLSCert             string            `yaml:"tlsCert,omitempty"`
+	TLSKey              string            `yaml:"tlsKey,omitempty"`
+	EdgeCheckinInterval int               `yaml:"edgeCheckinInterval,omitempty"`
+}
+
+type environmentRecordsFile struct {
+	Environments []environmentRecord `yaml:"environments"`
+}
+
+// CSV columns, in the order they are exported
+const (
+	csvColumnName                = "name"
+	csvColumnType                = "type"
+	csvColumnURL                 = "url"
+	csvColumnPublicURL           = "public_url"
+	csvColumnGroup               = "group"
+	csvColumnTags                = "tags"
+	csvColumnLabels              = "labels"
+	csvColumnContainerEngine     = "container_engine"
+	csvColumnTLS                 = "tls"
+	csvColumnTLSSkipVerify       = "tls_skip_verify"
+	csvColumnTLSSkipClientVerify = "tls_skip_client_verify"
+	csvColumnTLSCACert           = "tls_ca_cert"
+	csvColumnTLSCert             = "tls_cert"
+	csvColumnTLSKey              = "tls_key"
+	csvColumnEdgeCheckinInterval = "edge_checkin_interval"
+)
+
+var csvColumns = []string{
+	csvColumnName,
+	csvColumnType,
+	csvColumnURL,
+	csvColumnPublicURL,
+	csvColumnGroup,
+	csvColumnTags,
+	csvColumnLabels,
+	csvColumnContainerEngine,
+	csvColumnTLS,
+	csvColumnTLSSkipVerify,
+	csvColumnTLSSkipClientVerify,
+	csvColumnTLSCACert,
+	csvColumnTLSCert,
+	csvColumnTLSKey,
+	csvColumnEdgeCheckinInterval,
+}
+
+// CSV cells holding several values use ';' to separate them, labels are written as key=value
+const csvListSeparator = ";"
+
+func parseEnvironmentRecords(format string, data []byte) ([]environmentRecord, error) {
+	switch format {
+	case environmentFileFormatCSV:
+		return parseEnvironmentRecordsCSV(bytes.NewReader(data))
+	case environmentFileFormatYAML:
+		return parseEnvironmentRecordsYAML(data)
+	}
+
+	return nil, fmt.Errorf("unsupported file format %q, must be one of: csv, yaml", format)
+}
+
+func parseEnvironmentRecordsYAML(data []byte) ([]environmentRecord, error) {
+	var file environmentRecordsFile
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid YAML file: %w", err)
+	}
+
+	return file.Environments, nil
+}
+
+func parseEnvironmentRecordsCSV(r io.Reader) ([]environmentRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(csvColumns, column) {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+
+		if _, ok := columns[column]; ok {
+			return nil, fmt.Errorf("duplicate CSV column %q", column)
+		}
+
+		columns[column] = i
+	}
+
+	for _, column := range []string{csvColumnName, csvColumnType} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("missing required CSV column %q", column)
+		}
+	}
+
+	var records []environmentRecord
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid CSV file: %w", err)
+		}
+
+		record, err := environmentRecordFromCSV(columns, row)
+		if err != nil {
+			// The header is line 1, records start at line 2
+			return nil, fmt.Errorf("row %d: %w", len(records)+2, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func environmentRecordFromCSV(columns map[string]int, row []string) (environmentRecord, error) {
+	value := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+
+		return strings.TrimSpace(row[i])
+	}
+
+	boolValue := func(column string) (bool, error) {
+		v := value(column)
+		if v == "" {
+			return false, nil
+		}
+
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("invalid boolean value %q for column %q", v, column)
+		}
+
+		return b, nil
+	}
+
+	record := environmentRecord{
+		Name:            value(csvColumnName),
+		Type:            value(csvColumnType),
+		URL:             value(csvColumnURL),
+		PublicURL:       value(csvColumnPublicURL),
+		Group:           value(csvColumnGroup),
+		ContainerEngine: value(csvColumnContainerEngine),
+		TLSCACert:       value(csvColumnTLSCACert),
+		TLSCert:         value(csvColumnTLSCert),
+		TLSKey:          value(csvColumnTLSKey),
+	}
+
+	var err error
+	if record.TLS, err = boolValue(csvColumnTLS); err != nil {
+		return record, err
+	}
+
+	if record.TLSSkipVerify, err = boolValue(csvColumnTLSSkipVerify); err != nil {
+		return record, err
+	}
+
+	if record.TLSSkipClientVerify, err = boolValue(csvColumnTLSSkipClientVerify); err != nil {
+		return record, err
+	}
+
+	if v := value(csvColumnEdgeCheckinInterval); v != "" {
+		if record.EdgeCheckinInterval, err = strconv.Atoi(v); err != nil {
+			return record, fmt.Errorf("invalid edge checkin interval %q", v)
+		}
+	}
+
+	for _, tag := range strings.Split(value(csvColumnTags), csvListSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			record.Tags = append(record.Tags, tag)
+		}
+	}
+
+	for _, label := range strings.Split(value(csvColumnLabels), csvListSeparator) {
+		if label = strings.TrimSpace(label); label == "" {
+			continue
+		}
+
+		key, v, _ := strings.Cut(label, "=")
+		key = strings.TrimSpace(key)
+
+		if record.Labels == nil {
+			record.Labels = map[string]string{}
+		}
+
+		if _, ok := record.Labels[key]; ok {
+			return record, fmt.Errorf("duplicate label %q", key)
+		}
+
+		record.Labels[key] = strings.TrimSpace(v)
+	}
+
+	return record, nil
+}
+
+func writeEnvironmentRecords(format string, records []environmentRecord) (string, error) {
+	switch format {
+	case environmentFileFormatCSV:
+		return writeEnvironmentRecordsCSV(records)
+	case environmentFileFormatYAML:
+		data, err := yaml.Marshal(environmentRecordsFile{Environments: records})
+		return string(data), err
+	}
+
+	return "", fmt.Errorf("unsupported file format %q, must be one of: csv, yaml", format)
+}
+
+func writeEnvironmentRecordsCSV(records []environmentRecord) (string, error) {
+	var buf bytes.Buffer
+
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(csvColumns); err != nil {
+		return "", err
+	}
+
+	for _, record := range records {
+		labels := make([]string, 0, len(record.Labels))
+		for _, key := range slices.Sorted(maps.Keys(record.Labels)) {
+			labels = append(labels, key+"="+record.Labels[key])
+		}
+
+		edgeCheckinInterval := ""
+		if record.EdgeCheckinInterval != 0 {
+			edgeCheckinInterval = strconv.Itoa(record.EdgeCheckinInterval)
+		}
+
+		if err := writer.Write([]string{
+			record.Name,
+			record.Type,
+			record.URL,
+			record.PublicURL,
+			record.Group,
+			strings.Join(record.Tags, csvListSeparator),
+			strings.Join(labels, csvListSeparator),
+			record.ContainerEngine,
+			strconv.FormatBool(record.TLS),
+			strconv.FormatBool(record.TLSSkipVerify),
+			strconv.FormatBool(record.TLSSkipClientVerify),
+			record.TLSCACert,
+			record.TLSCert,
+			record.TLSKey,
+			edgeCheckinInterval,
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	writer.Flush()
+
+	return buf.String(), writer.Error()
+}
+
+func environmentRecordType(endpointType portainer.EndpointType) string {
+	for name, t := range environmentRecordTypes {
+		if t == endpointType {
+			return name
+		}
+	}
+
+	return strconv.Itoa(int(endpointType))
+}
@@ -40,6 +40,13 @@ type EnvironmentsQuery struct {
 	excludeIds               []portainer.EndpointID
 	edgeGroupIds             []portainer.EdgeGroupID
 	excludeEdgeGroupIds      []portainer.EdgeGroupID
+	labels                   []labelFilter
+}
+
+// labelFilter matches environments having the label key, with the given value when it is not nil
+type labelFilter struct {
+	key   string
+	value *string
 }
 
 func parseQuery(r *http.Request) (EnvironmentsQuery, error) {
@@ -92,6 +99,8 @@ func parseQuery(r *http.Request) (EnvironmentsQuery, error) {
 
 	agentVersions := getArrayQueryParameter(r, "agentVersions")
 
+	labels := parseLabelFilters(getArrayQueryParameter(r, "labels"))
+
 	name, _ := request.RetrieveQueryParameter(r, "name", true)
 
 	var edgeAsync *bool
@@ -132,9 +141,27 @@ func parseQuery(r *http.Request) (EnvironmentsQuery, error) {
 		edgeStackStatus:          edgeStackStatus,
 		edgeGroupIds:             edgeGroupIDs,
 		excludeEdgeGroupIds:      excludeEdgeGroupIds,
+		labels:                   labels,
 	}, nil
 }
 
+// parseLabelFilters parses "key:value" filters, a filter without value matching any environment having the key
+func parseLabelFilters(params []string) []labelFilter {
+	filters := make([]labelFilter, 0, len(params))
+	for _, param := range params {
+		key, value, found := strings.Cut(param, ":")
+
+		filter := labelFilter{key: key}
+		if found {
+			filter.value = &value
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters
+}
+
 func (handler *Handler) filterEndpointsByQuery(
 	filteredEndpoints []portainer.Endpoint,
 	query EnvironmentsQuery,
@@ -238,6 +265,10 @@ func (handler *Handler) filterEndpointsByQuery(
 		filteredEndpoints = filteredEndpointsByTags(filteredEndpoints, query.tagIds, groups, query.tagsPartialMatch)
 	}
 
+	if len(query.labels) > 0 {
+		filteredEndpoints = filterEndpointsByLabels(filteredEndpoints, groups, query.labels)
+	}
+
 	if len(query.agentVersions) > 0 {
 		filteredEndpoints = filter(filteredEndpoints, func(endpoint portainer.Endpoint) bool {
 			return !endpointutils.IsAgentEndpoint(&endpoint) || contains(query.agentVersions, endpoint.Agent.Version)
@@ -283,7 +314,7 @@ func filterEndpointsByEdgeStack(endpoints []portainer.Endpoint, edgeStackId port
 		}
 
 		if edgeGroup.Dynamic {
-			endpointIDs, err := edgegroups.GetEndpointsByTags(datastore, edgeGroup.TagIDs, edgeGroup.PartialMatch)
+			endpointIDs, err := edgegroups.GetDynamicEdgeGroupEndpoints(datastore, edgeGroup)
 			if err != nil {
 				return nil, errors.WithMessage(err, "Unable to retrieve environments and environment groups for Edge group")
 			}
@@ -329,6 +360,26 @@ func filterEndpointsByGroupIDs(endpoints []portainer.Endpoint, endpointGroupIDs
 	return endpoints[:n]
 }
 
+func filterEndpointsByLabels(endpoints []portainer.Endpoint, endpointGroups []portainer.EndpointGroup, labelFilters []labelFilter) []portainer.Endpoint {
+	groupsByID := make(map[portainer.EndpointGroupID]*portainer.EndpointGroup, len(endpointGroups))
+	for i := range endpointGroups {
+		groupsByID[endpointGroups[i].ID] = &endpointGroups[i]
+	}
+
+	return filter(endpoints, func(endpoint portainer.Endpoint) bool {
+		labels := endpointutils.EndpointLabels(&endpoint, groupsByID[endpoint.GroupID])
+
+		for _, labelFilter := range labelFilters {
+			value, ok := endpointutils.LabelValue(labels, labelFilter.key)
+			if !ok || (labelFilter.value != nil && value != *labelFilter.value) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
 func filterEndpointsByEdgeGroupIDs(endpoints []portainer.Endpoint, edgeGroups []portainer.EdgeGroup, edgeGroupIDs []portainer.EdgeGroupID) ([]portainer.Endpoint, []portainer.EdgeGroup) {
 	edgeGroupIDFilterSet := make(map[portainer.EdgeGroupID]struct{}, len(edgeGroupIDs))
 	for _, id := range edgeGroupIDs {
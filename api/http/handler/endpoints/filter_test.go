@@ -150,6 +150,39 @@ func Test_Filter_excludeIDs(t *testing.T) {
 	runTests(tests, t, handler, environments)
 }
 
+func Test_Filter_labels(t *testing.T) {
+	environments := []portainer.Endpoint{
+		{ID: 1, GroupID: 1, Labels: []portainer.Pair{{Name: "site", Value: "paris"}, {Name: "owner", Value: "team-x"}}},
+		{ID: 2, GroupID: 1, Labels: []portainer.Pair{{Name: "site", Value: "lyon"}}},
+		{ID: 3, GroupID: 2},
+		{ID: 4, GroupID: 2, Labels: []portainer.Pair{{Name: "site", Value: "lyon"}}},
+	}
+
+	environmentGroups := []portainer.EndpointGroup{
+		{ID: 1},
+		{ID: 2, EnvironmentLabels: []portainer.Pair{{Name: "site", Value: "paris"}, {Name: "owner", Value: "team-y"}}},
+	}
+
+	tests := []struct {
+		title    string
+		labels   []string
+		expected []portainer.EndpointID
+	}{
+		{"should match a label value", []string{"site:paris"}, []portainer.EndpointID{1, 3}},
+		{"should match a label key", []string{"owner"}, []portainer.EndpointID{1, 3, 4}},
+		{"should match all the labels", []string{"site:lyon", "owner:team-y"}, []portainer.EndpointID{4}},
+		{"should match an empty value", []string{"site:"}, []portainer.EndpointID{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			filtered := filterEndpointsByLabels(append([]portainer.Endpoint{}, environments...), environmentGroups, parseLabelFilters(test.labels))
+
+			assert.ElementsMatch(t, test.expected, getEndpointIDs(filtered))
+		})
+	}
+}
+
 func BenchmarkFilterEndpointsBySearchCriteria_PartialMatch(b *testing.B) {
 	n := 10000
 
@@ -2,9 +2,11 @@ package endpoints
 
 import (
 	"slices"
+	"strings"
 
 	"github.com/fvbommel/sortorder"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/endpointutils"
 )
 
 type comp[T any] func(a, b T) int
@@ -61,6 +63,25 @@ func sortEnvironmentsByField(environments []portainer.Endpoint, environmentGroup
 			return stringComp(a.EdgeID, b.EdgeID)
 		}
 
+	default:
+		labelKey, ok := strings.CutPrefix(string(sortField), sortKeyLabelPrefix)
+		if !ok {
+			return
+		}
+
+		environmentGroupsByID := make(map[portainer.EndpointGroupID]*portainer.EndpointGroup, len(environmentGroups))
+		for i := range environmentGroups {
+			environmentGroupsByID[environmentGroups[i].ID] = &environmentGroups[i]
+		}
+
+		labelValue := func(environment portainer.Endpoint) string {
+			value, _ := endpointutils.LabelValue(endpointutils.EndpointLabels(&environment, environmentGroupsByID[environment.GroupID]), labelKey)
+			return value
+		}
+
+		less = func(a, b portainer.Endpoint) int {
+			return stringComp(labelValue(a), labelValue(b))
+		}
 	}
 
 	slices.SortStableFunc(environments, func(a, b portainer.Endpoint) int {
@@ -82,6 +103,9 @@ const (
 	sortKeyStatus          sortKey = "Status"
 	sortKeyLastCheckInDate sortKey = "LastCheckIn"
 	sortKeyEdgeID          sortKey = "EdgeID"
+
+	// sortKeyLabelPrefix prefixes the label key to sort by, e.g. "Label:site"
+	sortKeyLabelPrefix = "Label:"
 )
 
 func getSortKey(sortField string) sortKey {
@@ -90,5 +114,9 @@ func getSortKey(sortField string) sortKey {
 		return fieldAsSortKey
 	}
 
+	if len(sortField) > len(sortKeyLabelPrefix) && strings.HasPrefix(sortField, sortKeyLabelPrefix) {
+		return fieldAsSortKey
+	}
+
 	return ""
 }
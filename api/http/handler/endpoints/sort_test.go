@@ -11,14 +11,14 @@ import (
 
 func TestSortEndpointsByField(t *testing.T) {
 	environments := []portainer.Endpoint{
-		{ID: 0, Name: "Environment 1", GroupID: 1, Status: 1, LastCheckInDate: 3, EdgeID: "edge32"},
-		{ID: 1, Name: "Environment 2", GroupID: 2, Status: 2, LastCheckInDate: 6, EdgeID: "edge57"},
+		{ID: 0, Name: "Environment 1", GroupID: 1, Status: 1, LastCheckInDate: 3, EdgeID: "edge32", Labels: []portainer.Pair{{Name: "site", Value: "b"}}},
+		{ID: 1, Name: "Environment 2", GroupID: 2, Status: 2, LastCheckInDate: 6, EdgeID: "edge57", Labels: []portainer.Pair{{Name: "site", Value: "d"}}},
 		{ID: 2, Name: "Environment 3", GroupID: 1, Status: 3, LastCheckInDate: 2, EdgeID: "test87"},
-		{ID: 3, Name: "Environment 4", GroupID: 2, Status: 4, LastCheckInDate: 1, EdgeID: "abc123"},
+		{ID: 3, Name: "Environment 4", GroupID: 2, Status: 4, LastCheckInDate: 1, EdgeID: "abc123", Labels: []portainer.Pair{{Name: "site", Value: "a"}}},
 	}
 
 	environmentGroups := []portainer.EndpointGroup{
-		{ID: 1, Name: "Group 1"},
+		{ID: 1, Name: "Group 1", EnvironmentLabels: []portainer.Pair{{Name: "site", Value: "c"}}},
 		{ID: 2, Name: "Group 2"},
 	}
 
@@ -137,6 +137,16 @@ func TestSortEndpointsByField(t *testing.T) {
 				environments[2].ID,
 			},
 		},
+		{
+			name:      "sort by label ascending",
+			sortField: "Label:site",
+			expected: []portainer.EndpointID{
+				environments[3].ID,
+				environments[0].ID,
+				environments[2].ID,
+				environments[1].ID,
+			},
+		},
 		{
 			name:       "sort by edge ID descending",
 			sortField:  "EdgeID",
@@ -0,0 +1,60 @@
+package ldap
+
+import (
+	"errors"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+// @id LDAPSyncPreview
+// @summary Preview a LDAP synchronization
+// @description Compute the users that would be disabled or enabled and the team memberships that would be added or removed by a synchronization with the LDAP server, without applying them.
+// @description **Access policy**: administrator
+// @tags ldap
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {object} portainer.LDAPSyncReport "Success"
+// @failure 400 "LDAP authentication is not enabled"
+// @failure 500 "Server error"
+// @router /ldap/sync/preview [get]
+func (handler *Handler) ldapSyncPreview(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.sync(w, true)
+}
+
+// @id LDAPSync
+// @summary Synchronize with the LDAP server
+// @description Disable the users no longer found in the LDAP server, enable the users found again and reconcile the memberships of the teams named after LDAP groups.
+// @description **Access policy**: administrator
+// @tags ldap
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {object} portainer.LDAPSyncReport "Success"
+// @failure 400 "LDAP authentication is not enabled"
+// @failure 500 "Server error"
+// @router /ldap/sync [post]
+func (handler *Handler) ldapSync(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.sync(w, false)
+}
+
+func (handler *Handler) sync(w http.ResponseWriter, dryRun bool) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the settings from the database", err)
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationLDAP {
+		return httperror.BadRequest("LDAP authentication is not enabled", errors.New("LDAP authentication is not enabled"))
+	}
+
+	report, err := handler.LDAPSyncService.Sync(dryRun)
+	if err != nil {
+		return httperror.InternalServerError("Unable to synchronize with the LDAP server", err)
+	}
+
+	return response.JSON(w, report)
+}
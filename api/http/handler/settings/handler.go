@@ -20,11 +20,13 @@ func hideFields(settings *portainer.Settings) {
 // Handler is the HTTP handler used to handle settings operations.
 type Handler struct {
 	*mux.Router
-	DataStore       dataservices.DataStore
-	FileService     portainer.FileService
-	JWTService      portainer.JWTService
-	LDAPService     portainer.LDAPService
-	SnapshotService portainer.SnapshotService
+	DataStore                dataservices.DataStore
+	FileService              portainer.FileService
+	JWTService               portainer.JWTService
+	LDAPService              portainer.LDAPService
+	LDAPSyncService          portainer.LDAPSyncService
+	ClientCertificateService portainer.ClientCertificateService
+	SnapshotService          portainer.SnapshotService
 }
 
 // NewHandler creates a handler to manage settings operations.
@@ -37,6 +39,8 @@ func NewHandler(bouncer security.BouncerService) *Handler {
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsInspect))).Methods(http.MethodGet)
 	h.Handle("/settings",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsUpdate))).Methods(http.MethodPut)
+	h.Handle("/settings/saml/metadata",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsSAMLMetadataImport))).Methods(http.MethodPost)
 	h.Handle("/settings/public",
 		bouncer.PublicAccess(httperror.LoggerHandler(h.settingsPublic))).Methods(http.MethodGet)
 
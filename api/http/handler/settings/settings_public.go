@@ -3,8 +3,10 @@ package settings
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/saml"
 	"github.com/portainer/portainer/pkg/featureflags"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/response"
@@ -13,10 +15,12 @@ import (
 type publicSettingsResponse struct {
 	// URL to a logo that will be displayed on the login page as well as on top of the sidebar. Will use default Portainer logo when value is empty string
 	LogoURL string `json:"LogoURL" example:"https://mycompany.mydomain.tld/logo.png"`
-	// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, or 3 for oauth
+	// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, 3 for oauth or 4 for SAML
 	AuthenticationMethod portainer.AuthenticationMethod `json:"AuthenticationMethod" example:"1"`
 	// The minimum required length for a password of any user when using internal auth mode
 	RequiredPasswordLength int `json:"RequiredPasswordLength" example:"1"`
+	// The rules a password must follow when using internal auth mode
+	PasswordPolicy portainer.PasswordPolicy `json:"PasswordPolicy"`
 	// Deployment options for encouraging deployment as code
 	GlobalDeploymentOptions portainer.GlobalDeploymentOptions `json:"GlobalDeploymentOptions"`
 	// Whether edge compute features are enabled
@@ -27,6 +31,10 @@ type publicSettingsResponse struct {
 	OAuthLoginURI string `json:"OAuthLoginURI" example:"https://gitlab.com/oauth"`
 	// The URL used for oauth logout
 	OAuthLogoutURI string `json:"OAuthLogoutURI" example:"https://gitlab.com/oauth/logout"`
+	// The URL used for SAML login
+	SAMLLoginURI string `json:"SAMLLoginURI" example:"https://portainer.mydomain.tld/api/auth/saml/login"`
+	// Whether users can log in with a TLS client certificate
+	ClientCertificateLoginEnabled bool `json:"ClientCertificateLoginEnabled" example:"false"`
 	// Whether telemetry is enabled
 	EnableTelemetry bool `json:"EnableTelemetry" example:"true"`
 	// The expiry of a Kubeconfig
@@ -76,6 +84,7 @@ func generatePublicSettings(appSettings *portainer.Settings) *publicSettingsResp
 		LogoURL:                   appSettings.LogoURL,
 		AuthenticationMethod:      appSettings.AuthenticationMethod,
 		RequiredPasswordLength:    appSettings.InternalAuthSettings.RequiredPasswordLength,
+		PasswordPolicy:            appSettings.InternalAuthSettings.PasswordPolicy,
 		EnableEdgeComputeFeatures: appSettings.EnableEdgeComputeFeatures,
 		GlobalDeploymentOptions:   appSettings.GlobalDeploymentOptions,
 		EnableTelemetry:           appSettings.EnableTelemetry,
@@ -90,6 +99,7 @@ func generatePublicSettings(appSettings *portainer.Settings) *publicSettingsResp
 	publicSettings.Edge.CheckinInterval = appSettings.EdgeAgentCheckinInterval
 
 	publicSettings.IsDockerDesktopExtension = appSettings.IsDockerDesktopExtension
+	publicSettings.ClientCertificateLoginEnabled = appSettings.ClientCertificateSettings.Enabled
 
 	// If OAuth authentication is on, compose the related fields from application settings
 	if publicSettings.AuthenticationMethod == portainer.AuthenticationOAuth {
@@ -105,6 +115,11 @@ func generatePublicSettings(appSettings *portainer.Settings) *publicSettingsResp
 			publicSettings.OAuthLoginURI += "&prompt=login"
 		}
 	}
+	// If SAML authentication is on, the login starts from Portainer which redirects to the identity provider
+	if publicSettings.AuthenticationMethod == portainer.AuthenticationSAML {
+		publicSettings.SAMLLoginURI = strings.TrimSuffix(appSettings.SAMLSettings.ServiceProviderURL, "/") + saml.LoginPath
+	}
+
 	// If LDAP authentication is on, compose the related fields from application settings
 	if publicSettings.AuthenticationMethod == portainer.AuthenticationLDAP && appSettings.LDAPSettings.GroupSearchSettings != nil {
 		if len(appSettings.LDAPSettings.GroupSearchSettings) > 0 {
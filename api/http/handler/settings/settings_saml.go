@@ -0,0 +1,68 @@
+package settings
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer/api/saml"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+
+	"github.com/pkg/errors"
+)
+
+type samlMetadataImportPayload struct {
+	// Metadata XML document of the SAML identity provider
+	Metadata string `validate:"required"`
+}
+
+func (payload *samlMetadataImportPayload) Validate(r *http.Request) error {
+	if payload.Metadata == "" {
+		return errors.New("Invalid identity provider metadata")
+	}
+
+	return nil
+}
+
+// @id SettingsSAMLMetadataImport
+// @summary Import the metadata of a SAML identity provider
+// @description Configure the entity ID, single sign-on URL and signing certificates of the SAML identity provider from its metadata.
+// @description **Access policy**: administrator
+// @tags settings
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param body body samlMetadataImportPayload true "Identity provider metadata"
+// @success 200 {object} portainer.Settings "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /settings/saml/metadata [post]
+func (handler *Handler) settingsSAMLMetadataImport(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload samlMetadataImportPayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	metadata, err := saml.ParseIdPMetadata([]byte(payload.Metadata))
+	if err != nil {
+		return httperror.BadRequest("Invalid identity provider metadata", err)
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the settings from the database", err)
+	}
+
+	settings.SAMLSettings.IdPEntityID = metadata.EntityID
+	settings.SAMLSettings.IdPSSOURL = metadata.SSOURL
+	settings.SAMLSettings.IdPCertificate = metadata.Certificate
+
+	if err := handler.DataStore.Settings().UpdateSettings(settings); err != nil {
+		return httperror.InternalServerError("Unable to persist settings changes inside the database", err)
+	}
+
+	hideFields(settings)
+
+	return response.JSON(w, settings)
+}
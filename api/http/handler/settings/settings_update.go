@@ -9,7 +9,10 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/ldap"
+	"github.com/portainer/portainer/api/saml"
 	"github.com/portainer/portainer/pkg/libhelm"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
@@ -20,16 +23,27 @@ import (
 	"golang.org/x/oauth2"
 )
 
+type internalAuthSettingsPayload struct {
+	RequiredPasswordLength int
+	// Whether internal users must enrol a TOTP second factor to log in
+	EnforceTwoFactor bool `example:"false"`
+	// Rules applied to the passwords of internal users, the current ones are kept when it is omitted
+	PasswordPolicy *portainer.PasswordPolicy
+}
+
 type settingsUpdatePayload struct {
 	// URL to a logo that will be displayed on the login page as well as on top of the sidebar. Will use default Portainer logo when value is empty string
 	LogoURL *string `example:"https://mycompany.mydomain.tld/logo.png"`
 	// A list of label name & value that will be used to hide containers when querying containers
 	BlackListedLabels []portainer.Pair
-	// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, or 3 for oauth
+	// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, 3 for oauth or 4 for SAML
 	AuthenticationMethod *int `example:"1"`
-	InternalAuthSettings *portainer.InternalAuthSettings
+	InternalAuthSettings *internalAuthSettingsPayload
 	LDAPSettings         *portainer.LDAPSettings
 	OAuthSettings        *portainer.OAuthSettings
+	SAMLSettings         *portainer.SAMLSettings
+	// Authentication with TLS client certificates, available whatever the authentication method
+	ClientCertificateSettings *portainer.ClientCertificateSettings
 	// The interval in which environment(endpoint) snapshots are created
 	SnapshotInterval *string `example:"5m"`
 	// URL to the templates that will be displayed in the UI when navigating to App Templates
@@ -57,9 +71,12 @@ type settingsUpdatePayload struct {
 	EdgePortainerURL *string `json:"EdgePortainerURL"`
 }
 
+// maxPasswordHistorySize is the maximum number of previous passwords kept to prevent their reuse
+const maxPasswordHistorySize = 24
+
 func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
-	if payload.AuthenticationMethod != nil && *payload.AuthenticationMethod != 1 && *payload.AuthenticationMethod != 2 && *payload.AuthenticationMethod != 3 {
-		return errors.New("Invalid authentication method value. Value must be one of: 1 (internal), 2 (LDAP/AD) or 3 (OAuth)")
+	if payload.AuthenticationMethod != nil && (*payload.AuthenticationMethod < 1 || *payload.AuthenticationMethod > 4) {
+		return errors.New("Invalid authentication method value. Value must be one of: 1 (internal), 2 (LDAP/AD), 3 (OAuth) or 4 (SAML)")
 	}
 
 	if payload.LogoURL != nil && *payload.LogoURL != "" && !validate.IsURL(*payload.LogoURL) {
@@ -92,6 +109,35 @@ func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
 		}
 	}
 
+	if payload.InternalAuthSettings != nil && payload.InternalAuthSettings.PasswordPolicy != nil {
+		policy := payload.InternalAuthSettings.PasswordPolicy
+		if policy.HistorySize < 0 || policy.HistorySize > maxPasswordHistorySize {
+			return errors.Errorf("Invalid password history size. Must be between 0 and %d", maxPasswordHistorySize)
+		}
+
+		if policy.MaxAgeDays < 0 {
+			return errors.New("Invalid maximum password age. Must be a positive number of days or 0")
+		}
+	}
+
+	if payload.LDAPSettings != nil {
+		if err := ldap.ValidateSyncInterval(payload.LDAPSettings.SyncInterval); err != nil {
+			return errors.Wrap(err, "Invalid LDAP synchronization interval")
+		}
+	}
+
+	if payload.ClientCertificateSettings != nil {
+		if err := security.ValidateClientCertificateSettings(payload.ClientCertificateSettings); err != nil {
+			return errors.Wrap(err, "Invalid client certificate settings")
+		}
+	}
+
+	if payload.SAMLSettings != nil {
+		if err := validateSAMLSettings(payload.SAMLSettings); err != nil {
+			return err
+		}
+	}
+
 	if payload.OAuthSettings != nil {
 		if payload.OAuthSettings.AuthStyle < oauth2.AuthStyleAutoDetect || payload.OAuthSettings.AuthStyle > oauth2.AuthStyleInHeader {
 			return errors.New("Invalid OAuth AuthStyle")
@@ -177,15 +223,28 @@ func (handler *Handler) updateSettings(tx dataservices.DataStoreTx, payload sett
 
 	if payload.InternalAuthSettings != nil {
 		settings.InternalAuthSettings.RequiredPasswordLength = payload.InternalAuthSettings.RequiredPasswordLength
+		settings.InternalAuthSettings.EnforceTwoFactor = payload.InternalAuthSettings.EnforceTwoFactor
+
+		if payload.InternalAuthSettings.PasswordPolicy != nil {
+			settings.InternalAuthSettings.PasswordPolicy = *payload.InternalAuthSettings.PasswordPolicy
+		}
 	}
 
 	if payload.LDAPSettings != nil {
 		ldapReaderDN := cmp.Or(payload.LDAPSettings.ReaderDN, settings.LDAPSettings.ReaderDN)
 		ldapPassword := cmp.Or(payload.LDAPSettings.Password, settings.LDAPSettings.Password)
 
+		syncInterval := settings.LDAPSettings.SyncInterval
+
 		settings.LDAPSettings = *payload.LDAPSettings
 		settings.LDAPSettings.ReaderDN = ldapReaderDN
 		settings.LDAPSettings.Password = ldapPassword
+
+		if settings.LDAPSettings.SyncInterval != syncInterval {
+			if err := handler.LDAPSyncService.SetSyncInterval(settings.LDAPSettings.SyncInterval); err != nil {
+				return nil, httperror.InternalServerError("Unable to update LDAP synchronization interval", err)
+			}
+		}
 	}
 
 	if payload.OAuthSettings != nil {
@@ -205,6 +264,24 @@ func (handler *Handler) updateSettings(tx dataservices.DataStoreTx, payload sett
 		settings.OAuthSettings.AuthStyle = payload.OAuthSettings.AuthStyle
 	}
 
+	if payload.SAMLSettings != nil {
+		settings.SAMLSettings = *payload.SAMLSettings
+	}
+
+	if payload.ClientCertificateSettings != nil {
+		settings.ClientCertificateSettings = *payload.ClientCertificateSettings
+
+		if err := handler.ClientCertificateService.SetSettings(&settings.ClientCertificateSettings); err != nil {
+			return nil, httperror.InternalServerError("Unable to update client certificate settings", err)
+		}
+	}
+
+	if settings.AuthenticationMethod == portainer.AuthenticationSAML {
+		if err := saml.ValidateSettings(&settings.SAMLSettings); err != nil {
+			return nil, httperror.BadRequest("Invalid SAML settings", err)
+		}
+	}
+
 	settings.EnableEdgeComputeFeatures = *cmp.Or(payload.EnableEdgeComputeFeatures, &settings.EnableEdgeComputeFeatures)
 	settings.TrustOnFirstConnect = *cmp.Or(payload.TrustOnFirstConnect, &settings.TrustOnFirstConnect)
 	settings.EnforceEdgeID = *cmp.Or(payload.EnforceEdgeID, &settings.EnforceEdgeID)
@@ -242,6 +319,30 @@ func (handler *Handler) updateSettings(tx dataservices.DataStoreTx, payload sett
 	return settings, nil
 }
 
+func validateSAMLSettings(settings *portainer.SAMLSettings) error {
+	if settings.IdPSSOURL != "" && !validate.IsURL(settings.IdPSSOURL) {
+		return errors.New("Invalid SAML identity provider single sign-on URL. Must correspond to a valid URL format")
+	}
+
+	if settings.ServiceProviderURL != "" && !validate.IsURL(settings.ServiceProviderURL) {
+		return errors.New("Invalid Portainer URL for SAML. Must correspond to a valid URL format")
+	}
+
+	if settings.IdPCertificate != "" {
+		if _, err := saml.ParseCertificates(settings.IdPCertificate); err != nil {
+			return err
+		}
+	}
+
+	for _, mapping := range settings.GroupTeamMappings {
+		if mapping.Group == "" || mapping.TeamID == 0 {
+			return errors.New("Invalid SAML group mapping. Group and team are required")
+		}
+	}
+
+	return nil
+}
+
 func (handler *Handler) updateSnapshotInterval(settings *portainer.Settings, snapshotInterval string) error {
 	settings.SnapshotInterval = snapshotInterval
 
@@ -0,0 +1,48 @@
+package settings
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSettingsKeepsPasswordPolicy(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	handler := &Handler{DataStore: store, FileService: fileService}
+
+	policy := portainer.PasswordPolicy{RequireDigit: true, HistorySize: 5, MaxAgeDays: 90}
+
+	update := func(payload settingsUpdatePayload) *portainer.Settings {
+		require.NoError(t, payload.Validate(nil))
+
+		var settings *portainer.Settings
+		require.NoError(t, store.UpdateTx(func(tx dataservices.DataStoreTx) error {
+			var err error
+			settings, err = handler.updateSettings(tx, payload)
+
+			return err
+		}))
+
+		return settings
+	}
+
+	settings := update(settingsUpdatePayload{InternalAuthSettings: &internalAuthSettingsPayload{RequiredPasswordLength: 12, PasswordPolicy: &policy}})
+	require.Equal(t, policy, settings.InternalAuthSettings.PasswordPolicy)
+
+	// an update without the policy keeps it
+	settings = update(settingsUpdatePayload{InternalAuthSettings: &internalAuthSettingsPayload{RequiredPasswordLength: 14}})
+	require.Equal(t, 14, settings.InternalAuthSettings.RequiredPasswordLength)
+	require.Equal(t, policy, settings.InternalAuthSettings.PasswordPolicy)
+
+	settings = update(settingsUpdatePayload{InternalAuthSettings: &internalAuthSettingsPayload{RequiredPasswordLength: 14, PasswordPolicy: &portainer.PasswordPolicy{}}})
+	require.Equal(t, portainer.PasswordPolicy{}, settings.InternalAuthSettings.PasswordPolicy)
+}
@@ -25,6 +25,8 @@ func NewHandler(bouncer security.BouncerService) *Handler {
 		bouncer.AdminAccess(httperror.LoggerHandler(h.sslInspect))).Methods(http.MethodGet)
 	h.Handle("/ssl",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.sslUpdate))).Methods(http.MethodPut)
+	h.Handle("/ssl/acme/renew",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.sslACMERenew))).Methods(http.MethodPost)
 
 	return h
 }
@@ -0,0 +1,34 @@
+package ssl
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/portainer/portainer/api/internal/ssl"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+// @id SSLACMERenew
+// @summary Renew the ACME certificate
+// @description Request a new certificate from the ACME server, regardless of the expiration of the current one.
+// @description The certificate is loaded without restarting the server.
+// @description **Access policy**: administrator
+// @tags ssl
+// @security ApiKeyAuth
+// @security jwt
+// @success 204 "Success"
+// @failure 400 "ACME is not enabled"
+// @failure 403 "Permission denied to access settings"
+// @failure 502 "Unable to obtain a certificate from the ACME server"
+// @router /ssl/acme/renew [post]
+func (handler *Handler) sslACMERenew(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	err := handler.SSLService.RenewACMECertificate(r.Context(), true)
+	if errors.Is(err, ssl.ErrACMEDisabled) {
+		return httperror.BadRequest("ACME is not enabled", err)
+	} else if err != nil {
+		return httperror.NewError(http.StatusBadGateway, "Failed to obtain a certificate from the ACME server", err)
+	}
+
+	return response.Empty(w)
+}
@@ -26,5 +26,10 @@ func (handler *Handler) sslInspect(w http.ResponseWriter, r *http.Request) *http
 		return httperror.InternalServerError("Failed to fetch certificate info", err)
 	}
 
+	// the DNS provider configuration holds credentials
+	if settings.ACME != nil {
+		settings.ACME.DNSProviderConfig = nil
+	}
+
 	return response.JSON(w, settings)
 }
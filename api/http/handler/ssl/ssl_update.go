@@ -4,6 +4,8 @@ import (
 	"errors"
 	"net/http"
 
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/ssl"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
 	"github.com/portainer/portainer/pkg/libhttp/response"
@@ -14,6 +16,8 @@ type sslUpdatePayload struct {
 	Cert        *string
 	Key         *string
 	HTTPEnabled *bool
+	// ACME client configuration, the certificate is requested when it does not match the domains
+	ACME *portainer.ACMESettings
 }
 
 func (payload *sslUpdatePayload) Validate(r *http.Request) error {
@@ -21,12 +25,22 @@ func (payload *sslUpdatePayload) Validate(r *http.Request) error {
 		return errors.New("both certificate and key files should be provided")
 	}
 
+	if payload.Cert != nil && payload.ACME != nil && payload.ACME.Enabled {
+		return errors.New("a certificate cannot be uploaded while ACME is enabled")
+	}
+
+	if payload.ACME != nil {
+		return ssl.ValidateACMESettings(payload.ACME)
+	}
+
 	return nil
 }
 
 // @id SSLUpdate
 // @summary Update the ssl settings
 // @description Update the ssl settings.
+// @description When ACME is enabled, a certificate is requested from the ACME server in the background if the current one does not match the domains.
+// @description The outcome of the request is reported by the lastError and notAfter fields of the ACME settings.
 // @description **Access policy**: administrator
 // @tags ssl
 // @security ApiKeyAuth
@@ -51,6 +65,12 @@ func (handler *Handler) sslUpdate(w http.ResponseWriter, r *http.Request) *httpe
 		}
 	}
 
+	if payload.ACME != nil {
+		if err := handler.SSLService.SetACMESettings(*payload.ACME); err != nil {
+			return httperror.InternalServerError("Failed to save the ACME settings", err)
+		}
+	}
+
 	if payload.HTTPEnabled != nil {
 		if err := handler.SSLService.SetHTTPEnabled(*payload.HTTPEnabled); err != nil {
 			return httperror.InternalServerError("Failed to force https", err)
@@ -7,6 +7,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/git"
 	"github.com/portainer/portainer/api/git/update"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/stacks/deployments"
@@ -162,6 +163,8 @@ type composeStackFromGitRepositoryPayload struct {
 	RepositoryURL string `example:"https://github.com/openfaas/faas" validate:"required"`
 	// Reference name of a Git repository hosting the Stack file
 	RepositoryReferenceName string `example:"refs/heads/master"`
+	// Semver constraint of the tags to track instead of the reference name, the highest matching tag is deployed
+	RepositoryTagConstraint string `example:"~1.4"`
 	// Use basic authentication to clone the Git repository
 	RepositoryAuthentication bool `example:"true"`
 	// Username used in basic authentication. Required when RepositoryAuthentication is true.
@@ -211,6 +214,9 @@ func (payload *composeStackFromGitRepositoryPayload) Validate(r *http.Request) e
 	if payload.RepositoryAuthentication && len(payload.RepositoryPassword) == 0 {
 		return errors.New("Invalid repository credentials. Password must be specified when authentication is enabled")
 	}
+	if payload.RepositoryTagConstraint != "" && git.ValidateTagConstraint(payload.RepositoryTagConstraint) != nil {
+		return errors.New("Invalid repository tag constraint. Must correspond to a valid semver constraint")
+	}
 	if err := update.ValidateAutoUpdateSettings(payload.AutoUpdate); err != nil {
 		return err
 	}
@@ -296,6 +302,7 @@ func (handler *Handler) createComposeStackFromGitRepository(w http.ResponseWrite
 		payload.FromAppTemplate,
 		payload.TLSSkipVerify,
 	)
+	stackPayload.TagConstraint = payload.RepositoryTagConstraint
 
 	composeStackBuilder := stackbuilders.CreateComposeStackGitBuilder(securityContext,
 		handler.DataStore,
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/git"
 	"github.com/portainer/portainer/api/git/update"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/internal/registryutils"
@@ -44,6 +45,7 @@ type kubernetesGitDeploymentPayload struct {
 	Namespace                string
 	RepositoryURL            string
 	RepositoryReferenceName  string
+	RepositoryTagConstraint  string
 	RepositoryAuthentication bool
 	RepositoryUsername       string
 	RepositoryPassword       string
@@ -104,6 +106,10 @@ func (payload *kubernetesGitDeploymentPayload) Validate(r *http.Request) error {
 		return errors.New("Invalid repository credentials. Password must be specified when authentication is enabled")
 	}
 
+	if payload.RepositoryTagConstraint != "" && git.ValidateTagConstraint(payload.RepositoryTagConstraint) != nil {
+		return errors.New("Invalid repository tag constraint. Must correspond to a valid semver constraint")
+	}
+
 	if len(payload.ManifestFile) == 0 {
 		return errors.New("Invalid manifest file in repository")
 	}
@@ -232,6 +238,7 @@ func (handler *Handler) createKubernetesStackFromGitRepository(w http.ResponseWr
 		payload.AutoUpdate,
 		payload.TLSSkipVerify,
 	)
+	stackPayload.TagConstraint = payload.RepositoryTagConstraint
 
 	k8sStackBuilder := stackbuilders.CreateKubernetesStackGitBuilder(handler.DataStore,
 		handler.FileService,
@@ -16,6 +16,7 @@ import (
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/kubernetes/cli"
 	"github.com/portainer/portainer/api/scheduler"
+	"github.com/portainer/portainer/api/stacks/buildlogs"
 	"github.com/portainer/portainer/api/stacks/deployments"
 	"github.com/portainer/portainer/api/stacks/stackutils"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
@@ -23,6 +24,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 )
 
@@ -42,6 +44,8 @@ type Handler struct {
 	KubernetesClientFactory *cli.ClientFactory
 	Scheduler               *scheduler.Scheduler
 	StackDeployer           deployments.StackDeployer
+	BuildLogs               *buildlogs.Service
+	connectionUpgrader      websocket.Upgrader
 }
 
 func stackExistsError(name string) *httperror.HandlerError {
@@ -50,6 +54,20 @@ func stackExistsError(name string) *httperror.HandlerError {
 	return httperror.Conflict(msg, err)
 }
 
+// sanitizeStackResponse removes the secrets of a stack sent in the http response to minimise possible security leaks,
+// the env vars overriding a secret variable of the variable sets of the stack are masked
+func sanitizeStackResponse(tx dataservices.DataStoreTx, stack *portainer.Stack) {
+	if stack.GitConfig != nil && stack.GitConfig.Authentication != nil {
+		stack.GitConfig.Authentication.Password = ""
+	}
+
+	if stack.AutoUpdate != nil {
+		stack.AutoUpdate.WebhookSecret = ""
+	}
+
+	stackutils.MaskStackEnv(tx, stack)
+}
+
 // NewHandler creates a handler to manage stack operations.
 func NewHandler(bouncer security.BouncerService) *Handler {
 	h := &Handler{
@@ -77,6 +95,10 @@ func NewHandler(bouncer security.BouncerService) *Handler {
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackUpdateGit))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/git/redeploy",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackGitRedeploy))).Methods(http.MethodPut)
+	h.Handle("/stacks/import",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackImport))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/export",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackExport))).Methods(http.MethodGet)
 	h.Handle("/stacks/{id}/file",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackFile))).Methods(http.MethodGet)
 	h.Handle("/stacks/{id}/migrate",
@@ -85,6 +107,24 @@ func NewHandler(bouncer security.BouncerService) *Handler {
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackStart))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/stop",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackStop))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/status",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackStatus))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/build_logs",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackBuildLogs))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/variables",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackVariablesPreview))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/variable_sets",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackVariableSetsUpdate))).Methods(http.MethodPut)
+	h.Handle("/stacks/{id}/services/{service}/start",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackServiceStart))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/services/{service}/stop",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackServiceStop))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/services/{service}/restart",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackServiceRestart))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/services/{service}/scale",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackServiceScale))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/services/{service}/recreate",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackServiceRecreate))).Methods(http.MethodPost)
 	h.Handle("/stacks/webhooks/{webhookID}",
 		bouncer.PublicAccess(httperror.LoggerHandler(h.webhookInvoke))).Methods(http.MethodPost)
 
@@ -0,0 +1,162 @@
+package stacks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/buildlogs"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	"github.com/portainer/portainer/api/ws"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// @id StackBuildLogs
+// @summary Stream the build logs of a stack
+// @description Upgrade the request to the websocket protocol and stream the output of the build of the images of a
+// @description Compose stack. When the stack is being deployed, the output written so far is sent first and the
+// @description connection is closed at the end of the build. Otherwise the output of the last build is sent.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Stack identifier"
+// @param token query string false "JWT token used for authentication against this stack"
+// @success 101 "Switching protocols"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/build_logs [get]
+func (handler *Handler) stackBuildLogs(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid stack identifier route variable", err)
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	stack, err := handler.DataStore.Stack().Read(portainer.StackID(stackID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a stack with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a stack with the specified identifier inside the database", err)
+	}
+
+	if stack.Type != portainer.DockerComposeStack {
+		errMsg := "The build logs are only available for Compose stacks"
+		return httperror.BadRequest(errMsg, errors.New(errMsg))
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an environment with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+	}
+
+	if err := handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint); err != nil {
+		return httperror.Forbidden("Permission denied to access environment", err)
+	}
+
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve a resource control associated to the stack", err)
+	}
+
+	access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+	if err != nil {
+		return httperror.InternalServerError("Unable to verify user authorizations to validate stack access", err)
+	}
+	if !access {
+		return httperror.Forbidden("Access denied to resource", httperrors.ErrResourceAccessDenied)
+	}
+
+	r.Header.Del("Origin")
+
+	websocketConn, err := handler.connectionUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return httperror.InternalServerError("Unable to upgrade the connection to the websocket protocol", err)
+	}
+	defer websocketConn.Close()
+
+	if err := handler.streamBuildLogs(r.Context(), websocketConn, stack); err != nil {
+		log.Debug().Err(err).Int("stack_id", int(stack.ID)).Msg("build logs stream ended")
+	}
+
+	return nil
+}
+
+// streamBuildLogs writes the output of the build in progress of the stack to the websocket, or the output of its
+// last build when it is not being built
+func (handler *Handler) streamBuildLogs(ctx context.Context, websocketConn *websocket.Conn, stack *portainer.Stack) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the messages of the client are discarded, reading them is required to notice that the connection was closed
+	go func() {
+		defer cancel()
+
+		for {
+			if _, _, err := websocketConn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	var build *buildlogs.Build
+	if handler.BuildLogs != nil {
+		build = handler.BuildLogs.Running(stack.ID)
+	}
+
+	if build == nil {
+		if stack.BuildLog != nil && stack.BuildLog.Output != "" {
+			if err := writeBuildOutput(websocketConn, []byte(stack.BuildLog.Output)); err != nil {
+				return err
+			}
+		}
+
+		return closeBuildLogs(websocketConn)
+	}
+
+	offset := 0
+	for {
+		output, next, done, err := build.Next(ctx, offset)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			return closeBuildLogs(websocketConn)
+		}
+
+		if err := writeBuildOutput(websocketConn, output); err != nil {
+			return err
+		}
+
+		offset = next
+	}
+}
+
+func writeBuildOutput(websocketConn *websocket.Conn, output []byte) error {
+	if err := websocketConn.SetWriteDeadline(time.Now().Add(ws.WriteWait)); err != nil {
+		return err
+	}
+
+	return websocketConn.WriteMessage(websocket.TextMessage, []byte(ws.ValidString(string(output))))
+}
+
+func closeBuildLogs(websocketConn *websocket.Conn) error {
+	return websocketConn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(ws.WriteWait))
+}
@@ -0,0 +1,99 @@
+package stacks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/stacks/buildlogs"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackBuildLogs(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{
+		ID:         1,
+		Name:       "built",
+		EndpointID: 1,
+		Type:       portainer.DockerComposeStack,
+		BuildLog:   &portainer.StackBuildLog{Success: true, Output: "Successfully built 0123456789ab\n"},
+	}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 2, Name: "building", EndpointID: 1, Type: portainer.DockerComposeStack}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 3, Name: "swarm", EndpointID: 1, Type: portainer.DockerSwarmStack}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+	h.BuildLogs = buildlogs.NewService()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(security.StoreRestrictedRequestContext(r, &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}))
+		h.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	dial := func(t *testing.T, path string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+path, nil)
+		require.NoError(t, err)
+
+		t.Cleanup(func() { conn.Close() })
+
+		return conn
+	}
+
+	readMessage := func(t *testing.T, conn *websocket.Conn) string {
+		_, message, err := conn.ReadMessage()
+		require.NoError(t, err)
+
+		return string(message)
+	}
+
+	requireClosed := func(t *testing.T, conn *websocket.Conn) {
+		_, _, err := conn.ReadMessage()
+		require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+	}
+
+	t.Run("last build", func(t *testing.T) {
+		conn := dial(t, "/stacks/1/build_logs")
+
+		require.Equal(t, "Successfully built 0123456789ab\n", readMessage(t, conn))
+		requireClosed(t, conn)
+	})
+
+	t.Run("build in progress", func(t *testing.T) {
+		build := h.BuildLogs.Start(2)
+
+		_, err := build.Write([]byte("Step 1/2 : FROM alpine\n"))
+		require.NoError(t, err)
+
+		conn := dial(t, "/stacks/2/build_logs")
+		require.Equal(t, "Step 1/2 : FROM alpine\n", readMessage(t, conn))
+
+		_, err = build.Write([]byte("Step 2/2 : RUN true\n"))
+		require.NoError(t, err)
+
+		require.Equal(t, "Step 2/2 : RUN true\n", readMessage(t, conn))
+
+		h.BuildLogs.Finish(2, build)
+		requireClosed(t, conn)
+	})
+
+	t.Run("swarm stack", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/stacks/3/build_logs", nil)
+		r = r.WithContext(security.StoreRestrictedRequestContext(r, &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
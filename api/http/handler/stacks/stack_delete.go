@@ -10,6 +10,7 @@ import (
 	"github.com/portainer/portainer/api/filesystem"
 	httperrors "github.com/portainer/portainer/api/http/errors"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/deploymentgroups"
 	"github.com/portainer/portainer/api/stacks/deployments"
 	"github.com/portainer/portainer/api/stacks/stackutils"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
@@ -34,6 +35,7 @@ import (
 // @failure 400 "Invalid request"
 // @failure 403 "Permission denied"
 // @failure 404 "Not found"
+// @failure 409 "The stack is part of a deployment group"
 // @failure 500 "Server error"
 // @router /stacks/{id} [delete]
 func (handler *Handler) stackDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -112,6 +114,15 @@ func (handler *Handler) stackDelete(w http.ResponseWriter, r *http.Request) *htt
 		return httperror.Forbidden(errMsg, errors.New(errMsg))
 	}
 
+	// the deployments of the group would fail on the missing stack
+	group, err := deploymentgroups.GroupOfStack(handler.DataStore, stack.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the deployment groups from the database", err)
+	} else if group != nil {
+		errMsg := fmt.Sprintf("The stack is part of the deployment group %s, it must be removed from the group first", group.Name)
+		return httperror.Conflict(errMsg, errors.New(errMsg))
+	}
+
 	// stop scheduler updates of the stack before removal
 	if stack.AutoUpdate != nil {
 		deployments.StopAutoupdate(stack.ID, stack.AutoUpdate.JobID, handler.Scheduler)
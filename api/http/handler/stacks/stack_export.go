@@ -0,0 +1,224 @@
+package stacks
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/stackbundle"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+
+	"github.com/pkg/errors"
+)
+
+// @id StackExport
+// @summary Export a stack as a portable bundle
+// @description Download a tar.gz archive holding the files of a Compose or Swarm stack along with a manifest describing
+// @description its environment variables, git repository, GitOps update settings and access control.
+// @description The archive can be imported on any environment with the StackImport operation.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @produce application/gzip
+// @param id path int true "Stack identifier"
+// @param excludeSecrets query bool false "Leave out the secret environment variables, the git password and the webhook secret, defaults to true. Only administrators can include them"
+// @success 200 {file} file "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/export [get]
+func (handler *Handler) stackExport(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid stack identifier route variable", err)
+	}
+
+	// the secrets are only included when explicitly asked for
+	excludeSecrets := r.URL.Query().Get("excludeSecrets") != "false"
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	if !excludeSecrets && !securityContext.IsAdmin {
+		return httperror.Forbidden("Only administrators can export the secrets of a stack", httperrors.ErrResourceAccessDenied)
+	}
+
+	stack, err := handler.DataStore.Stack().Read(portainer.StackID(stackID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a stack with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a stack with the specified identifier inside the database", err)
+	}
+
+	if stack.Type != portainer.DockerComposeStack && stack.Type != portainer.DockerSwarmStack {
+		errMsg := "Only Compose and Swarm stacks can be exported"
+		return httperror.BadRequest(errMsg, errors.New(errMsg))
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an environment with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+	}
+
+	canManage, err := handler.userCanManageStacks(securityContext, endpoint)
+	if err != nil {
+		return httperror.InternalServerError("Unable to verify user authorizations to validate stack export", err)
+	}
+	if !canManage {
+		errMsg := "Stack management is disabled for non-admin users"
+		return httperror.Forbidden(errMsg, errors.New(errMsg))
+	}
+
+	if err := handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint); err != nil {
+		return httperror.Forbidden("Permission denied to access environment", err)
+	}
+
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve a resource control associated to the stack", err)
+	}
+
+	access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+	if err != nil {
+		return httperror.InternalServerError("Unable to verify user authorizations to validate stack access", err)
+	}
+	if !access {
+		return httperror.Forbidden("Access denied to resource", httperrors.ErrResourceAccessDenied)
+	}
+
+	var bundle *stackbundle.Bundle
+	err = handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		bundle, err = handler.createStackBundle(tx, stack, resourceControl, excludeSecrets)
+
+		return err
+	})
+	if err != nil {
+		return httperror.InternalServerError("Unable to create the stack bundle", err)
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename="+stack.Name+".stack.tar.gz")
+	w.Header().Set("Content-Type", "application/gzip")
+
+	if err := stackbundle.Write(w, bundle); err != nil {
+		return httperror.InternalServerError("Unable to write the stack bundle", err)
+	}
+
+	return nil
+}
+
+func (handler *Handler) createStackBundle(tx dataservices.DataStoreTx, stack *portainer.Stack, resourceControl *portainer.ResourceControl, excludeSecrets bool) (*stackbundle.Bundle, error) {
+	bundle := &stackbundle.Bundle{
+		Manifest: stackbundle.Manifest{
+			Version:         stackbundle.Version,
+			Name:            stack.Name,
+			Type:            stack.Type,
+			EntryPoint:      stack.EntryPoint,
+			AdditionalFiles: stack.AdditionalFiles,
+			Env:             stack.Env,
+			Option:          stack.Option,
+		},
+		Files: make(map[string][]byte),
+	}
+
+	if excludeSecrets {
+		secretNames := stackutils.SecretVariableNames(tx, stack)
+
+		bundle.Manifest.Env = make([]portainer.Pair, 0, len(stack.Env))
+		for _, pair := range stack.Env {
+			if !secretNames[pair.Name] {
+				bundle.Manifest.Env = append(bundle.Manifest.Env, pair)
+			}
+		}
+	}
+
+	if stack.GitConfig != nil {
+		gitConfig := *stack.GitConfig
+		// the commit is resolved again when the stack is cloned on import
+		gitConfig.ConfigHash = ""
+
+		if gitConfig.Authentication != nil {
+			authentication := *gitConfig.Authentication
+			// git credentials are specific to the instance the stack is exported from
+			authentication.GitCredentialID = 0
+			if excludeSecrets {
+				authentication.Password = ""
+			}
+
+			gitConfig.Authentication = &authentication
+		}
+
+		bundle.Manifest.GitConfig = &gitConfig
+	}
+
+	if stack.AutoUpdate != nil {
+		autoUpdate := *stack.AutoUpdate
+		autoUpdate.JobID = ""
+		if excludeSecrets {
+			autoUpdate.WebhookSecret = ""
+		}
+
+		bundle.Manifest.AutoUpdate = &autoUpdate
+	}
+
+	if resourceControl != nil {
+		manifestResourceControl, err := exportResourceControl(tx, resourceControl)
+		if err != nil {
+			return nil, err
+		}
+
+		bundle.Manifest.ResourceControl = manifestResourceControl
+	}
+
+	for _, filePath := range stackutils.GetStackFilePaths(stack, false) {
+		content, err := handler.FileService.GetFileContent(stack.ProjectPath, filePath)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "unable to read the stack file %s", filePath)
+		}
+
+		bundle.Files[filePath] = content
+	}
+
+	return bundle, nil
+}
+
+// exportResourceControl references the users and teams of the resource control by name
+func exportResourceControl(tx dataservices.DataStoreTx, resourceControl *portainer.ResourceControl) (*stackbundle.ResourceControl, error) {
+	manifestResourceControl := &stackbundle.ResourceControl{
+		Public:             resourceControl.Public,
+		AdministratorsOnly: resourceControl.AdministratorsOnly,
+	}
+
+	for _, userAccess := range resourceControl.UserAccesses {
+		user, err := tx.User().Read(userAccess.UserID)
+		if tx.IsErrObjectNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		manifestResourceControl.Users = append(manifestResourceControl.Users, user.Username)
+	}
+
+	for _, teamAccess := range resourceControl.TeamAccesses {
+		team, err := tx.Team().Read(teamAccess.TeamID)
+		if tx.IsErrObjectNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		manifestResourceControl.Teams = append(manifestResourceControl.Teams, team.Name)
+	}
+
+	return manifestResourceControl, nil
+}
@@ -0,0 +1,128 @@
+package stacks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/stacks/stackbundle"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackExport(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	fs, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	projectPath, err := fs.StoreStackFileFromBytes("1", "docker-compose.yml", []byte("services:\n  web:\n    image: nginx"))
+	require.NoError(t, err)
+	_, err = fs.StoreStackFileFromBytes("1", "overrides/prod.yml", []byte("services:\n  web:\n    restart: always"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.User().Create(&portainer.User{Username: "alice", Role: portainer.StandardUserRole}))
+	require.NoError(t, store.Team().Create(&portainer.Team{Name: "developers"}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", GroupID: 1, Type: portainer.DockerEnvironment}))
+	require.NoError(t, store.VariableSet().Create(&portainer.VariableSet{
+		Name:      "production",
+		ScopeType: portainer.EndpointGroupVariableSetScope,
+		ScopeID:   1,
+		Variables: []portainer.StackVariable{{Name: "DB_PASSWORD", Value: "s3cr3t", Secret: true}},
+	}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{
+		ID:              1,
+		Name:            "app",
+		EndpointID:      1,
+		Type:            portainer.DockerComposeStack,
+		EntryPoint:      "docker-compose.yml",
+		AdditionalFiles: []string{"overrides/prod.yml"},
+		ProjectPath:     projectPath,
+		Env:             []portainer.Pair{{Name: "TAG", Value: "1.0"}, {Name: "DB_PASSWORD", Value: "override"}},
+		VariableSetIDs:  []portainer.VariableSetID{1},
+		GitConfig: &gittypes.RepoConfig{
+			URL:            "https://github.com/portainer/app",
+			ConfigFilePath: "docker-compose.yml",
+			ConfigHash:     "0123456789abcdef",
+			Authentication: &gittypes.GitAuthentication{Username: "git", Password: "token", GitCredentialID: 3},
+		},
+		AutoUpdate: &portainer.AutoUpdateSettings{Interval: "5m", JobID: "15"},
+	}))
+	require.NoError(t, store.ResourceControl().Create(&portainer.ResourceControl{
+		ResourceID:   "1_app",
+		Type:         portainer.StackResourceControl,
+		UserAccesses: []portainer.UserResourceAccess{{UserID: 2, AccessLevel: portainer.ReadWriteAccessLevel}},
+		TeamAccesses: []portainer.TeamResourceAccess{{TeamID: 1, AccessLevel: portainer.ReadWriteAccessLevel}},
+	}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+	h.FileService = fs
+
+	exportAs := func(userID portainer.UserID, isAdmin bool, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r = r.WithContext(security.StoreRestrictedRequestContext(r, &security.RestrictedRequestContext{UserID: userID, IsAdmin: isAdmin}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		return rec
+	}
+
+	export := func(t *testing.T, path string) *stackbundle.Bundle {
+		rec := exportAs(1, true, path)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Equal(t, "attachment; filename=app.stack.tar.gz", rec.Header().Get("Content-Disposition"))
+
+		bundle, err := stackbundle.Read(rec.Body)
+		require.NoError(t, err)
+
+		return bundle
+	}
+
+	t.Run("with secrets", func(t *testing.T) {
+		bundle := export(t, "/stacks/1/export?excludeSecrets=false")
+
+		require.Equal(t, map[string][]byte{
+			"docker-compose.yml": []byte("services:\n  web:\n    image: nginx"),
+			"overrides/prod.yml": []byte("services:\n  web:\n    restart: always"),
+		}, bundle.Files)
+
+		manifest := bundle.Manifest
+		require.Equal(t, "app", manifest.Name)
+		require.Len(t, manifest.Env, 2)
+		require.Equal(t, "token", manifest.GitConfig.Authentication.Password)
+		require.Zero(t, manifest.GitConfig.Authentication.GitCredentialID)
+		require.Empty(t, manifest.GitConfig.ConfigHash)
+		require.Equal(t, "5m", manifest.AutoUpdate.Interval)
+		require.Empty(t, manifest.AutoUpdate.JobID)
+		require.Equal(t, &stackbundle.ResourceControl{Users: []string{"alice"}, Teams: []string{"developers"}}, manifest.ResourceControl)
+	})
+
+	t.Run("without secrets", func(t *testing.T) {
+		for _, path := range []string{"/stacks/1/export", "/stacks/1/export?excludeSecrets=true", "/stacks/1/export?excludeSecrets=0"} {
+			manifest := export(t, path).Manifest
+
+			require.Equal(t, []portainer.Pair{{Name: "TAG", Value: "1.0"}}, manifest.Env)
+			require.Empty(t, manifest.GitConfig.Authentication.Password)
+			require.Equal(t, "git", manifest.GitConfig.Authentication.Username)
+		}
+	})
+
+	t.Run("secrets of a non-admin user", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, exportAs(2, false, "/stacks/1/export?excludeSecrets=false").Code)
+	})
+
+	stack, err := store.Stack().Read(1)
+	require.NoError(t, err)
+	require.Equal(t, "token", stack.GitConfig.Authentication.Password)
+	require.Equal(t, "15", stack.AutoUpdate.JobID)
+}
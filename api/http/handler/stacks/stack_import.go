@@ -0,0 +1,389 @@
+package stacks
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/git/update"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/stacks/deployments"
+	"github.com/portainer/portainer/api/stacks/stackbuilders"
+	"github.com/portainer/portainer/api/stacks/stackbundle"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type stackImportPayload struct {
+	// Stack bundle created by the StackExport operation
+	Bundle *stackbundle.Bundle
+	// Name of the stack, overrides the name of the bundle
+	Name string
+	// Swarm cluster identifier, required when importing a Swarm stack
+	SwarmID string
+	// Password used to clone the git repository, required when the bundle was exported without secrets
+	RepositoryPassword string
+	// Secret used to verify the signature of the push events, required when the bundle was exported without secrets
+	// and the stack has a webhook provider
+	WebhookSecret string
+}
+
+func decodeStackImportForm(r *http.Request) (*stackImportPayload, error) {
+	payload := &stackImportPayload{}
+
+	bundleContent, _, err := request.RetrieveMultiPartFormFile(r, "file")
+	if err != nil {
+		return nil, errors.New("Invalid stack bundle. Ensure that the bundle is uploaded correctly")
+	}
+
+	payload.Bundle, err = stackbundle.Read(bytes.NewReader(bundleContent))
+	if err != nil {
+		return nil, err
+	}
+
+	payload.Name, _ = request.RetrieveMultiPartFormValue(r, "Name", true)
+	payload.SwarmID, _ = request.RetrieveMultiPartFormValue(r, "SwarmID", true)
+	payload.RepositoryPassword, _ = request.RetrieveMultiPartFormValue(r, "RepositoryPassword", true)
+	payload.WebhookSecret, _ = request.RetrieveMultiPartFormValue(r, "WebhookSecret", true)
+
+	if payload.Name == "" {
+		payload.Name = payload.Bundle.Manifest.Name
+	}
+
+	if payload.Bundle.Manifest.Type == portainer.DockerSwarmStack && payload.SwarmID == "" {
+		return nil, errors.New("Invalid Swarm ID. A Swarm ID is required to import a Swarm stack")
+	}
+
+	if autoUpdate := payload.Bundle.Manifest.AutoUpdate; autoUpdate != nil && payload.WebhookSecret != "" {
+		autoUpdate.WebhookSecret = payload.WebhookSecret
+	}
+
+	if err := update.ValidateAutoUpdateSettings(payload.Bundle.Manifest.AutoUpdate); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// @id StackImport
+// @summary Import a stack from a bundle
+// @description Recreate and deploy a Compose or Swarm stack from a bundle created by the StackExport operation.
+// @description The stack files are validated against the maximum supported Compose syntax version before the deployment.
+// @description Stacks deployed from a git repository are cloned again, the webhook of their GitOps update settings is regenerated.
+// @description The access control of the bundle is restored for administrators, the stack is private to other users.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @accept multipart/form-data
+// @produce json
+// @param endpointId query int true "Identifier of the environment that will be used to deploy the stack"
+// @param file formData file true "Stack bundle"
+// @param Name formData string false "Name of the stack, defaults to the name of the bundle"
+// @param SwarmID formData string false "Swarm cluster identifier, required when importing a Swarm stack"
+// @param RepositoryPassword formData string false "Password used to clone the git repository"
+// @param WebhookSecret formData string false "Secret used to verify the signature of the push events of the webhook provider"
+// @success 200 {object} portainer.Stack
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Environment not found"
+// @failure 409 "Stack name already exists"
+// @failure 500 "Server error"
+// @router /stacks/import [post]
+func (handler *Handler) stackImport(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", false)
+	if err != nil {
+		return httperror.BadRequest("Invalid query parameter: endpointId", err)
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an environment with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve user info from request context", err)
+	}
+
+	canManage, err := handler.userCanManageStacks(securityContext, endpoint)
+	if err != nil {
+		return httperror.InternalServerError("Unable to verify user authorizations to validate stack import", err)
+	}
+	if !canManage {
+		errMsg := "Stack creation is disabled for non-admin users"
+		return httperror.Forbidden(errMsg, errors.New(errMsg))
+	}
+
+	if err := handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint); err != nil {
+		return httperror.Forbidden("Permission denied to access environment", err)
+	}
+
+	payload, err := decodeStackImportForm(r)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	manifest := payload.Bundle.Manifest
+
+	for filePath, content := range payload.Bundle.Files {
+		if err := stackutils.ValidateComposeSyntaxVersion(content, handler.ComposeStackManager.ComposeSyntaxMaxVersion()); err != nil {
+			return httperror.BadRequest("Invalid stack file "+filePath, err)
+		}
+	}
+
+	payload.Name = handler.ComposeStackManager.NormalizeStackName(payload.Name)
+
+	isUnique, err := handler.checkUniqueStackNameInDocker(endpoint, payload.Name, 0, manifest.Type == portainer.DockerSwarmStack)
+	if err != nil {
+		return httperror.InternalServerError("Unable to check for name collision", err)
+	}
+	if !isUnique {
+		return stackExistsError(payload.Name)
+	}
+
+	var stack *portainer.Stack
+	var httpErr *httperror.HandlerError
+	if manifest.GitConfig != nil {
+		stack, httpErr = handler.importGitStack(payload, securityContext, endpoint)
+	} else {
+		stack, httpErr = handler.importFileStack(payload, securityContext, endpoint)
+	}
+	if httpErr != nil {
+		return httpErr
+	}
+
+	err = handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		resourceControl, err := importResourceControl(tx, stack, manifest.ResourceControl, securityContext)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.ResourceControl().Create(resourceControl); err != nil {
+			return err
+		}
+
+		stack.ResourceControl = resourceControl
+
+		return nil
+	})
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist resource control inside the database", err)
+	}
+
+	sanitizeStackResponse(handler.DataStore, stack)
+
+	return response.JSON(w, stack)
+}
+
+// importGitStack clones the repository of the bundle and deploys the stack with the git builders
+func (handler *Handler) importGitStack(payload *stackImportPayload, securityContext *security.RestrictedRequestContext, endpoint *portainer.Endpoint) (*portainer.Stack, *httperror.HandlerError) {
+	manifest := payload.Bundle.Manifest
+
+	stackPayload := stackbuilders.StackPayload{
+		Name:    payload.Name,
+		SwarmID: payload.SwarmID,
+		RepositoryConfigPayload: stackbuilders.RepositoryConfigPayload{
+			URL:           manifest.GitConfig.URL,
+			ReferenceName: manifest.GitConfig.ReferenceName,
+			TagConstraint: manifest.GitConfig.TagConstraint,
+			TLSSkipVerify: manifest.GitConfig.TLSSkipVerify,
+		},
+		ComposeFile:     manifest.GitConfig.ConfigFilePath,
+		AdditionalFiles: manifest.AdditionalFiles,
+		AutoUpdate:      manifest.AutoUpdate,
+		Env:             manifest.Env,
+	}
+
+	if manifest.GitConfig.Authentication != nil {
+		stackPayload.Authentication = true
+		stackPayload.Username = manifest.GitConfig.Authentication.Username
+		stackPayload.Password = manifest.GitConfig.Authentication.Password
+	}
+
+	if payload.RepositoryPassword != "" {
+		stackPayload.Authentication = true
+		stackPayload.Password = payload.RepositoryPassword
+	}
+
+	// the webhook of the exported stack may still be in use on this instance
+	if stackPayload.AutoUpdate != nil && stackPayload.AutoUpdate.Webhook != "" {
+		webhook, err := uuid.NewV4()
+		if err != nil {
+			return nil, httperror.InternalServerError("Unable to generate the stack webhook", err)
+		}
+
+		stackPayload.AutoUpdate.Webhook = webhook.String()
+	}
+
+	var stackBuilderDirector *stackbuilders.StackBuilderDirector
+	if manifest.Type == portainer.DockerSwarmStack {
+		stackBuilderDirector = stackbuilders.NewStackBuilderDirector(stackbuilders.CreateSwarmStackGitBuilder(securityContext,
+			handler.DataStore,
+			handler.FileService,
+			handler.GitService,
+			handler.Scheduler,
+			handler.StackDeployer))
+	} else {
+		stackBuilderDirector = stackbuilders.NewStackBuilderDirector(stackbuilders.CreateComposeStackGitBuilder(securityContext,
+			handler.DataStore,
+			handler.FileService,
+			handler.GitService,
+			handler.Scheduler,
+			handler.StackDeployer))
+	}
+
+	stack, httpErr := stackBuilderDirector.Build(&stackPayload, endpoint)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	if manifest.Option != nil {
+		stack.Option = manifest.Option
+
+		if err := handler.DataStore.Stack().Update(stack.ID, stack); err != nil {
+			return nil, httperror.InternalServerError("Unable to persist the stack changes inside the database", err)
+		}
+	}
+
+	return stack, nil
+}
+
+// importFileStack stores the files of the bundle and deploys the stack
+func (handler *Handler) importFileStack(payload *stackImportPayload, securityContext *security.RestrictedRequestContext, endpoint *portainer.Endpoint) (*portainer.Stack, *httperror.HandlerError) {
+	manifest := payload.Bundle.Manifest
+
+	stack := &portainer.Stack{
+		ID:              portainer.StackID(handler.DataStore.Stack().GetNextIdentifier()),
+		Name:            payload.Name,
+		Type:            manifest.Type,
+		EndpointID:      endpoint.ID,
+		EntryPoint:      manifest.EntryPoint,
+		AdditionalFiles: manifest.AdditionalFiles,
+		Env:             manifest.Env,
+		Option:          manifest.Option,
+		Status:          portainer.StackStatusActive,
+		CreationDate:    time.Now().Unix(),
+	}
+
+	if manifest.Type == portainer.DockerSwarmStack {
+		stack.SwarmID = payload.SwarmID
+	}
+
+	stackFolder := strconv.Itoa(int(stack.ID))
+	for filePath, content := range payload.Bundle.Files {
+		projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, filePath, content)
+		if err != nil {
+			handler.removeImportedStackFiles(stack)
+
+			return nil, httperror.InternalServerError("Unable to persist the stack files on disk", err)
+		}
+
+		stack.ProjectPath = projectPath
+	}
+
+	var deploymentConfig deployments.StackDeploymentConfiger
+	var err error
+	if manifest.Type == portainer.DockerSwarmStack {
+		prune := stack.Option != nil && stack.Option.Prune
+		deploymentConfig, err = deployments.CreateSwarmStackDeploymentConfig(securityContext, stack, endpoint, handler.DataStore, handler.FileService, handler.StackDeployer, prune, true)
+	} else {
+		deploymentConfig, err = deployments.CreateComposeStackDeploymentConfig(securityContext, stack, endpoint, handler.DataStore, handler.FileService, handler.StackDeployer, false, false)
+	}
+	if err != nil {
+		handler.removeImportedStackFiles(stack)
+
+		return nil, httperror.InternalServerError(err.Error(), err)
+	}
+
+	if err := deploymentConfig.Deploy(); err != nil {
+		handler.removeImportedStackFiles(stack)
+
+		return nil, httperror.InternalServerError(err.Error(), err)
+	}
+
+	stack.CreatedBy = deploymentConfig.GetUsername()
+
+	if err := handler.DataStore.Stack().Create(stack); err != nil {
+		handler.removeImportedStackFiles(stack)
+
+		return nil, httperror.InternalServerError("Unable to persist the stack inside the database", err)
+	}
+
+	return stack, nil
+}
+
+func (handler *Handler) removeImportedStackFiles(stack *portainer.Stack) {
+	if stack.ProjectPath == "" {
+		return
+	}
+
+	if err := handler.FileService.RemoveDirectory(stack.ProjectPath); err != nil {
+		log.Error().Err(err).Int("stack_id", int(stack.ID)).Msg("unable to cleanup stack import")
+	}
+}
+
+// importResourceControl creates the resource control of the imported stack, the users and teams of the bundle are
+// matched by name and the ones missing on this instance are skipped. Non-administrators get a private stack.
+func importResourceControl(tx dataservices.DataStoreTx, stack *portainer.Stack, manifestResourceControl *stackbundle.ResourceControl, securityContext *security.RestrictedRequestContext) (*portainer.ResourceControl, error) {
+	resourceID := stackutils.ResourceControlID(stack.EndpointID, stack.Name)
+
+	if !securityContext.IsAdmin {
+		return authorization.NewPrivateResourceControl(resourceID, portainer.StackResourceControl, securityContext.UserID), nil
+	}
+
+	if manifestResourceControl == nil || manifestResourceControl.AdministratorsOnly {
+		return authorization.NewAdministratorsOnlyResourceControl(resourceID, portainer.StackResourceControl), nil
+	}
+
+	if manifestResourceControl.Public {
+		return authorization.NewPublicResourceControl(resourceID, portainer.StackResourceControl), nil
+	}
+
+	var userIDs []portainer.UserID
+	for _, username := range manifestResourceControl.Users {
+		user, err := tx.User().UserByUsername(username)
+		if tx.IsErrObjectNotFound(err) {
+			log.Warn().Str("username", username).Msg("the user of the stack bundle does not exist, skipping its access")
+
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to retrieve the user %s: %w", username, err)
+		}
+
+		userIDs = append(userIDs, user.ID)
+	}
+
+	var teamIDs []portainer.TeamID
+	for _, teamName := range manifestResourceControl.Teams {
+		team, err := tx.Team().TeamByName(teamName)
+		if tx.IsErrObjectNotFound(err) {
+			log.Warn().Str("team", teamName).Msg("the team of the stack bundle does not exist, skipping its access")
+
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to retrieve the team %s: %w", teamName, err)
+		}
+
+		teamIDs = append(teamIDs, team.ID)
+	}
+
+	if len(userIDs) == 0 && len(teamIDs) == 0 {
+		return authorization.NewAdministratorsOnlyResourceControl(resourceID, portainer.StackResourceControl), nil
+	}
+
+	return authorization.NewRestrictedResourceControl(resourceID, portainer.StackResourceControl, userIDs, teamIDs), nil
+}
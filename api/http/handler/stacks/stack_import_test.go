@@ -0,0 +1,205 @@
+package stacks
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/stacks/stackbundle"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackImportInvalidBundle(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+	h.ComposeStackManager = testhelpers.NewComposeStackManager()
+
+	bundle := func(manifest stackbundle.Manifest, files map[string][]byte) []byte {
+		var archive bytes.Buffer
+		require.NoError(t, stackbundle.Write(&archive, &stackbundle.Bundle{Manifest: manifest, Files: files}))
+
+		return archive.Bytes()
+	}
+
+	tests := []struct {
+		name   string
+		bundle []byte
+	}{
+		{
+			name:   "not an archive",
+			bundle: []byte("not an archive"),
+		},
+		{
+			name: "missing stack file",
+			bundle: bundle(stackbundle.Manifest{
+				Version:    stackbundle.Version,
+				Name:       "app",
+				Type:       portainer.DockerComposeStack,
+				EntryPoint: "docker-compose.yml",
+			}, nil),
+		},
+		{
+			name: "unsupported compose syntax version",
+			bundle: bundle(stackbundle.Manifest{
+				Version:    stackbundle.Version,
+				Name:       "app",
+				Type:       portainer.DockerComposeStack,
+				EntryPoint: "docker-compose.yml",
+			}, map[string][]byte{"docker-compose.yml": []byte("version: '4.0'\nservices:\n  web:\n    image: nginx")}),
+		},
+		{
+			name: "swarm stack without swarm ID",
+			bundle: bundle(stackbundle.Manifest{
+				Version:    stackbundle.Version,
+				Name:       "app",
+				Type:       portainer.DockerSwarmStack,
+				EntryPoint: "docker-compose.yml",
+			}, map[string][]byte{"docker-compose.yml": []byte("services:\n  web:\n    image: nginx")}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+
+			part, err := writer.CreateFormFile("file", "app.stack.tar.gz")
+			require.NoError(t, err)
+			_, err = part.Write(tt.bundle)
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			r := httptest.NewRequest(http.MethodPost, "/stacks/import?endpointId=1", &body)
+			r.Header.Set("Content-Type", writer.FormDataContentType())
+			r = r.WithContext(security.StoreRestrictedRequestContext(r, &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}))
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestImportResourceControl(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.User().Create(&portainer.User{Username: "alice", Role: portainer.StandardUserRole}))
+	require.NoError(t, store.Team().Create(&portainer.Team{Name: "developers"}))
+
+	stack := &portainer.Stack{Name: "app", EndpointID: 1}
+	admin := &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}
+
+	importRC := func(manifestResourceControl *stackbundle.ResourceControl, securityContext *security.RestrictedRequestContext) *portainer.ResourceControl {
+		var resourceControl *portainer.ResourceControl
+
+		err := store.ViewTx(func(tx dataservices.DataStoreTx) error {
+			var err error
+			resourceControl, err = importResourceControl(tx, stack, manifestResourceControl, securityContext)
+
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, "1_app", resourceControl.ResourceID)
+
+		return resourceControl
+	}
+
+	require.True(t, importRC(nil, admin).AdministratorsOnly)
+	require.True(t, importRC(&stackbundle.ResourceControl{Public: true}, admin).Public)
+
+	restricted := importRC(&stackbundle.ResourceControl{Users: []string{"alice", "bob"}, Teams: []string{"developers"}}, admin)
+	require.Equal(t, []portainer.UserResourceAccess{{UserID: 2, AccessLevel: portainer.ReadWriteAccessLevel}}, restricted.UserAccesses)
+	require.Equal(t, []portainer.TeamResourceAccess{{TeamID: 1, AccessLevel: portainer.ReadWriteAccessLevel}}, restricted.TeamAccesses)
+
+	require.True(t, importRC(&stackbundle.ResourceControl{Users: []string{"bob"}}, admin).AdministratorsOnly)
+
+	private := importRC(&stackbundle.ResourceControl{Public: true}, &security.RestrictedRequestContext{UserID: 2})
+	require.False(t, private.Public)
+	require.Equal(t, []portainer.UserResourceAccess{{UserID: 2, AccessLevel: portainer.ReadWriteAccessLevel}}, private.UserAccesses)
+}
+
+func TestStackExportImportWebhookSecret(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	fs, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	projectPath, err := fs.StoreStackFileFromBytes("1", "docker-compose.yml", []byte("services:\n  web:\n    image: nginx"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{
+		ID:          1,
+		Name:        "app",
+		EndpointID:  1,
+		Type:        portainer.DockerComposeStack,
+		EntryPoint:  "docker-compose.yml",
+		ProjectPath: projectPath,
+		GitConfig:   &gittypes.RepoConfig{URL: "https://github.com/portainer/app", ConfigFilePath: "docker-compose.yml"},
+		AutoUpdate: &portainer.AutoUpdateSettings{
+			Webhook:         "c2c2b1b8-3e0f-4b1a-9d9e-2f6a1f0e6a3b",
+			WebhookProvider: portainer.GitWebhookProviderGitHub,
+			WebhookSecret:   "s3cr3t",
+		},
+	}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+	h.FileService = fs
+
+	r := httptest.NewRequest(http.MethodGet, "/stacks/1/export", nil)
+	r = r.WithContext(security.StoreRestrictedRequestContext(r, &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	archive := rec.Body.Bytes()
+
+	importRequest := func(fields map[string]string) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+
+		part, err := writer.CreateFormFile("file", "app.stack.tar.gz")
+		require.NoError(t, err)
+		_, err = part.Write(archive)
+		require.NoError(t, err)
+
+		for name, value := range fields {
+			require.NoError(t, writer.WriteField(name, value))
+		}
+		require.NoError(t, writer.Close())
+
+		r := httptest.NewRequest(http.MethodPost, "/stacks/import?endpointId=1", &body)
+		r.Header.Set("Content-Type", writer.FormDataContentType())
+
+		return r
+	}
+
+	// the secret is excluded from the export by default
+	_, err = decodeStackImportForm(importRequest(nil))
+	require.Error(t, err)
+
+	payload, err := decodeStackImportForm(importRequest(map[string]string{"WebhookSecret": "n3w-s3cr3t"}))
+	require.NoError(t, err)
+	require.Equal(t, portainer.GitWebhookProviderGitHub, payload.Bundle.Manifest.AutoUpdate.WebhookProvider)
+	require.Equal(t, "n3w-s3cr3t", payload.Bundle.Manifest.AutoUpdate.WebhookSecret)
+}
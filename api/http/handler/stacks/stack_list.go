@@ -82,10 +82,14 @@ func (handler *Handler) stackList(w http.ResponseWriter, r *http.Request) *httpe
 		stacks = authorization.FilterAuthorizedStacks(stacks, user, userTeamIDs)
 	}
 
-	for _, stack := range stacks {
-		if stack.GitConfig != nil && stack.GitConfig.Authentication != nil && stack.GitConfig.Authentication.Password != "" {
-			// sanitize password in the http response to minimise possible security leaks
-			stack.GitConfig.Authentication.Password = ""
+	for i := range stacks {
+		sanitizeStackResponse(handler.DataStore, &stacks[i])
+
+		if stacks[i].BuildLog != nil {
+			// the build output is only part of the stack details
+			buildLog := *stacks[i].BuildLog
+			buildLog.Output = ""
+			stacks[i].BuildLog = &buildLog
 		}
 	}
 
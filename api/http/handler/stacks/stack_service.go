@@ -0,0 +1,182 @@
+package stacks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+	"github.com/portainer/portainer/pkg/libstack"
+)
+
+// @id StackServiceStart
+// @summary Start a service of a stack
+// @description Start the existing containers of a service of a compose stack.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Stack identifier"
+// @param service path string true "Service name"
+// @param endpointId query int true "Environment identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/services/{service}/start [post]
+func (handler *Handler) stackServiceStart(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.stackServiceOperation(w, r, "start", func(stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+		return handler.ComposeStackManager.StartService(r.Context(), stack, endpoint, serviceName)
+	})
+}
+
+// @id StackServiceStop
+// @summary Stop a service of a stack
+// @description Stop the containers of a service of a compose stack without removing them.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Stack identifier"
+// @param service path string true "Service name"
+// @param endpointId query int true "Environment identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/services/{service}/stop [post]
+func (handler *Handler) stackServiceStop(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.stackServiceOperation(w, r, "stop", func(stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+		return handler.ComposeStackManager.StopService(r.Context(), stack, endpoint, serviceName)
+	})
+}
+
+// @id StackServiceRestart
+// @summary Restart a service of a stack
+// @description Restart the containers of a service of a compose stack, the services it depends on are not restarted.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Stack identifier"
+// @param service path string true "Service name"
+// @param endpointId query int true "Environment identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/services/{service}/restart [post]
+func (handler *Handler) stackServiceRestart(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.stackServiceOperation(w, r, "restart", func(stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+		return handler.ComposeStackManager.RestartService(r.Context(), stack, endpoint, serviceName)
+	})
+}
+
+type stackServiceOperationFunc func(stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error
+
+// stackServiceOperation runs an operation on a service of an active compose stack, once the user is checked to be
+// allowed to manage the stack
+func (handler *Handler) stackServiceOperation(w http.ResponseWriter, r *http.Request, operation string, operationFn stackServiceOperationFunc) *httperror.HandlerError {
+	stack, endpoint, serviceName, handlerErr := handler.retrieveServiceStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if err := operationFn(stack, endpoint, serviceName); errors.Is(err, libstack.ErrServiceNotFound) {
+		return httperror.NotFound("Unable to find the service in the stack", err)
+	} else if err != nil {
+		return httperror.InternalServerError(fmt.Sprintf("Unable to %s the stack service", operation), err)
+	}
+
+	return response.Empty(w)
+}
+
+// retrieveServiceStack returns the stack, the environment and the service targeted by a service operation request
+func (handler *Handler) retrieveServiceStack(r *http.Request) (*portainer.Stack, *portainer.Endpoint, string, *httperror.HandlerError) {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, "", httperror.BadRequest("Invalid stack identifier route variable", err)
+	}
+
+	serviceName, err := request.RetrieveRouteVariableValue(r, "service")
+	if err != nil {
+		return nil, nil, "", httperror.BadRequest("Invalid service name route variable", err)
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, nil, "", httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	stack, err := handler.DataStore.Stack().Read(portainer.StackID(stackID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return nil, nil, "", httperror.NotFound("Unable to find a stack with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, nil, "", httperror.InternalServerError("Unable to find a stack with the specified identifier inside the database", err)
+	}
+
+	if stack.Type != portainer.DockerComposeStack {
+		errMsg := "Service operations are only supported on compose stacks"
+		return nil, nil, "", httperror.BadRequest(errMsg, errors.New(errMsg))
+	}
+
+	if stackutils.IsRelativePathStack(stack) {
+		errMsg := "Service operations are not supported on stacks using relative paths"
+		return nil, nil, "", httperror.BadRequest(errMsg, errors.New(errMsg))
+	}
+
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", false)
+	if err != nil {
+		return nil, nil, "", httperror.BadRequest("Invalid query parameter: endpointId", err)
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return nil, nil, "", httperror.NotFound("Unable to find an environment with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, nil, "", httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+	}
+
+	if err := handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint); err != nil {
+		return nil, nil, "", httperror.Forbidden("Permission denied to access environment", err)
+	}
+
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+	if err != nil {
+		return nil, nil, "", httperror.InternalServerError("Unable to retrieve a resource control associated to the stack", err)
+	}
+
+	access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+	if err != nil {
+		return nil, nil, "", httperror.InternalServerError("Unable to verify user authorizations to validate stack access", err)
+	}
+	if !access {
+		return nil, nil, "", httperror.Forbidden("Access denied to resource", httperrors.ErrResourceAccessDenied)
+	}
+
+	canManage, err := handler.userCanManageStacks(securityContext, endpoint)
+	if err != nil {
+		return nil, nil, "", httperror.InternalServerError("Unable to verify user authorizations to validate stack management", err)
+	}
+	if !canManage {
+		errMsg := "stack management is disabled for non-admin users"
+		return nil, nil, "", httperror.Forbidden(errMsg, errors.New(errMsg))
+	}
+
+	if stack.Status == portainer.StackStatusInactive {
+		return nil, nil, "", httperror.BadRequest("Stack is inactive", errors.New("Stack is inactive"))
+	}
+
+	stack.Name = handler.ComposeStackManager.NormalizeStackName(stack.Name)
+
+	return stack, endpoint, serviceName, nil
+}
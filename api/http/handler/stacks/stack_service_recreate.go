@@ -0,0 +1,59 @@
+package stacks
+
+import (
+	"fmt"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+// @id StackServiceRecreate
+// @summary Recreate a service of a stack
+// @description Recreate the containers of a service of a compose stack, optionally pulling its image first. The services it depends on are not recreated.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Stack identifier"
+// @param service path string true "Service name"
+// @param endpointId query int true "Environment identifier"
+// @param pullImage query bool false "Pull the image of the service before recreating its containers"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/services/{service}/recreate [post]
+func (handler *Handler) stackServiceRecreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	pullImage, err := request.RetrieveBooleanQueryParameter(r, "pullImage", true)
+	if err != nil {
+		return httperror.BadRequest("Invalid query parameter: pullImage", err)
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	return handler.stackServiceOperation(w, r, "recreate", func(stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+		user, err := handler.DataStore.User().Read(securityContext.UserID)
+		if err != nil {
+			return fmt.Errorf("unable to load user information from the database: %w", err)
+		}
+
+		registries, err := handler.DataStore.Registry().ReadAll()
+		if err != nil {
+			return fmt.Errorf("unable to retrieve registries from the database: %w", err)
+		}
+
+		return handler.ComposeStackManager.RecreateService(r.Context(), stack, endpoint, serviceName, portainer.ComposeRecreateServiceOptions{
+			ComposeOptions: portainer.ComposeOptions{
+				Registries: security.FilterRegistries(registries, user, securityContext.UserMemberships, endpoint.ID),
+			},
+			PullImage: pullImage,
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package stacks
+
+import (
+	"errors"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+type stackServiceScalePayload struct {
+	// Number of containers the service must run
+	Replicas int `example:"3"`
+}
+
+func (payload *stackServiceScalePayload) Validate(r *http.Request) error {
+	if payload.Replicas < 0 {
+		return errors.New("Invalid number of replicas")
+	}
+
+	return nil
+}
+
+// @id StackServiceScale
+// @summary Scale a service of a stack
+// @description Create or remove the containers of a service of a compose stack so that it runs the requested number of replicas.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @param id path int true "Stack identifier"
+// @param service path string true "Service name"
+// @param endpointId query int true "Environment identifier"
+// @param body body stackServiceScalePayload true "Number of replicas"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/services/{service}/scale [post]
+func (handler *Handler) stackServiceScale(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload stackServiceScalePayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	return handler.stackServiceOperation(w, r, "scale", func(stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+		return handler.ComposeStackManager.ScaleService(r.Context(), stack, endpoint, serviceName, payload.Replicas)
+	})
+}
@@ -0,0 +1,109 @@
+package stacks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/pkg/libstack"
+
+	"github.com/stretchr/testify/require"
+)
+
+type serviceRecordingStackManager struct {
+	portainer.ComposeStackManager
+	calls []string
+}
+
+func (manager *serviceRecordingStackManager) record(ctx context.Context, operation string, serviceName string) error {
+	// the operation is cancelled along with the request
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if serviceName != "web" {
+		return libstack.ErrServiceNotFound
+	}
+
+	manager.calls = append(manager.calls, operation)
+
+	return nil
+}
+
+func (manager *serviceRecordingStackManager) StartService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return manager.record(ctx, "start", serviceName)
+}
+
+func (manager *serviceRecordingStackManager) StopService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return manager.record(ctx, "stop", serviceName)
+}
+
+func (manager *serviceRecordingStackManager) RestartService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return manager.record(ctx, "restart", serviceName)
+}
+
+func (manager *serviceRecordingStackManager) ScaleService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string, replicas int) error {
+	return manager.record(ctx, fmt.Sprintf("scale=%d", replicas), serviceName)
+}
+
+func (manager *serviceRecordingStackManager) RecreateService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string, options portainer.ComposeRecreateServiceOptions) error {
+	return manager.record(ctx, fmt.Sprintf("recreate pull=%t", options.PullImage), serviceName)
+}
+
+func TestStackServiceOperations(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 1, Name: "app", EndpointID: 1, Type: portainer.DockerComposeStack, Status: portainer.StackStatusActive}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 2, Name: "stopped", EndpointID: 1, Type: portainer.DockerComposeStack, Status: portainer.StackStatusInactive}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 3, Name: "swarm", EndpointID: 1, Type: portainer.DockerSwarmStack, Status: portainer.StackStatusActive}))
+
+	manager := &serviceRecordingStackManager{ComposeStackManager: testhelpers.NewComposeStackManager()}
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+	h.ComposeStackManager = manager
+
+	postWithContext := func(ctx context.Context, path, body string) int {
+		r := httptest.NewRequestWithContext(ctx, http.MethodPost, path, strings.NewReader(body))
+		r = r.WithContext(security.StoreRestrictedRequestContext(r, &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		return rec.Code
+	}
+
+	post := func(path, body string) int {
+		return postWithContext(context.Background(), path, body)
+	}
+
+	require.Equal(t, http.StatusNoContent, post("/stacks/1/services/web/start?endpointId=1", ""))
+	require.Equal(t, http.StatusNoContent, post("/stacks/1/services/web/stop?endpointId=1", ""))
+	require.Equal(t, http.StatusNoContent, post("/stacks/1/services/web/restart?endpointId=1", ""))
+	require.Equal(t, http.StatusNoContent, post("/stacks/1/services/web/scale?endpointId=1", `{"Replicas":3}`))
+	require.Equal(t, http.StatusNoContent, post("/stacks/1/services/web/recreate?endpointId=1&pullImage=true", ""))
+	require.Equal(t, []string{"start", "stop", "restart", "scale=3", "recreate pull=true"}, manager.calls)
+
+	require.Equal(t, http.StatusBadRequest, post("/stacks/1/services/web/scale?endpointId=1", `{"Replicas":-1}`))
+	require.Equal(t, http.StatusNotFound, post("/stacks/1/services/db/start?endpointId=1", ""))
+	require.Equal(t, http.StatusNotFound, post("/stacks/4/services/web/start?endpointId=1", ""))
+	require.Equal(t, http.StatusBadRequest, post("/stacks/1/services/web/start", ""))
+	require.Equal(t, http.StatusBadRequest, post("/stacks/2/services/web/start?endpointId=1", ""))
+	require.Equal(t, http.StatusBadRequest, post("/stacks/3/services/web/start?endpointId=1", ""))
+	require.Len(t, manager.calls, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.Equal(t, http.StatusInternalServerError, postWithContext(ctx, "/stacks/1/services/web/recreate?endpointId=1&pullImage=true", ""))
+	require.Len(t, manager.calls, 5)
+}
@@ -0,0 +1,84 @@
+package stacks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/require"
+)
+
+type statusComposeStackManager struct {
+	portainer.ComposeStackManager
+}
+
+func (manager *statusComposeStackManager) Status(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) (*portainer.StackHealth, error) {
+	return &portainer.StackHealth{
+		Status: "degraded",
+		Services: []portainer.StackServiceHealth{
+			{Name: "web", Status: "degraded", DesiredReplicas: 2, RunningReplicas: 1, Health: "unhealthy"},
+		},
+	}, nil
+}
+
+type statusSwarmStackManager struct {
+	portainer.SwarmStackManager
+}
+
+func (manager *statusSwarmStackManager) NormalizeStackName(name string) string {
+	return name
+}
+
+func (manager *statusSwarmStackManager) Status(stack *portainer.Stack, endpoint *portainer.Endpoint) (*portainer.StackHealth, error) {
+	return &portainer.StackHealth{Status: "running"}, nil
+}
+
+func TestStackStatus(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 1, Name: "app", EndpointID: 1, Type: portainer.DockerComposeStack}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 2, Name: "swarm", EndpointID: 1, Type: portainer.DockerSwarmStack}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 3, Name: "kube", EndpointID: 1, Type: portainer.KubernetesStack}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+	h.ComposeStackManager = &statusComposeStackManager{ComposeStackManager: testhelpers.NewComposeStackManager()}
+	h.SwarmStackManager = &statusSwarmStackManager{}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r = r.WithContext(security.StoreRestrictedRequestContext(r, &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		return rec
+	}
+
+	rec := get("/stacks/1/status")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var health portainer.StackHealth
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&health))
+	require.Equal(t, "degraded", health.Status)
+	require.Len(t, health.Services, 1)
+	require.Equal(t, 1, health.Services[0].RunningReplicas)
+	require.Equal(t, "unhealthy", health.Services[0].Health)
+
+	rec = get("/stacks/2/status")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&health))
+	require.Equal(t, "running", health.Status)
+
+	require.Equal(t, http.StatusBadRequest, get("/stacks/3/status").Code)
+	require.Equal(t, http.StatusNotFound, get("/stacks/4/status").Code)
+}
@@ -151,10 +151,7 @@ func (handler *Handler) stackUpdate(w http.ResponseWriter, r *http.Request) *htt
 		return httperror.InternalServerError("Unable to persist the stack changes inside the database", err)
 	}
 
-	if stack.GitConfig != nil && stack.GitConfig.Authentication != nil && stack.GitConfig.Authentication.Password != "" {
-		// Sanitize password in the http response to minimise possible security leaks
-		stack.GitConfig.Authentication.Password = ""
-	}
+	sanitizeStackResponse(handler.DataStore, stack)
 
 	return response.JSON(w, stack)
 }
@@ -191,7 +188,7 @@ func (handler *Handler) updateComposeStack(r *http.Request, stack *portainer.Sta
 		return httperror.BadRequest("Invalid request payload", err)
 	}
 
-	stack.Env = payload.Env
+	stack.Env = stackutils.RestoreMaskedEnv(stack.Env, payload.Env)
 
 	if stack.GitConfig != nil {
 		// detach from git
@@ -258,7 +255,7 @@ func (handler *Handler) updateSwarmStack(r *http.Request, stack *portainer.Stack
 		return httperror.BadRequest("Invalid request payload", err)
 	}
 
-	stack.Env = payload.Env
+	stack.Env = stackutils.RestoreMaskedEnv(stack.Env, payload.Env)
 
 	if stack.GitConfig != nil {
 		// detach from git
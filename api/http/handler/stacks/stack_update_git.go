@@ -5,6 +5,7 @@ import (
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/git"
 	gittypes "github.com/portainer/portainer/api/git/types"
 	"github.com/portainer/portainer/api/git/update"
 	httperrors "github.com/portainer/portainer/api/http/errors"
@@ -23,6 +24,7 @@ type stackGitUpdatePayload struct {
 	Env                      []portainer.Pair
 	Prune                    bool
 	RepositoryReferenceName  string
+	RepositoryTagConstraint  string
 	RepositoryAuthentication bool
 	RepositoryUsername       string
 	RepositoryPassword       string
@@ -30,7 +32,11 @@ type stackGitUpdatePayload struct {
 }
 
 func (payload *stackGitUpdatePayload) Validate(r *http.Request) error {
-	return update.ValidateAutoUpdateSettings(payload.AutoUpdate)
+	if payload.RepositoryTagConstraint != "" && git.ValidateTagConstraint(payload.RepositoryTagConstraint) != nil {
+		return errors.New("Invalid repository tag constraint. Must correspond to a valid semver constraint")
+	}
+
+	return update.ValidateAutoUpdateSettingsUpdate(payload.AutoUpdate)
 }
 
 // @id StackUpdateGit
@@ -124,16 +130,25 @@ func (handler *Handler) stackUpdateGit(w http.ResponseWriter, r *http.Request) *
 		return httperror.Forbidden(errMsg, errors.New(errMsg))
 	}
 
+	autoUpdate := stackutils.KeepWebhookSecret(stack.AutoUpdate, payload.AutoUpdate)
+	if err := update.ValidateWebhookSecret(autoUpdate); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
 	//stop the autoupdate job if there is any
 	if stack.AutoUpdate != nil {
 		deployments.StopAutoupdate(stack.ID, stack.AutoUpdate.JobID, handler.Scheduler)
 	}
 
 	//update retrieved stack data based on the payload
-	stack.GitConfig.ReferenceName = payload.RepositoryReferenceName
+	// the reference of a stack tracking a tag constraint is the deployed tag, it is updated on the next deployment
+	stack.GitConfig.TagConstraint = payload.RepositoryTagConstraint
+	if stack.GitConfig.TagConstraint == "" {
+		stack.GitConfig.ReferenceName = payload.RepositoryReferenceName
+	}
 	stack.GitConfig.TLSSkipVerify = payload.TLSSkipVerify
-	stack.AutoUpdate = payload.AutoUpdate
-	stack.Env = payload.Env
+	stack.AutoUpdate = autoUpdate
+	stack.Env = stackutils.RestoreMaskedEnv(stack.Env, payload.Env)
 	stack.UpdatedBy = user.Username
 	stack.UpdateDate = time.Now().Unix()
 
@@ -175,10 +190,7 @@ func (handler *Handler) stackUpdateGit(w http.ResponseWriter, r *http.Request) *
 		return httperror.InternalServerError("Unable to persist the stack changes inside the database", err)
 	}
 
-	if stack.GitConfig != nil && stack.GitConfig.Authentication != nil && stack.GitConfig.Authentication.Password != "" {
-		// sanitize password in the http response to minimise possible security leaks
-		stack.GitConfig.Authentication.Password = ""
-	}
+	sanitizeStackResponse(handler.DataStore, stack)
 
 	return response.JSON(w, stack)
 }
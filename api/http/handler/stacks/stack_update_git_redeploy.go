@@ -123,8 +123,10 @@ func (handler *Handler) stackGitRedeploy(w http.ResponseWriter, r *http.Request)
 		return httperror.BadRequest("Invalid request payload", err)
 	}
 
-	stack.GitConfig.ReferenceName = payload.RepositoryReferenceName
-	stack.Env = payload.Env
+	if stack.GitConfig.TagConstraint == "" {
+		stack.GitConfig.ReferenceName = payload.RepositoryReferenceName
+	}
+	stack.Env = stackutils.RestoreMaskedEnv(stack.Env, payload.Env)
 	if stack.Type == portainer.DockerSwarmStack {
 		stack.Option = &portainer.StackOption{Prune: payload.Prune}
 	}
@@ -146,6 +148,10 @@ func (handler *Handler) stackGitRedeploy(w http.ResponseWriter, r *http.Request)
 		repositoryUsername = payload.RepositoryUsername
 	}
 
+	if _, err := git.ResolveTagConstraint(handler.GitService, stack.GitConfig, repositoryUsername, repositoryPassword); err != nil {
+		return httperror.InternalServerError("Unable to resolve the repository tag constraint", err)
+	}
+
 	cloneOptions := git.CloneOptions{
 		ProjectPath:   stack.ProjectPath,
 		URL:           stack.GitConfig.URL,
@@ -184,10 +190,7 @@ func (handler *Handler) stackGitRedeploy(w http.ResponseWriter, r *http.Request)
 		return httperror.InternalServerError("Unable to persist the stack changes inside the database", errors.Wrap(err, "failed to update the stack"))
 	}
 
-	if stack.GitConfig != nil && stack.GitConfig.Authentication != nil && stack.GitConfig.Authentication.Password != "" {
-		// Sanitize password in the http response to minimise possible security leaks
-		stack.GitConfig.Authentication.Password = ""
-	}
+	sanitizeStackResponse(handler.DataStore, stack)
 
 	return response.JSON(w, stack)
 }
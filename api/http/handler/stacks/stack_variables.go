@@ -0,0 +1,194 @@
+package stacks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+type stackVariableSetsUpdatePayload struct {
+	// Identifiers of the variable sets inherited by the stack, the sets of a same scope are applied in this order
+	VariableSetIDs []portainer.VariableSetID `example:"1,2"`
+}
+
+func (payload *stackVariableSetsUpdatePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id StackVariableSetsUpdate
+// @summary Update the variable sets of a stack
+// @description Set the variable sets whose variables are inherited by a Compose or Swarm stack. The variable sets must be
+// @description scoped to the environment of the stack, to its group or to a team of the user. The variables are applied at
+// @description the next deployment of the stack.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Stack identifier"
+// @param body body stackVariableSetsUpdatePayload true "Variable sets of the stack"
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/variable_sets [put]
+func (handler *Handler) stackVariableSetsUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload stackVariableSetsUpdatePayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	stack, endpoint, securityContext, handlerErr := handler.readAccessibleStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	err := handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		variableSetIDs := make([]portainer.VariableSetID, 0, len(payload.VariableSetIDs))
+
+		for _, variableSetID := range payload.VariableSetIDs {
+			variableSet, err := tx.VariableSet().Read(variableSetID)
+			if tx.IsErrObjectNotFound(err) {
+				return httperror.BadRequest("Unable to find a variable set with the specified identifier inside the database", err)
+			} else if err != nil {
+				return httperror.InternalServerError("Unable to find a variable set with the specified identifier inside the database", err)
+			}
+
+			if !stackutils.VariableSetAppliesTo(variableSet, endpoint) {
+				errMsg := fmt.Sprintf("The variable set %s is scoped to another environment", variableSet.Name)
+				return httperror.BadRequest(errMsg, errors.New(errMsg))
+			}
+
+			access, err := security.AuthorizedVariableSetAccess(tx, variableSet, securityContext)
+			if err != nil {
+				return httperror.InternalServerError("Unable to verify user authorizations to access the variable set", err)
+			} else if !access {
+				return httperror.Forbidden("Access denied to the variable set", httperrors.ErrResourceAccessDenied)
+			}
+
+			variableSetIDs = append(variableSetIDs, variableSetID)
+		}
+
+		stack.VariableSetIDs = variableSetIDs
+
+		if err := tx.Stack().Update(stack.ID, stack); err != nil {
+			return httperror.InternalServerError("Unable to persist the stack changes inside the database", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		var handlerError *httperror.HandlerError
+		if errors.As(err, &handlerError) {
+			return handlerError
+		}
+
+		return httperror.InternalServerError("Unexpected error", err)
+	}
+
+	sanitizeStackResponse(handler.DataStore, stack)
+
+	return response.JSON(w, stack)
+}
+
+// @id StackVariablesPreview
+// @summary Preview the variables of a stack
+// @description Retrieve the final variables fed to the deployment of a Compose or Swarm stack, along with the origin of each
+// @description value. The environment labels are overridden by the variable sets, from the broadest to the narrowest scope,
+// @description which are overridden by the variables of the stack. The values of the secret variables are masked.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @success 200 {array} stackutils.ResolvedVariable "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/variables [get]
+func (handler *Handler) stackVariablesPreview(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stack, endpoint, _, handlerErr := handler.readAccessibleStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	endpointGroup, err := handler.DataStore.EndpointGroup().Read(endpoint.GroupID)
+	if err != nil && !handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.InternalServerError("Unable to find the environment group inside the database", err)
+	}
+
+	labelsEnv := endpointutils.LabelsEnv(endpointutils.EndpointLabels(endpoint, endpointGroup))
+
+	variables := stackutils.ResolveStackVariables(handler.DataStore, stack, endpoint, labelsEnv)
+	if variables == nil {
+		variables = []stackutils.ResolvedVariable{}
+	}
+
+	return response.JSON(w, variables)
+}
+
+// readAccessibleStack returns the Compose or Swarm stack identified by the route and its environment once it is
+// checked that the user can access them
+func (handler *Handler) readAccessibleStack(r *http.Request) (*portainer.Stack, *portainer.Endpoint, *security.RestrictedRequestContext, *httperror.HandlerError) {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, nil, httperror.BadRequest("Invalid stack identifier route variable", err)
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, nil, nil, httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	stack, err := handler.DataStore.Stack().Read(portainer.StackID(stackID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return nil, nil, nil, httperror.NotFound("Unable to find a stack with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, nil, nil, httperror.InternalServerError("Unable to find a stack with the specified identifier inside the database", err)
+	}
+
+	if stack.Type != portainer.DockerComposeStack && stack.Type != portainer.DockerSwarmStack {
+		errMsg := "The variable sets are only available for Compose and Swarm stacks"
+		return nil, nil, nil, httperror.BadRequest(errMsg, errors.New(errMsg))
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return nil, nil, nil, httperror.NotFound("Unable to find an environment with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, nil, nil, httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+	}
+
+	if err := handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint); err != nil {
+		return nil, nil, nil, httperror.Forbidden("Permission denied to access environment", err)
+	}
+
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+	if err != nil {
+		return nil, nil, nil, httperror.InternalServerError("Unable to retrieve a resource control associated to the stack", err)
+	}
+
+	access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+	if err != nil {
+		return nil, nil, nil, httperror.InternalServerError("Unable to verify user authorizations to validate stack access", err)
+	}
+	if !access {
+		return nil, nil, nil, httperror.Forbidden("Access denied to resource", httperrors.ErrResourceAccessDenied)
+	}
+
+	return stack, endpoint, securityContext, nil
+}
@@ -0,0 +1,106 @@
+package stacks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackVariables(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", GroupID: 1, Type: portainer.DockerEnvironment}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{
+		ID:         1,
+		Name:       "app",
+		EndpointID: 1,
+		Type:       portainer.DockerComposeStack,
+		Env:        []portainer.Pair{{Name: "DB_PASSWORD", Value: "override"}},
+	}))
+	require.NoError(t, store.VariableSet().Create(&portainer.VariableSet{
+		Name:      "production",
+		ScopeType: portainer.EndpointGroupVariableSetScope,
+		ScopeID:   1,
+		Variables: []portainer.StackVariable{{Name: "DB_HOST", Value: "db.internal"}, {Name: "DB_PASSWORD", Value: "s3cr3t", Secret: true}},
+	}))
+	require.NoError(t, store.VariableSet().Create(&portainer.VariableSet{Name: "remote", ScopeType: portainer.EndpointVariableSetScope, ScopeID: 2}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r = r.WithContext(security.StoreRestrictedRequestContext(r, &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		return rec
+	}
+
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/stacks/1/variable_sets", `{"VariableSetIDs":[2]}`).Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/stacks/1/variable_sets", `{"VariableSetIDs":[3]}`).Code)
+
+	rec := do(http.MethodPut, "/stacks/1/variable_sets", `{"VariableSetIDs":[1]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stack portainer.Stack
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stack))
+	require.Equal(t, []portainer.VariableSetID{1}, stack.VariableSetIDs)
+	require.Equal(t, stackutils.MaskedVariableValue, stack.Env[0].Value)
+
+	rec = do(http.MethodGet, "/stacks/1/variables", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var variables []stackutils.ResolvedVariable
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&variables))
+	require.Equal(t, []stackutils.ResolvedVariable{
+		{Name: "DB_HOST", Value: "db.internal", Source: "variable set production"},
+		{Name: "DB_PASSWORD", Value: stackutils.MaskedVariableValue, Secret: true, Source: "stack"},
+	}, variables)
+
+	rec = do(http.MethodGet, "/stacks/1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stack))
+	require.Equal(t, stackutils.MaskedVariableValue, stack.Env[0].Value)
+
+	stored, err := store.Stack().Read(1)
+	require.NoError(t, err)
+	require.Equal(t, "override", stored.Env[0].Value)
+}
+
+func TestSanitizeStackResponse(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	require.NoError(t, store.VariableSet().Create(&portainer.VariableSet{
+		Name:      "production",
+		ScopeType: portainer.EndpointGroupVariableSetScope,
+		ScopeID:   1,
+		Variables: []portainer.StackVariable{{Name: "DB_PASSWORD", Value: "s3cr3t", Secret: true}},
+	}))
+
+	stack := &portainer.Stack{
+		Env:            []portainer.Pair{{Name: "TAG", Value: "1.0"}, {Name: "DB_PASSWORD", Value: "override"}},
+		VariableSetIDs: []portainer.VariableSetID{1},
+		GitConfig:      &gittypes.RepoConfig{Authentication: &gittypes.GitAuthentication{Username: "git", Password: "token"}},
+		AutoUpdate:     &portainer.AutoUpdateSettings{WebhookSecret: "s3cr3t"},
+	}
+
+	sanitizeStackResponse(store, stack)
+
+	require.Equal(t, []portainer.Pair{{Name: "TAG", Value: "1.0"}, {Name: "DB_PASSWORD", Value: stackutils.MaskedVariableValue}}, stack.Env)
+	require.Empty(t, stack.GitConfig.Authentication.Password)
+	require.Empty(t, stack.AutoUpdate.WebhookSecret)
+}
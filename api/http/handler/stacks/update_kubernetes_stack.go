@@ -7,12 +7,14 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/git"
 	gittypes "github.com/portainer/portainer/api/git/types"
 	"github.com/portainer/portainer/api/git/update"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/registryutils"
 	k "github.com/portainer/portainer/api/kubernetes"
 	"github.com/portainer/portainer/api/stacks/deployments"
+	"github.com/portainer/portainer/api/stacks/stackutils"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
 
@@ -28,6 +30,7 @@ type kubernetesFileStackUpdatePayload struct {
 
 type kubernetesGitStackUpdatePayload struct {
 	RepositoryReferenceName  string
+	RepositoryTagConstraint  string
 	RepositoryAuthentication bool
 	RepositoryUsername       string
 	RepositoryPassword       string
@@ -44,7 +47,11 @@ func (payload *kubernetesFileStackUpdatePayload) Validate(r *http.Request) error
 }
 
 func (payload *kubernetesGitStackUpdatePayload) Validate(r *http.Request) error {
-	if err := update.ValidateAutoUpdateSettings(payload.AutoUpdate); err != nil {
+	if payload.RepositoryTagConstraint != "" && git.ValidateTagConstraint(payload.RepositoryTagConstraint) != nil {
+		return errors.New("Invalid repository tag constraint. Must correspond to a valid semver constraint")
+	}
+
+	if err := update.ValidateAutoUpdateSettingsUpdate(payload.AutoUpdate); err != nil {
 		return err
 	}
 
@@ -64,10 +71,17 @@ func (handler *Handler) updateKubernetesStack(r *http.Request, stack *portainer.
 			return httperror.BadRequest("Invalid request payload", err)
 		}
 
-		stack.GitConfig.ReferenceName = payload.RepositoryReferenceName
+		// the reference of a stack tracking a tag constraint is the deployed tag, it is updated on the next deployment
+		stack.GitConfig.TagConstraint = payload.RepositoryTagConstraint
+		if stack.GitConfig.TagConstraint == "" {
+			stack.GitConfig.ReferenceName = payload.RepositoryReferenceName
+		}
 		stack.GitConfig.TLSSkipVerify = payload.TLSSkipVerify
 		stack.GitConfig.Authentication = nil
-		stack.AutoUpdate = payload.AutoUpdate
+		stack.AutoUpdate = stackutils.KeepWebhookSecret(stack.AutoUpdate, payload.AutoUpdate)
+		if err := update.ValidateWebhookSecret(stack.AutoUpdate); err != nil {
+			return httperror.BadRequest("Invalid request payload", err)
+		}
 
 		if payload.RepositoryAuthentication {
 			password := payload.RepositoryPassword
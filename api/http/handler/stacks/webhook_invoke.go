@@ -3,22 +3,37 @@ package stacks
 import (
 	"errors"
 	"net/http"
+	"slices"
+	"time"
 
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/git/webhooks"
 	"github.com/portainer/portainer/api/stacks/deployments"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
 	"github.com/portainer/portainer/pkg/libhttp/request"
 	"github.com/portainer/portainer/pkg/libhttp/response"
 
 	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
 )
 
+// maxWebhookDeliveries is the number of push events kept in the delivery history of a stack
+const maxWebhookDeliveries = 20
+
 // @id WebhookInvoke
 // @summary Webhook for triggering stack updates from git
+// @description When the auto update settings of the stack set a WebhookProvider, the request must be a push event
+// @description of this provider signed with the WebhookSecret. The stack is only redeployed when the pushed reference
+// @description is the one it is deployed from, the delivery is recorded in the WebhookDeliveries of the stack and
+// @description updated with the outcome of the redeployment once it ends.
 // @description **Access policy**: public
 // @tags stacks
 // @param webhookID path string true "Stack identifier"
-// @success 200 "Success"
+// @success 200 {object} portainer.StackWebhookDelivery "Push event delivery"
+// @success 204 "Success"
 // @failure 400 "Invalid request"
+// @failure 401 "Invalid push event signature"
 // @failure 409 "Autoupdate for the stack isn't available"
 // @failure 500 "Server error"
 // @router /stacks/webhooks/{webhookID} [post]
@@ -38,6 +53,10 @@ func (handler *Handler) webhookInvoke(w http.ResponseWriter, r *http.Request) *h
 		return httperror.NewError(statusCode, "Unable to find the stack by webhook ID", err)
 	}
 
+	if stack.AutoUpdate.WebhookProvider != "" {
+		return handler.webhookInvokePushEvent(w, r, stack)
+	}
+
 	if err = deployments.RedeployWhenChanged(stack.ID, handler.StackDeployer, handler.DataStore, handler.GitService); err != nil {
 		var StackAuthorMissingErr *deployments.StackAuthorMissingErr
 		if errors.As(err, &StackAuthorMissingErr) {
@@ -50,6 +69,103 @@ func (handler *Handler) webhookInvoke(w http.ResponseWriter, r *http.Request) *h
 	return response.Empty(w)
 }
 
+// webhookInvokePushEvent redeploys the stack when the signed push event of its provider updates the reference the stack is deployed from
+func (handler *Handler) webhookInvokePushEvent(w http.ResponseWriter, r *http.Request, stack *portainer.Stack) *httperror.HandlerError {
+	event, err := webhooks.Parse(stack.AutoUpdate.WebhookProvider, stack.AutoUpdate.WebhookSecret, r)
+	if errors.Is(err, webhooks.ErrInvalidSignature) {
+		return httperror.Unauthorized("Invalid push event signature", err)
+	} else if err != nil {
+		return httperror.BadRequest("Invalid push event", err)
+	} else if event == nil {
+		// the pings and the other events of the provider are acknowledged
+		return response.Empty(w)
+	}
+
+	if stack.GitConfig == nil {
+		return httperror.Conflict("Autoupdate for the stack isn't available", errors.New("the stack is not deployed from git"))
+	}
+
+	delivery := portainer.StackWebhookDelivery{
+		ID:       event.DeliveryID,
+		Provider: stack.AutoUpdate.WebhookProvider,
+		Date:     time.Now().Unix(),
+	}
+
+	ref, triggered := event.MatchingRef(stack.GitConfig)
+	if !triggered && len(event.Refs) > 0 {
+		ref = event.Refs[0]
+	}
+
+	delivery.Ref = ref.Name
+	delivery.CommitSHA = ref.Commit
+	delivery.Triggered = triggered
+
+	// the delivery is recorded before the redeployment which saves the stack read from the database
+	if err := handler.recordWebhookDelivery(stack.ID, delivery); err != nil {
+		return httperror.InternalServerError("Unable to record the push event delivery", err)
+	}
+
+	if !triggered {
+		return response.JSON(w, delivery)
+	}
+
+	// the outcome of the redeployment, which runs in the background, replaces the recorded delivery
+	pushed := delivery
+	err = deployments.RedeployPushedStack(stack.ID, handler.StackDeployer, handler.DataStore, handler.GitService, func(deployed bool, err error) {
+		delivery := pushed
+		delivery.Triggered = deployed
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+
+		if err := handler.recordWebhookDelivery(stack.ID, delivery); err != nil {
+			log.Error().Err(err).Int("stack_id", int(stack.ID)).Msg("unable to record the push event delivery")
+		}
+	})
+	if err != nil {
+		delivery.Triggered = false
+		delivery.Error = err.Error()
+
+		if err := handler.recordWebhookDelivery(stack.ID, delivery); err != nil {
+			return httperror.InternalServerError("Unable to record the push event delivery", err)
+		}
+
+		var StackAuthorMissingErr *deployments.StackAuthorMissingErr
+		if errors.As(err, &StackAuthorMissingErr) {
+			return httperror.Conflict("Autoupdate for the stack isn't available", err)
+		}
+
+		return httperror.InternalServerError("Failed to update the stack", err)
+	}
+
+	return response.JSON(w, delivery)
+}
+
+// recordWebhookDelivery adds the delivery to the history of the stack, or replaces the one with the same identifier and date
+func (handler *Handler) recordWebhookDelivery(stackID portainer.StackID, delivery portainer.StackWebhookDelivery) error {
+	return handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		stack, err := tx.Stack().Read(stackID)
+		if err != nil {
+			return err
+		}
+
+		i := slices.IndexFunc(stack.WebhookDeliveries, func(d portainer.StackWebhookDelivery) bool {
+			return d.ID == delivery.ID && d.Date == delivery.Date
+		})
+		if i >= 0 {
+			stack.WebhookDeliveries[i] = delivery
+		} else {
+			stack.WebhookDeliveries = append(stack.WebhookDeliveries, delivery)
+		}
+
+		if n := len(stack.WebhookDeliveries); n > maxWebhookDeliveries {
+			stack.WebhookDeliveries = stack.WebhookDeliveries[n-maxWebhookDeliveries:]
+		}
+
+		return tx.Stack().Update(stackID, stack)
+	})
+}
+
 func retrieveUUIDRouteVariableValue(r *http.Request, name string) (uuid.UUID, error) {
 	webhookID, err := request.RetrieveRouteVariableValue(r, name)
 	if err != nil {
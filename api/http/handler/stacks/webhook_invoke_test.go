@@ -1,16 +1,25 @@
 package stacks
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/datastore"
+	gittypes "github.com/portainer/portainer/api/git/types"
 	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/stacks/deployments"
 
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandler_webhookInvoke(t *testing.T) {
@@ -49,6 +58,176 @@ func TestHandler_webhookInvoke(t *testing.T) {
 	})
 }
 
+func TestHandler_webhookInvokePushEvent(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	const secret = "s3cr3t"
+	webhookID := newGuidString(t)
+	err := store.StackService.Create(&portainer.Stack{
+		ID:        1,
+		CreatedBy: "admin",
+		GitConfig: &gittypes.RepoConfig{URL: "https://github.com/portainer/portainer", ReferenceName: "refs/heads/main"},
+		AutoUpdate: &portainer.AutoUpdateSettings{
+			Webhook:         webhookID,
+			WebhookProvider: portainer.GitWebhookProviderGitHub,
+			WebhookSecret:   secret,
+		},
+	})
+	require.NoError(t, err)
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+
+	push := func(deliveryID, event, body, signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/stacks/webhooks/"+webhookID, strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+signature)
+
+		w := httptest.NewRecorder()
+		h.Router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	deliveries := func() []portainer.StackWebhookDelivery {
+		stack, err := store.Stack().Read(1)
+		require.NoError(t, err)
+
+		return stack.WebhookDeliveries
+	}
+
+	t.Run("invalid signature results in http.StatusUnauthorized", func(t *testing.T) {
+		body := `{"ref":"refs/heads/main","after":"a5c9e5d3f2e7c3b04d8d6a7d5e4f3c2b1a0f9e8d"}`
+		w := push("1", "push", body, sign("{}"))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, deliveries())
+	})
+
+	t.Run("ping results in http.StatusNoContent", func(t *testing.T) {
+		w := push("2", "ping", "{}", sign("{}"))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, deliveries())
+	})
+
+	t.Run("push to another branch is recorded without redeployment", func(t *testing.T) {
+		body := `{"ref":"refs/heads/feature","after":"1111111111111111111111111111111111111111"}`
+		w := push("3", "push", body, sign(body))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		recorded := deliveries()
+		require.Len(t, recorded, 1)
+		assert.Equal(t, "3", recorded[0].ID)
+		assert.Equal(t, portainer.GitWebhookProviderGitHub, recorded[0].Provider)
+		assert.Equal(t, "refs/heads/feature", recorded[0].Ref)
+		assert.Equal(t, "1111111111111111111111111111111111111111", recorded[0].CommitSHA)
+		assert.False(t, recorded[0].Triggered)
+	})
+
+	t.Run("push to the stack branch records the failed redeployment", func(t *testing.T) {
+		body := `{"ref":"refs/heads/main","after":"a5c9e5d3f2e7c3b04d8d6a7d5e4f3c2b1a0f9e8d"}`
+		w := push("4", "push", body, sign(body))
+		// the environment of the stack does not exist
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		recorded := deliveries()
+		require.Len(t, recorded, 2)
+		assert.Equal(t, "4", recorded[1].ID)
+		assert.Equal(t, "refs/heads/main", recorded[1].Ref)
+		assert.Equal(t, "a5c9e5d3f2e7c3b04d8d6a7d5e4f3c2b1a0f9e8d", recorded[1].CommitSHA)
+		assert.False(t, recorded[1].Triggered)
+		assert.NotEmpty(t, recorded[1].Error)
+	})
+}
+
+type failingDeployer struct {
+	deployments.StackDeployer
+}
+
+func (d *failingDeployer) DeployComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage, forceRecreate bool) error {
+	return errors.New("deployment failed")
+}
+
+func TestHandler_webhookInvokePushEvent_RedeploymentOutcome(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	const secret = "s3cr3t"
+
+	require.NoError(t, store.User().Create(&portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+	h.GitService = testhelpers.NewGitService(nil, "newHash")
+	h.StackDeployer = &failingDeployer{}
+
+	tests := []struct {
+		name       string
+		autoUpdate portainer.AutoUpdateSettings
+		wantError  bool
+	}{
+		{name: "failed deployment", wantError: true},
+		{name: "changes filtered out", autoUpdate: portainer.AutoUpdateSettings{PathFilters: []string{"services/api/**"}}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stackID := portainer.StackID(i + 1)
+			webhookID := newGuidString(t)
+
+			autoUpdate := tt.autoUpdate
+			autoUpdate.Webhook = webhookID
+			autoUpdate.WebhookProvider = portainer.GitWebhookProviderGitHub
+			autoUpdate.WebhookSecret = secret
+
+			err := store.StackService.Create(&portainer.Stack{
+				ID:         stackID,
+				Type:       portainer.DockerComposeStack,
+				EndpointID: 1,
+				CreatedBy:  "admin",
+				GitConfig:  &gittypes.RepoConfig{URL: "https://github.com/portainer/portainer", ReferenceName: "refs/heads/main", ConfigHash: "oldHash"},
+				AutoUpdate: &autoUpdate,
+			})
+			require.NoError(t, err)
+
+			body := `{"ref":"refs/heads/main","after":"a5c9e5d3f2e7c3b04d8d6a7d5e4f3c2b1a0f9e8d"}`
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(body))
+
+			req := httptest.NewRequest(http.MethodPost, "/stacks/webhooks/"+webhookID, strings.NewReader(body))
+			req.Header.Set("X-GitHub-Event", "push")
+			req.Header.Set("X-GitHub-Delivery", tt.name)
+			req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+			w := httptest.NewRecorder()
+			h.Router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			// the redeployment runs in the background and records its outcome once it ends
+			var delivery portainer.StackWebhookDelivery
+			require.Eventually(t, func() bool {
+				stack, err := store.Stack().Read(stackID)
+				if err != nil || len(stack.WebhookDeliveries) != 1 {
+					return false
+				}
+
+				delivery = stack.WebhookDeliveries[0]
+
+				return !delivery.Triggered
+			}, 5*time.Second, 10*time.Millisecond)
+
+			assert.Equal(t, tt.wantError, delivery.Error != "", delivery.Error)
+		})
+	}
+}
+
 func newGuidString(t *testing.T) string {
 	uuid, err := uuid.NewV4()
 	assert.NoError(t, err)
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	httperror "github.com/portainer/portainer/pkg/libhttp/error"
@@ -57,13 +58,15 @@ func (handler *Handler) adminInit(w http.ResponseWriter, r *http.Request) *httpe
 		return httperror.Conflict("Unable to create administrator user", errAdminAlreadyInitialized)
 	}
 
-	if !handler.passwordStrengthChecker.Check(payload.Password) {
-		return httperror.BadRequest("Password does not meet the requirements", nil)
+	if violations := handler.passwordStrengthChecker.Violations(payload.Password); len(violations) > 0 {
+		return passwordPolicyError(violations)
 	}
 
 	user := &portainer.User{
-		Username: payload.Username,
-		Role:     portainer.AdministratorRole,
+		Username:          payload.Username,
+		Role:              portainer.AdministratorRole,
+		PasswordChangedAt: time.Now().Unix(),
+		Origin:            portainer.UserOriginInternal,
 	}
 
 	user.Password, err = handler.CryptoService.Hash(payload.Password)
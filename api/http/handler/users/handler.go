@@ -23,6 +23,10 @@ var (
 
 func hideFields(user *portainer.User) {
 	user.Password = ""
+	user.TwoFactor.Secret = ""
+	user.TwoFactor.RecoveryCodes = nil
+	user.TwoFactor.LastUsedStep = 0
+	user.PasswordHistory = nil
 }
 
 // Handler is the HTTP handler used to handle user operations.
@@ -62,7 +66,11 @@ func NewHandler(bouncer security.BouncerService, rateLimiter *security.RateLimit
 	publicRouter.Use(bouncer.PublicAccess)
 
 	adminRouter.Handle("/users", httperror.LoggerHandler(h.userCreate)).Methods(http.MethodPost)
+	adminRouter.Handle("/users/tokens", httperror.LoggerHandler(h.userListAllAccessTokens)).Methods(http.MethodGet)
+	adminRouter.Handle("/users/tokens/{keyID}", httperror.LoggerHandler(h.userRevokeAccessToken)).Methods(http.MethodDelete)
+	adminRouter.Handle("/users/sessions", httperror.LoggerHandler(h.userRevokeAllSessions)).Methods(http.MethodDelete)
 	restrictedRouter.Handle("/users", httperror.LoggerHandler(h.userList)).Methods(http.MethodGet)
+	authenticatedRouter.Handle("/users/password/check", httperror.LoggerHandler(h.userPasswordCheck)).Methods(http.MethodPost)
 
 	authenticatedRouter.Handle("/users/me", httperror.LoggerHandler(h.userInspectMe)).Methods(http.MethodGet)
 	restrictedRouter.Handle("/users/{id}", httperror.LoggerHandler(h.userInspect)).Methods(http.MethodGet)
@@ -71,8 +79,13 @@ func NewHandler(bouncer security.BouncerService, rateLimiter *security.RateLimit
 	restrictedRouter.Handle("/users/{id}/tokens", httperror.LoggerHandler(h.userGetAccessTokens)).Methods(http.MethodGet)
 	restrictedRouter.Handle("/users/{id}/tokens", rateLimiter.LimitAccess(httperror.LoggerHandler(h.userCreateAccessToken))).Methods(http.MethodPost)
 	restrictedRouter.Handle("/users/{id}/tokens/{keyID}", httperror.LoggerHandler(h.userRemoveAccessToken)).Methods(http.MethodDelete)
+	restrictedRouter.Handle("/users/{id}/sessions", httperror.LoggerHandler(h.userListSessions)).Methods(http.MethodGet)
+	restrictedRouter.Handle("/users/{id}/sessions/{sessionID}", httperror.LoggerHandler(h.userRevokeSession)).Methods(http.MethodDelete)
 	restrictedRouter.Handle("/users/{id}/memberships", httperror.LoggerHandler(h.userMemberships)).Methods(http.MethodGet)
 	authenticatedRouter.Handle("/users/{id}/passwd", rateLimiter.LimitAccess(httperror.LoggerHandler(h.userUpdatePassword))).Methods(http.MethodPut)
+	authenticatedRouter.Handle("/users/{id}/2fa", httperror.LoggerHandler(h.userTwoFactorEnrol)).Methods(http.MethodPost)
+	authenticatedRouter.Handle("/users/{id}/2fa/verify", rateLimiter.LimitAccess(httperror.LoggerHandler(h.userTwoFactorVerify))).Methods(http.MethodPost)
+	adminRouter.Handle("/users/{id}/2fa", httperror.LoggerHandler(h.userTwoFactorReset)).Methods(http.MethodDelete)
 
 	publicRouter.Handle("/users/admin/check", httperror.LoggerHandler(h.adminCheck)).Methods(http.MethodGet)
 	publicRouter.Handle("/users/admin/init", httperror.LoggerHandler(h.adminInit)).Methods(http.MethodPost)
@@ -0,0 +1,185 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/twofactor"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+type userTwoFactorVerifyPayload struct {
+	// TOTP code generated by the authenticator application from the new secret
+	Code string `example:"123456" validate:"required"`
+}
+
+func (payload *userTwoFactorVerifyPayload) Validate(r *http.Request) error {
+	if len(payload.Code) == 0 {
+		return errors.New("Invalid code")
+	}
+
+	return nil
+}
+
+type userTwoFactorVerifyResponse struct {
+	// One-time recovery codes, they are not retrievable afterwards
+	RecoveryCodes []string `json:"RecoveryCodes"`
+}
+
+// @id UserTwoFactorEnrol
+// @summary Start the two-factor authentication enrolment of a user
+// @description Generate a new TOTP secret for the current user. The second factor is only enabled once a code generated from the secret is verified.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 {object} twofactor.Enrolment "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 409 "Two-factor authentication is already enabled"
+// @failure 500 "Server error"
+// @router /users/{id}/2fa [post]
+func (handler *Handler) userTwoFactorEnrol(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, httpErr := handler.twoFactorSelf(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if user.TwoFactor.Enabled {
+		return httperror.Conflict("Two-factor authentication is already enabled for this user", errors.New("two-factor authentication is already enabled"))
+	}
+
+	enrolment, err := twofactor.NewEnrolment(user)
+	if err != nil {
+		return httperror.InternalServerError("Unable to generate a two-factor authentication secret", err)
+	}
+
+	user.TwoFactor = portainer.UserTwoFactor{Secret: enrolment.Secret}
+
+	if err := handler.DataStore.User().Update(user.ID, user); err != nil {
+		return httperror.InternalServerError("Unable to persist user changes inside the database", err)
+	}
+
+	return response.JSON(w, enrolment)
+}
+
+// @id UserTwoFactorVerify
+// @summary Confirm the two-factor authentication enrolment of a user
+// @description Verify a code generated from the secret returned by the enrolment and enable the second factor of the current user.
+// @description The recovery codes are only returned by this call.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "User identifier"
+// @param body body userTwoFactorVerifyPayload true "Code"
+// @success 200 {object} userTwoFactorVerifyResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 409 "Two-factor authentication is already enabled"
+// @failure 422 "Invalid code"
+// @failure 500 "Server error"
+// @router /users/{id}/2fa/verify [post]
+func (handler *Handler) userTwoFactorVerify(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload userTwoFactorVerifyPayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	user, httpErr := handler.twoFactorSelf(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if user.TwoFactor.Enabled {
+		return httperror.Conflict("Two-factor authentication is already enabled for this user", errors.New("two-factor authentication is already enabled"))
+	}
+
+	recoveryCodes, err := twofactor.Enable(user, user.TwoFactor.Secret, payload.Code, handler.CryptoService, time.Now())
+	if errors.Is(err, twofactor.ErrNoPendingSetup) {
+		return httperror.BadRequest("Two-factor authentication enrolment has not been started", err)
+	} else if errors.Is(err, twofactor.ErrInvalidCode) {
+		return httperror.NewError(http.StatusUnprocessableEntity, "Invalid two-factor authentication code", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to enable two-factor authentication", err)
+	}
+
+	if err := handler.DataStore.User().Update(user.ID, user); err != nil {
+		return httperror.InternalServerError("Unable to persist user changes inside the database", err)
+	}
+
+	return response.JSON(w, &userTwoFactorVerifyResponse{RecoveryCodes: recoveryCodes})
+}
+
+// @id UserTwoFactorReset
+// @summary Reset the two-factor authentication of a user
+// @description Remove the second factor and recovery codes of a user, who will have to enrol again if two-factor authentication is enforced.
+// @description **Access policy**: administrator
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "User identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/2fa [delete]
+func (handler *Handler) userTwoFactorReset(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid user identifier route variable", err)
+	}
+
+	user, err := handler.DataStore.User().Read(portainer.UserID(userID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a user with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a user with the specified identifier inside the database", err)
+	}
+
+	user.TwoFactor = portainer.UserTwoFactor{}
+
+	if err := handler.DataStore.User().Update(user.ID, user); err != nil {
+		return httperror.InternalServerError("Unable to persist user changes inside the database", err)
+	}
+
+	return response.Empty(w)
+}
+
+// twoFactorSelf returns the user of the route, only users can manage their own second factor
+func (handler *Handler) twoFactorSelf(r *http.Request) (*portainer.User, *httperror.HandlerError) {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, httperror.BadRequest("Invalid user identifier route variable", err)
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve user authentication token", err)
+	}
+
+	if tokenData.ID != portainer.UserID(userID) {
+		return nil, httperror.Forbidden("Permission denied to manage the two-factor authentication of this user", httperrors.ErrUnauthorized)
+	}
+
+	user, err := handler.DataStore.User().Read(portainer.UserID(userID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return nil, httperror.NotFound("Unable to find a user with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, httperror.InternalServerError("Unable to find a user with the specified identifier inside the database", err)
+	}
+
+	return user, nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
@@ -73,6 +74,14 @@ func (handler *Handler) userCreate(w http.ResponseWriter, r *http.Request) *http
 	return response.JSON(w, user)
 }
 
+// userOrigins maps the authentication methods with the origin of the users created while they are active
+var userOrigins = map[portainer.AuthenticationMethod]portainer.UserOrigin{
+	portainer.AuthenticationInternal: portainer.UserOriginInternal,
+	portainer.AuthenticationLDAP:     portainer.UserOriginLDAP,
+	portainer.AuthenticationOAuth:    portainer.UserOriginOAuth,
+	portainer.AuthenticationSAML:     portainer.UserOriginSAML,
+}
+
 func (handler *Handler) createUser(tx dataservices.DataStoreTx, payload userCreatePayload) (*portainer.User, error) {
 	user, err := tx.User().UserByUsername(payload.Username)
 	if err != nil && !tx.IsErrObjectNotFound(err) {
@@ -100,16 +109,19 @@ func (handler *Handler) createUser(tx dataservices.DataStoreTx, payload userCrea
 	}
 
 	if settings.AuthenticationMethod == portainer.AuthenticationInternal {
-		if !handler.passwordStrengthChecker.Check(payload.Password) {
-			return nil, httperror.BadRequest("Password does not meet the requirements", nil)
+		if violations := handler.passwordStrengthChecker.Violations(payload.Password); len(violations) > 0 {
+			return nil, passwordPolicyError(violations)
 		}
 
 		user.Password, err = handler.CryptoService.Hash(payload.Password)
 		if err != nil {
 			return nil, httperror.InternalServerError("Unable to hash user password", errCryptoHashFailure)
 		}
+		user.PasswordChangedAt = time.Now().Unix()
 	}
 
+	user.Origin = userOrigins[settings.AuthenticationMethod]
+
 	if err := tx.User().Create(user); err != nil {
 		return nil, httperror.InternalServerError("Unable to persist user inside the database", err)
 	}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	httperrors "github.com/portainer/portainer/api/http/errors"
@@ -17,6 +18,10 @@ import (
 type userAccessTokenCreatePayload struct {
 	Password    string `validate:"required" example:"password" json:"password"`
 	Description string `validate:"required" example:"github-api-key" json:"description"`
+	// Unix timestamp (UTC) after which the key is rejected, the key never expires when omitted
+	ExpiresAt int64 `example:"1700000000" json:"expiresAt"`
+	// Restrictions applied on top of the permissions of the user
+	Scope *portainer.APIKeyScope `json:"scope"`
 }
 
 func (payload *userAccessTokenCreatePayload) Validate(r *http.Request) error {
@@ -29,7 +34,11 @@ func (payload *userAccessTokenCreatePayload) Validate(r *http.Request) error {
 	if validate.MinStringLength(payload.Description, 128) {
 		return errors.New("invalid description: cannot be longer than 128 characters")
 	}
-	return nil
+	if payload.ExpiresAt != 0 && payload.ExpiresAt <= time.Now().Unix() {
+		return errors.New("invalid expiration date: must be in the future")
+	}
+
+	return security.ValidateAPIKeyScope(payload.Scope)
 }
 
 type accessTokenResponse struct {
@@ -42,6 +51,7 @@ type accessTokenResponse struct {
 // @description Generates an API key for a user.
 // @description Only the calling user can generate a token for themselves.
 // @description Password is required only for internal authentication.
+// @description The key can expire and be restricted to read operations, to some environments or to some route groups.
 // @description **Access policy**: restricted
 // @tags users
 // @security jwt
@@ -109,7 +119,17 @@ func (handler *Handler) userCreateAccessToken(w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	rawAPIKey, apiKey, err := handler.apiKeyService.GenerateApiKey(*user, payload.Description)
+	if payload.Scope != nil {
+		for _, endpointID := range payload.Scope.EndpointIDs {
+			if _, err := handler.DataStore.Endpoint().Endpoint(endpointID); handler.DataStore.IsErrObjectNotFound(err) {
+				return httperror.BadRequest("Invalid request payload", fmt.Errorf("environment %d does not exist", endpointID))
+			} else if err != nil {
+				return httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+			}
+		}
+	}
+
+	rawAPIKey, apiKey, err := handler.apiKeyService.GenerateScopedApiKey(*user, payload.Description, payload.ExpiresAt, payload.Scope)
 	if err != nil {
 		return httperror.InternalServerError("Internal Server Error", err)
 	}
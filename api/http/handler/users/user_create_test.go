@@ -10,6 +10,7 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/crypto"
 	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
@@ -21,6 +22,10 @@ func (m *mockPasswordStrengthChecker) Check(string) bool {
 	return true
 }
 
+func (m *mockPasswordStrengthChecker) Violations(string) []security.PasswordViolation {
+	return nil
+}
+
 func TestConcurrentUserCreation(t *testing.T) {
 	_, store := datastore.MustNewTestStore(t, true, false)
 
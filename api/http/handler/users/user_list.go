@@ -15,6 +15,8 @@ type User struct {
 	Username string           `json:"Username" example:"bob"`
 	// User role (1 for administrator account and 2 for regular account)
 	Role portainer.UserRole `json:"Role" example:"1"`
+	// Whether the user has enabled two-factor authentication
+	TwoFactorEnabled bool `json:"TwoFactorEnabled" example:"false"`
 }
 
 // @id UserList
@@ -93,6 +95,8 @@ func sanitizeUser(user portainer.User) User {
 		ID:       user.ID,
 		Username: user.Username,
 		Role:     user.Role,
+
+		TwoFactorEnabled: user.TwoFactor.Enabled,
 	}
 }
 
@@ -0,0 +1,66 @@
+package users
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+type accessTokenListItem struct {
+	portainer.APIKey
+	// Username of the owner of the key
+	Username string `json:"username" example:"bob"`
+	// True when the expiration date of the key is passed
+	Expired bool `json:"expired" example:"false"`
+}
+
+// @id UserListAllAPIKeys
+// @summary List the API keys of all the users
+// @description List the API keys of all the users, along with their owner, expiration and scope.
+// @description **Access policy**: administrator
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {array} accessTokenListItem "Success"
+// @failure 500 "Server error"
+// @router /users/tokens [get]
+func (handler *Handler) userListAllAccessTokens(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	apiKeys, err := handler.apiKeyService.GetAllAPIKeys()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve API keys from the database", err)
+	}
+
+	users, err := handler.DataStore.User().ReadAll()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve users from the database", err)
+	}
+
+	usernames := make(map[portainer.UserID]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+
+	now := time.Now().Unix()
+
+	items := make([]accessTokenListItem, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		hideAPIKeyFields(&apiKey)
+
+		items = append(items, accessTokenListItem{
+			APIKey:   apiKey,
+			Username: usernames[apiKey.UserID],
+			Expired:  apiKey.ExpiresAt > 0 && apiKey.ExpiresAt <= now,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+
+	return response.JSON(w, items)
+}
@@ -0,0 +1,106 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/segmentio/encoding/json"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_userListAllAccessTokens(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	adminUser := &portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(adminUser))
+
+	user := &portainer.User{ID: 2, Username: "standard", Role: portainer.StandardUserRole}
+	require.NoError(t, store.User().Create(user))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	requestBouncer := security.NewRequestBouncer(store, jwtService, apiKeyService)
+	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
+	passwordChecker := security.NewPasswordStrengthChecker(store.SettingsService)
+
+	h := NewHandler(requestBouncer, rateLimiter, apiKeyService, passwordChecker)
+	h.DataStore = store
+
+	adminJWT, _, _ := jwtService.GenerateToken(&portainer.TokenData{ID: adminUser.ID, Username: adminUser.Username, Role: adminUser.Role})
+	jwt, _, _ := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+
+	_, _, err = apiKeyService.GenerateApiKey(*adminUser, "admin-key")
+	require.NoError(t, err)
+
+	rawAPIKey, userKey, err := apiKeyService.GenerateScopedApiKey(*user, "user-key", time.Now().Add(time.Hour).Unix(), &portainer.APIKeyScope{ReadOnly: true})
+	require.NoError(t, err)
+
+	t.Run("standard user cannot list all the keys", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/tokens", nil)
+		testhelpers.AddTestSecurityCookie(req, jwt)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("admin lists the keys of all the users", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/tokens", nil)
+		testhelpers.AddTestSecurityCookie(req, adminJWT)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp []accessTokenListItem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.Len(t, resp, 2)
+
+		require.Equal(t, "admin", resp[0].Username)
+		require.Equal(t, "standard", resp[1].Username)
+		require.Empty(t, resp[1].Digest)
+		require.Equal(t, userKey.ExpiresAt, resp[1].ExpiresAt)
+		require.True(t, resp[1].Scope.ReadOnly)
+		require.False(t, resp[1].Expired)
+	})
+
+	t.Run("admin revokes the key of a user", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/users/tokens/2", nil)
+		testhelpers.AddTestSecurityCookie(req, adminJWT)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/users/2/tokens", nil)
+		req.Header.Add("x-api-key", rawAPIKey)
+
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("revoking an unknown key fails", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/users/tokens/42", nil)
+		testhelpers.AddTestSecurityCookie(req, adminJWT)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,146 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+
+	"github.com/pkg/errors"
+)
+
+type userPasswordCheckPayload struct {
+	// Password to check
+	Password string `example:"new_passwd" validate:"required"`
+	// User whose previous passwords cannot be reused, omit it for a new user
+	UserID portainer.UserID `example:"2"`
+}
+
+func (payload *userPasswordCheckPayload) Validate(r *http.Request) error {
+	if len(payload.Password) == 0 {
+		return errors.New("Invalid password")
+	}
+
+	return nil
+}
+
+type userPasswordCheckResponse struct {
+	// Whether the password follows the password policy
+	Valid bool `json:"Valid" example:"false"`
+	// Rules of the password policy broken by the password
+	Violations []security.PasswordViolation `json:"Violations" example:"uppercase"`
+}
+
+// @id UserPasswordCheck
+// @summary Check a password against the password policy
+// @description Check a password against the password policy, to give live feedback while the password is typed.
+// @description When a user is specified, the reuse of one of their previous passwords is also checked.
+// @description Only the specified user or an admin can check the reuse of the previous passwords.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param body body userPasswordCheckPayload true "Password to check"
+// @success 200 {object} userPasswordCheckResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/password/check [post]
+func (handler *Handler) userPasswordCheck(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload userPasswordCheckPayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	var user *portainer.User
+	if payload.UserID != 0 {
+		tokenData, err := security.RetrieveTokenData(r)
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve user authentication token", err)
+		}
+
+		if tokenData.Role != portainer.AdministratorRole && tokenData.ID != payload.UserID {
+			return httperror.Forbidden("Permission denied to check the password of this user", httperrors.ErrUnauthorized)
+		}
+
+		user, err = handler.DataStore.User().Read(payload.UserID)
+		if handler.DataStore.IsErrObjectNotFound(err) {
+			return httperror.NotFound("Unable to find a user with the specified identifier inside the database", err)
+		} else if err != nil {
+			return httperror.InternalServerError("Unable to find a user with the specified identifier inside the database", err)
+		}
+	}
+
+	violations, err := handler.passwordViolations(payload.Password, user)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
+	}
+
+	return response.JSON(w, &userPasswordCheckResponse{Valid: len(violations) == 0, Violations: violations})
+}
+
+// passwordViolations returns the rules of the password policy broken by a new password of user, nil for a new user
+func (handler *Handler) passwordViolations(password string, user *portainer.User) ([]security.PasswordViolation, error) {
+	violations := handler.passwordStrengthChecker.Violations(password)
+
+	if user == nil {
+		return violations, nil
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	if security.PasswordReused(password, user, settings.InternalAuthSettings.PasswordPolicy.HistorySize, handler.CryptoService) {
+		violations = append(violations, security.PasswordViolationReused)
+	}
+
+	return violations, nil
+}
+
+// setUserPassword checks a new password of an existing user against the password policy before replacing it
+func (handler *Handler) setUserPassword(user *portainer.User, password string) *httperror.HandlerError {
+	violations, err := handler.passwordViolations(password, user)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
+	}
+
+	if len(violations) > 0 {
+		return passwordPolicyError(violations)
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
+	}
+
+	hash, err := handler.CryptoService.Hash(password)
+	if err != nil {
+		return httperror.InternalServerError("Unable to hash user password", errCryptoHashFailure)
+	}
+
+	security.RecordPasswordChange(user, hash, settings.InternalAuthSettings.PasswordPolicy.HistorySize, time.Now())
+
+	return nil
+}
+
+// passwordPolicyError describes the rules of the password policy broken by a password
+func passwordPolicyError(violations []security.PasswordViolation) *httperror.HandlerError {
+	rules := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		rules = append(rules, string(violation))
+	}
+
+	return httperror.BadRequest("Password does not meet the requirements", fmt.Errorf("password policy violations: %s", strings.Join(rules, ", ")))
+}
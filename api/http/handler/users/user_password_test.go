@@ -0,0 +1,125 @@
+package users
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/segmentio/encoding/json"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_userPasswordPolicy(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	cryptoService := &crypto.Service{}
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+	settings.InternalAuthSettings.RequiredPasswordLength = 10
+	settings.InternalAuthSettings.PasswordPolicy = portainer.PasswordPolicy{
+		RequireUppercase:    true,
+		RequireDigit:        true,
+		DenyCommonPasswords: true,
+		HistorySize:         2,
+	}
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	hash, err := cryptoService.Hash("First-password-1")
+	require.NoError(t, err)
+
+	user := &portainer.User{Username: "standard", Role: portainer.StandardUserRole, Password: hash}
+	require.NoError(t, store.User().Create(user))
+
+	other := &portainer.User{Username: "other", Role: portainer.StandardUserRole}
+	require.NoError(t, store.User().Create(other))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	requestBouncer := security.NewRequestBouncer(store, jwtService, apiKeyService)
+	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
+	passwordChecker := security.NewPasswordStrengthChecker(store.SettingsService)
+
+	h := NewHandler(requestBouncer, rateLimiter, apiKeyService, passwordChecker)
+	h.DataStore = store
+	h.CryptoService = cryptoService
+
+	do := func(method, path string, payload any) *httptest.ResponseRecorder {
+		// the password change invalidates the previous tokens
+		token, _, err := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+		require.NoError(t, err)
+
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		testhelpers.AddTestSecurityCookie(req, token)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	check := func(payload userPasswordCheckPayload) userPasswordCheckResponse {
+		rr := do(http.MethodPost, "/users/password/check", payload)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp userPasswordCheckResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+		return resp
+	}
+
+	changePassword := func(current, next string) int {
+		return do(http.MethodPut, fmt.Sprintf("/users/%d/passwd", user.ID), userUpdatePasswordPayload{Password: current, NewPassword: next}).Code
+	}
+
+	t.Run("check reports the broken rules", func(t *testing.T) {
+		resp := check(userPasswordCheckPayload{Password: "short"})
+		require.False(t, resp.Valid)
+		require.Equal(t, []security.PasswordViolation{security.PasswordViolationLength, security.PasswordViolationUppercase, security.PasswordViolationDigit}, resp.Violations)
+
+		resp = check(userPasswordCheckPayload{Password: "Welcome123!"})
+		require.Equal(t, []security.PasswordViolation{security.PasswordViolationCommon}, resp.Violations)
+
+		resp = check(userPasswordCheckPayload{Password: "First-password-1"})
+		require.True(t, resp.Valid)
+
+		resp = check(userPasswordCheckPayload{Password: "First-password-1", UserID: user.ID})
+		require.Equal(t, []security.PasswordViolation{security.PasswordViolationReused}, resp.Violations)
+	})
+
+	t.Run("check of the previous passwords of another user is forbidden", func(t *testing.T) {
+		rr := do(http.MethodPost, "/users/password/check", userPasswordCheckPayload{Password: "First-password-1", UserID: other.ID})
+		require.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("previous passwords cannot be reused", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, changePassword("First-password-1", "weak"))
+		require.Equal(t, http.StatusBadRequest, changePassword("First-password-1", "First-password-1"))
+		require.Equal(t, http.StatusNoContent, changePassword("First-password-1", "Second-password-2"))
+		require.Equal(t, http.StatusBadRequest, changePassword("Second-password-2", "First-password-1"))
+		require.Equal(t, http.StatusNoContent, changePassword("Second-password-2", "Third-password-3"))
+
+		// only the last two passwords are kept
+		require.Equal(t, http.StatusNoContent, changePassword("Third-password-3", "First-password-1"))
+
+		updated, err := store.User().Read(user.ID)
+		require.NoError(t, err)
+		require.Len(t, updated.PasswordHistory, 1)
+		require.NotZero(t, updated.PasswordChangedAt)
+	})
+}
@@ -0,0 +1,42 @@
+package users
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+// @id UserRevokeAPIKey
+// @summary Revoke an API key
+// @description Remove an API key of any user, it is rejected right away.
+// @description **Access policy**: administrator
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @param keyID path int true "Api Key identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /users/tokens/{keyID} [delete]
+func (handler *Handler) userRevokeAccessToken(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	apiKeyID, err := request.RetrieveNumericRouteVariableValue(r, "keyID")
+	if err != nil {
+		return httperror.BadRequest("Invalid api-key identifier route variable", err)
+	}
+
+	if _, err := handler.apiKeyService.GetAPIKey(portainer.APIKeyID(apiKeyID)); handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an api-key with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an api-key with the specified identifier inside the database", err)
+	}
+
+	if err := handler.apiKeyService.DeleteAPIKey(portainer.APIKeyID(apiKeyID)); err != nil {
+		return httperror.InternalServerError("Unable to remove the api-key", err)
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,163 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+type userSessionListItem struct {
+	portainer.UserSession
+	// Whether the session is the one used to make the request
+	Current bool `json:"Current" example:"true"`
+}
+
+// @id UserListSessions
+// @summary List the sessions of a user
+// @description List the active sessions of a user, the most recently used first.
+// @description Only the calling user or admin can list the sessions.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 {array} userSessionListItem "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/sessions [get]
+func (handler *Handler) userListSessions(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, tokenData, httpErr := handler.authorizeSessionAccess(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	currentID := security.TokenID(tokenData.Token)
+
+	sessions := handler.bouncer.UserSessions(userID)
+	items := make([]userSessionListItem, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, userSessionListItem{
+			UserSession: session,
+			Current:     currentID != "" && session.TokenID == currentID,
+		})
+	}
+
+	return response.JSON(w, items)
+}
+
+// @id UserRevokeSession
+// @summary Revoke a session of a user
+// @description Revoke a session of a user, the requests made with its token are rejected from then on.
+// @description Only the calling user or admin can revoke the sessions.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "User identifier"
+// @param sessionID path int true "Session identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @router /users/{id}/sessions/{sessionID} [delete]
+func (handler *Handler) userRevokeSession(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	sessionID, err := request.RetrieveNumericRouteVariableValue(r, "sessionID")
+	if err != nil {
+		return httperror.BadRequest("Invalid session identifier route variable", err)
+	}
+
+	userID, _, httpErr := handler.authorizeSessionAccess(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if err := handler.bouncer.RevokeUserSession(userID, portainer.UserSessionID(sessionID)); err != nil {
+		if errors.Is(err, security.ErrSessionNotFound) {
+			return httperror.NotFound("Unable to find an active session with the specified identifier", err)
+		}
+
+		return httperror.InternalServerError("Unable to revoke the session", err)
+	}
+
+	return response.Empty(w)
+}
+
+// @id UserRevokeAllSessions
+// @summary Log out every user
+// @description Revoke the sessions of every user except the session used to make the request.
+// @description The tokens issued to the other users before the request are rejected, including the ones that were never used.
+// @description **Access policy**: administrator
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @success 204 "Success"
+// @failure 500 "Server error"
+// @router /users/sessions [delete]
+func (handler *Handler) userRevokeAllSessions(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve user authentication token", err)
+	}
+
+	users, err := handler.DataStore.User().ReadAll()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve users from the database", err)
+	}
+
+	now := time.Now().Unix()
+	for _, user := range users {
+		if user.ID == tokenData.ID {
+			continue
+		}
+
+		user.TokenIssueAt = now
+		if err := handler.DataStore.User().Update(user.ID, &user); err != nil {
+			return httperror.InternalServerError("Unable to persist user changes inside the database", err)
+		}
+	}
+
+	if err := handler.bouncer.RevokeAllSessions(tokenData.Token); err != nil {
+		return httperror.InternalServerError("Unable to revoke the sessions", err)
+	}
+
+	return response.Empty(w)
+}
+
+// authorizeSessionAccess checks that the caller can manage the sessions of the user of the request
+func (handler *Handler) authorizeSessionAccess(r *http.Request) (portainer.UserID, *portainer.TokenData, *httperror.HandlerError) {
+	id, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return 0, nil, httperror.BadRequest("Invalid user identifier route variable", err)
+	}
+	userID := portainer.UserID(id)
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return 0, nil, httperror.InternalServerError("Unable to retrieve user authentication token", err)
+	}
+
+	if tokenData.Role != portainer.AdministratorRole && tokenData.ID != userID {
+		return 0, nil, httperror.Forbidden("Permission denied to manage the user sessions", httperrors.ErrUnauthorized)
+	}
+
+	if _, err := handler.DataStore.User().Read(userID); err != nil {
+		if handler.DataStore.IsErrObjectNotFound(err) {
+			return 0, nil, httperror.NotFound("Unable to find a user with the specified identifier inside the database", err)
+		}
+
+		return 0, nil, httperror.InternalServerError("Unable to find a user with the specified identifier inside the database", err)
+	}
+
+	return userID, tokenData, nil
+}
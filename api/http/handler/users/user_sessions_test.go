@@ -0,0 +1,113 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/segmentio/encoding/json"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_userSessions(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	adminUser := &portainer.User{Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(adminUser))
+
+	user := &portainer.User{Username: "standard", Role: portainer.StandardUserRole}
+	require.NoError(t, store.User().Create(user))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	requestBouncer := security.NewRequestBouncer(store, jwtService, apiKeyService)
+	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
+	passwordChecker := security.NewPasswordStrengthChecker(store.SettingsService)
+
+	h := NewHandler(requestBouncer, rateLimiter, apiKeyService, passwordChecker)
+	h.DataStore = store
+
+	adminJWT, _, _ := jwtService.GenerateToken(&portainer.TokenData{ID: adminUser.ID, Username: adminUser.Username, Role: adminUser.Role})
+	jwt, _, _ := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		testhelpers.AddTestSecurityCookie(req, token)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	listSessions := func(userID portainer.UserID, token string) []userSessionListItem {
+		rr := do(http.MethodGet, fmt.Sprintf("/users/%d/sessions", userID), token)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp []userSessionListItem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+		return resp
+	}
+
+	t.Run("user lists their own session", func(t *testing.T) {
+		sessions := listSessions(user.ID, jwt)
+		require.Len(t, sessions, 1)
+		require.True(t, sessions[0].Current)
+	})
+
+	t.Run("standard user cannot list the sessions of another user", func(t *testing.T) {
+		rr := do(http.MethodGet, fmt.Sprintf("/users/%d/sessions", adminUser.ID), jwt)
+		require.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("admin revokes the session of a user", func(t *testing.T) {
+		sessions := listSessions(user.ID, adminJWT)
+		require.Len(t, sessions, 1)
+		require.False(t, sessions[0].Current)
+
+		rr := do(http.MethodDelete, fmt.Sprintf("/users/%d/sessions/%d", user.ID, sessions[0].ID), adminJWT)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = do(http.MethodGet, fmt.Sprintf("/users/%d/sessions", user.ID), jwt)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		rr = do(http.MethodDelete, fmt.Sprintf("/users/%d/sessions/%d", user.ID, sessions[0].ID), adminJWT)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("admin logs out everyone", func(t *testing.T) {
+		otherJWT, _, _ := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+		otherAdminJWT, _, _ := jwtService.GenerateToken(&portainer.TokenData{ID: adminUser.ID, Username: adminUser.Username, Role: adminUser.Role})
+		require.Len(t, listSessions(adminUser.ID, otherAdminJWT), 2)
+
+		// Tokens issued in the same second as the revocation are still accepted
+		time.Sleep(time.Second)
+
+		rr := do(http.MethodDelete, "/users/sessions", jwt)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		rr = do(http.MethodDelete, "/users/sessions", adminJWT)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = do(http.MethodGet, fmt.Sprintf("/users/%d/sessions", user.ID), otherJWT)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		rr = do(http.MethodGet, fmt.Sprintf("/users/%d/sessions", adminUser.ID), otherAdminJWT)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		sessions := listSessions(adminUser.ID, adminJWT)
+		require.Len(t, sessions, 1)
+		require.True(t, sessions[0].Current)
+	})
+}
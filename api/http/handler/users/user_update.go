@@ -123,13 +123,8 @@ func (handler *Handler) userUpdate(w http.ResponseWriter, r *http.Request) *http
 			}
 		}
 
-		if !handler.passwordStrengthChecker.Check(payload.NewPassword) {
-			return httperror.BadRequest("Password does not meet the minimum strength requirements", nil)
-		}
-
-		user.Password, err = handler.CryptoService.Hash(payload.NewPassword)
-		if err != nil {
-			return httperror.InternalServerError("Unable to hash user password", errCryptoHashFailure)
+		if httpErr := handler.setUserPassword(user, payload.NewPassword); httpErr != nil {
+			return httpErr
 		}
 		user.TokenIssueAt = time.Now().Unix()
 	}
@@ -152,8 +147,8 @@ func (handler *Handler) userUpdate(w http.ResponseWriter, r *http.Request) *http
 	// remove all of the users persisted API keys
 	handler.apiKeyService.InvalidateUserKeyCache(user.ID)
 
-	// hide the password field in the response payload
-	user.Password = ""
+	// hide the password and second factor fields in the response payload
+	hideFields(user)
 
 	return response.JSON(w, user)
 }
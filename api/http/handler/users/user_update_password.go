@@ -80,13 +80,8 @@ func (handler *Handler) userUpdatePassword(w http.ResponseWriter, r *http.Reques
 		return httperror.Forbidden("Current password doesn't match", errors.New("Current password does not match the password provided. Please try again"))
 	}
 
-	if !handler.passwordStrengthChecker.Check(payload.NewPassword) {
-		return httperror.BadRequest("Password does not meet the minimum strength requirements", nil)
-	}
-
-	user.Password, err = handler.CryptoService.Hash(payload.NewPassword)
-	if err != nil {
-		return httperror.InternalServerError("Unable to hash user password", errCryptoHashFailure)
+	if httpErr := handler.setUserPassword(user, payload.NewPassword); httpErr != nil {
+		return httpErr
 	}
 
 	user.TokenIssueAt = time.Now().Unix()
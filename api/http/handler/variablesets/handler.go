@@ -0,0 +1,52 @@
+package variablesets
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/security"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+
+	"github.com/gorilla/mux"
+)
+
+// Handler is the HTTP handler used to handle variable set operations.
+type Handler struct {
+	*mux.Router
+	DataStore dataservices.DataStore
+}
+
+// NewHandler creates a handler to manage variable set operations.
+func NewHandler(bouncer security.BouncerService) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+
+	h.Handle("/variable_sets",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.variableSetCreate))).Methods(http.MethodPost)
+	h.Handle("/variable_sets",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.variableSetList))).Methods(http.MethodGet)
+	h.Handle("/variable_sets/{id}",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.variableSetInspect))).Methods(http.MethodGet)
+	h.Handle("/variable_sets/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.variableSetUpdate))).Methods(http.MethodPut)
+	h.Handle("/variable_sets/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.variableSetDelete))).Methods(http.MethodDelete)
+
+	return h
+}
+
+func txResponse(w http.ResponseWriter, r any, err error) *httperror.HandlerError {
+	if err != nil {
+		var handlerError *httperror.HandlerError
+		if errors.As(err, &handlerError) {
+			return handlerError
+		}
+
+		return httperror.InternalServerError("Unexpected error", err)
+	}
+
+	return response.JSON(w, r)
+}
@@ -0,0 +1,163 @@
+package variablesets
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+var variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type variableSetCreatePayload struct {
+	// Variable set name
+	Name string `validate:"required" example:"production"`
+	// Scope of the variable set, 1 for an environment group, 2 for an environment and 3 for a team
+	ScopeType portainer.VariableSetScopeType `validate:"required" example:"1" enums:"1,2,3"`
+	// Identifier of the environment group, environment or team the variable set is scoped to
+	ScopeID int `validate:"required" example:"1"`
+	// Variables of the set
+	Variables []portainer.StackVariable
+}
+
+func (payload *variableSetCreatePayload) Validate(r *http.Request) error {
+	if len(payload.Name) == 0 {
+		return errors.New("invalid variable set name")
+	}
+
+	if payload.ScopeType != portainer.EndpointGroupVariableSetScope &&
+		payload.ScopeType != portainer.EndpointVariableSetScope &&
+		payload.ScopeType != portainer.TeamVariableSetScope {
+		return errors.New("invalid variable set scope type, must be 1, 2 or 3")
+	}
+
+	if payload.ScopeID <= 0 {
+		return errors.New("invalid variable set scope identifier")
+	}
+
+	return validateVariables(payload.Variables)
+}
+
+func validateVariables(variables []portainer.StackVariable) error {
+	names := make(map[string]bool, len(variables))
+
+	for _, variable := range variables {
+		if !variableNameRegex.MatchString(variable.Name) {
+			return fmt.Errorf("invalid variable name %q", variable.Name)
+		}
+
+		if names[variable.Name] {
+			return fmt.Errorf("duplicate variable %q", variable.Name)
+		}
+
+		names[variable.Name] = true
+	}
+
+	return nil
+}
+
+// @id VariableSetCreate
+// @summary Create a variable set
+// @description Create a named set of variables scoped to an environment group, an environment or a team, which the stacks
+// @description can reference to inherit its variables. The values are encrypted at rest when a secrets key is loaded.
+// @description **Access policy**: administrator
+// @tags variable_sets
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param body body variableSetCreatePayload true "Variable set details"
+// @success 200 {object} portainer.VariableSet "Success"
+// @failure 400 "Invalid request"
+// @failure 409 "This name is already associated to a variable set of the same scope"
+// @failure 500 "Server error"
+// @router /variable_sets [post]
+func (handler *Handler) variableSetCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload variableSetCreatePayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	var variableSet *portainer.VariableSet
+	err := handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		var err error
+		variableSet, err = createVariableSet(tx, payload)
+
+		return err
+	})
+	if err == nil {
+		variableSet.Variables = stackutils.MaskVariables(variableSet.Variables)
+	}
+
+	return txResponse(w, variableSet, err)
+}
+
+func createVariableSet(tx dataservices.DataStoreTx, payload variableSetCreatePayload) (*portainer.VariableSet, error) {
+	if err := checkScopeExists(tx, payload.ScopeType, payload.ScopeID); err != nil {
+		return nil, err
+	}
+
+	variableSet := &portainer.VariableSet{
+		Name:      payload.Name,
+		ScopeType: payload.ScopeType,
+		ScopeID:   payload.ScopeID,
+		Variables: payload.Variables,
+	}
+
+	if err := checkUniqueName(tx, variableSet); err != nil {
+		return nil, err
+	}
+
+	if variableSet.Variables == nil {
+		variableSet.Variables = []portainer.StackVariable{}
+	}
+
+	if err := tx.VariableSet().Create(variableSet); err != nil {
+		return nil, httperror.InternalServerError("Unable to persist the variable set inside the database", err)
+	}
+
+	return variableSet, nil
+}
+
+func checkScopeExists(tx dataservices.DataStoreTx, scopeType portainer.VariableSetScopeType, scopeID int) error {
+	var err error
+
+	switch scopeType {
+	case portainer.EndpointGroupVariableSetScope:
+		_, err = tx.EndpointGroup().Read(portainer.EndpointGroupID(scopeID))
+	case portainer.EndpointVariableSetScope:
+		_, err = tx.Endpoint().Endpoint(portainer.EndpointID(scopeID))
+	case portainer.TeamVariableSetScope:
+		_, err = tx.Team().Read(portainer.TeamID(scopeID))
+	}
+
+	if tx.IsErrObjectNotFound(err) {
+		return httperror.BadRequest("Unable to find the object the variable set is scoped to", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find the object the variable set is scoped to", err)
+	}
+
+	return nil
+}
+
+func checkUniqueName(tx dataservices.DataStoreTx, variableSet *portainer.VariableSet) error {
+	variableSets, err := tx.VariableSet().ReadAll()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the variable sets from the database", err)
+	}
+
+	for _, existing := range variableSets {
+		if existing.ID != variableSet.ID && existing.Name == variableSet.Name &&
+			existing.ScopeType == variableSet.ScopeType && existing.ScopeID == variableSet.ScopeID {
+			return httperror.Conflict("This name is already associated to a variable set of the same scope", errors.New("a variable set already exists with this name"))
+		}
+	}
+
+	return nil
+}
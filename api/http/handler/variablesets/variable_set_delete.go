@@ -0,0 +1,71 @@
+package variablesets
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+	"github.com/portainer/portainer/pkg/libhttp/response"
+)
+
+// @id VariableSetDelete
+// @summary Remove a variable set
+// @description Remove a variable set, the variable sets still referenced by stacks cannot be removed.
+// @description **Access policy**: administrator
+// @tags variable_sets
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Variable set identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Variable set not found"
+// @failure 409 "The variable set is referenced by stacks"
+// @failure 500 "Server error"
+// @router /variable_sets/{id} [delete]
+func (handler *Handler) variableSetDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	id, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid variable set identifier route variable", err)
+	}
+
+	err = handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		return deleteVariableSet(tx, portainer.VariableSetID(id))
+	})
+	if err != nil {
+		var handlerError *httperror.HandlerError
+		if errors.As(err, &handlerError) {
+			return handlerError
+		}
+
+		return httperror.InternalServerError("Unexpected error", err)
+	}
+
+	return response.Empty(w)
+}
+
+func deleteVariableSet(tx dataservices.DataStoreTx, id portainer.VariableSetID) error {
+	if _, err := readVariableSet(tx, id); err != nil {
+		return err
+	}
+
+	stacks, err := tx.Stack().ReadAll()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve stacks from the database", err)
+	}
+
+	for _, stack := range stacks {
+		if slices.Contains(stack.VariableSetIDs, id) {
+			return httperror.Conflict("The variable set is referenced by stacks", errors.New("the variable set is referenced by the stack "+stack.Name))
+		}
+	}
+
+	if err := tx.VariableSet().Delete(id); err != nil {
+		return httperror.InternalServerError("Unable to remove the variable set from the database", err)
+	}
+
+	return nil
+}
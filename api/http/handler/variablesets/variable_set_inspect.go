@@ -0,0 +1,73 @@
+package variablesets
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+// @id VariableSetInspect
+// @summary Inspect a variable set
+// @description Retrieve details about a variable set, the values of the secret variables are masked.
+// @description **Access policy**: authenticated
+// @tags variable_sets
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Variable set identifier"
+// @success 200 {object} portainer.VariableSet "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Variable set not found"
+// @failure 500 "Server error"
+// @router /variable_sets/{id} [get]
+func (handler *Handler) variableSetInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	id, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid variable set identifier route variable", err)
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	var variableSet *portainer.VariableSet
+	err = handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		variableSet, err = readVariableSet(tx, portainer.VariableSetID(id))
+		if err != nil {
+			return err
+		}
+
+		access, err := security.AuthorizedVariableSetAccess(tx, variableSet, securityContext)
+		if err != nil {
+			return httperror.InternalServerError("Unable to verify user authorizations to access the variable set", err)
+		} else if !access {
+			return httperror.Forbidden("Access denied to resource", httperrors.ErrResourceAccessDenied)
+		}
+
+		return nil
+	})
+	if err == nil {
+		variableSet.Variables = stackutils.MaskVariables(variableSet.Variables)
+	}
+
+	return txResponse(w, variableSet, err)
+}
+
+func readVariableSet(tx dataservices.DataStoreTx, id portainer.VariableSetID) (*portainer.VariableSet, error) {
+	variableSet, err := tx.VariableSet().Read(id)
+	if tx.IsErrObjectNotFound(err) {
+		return nil, httperror.NotFound("Unable to find a variable set with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, httperror.InternalServerError("Unable to find a variable set with the specified identifier inside the database", err)
+	}
+
+	return variableSet, nil
+}
@@ -0,0 +1,56 @@
+package variablesets
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+)
+
+// @id VariableSetList
+// @summary List variable sets
+// @description List the variable sets the user can reference from a stack, the values of the secret variables are masked.
+// @description **Access policy**: authenticated
+// @tags variable_sets
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {array} portainer.VariableSet "Success"
+// @failure 500 "Server error"
+// @router /variable_sets [get]
+func (handler *Handler) variableSetList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	var variableSets []portainer.VariableSet
+	err = handler.DataStore.ViewTx(func(tx dataservices.DataStoreTx) error {
+		all, err := tx.VariableSet().ReadAll()
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve the variable sets from the database", err)
+		}
+
+		variableSets = make([]portainer.VariableSet, 0, len(all))
+		for _, variableSet := range all {
+			access, err := security.AuthorizedVariableSetAccess(tx, &variableSet, securityContext)
+			if err != nil {
+				return httperror.InternalServerError("Unable to verify user authorizations to access the variable set", err)
+			}
+
+			if !access {
+				continue
+			}
+
+			variableSet.Variables = stackutils.MaskVariables(variableSet.Variables)
+			variableSets = append(variableSets, variableSet)
+		}
+
+		return nil
+	})
+
+	return txResponse(w, variableSets, err)
+}
@@ -0,0 +1,94 @@
+package variablesets
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariableSetOperations(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	require.NoError(t, store.Team().Create(&portainer.Team{ID: 1, Name: "dev"}))
+	require.NoError(t, store.Team().Create(&portainer.Team{ID: 2, Name: "ops"}))
+	require.NoError(t, store.Stack().Create(&portainer.Stack{ID: 1, Name: "app", VariableSetIDs: []portainer.VariableSetID{1}}))
+
+	h := NewHandler(testhelpers.NewTestRequestBouncer())
+	h.DataStore = store
+
+	admin := &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}
+	member := &security.RestrictedRequestContext{UserID: 2, UserMemberships: []portainer.TeamMembership{{UserID: 2, TeamID: 1}}}
+
+	do := func(method, path string, body any, context *security.RestrictedRequestContext, result any) int {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(method, path, bytes.NewReader(data))
+		r = r.WithContext(security.StoreRestrictedRequestContext(r, context))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		if result != nil && rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(result))
+		}
+
+		return rec.Code
+	}
+
+	var variableSet portainer.VariableSet
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/variable_sets", variableSetCreatePayload{
+		Name:      "dev",
+		ScopeType: portainer.TeamVariableSetScope,
+		ScopeID:   1,
+		Variables: []portainer.StackVariable{{Name: "DB_HOST", Value: "db.internal"}, {Name: "DB_PASSWORD", Value: "s3cr3t", Secret: true}},
+	}, admin, &variableSet))
+	require.Equal(t, stackutils.MaskedVariableValue, variableSet.Variables[1].Value)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/variable_sets", variableSetCreatePayload{
+		Name:      "ops",
+		ScopeType: portainer.TeamVariableSetScope,
+		ScopeID:   2,
+	}, admin, nil))
+
+	require.Equal(t, http.StatusConflict, do(http.MethodPost, "/variable_sets", variableSetCreatePayload{Name: "dev", ScopeType: portainer.TeamVariableSetScope, ScopeID: 1}, admin, nil))
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/variable_sets", variableSetCreatePayload{Name: "missing", ScopeType: portainer.TeamVariableSetScope, ScopeID: 3}, admin, nil))
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/variable_sets", variableSetCreatePayload{
+		Name:      "invalid",
+		ScopeType: portainer.TeamVariableSetScope,
+		ScopeID:   1,
+		Variables: []portainer.StackVariable{{Name: "1NVALID"}},
+	}, admin, nil))
+
+	// the members of a team only see the variable sets of their teams
+	var variableSets []portainer.VariableSet
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/variable_sets", nil, member, &variableSets))
+	require.Len(t, variableSets, 1)
+	require.Equal(t, "dev", variableSets[0].Name)
+	require.Equal(t, stackutils.MaskedVariableValue, variableSets[0].Variables[1].Value)
+
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/variable_sets/2", nil, member, nil))
+
+	// the masked values sent back keep the secrets
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/variable_sets/1", variableSetUpdatePayload{
+		Variables: []portainer.StackVariable{{Name: "DB_HOST", Value: "db.prod"}, {Name: "DB_PASSWORD", Value: stackutils.MaskedVariableValue, Secret: true}},
+	}, admin, nil))
+
+	stored, err := store.VariableSet().Read(1)
+	require.NoError(t, err)
+	require.Equal(t, []portainer.StackVariable{{Name: "DB_HOST", Value: "db.prod"}, {Name: "DB_PASSWORD", Value: "s3cr3t", Secret: true}}, stored.Variables)
+
+	require.Equal(t, http.StatusConflict, do(http.MethodDelete, "/variable_sets/1", nil, admin, nil))
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/variable_sets/2", nil, admin, nil))
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/variable_sets/2", nil, admin, nil))
+}
@@ -0,0 +1,83 @@
+package variablesets
+
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	httperror "github.com/portainer/portainer/pkg/libhttp/error"
+	"github.com/portainer/portainer/pkg/libhttp/request"
+)
+
+type variableSetUpdatePayload struct {
+	// Variable set name
+	Name *string `example:"production"`
+	// Variables of the set, replacing the current ones. The secret variables sent with the masked value keep their
+	// current value
+	Variables []portainer.StackVariable
+}
+
+func (payload *variableSetUpdatePayload) Validate(r *http.Request) error {
+	return validateVariables(payload.Variables)
+}
+
+// @id VariableSetUpdate
+// @summary Update a variable set
+// @description Update the name or the variables of a variable set, the stacks inherit the new values at their next deployment.
+// @description **Access policy**: administrator
+// @tags variable_sets
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Variable set identifier"
+// @param body body variableSetUpdatePayload true "Variable set details"
+// @success 200 {object} portainer.VariableSet "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Variable set not found"
+// @failure 409 "This name is already associated to a variable set of the same scope"
+// @failure 500 "Server error"
+// @router /variable_sets/{id} [put]
+func (handler *Handler) variableSetUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	id, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid variable set identifier route variable", err)
+	}
+
+	var payload variableSetUpdatePayload
+	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	var variableSet *portainer.VariableSet
+	err = handler.DataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		variableSet, err = readVariableSet(tx, portainer.VariableSetID(id))
+		if err != nil {
+			return err
+		}
+
+		if payload.Name != nil && *payload.Name != "" {
+			variableSet.Name = *payload.Name
+
+			if err := checkUniqueName(tx, variableSet); err != nil {
+				return err
+			}
+		}
+
+		if payload.Variables != nil {
+			variableSet.Variables = stackutils.RestoreMaskedVariables(variableSet.Variables, payload.Variables)
+		}
+
+		if err := tx.VariableSet().Update(variableSet.ID, variableSet); err != nil {
+			return httperror.InternalServerError("Unable to persist the variable set changes inside the database", err)
+		}
+
+		return nil
+	})
+	if err == nil {
+		variableSet.Variables = stackutils.MaskVariables(variableSet.Variables)
+	}
+
+	return txResponse(w, variableSet, err)
+}
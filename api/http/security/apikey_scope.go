@@ -0,0 +1,127 @@
+package security
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrAPIKeyExpired = errors.New("API key has expired")
+	ErrAPIKeyScope   = errors.New("the API key scope does not allow this request")
+)
+
+// apiKeyRouteGroups maps the route groups an API key can be restricted to with their path prefixes
+var apiKeyRouteGroups = map[portainer.APIKeyRouteGroup][]string{
+	portainer.APIKeyRouteGroupEnvironments: {"/endpoints", "/endpoint_groups", "/docker", "/kubernetes", "/websocket"},
+	portainer.APIKeyRouteGroupStacks:       {"/stacks", "/gitops"},
+	portainer.APIKeyRouteGroupWebhooks:     {"/webhooks"},
+	portainer.APIKeyRouteGroupEdge:         {"/edge_groups", "/edge_stacks", "/edge_jobs"},
+	portainer.APIKeyRouteGroupRegistries:   {"/registries"},
+	portainer.APIKeyRouteGroupTemplates:    {"/templates", "/custom_templates"},
+	portainer.APIKeyRouteGroupUsers:        {"/users", "/teams", "/team_memberships"},
+}
+
+// endpointPathRegex matches the routes that target a single environment
+var endpointPathRegex = regexp.MustCompile(`^/(?:endpoints|docker|kubernetes)/(\d+)(?:/|$)`)
+
+// ValidateAPIKeyScope checks the scope requested for a new API key
+func ValidateAPIKeyScope(scope *portainer.APIKeyScope) error {
+	if scope == nil {
+		return nil
+	}
+
+	for _, group := range scope.RouteGroups {
+		if _, ok := apiKeyRouteGroups[group]; !ok {
+			return fmt.Errorf("invalid route group %q", group)
+		}
+	}
+
+	return nil
+}
+
+// APIKeyScopeAllowsEndpoint returns false when the environments of scope are restricted and do not include endpointID
+func APIKeyScopeAllowsEndpoint(scope *portainer.APIKeyScope, endpointID portainer.EndpointID) bool {
+	return scope == nil || len(scope.EndpointIDs) == 0 || slices.Contains(scope.EndpointIDs, endpointID)
+}
+
+// checkAPIKeyScope verifies that the request is allowed by the scope of the API key
+func checkAPIKeyScope(r *http.Request, scope *portainer.APIKeyScope) error {
+	if scope == nil {
+		return nil
+	}
+
+	path := apiPath(r)
+
+	if scope.ReadOnly && !isReadOnlyRequest(r, path) {
+		return ErrAPIKeyScope
+	}
+
+	if len(scope.RouteGroups) > 0 && !slices.ContainsFunc(scope.RouteGroups, func(group portainer.APIKeyRouteGroup) bool {
+		return slices.ContainsFunc(apiKeyRouteGroups[group], func(prefix string) bool {
+			return path == prefix || strings.HasPrefix(path, prefix+"/")
+		})
+	}) {
+		return ErrAPIKeyScope
+	}
+
+	if endpointID, ok := requestEndpointID(r, path); ok && !APIKeyScopeAllowsEndpoint(scope, endpointID) {
+		return ErrAPIKeyScope
+	}
+
+	return nil
+}
+
+// isReadOnlyRequest returns true when the request cannot change anything. The websocket upgrades are GET requests
+// but they open shells and attach to containers, they are never read-only.
+func isReadOnlyRequest(r *http.Request, path string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		return false
+	}
+
+	if path == "/websocket" || strings.HasPrefix(path, "/websocket/") {
+		return false
+	}
+
+	return !strings.Contains(strings.ToLower(r.Header.Get("Upgrade")), "websocket")
+}
+
+// apiPath returns the path of the request relative to the API root. The original request URI is used
+// since the handlers strip their prefix before the request reaches the bouncer.
+func apiPath(r *http.Request) string {
+	path := r.URL.Path
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		path = u.Path
+	}
+
+	return strings.TrimPrefix(path, "/api")
+}
+
+// requestEndpointID returns the environment targeted by the request, from its path or its query
+func requestEndpointID(r *http.Request, path string) (portainer.EndpointID, bool) {
+	value := ""
+	if matches := endpointPathRegex.FindStringSubmatch(path); matches != nil {
+		value = matches[1]
+	} else if v := r.URL.Query().Get("endpointId"); v != "" {
+		value = v
+	}
+
+	if value == "" {
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return portainer.EndpointID(id), true
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
 )
 
 // IsAdmin returns true if the logged-in user is an admin
@@ -135,6 +136,51 @@ func authorizedEndpointGroupAccess(endpointGroup *portainer.EndpointGroup, userI
 	return AuthorizedAccess(userID, memberships, endpointGroup.UserAccessPolicies, endpointGroup.TeamAccessPolicies)
 }
 
+// AuthorizedVariableSetAccess ensure that the user can use the specified variable set. The team variable sets are
+// restricted to the members of the team, the other ones to the users who can access the environment(endpoint) group
+// or the environment(endpoint) the variable set is scoped to.
+func AuthorizedVariableSetAccess(tx dataservices.DataStoreTx, variableSet *portainer.VariableSet, context *RestrictedRequestContext) (bool, error) {
+	if context.IsAdmin {
+		return true, nil
+	}
+
+	switch variableSet.ScopeType {
+	case portainer.TeamVariableSetScope:
+		for _, membership := range context.UserMemberships {
+			if int(membership.TeamID) == variableSet.ScopeID {
+				return true, nil
+			}
+		}
+
+	case portainer.EndpointGroupVariableSetScope:
+		endpointGroup, err := tx.EndpointGroup().Read(portainer.EndpointGroupID(variableSet.ScopeID))
+		if tx.IsErrObjectNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		return authorizedEndpointGroupAccess(endpointGroup, context.UserID, context.UserMemberships), nil
+
+	case portainer.EndpointVariableSetScope:
+		endpoint, err := tx.Endpoint().Endpoint(portainer.EndpointID(variableSet.ScopeID))
+		if tx.IsErrObjectNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		endpointGroup, err := tx.EndpointGroup().Read(endpoint.GroupID)
+		if err != nil {
+			return false, err
+		}
+
+		return AuthorizedEndpointAccess(endpoint, endpointGroup, context.UserID, context.UserMemberships), nil
+	}
+
+	return false, nil
+}
+
 // AuthorizedRegistryAccess ensure that the user can access the specified registry.
 // It will check if the user is part of the authorized users or part of a team that is
 // listed in the authorized teams for a specified environment(endpoint),
@@ -2,6 +2,7 @@ package security
 
 import (
 	"net/http"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -35,6 +36,9 @@ type (
 		JWTAuthLookup(*http.Request) (*portainer.TokenData, error)
 		TrustedEdgeEnvironmentAccess(dataservices.DataStoreTx, *portainer.Endpoint) error
 		RevokeJWT(string)
+		UserSessions(portainer.UserID) []portainer.UserSession
+		RevokeUserSession(portainer.UserID, portainer.UserSessionID) error
+		RevokeAllSessions(exceptToken string) error
 		DisableCSP()
 	}
 
@@ -44,8 +48,12 @@ type (
 		jwtService    portainer.JWTService
 		apiKeyService apikey.APIKeyService
 		revokedJWT    sync.Map
+		sessionsMu    sync.Mutex
+		sessions      map[string]*portainer.UserSession
 		hsts          bool
 		csp           bool
+		// optional, the API requests cannot be authenticated with client certificates when nil
+		clientCertificateService portainer.ClientCertificateService
 	}
 
 	// RestrictedRequestContext is a data structure containing information
@@ -55,6 +63,8 @@ type (
 		IsTeamLeader    bool
 		UserID          portainer.UserID
 		UserMemberships []portainer.TeamMembership
+		// APIKeyScope is set when the request is authenticated with a scoped API key
+		APIKeyScope *portainer.APIKeyScope
 	}
 
 	// tokenLookup looks up a token in the request
@@ -62,10 +72,13 @@ type (
 )
 
 var (
-	ErrInvalidKey = errors.New("Invalid API key")
-	ErrRevokedJWT = errors.New("the JWT has been revoked")
+	ErrInvalidKey      = errors.New("Invalid API key")
+	ErrRevokedJWT      = errors.New("the JWT has been revoked")
+	ErrPasswordExpired = errors.New("the password has expired")
 )
 
+var passwordChangePathRegex = regexp.MustCompile(`^/users/\d+/passwd$`)
+
 // NewRequestBouncer initializes a new RequestBouncer
 func NewRequestBouncer(dataStore dataservices.DataStore, jwtService portainer.JWTService, apiKeyService apikey.APIKeyService) *RequestBouncer {
 	b := &RequestBouncer{
@@ -74,6 +87,11 @@ func NewRequestBouncer(dataStore dataservices.DataStore, jwtService portainer.JW
 		apiKeyService: apiKeyService,
 		hsts:          featureflags.IsEnabled("hsts"),
 		csp:           true,
+		sessions:      make(map[string]*portainer.UserSession),
+	}
+
+	if dataStore != nil {
+		b.loadSessions()
 	}
 
 	go b.cleanUpExpiredJWT()
@@ -155,6 +173,10 @@ func (bouncer *RequestBouncer) AuthorizedEndpointOperation(r *http.Request, endp
 		return err
 	}
 
+	if !APIKeyScopeAllowsEndpoint(tokenData.APIKeyScope, endpoint.ID) {
+		return httperrors.ErrEndpointAccessDenied
+	}
+
 	if tokenData.Role == portainer.AdministratorRole {
 		return nil
 	}
@@ -221,6 +243,7 @@ func (bouncer *RequestBouncer) mwAuthenticatedUser(h http.Handler) http.Handler
 		bouncer.apiKeyLookup,
 		bouncer.CookieAuthLookup,
 		bouncer.JWTAuthLookup,
+		bouncer.clientCertificateLookup,
 	}, h)
 	h = MWSecureHeaders(h, bouncer.hsts, bouncer.csp)
 
@@ -277,6 +300,8 @@ func (bouncer *RequestBouncer) mwUpgradeToRestrictedRequest(next http.Handler) h
 			return
 		}
 
+		requestContext.APIKeyScope = tokenData.APIKeyScope
+
 		ctx := StoreRestrictedRequestContext(r, requestContext)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -308,7 +333,15 @@ func (bouncer *RequestBouncer) mwAuthenticateFirst(tokenLookups []tokenLookup, n
 
 		for _, lookup := range tokenLookups {
 			resultToken, err := lookup(r)
-			if err != nil {
+			if errors.Is(err, ErrAPIKeyScope) {
+				httperror.WriteError(w, http.StatusForbidden, "Access denied by the API key scope", err)
+
+				return
+			} else if errors.Is(err, ErrAPIKeyExpired) {
+				httperror.WriteError(w, http.StatusUnauthorized, "The API key has expired", err)
+
+				return
+			} else if err != nil {
 				httperror.WriteError(w, http.StatusUnauthorized, "Invalid JWT token", httperrors.ErrUnauthorized)
 
 				return
@@ -327,12 +360,25 @@ func (bouncer *RequestBouncer) mwAuthenticateFirst(tokenLookups []tokenLookup, n
 			return
 		}
 
-		if ok, _ := bouncer.dataStore.User().Exists(token.ID); !ok {
+		user, err := bouncer.dataStore.User().Read(token.ID)
+		if err != nil || user.Disabled {
 			httperror.WriteError(w, http.StatusUnauthorized, "The authorization token is invalid", httperrors.ErrUnauthorized)
 
 			return
 		}
 
+		if !passwordChangeRoute(r) {
+			if expired, err := bouncer.passwordExpired(user); err != nil {
+				httperror.WriteError(w, http.StatusInternalServerError, "Unable to retrieve the settings from the database", err)
+
+				return
+			} else if expired {
+				httperror.WriteError(w, http.StatusForbidden, "The password has expired and must be changed", ErrPasswordExpired)
+
+				return
+			}
+		}
+
 		ctx := StoreTokenData(r, token)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -346,7 +392,7 @@ func (bouncer *RequestBouncer) CookieAuthLookup(r *http.Request) (*portainer.Tok
 		return nil, nil
 	}
 
-	tokenData, jti, _, err := bouncer.jwtService.ParseAndVerifyToken(token)
+	tokenData, jti, exp, err := bouncer.jwtService.ParseAndVerifyToken(token)
 	if err != nil {
 		return nil, err
 	}
@@ -355,9 +401,48 @@ func (bouncer *RequestBouncer) CookieAuthLookup(r *http.Request) (*portainer.Tok
 		return nil, ErrRevokedJWT
 	}
 
+	bouncer.trackSession(r, tokenData, jti, exp)
+
 	return tokenData, nil
 }
 
+// passwordExpired returns true when user logs in with an internal password older than the password policy allows
+func (bouncer *RequestBouncer) passwordExpired(user *portainer.User) (bool, error) {
+	// the age of the password is only known once the policy applies to it
+	if user.PasswordChangedAt == 0 {
+		return false, nil
+	}
+
+	settings, err := bouncer.dataStore.Settings().Settings()
+	if err != nil {
+		return false, err
+	}
+
+	// the initial administrator always logs in with its internal password
+	if settings.AuthenticationMethod != portainer.AuthenticationInternal && user.ID != 1 {
+		return false, nil
+	}
+
+	return PasswordExpired(user, settings.InternalAuthSettings.PasswordPolicy.MaxAgeDays, time.Now()), nil
+}
+
+// passwordChangeRoute returns true for the routes that stay available until an expired password is changed, the
+// logout route is public
+func passwordChangeRoute(r *http.Request) bool {
+	path := apiPath(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		return path == "/users/me"
+	case http.MethodPost:
+		return path == "/users/password/check"
+	case http.MethodPut:
+		return passwordChangePathRegex.MatchString(path)
+	}
+
+	return false
+}
+
 // JWTAuthLookup looks up a valid bearer in the request.
 func (bouncer *RequestBouncer) JWTAuthLookup(r *http.Request) (*portainer.TokenData, error) {
 	// get token from the Authorization header or query parameter
@@ -366,7 +451,7 @@ func (bouncer *RequestBouncer) JWTAuthLookup(r *http.Request) (*portainer.TokenD
 		return nil, nil
 	}
 
-	tokenData, jti, _, err := bouncer.jwtService.ParseAndVerifyToken(token)
+	tokenData, jti, exp, err := bouncer.jwtService.ParseAndVerifyToken(token)
 	if err != nil {
 		return nil, err
 	}
@@ -375,16 +460,19 @@ func (bouncer *RequestBouncer) JWTAuthLookup(r *http.Request) (*portainer.TokenD
 		return nil, ErrRevokedJWT
 	}
 
+	bouncer.trackSession(r, tokenData, jti, exp)
+
 	return tokenData, nil
 }
 
 func (bouncer *RequestBouncer) RevokeJWT(token string) {
-	_, jti, exp, err := bouncer.jwtService.ParseAndVerifyToken(token)
+	tokenData, jti, exp, err := bouncer.jwtService.ParseAndVerifyToken(token)
 	if err != nil {
 		return
 	}
 
 	bouncer.revokedJWT.Store(jti, exp)
+	bouncer.persistRevocation(tokenData, jti, exp)
 }
 
 func (bouncer *RequestBouncer) cleanUpExpiredJWTPass() {
@@ -404,6 +492,7 @@ func (bouncer *RequestBouncer) cleanUpExpiredJWT() {
 
 	for range ticker.C {
 		bouncer.cleanUpExpiredJWTPass()
+		bouncer.cleanUpExpiredSessions()
 	}
 }
 
@@ -411,6 +500,7 @@ func (bouncer *RequestBouncer) cleanUpExpiredJWT() {
 // - computing the digest of the raw api-key
 // - verifying it exists in cache/database
 // - matching the key to a user (ID, Role)
+// - rejecting expired keys and requests outside of the key scope
 // If the key is valid/verified, the last updated time of the key is updated.
 // Successful verification of the key will return a TokenData object - since the downstream handlers
 // utilise the token injected in the request context.
@@ -427,10 +517,19 @@ func (bouncer *RequestBouncer) apiKeyLookup(r *http.Request) (*portainer.TokenDa
 		return nil, ErrInvalidKey
 	}
 
+	if apiKey.ExpiresAt > 0 && time.Now().Unix() >= apiKey.ExpiresAt {
+		return nil, ErrAPIKeyExpired
+	}
+
+	if err := checkAPIKeyScope(r, apiKey.Scope); err != nil {
+		return nil, err
+	}
+
 	tokenData := &portainer.TokenData{
-		ID:       user.ID,
-		Username: user.Username,
-		Role:     user.Role,
+		ID:          user.ID,
+		Username:    user.Username,
+		Role:        user.Role,
+		APIKeyScope: apiKey.Scope,
 	}
 	if _, _, err := bouncer.jwtService.GenerateToken(tokenData); err != nil {
 		log.Debug().Err(err).Msg("Failed to generate token")
@@ -116,6 +116,33 @@ func Test_mwAuthenticateFirst(t *testing.T) {
 	}
 }
 
+func Test_mwAuthenticateFirst_disabledUser(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	bouncer := NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(nil, nil))
+
+	user := &portainer.User{ID: 2, Username: "bob", Disabled: true}
+	require.NoError(t, store.User().Create(user))
+
+	lookup := func(r *http.Request) (*portainer.TokenData, error) {
+		return &portainer.TokenData{ID: user.ID, Username: user.Username}, nil
+	}
+
+	rr := httptest.NewRecorder()
+	bouncer.mwAuthenticateFirst([]tokenLookup{lookup}, testHandler200).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	user.Disabled = false
+	require.NoError(t, store.User().Update(user.ID, user))
+
+	rr = httptest.NewRecorder()
+	bouncer.mwAuthenticateFirst([]tokenLookup{lookup}, testHandler200).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
 func Test_extractKeyFromCookie(t *testing.T) {
 	is := assert.New(t)
 
@@ -384,6 +411,105 @@ func Test_apiKeyLookup(t *testing.T) {
 
 		is.True(apiKeyUpdated.LastUsed > apiKey.LastUsed)
 	})
+
+	t.Run("expired api-key fails api-key lookup", func(t *testing.T) {
+		rawAPIKey, apiKey, err := apiKeyService.GenerateScopedApiKey(*user, "test", time.Now().Add(-time.Minute).Unix(), nil)
+		is.NoError(err)
+		defer apiKeyService.DeleteAPIKey(apiKey.ID)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Add("x-api-key", rawAPIKey)
+
+		token, err := bouncer.apiKeyLookup(req)
+		is.Nil(token)
+		is.ErrorIs(err, ErrAPIKeyExpired)
+	})
+
+	t.Run("scoped api-key is limited to its scope", func(t *testing.T) {
+		scope := &portainer.APIKeyScope{
+			ReadOnly:    true,
+			EndpointIDs: []portainer.EndpointID{1},
+			RouteGroups: []portainer.APIKeyRouteGroup{portainer.APIKeyRouteGroupStacks, portainer.APIKeyRouteGroupEnvironments},
+		}
+
+		rawAPIKey, apiKey, err := apiKeyService.GenerateScopedApiKey(*user, "test", time.Now().Add(time.Hour).Unix(), scope)
+		is.NoError(err)
+		defer apiKeyService.DeleteAPIKey(apiKey.ID)
+
+		for _, test := range []struct {
+			method  string
+			target  string
+			upgrade string
+			allowed bool
+		}{
+			{http.MethodGet, "/api/stacks", "", true},
+			{http.MethodGet, "/api/stacks?endpointId=1", "", true},
+			{http.MethodGet, "/api/endpoints/1/docker/containers/json", "", true},
+			{http.MethodPost, "/api/stacks/create/standalone/string?endpointId=1", "", false},
+			{http.MethodGet, "/api/stacks?endpointId=2", "", false},
+			{http.MethodGet, "/api/endpoints/2/docker/containers/json", "", false},
+			{http.MethodGet, "/api/docker/2/dashboard", "", false},
+			{http.MethodGet, "/api/webhooks", "", false},
+			{http.MethodGet, "/api/stacksx", "", false},
+			// the websocket upgrades are GET requests that run commands
+			{http.MethodGet, "/api/websocket/exec?endpointId=1&id=container", "websocket", false},
+			{http.MethodGet, "/api/websocket/attach?endpointId=1&id=container", "websocket", false},
+			{http.MethodGet, "/api/websocket/pod?endpointId=1&namespace=default&podName=pod&containerName=container&command=sh", "websocket", false},
+			{http.MethodGet, "/api/websocket/kubernetes-shell?endpointId=1", "websocket", false},
+			{http.MethodGet, "/api/websocket/exec?endpointId=1&id=container", "", false},
+			{http.MethodGet, "/api/endpoints/1/docker/containers/json", "WebSocket", false},
+		} {
+			req := httptest.NewRequest(test.method, test.target, nil)
+			req.Header.Add("x-api-key", rawAPIKey)
+			if test.upgrade != "" {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", test.upgrade)
+			}
+
+			token, err := bouncer.apiKeyLookup(req)
+			if !test.allowed {
+				is.ErrorIs(err, ErrAPIKeyScope, "%s %s", test.method, test.target)
+
+				continue
+			}
+
+			require.NoError(t, err, "%s %s", test.method, test.target)
+			is.Equal(scope, token.APIKeyScope)
+		}
+	})
+
+	t.Run("the websocket routes are available to the api-keys that are not read-only", func(t *testing.T) {
+		rawAPIKey, apiKey, err := apiKeyService.GenerateScopedApiKey(*user, "test", 0, &portainer.APIKeyScope{EndpointIDs: []portainer.EndpointID{1}})
+		is.NoError(err)
+		defer apiKeyService.DeleteAPIKey(apiKey.ID)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/websocket/exec?endpointId=1&id=container", nil)
+		req.Header.Add("x-api-key", rawAPIKey)
+		req.Header.Set("Upgrade", "websocket")
+
+		_, err = bouncer.apiKeyLookup(req)
+		require.NoError(t, err)
+	})
+}
+
+func TestAuthorizedEndpointOperationWithAPIKeyScope(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	bouncer := NewRequestBouncer(store, jwtService, nil)
+
+	tokenData := &portainer.TokenData{ID: 1, Role: portainer.AdministratorRole, APIKeyScope: &portainer.APIKeyScope{EndpointIDs: []portainer.EndpointID{1}}}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(StoreTokenData(req, tokenData))
+
+	require.NoError(t, bouncer.AuthorizedEndpointOperation(req, &portainer.Endpoint{ID: 1}))
+	require.Error(t, bouncer.AuthorizedEndpointOperation(req, &portainer.Endpoint{ID: 2}))
+
+	endpoints := FilterEndpoints([]portainer.Endpoint{{ID: 1}, {ID: 2}}, nil, &RestrictedRequestContext{IsAdmin: true, APIKeyScope: tokenData.APIKeyScope})
+	require.Len(t, endpoints, 1)
+	require.Equal(t, portainer.EndpointID(1), endpoints[0].ID)
 }
 
 func Test_ShouldSkipCSRFCheck(t *testing.T) {
@@ -561,3 +687,57 @@ func TestCSPHeaderDisabled(t *testing.T) {
 
 	require.NotContains(t, resp.Header, "Content-Security-Policy")
 }
+
+func TestExpiredPasswordRestrictsAccess(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+	settings.AuthenticationMethod = portainer.AuthenticationInternal
+	settings.InternalAuthSettings.PasswordPolicy.MaxAgeDays = 30
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	// the initial administrator always logs in with its internal password
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+
+	user := &portainer.User{Username: "bob", Role: portainer.StandardUserRole, PasswordChangedAt: time.Now().AddDate(0, 0, -31).Unix()}
+	require.NoError(t, store.User().Create(user))
+	require.Equal(t, portainer.UserID(2), user.ID)
+
+	token, _, err := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+	require.NoError(t, err)
+
+	bouncer := NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(nil, nil))
+	handler := bouncer.AuthenticatedAccess(testHandler200)
+
+	do := func(method, target string) int {
+		r := httptest.NewRequest(method, target, nil)
+		r.Header.Add(jwtTokenHeader, "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/stacks"))
+	require.Equal(t, http.StatusForbidden, do(http.MethodPut, "/api/users/2"))
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/users/me"))
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/api/users/password/check"))
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/api/users/2/passwd"))
+
+	// the access is restored once the password is changed
+	user.PasswordChangedAt = time.Now().Unix()
+	require.NoError(t, store.User().Update(user.ID, user))
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/stacks"))
+
+	// the policy only applies to the internal authentication
+	user.PasswordChangedAt = time.Now().AddDate(0, 0, -31).Unix()
+	require.NoError(t, store.User().Update(user.ID, user))
+	settings.AuthenticationMethod = portainer.AuthenticationLDAP
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/stacks"))
+}
@@ -0,0 +1,239 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"regexp"
+	"sync"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+
+	"github.com/pkg/errors"
+)
+
+// ClientCertificateAuthHeader must be set to "true" for an API request to be authenticated with the client certificate.
+// Browsers send the client certificates along with any request but cannot add this header to cross-site requests, so
+// it protects the API from cross-site request forgery.
+const ClientCertificateAuthHeader = "X-Client-Certificate-Auth"
+
+var (
+	ErrClientCertificateDisabled = errors.New("client certificate authentication is not enabled")
+	ErrNoClientCertificate       = errors.New("no client certificate")
+	ErrInvalidClientCertificate  = errors.New("invalid client certificate")
+)
+
+// ClientCertificateService authenticates users with the TLS client certificate of the requests, the certificate must
+// be issued by one of the configured authorities
+type ClientCertificateService struct {
+	mu              sync.RWMutex
+	settings        portainer.ClientCertificateSettings
+	pool            *x509.CertPool
+	usernamePattern *regexp.Regexp
+}
+
+// NewClientCertificateService returns a new ClientCertificateService configured with the settings of the datastore
+func NewClientCertificateService(dataStore dataservices.DataStore) (*ClientCertificateService, error) {
+	settings, err := dataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	service := &ClientCertificateService{}
+
+	if err := service.SetSettings(&settings.ClientCertificateSettings); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+// ValidateClientCertificateSettings returns an error when the certificate authorities, the username source or the
+// username pattern are invalid
+func ValidateClientCertificateSettings(settings *portainer.ClientCertificateSettings) error {
+	if _, err := CompileAutoCreateUsernamePattern(settings); err != nil {
+		return err
+	}
+
+	_, err := parseClientCertificateSettings(settings)
+
+	return err
+}
+
+// CompileAutoCreateUsernamePattern compiles the pattern the usernames must match to be created on their first login.
+// The pattern must match the whole username and is required when the users are created automatically, it returns nil
+// when they are not.
+func CompileAutoCreateUsernamePattern(settings *portainer.ClientCertificateSettings) (*regexp.Regexp, error) {
+	if settings.AutoCreateUsernamePattern == "" {
+		if settings.AutoCreateUsers {
+			return nil, errors.New("the username pattern is required to create the users automatically")
+		}
+
+		return nil, nil
+	}
+
+	pattern, err := regexp.Compile("^(?:" + settings.AutoCreateUsernamePattern + ")$")
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid username pattern")
+	}
+
+	if !settings.AutoCreateUsers {
+		return nil, nil
+	}
+
+	return pattern, nil
+}
+
+func parseClientCertificateSettings(settings *portainer.ClientCertificateSettings) (*x509.CertPool, error) {
+	switch settings.UsernameSource {
+	case "", portainer.ClientCertificateUsernameCommonName, portainer.ClientCertificateUsernameEmail, portainer.ClientCertificateUsernameDNSName:
+	default:
+		return nil, errors.Errorf("invalid username source %q", settings.UsernameSource)
+	}
+
+	if settings.CACertificates == "" {
+		if settings.Enabled {
+			return nil, errors.New("the certificate authorities are required")
+		}
+
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(settings.CACertificates)) {
+		return nil, errors.New("invalid certificate authorities, a PEM encoded certificate is expected")
+	}
+
+	return pool, nil
+}
+
+// SetSettings replaces the settings, the certificate authorities are used from the next TLS handshakes
+func (service *ClientCertificateService) SetSettings(settings *portainer.ClientCertificateSettings) error {
+	usernamePattern, err := CompileAutoCreateUsernamePattern(settings)
+	if err != nil {
+		return err
+	}
+
+	pool, err := parseClientCertificateSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.settings = *settings
+	service.pool = pool
+	service.usernamePattern = usernamePattern
+
+	return nil
+}
+
+// CanCreateUser returns true when the users are created automatically and the whole username matches their pattern
+func (service *ClientCertificateService) CanCreateUser(username string) bool {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	return service.usernamePattern != nil && service.usernamePattern.MatchString(username)
+}
+
+// GetConfigForClient returns a function to use as tls.Config.GetConfigForClient. Client certificates are only requested
+// while the authentication is enabled, they are verified when the requests are authenticated so that a browser
+// sending an unrelated certificate can still use the other authentication methods.
+func (service *ClientCertificateService) GetConfigForClient(base *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		service.mu.RLock()
+		defer service.mu.RUnlock()
+
+		if !service.settings.Enabled || service.pool == nil {
+			return nil, nil
+		}
+
+		config := base.Clone()
+		config.GetConfigForClient = nil
+		config.ClientAuth = tls.RequestClientCert
+		config.ClientCAs = service.pool
+
+		return config, nil
+	}
+}
+
+// Username verifies the client certificate of the request and returns the username it holds
+func (service *ClientCertificateService) Username(r *http.Request) (string, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	if !service.settings.Enabled || service.pool == nil {
+		return "", ErrClientCertificateDisabled
+	}
+
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return "", ErrNoClientCertificate
+	}
+
+	leaf := r.TLS.PeerCertificates[0]
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         service.pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return "", errors.Wrap(ErrInvalidClientCertificate, err.Error())
+	}
+
+	var username string
+
+	switch service.settings.UsernameSource {
+	case portainer.ClientCertificateUsernameEmail:
+		if len(leaf.EmailAddresses) > 0 {
+			username = leaf.EmailAddresses[0]
+		}
+	case portainer.ClientCertificateUsernameDNSName:
+		if len(leaf.DNSNames) > 0 {
+			username = leaf.DNSNames[0]
+		}
+	default:
+		username = leaf.Subject.CommonName
+	}
+
+	if username == "" {
+		return "", errors.Wrap(ErrInvalidClientCertificate, "the certificate does not hold a username")
+	}
+
+	return username, nil
+}
+
+// clientCertificateLookup authenticates the requests carrying the ClientCertificateAuthHeader as the user mapped to
+// their client certificate, the user must exist
+func (bouncer *RequestBouncer) clientCertificateLookup(r *http.Request) (*portainer.TokenData, error) {
+	if bouncer.clientCertificateService == nil || r.Header.Get(ClientCertificateAuthHeader) != "true" {
+		return nil, nil
+	}
+
+	username, err := bouncer.clientCertificateService.Username(r)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := bouncer.dataStore.User().UserByUsername(username)
+	if err != nil {
+		return nil, ErrInvalidClientCertificate
+	}
+
+	return &portainer.TokenData{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}, nil
+}
+
+// SetClientCertificateService enables the authentication of the API requests with client certificates
+func (bouncer *RequestBouncer) SetClientCertificateService(service portainer.ClientCertificateService) {
+	bouncer.clientCertificateService = service
+}
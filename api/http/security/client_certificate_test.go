@@ -0,0 +1,254 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         string
+}
+
+func newTestAuthority(t *testing.T) *testAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test authority"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testAuthority{
+		certificate: certificate,
+		key:         key,
+		pem:         string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+}
+
+func (authority *testAuthority) issue(t *testing.T, commonName string, emails []string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(time.Now().UnixNano()),
+		Subject:        pkix.Name{CommonName: commonName},
+		EmailAddresses: emails,
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{usage},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, authority.certificate, &key.PublicKey, authority.key)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func requestWithCertificate(certificate tls.Certificate) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate.Leaf}}
+
+	return r
+}
+
+func TestClientCertificateUsername(t *testing.T) {
+	authority := newTestAuthority(t)
+
+	service := &ClientCertificateService{}
+	require.NoError(t, service.SetSettings(&portainer.ClientCertificateSettings{Enabled: true, CACertificates: authority.pem}))
+
+	username, err := service.Username(requestWithCertificate(authority.issue(t, "alice", []string{"alice@example.com"}, x509.ExtKeyUsageClientAuth)))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+
+	t.Run("email", func(t *testing.T) {
+		require.NoError(t, service.SetSettings(&portainer.ClientCertificateSettings{
+			Enabled:        true,
+			CACertificates: authority.pem,
+			UsernameSource: portainer.ClientCertificateUsernameEmail,
+		}))
+
+		username, err := service.Username(requestWithCertificate(authority.issue(t, "alice", []string{"alice@example.com"}, x509.ExtKeyUsageClientAuth)))
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", username)
+
+		_, err = service.Username(requestWithCertificate(authority.issue(t, "alice", nil, x509.ExtKeyUsageClientAuth)))
+		require.ErrorIs(t, err, ErrInvalidClientCertificate)
+	})
+
+	t.Run("certificate issued by another authority", func(t *testing.T) {
+		_, err := service.Username(requestWithCertificate(newTestAuthority(t).issue(t, "alice", nil, x509.ExtKeyUsageClientAuth)))
+		require.ErrorIs(t, err, ErrInvalidClientCertificate)
+	})
+
+	t.Run("server certificate", func(t *testing.T) {
+		_, err := service.Username(requestWithCertificate(authority.issue(t, "alice", nil, x509.ExtKeyUsageServerAuth)))
+		require.ErrorIs(t, err, ErrInvalidClientCertificate)
+	})
+
+	t.Run("no certificate", func(t *testing.T) {
+		_, err := service.Username(httptest.NewRequest(http.MethodGet, "/", nil))
+		require.ErrorIs(t, err, ErrNoClientCertificate)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.NoError(t, service.SetSettings(&portainer.ClientCertificateSettings{CACertificates: authority.pem}))
+
+		_, err := service.Username(requestWithCertificate(authority.issue(t, "alice", nil, x509.ExtKeyUsageClientAuth)))
+		require.ErrorIs(t, err, ErrClientCertificateDisabled)
+	})
+}
+
+func TestValidateClientCertificateSettings(t *testing.T) {
+	authority := newTestAuthority(t)
+
+	require.NoError(t, ValidateClientCertificateSettings(&portainer.ClientCertificateSettings{}))
+	require.NoError(t, ValidateClientCertificateSettings(&portainer.ClientCertificateSettings{Enabled: true, CACertificates: authority.pem, AutoCreateUsernamePattern: "@example\\.com$"}))
+	require.NoError(t, ValidateClientCertificateSettings(&portainer.ClientCertificateSettings{Enabled: true, CACertificates: authority.pem, AutoCreateUsers: true, AutoCreateUsernamePattern: ".+@example\\.com"}))
+
+	require.Error(t, ValidateClientCertificateSettings(&portainer.ClientCertificateSettings{Enabled: true}))
+	require.Error(t, ValidateClientCertificateSettings(&portainer.ClientCertificateSettings{Enabled: true, CACertificates: "not a certificate"}))
+	require.Error(t, ValidateClientCertificateSettings(&portainer.ClientCertificateSettings{CACertificates: authority.pem, UsernameSource: "uid"}))
+	require.Error(t, ValidateClientCertificateSettings(&portainer.ClientCertificateSettings{CACertificates: authority.pem, AutoCreateUsernamePattern: "("}))
+	require.Error(t, ValidateClientCertificateSettings(&portainer.ClientCertificateSettings{CACertificates: authority.pem, AutoCreateUsers: true}))
+}
+
+func TestClientCertificateCanCreateUser(t *testing.T) {
+	authority := newTestAuthority(t)
+
+	service := &ClientCertificateService{}
+	require.NoError(t, service.SetSettings(&portainer.ClientCertificateSettings{
+		Enabled:                   true,
+		CACertificates:            authority.pem,
+		AutoCreateUsers:           true,
+		AutoCreateUsernamePattern: `.+@example\.com|admin`,
+	}))
+
+	assert.True(t, service.CanCreateUser("alice@example.com"))
+	assert.True(t, service.CanCreateUser("admin"))
+	assert.False(t, service.CanCreateUser("alice@example.com.evil.org"))
+	assert.False(t, service.CanCreateUser("superadmin"))
+
+	// the pattern is ignored while the users are not created automatically
+	require.NoError(t, service.SetSettings(&portainer.ClientCertificateSettings{
+		Enabled:                   true,
+		CACertificates:            authority.pem,
+		AutoCreateUsernamePattern: `.+@example\.com`,
+	}))
+
+	assert.False(t, service.CanCreateUser("alice@example.com"))
+}
+
+func TestClientCertificateTLSHandshake(t *testing.T) {
+	authority := newTestAuthority(t)
+
+	service := &ClientCertificateService{}
+	require.NoError(t, service.SetSettings(&portainer.ClientCertificateSettings{Enabled: true, CACertificates: authority.pem}))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, err := service.Username(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(username))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{authority.issue(t, "portainer", nil, x509.ExtKeyUsageServerAuth)}}
+	server.TLS.GetConfigForClient = service.GetConfigForClient(server.TLS)
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(certificates ...tls.Certificate) *http.Response {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			Certificates:       certificates,
+			InsecureSkipVerify: true,
+		}}}
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	resp := get(authority.issue(t, "alice", nil, x509.ExtKeyUsageClientAuth))
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// an unrelated certificate does not fail the handshake
+	resp = get(newTestAuthority(t).issue(t, "alice", nil, x509.ExtKeyUsageClientAuth))
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestClientCertificateLookup(t *testing.T) {
+	authority := newTestAuthority(t)
+
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	bouncer := NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(nil, nil))
+
+	service := &ClientCertificateService{}
+	require.NoError(t, service.SetSettings(&portainer.ClientCertificateSettings{Enabled: true, CACertificates: authority.pem}))
+	bouncer.SetClientCertificateService(service)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "alice", Role: portainer.StandardUserRole}))
+
+	r := requestWithCertificate(authority.issue(t, "alice", nil, x509.ExtKeyUsageClientAuth))
+
+	// the certificate is ignored without the header
+	token, err := bouncer.clientCertificateLookup(r)
+	require.NoError(t, err)
+	require.Nil(t, token)
+
+	r.Header.Set(ClientCertificateAuthHeader, "true")
+
+	token, err = bouncer.clientCertificateLookup(r)
+	require.NoError(t, err)
+	require.Equal(t, "alice", token.Username)
+
+	r = requestWithCertificate(authority.issue(t, "bob", nil, x509.ExtKeyUsageClientAuth))
+	r.Header.Set(ClientCertificateAuthHeader, "true")
+
+	_, err = bouncer.clientCertificateLookup(r)
+	require.ErrorIs(t, err, ErrInvalidClientCertificate)
+}
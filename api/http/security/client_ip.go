@@ -0,0 +1,119 @@
+package security
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// trustedProxies holds the networks of the reverse proxies allowed to set X-Forwarded-For
+var trustedProxies atomic.Pointer[[]netip.Prefix]
+
+// ParseTrustedProxies parses a list of IP addresses and CIDR ranges
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+
+			prefixes = append(prefixes, prefix.Masked())
+
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// SetTrustedProxies sets the reverse proxies whose X-Forwarded-For header is used to find the client IP
+func SetTrustedProxies(proxies []netip.Prefix) {
+	trustedProxies.Store(&proxies)
+}
+
+// RealClientIP returns the IP address of the client of the request. When the request comes from a trusted
+// proxy, the X-Forwarded-For header is walked from the right and the first untrusted address is returned.
+func RealClientIP(r *http.Request) string {
+	remote := remoteIP(r.RemoteAddr)
+
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	forwarded := forwardedFor(r)
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if !isTrustedProxy(forwarded[i]) {
+			return forwarded[i]
+		}
+	}
+
+	// every hop is trusted, the leftmost address is the original client
+	if len(forwarded) > 0 {
+		return forwarded[0]
+	}
+
+	return remote
+}
+
+func forwardedFor(r *http.Request) []string {
+	var addrs []string
+
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(header, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	return addrs
+}
+
+func isTrustedProxy(ip string) bool {
+	proxies := trustedProxies.Load()
+	if proxies == nil || len(*proxies) == 0 {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range *proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+
+	return host
+}
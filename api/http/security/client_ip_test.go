@@ -0,0 +1,46 @@
+package security
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.1", ""})
+	require.NoError(t, err)
+
+	SetTrustedProxies(proxies)
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.5:4000", expected: "203.0.113.5"},
+		{name: "untrusted peer cannot spoof", remoteAddr: "203.0.113.5:4000", forwarded: []string{"1.2.3.4"}, expected: "203.0.113.5"},
+		{name: "trusted proxy", remoteAddr: "10.1.2.3:4000", forwarded: []string{"198.51.100.7"}, expected: "198.51.100.7"},
+		{name: "spoofed entry before the proxy chain", remoteAddr: "10.1.2.3:4000", forwarded: []string{"1.2.3.4, 198.51.100.7, 192.168.1.1"}, expected: "198.51.100.7"},
+		{name: "several headers", remoteAddr: "10.1.2.3:4000", forwarded: []string{"198.51.100.7", "10.0.0.9"}, expected: "198.51.100.7"},
+		{name: "only trusted hops", remoteAddr: "10.1.2.3:4000", forwarded: []string{"10.0.0.8, 10.0.0.9"}, expected: "10.0.0.8"},
+		{name: "trusted proxy without header", remoteAddr: "[::ffff:10.1.2.3]:4000", expected: "::ffff:10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			require.Equal(t, tt.expected, RealClientIP(r))
+		})
+	}
+
+	_, err = ParseTrustedProxies([]string{"not-an-ip"})
+	require.Error(t, err)
+}
@@ -90,13 +90,25 @@ func FilterRegistries(registries []portainer.Registry, user *portainer.User, tea
 
 // FilterEndpoints filters environments(endpoints) based on user role and team memberships.
 // Non administrator only have access to authorized environments(endpoints) (can be inherited via endpoint groups).
+// Requests authenticated with a scoped API key only get the environments of the scope.
 func FilterEndpoints(endpoints []portainer.Endpoint, groups []portainer.EndpointGroup, context *RestrictedRequestContext) []portainer.Endpoint {
-	if context.IsAdmin {
+	if context.IsAdmin && context.APIKeyScope == nil {
 		return endpoints
 	}
 
 	n := 0
 	for _, endpoint := range endpoints {
+		if !APIKeyScopeAllowsEndpoint(context.APIKeyScope, endpoint.ID) {
+			continue
+		}
+
+		if context.IsAdmin {
+			endpoints[n] = endpoint
+			n++
+
+			continue
+		}
+
 		endpointGroup := getAssociatedGroup(&endpoint, groups)
 
 		if AuthorizedEndpointAccess(&endpoint, endpointGroup, context.UserID, context.UserMemberships) {
@@ -0,0 +1,313 @@
+package security
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// loginFreeAttempts is the number of failed logins allowed before the next attempts are delayed
+	loginFreeAttempts = 3
+	// loginMaxFailures is the number of failed logins after which the account is locked
+	loginMaxFailures = 10
+	loginBaseDelay   = time.Second
+	loginMaxDelay    = 30 * time.Second
+	// LoginLockoutDuration is the time an account stays locked after too many failed logins
+	LoginLockoutDuration = 15 * time.Minute
+	// loginFailureWindow is the time after which the failed logins of an account are forgotten
+	loginFailureWindow = time.Hour
+	maxLoginEvents     = 500
+	// maxLoginAccounts bounds the number of tracked usernames, the least recently failed are forgotten first
+	maxLoginAccounts = 10000
+)
+
+var (
+	ErrAccountLocked  = errors.New("the account is temporarily locked after too many failed login attempts")
+	ErrLoginThrottled = errors.New("too many failed login attempts, retry later")
+)
+
+// LoginEventType represents the type of a lockout event
+type LoginEventType string
+
+const (
+	LoginEventLocked   LoginEventType = "locked"
+	LoginEventUnlocked LoginEventType = "unlocked"
+)
+
+type (
+	// LoginGuard counts the failed logins of each username, delays the next attempts progressively
+	// and locks the account temporarily once the failures reach a limit
+	LoginGuard struct {
+		mu       sync.Mutex
+		accounts map[string]*loginAccount
+		events   []LoginEvent
+		now      func() time.Time
+	}
+
+	loginAccount struct {
+		username string
+		failures int
+		// number of attempts reserved and not released yet
+		pending       int
+		lastFailure   time.Time
+		lastClientIP  string
+		nextAttemptAt time.Time
+		lockedUntil   time.Time
+	}
+
+	// LoginLockout represents an account locked after too many failed logins
+	LoginLockout struct {
+		Username string `json:"Username" example:"bob"`
+		// Number of consecutive failed logins
+		Failures int `json:"Failures" example:"10"`
+		// Unix timestamp (UTC) of the last failed login
+		LastFailure int64 `json:"LastFailure" example:"1700000000"`
+		// IP address the last failed login came from
+		LastClientIP string `json:"LastClientIP" example:"10.0.0.10"`
+		// Unix timestamp (UTC) when the account is unlocked
+		LockedUntil int64 `json:"LockedUntil" example:"1700000900"`
+	}
+
+	// LoginEvent represents an account being locked or unlocked
+	LoginEvent struct {
+		Type     LoginEventType `json:"Type" example:"locked"`
+		Username string         `json:"Username" example:"bob"`
+		// IP address of the failed login that locked the account
+		ClientIP string `json:"ClientIP,omitempty" example:"10.0.0.10"`
+		// Administrator who unlocked the account
+		UnlockedBy string `json:"UnlockedBy,omitempty" example:"admin"`
+		// Unix timestamp (UTC) of the event
+		Time int64 `json:"Time" example:"1700000000"`
+	}
+)
+
+// NewLoginGuard initializes a new LoginGuard
+func NewLoginGuard() *LoginGuard {
+	guard := &LoginGuard{
+		accounts: make(map[string]*loginAccount),
+		now:      time.Now,
+	}
+
+	go guard.cleanUpTask()
+
+	return guard
+}
+
+// Reserve checks that username can attempt to log in and reserves the attempt, the returned function releases it
+// once the credentials are checked and Fail or Succeed is called. Once the free attempts are used, a single attempt
+// can be in progress at a time so that parallel requests cannot get past the delays and the lockout.
+func (guard *LoginGuard) Reserve(username string) (func(), time.Duration, error) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	now := guard.now()
+	key := loginKey(username)
+
+	account, ok := guard.accounts[key]
+	if ok {
+		if now.Before(account.lockedUntil) {
+			return nil, account.lockedUntil.Sub(now), ErrAccountLocked
+		}
+
+		if now.Before(account.nextAttemptAt) {
+			return nil, account.nextAttemptAt.Sub(now), ErrLoginThrottled
+		}
+
+		if account.pending > 0 && account.failures+account.pending >= loginFreeAttempts {
+			return nil, loginBaseDelay, ErrLoginThrottled
+		}
+	} else {
+		account = guard.addAccount(key, username, now)
+	}
+
+	account.pending++
+
+	release := func() {
+		guard.mu.Lock()
+		defer guard.mu.Unlock()
+
+		account.pending--
+
+		if account.pending == 0 && account.failures == 0 && guard.accounts[key] == account {
+			delete(guard.accounts, key)
+		}
+	}
+
+	return release, 0, nil
+}
+
+// addAccount starts tracking username, forgetting the least recently failed account when too many are tracked
+func (guard *LoginGuard) addAccount(key, username string, now time.Time) *loginAccount {
+	if len(guard.accounts) >= maxLoginAccounts {
+		guard.removeExpiredAccounts(now)
+	}
+
+	if len(guard.accounts) >= maxLoginAccounts {
+		oldestKey := ""
+		var oldest *loginAccount
+
+		for key, account := range guard.accounts {
+			if account.pending > 0 {
+				continue
+			}
+
+			if oldest == nil || account.lastFailure.Before(oldest.lastFailure) {
+				oldestKey, oldest = key, account
+			}
+		}
+
+		if oldest != nil {
+			delete(guard.accounts, oldestKey)
+		}
+	}
+
+	account := &loginAccount{username: username}
+	guard.accounts[key] = account
+
+	return account
+}
+
+// Fail records a failed login for username
+func (guard *LoginGuard) Fail(username, clientIP string) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	now := guard.now()
+	key := loginKey(username)
+
+	account, ok := guard.accounts[key]
+	if !ok {
+		account = guard.addAccount(key, username, now)
+	} else if now.Sub(account.lastFailure) > loginFailureWindow || (!account.lockedUntil.IsZero() && !now.Before(account.lockedUntil)) {
+		// the attempts in progress are kept
+		*account = loginAccount{username: username, pending: account.pending}
+	}
+
+	account.failures++
+	account.lastFailure = now
+	account.lastClientIP = clientIP
+
+	if account.failures >= loginMaxFailures {
+		account.lockedUntil = now.Add(LoginLockoutDuration)
+		guard.addEvent(LoginEvent{Type: LoginEventLocked, Username: account.username, ClientIP: clientIP, Time: now.Unix()})
+
+		log.Warn().Str("username", account.username).Str("client_ip", clientIP).Msg("account locked after too many failed login attempts")
+
+		return
+	}
+
+	if account.failures >= loginFreeAttempts {
+		delay := loginBaseDelay << (account.failures - loginFreeAttempts)
+		account.nextAttemptAt = now.Add(min(delay, loginMaxDelay))
+	}
+}
+
+// Succeed forgets the failed logins of username
+func (guard *LoginGuard) Succeed(username string) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	delete(guard.accounts, loginKey(username))
+}
+
+// Unlock unlocks the account of username, it returns false when the account was not locked
+func (guard *LoginGuard) Unlock(username, unlockedBy string) bool {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	key := loginKey(username)
+	account, ok := guard.accounts[key]
+	if !ok || !guard.now().Before(account.lockedUntil) {
+		return false
+	}
+
+	delete(guard.accounts, key)
+	guard.addEvent(LoginEvent{Type: LoginEventUnlocked, Username: account.username, UnlockedBy: unlockedBy, Time: guard.now().Unix()})
+
+	return true
+}
+
+// Lockouts returns the accounts that are currently locked
+func (guard *LoginGuard) Lockouts() []LoginLockout {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	now := guard.now()
+	lockouts := make([]LoginLockout, 0)
+
+	for _, account := range guard.accounts {
+		if !now.Before(account.lockedUntil) {
+			continue
+		}
+
+		lockouts = append(lockouts, LoginLockout{
+			Username:     account.username,
+			Failures:     account.failures,
+			LastFailure:  account.lastFailure.Unix(),
+			LastClientIP: account.lastClientIP,
+			LockedUntil:  account.lockedUntil.Unix(),
+		})
+	}
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].LockedUntil > lockouts[j].LockedUntil
+	})
+
+	return lockouts
+}
+
+// Events returns the most recent lockout events, newest first
+func (guard *LoginGuard) Events() []LoginEvent {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	events := make([]LoginEvent, len(guard.events))
+	for i, event := range guard.events {
+		events[len(events)-1-i] = event
+	}
+
+	return events
+}
+
+func (guard *LoginGuard) addEvent(event LoginEvent) {
+	if len(guard.events) >= maxLoginEvents {
+		guard.events = guard.events[1:]
+	}
+
+	guard.events = append(guard.events, event)
+}
+
+func (guard *LoginGuard) cleanUpTask() {
+	ticker := time.NewTicker(10 * time.Minute)
+
+	for range ticker.C {
+		guard.cleanUp()
+	}
+}
+
+// cleanUp forgets the accounts whose failures are too old to matter
+func (guard *LoginGuard) cleanUp() {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	guard.removeExpiredAccounts(guard.now())
+}
+
+func (guard *LoginGuard) removeExpiredAccounts(now time.Time) {
+	for key, account := range guard.accounts {
+		if account.pending > 0 || now.Before(account.lockedUntil) || now.Sub(account.lastFailure) <= loginFailureWindow {
+			continue
+		}
+
+		delete(guard.accounts, key)
+	}
+}
+
+func loginKey(username string) string {
+	return strings.ToLower(username)
+}
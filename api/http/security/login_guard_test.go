@@ -0,0 +1,157 @@
+package security
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLoginGuard(now *time.Time) *LoginGuard {
+	return &LoginGuard{
+		accounts: make(map[string]*loginAccount),
+		now:      func() time.Time { return *now },
+	}
+}
+
+// check reserves an attempt for username and releases it right away
+func check(guard *LoginGuard, username string) (time.Duration, error) {
+	release, retryAfter, err := guard.Reserve(username)
+	if release != nil {
+		release()
+	}
+
+	return retryAfter, err
+}
+
+func TestLoginGuardProgressiveDelay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	guard := newTestLoginGuard(&now)
+
+	for range loginFreeAttempts - 1 {
+		guard.Fail("bob", "10.0.0.1")
+
+		_, err := check(guard, "bob")
+		require.NoError(t, err)
+	}
+
+	guard.Fail("bob", "10.0.0.1")
+	retryAfter, err := check(guard, "BOB")
+	require.ErrorIs(t, err, ErrLoginThrottled)
+	require.Equal(t, loginBaseDelay, retryAfter)
+
+	now = now.Add(loginBaseDelay)
+	guard.Fail("bob", "10.0.0.1")
+	retryAfter, err = check(guard, "bob")
+	require.ErrorIs(t, err, ErrLoginThrottled)
+	require.Equal(t, 2*loginBaseDelay, retryAfter)
+
+	guard.Succeed("Bob")
+	_, err = check(guard, "bob")
+	require.NoError(t, err)
+}
+
+func TestLoginGuardLockout(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	guard := newTestLoginGuard(&now)
+
+	for range loginMaxFailures {
+		guard.Fail("bob", "10.0.0.1")
+		now = now.Add(loginMaxDelay)
+	}
+
+	retryAfter, err := check(guard, "bob")
+	require.ErrorIs(t, err, ErrAccountLocked)
+	require.Equal(t, LoginLockoutDuration-loginMaxDelay, retryAfter)
+
+	lockouts := guard.Lockouts()
+	require.Len(t, lockouts, 1)
+	require.Equal(t, "bob", lockouts[0].Username)
+	require.Equal(t, loginMaxFailures, lockouts[0].Failures)
+	require.Equal(t, "10.0.0.1", lockouts[0].LastClientIP)
+
+	require.False(t, guard.Unlock("alice", "admin"))
+	require.True(t, guard.Unlock("bob", "admin"))
+	require.Empty(t, guard.Lockouts())
+
+	_, err = check(guard, "bob")
+	require.NoError(t, err)
+
+	events := guard.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, LoginEventUnlocked, events[0].Type)
+	require.Equal(t, "admin", events[0].UnlockedBy)
+	require.Equal(t, LoginEventLocked, events[1].Type)
+	require.Equal(t, "10.0.0.1", events[1].ClientIP)
+}
+
+func TestLoginGuardLockoutExpires(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	guard := newTestLoginGuard(&now)
+
+	for range loginMaxFailures {
+		guard.Fail("bob", "10.0.0.1")
+	}
+
+	now = now.Add(LoginLockoutDuration)
+
+	_, err := check(guard, "bob")
+	require.NoError(t, err)
+
+	// A new failure starts a new count
+	guard.Fail("bob", "10.0.0.1")
+	_, err = check(guard, "bob")
+	require.NoError(t, err)
+
+	now = now.Add(loginFailureWindow + time.Second)
+	guard.cleanUp()
+	require.Empty(t, guard.accounts)
+}
+
+func TestLoginGuardReservation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	guard := newTestLoginGuard(&now)
+
+	// the free attempts can run in parallel
+	var releases []func()
+	for range loginFreeAttempts {
+		release, _, err := guard.Reserve("bob")
+		require.NoError(t, err)
+
+		releases = append(releases, release)
+	}
+
+	// the next ones wait for the attempts in progress to fail or succeed
+	_, retryAfter, err := guard.Reserve("bob")
+	require.ErrorIs(t, err, ErrLoginThrottled)
+	require.Equal(t, loginBaseDelay, retryAfter)
+
+	for _, release := range releases {
+		guard.Fail("bob", "10.0.0.1")
+		release()
+	}
+
+	_, err = check(guard, "bob")
+	require.ErrorIs(t, err, ErrLoginThrottled)
+
+	// a successful attempt is not tracked
+	release, _, err := guard.Reserve("alice")
+	require.NoError(t, err)
+	release()
+	require.NotContains(t, guard.accounts, "alice")
+}
+
+func TestLoginGuardBoundedAccounts(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	guard := newTestLoginGuard(&now)
+
+	for i := range maxLoginAccounts + 10 {
+		guard.Fail(fmt.Sprintf("user-%d", i), "10.0.0.1")
+		now = now.Add(time.Millisecond)
+	}
+
+	require.Len(t, guard.accounts, maxLoginAccounts)
+	require.NotContains(t, guard.accounts, "user-0")
+	require.Contains(t, guard.accounts, fmt.Sprintf("user-%d", maxLoginAccounts+9))
+}
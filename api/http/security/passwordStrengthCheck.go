@@ -1,6 +1,12 @@
 package security
 
 import (
+	_ "embed"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
 	portainer "github.com/portainer/portainer/api"
 
 	"github.com/rs/zerolog/log"
@@ -8,8 +14,37 @@ import (
 
 type PasswordStrengthChecker interface {
 	Check(password string) bool
+	// Violations returns the rules of the password policy broken by password
+	Violations(password string) []PasswordViolation
 }
 
+// PasswordViolation represents a rule of the password policy broken by a password
+type PasswordViolation string
+
+const (
+	PasswordViolationLength    PasswordViolation = "length"
+	PasswordViolationUppercase PasswordViolation = "uppercase"
+	PasswordViolationLowercase PasswordViolation = "lowercase"
+	PasswordViolationDigit     PasswordViolation = "digit"
+	PasswordViolationSpecial   PasswordViolation = "special"
+	PasswordViolationCommon    PasswordViolation = "common"
+	PasswordViolationReused    PasswordViolation = "reused"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+var commonPasswords = sync.OnceValue(func() map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, password := range strings.Split(commonPasswordsList, "\n") {
+		if password = strings.TrimSpace(password); password != "" {
+			passwords[strings.ToLower(password)] = struct{}{}
+		}
+	}
+
+	return passwords
+})
+
 type passwordStrengthChecker struct {
 	settings settingsService
 }
@@ -22,14 +57,114 @@ func NewPasswordStrengthChecker(settings settingsService) *passwordStrengthCheck
 
 // Check returns true if the password is strong enough
 func (c *passwordStrengthChecker) Check(password string) bool {
+	return len(c.Violations(password)) == 0
+}
+
+// Violations returns the rules of the password policy broken by password
+func (c *passwordStrengthChecker) Violations(password string) []PasswordViolation {
 	s, err := c.settings.Settings()
 	if err != nil {
 		log.Warn().Err(err).Msg("failed to fetch Portainer settings to validate user password")
 
-		return true
+		return nil
+	}
+
+	return PasswordPolicyViolations(password, s.InternalAuthSettings.RequiredPasswordLength, s.InternalAuthSettings.PasswordPolicy)
+}
+
+// PasswordPolicyViolations returns the rules broken by password, except the reuse of a previous password
+func PasswordPolicyViolations(password string, requiredLength int, policy portainer.PasswordPolicy) []PasswordViolation {
+	violations := make([]PasswordViolation, 0)
+
+	if len(password) < requiredLength {
+		violations = append(violations, PasswordViolationLength)
+	}
+
+	var upper, lower, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			special = true
+		}
+	}
+
+	if policy.RequireUppercase && !upper {
+		violations = append(violations, PasswordViolationUppercase)
+	}
+
+	if policy.RequireLowercase && !lower {
+		violations = append(violations, PasswordViolationLowercase)
+	}
+
+	if policy.RequireDigit && !digit {
+		violations = append(violations, PasswordViolationDigit)
+	}
+
+	if policy.RequireSpecial && !special {
+		violations = append(violations, PasswordViolationSpecial)
+	}
+
+	if policy.DenyCommonPasswords {
+		if _, ok := commonPasswords()[strings.ToLower(password)]; ok {
+			violations = append(violations, PasswordViolationCommon)
+		}
+	}
+
+	return violations
+}
+
+// PasswordReused returns true when password is the current password of user or one of the
+// previous passwords kept by the history of the password policy
+func PasswordReused(password string, user *portainer.User, historySize int, cryptoService portainer.CryptoService) bool {
+	if historySize <= 0 || user == nil {
+		return false
+	}
+
+	hashes := append([]string{user.Password}, user.PasswordHistory...)
+	for i, hash := range hashes {
+		if i >= historySize {
+			break
+		}
+
+		if hash != "" && cryptoService.CompareHashAndData(hash, password) == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RecordPasswordChange replaces the password hash of user and keeps the previous hashes required by the history
+// of the password policy, the current password counts as one of the historySize last passwords
+func RecordPasswordChange(user *portainer.User, hash string, historySize int, now time.Time) {
+	var history []string
+	if user.Password != "" {
+		history = append(history, user.Password)
+	}
+	history = append(history, user.PasswordHistory...)
+
+	user.PasswordHistory = nil
+	if historySize > 1 {
+		user.PasswordHistory = history[:min(len(history), historySize-1)]
+	}
+
+	user.Password = hash
+	user.PasswordChangedAt = now.Unix()
+}
+
+// PasswordExpired returns true when the password of user is older than the maximum age of the password policy
+func PasswordExpired(user *portainer.User, maxAgeDays int, now time.Time) bool {
+	if maxAgeDays <= 0 || user.PasswordChangedAt == 0 {
+		return false
 	}
 
-	return len(password) >= s.InternalAuthSettings.RequiredPasswordLength
+	return now.After(time.Unix(user.PasswordChangedAt, 0).AddDate(0, 0, maxAgeDays))
 }
 
 type settingsService interface {
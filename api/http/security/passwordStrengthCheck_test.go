@@ -2,8 +2,12 @@ package security
 
 import (
 	"testing"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestStrengthCheck(t *testing.T) {
@@ -47,3 +51,71 @@ func (s settingsStub) Settings() (*portainer.Settings, error) {
 		},
 	}, nil
 }
+
+func TestPasswordPolicyViolations(t *testing.T) {
+	policy := portainer.PasswordPolicy{
+		RequireUppercase:    true,
+		RequireLowercase:    true,
+		RequireDigit:        true,
+		RequireSpecial:      true,
+		DenyCommonPasswords: true,
+	}
+
+	tests := []struct {
+		password   string
+		violations []PasswordViolation
+	}{
+		{"Portainer-123", []PasswordViolation{}},
+		{"portainer-123", []PasswordViolation{PasswordViolationUppercase}},
+		{"PORTAINER-123", []PasswordViolation{PasswordViolationLowercase}},
+		{"Portainer-abc", []PasswordViolation{PasswordViolationDigit}},
+		{"Portainer1234", []PasswordViolation{PasswordViolationSpecial}},
+		{"Short-1", []PasswordViolation{PasswordViolationLength}},
+		{"Welcome123!", []PasswordViolation{PasswordViolationCommon}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			require.Equal(t, tt.violations, PasswordPolicyViolations(tt.password, 10, policy))
+		})
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	cryptoService := &crypto.Service{}
+	now := time.Unix(1700000000, 0)
+
+	hash := func(password string) string {
+		h, err := cryptoService.Hash(password)
+		require.NoError(t, err)
+
+		return h
+	}
+
+	user := &portainer.User{Password: hash("first")}
+
+	RecordPasswordChange(user, hash("second"), 3, now)
+	RecordPasswordChange(user, hash("third"), 3, now)
+	RecordPasswordChange(user, hash("fourth"), 3, now)
+	require.Len(t, user.PasswordHistory, 2)
+	require.Equal(t, now.Unix(), user.PasswordChangedAt)
+
+	require.True(t, PasswordReused("fourth", user, 3, cryptoService))
+	require.True(t, PasswordReused("second", user, 3, cryptoService))
+	require.False(t, PasswordReused("first", user, 3, cryptoService))
+	require.False(t, PasswordReused("second", user, 2, cryptoService))
+	require.False(t, PasswordReused("fourth", user, 0, cryptoService))
+
+	RecordPasswordChange(user, hash("fifth"), 0, now)
+	require.Empty(t, user.PasswordHistory)
+}
+
+func TestPasswordExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	user := &portainer.User{PasswordChangedAt: now.AddDate(0, 0, -31).Unix()}
+
+	require.True(t, PasswordExpired(user, 30, now))
+	require.False(t, PasswordExpired(user, 31, now))
+	require.False(t, PasswordExpired(user, 0, now))
+	require.False(t, PasswordExpired(&portainer.User{}, 30, now))
+}
@@ -26,10 +26,10 @@ func NewRateLimiter(maxRequests int, duration time.Duration, banDuration time.Du
 	}
 }
 
-// LimitAccess wraps current request with check if remote address does not goes above the defined limits
+// LimitAccess wraps current request with check if the client address does not goes above the defined limits
 func (limiter *RateLimiter) LimitAccess(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := StripAddrPort(r.RemoteAddr)
+		ip := RealClientIP(r)
 		if banned := limiter.Inc(ip); banned {
 			httperror.WriteError(w, http.StatusForbidden, "Access denied", errors.ErrResourceAccessDenied)
 			return
@@ -0,0 +1,258 @@
+package security
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// sessionLastSeenInterval limits how often the last activity of a session is persisted
+const sessionLastSeenInterval = time.Minute
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// UserSessions returns the active sessions of a user, most recently used first
+func (bouncer *RequestBouncer) UserSessions(userID portainer.UserID) []portainer.UserSession {
+	bouncer.sessionsMu.Lock()
+	defer bouncer.sessionsMu.Unlock()
+
+	now := time.Now()
+	sessions := make([]portainer.UserSession, 0)
+
+	for _, session := range bouncer.sessions {
+		if session.UserID == userID && !session.Revoked && !sessionExpired(session, now) {
+			sessions = append(sessions, *session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen > sessions[j].LastSeen
+	})
+
+	return sessions
+}
+
+// RevokeUserSession revokes a session of a user, the JWT of the session is rejected from then on
+func (bouncer *RequestBouncer) RevokeUserSession(userID portainer.UserID, sessionID portainer.UserSessionID) error {
+	bouncer.sessionsMu.Lock()
+	defer bouncer.sessionsMu.Unlock()
+
+	for _, session := range bouncer.sessions {
+		if session.ID == sessionID && session.UserID == userID && !session.Revoked {
+			return bouncer.revokeSession(session)
+		}
+	}
+
+	return ErrSessionNotFound
+}
+
+// RevokeAllSessions revokes every active session except the one of the token passed as argument
+func (bouncer *RequestBouncer) RevokeAllSessions(exceptToken string) error {
+	exceptID := TokenID(exceptToken)
+
+	bouncer.sessionsMu.Lock()
+	defer bouncer.sessionsMu.Unlock()
+
+	now := time.Now()
+
+	for _, session := range bouncer.sessions {
+		if session.Revoked || session.TokenID == exceptID || sessionExpired(session, now) {
+			continue
+		}
+
+		if err := bouncer.revokeSession(session); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TokenID returns the identifier (jti) of a JWT without verifying it, the token must be verified beforehand
+func TokenID(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return ""
+	}
+
+	return claims.ID
+}
+
+// loadSessions restores the sessions recorded before the last restart so that revoked JWTs stay revoked
+func (bouncer *RequestBouncer) loadSessions() {
+	sessions, err := bouncer.dataStore.UserSession().ReadAll()
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to load the user sessions")
+
+		return
+	}
+
+	now := time.Now()
+
+	bouncer.sessionsMu.Lock()
+	defer bouncer.sessionsMu.Unlock()
+
+	for i := range sessions {
+		session := &sessions[i]
+
+		if sessionExpired(session, now) {
+			if err := bouncer.dataStore.UserSession().Delete(session.ID); err != nil {
+				log.Warn().Err(err).Int("session_id", int(session.ID)).Msg("unable to remove an expired user session")
+			}
+
+			continue
+		}
+
+		bouncer.sessions[session.TokenID] = session
+
+		if session.Revoked {
+			bouncer.revokedJWT.Store(session.TokenID, sessionExpiration(session))
+		}
+	}
+}
+
+// trackSession records the use of a verified JWT, creating its session on first use
+func (bouncer *RequestBouncer) trackSession(r *http.Request, tokenData *portainer.TokenData, jti string, exp time.Time) {
+	if bouncer.dataStore == nil || jti == "" {
+		return
+	}
+
+	now := time.Now()
+
+	bouncer.sessionsMu.Lock()
+	defer bouncer.sessionsMu.Unlock()
+
+	session, ok := bouncer.sessions[jti]
+	if ok {
+		if now.Sub(time.Unix(session.LastSeen, 0)) < sessionLastSeenInterval {
+			return
+		}
+
+		session.LastSeen = now.Unix()
+		session.ClientIP = RealClientIP(r)
+		session.UserAgent = r.UserAgent()
+
+		if err := bouncer.dataStore.UserSession().Update(session.ID, session); err != nil {
+			log.Warn().Err(err).Int("session_id", int(session.ID)).Msg("unable to update the user session")
+		}
+
+		return
+	}
+
+	session = newSession(tokenData, jti, exp, now)
+	session.ClientIP = RealClientIP(r)
+	session.UserAgent = r.UserAgent()
+
+	if err := bouncer.dataStore.UserSession().Create(session); err != nil {
+		log.Warn().Err(err).Msg("unable to record the user session")
+
+		return
+	}
+
+	bouncer.sessions[jti] = session
+}
+
+// persistRevocation marks the session of a revoked JWT so that the revocation survives a restart
+func (bouncer *RequestBouncer) persistRevocation(tokenData *portainer.TokenData, jti string, exp time.Time) {
+	if bouncer.dataStore == nil || jti == "" {
+		return
+	}
+
+	bouncer.sessionsMu.Lock()
+	defer bouncer.sessionsMu.Unlock()
+
+	session, ok := bouncer.sessions[jti]
+	if !ok {
+		session = newSession(tokenData, jti, exp, time.Now())
+		session.Revoked = true
+
+		if err := bouncer.dataStore.UserSession().Create(session); err != nil {
+			log.Warn().Err(err).Msg("unable to record the revoked user session")
+
+			return
+		}
+
+		bouncer.sessions[jti] = session
+
+		return
+	}
+
+	if err := bouncer.revokeSession(session); err != nil {
+		log.Warn().Err(err).Int("session_id", int(session.ID)).Msg("unable to revoke the user session")
+	}
+}
+
+// revokeSession must be called with sessionsMu held
+func (bouncer *RequestBouncer) revokeSession(session *portainer.UserSession) error {
+	session.Revoked = true
+	bouncer.revokedJWT.Store(session.TokenID, sessionExpiration(session))
+
+	return bouncer.dataStore.UserSession().Update(session.ID, session)
+}
+
+func (bouncer *RequestBouncer) cleanUpExpiredSessions() {
+	if bouncer.dataStore == nil {
+		return
+	}
+
+	now := time.Now()
+
+	bouncer.sessionsMu.Lock()
+	defer bouncer.sessionsMu.Unlock()
+
+	for jti, session := range bouncer.sessions {
+		if !sessionExpired(session, now) {
+			continue
+		}
+
+		if err := bouncer.dataStore.UserSession().Delete(session.ID); err != nil {
+			log.Warn().Err(err).Int("session_id", int(session.ID)).Msg("unable to remove an expired user session")
+
+			continue
+		}
+
+		delete(bouncer.sessions, jti)
+	}
+}
+
+func newSession(tokenData *portainer.TokenData, jti string, exp time.Time, now time.Time) *portainer.UserSession {
+	session := &portainer.UserSession{
+		UserID:   tokenData.ID,
+		TokenID:  jti,
+		IssuedAt: now.Unix(),
+		LastSeen: now.Unix(),
+	}
+
+	if !exp.IsZero() {
+		session.ExpiresAt = exp.Unix()
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenData.Token, claims); err == nil && claims.IssuedAt != nil {
+		session.IssuedAt = claims.IssuedAt.Unix()
+	}
+
+	return session
+}
+
+func sessionExpired(session *portainer.UserSession, now time.Time) bool {
+	return session.ExpiresAt != 0 && now.Unix() > session.ExpiresAt
+}
+
+func sessionExpiration(session *portainer.UserSession) time.Time {
+	if session.ExpiresAt == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(session.ExpiresAt, 0)
+}
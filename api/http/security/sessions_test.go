@@ -0,0 +1,104 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTracking(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "alice"}))
+	require.NoError(t, store.User().Create(&portainer.User{Username: "bob"}))
+
+	bouncer := NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(nil, nil))
+
+	lookup := func(b *RequestBouncer, token string) error {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.10:41000"
+		r.Header.Set("User-Agent", "test-agent")
+		r.Header.Add(jwtTokenHeader, "Bearer "+token)
+
+		_, err := b.JWTAuthLookup(r)
+
+		return err
+	}
+
+	aliceToken, _, err := jwtService.GenerateToken(&portainer.TokenData{ID: 1})
+	require.NoError(t, err)
+	aliceOtherToken, _, err := jwtService.GenerateToken(&portainer.TokenData{ID: 1})
+	require.NoError(t, err)
+	bobToken, _, err := jwtService.GenerateToken(&portainer.TokenData{ID: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, lookup(bouncer, aliceToken))
+	require.NoError(t, lookup(bouncer, aliceToken))
+	require.NoError(t, lookup(bouncer, aliceOtherToken))
+	require.NoError(t, lookup(bouncer, bobToken))
+
+	sessions := bouncer.UserSessions(1)
+	require.Len(t, sessions, 2)
+	require.Equal(t, "10.0.0.10", sessions[0].ClientIP)
+	require.Equal(t, "test-agent", sessions[0].UserAgent)
+	require.NotZero(t, sessions[0].IssuedAt)
+	require.NotZero(t, sessions[0].ExpiresAt)
+
+	// The session of another user cannot be revoked through the wrong user
+	require.ErrorIs(t, bouncer.RevokeUserSession(2, sessions[0].ID), ErrSessionNotFound)
+
+	var revoked portainer.UserSession
+	for _, session := range sessions {
+		if session.TokenID == TokenID(aliceOtherToken) {
+			revoked = session
+		}
+	}
+
+	require.NoError(t, bouncer.RevokeUserSession(1, revoked.ID))
+	require.ErrorIs(t, lookup(bouncer, aliceOtherToken), ErrRevokedJWT)
+	require.Len(t, bouncer.UserSessions(1), 1)
+
+	// The revocation survives a restart
+	restarted := NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(nil, nil))
+	require.ErrorIs(t, lookup(restarted, aliceOtherToken), ErrRevokedJWT)
+	require.NoError(t, lookup(restarted, aliceToken))
+	require.Len(t, restarted.UserSessions(1), 1)
+
+	require.NoError(t, restarted.RevokeAllSessions(aliceToken))
+	require.NoError(t, lookup(restarted, aliceToken))
+	require.ErrorIs(t, lookup(restarted, bobToken), ErrRevokedJWT)
+	require.Empty(t, restarted.UserSessions(2))
+}
+
+func TestRevokeJWTPersistsUnusedToken(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "alice"}))
+
+	token, _, err := jwtService.GenerateToken(&portainer.TokenData{ID: 1})
+	require.NoError(t, err)
+
+	NewRequestBouncer(store, jwtService, nil).RevokeJWT(token)
+
+	sessions, err := store.UserSession().ReadAll()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.True(t, sessions[0].Revoked)
+
+	bouncer := NewRequestBouncer(store, jwtService, nil)
+	_, ok := bouncer.revokedJWT.Load(TokenID(token))
+	require.True(t, ok)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/portainer/portainer/api/http/handler/auth"
 	"github.com/portainer/portainer/api/http/handler/backup"
 	"github.com/portainer/portainer/api/http/handler/customtemplates"
+	deploymentgroupshandler "github.com/portainer/portainer/api/http/handler/deploymentgroups"
 	dockerhandler "github.com/portainer/portainer/api/http/handler/docker"
 	"github.com/portainer/portainer/api/http/handler/edgegroups"
 	"github.com/portainer/portainer/api/http/handler/edgejobs"
@@ -49,6 +50,7 @@ import (
 	"github.com/portainer/portainer/api/http/handler/templates"
 	"github.com/portainer/portainer/api/http/handler/upload"
 	"github.com/portainer/portainer/api/http/handler/users"
+	"github.com/portainer/portainer/api/http/handler/variablesets"
 	"github.com/portainer/portainer/api/http/handler/webhooks"
 	"github.com/portainer/portainer/api/http/handler/websocket"
 	"github.com/portainer/portainer/api/http/middlewares"
@@ -66,6 +68,8 @@ import (
 	"github.com/portainer/portainer/api/pendingactions"
 	"github.com/portainer/portainer/api/platform"
 	"github.com/portainer/portainer/api/scheduler"
+	"github.com/portainer/portainer/api/stacks/buildlogs"
+	"github.com/portainer/portainer/api/stacks/deploymentgroups"
 	"github.com/portainer/portainer/api/stacks/deployments"
 	libhelmtypes "github.com/portainer/portainer/pkg/libhelm/types"
 
@@ -83,6 +87,7 @@ type Server struct {
 	Status                      *portainer.Status
 	ReverseTunnelService        portainer.ReverseTunnelService
 	ComposeStackManager         portainer.ComposeStackManager
+	StackBuildLogs              *buildlogs.Service
 	CryptoService               portainer.CryptoService
 	EdgeStacksService           *edgestackservice.Service
 	SignatureService            portainer.DigitalSignatureService
@@ -94,13 +99,16 @@ type Server struct {
 	APIKeyService               apikey.APIKeyService
 	JWTService                  portainer.JWTService
 	LDAPService                 portainer.LDAPService
+	LDAPSyncService             portainer.LDAPSyncService
 	OAuthService                portainer.OAuthService
+	SAMLService                 portainer.SAMLService
 	SwarmStackManager           portainer.SwarmStackManager
 	ProxyManager                *proxy.Manager
 	KubernetesTokenCacheManager *kubernetes.TokenCacheManager
 	KubeClusterAccessService    k8s.KubeClusterAccessService
 	Handler                     *handler.Handler
 	SSLService                  *ssl.Service
+	ClientCertificateService    *security.ClientCertificateService
 	DockerClientFactory         *dockerclient.ClientFactory
 	KubernetesClientFactory     *cli.ClientFactory
 	KubernetesDeployer          portainer.KubernetesDeployer
@@ -125,6 +133,9 @@ func (server *Server) Start() error {
 	if !server.CSP {
 		requestBouncer.DisableCSP()
 	}
+	if server.ClientCertificateService != nil {
+		requestBouncer.SetClientCertificateService(server.ClientCertificateService)
+	}
 
 	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
 	offlineGate := offlinegate.NewOfflineGate()
@@ -139,6 +150,8 @@ func (server *Server) Start() error {
 	authHandler.ProxyManager = server.ProxyManager
 	authHandler.KubernetesTokenCacheManager = kubernetesTokenCacheManager
 	authHandler.OAuthService = server.OAuthService
+	authHandler.SAMLService = server.SAMLService
+	authHandler.ClientCertificateService = server.ClientCertificateService
 
 	adminMonitor := adminmonitor.New(5*time.Minute, server.DataStore, server.ShutdownCtx)
 	adminMonitor.Start()
@@ -157,6 +170,10 @@ func (server *Server) Start() error {
 
 	var customTemplatesHandler = customtemplates.NewHandler(requestBouncer, server.DataStore, server.FileService, server.GitService)
 
+	var deploymentGroupHandler = deploymentgroupshandler.NewHandler(requestBouncer)
+	deploymentGroupHandler.DataStore = server.DataStore
+	deploymentGroupHandler.DeploymentGroupService = deploymentgroups.NewService(server.DataStore, server.FileService, server.StackDeployer, server.Scheduler, server.GitService)
+
 	var edgeGroupsHandler = edgegroups.NewHandler(requestBouncer)
 	edgeGroupsHandler.DataStore = server.DataStore
 	edgeGroupsHandler.ReverseTunnelService = server.ReverseTunnelService
@@ -216,6 +233,7 @@ func (server *Server) Start() error {
 	ldapHandler.DataStore = server.DataStore
 	ldapHandler.FileService = server.FileService
 	ldapHandler.LDAPService = server.LDAPService
+	ldapHandler.LDAPSyncService = server.LDAPSyncService
 
 	var motdHandler = motd.NewHandler(requestBouncer)
 
@@ -233,6 +251,8 @@ func (server *Server) Start() error {
 	settingsHandler.FileService = server.FileService
 	settingsHandler.JWTService = server.JWTService
 	settingsHandler.LDAPService = server.LDAPService
+	settingsHandler.LDAPSyncService = server.LDAPSyncService
+	settingsHandler.ClientCertificateService = server.ClientCertificateService
 	settingsHandler.SnapshotService = server.SnapshotService
 
 	var sslHandler = sslhandler.NewHandler(requestBouncer)
@@ -254,6 +274,7 @@ func (server *Server) Start() error {
 	stackHandler.SwarmStackManager = server.SwarmStackManager
 	stackHandler.ComposeStackManager = server.ComposeStackManager
 	stackHandler.StackDeployer = server.StackDeployer
+	stackHandler.BuildLogs = server.StackBuildLogs
 
 	var storybookHandler = storybook.NewHandler(server.AssetsPath)
 
@@ -287,6 +308,9 @@ func (server *Server) Start() error {
 	userHandler.AdminCreationDone = server.AdminCreationDone
 	userHandler.FileService = server.FileService
 
+	var variableSetHandler = variablesets.NewHandler(requestBouncer)
+	variableSetHandler.DataStore = server.DataStore
+
 	var websocketHandler = websocket.NewHandler(server.KubernetesTokenCacheManager, requestBouncer)
 	websocketHandler.DataStore = server.DataStore
 	websocketHandler.SignatureService = server.SignatureService
@@ -302,6 +326,7 @@ func (server *Server) Start() error {
 		AuthHandler:            authHandler,
 		BackupHandler:          backupHandler,
 		CustomTemplatesHandler: customTemplatesHandler,
+		DeploymentGroupHandler: deploymentGroupHandler,
 		DockerHandler:          dockerHandler,
 		EdgeGroupsHandler:      edgeGroupsHandler,
 		EdgeJobsHandler:        edgeJobsHandler,
@@ -331,6 +356,7 @@ func (server *Server) Start() error {
 		TemplatesHandler:       templatesHandler,
 		UploadHandler:          uploadHandler,
 		UserHandler:            userHandler,
+		VariableSetHandler:     variableSetHandler,
 		WebSocketHandler:       websocketHandler,
 		WebhookHandler:         webhookHandler,
 	}
@@ -346,12 +372,18 @@ func (server *Server) Start() error {
 		return errors.Wrap(err, "failed to create CSRF middleware")
 	}
 
-	if server.HTTPEnabled {
+	if server.HTTPEnabled || server.SSLService.ACMEHTTPChallengeEnabled() {
+		// when HTTP is disabled, the listener only answers the ACME http-01 challenges
+		var httpHandler http.Handler = http.NotFoundHandler()
+		if server.HTTPEnabled {
+			httpHandler = middlewares.PlaintextHTTPRequest(handler)
+		}
+
 		go func() {
 			log.Info().Str("bind_address", server.BindAddress).Msg("starting HTTP server")
 			httpServer := &http.Server{
 				Addr:     server.BindAddress,
-				Handler:  middlewares.PlaintextHTTPRequest(handler),
+				Handler:  server.SSLService.ACMEChallengeHandler(httpHandler),
 				ErrorLog: errorLogger,
 			}
 
@@ -376,6 +408,9 @@ func (server *Server) Start() error {
 	httpsServer.TLSConfig.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
 		return server.SSLService.GetRawCertificate(), nil
 	}
+	if server.ClientCertificateService != nil {
+		httpsServer.TLSConfig.GetConfigForClient = server.ClientCertificateService.GetConfigForClient(httpsServer.TLSConfig)
+	}
 
 	go shutdown(server.ShutdownCtx, httpsServer)
 	go snapshot.NewBackgroundSnapshotter(server.DataStore, server.ReverseTunnelService)
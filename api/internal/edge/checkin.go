@@ -0,0 +1,101 @@
+package edge
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	"github.com/portainer/portainer/api/scheduler"
+	"github.com/portainer/portainer/api/set"
+
+	"github.com/pkg/errors"
+)
+
+// checkInAgeRefreshInterval is the precision of the membership of the edge groups selecting the environments by
+// last check-in age
+const checkInAgeRefreshInterval = time.Minute
+
+// StartCheckInAgeRefresh periodically recomputes the Edge stacks related to the Edge environments(endpoints), the
+// membership of the edge groups selecting them by last check-in age changes with time only
+func StartCheckInAgeRefresh(dataStore dataservices.DataStore, s *scheduler.Scheduler) {
+	s.StartJobEvery(checkInAgeRefreshInterval, func() error {
+		return dataStore.UpdateTx(RefreshCheckInAgeRelations)
+	})
+}
+
+// RefreshCheckInAgeRelations recomputes the Edge stacks related to the Edge environments(endpoints) when a dynamic
+// edge group selects them by last check-in age
+func RefreshCheckInAgeRelations(tx dataservices.DataStoreTx) error {
+	edgeGroups, err := tx.EdgeGroup().ReadAll()
+	if err != nil {
+		return errors.WithMessage(err, "Unable to retrieve edge groups from the database")
+	}
+
+	if !slices.ContainsFunc(edgeGroups, func(edgeGroup portainer.EdgeGroup) bool {
+		return edgeGroup.Dynamic && edgeGroup.Selector != nil && selectsCheckInAge(edgeGroup.Selector)
+	}) {
+		return nil
+	}
+
+	endpoints, err := tx.Endpoint().Endpoints()
+	if err != nil {
+		return errors.WithMessage(err, "Unable to retrieve environments from the database")
+	}
+
+	endpointGroups, err := tx.EndpointGroup().ReadAll()
+	if err != nil {
+		return errors.WithMessage(err, "Unable to retrieve environment groups from the database")
+	}
+
+	edgeStacks, err := tx.EdgeStack().EdgeStacks()
+	if err != nil {
+		return errors.WithMessage(err, "Unable to retrieve edge stacks from the database")
+	}
+
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		if !endpointutils.IsEdgeEndpoint(endpoint) {
+			continue
+		}
+
+		relation, err := tx.EndpointRelation().EndpointRelation(endpoint.ID)
+		if err != nil {
+			return errors.WithMessage(err, "Unable to retrieve environment relation inside the database")
+		}
+
+		var endpointGroup *portainer.EndpointGroup
+		if i := slices.IndexFunc(endpointGroups, func(group portainer.EndpointGroup) bool { return group.ID == endpoint.GroupID }); i >= 0 {
+			endpointGroup = &endpointGroups[i]
+		}
+
+		edgeStacksSet := set.ToSet(EndpointRelatedEdgeStacks(endpoint, endpointGroup, edgeGroups, edgeStacks))
+		if maps.Equal(edgeStacksSet, relation.EdgeStacks) {
+			continue
+		}
+
+		relation.EdgeStacks = edgeStacksSet
+
+		if err := tx.EndpointRelation().UpdateEndpointRelation(endpoint.ID, relation); err != nil {
+			return errors.WithMessage(err, "Unable to persist environment relation changes inside the database")
+		}
+	}
+
+	return nil
+}
+
+func selectsCheckInAge(selector *portainer.EdgeGroupSelector) bool {
+	if selector.Field == portainer.EdgeGroupSelectorLastCheckInAge {
+		return true
+	}
+
+	for i := range selector.Selectors {
+		if selectsCheckInAge(&selector.Selectors[i]) {
+			return true
+		}
+	}
+
+	return false
+}
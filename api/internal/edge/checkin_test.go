@@ -0,0 +1,53 @@
+package edge
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/datastore"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshCheckInAgeRelations(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	endpoint := &portainer.Endpoint{
+		ID:              1,
+		Name:            "edge-1",
+		Type:            portainer.EdgeAgentOnDockerEnvironment,
+		GroupID:         1,
+		LastCheckInDate: time.Now().Unix(),
+	}
+	require.NoError(t, store.Endpoint().Create(endpoint))
+	require.NoError(t, store.EndpointRelation().Create(&portainer.EndpointRelation{EndpointID: endpoint.ID, EdgeStacks: map[portainer.EdgeStackID]bool{}}))
+
+	require.NoError(t, store.EdgeGroup().Create(&portainer.EdgeGroup{
+		ID:       1,
+		Name:     "online",
+		Dynamic:  true,
+		Selector: &portainer.EdgeGroupSelector{Field: portainer.EdgeGroupSelectorLastCheckInAge, Value: "5m"},
+	}))
+	require.NoError(t, store.EdgeStack().Create(1, &portainer.EdgeStack{ID: 1, Name: "stack", EdgeGroups: []portainer.EdgeGroupID{1}}))
+
+	relatedEdgeStacks := func() map[portainer.EdgeStackID]bool {
+		require.NoError(t, store.UpdateTx(RefreshCheckInAgeRelations))
+
+		relation, err := store.EndpointRelation().EndpointRelation(endpoint.ID)
+		require.NoError(t, err)
+
+		return relation.EdgeStacks
+	}
+
+	require.True(t, relatedEdgeStacks()[1])
+
+	// the environment leaves the edge group once it stops checking in
+	endpoint.LastCheckInDate = time.Now().Add(-10 * time.Minute).Unix()
+	require.NoError(t, store.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		return tx.Endpoint().UpdateEndpoint(endpoint.ID, endpoint)
+	}))
+
+	require.False(t, relatedEdgeStacks()[1])
+}
@@ -1,12 +1,19 @@
 package edge
 
 import (
+	"errors"
+	"fmt"
+	"path"
 	"slices"
+	"strconv"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/tag"
+
+	"github.com/Masterminds/semver/v3"
 )
 
 // EdgeGroupRelatedEndpoints returns a list of environments(endpoints) related to this Edge group
@@ -75,6 +82,18 @@ func edgeGroupRelatedToEndpoint(edgeGroup *portainer.EdgeGroup, endpoint *portai
 		return slices.Contains(edgeGroup.Endpoints, endpoint.ID)
 	}
 
+	if edgeGroup.Selector != nil {
+		if len(edgeGroup.TagIDs) > 0 && !edgeGroupTagsMatch(edgeGroup, endpoint, endpointGroup) {
+			return false
+		}
+
+		return MatchEdgeGroupSelector(edgeGroup.Selector, endpoint, endpointGroup, time.Now())
+	}
+
+	return edgeGroupTagsMatch(edgeGroup, endpoint, endpointGroup)
+}
+
+func edgeGroupTagsMatch(edgeGroup *portainer.EdgeGroup, endpoint *portainer.Endpoint, endpointGroup *portainer.EndpointGroup) bool {
 	endpointTags := tag.Set(endpoint.TagIDs)
 	if endpointGroup != nil && endpointGroup.TagIDs != nil {
 		endpointTags = tag.Union(endpointTags, tag.Set(endpointGroup.TagIDs))
@@ -86,3 +105,148 @@ func edgeGroupRelatedToEndpoint(edgeGroup *portainer.EdgeGroup, endpoint *portai
 
 	return tag.FullMatch(edgeGroup.TagIDs, endpointTags)
 }
+
+// ValidateEdgeGroupSelector returns an error when the selector or one of its nested selectors cannot be evaluated
+func ValidateEdgeGroupSelector(selector *portainer.EdgeGroupSelector) error {
+	switch selector.Operator {
+	case portainer.EdgeGroupSelectorAnd, portainer.EdgeGroupSelectorOr:
+		if len(selector.Selectors) == 0 {
+			return fmt.Errorf("the %q operator requires at least one selector", selector.Operator)
+		}
+	case portainer.EdgeGroupSelectorNot:
+		if len(selector.Selectors) != 1 {
+			return errors.New(`the "not" operator requires exactly one selector`)
+		}
+	case "":
+		return validateEdgeGroupSelectorField(selector)
+	default:
+		return fmt.Errorf("invalid selector operator: %q", selector.Operator)
+	}
+
+	for i := range selector.Selectors {
+		if err := ValidateEdgeGroupSelector(&selector.Selectors[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateEdgeGroupSelectorField(selector *portainer.EdgeGroupSelector) error {
+	var err error
+
+	switch selector.Field {
+	case portainer.EdgeGroupSelectorName:
+		_, err = path.Match(selector.Value, "")
+	case portainer.EdgeGroupSelectorGroupID, portainer.EdgeGroupSelectorType:
+		_, err = strconv.Atoi(selector.Value)
+	case portainer.EdgeGroupSelectorAgentVersion:
+		_, err = semver.NewConstraint(selector.Value)
+	case portainer.EdgeGroupSelectorPlatform:
+		if selector.Value != edgeGroupSelectorPlatformDocker && selector.Value != edgeGroupSelectorPlatformKubernetes {
+			err = fmt.Errorf("platform must be either %q or %q", edgeGroupSelectorPlatformDocker, edgeGroupSelectorPlatformKubernetes)
+		}
+	case portainer.EdgeGroupSelectorContainerEngine:
+		if selector.Value == "" {
+			err = errors.New("container engine is mandatory")
+		}
+	case portainer.EdgeGroupSelectorLastCheckInAge:
+		_, err = time.ParseDuration(selector.Value)
+	case portainer.EdgeGroupSelectorLabel:
+		if selector.Key == "" {
+			return errors.New("label key is mandatory for a label selector")
+		}
+
+		_, err = path.Match(selector.Value, "")
+	default:
+		return fmt.Errorf("invalid selector field: %q", selector.Field)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid value %q for the %q selector: %w", selector.Value, selector.Field, err)
+	}
+
+	return nil
+}
+
+const (
+	edgeGroupSelectorPlatformDocker     = "docker"
+	edgeGroupSelectorPlatformKubernetes = "kubernetes"
+)
+
+// MatchEdgeGroupSelector returns true if the environment(endpoint) is matched by the selector.
+// Labels inherited from the environment(endpoint) group are taken into account and the check-in age is computed relatively to now.
+func MatchEdgeGroupSelector(selector *portainer.EdgeGroupSelector, endpoint *portainer.Endpoint, endpointGroup *portainer.EndpointGroup, now time.Time) bool {
+	switch selector.Operator {
+	case portainer.EdgeGroupSelectorAnd:
+		for i := range selector.Selectors {
+			if !MatchEdgeGroupSelector(&selector.Selectors[i], endpoint, endpointGroup, now) {
+				return false
+			}
+		}
+
+		return true
+	case portainer.EdgeGroupSelectorOr:
+		for i := range selector.Selectors {
+			if MatchEdgeGroupSelector(&selector.Selectors[i], endpoint, endpointGroup, now) {
+				return true
+			}
+		}
+
+		return false
+	case portainer.EdgeGroupSelectorNot:
+		return len(selector.Selectors) == 1 && !MatchEdgeGroupSelector(&selector.Selectors[0], endpoint, endpointGroup, now)
+	}
+
+	switch selector.Field {
+	case portainer.EdgeGroupSelectorName:
+		match, err := path.Match(selector.Value, endpoint.Name)
+		return err == nil && match
+	case portainer.EdgeGroupSelectorGroupID:
+		return strconv.Itoa(int(endpoint.GroupID)) == selector.Value
+	case portainer.EdgeGroupSelectorAgentVersion:
+		return matchAgentVersion(selector.Value, endpoint.Agent.Version)
+	case portainer.EdgeGroupSelectorType:
+		return strconv.Itoa(int(endpoint.Type)) == selector.Value
+	case portainer.EdgeGroupSelectorPlatform:
+		switch selector.Value {
+		case edgeGroupSelectorPlatformDocker:
+			return endpointutils.IsDockerEndpoint(endpoint)
+		case edgeGroupSelectorPlatformKubernetes:
+			return endpointutils.IsKubernetesEndpoint(endpoint)
+		}
+	case portainer.EdgeGroupSelectorContainerEngine:
+		return endpoint.ContainerEngine == selector.Value
+	case portainer.EdgeGroupSelectorLastCheckInAge:
+		maxAge, err := time.ParseDuration(selector.Value)
+		if err != nil || endpoint.LastCheckInDate == 0 {
+			return false
+		}
+
+		return now.Sub(time.Unix(endpoint.LastCheckInDate, 0)) <= maxAge
+	case portainer.EdgeGroupSelectorLabel:
+		value, ok := endpointutils.LabelValue(endpointutils.EndpointLabels(endpoint, endpointGroup), selector.Key)
+		if !ok {
+			return false
+		}
+
+		match, err := path.Match(selector.Value, value)
+		return err == nil && match
+	}
+
+	return false
+}
+
+func matchAgentVersion(constraint, version string) bool {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	return c.Check(v)
+}
@@ -0,0 +1,138 @@
+package edge
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchEdgeGroupSelector(t *testing.T) {
+	now := time.Now()
+
+	endpoint := &portainer.Endpoint{
+		ID:              1,
+		Name:            "paris-edge-01",
+		Type:            portainer.EdgeAgentOnDockerEnvironment,
+		GroupID:         3,
+		ContainerEngine: "podman",
+		LastCheckInDate: now.Add(-2 * time.Minute).Unix(),
+		Labels:          []portainer.Pair{{Name: "site", Value: "paris"}},
+	}
+	endpoint.Agent.Version = "2.21.1"
+
+	field := func(field portainer.EdgeGroupSelectorField, value string) portainer.EdgeGroupSelector {
+		return portainer.EdgeGroupSelector{Field: field, Value: value}
+	}
+
+	tests := []struct {
+		name     string
+		selector portainer.EdgeGroupSelector
+		expected bool
+	}{
+		{"name glob", field(portainer.EdgeGroupSelectorName, "paris-*"), true},
+		{"name glob mismatch", field(portainer.EdgeGroupSelectorName, "lyon-*"), false},
+		{"group", field(portainer.EdgeGroupSelectorGroupID, "3"), true},
+		{"agent version", field(portainer.EdgeGroupSelectorAgentVersion, ">=2.20.0"), true},
+		{"agent version mismatch", field(portainer.EdgeGroupSelectorAgentVersion, "<2.20.0"), false},
+		{"type", field(portainer.EdgeGroupSelectorType, "4"), true},
+		{"platform", field(portainer.EdgeGroupSelectorPlatform, "docker"), true},
+		{"platform mismatch", field(portainer.EdgeGroupSelectorPlatform, "kubernetes"), false},
+		{"container engine", field(portainer.EdgeGroupSelectorContainerEngine, "podman"), true},
+		{"check-in age", field(portainer.EdgeGroupSelectorLastCheckInAge, "5m"), true},
+		{"check-in age too old", field(portainer.EdgeGroupSelectorLastCheckInAge, "1m"), false},
+		{"label", portainer.EdgeGroupSelector{Field: portainer.EdgeGroupSelectorLabel, Key: "site", Value: "par*"}, true},
+		{"missing label", portainer.EdgeGroupSelector{Field: portainer.EdgeGroupSelectorLabel, Key: "owner", Value: "*"}, false},
+		{
+			"and",
+			portainer.EdgeGroupSelector{Operator: portainer.EdgeGroupSelectorAnd, Selectors: []portainer.EdgeGroupSelector{
+				field(portainer.EdgeGroupSelectorName, "paris-*"),
+				field(portainer.EdgeGroupSelectorContainerEngine, "docker"),
+			}},
+			false,
+		},
+		{
+			"or",
+			portainer.EdgeGroupSelector{Operator: portainer.EdgeGroupSelectorOr, Selectors: []portainer.EdgeGroupSelector{
+				field(portainer.EdgeGroupSelectorName, "lyon-*"),
+				field(portainer.EdgeGroupSelectorContainerEngine, "podman"),
+			}},
+			true,
+		},
+		{
+			"not",
+			portainer.EdgeGroupSelector{Operator: portainer.EdgeGroupSelectorNot, Selectors: []portainer.EdgeGroupSelector{
+				field(portainer.EdgeGroupSelectorGroupID, "3"),
+			}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, ValidateEdgeGroupSelector(&tt.selector))
+			require.Equal(t, tt.expected, MatchEdgeGroupSelector(&tt.selector, endpoint, nil, now))
+		})
+	}
+}
+
+func TestValidateEdgeGroupSelector(t *testing.T) {
+	invalid := []portainer.EdgeGroupSelector{
+		{Operator: "xor"},
+		{Operator: portainer.EdgeGroupSelectorAnd},
+		{Operator: portainer.EdgeGroupSelectorNot, Selectors: []portainer.EdgeGroupSelector{
+			{Field: portainer.EdgeGroupSelectorName, Value: "a"},
+			{Field: portainer.EdgeGroupSelectorName, Value: "b"},
+		}},
+		{Field: "unknown", Value: "a"},
+		{Field: portainer.EdgeGroupSelectorName, Value: "["},
+		{Field: portainer.EdgeGroupSelectorGroupID, Value: "one"},
+		{Field: portainer.EdgeGroupSelectorAgentVersion, Value: "not a version"},
+		{Field: portainer.EdgeGroupSelectorPlatform, Value: "windows"},
+		{Field: portainer.EdgeGroupSelectorLastCheckInAge, Value: "yesterday"},
+		{Field: portainer.EdgeGroupSelectorLabel, Value: "paris"},
+	}
+
+	for _, selector := range invalid {
+		require.Error(t, ValidateEdgeGroupSelector(&selector), "selector %+v should be invalid", selector)
+	}
+}
+
+func TestEdgeGroupRelatedEndpointsWithSelector(t *testing.T) {
+	endpoints := []portainer.Endpoint{
+		{ID: 1, Name: "paris-1", Type: portainer.EdgeAgentOnDockerEnvironment, TagIDs: []portainer.TagID{1}},
+		{ID: 2, Name: "paris-2", Type: portainer.EdgeAgentOnDockerEnvironment},
+		{ID: 3, Name: "lyon-1", Type: portainer.EdgeAgentOnDockerEnvironment, TagIDs: []portainer.TagID{1}},
+		{ID: 4, Name: "paris-3", Type: portainer.AgentOnDockerEnvironment, TagIDs: []portainer.TagID{1}},
+	}
+
+	edgeGroup := &portainer.EdgeGroup{
+		Dynamic:  true,
+		Selector: &portainer.EdgeGroupSelector{Field: portainer.EdgeGroupSelectorName, Value: "paris-*"},
+	}
+
+	require.ElementsMatch(t, []portainer.EndpointID{1, 2}, EdgeGroupRelatedEndpoints(edgeGroup, endpoints, nil))
+
+	edgeGroup.TagIDs = []portainer.TagID{1}
+	require.ElementsMatch(t, []portainer.EndpointID{1}, EdgeGroupRelatedEndpoints(edgeGroup, endpoints, nil))
+}
+
+func TestMatchEdgeGroupSelectorWithInheritedLabels(t *testing.T) {
+	endpoint := &portainer.Endpoint{
+		ID:     1,
+		Labels: []portainer.Pair{{Name: "site", Value: "paris"}},
+	}
+
+	endpointGroup := &portainer.EndpointGroup{
+		EnvironmentLabels: []portainer.Pair{{Name: "site", Value: "lyon"}, {Name: "owner", Value: "team-x"}},
+	}
+
+	owner := portainer.EdgeGroupSelector{Field: portainer.EdgeGroupSelectorLabel, Key: "owner", Value: "team-x"}
+	require.True(t, MatchEdgeGroupSelector(&owner, endpoint, endpointGroup, time.Now()))
+	require.False(t, MatchEdgeGroupSelector(&owner, endpoint, nil, time.Now()))
+
+	site := portainer.EdgeGroupSelector{Field: portainer.EdgeGroupSelectorLabel, Key: "site", Value: "lyon"}
+	require.False(t, MatchEdgeGroupSelector(&site, endpoint, endpointGroup, time.Now()))
+}
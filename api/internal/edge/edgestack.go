@@ -65,3 +65,20 @@ func FetchEndpointRelationsConfig(tx dataservices.DataStoreTx) (*EndpointRelatio
 		EdgeGroups:     edgeGroups,
 	}, nil
 }
+
+// EdgeStackRollbackTarget returns the version an environment has been instructed to roll back to,
+// or nil when no rollback is in effect. The statuses are cleared on every new version of the stack,
+// so a rollback only applies to the version that failed.
+func EdgeStackRollbackTarget(status *portainer.EdgeStackStatusForEnv) *int {
+	if status == nil {
+		return nil
+	}
+
+	for _, s := range status.Status {
+		if s.Type == portainer.EdgeStackStatusRollingBack && s.RollbackTo != nil {
+			return s.RollbackTo
+		}
+	}
+
+	return nil
+}
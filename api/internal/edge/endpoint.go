@@ -5,7 +5,10 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	"github.com/portainer/portainer/api/set"
 
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
@@ -32,6 +35,42 @@ func EndpointRelatedEdgeStacks(endpoint *portainer.Endpoint, endpointGroup *port
 	return relatedEdgeStacks
 }
 
+// UpdateEndpointRelatedEdgeStacks recomputes the Edge stacks related to an Edge environment(endpoint), it must be
+// called when a property matched by the edge groups changes, such as its tags, labels, name or agent version
+func UpdateEndpointRelatedEdgeStacks(tx dataservices.DataStoreTx, endpoint *portainer.Endpoint) error {
+	if !endpointutils.IsEdgeEndpoint(endpoint) {
+		return nil
+	}
+
+	relation, err := tx.EndpointRelation().EndpointRelation(endpoint.ID)
+	if err != nil {
+		return errors.WithMessage(err, "Unable to retrieve environment relation inside the database")
+	}
+
+	endpointGroup, err := tx.EndpointGroup().Read(endpoint.GroupID)
+	if err != nil {
+		return errors.WithMessage(err, "Unable to find environment group inside the database")
+	}
+
+	edgeGroups, err := tx.EdgeGroup().ReadAll()
+	if err != nil {
+		return errors.WithMessage(err, "Unable to retrieve edge groups from the database")
+	}
+
+	edgeStacks, err := tx.EdgeStack().EdgeStacks()
+	if err != nil {
+		return errors.WithMessage(err, "Unable to retrieve edge stacks from the database")
+	}
+
+	relation.EdgeStacks = set.ToSet(EndpointRelatedEdgeStacks(endpoint, endpointGroup, edgeGroups, edgeStacks))
+
+	if err := tx.EndpointRelation().UpdateEndpointRelation(endpoint.ID, relation); err != nil {
+		return errors.WithMessage(err, "Unable to persist environment relation changes inside the database")
+	}
+
+	return nil
+}
+
 func EffectiveCheckinInterval(tx dataservices.DataStoreTx, endpoint *portainer.Endpoint) int {
 	if endpoint.EdgeCheckinInterval != 0 {
 		return endpoint.EdgeCheckinInterval
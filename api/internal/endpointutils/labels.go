@@ -0,0 +1,91 @@
+package endpointutils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// LabelEnvPrefix is the prefix of the environment variables exposing the environment(endpoint) labels to stacks
+const LabelEnvPrefix = "PORTAINER_LABEL_"
+
+var labelKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+var labelEnvNameReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// ValidateLabels returns an error when a label key is empty, invalid or duplicated, including the keys which would
+// be exposed to stacks under the same environment variable such as "site.a" and "site-a"
+func ValidateLabels(labels []portainer.Pair) error {
+	keys := make(map[string]string, len(labels))
+
+	for _, label := range labels {
+		if label.Name == "" {
+			return errors.New("label key is mandatory")
+		}
+
+		if !labelKeyRegex.MatchString(label.Name) {
+			return fmt.Errorf("invalid label key %q, it must only contain alphanumeric characters, dots, hyphens or underscores", label.Name)
+		}
+
+		envName := labelEnvName(label.Name)
+		if key, ok := keys[envName]; ok {
+			if key == label.Name {
+				return fmt.Errorf("duplicate label key %q", label.Name)
+			}
+
+			return fmt.Errorf("label keys %q and %q are both exposed as %s", key, label.Name, envName)
+		}
+
+		keys[envName] = label.Name
+	}
+
+	return nil
+}
+
+// EndpointLabels returns the labels of the environment(endpoint) merged with the ones inherited from its group,
+// the environment(endpoint) labels taking precedence
+func EndpointLabels(endpoint *portainer.Endpoint, endpointGroup *portainer.EndpointGroup) []portainer.Pair {
+	if endpointGroup == nil || len(endpointGroup.EnvironmentLabels) == 0 {
+		return endpoint.Labels
+	}
+
+	labels := make([]portainer.Pair, 0, len(endpoint.Labels)+len(endpointGroup.EnvironmentLabels))
+	for _, label := range endpointGroup.EnvironmentLabels {
+		if _, ok := LabelValue(endpoint.Labels, label.Name); !ok {
+			labels = append(labels, label)
+		}
+	}
+
+	return append(labels, endpoint.Labels...)
+}
+
+// LabelValue returns the value of the label with the given key
+func LabelValue(labels []portainer.Pair, key string) (string, bool) {
+	for _, label := range labels {
+		if label.Name == key {
+			return label.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// LabelsEnv converts labels to environment variables, "site" becoming PORTAINER_LABEL_SITE
+func LabelsEnv(labels []portainer.Pair) []portainer.Pair {
+	env := make([]portainer.Pair, 0, len(labels))
+	for _, label := range labels {
+		env = append(env, portainer.Pair{
+			Name:  labelEnvName(label.Name),
+			Value: label.Value,
+		})
+	}
+
+	return env
+}
+
+func labelEnvName(key string) string {
+	return LabelEnvPrefix + strings.ToUpper(labelEnvNameReplacer.Replace(key))
+}
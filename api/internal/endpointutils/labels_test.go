@@ -0,0 +1,43 @@
+package endpointutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointLabels(t *testing.T) {
+	endpoint := &portainer.Endpoint{Labels: []portainer.Pair{{Name: "site", Value: "paris"}}}
+	endpointGroup := &portainer.EndpointGroup{EnvironmentLabels: []portainer.Pair{
+		{Name: "site", Value: "lyon"},
+		{Name: "owner", Value: "team-x"},
+	}}
+
+	require.Equal(t, []portainer.Pair{
+		{Name: "owner", Value: "team-x"},
+		{Name: "site", Value: "paris"},
+	}, EndpointLabels(endpoint, endpointGroup))
+
+	require.Equal(t, endpoint.Labels, EndpointLabels(endpoint, nil))
+}
+
+func TestValidateLabels(t *testing.T) {
+	require.NoError(t, ValidateLabels([]portainer.Pair{{Name: "site", Value: "paris"}, {Name: "team.owner-id", Value: ""}}))
+
+	require.Error(t, ValidateLabels([]portainer.Pair{{Name: "", Value: "paris"}}))
+	require.Error(t, ValidateLabels([]portainer.Pair{{Name: "site:id", Value: "paris"}}))
+	require.Error(t, ValidateLabels([]portainer.Pair{{Name: "site", Value: "paris"}, {Name: "site", Value: "lyon"}}))
+
+	// keys exposed to stacks under the same environment variable
+	require.Error(t, ValidateLabels([]portainer.Pair{{Name: "site.a", Value: "paris"}, {Name: "site-a", Value: "lyon"}}))
+	require.Error(t, ValidateLabels([]portainer.Pair{{Name: "site_a", Value: "paris"}, {Name: "SITE_A", Value: "lyon"}}))
+}
+
+func TestLabelsEnv(t *testing.T) {
+	require.Equal(t, []portainer.Pair{
+		{Name: "PORTAINER_LABEL_SITE", Value: "paris"},
+		{Name: "PORTAINER_LABEL_TEAM_OWNER_ID", Value: "x"},
+	}, LabelsEnv([]portainer.Pair{{Name: "site", Value: "paris"}, {Name: "team.owner-id", Value: "x"}}))
+}
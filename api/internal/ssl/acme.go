@@ -0,0 +1,523 @@
+package ssl
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/scheduler"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	// ACMEChallengePathPrefix is the path under which the http-01 challenges are served
+	ACMEChallengePathPrefix = "/.well-known/acme-challenge/"
+
+	defaultACMERenewBeforeDays = 30
+	acmeRenewalCheckInterval   = 12 * time.Hour
+	acmeOrderTimeout           = 10 * time.Minute
+)
+
+var ErrACMEDisabled = errors.New("ACME is not enabled")
+
+// ValidateACMESettings checks the ACME settings before they are persisted
+func ValidateACMESettings(settings *portainer.ACMESettings) error {
+	if !settings.Enabled {
+		return nil
+	}
+
+	if len(settings.Domains) == 0 {
+		return errors.New("at least one domain is required")
+	}
+
+	for _, domain := range settings.Domains {
+		if domain == "" || strings.ContainsAny(domain, " /:") {
+			return fmt.Errorf("invalid domain %q", domain)
+		}
+
+		if strings.HasPrefix(domain, "*.") && settings.ChallengeType != portainer.ACMEChallengeDNS01 {
+			return fmt.Errorf("wildcard domain %q requires the %s challenge", domain, portainer.ACMEChallengeDNS01)
+		}
+	}
+
+	if settings.RenewBeforeDays < 0 {
+		return errors.New("renewBeforeDays cannot be negative")
+	}
+
+	switch settings.ChallengeType {
+	case portainer.ACMEChallengeHTTP01:
+	case portainer.ACMEChallengeDNS01:
+		if _, err := newDNSProvider(settings.DNSProvider, settings.DNSProviderConfig); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported challenge type %q", settings.ChallengeType)
+	}
+
+	return nil
+}
+
+// SetACMESettings persists the ACME settings and requests a certificate in the background when the current one does
+// not match them, the outcome of the order is reported through the LastError and NotAfter fields of the settings.
+// The server is restarted instead when the HTTP listener has to be started or stopped for the http-01 challenge.
+func (service *Service) SetACMESettings(acmeSettings portainer.ACMESettings) error {
+	if acmeSettings.DirectoryURL == "" {
+		acmeSettings.DirectoryURL = acme.LetsEncryptURL
+	}
+
+	if acmeSettings.RenewBeforeDays == 0 {
+		acmeSettings.RenewBeforeDays = defaultACMERenewBeforeDays
+	}
+
+	if err := ValidateACMESettings(&acmeSettings); err != nil {
+		return err
+	}
+
+	settings, err := service.GetSSLSettings()
+	if err != nil {
+		return err
+	}
+
+	listenerRequired := service.ACMEHTTPChallengeEnabled()
+
+	if previous := settings.ACME; previous != nil {
+		acmeSettings.NotAfter = previous.NotAfter
+		acmeSettings.LastRenewal = previous.LastRenewal
+
+		// the provider configuration is not returned to the clients, keep it when it is not sent again
+		if acmeSettings.DNSProviderConfig == nil && acmeSettings.DNSProvider == previous.DNSProvider {
+			acmeSettings.DNSProviderConfig = previous.DNSProviderConfig
+		}
+	}
+
+	acmeSettings.LastError = ""
+
+	restartRequired := !settings.HTTPEnabled && listenerRequired != (acmeSettings.Enabled && acmeSettings.ChallengeType == portainer.ACMEChallengeHTTP01)
+
+	settings.ACME = &acmeSettings
+
+	service.acmeMu.Lock()
+	err = service.dataStore.SSLSettings().UpdateSettings(settings)
+	service.acmeMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if restartRequired {
+		// the challenge cannot be answered before the listener is started, the certificate is requested by the
+		// renewal check once the server is restarted
+		service.shutdownTrigger()
+
+		return nil
+	}
+
+	if acmeSettings.Enabled {
+		// the order, including the DNS propagation waits, outlives the request updating the settings
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), acmeOrderTimeout)
+			defer cancel()
+
+			if err := service.RenewACMECertificate(ctx, false); err != nil {
+				log.Error().Err(err).Msg("unable to obtain the ACME certificate")
+			}
+		}()
+	}
+
+	return nil
+}
+
+// ACMEHTTPChallengeEnabled returns true when the certificate is requested with the http-01 challenge
+func (service *Service) ACMEHTTPChallengeEnabled() bool {
+	settings, err := service.GetSSLSettings()
+	if err != nil {
+		return false
+	}
+
+	return settings.ACME != nil && settings.ACME.Enabled && settings.ACME.ChallengeType == portainer.ACMEChallengeHTTP01
+}
+
+// StartACMERenewal checks the certificate right away and then periodically, renewing it when it expires soon
+func (service *Service) StartACMERenewal(s *scheduler.Scheduler) {
+	renew := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), acmeOrderTimeout)
+		defer cancel()
+
+		return service.RenewACMECertificate(ctx, false)
+	}
+
+	go func() {
+		if err := renew(); err != nil {
+			log.Error().Err(err).Msg("unable to obtain the ACME certificate")
+		}
+	}()
+
+	s.StartJobEvery(acmeRenewalCheckInterval, renew)
+}
+
+// RenewACMECertificate requests a new certificate from the ACME server and loads it without restarting the server.
+// Unless force is set, nothing is done while the current certificate matches the domains and is not about to expire.
+func (service *Service) RenewACMECertificate(ctx context.Context, force bool) error {
+	service.acmeMu.Lock()
+	defer service.acmeMu.Unlock()
+
+	settings, err := service.GetSSLSettings()
+	if err != nil {
+		return err
+	}
+
+	if settings.ACME == nil || !settings.ACME.Enabled {
+		if force {
+			return ErrACMEDisabled
+		}
+
+		return nil
+	}
+
+	acmeSettings := *settings.ACME
+	if err := service.renewACMECertificate(ctx, &acmeSettings, force); err != nil {
+		if updateErr := service.updateACMEStatus(func(status *portainer.ACMESettings) {
+			status.LastError = err.Error()
+		}); updateErr != nil {
+			log.Warn().Err(updateErr).Msg("unable to persist the ACME error")
+		}
+
+		return err
+	}
+
+	return service.updateACMEStatus(func(status *portainer.ACMESettings) {
+		status.NotAfter = acmeSettings.NotAfter
+		status.LastRenewal = acmeSettings.LastRenewal
+		status.LastError = ""
+	})
+}
+
+// renewACMECertificate obtains and installs a certificate matching the ACME settings, their status is updated but
+// they are not persisted. The caller must hold acmeMu.
+func (service *Service) renewACMECertificate(ctx context.Context, acmeSettings *portainer.ACMESettings, force bool) error {
+	if service.certSupplied {
+		log.Warn().Msg("the certificate is supplied by the command line flags, skipping the ACME renewal")
+
+		return nil
+	}
+
+	if !force && !acmeRenewalRequired(service.GetRawCertificate(), acmeSettings, time.Now()) {
+		return nil
+	}
+
+	log.Info().Strs("domains", acmeSettings.Domains).Msg("requesting a certificate from the ACME server")
+
+	certPEM, keyPEM, notAfter, err := service.obtainACMECertificate(ctx, acmeSettings)
+	if err != nil {
+		return errors.Wrap(err, "failed obtaining the ACME certificate")
+	}
+
+	certPath, keyPath, err := service.fileService.StoreSSLCertPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	// the HTTPS server reads the cached certificate on every handshake, no restart is required
+	if err := service.cacheInfo(certPath, keyPath, false); err != nil {
+		return err
+	}
+
+	log.Info().Time("not_after", notAfter).Msg("ACME certificate installed")
+
+	acmeSettings.NotAfter = notAfter.Unix()
+	acmeSettings.LastRenewal = time.Now().Unix()
+	acmeSettings.LastError = ""
+
+	return nil
+}
+
+// ACMEChallengeHandler answers the http-01 challenges and passes the other requests to next
+func (service *Service) ACMEChallengeHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.URL.Path, ACMEChallengePathPrefix)
+		if !ok {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		service.httpChallengesMu.RLock()
+		keyAuth, ok := service.httpChallenges[token]
+		service.httpChallengesMu.RUnlock()
+
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(keyAuth))
+	})
+}
+
+// acmeRenewalRequired returns true when cert does not cover the configured domains or expires soon
+func acmeRenewalRequired(cert *tls.Certificate, settings *portainer.ACMESettings, now time.Time) bool {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return true
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return true
+	}
+
+	for _, domain := range settings.Domains {
+		if !slices.Contains(leaf.DNSNames, domain) {
+			return true
+		}
+	}
+
+	renewBefore := settings.RenewBeforeDays
+	if renewBefore == 0 {
+		renewBefore = defaultACMERenewBeforeDays
+	}
+
+	return now.AddDate(0, 0, renewBefore).After(leaf.NotAfter)
+}
+
+func (service *Service) obtainACMECertificate(ctx context.Context, settings *portainer.ACMESettings) ([]byte, []byte, time.Time, error) {
+	accountKey, err := service.acmeAccountKey()
+	if err != nil {
+		return nil, nil, time.Time{}, errors.Wrap(err, "failed loading the ACME account key")
+	}
+
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: settings.DirectoryURL,
+		UserAgent:    "Portainer",
+	}
+
+	account := &acme.Account{}
+	if settings.Email != "" {
+		account.Contact = []string{"mailto:" + settings.Email}
+	}
+
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, nil, time.Time{}, errors.Wrap(err, "failed registering the ACME account")
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(settings.Domains...))
+	if err != nil {
+		return nil, nil, time.Time{}, errors.Wrap(err, "failed creating the ACME order")
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := service.authorize(ctx, client, authzURL, settings); err != nil {
+			return nil, nil, time.Time{}, err
+		}
+	}
+
+	if _, err := client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, nil, time.Time{}, errors.Wrap(err, "the ACME order was not authorized")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: settings.Domains[0]},
+		DNSNames: settings.Domains,
+	}, key)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, time.Time{}, errors.Wrap(err, "failed finalizing the ACME order")
+	}
+
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, nil, time.Time{}, errors.Wrap(err, "invalid certificate returned by the ACME server")
+	}
+
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, leaf.NotAfter, nil
+}
+
+// authorize completes the challenge of a single authorization of the order
+func (service *Service) authorize(ctx context.Context, client *acme.Client, authzURL string, settings *portainer.ACMESettings) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return errors.Wrap(err, "failed fetching the ACME authorization")
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	domain := authz.Identifier.Value
+
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == string(settings.ChallengeType) {
+			challenge = c
+
+			break
+		}
+	}
+
+	if challenge == nil {
+		return fmt.Errorf("the ACME server does not offer the %s challenge for %s", settings.ChallengeType, domain)
+	}
+
+	cleanUp, err := service.presentChallenge(ctx, client, domain, challenge, settings)
+	if err != nil {
+		return errors.Wrapf(err, "failed preparing the %s challenge for %s", challenge.Type, domain)
+	}
+	defer cleanUp()
+
+	if _, err := client.Accept(ctx, challenge); err != nil {
+		return errors.Wrapf(err, "failed accepting the %s challenge for %s", challenge.Type, domain)
+	}
+
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return errors.Wrapf(err, "the %s challenge failed for %s", challenge.Type, domain)
+	}
+
+	return nil
+}
+
+// presentChallenge makes the challenge response available to the ACME server and returns a function removing it
+func (service *Service) presentChallenge(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge, settings *portainer.ACMESettings) (func(), error) {
+	switch challenge.Type {
+	case string(portainer.ACMEChallengeHTTP01):
+		keyAuth, err := client.HTTP01ChallengeResponse(challenge.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		service.httpChallengesMu.Lock()
+		service.httpChallenges[challenge.Token] = keyAuth
+		service.httpChallengesMu.Unlock()
+
+		return func() {
+			service.httpChallengesMu.Lock()
+			delete(service.httpChallenges, challenge.Token)
+			service.httpChallengesMu.Unlock()
+		}, nil
+
+	case string(portainer.ACMEChallengeDNS01):
+		provider, err := newDNSProvider(settings.DNSProvider, settings.DNSProviderConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := client.DNS01ChallengeRecord(challenge.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		fqdn := "_acme-challenge." + domain
+		if err := provider.Present(ctx, fqdn, value); err != nil {
+			return nil, err
+		}
+
+		return func() {
+			if err := provider.CleanUp(context.WithoutCancel(ctx), fqdn, value); err != nil {
+				log.Warn().Err(err).Str("record", fqdn).Msg("unable to remove the ACME challenge record")
+			}
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported challenge type %q", challenge.Type)
+}
+
+// acmeAccountKey loads the ACME account key, generating it on first use
+func (service *Service) acmeAccountKey() (crypto.Signer, error) {
+	keyPath := service.fileService.GetDefaultACMEAccountKeyPath()
+
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("invalid ACME account key file")
+		}
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.fileService.StoreACMEAccountKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (service *Service) updateACMEStatus(update func(*portainer.ACMESettings)) error {
+	settings, err := service.GetSSLSettings()
+	if err != nil {
+		return err
+	}
+
+	if settings.ACME == nil {
+		return nil
+	}
+
+	update(settings.ACME)
+
+	return service.dataStore.SSLSettings().UpdateSettings(settings)
+}
+
+func (service *Service) disableACME() error {
+	settings, err := service.GetSSLSettings()
+	if err != nil {
+		return err
+	}
+
+	if settings.ACME == nil || !settings.ACME.Enabled {
+		return nil
+	}
+
+	settings.ACME.Enabled = false
+
+	return service.dataStore.SSLSettings().UpdateSettings(settings)
+}
@@ -0,0 +1,486 @@
+package ssl
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeACMEServer is a minimal RFC 8555 server, signatures are not verified
+type fakeACMEServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	thumbprint string
+	authzs     []*fakeAuthz
+	finalized  bool
+	chain      []byte
+	orders     int
+	caKey      *ecdsa.PrivateKey
+	caCert     *x509.Certificate
+	validate   func(challengeType, domain, token, keyAuth string) bool
+	validUntil time.Time
+}
+
+type fakeAuthz struct {
+	domain string
+	token  string
+	status string
+}
+
+func newFakeACMEServer(t *testing.T, validate func(challengeType, domain, token, keyAuth string) bool) *fakeACMEServer {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake ACME CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	s := &fakeACMEServer{caKey: caKey, caCert: caCert, validate: validate, validUntil: time.Now().AddDate(0, 0, 90)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *fakeACMEServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+
+	if r.URL.Path == "/dir" {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   s.URL + "/nonce",
+			"newAccount": s.URL + "/account",
+			"newOrder":   s.URL + "/order",
+		})
+
+		return
+	}
+
+	if r.URL.Path == "/nonce" {
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+
+	switch {
+	case r.URL.Path == "/account":
+		status := http.StatusCreated
+		if s.thumbprint != "" {
+			status = http.StatusOK
+		}
+
+		protected, _ := base64.RawURLEncoding.DecodeString(jws.Protected)
+
+		var header struct {
+			JWK struct {
+				Crv string `json:"crv"`
+				Kty string `json:"kty"`
+				X   string `json:"x"`
+				Y   string `json:"y"`
+			} `json:"jwk"`
+		}
+		json.Unmarshal(protected, &header)
+
+		jwk := fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, header.JWK.Crv, header.JWK.Kty, header.JWK.X, header.JWK.Y)
+		sum := sha256.Sum256([]byte(jwk))
+		s.thumbprint = base64.RawURLEncoding.EncodeToString(sum[:])
+
+		w.Header().Set("Location", s.URL+"/account/1")
+		writeJSON(w, status, map[string]string{"status": "valid"})
+
+	case r.URL.Path == "/order":
+		var req struct {
+			Identifiers []struct{ Value string } `json:"identifiers"`
+		}
+		json.Unmarshal(payload, &req)
+
+		s.orders++
+		s.finalized = false
+		s.authzs = nil
+
+		for i, id := range req.Identifiers {
+			s.authzs = append(s.authzs, &fakeAuthz{domain: id.Value, token: fmt.Sprintf("token-%d-%d", s.orders, i), status: "pending"})
+		}
+
+		w.Header().Set("Location", s.URL+"/order/1")
+		writeJSON(w, http.StatusCreated, s.order())
+
+	case r.URL.Path == "/order/1":
+		w.Header().Set("Location", s.URL+"/order/1")
+		writeJSON(w, http.StatusOK, s.order())
+
+	case strings.HasPrefix(r.URL.Path, "/authz/"):
+		var i int
+		fmt.Sscanf(r.URL.Path, "/authz/%d", &i)
+
+		writeJSON(w, http.StatusOK, s.authorization(i))
+
+	case strings.HasPrefix(r.URL.Path, "/chal/"):
+		var i int
+		var challengeType string
+		fmt.Sscanf(strings.ReplaceAll(r.URL.Path, "/", " "), " chal %d %s", &i, &challengeType)
+
+		authz := s.authzs[i]
+		authz.status = "invalid"
+		if s.validate(challengeType, authz.domain, authz.token, authz.token+"."+s.thumbprint) {
+			authz.status = "valid"
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"type": challengeType, "url": s.URL + r.URL.Path, "token": authz.token, "status": authz.status})
+
+	case r.URL.Path == "/finalize/1":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		json.Unmarshal(payload, &req)
+
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(s.orders + 1)),
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     s.validUntil,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+
+		certDER, err := x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		s.chain = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})...)
+		s.finalized = true
+
+		w.Header().Set("Location", s.URL+"/order/1")
+		writeJSON(w, http.StatusOK, s.order())
+
+	case r.URL.Path == "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(s.chain)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeACMEServer) order() map[string]any {
+	status := "ready"
+	authorizations := []string{}
+
+	for i, authz := range s.authzs {
+		authorizations = append(authorizations, fmt.Sprintf("%s/authz/%d", s.URL, i))
+
+		switch {
+		case authz.status == "invalid":
+			status = "invalid"
+		case authz.status == "pending" && status == "ready":
+			status = "pending"
+		}
+	}
+
+	order := map[string]any{
+		"status":         status,
+		"authorizations": authorizations,
+		"finalize":       s.URL + "/finalize/1",
+	}
+
+	if s.finalized {
+		order["status"] = "valid"
+		order["certificate"] = s.URL + "/cert/1"
+	}
+
+	return order
+}
+
+func (s *fakeACMEServer) authorization(i int) map[string]any {
+	authz := s.authzs[i]
+
+	challenges := []map[string]string{}
+	for _, challengeType := range []string{"http-01", "dns-01"} {
+		challenges = append(challenges, map[string]string{
+			"type":   challengeType,
+			"url":    fmt.Sprintf("%s/chal/%d/%s", s.URL, i, challengeType),
+			"token":  authz.token,
+			"status": "pending",
+		})
+	}
+
+	return map[string]any{
+		"status":     authz.status,
+		"identifier": map[string]string{"type": "dns", "value": authz.domain},
+		"challenges": challenges,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestService(t *testing.T) *Service {
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	service := NewService(fileService, store, func() {})
+	require.NoError(t, service.Init("127.0.0.1", "", ""))
+
+	settings, err := service.GetSSLSettings()
+	require.NoError(t, err)
+	settings.HTTPEnabled = true
+	require.NoError(t, store.SSLSettings().UpdateSettings(settings))
+
+	return service
+}
+
+func leafCertificate(t *testing.T, cert *tls.Certificate) *x509.Certificate {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf
+}
+
+func TestACMEHTTP01(t *testing.T) {
+	service := newTestService(t)
+
+	// stands for the HTTP listener the ACME server reaches on port 80
+	listener := httptest.NewServer(service.ACMEChallengeHandler(http.NotFoundHandler()))
+	defer listener.Close()
+
+	acmeServer := newFakeACMEServer(t, func(challengeType, domain, token, keyAuth string) bool {
+		if challengeType != "http-01" {
+			return false
+		}
+
+		resp, err := http.Get(listener.URL + ACMEChallengePathPrefix + token)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		return resp.StatusCode == http.StatusOK && string(body) == keyAuth
+	})
+
+	err := service.SetACMESettings(portainer.ACMESettings{
+		Enabled:       true,
+		DirectoryURL:  acmeServer.URL + "/dir",
+		Email:         "admin@example.com",
+		Domains:       []string{"portainer.example.com", "www.example.com"},
+		ChallengeType: portainer.ACMEChallengeHTTP01,
+	})
+	require.NoError(t, err)
+
+	// the certificate is requested in the background once the settings are saved
+	waitACMEStatus(t, service, func(acmeSettings *portainer.ACMESettings) bool { return acmeSettings.NotAfter != 0 })
+
+	leaf := leafCertificate(t, service.GetRawCertificate())
+	require.ElementsMatch(t, []string{"portainer.example.com", "www.example.com"}, leaf.DNSNames)
+	require.Equal(t, "fake ACME CA", leaf.Issuer.CommonName)
+
+	settings, err := service.GetSSLSettings()
+	require.NoError(t, err)
+	require.False(t, settings.SelfSigned)
+	require.Equal(t, leaf.NotAfter.Unix(), settings.ACME.NotAfter)
+	require.NotZero(t, settings.ACME.LastRenewal)
+	require.Empty(t, settings.ACME.LastError)
+
+	// the challenge tokens are removed once the order is complete
+	require.Empty(t, service.httpChallenges)
+
+	// the certificate is still valid for long enough
+	require.NoError(t, service.RenewACMECertificate(context.Background(), false))
+	require.Equal(t, 1, acmeServer.orders)
+
+	require.NoError(t, service.RenewACMECertificate(context.Background(), true))
+	require.Equal(t, 2, acmeServer.orders)
+	require.NotEqual(t, leaf.SerialNumber, leafCertificate(t, service.GetRawCertificate()).SerialNumber)
+}
+
+type memoryDNSProvider struct {
+	mu      sync.Mutex
+	records map[string]string
+}
+
+func (p *memoryDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records[fqdn] = value
+
+	return nil
+}
+
+func (p *memoryDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.records, fqdn)
+
+	return nil
+}
+
+func TestACMEDNS01(t *testing.T) {
+	service := newTestService(t)
+
+	provider := &memoryDNSProvider{records: make(map[string]string)}
+	RegisterDNSProvider("memory", func(config map[string]string) (DNSProvider, error) {
+		return provider, nil
+	})
+
+	acmeServer := newFakeACMEServer(t, func(challengeType, domain, token, keyAuth string) bool {
+		sum := sha256.Sum256([]byte(keyAuth))
+
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+
+		return challengeType == "dns-01" && provider.records["_acme-challenge."+domain] == base64.RawURLEncoding.EncodeToString(sum[:])
+	})
+
+	err := service.SetACMESettings(portainer.ACMESettings{
+		Enabled:       true,
+		DirectoryURL:  acmeServer.URL + "/dir",
+		Domains:       []string{"*.example.com"},
+		ChallengeType: portainer.ACMEChallengeDNS01,
+		DNSProvider:   "memory",
+	})
+	require.NoError(t, err)
+
+	waitACMEStatus(t, service, func(acmeSettings *portainer.ACMESettings) bool { return acmeSettings.NotAfter != 0 })
+
+	require.Equal(t, []string{"*.example.com"}, leafCertificate(t, service.GetRawCertificate()).DNSNames)
+	require.Empty(t, provider.records)
+}
+
+func TestACMEFailureKeepsCertificate(t *testing.T) {
+	service := newTestService(t)
+	previous := service.GetRawCertificate()
+
+	acmeServer := newFakeACMEServer(t, func(challengeType, domain, token, keyAuth string) bool {
+		return false
+	})
+
+	err := service.SetACMESettings(portainer.ACMESettings{
+		Enabled:       true,
+		DirectoryURL:  acmeServer.URL + "/dir",
+		Domains:       []string{"portainer.example.com"},
+		ChallengeType: portainer.ACMEChallengeHTTP01,
+	})
+	require.NoError(t, err)
+
+	// the failure of the order is reported by the saved settings
+	waitACMEStatus(t, service, func(acmeSettings *portainer.ACMESettings) bool { return acmeSettings.LastError != "" })
+	require.Same(t, previous, service.GetRawCertificate())
+
+	settings, err := service.GetSSLSettings()
+	require.NoError(t, err)
+	require.Zero(t, settings.ACME.NotAfter)
+	require.True(t, settings.SelfSigned)
+}
+
+// waitACMEStatus waits for the saved ACME settings to satisfy done
+func waitACMEStatus(t *testing.T, service *Service, done func(*portainer.ACMESettings) bool) {
+	require.Eventually(t, func() bool {
+		settings, err := service.GetSSLSettings()
+
+		return err == nil && settings.ACME != nil && done(settings.ACME)
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestACMERenewalRequired(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	newCert := func(notAfter time.Time, domains ...string) *tls.Certificate {
+		template := &x509.Certificate{SerialNumber: big.NewInt(1), DNSNames: domains, NotBefore: time.Now(), NotAfter: notAfter}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		require.NoError(t, err)
+
+		return &tls.Certificate{Certificate: [][]byte{der}}
+	}
+
+	now := time.Now()
+	settings := &portainer.ACMESettings{Domains: []string{"a.example.com"}, RenewBeforeDays: 30}
+
+	require.True(t, acmeRenewalRequired(nil, settings, now))
+	require.False(t, acmeRenewalRequired(newCert(now.AddDate(0, 0, 60), "a.example.com"), settings, now))
+	require.True(t, acmeRenewalRequired(newCert(now.AddDate(0, 0, 20), "a.example.com"), settings, now))
+	require.True(t, acmeRenewalRequired(newCert(now.AddDate(0, 0, 60), "b.example.com"), settings, now))
+}
+
+func TestValidateACMESettings(t *testing.T) {
+	require.NoError(t, ValidateACMESettings(&portainer.ACMESettings{}))
+
+	require.Error(t, ValidateACMESettings(&portainer.ACMESettings{Enabled: true, ChallengeType: portainer.ACMEChallengeHTTP01}))
+	require.Error(t, ValidateACMESettings(&portainer.ACMESettings{Enabled: true, Domains: []string{"*.example.com"}, ChallengeType: portainer.ACMEChallengeHTTP01}))
+	require.Error(t, ValidateACMESettings(&portainer.ACMESettings{Enabled: true, Domains: []string{"example.com"}, ChallengeType: "tls-alpn-01"}))
+	require.Error(t, ValidateACMESettings(&portainer.ACMESettings{Enabled: true, Domains: []string{"example.com"}, ChallengeType: portainer.ACMEChallengeDNS01, DNSProvider: "unknown"}))
+	require.Error(t, ValidateACMESettings(&portainer.ACMESettings{Enabled: true, Domains: []string{"example.com"}, ChallengeType: portainer.ACMEChallengeDNS01, DNSProvider: "exec", DNSProviderConfig: map[string]string{"command": "/bin/true"}}))
+	require.Error(t, ValidateACMESettings(&portainer.ACMESettings{Enabled: true, Domains: []string{"example.com"}, ChallengeType: portainer.ACMEChallengeDNS01, DNSProvider: "cloudflare"}))
+	require.NoError(t, ValidateACMESettings(&portainer.ACMESettings{Enabled: true, Domains: []string{"example.com"}, ChallengeType: portainer.ACMEChallengeDNS01, DNSProvider: "cloudflare", DNSProviderConfig: map[string]string{"apiToken": "token", "zoneId": "zone"}}))
+}
@@ -0,0 +1,144 @@
+package ssl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const cloudflareAPIURL = "https://api.cloudflare.com/client/v4"
+
+// cloudflareDNSProvider creates the records through the Cloudflare API, it is configured with an API token
+// allowed to edit the DNS records of the zone
+type cloudflareDNSProvider struct {
+	baseURL     string
+	apiToken    string
+	zoneID      string
+	propagation time.Duration
+	client      *http.Client
+
+	mu sync.Mutex
+	// identifiers of the created records, keyed by name and value
+	records map[string]string
+}
+
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result struct {
+		ID string `json:"id"`
+	} `json:"result"`
+}
+
+func newCloudflareDNSProvider(config map[string]string) (DNSProvider, error) {
+	provider := &cloudflareDNSProvider{
+		baseURL:  cloudflareAPIURL,
+		apiToken: config["apiToken"],
+		zoneID:   config["zoneId"],
+		client:   &http.Client{Timeout: 30 * time.Second},
+		records:  make(map[string]string),
+	}
+
+	if provider.apiToken == "" || provider.zoneID == "" {
+		return nil, errors.New("the cloudflare DNS provider requires an apiToken and a zoneId")
+	}
+
+	propagation, err := propagationDelay(config)
+	if err != nil {
+		return nil, err
+	}
+	provider.propagation = propagation
+
+	return provider, nil
+}
+
+func (provider *cloudflareDNSProvider) Present(ctx context.Context, fqdn, value string) error {
+	record := map[string]any{
+		"type":    "TXT",
+		"name":    strings.TrimSuffix(fqdn, "."),
+		"content": value,
+		"ttl":     120,
+	}
+
+	result, err := provider.do(ctx, http.MethodPost, "dns_records", record)
+	if err != nil {
+		return err
+	}
+
+	provider.mu.Lock()
+	provider.records[fqdn+" "+value] = result.Result.ID
+	provider.mu.Unlock()
+
+	// give the record the time to reach the authoritative servers
+	select {
+	case <-time.After(provider.propagation):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (provider *cloudflareDNSProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	provider.mu.Lock()
+	id, ok := provider.records[fqdn+" "+value]
+	delete(provider.records, fqdn+" "+value)
+	provider.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	_, err := provider.do(ctx, http.MethodDelete, "dns_records/"+url.PathEscape(id), nil)
+
+	return err
+}
+
+func (provider *cloudflareDNSProvider) do(ctx context.Context, method, path string, payload any) (*cloudflareResponse, error) {
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	endpoint := fmt.Sprintf("%s/zones/%s/%s", provider.baseURL, url.PathEscape(provider.zoneID), path)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+provider.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := provider.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "cloudflare API request failed")
+	}
+	defer resp.Body.Close()
+
+	var result cloudflareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.Wrapf(err, "unable to decode the cloudflare API response, status %d", resp.StatusCode)
+	}
+
+	if !result.Success {
+		messages := make([]string, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			messages = append(messages, e.Message)
+		}
+
+		return nil, fmt.Errorf("cloudflare API request failed: %s", strings.Join(messages, ", "))
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,65 @@
+package ssl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudflareDNSProvider(t *testing.T) {
+	records := map[string]map[string]any{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"success": false, "errors": []map[string]string{{"message": "invalid token"}}})
+
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/zone/dns_records":
+			var record map[string]any
+			json.NewDecoder(r.Body).Decode(&record)
+			records["record-1"] = record
+
+			json.NewEncoder(w).Encode(map[string]any{"success": true, "result": map[string]string{"id": "record-1"}})
+
+		case r.Method == http.MethodDelete && r.URL.Path == "/zones/zone/dns_records/record-1":
+			delete(records, "record-1")
+
+			json.NewEncoder(w).Encode(map[string]any{"success": true, "result": map[string]string{"id": "record-1"}})
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"success": false, "errors": []map[string]string{{"message": "not found"}}})
+		}
+	}))
+	defer server.Close()
+
+	_, err := newCloudflareDNSProvider(map[string]string{"apiToken": "token"})
+	require.Error(t, err)
+
+	dnsProvider, err := newCloudflareDNSProvider(map[string]string{"apiToken": "token", "zoneId": "zone"})
+	require.NoError(t, err)
+
+	provider := dnsProvider.(*cloudflareDNSProvider)
+	provider.baseURL = server.URL
+
+	ctx := context.Background()
+
+	require.NoError(t, provider.Present(ctx, "_acme-challenge.example.com.", "value"))
+	require.Equal(t, "_acme-challenge.example.com", records["record-1"]["name"])
+	require.Equal(t, "TXT", records["record-1"]["type"])
+	require.Equal(t, "value", records["record-1"]["content"])
+
+	require.NoError(t, provider.CleanUp(ctx, "_acme-challenge.example.com.", "value"))
+	require.Empty(t, records)
+
+	provider.apiToken = "invalid"
+	require.ErrorContains(t, provider.Present(ctx, "_acme-challenge.example.com.", "value"), "invalid token")
+}
@@ -0,0 +1,78 @@
+package ssl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DNSProvider creates and removes the TXT records used by the ACME dns-01 challenge
+type DNSProvider interface {
+	// Present creates a TXT record named fqdn with value
+	Present(ctx context.Context, fqdn, value string) error
+	// CleanUp removes the TXT record created by Present
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// DNSProviderFactory creates a DNS provider from the configuration stored in the ACME settings
+type DNSProviderFactory func(config map[string]string) (DNSProvider, error)
+
+var (
+	dnsProvidersMu sync.RWMutex
+	dnsProviders   = map[string]DNSProviderFactory{
+		"cloudflare": newCloudflareDNSProvider,
+	}
+)
+
+// RegisterDNSProvider makes a DNS provider available to the dns-01 challenge under name
+func RegisterDNSProvider(name string, factory DNSProviderFactory) {
+	dnsProvidersMu.Lock()
+	defer dnsProvidersMu.Unlock()
+
+	dnsProviders[name] = factory
+}
+
+// DNSProviders returns the names of the registered DNS providers
+func DNSProviders() []string {
+	dnsProvidersMu.RLock()
+	defer dnsProvidersMu.RUnlock()
+
+	names := make([]string, 0, len(dnsProviders))
+	for name := range dnsProviders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func newDNSProvider(name string, config map[string]string) (DNSProvider, error) {
+	dnsProvidersMu.RLock()
+	factory, ok := dnsProviders[name]
+	dnsProvidersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS provider %q", name)
+	}
+
+	return factory(config)
+}
+
+// propagationDelay reads the time given to the records to reach the authoritative servers
+func propagationDelay(config map[string]string) (time.Duration, error) {
+	value := config["propagationSeconds"]
+	if value == "" {
+		return 0, nil
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid propagationSeconds %q", value)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"os"
+	"sync"
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
@@ -19,7 +20,16 @@ type Service struct {
 	fileService     portainer.FileService
 	dataStore       dataservices.DataStore
 	rawCert         *tls.Certificate
+	certMu          sync.RWMutex
 	shutdownTrigger context.CancelFunc
+	// certSupplied is set when the certificate comes from the command line flags,
+	// the ACME client leaves it untouched
+	certSupplied bool
+	// acmeMu prevents concurrent ACME orders
+	acmeMu sync.Mutex
+	// httpChallenges maps the pending ACME http-01 tokens to their key authorization
+	httpChallenges   map[string]string
+	httpChallengesMu sync.RWMutex
 }
 
 // NewService returns a pointer to a new Service
@@ -28,6 +38,7 @@ func NewService(fileService portainer.FileService, dataStore dataservices.DataSt
 		fileService:     fileService,
 		dataStore:       dataStore,
 		shutdownTrigger: shutdownTrigger,
+		httpChallenges:  make(map[string]string),
 	}
 }
 
@@ -40,6 +51,8 @@ func (service *Service) Init(host, certPath, keyPath string) error {
 			return errors.Wrap(err, "failed copying supplied certs")
 		}
 
+		service.certSupplied = true
+
 		return service.cacheInfo(newCertPath, newKeyPath, false)
 	}
 
@@ -83,6 +96,9 @@ func generateSelfSignedCertificates(ip, certPath, keyPath string) error {
 
 // GetRawCertificate gets the raw certificate
 func (service *Service) GetRawCertificate() *tls.Certificate {
+	service.certMu.RLock()
+	defer service.certMu.RUnlock()
+
 	return service.rawCert
 }
 
@@ -106,6 +122,11 @@ func (service *Service) SetCertificates(certData, keyData []byte) error {
 		return err
 	}
 
+	// an uploaded certificate replaces the one managed by the ACME client
+	if err := service.disableACME(); err != nil {
+		return err
+	}
+
 	if err := service.cacheInfo(certPath, keyPath, false); err != nil {
 		return err
 	}
@@ -142,7 +163,9 @@ func (service *Service) cacheCertificate(certPath, keyPath string) error {
 		return err
 	}
 
+	service.certMu.Lock()
 	service.rawCert = &rawCert
+	service.certMu.Unlock()
 
 	return nil
 }
@@ -36,3 +36,31 @@ func (manager *composeStackManager) Down(ctx context.Context, stack *portainer.S
 func (manager *composeStackManager) Pull(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, options portainer.ComposeOptions) error {
 	return nil
 }
+
+func (manager *composeStackManager) StartService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return nil
+}
+
+func (manager *composeStackManager) StopService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return nil
+}
+
+func (manager *composeStackManager) RestartService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string) error {
+	return nil
+}
+
+func (manager *composeStackManager) ScaleService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string, replicas int) error {
+	return nil
+}
+
+func (manager *composeStackManager) RecreateService(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, serviceName string, options portainer.ComposeRecreateServiceOptions) error {
+	return nil
+}
+
+func (manager *composeStackManager) Status(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) (*portainer.StackHealth, error) {
+	return &portainer.StackHealth{}, nil
+}
+
+func (manager *composeStackManager) WaitForRunning(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	return nil
+}
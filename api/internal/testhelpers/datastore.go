@@ -38,6 +38,9 @@ type testDatastore struct {
 	version                 dataservices.VersionService
 	webhook                 dataservices.WebhookService
 	pendingActionsService   dataservices.PendingActionsService
+	userSessionService      dataservices.UserSessionService
+	variableSetService      dataservices.VariableSetService
+	deploymentGroupService  dataservices.DeploymentGroupService
 	connection              portainer.Connection
 }
 
@@ -92,6 +95,18 @@ func (d *testDatastore) PendingActions() dataservices.PendingActionsService {
 	return d.pendingActionsService
 }
 
+func (d *testDatastore) UserSession() dataservices.UserSessionService {
+	return d.userSessionService
+}
+
+func (d *testDatastore) VariableSet() dataservices.VariableSetService {
+	return d.variableSetService
+}
+
+func (d *testDatastore) DeploymentGroup() dataservices.DeploymentGroupService {
+	return d.deploymentGroupService
+}
+
 func (d *testDatastore) Connection() portainer.Connection {
 	return d.connection
 }
@@ -460,3 +475,38 @@ func WithStacks(stacks []portainer.Stack) datastoreOption {
 		d.stack = &stubStacksService{stacks: stacks}
 	}
 }
+
+type stubVariableSetService struct {
+	dataservices.VariableSetService
+
+	variableSets []portainer.VariableSet
+}
+
+func (s *stubVariableSetService) BucketName() string { return "variable_set" }
+
+func (s *stubVariableSetService) Read(ID portainer.VariableSetID) (*portainer.VariableSet, error) {
+	for _, variableSet := range s.variableSets {
+		if variableSet.ID == ID {
+			return &variableSet, nil
+		}
+	}
+
+	return nil, errors.ErrObjectNotFound
+}
+
+func (s *stubVariableSetService) ReadAll(predicates ...func(portainer.VariableSet) bool) ([]portainer.VariableSet, error) {
+	filtered := s.variableSets
+
+	for _, p := range predicates {
+		filtered = slicesx.Filter(filtered, p)
+	}
+
+	return filtered, nil
+}
+
+// WithVariableSets option will instruct testDatastore to return provided variable sets
+func WithVariableSets(variableSets []portainer.VariableSet) datastoreOption {
+	return func(d *testDatastore) {
+		d.variableSetService = &stubVariableSetService{variableSets: variableSets}
+	}
+}
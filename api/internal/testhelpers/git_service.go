@@ -30,3 +30,21 @@ func (g *gitService) ListRefs(repositoryURL, username, password string, hardRefr
 func (g *gitService) ListFiles(repositoryURL, referenceName, username, password string, dirOnly, hardRefresh bool, includedExts []string, tlsSkipVerify bool) ([]string, error) {
 	return nil, nil
 }
+
+func (g *gitService) CloneCommit(destination, repositoryURL, referenceName, commitID, username, password string, tlsSkipVerify bool) error {
+	return g.cloneErr
+}
+
+func (g *gitService) FetchHistory(repositoryURL, referenceName, username, password string, tlsSkipVerify bool, depth int) (portainer.GitHistory, error) {
+	return gitHistory{}, nil
+}
+
+type gitHistory struct{}
+
+func (gitHistory) ChangedFiles(fromCommitID, toCommitID string) ([]string, error) {
+	return nil, nil
+}
+
+func (gitHistory) VerifyCommitSignature(commitID string, trustedKeys []string) error {
+	return nil
+}
@@ -60,6 +60,18 @@ func (testRequestBouncer) JWTAuthLookup(r *http.Request) (*portainer.TokenData,
 
 func (testRequestBouncer) RevokeJWT(jti string) {}
 
+func (testRequestBouncer) UserSessions(userID portainer.UserID) []portainer.UserSession {
+	return nil
+}
+
+func (testRequestBouncer) RevokeUserSession(userID portainer.UserID, sessionID portainer.UserSessionID) error {
+	return nil
+}
+
+func (testRequestBouncer) RevokeAllSessions(exceptToken string) error {
+	return nil
+}
+
 func (testRequestBouncer) DisableCSP() {}
 
 // AddTestSecurityCookie adds a security cookie to the request
@@ -0,0 +1,116 @@
+// Package twofactor manages the TOTP second factor of internal users.
+package twofactor
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+)
+
+const (
+	// Issuer is the name displayed by authenticator applications
+	Issuer = "Portainer"
+	// RecoveryCodesCount is the number of recovery codes generated on enrolment
+	RecoveryCodesCount = 10
+)
+
+var (
+	ErrInvalidCode    = errors.New("invalid two-factor authentication code")
+	ErrNotEnrolled    = errors.New("two-factor authentication is not enabled for this user")
+	ErrNoPendingSetup = errors.New("two-factor authentication enrolment has not been started")
+)
+
+// Enrolment is the information required to register a secret in an authenticator application
+type Enrolment struct {
+	// Base32 encoded TOTP secret, for manual entry
+	Secret string `example:"JBSWY3DPEHPK3PXP"`
+	// otpauth:// URI to render as a QR code
+	ProvisioningURI string `example:"otpauth://totp/Portainer:admin?secret=JBSWY3DPEHPK3PXP&issuer=Portainer"`
+}
+
+// NewEnrolment generates a new secret for user
+func NewEnrolment(user *portainer.User) (*Enrolment, error) {
+	secret, err := crypto.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrolment{
+		Secret:          secret,
+		ProvisioningURI: crypto.TOTPProvisioningURI(Issuer, user.Username, secret),
+	}, nil
+}
+
+// Enable checks that code was generated from secret and enables the second factor of user with it.
+// It returns the recovery codes in clear, only their hashes are kept on the user.
+func Enable(user *portainer.User, secret, code string, cryptoService portainer.CryptoService, now time.Time) ([]string, error) {
+	if secret == "" {
+		return nil, ErrNoPendingSetup
+	}
+
+	step, ok := crypto.ValidateTOTPCode(secret, code, now)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	recoveryCodes, err := crypto.GenerateRecoveryCodes(RecoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, recoveryCode := range recoveryCodes {
+		hash, err := cryptoService.Hash(recoveryCode)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	user.TwoFactor = portainer.UserTwoFactor{
+		Enabled:       true,
+		Secret:        secret,
+		RecoveryCodes: hashes,
+		LastUsedStep:  step,
+	}
+
+	return recoveryCodes, nil
+}
+
+// Verify checks a TOTP code, or a recovery code when code is empty, against the second factor of user.
+// The user is updated to prevent the code from being used again and must be persisted by the caller.
+func Verify(user *portainer.User, code, recoveryCode string, cryptoService portainer.CryptoService, now time.Time) error {
+	if !user.TwoFactor.Enabled {
+		return ErrNotEnrolled
+	}
+
+	if code != "" {
+		step, ok := crypto.ValidateTOTPCode(user.TwoFactor.Secret, code, now)
+		if !ok || step <= user.TwoFactor.LastUsedStep {
+			return ErrInvalidCode
+		}
+
+		user.TwoFactor.LastUsedStep = step
+
+		return nil
+	}
+
+	recoveryCode = strings.ToLower(strings.TrimSpace(recoveryCode))
+	if recoveryCode == "" {
+		return ErrInvalidCode
+	}
+
+	for i, hash := range user.TwoFactor.RecoveryCodes {
+		if cryptoService.CompareHashAndData(hash, recoveryCode) == nil {
+			user.TwoFactor.RecoveryCodes = append(user.TwoFactor.RecoveryCodes[:i:i], user.TwoFactor.RecoveryCodes[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return ErrInvalidCode
+}
@@ -0,0 +1,54 @@
+package twofactor
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableAndVerify(t *testing.T) {
+	cryptoService := &crypto.Service{}
+	user := &portainer.User{ID: 2, Username: "alice"}
+	now := time.Unix(1700000000, 0)
+
+	enrolment, err := NewEnrolment(user)
+	require.NoError(t, err)
+	require.Contains(t, enrolment.ProvisioningURI, "Portainer:alice")
+
+	_, err = Enable(user, enrolment.Secret, "000000", cryptoService, now)
+	require.ErrorIs(t, err, ErrInvalidCode)
+	require.False(t, user.TwoFactor.Enabled)
+
+	code, err := crypto.GenerateTOTPCode(enrolment.Secret, crypto.TOTPStep(now))
+	require.NoError(t, err)
+
+	recoveryCodes, err := Enable(user, enrolment.Secret, code, cryptoService, now)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, RecoveryCodesCount)
+	require.True(t, user.TwoFactor.Enabled)
+	require.NotContains(t, user.TwoFactor.RecoveryCodes, recoveryCodes[0])
+
+	// The code used for the enrolment cannot be replayed
+	require.ErrorIs(t, Verify(user, code, "", cryptoService, now), ErrInvalidCode)
+
+	later := now.Add(crypto.TOTPPeriod)
+	code, err = crypto.GenerateTOTPCode(enrolment.Secret, crypto.TOTPStep(later))
+	require.NoError(t, err)
+	require.NoError(t, Verify(user, code, "", cryptoService, later))
+	require.ErrorIs(t, Verify(user, code, "", cryptoService, later), ErrInvalidCode)
+
+	// Recovery codes can only be used once
+	require.NoError(t, Verify(user, "", recoveryCodes[3], cryptoService, later))
+	require.Len(t, user.TwoFactor.RecoveryCodes, RecoveryCodesCount-1)
+	require.ErrorIs(t, Verify(user, "", recoveryCodes[3], cryptoService, later), ErrInvalidCode)
+}
+
+func TestVerifyNotEnrolled(t *testing.T) {
+	user := &portainer.User{ID: 2, Username: "alice"}
+
+	require.ErrorIs(t, Verify(user, "123456", "", &crypto.Service{}, time.Now()), ErrNotEnrolled)
+}
@@ -1,6 +1,7 @@
 package ldap
 
 import (
+	"cmp"
 	"fmt"
 	"strings"
 
@@ -11,6 +12,14 @@ import (
 	httperrors "github.com/portainer/portainer/api/http/errors"
 )
 
+const (
+	// matchingRuleInChain is the OID of the Active Directory LDAP_MATCHING_RULE_IN_CHAIN rule, which walks the chain of
+	// ancestry of the objects
+	matchingRuleInChain = "1.2.840.113556.1.4.1941"
+	// maxGroupNestingDepth is the maximum number of levels of nested groups resolved by the client
+	maxGroupNestingDepth = 16
+)
+
 var (
 	// errUserNotFound defines an error raised when the user is not found via LDAP search
 	// or that too many entries (> 1) are returned.
@@ -113,7 +122,8 @@ func (*Service) GetUserGroups(username string, settings *portainer.LDAPSettings)
 		return nil, err
 	}
 
-	userGroups := getGroupsByUser(userDN, connection, settings.GroupSearchSettings)
+	// the groups found are used even when some of the searches failed
+	userGroups, _ := getGroupsByUser(userDN, connection, settings.GroupSearchSettings)
 
 	return userGroups, nil
 }
@@ -262,35 +272,181 @@ func searchUser(username string, conn *ldap.Conn, settings []portainer.LDAPSearc
 	return userDN, nil
 }
 
-// Get a list of group names for specified user from LDAP/AD
-func getGroupsByUser(userDN string, conn *ldap.Conn, settings []portainer.LDAPGroupSearchSettings) []string {
+// Get a list of group names for specified user from LDAP/AD. The search errors are skipped so that the groups found
+// with the other search settings are still returned, the first one is returned along with the groups.
+func getGroupsByUser(userDN string, conn *ldap.Conn, settings []portainer.LDAPGroupSearchSettings) ([]string, error) {
 	groups := make([]string, 0)
-	userDNEscaped := ldap.EscapeFilter(userDN)
+	seen := map[string]bool{}
+
+	var firstErr error
+
+	for _, searchSettings := range settings {
+		names, err := searchGroupsOfMember(userDN, conn, searchSettings)
+		if err != nil {
+			firstErr = cmp.Or(firstErr, err)
+		}
+
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				groups = append(groups, name)
+			}
+		}
+	}
+
+	return groups, firstErr
+}
+
+// searchGroupsOfMember returns the names of the groups the member belongs to, directly or, when nested groups are
+// enabled, through other groups
+func searchGroupsOfMember(memberDN string, conn *ldap.Conn, settings portainer.LDAPGroupSearchSettings) ([]string, error) {
+	if settings.MatchingRuleInChain {
+		entries, err := searchGroups(conn, settings, fmt.Sprintf("(&%s(%s:%s:=%s))", settings.GroupFilter, settings.GroupAttribute, matchingRuleInChain, ldap.EscapeFilter(memberDN)))
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.GetAttributeValue("cn"))
+		}
+
+		return names, nil
+	}
+
+	var names []string
+	visited := map[string]bool{strings.ToLower(memberDN): true}
+	members := []string{memberDN}
+
+	for depth := 0; len(members) > 0 && depth < maxGroupNestingDepth; depth++ {
+		var parents []string
+
+		for _, member := range members {
+			entries, err := searchGroups(conn, settings, fmt.Sprintf("(&%s(%s=%s))", settings.GroupFilter, settings.GroupAttribute, ldap.EscapeFilter(member)))
+			if err != nil {
+				return names, err
+			}
+
+			for _, entry := range entries {
+				if visited[strings.ToLower(entry.DN)] {
+					continue
+				}
+
+				visited[strings.ToLower(entry.DN)] = true
+				names = append(names, entry.GetAttributeValue("cn"))
+				parents = append(parents, entry.DN)
+			}
+		}
+
+		if !settings.NestedGroups {
+			break
+		}
+
+		members = parents
+	}
+
+	return names, nil
+}
+
+func searchGroups(conn *ldap.Conn, settings portainer.LDAPGroupSearchSettings, filter string) ([]*ldap.Entry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		settings.GroupBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{"cn"},
+		nil,
+	)
+
+	sr, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return sr.Entries, nil
+}
+
+// ReadDirectory looks up the users and their groups in the LDAP/AD, along with the names of all the groups. Unlike the
+// login, any search error fails the whole read so that an outage of the server is not mistaken for missing users.
+func (*Service) ReadDirectory(usernames []string, settings *portainer.LDAPSettings) (*portainer.LDAPDirectory, error) {
+	connection, err := createConnection(settings)
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+
+	if !settings.AnonymousMode {
+		err = connection.Bind(settings.ReaderDN, settings.Password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	directory := &portainer.LDAPDirectory{
+		UserGroups: map[string][]string{},
+		Groups:     []string{},
+	}
+
+	for _, username := range usernames {
+		userDN, err := lookupUser(username, connection, settings.SearchSettings)
+		if err != nil {
+			return nil, err
+		}
+
+		if userDN == "" {
+			continue
+		}
+
+		groups, err := getGroupsByUser(userDN, connection, settings.GroupSearchSettings)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed searching the groups of %q", username)
+		}
+
+		directory.UserGroups[username] = groups
+	}
+
+	seen := map[string]bool{}
+	for _, searchSettings := range settings.GroupSearchSettings {
+		entries, err := searchGroups(connection, searchSettings, fmt.Sprintf("(&%s(objectClass=*))", searchSettings.GroupFilter))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed searching the groups")
+		}
+
+		for _, entry := range entries {
+			name := entry.GetAttributeValue("cn")
+			if name != "" && !seen[name] {
+				seen[name] = true
+				directory.Groups = append(directory.Groups, name)
+			}
+		}
+	}
+
+	return directory, nil
+}
+
+// lookupUser returns the DN of the user, or an empty string when none of the search settings finds exactly one entry
+func lookupUser(username string, conn *ldap.Conn, settings []portainer.LDAPSearchSettings) (string, error) {
+	usernameEscaped := ldap.EscapeFilter(username)
 
 	for _, searchSettings := range settings {
 		searchRequest := ldap.NewSearchRequest(
-			searchSettings.GroupBaseDN,
+			searchSettings.BaseDN,
 			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-			fmt.Sprintf("(&%s(%s=%s))", searchSettings.GroupFilter, searchSettings.GroupAttribute, userDNEscaped),
-			[]string{"cn"},
+			fmt.Sprintf("(&%s(%s=%s))", searchSettings.Filter, searchSettings.UserNameAttribute, usernameEscaped),
+			[]string{"dn"},
 			nil,
 		)
 
-		// Deliberately skip errors on the search request so that we can jump to other search settings
-		// if any issue arise with the current one.
 		sr, err := conn.Search(searchRequest)
 		if err != nil {
-			continue
+			return "", errors.Wrapf(err, "failed searching the user %q", username)
 		}
 
-		for _, entry := range sr.Entries {
-			for _, attr := range entry.Attributes {
-				groups = append(groups, attr.Values[0])
-			}
+		if len(sr.Entries) == 1 {
+			return sr.Entries[0].DN, nil
 		}
 	}
 
-	return groups
+	return "", nil
 }
 
 // TestConnectivity is used to test a connection against the LDAP server using the credentials
@@ -0,0 +1,125 @@
+package ldap
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/ldap/ldaptest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func group(name string, members ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:         "cn=" + name + ",ou=groups,dc=example,dc=com",
+		Attributes: map[string][]string{"objectClass": {"groupOfNames"}, "cn": {name}, "member": members},
+	}
+}
+
+func user(name string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:         "uid=" + name + ",ou=users,dc=example,dc=com",
+		Attributes: map[string][]string{"objectClass": {"inetOrgPerson"}, "uid": {name}, "userPassword": {name + "-password"}},
+	}
+}
+
+func testDirectory() []ldaptest.Entry {
+	return []ldaptest.Entry{
+		{DN: "cn=reader,dc=example,dc=com", Attributes: map[string][]string{"userPassword": {"reader-password"}}},
+		{DN: "ou=users,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		{DN: "ou=groups,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		user("alice"),
+		user("bob"),
+		group("developers", "uid=alice,ou=users,dc=example,dc=com"),
+		group("engineering", "cn=developers,ou=groups,dc=example,dc=com"),
+		group("staff", "cn=engineering,ou=groups,dc=example,dc=com", "uid=bob,ou=users,dc=example,dc=com"),
+		group("ops", "uid=bob,ou=users,dc=example,dc=com"),
+		// groups nested in each other
+		group("loop-a", "cn=loop-b,ou=groups,dc=example,dc=com", "uid=alice,ou=users,dc=example,dc=com"),
+		group("loop-b", "cn=loop-a,ou=groups,dc=example,dc=com"),
+	}
+}
+
+func runTestServer(t *testing.T) (*ldaptest.Server, *portainer.LDAPSettings) {
+	server, err := ldaptest.RunLDAPServer(testDirectory())
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	settings := &portainer.LDAPSettings{
+		ReaderDN: "cn=reader,dc=example,dc=com",
+		Password: "reader-password",
+		URL:      server.URL(),
+		SearchSettings: []portainer.LDAPSearchSettings{
+			{BaseDN: "ou=users,dc=example,dc=com", Filter: "(objectClass=inetOrgPerson)", UserNameAttribute: "uid"},
+		},
+		GroupSearchSettings: []portainer.LDAPGroupSearchSettings{
+			{GroupBaseDN: "ou=groups,dc=example,dc=com", GroupFilter: "(objectClass=groupOfNames)", GroupAttribute: "member"},
+		},
+	}
+
+	return server, settings
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	_, settings := runTestServer(t)
+
+	service := &Service{}
+
+	require.NoError(t, service.AuthenticateUser("alice", "alice-password", settings))
+	require.Error(t, service.AuthenticateUser("alice", "bob-password", settings))
+	require.Error(t, service.AuthenticateUser("carol", "carol-password", settings))
+}
+
+func TestGetUserGroups(t *testing.T) {
+	_, settings := runTestServer(t)
+
+	service := &Service{}
+
+	groups, err := service.GetUserGroups("alice", settings)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"developers", "loop-a"}, groups)
+
+	t.Run("nested groups", func(t *testing.T) {
+		settings.GroupSearchSettings[0].NestedGroups = true
+
+		groups, err := service.GetUserGroups("alice", settings)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"developers", "engineering", "staff", "loop-a", "loop-b"}, groups)
+
+		groups, err = service.GetUserGroups("bob", settings)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"staff", "ops"}, groups)
+	})
+
+	t.Run("matching rule in chain", func(t *testing.T) {
+		settings.GroupSearchSettings[0].NestedGroups = false
+		settings.GroupSearchSettings[0].MatchingRuleInChain = true
+
+		groups, err := service.GetUserGroups("alice", settings)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"developers", "engineering", "staff", "loop-a", "loop-b"}, groups)
+	})
+}
+
+func TestReadDirectory(t *testing.T) {
+	server, settings := runTestServer(t)
+
+	service := &Service{}
+
+	directory, err := service.ReadDirectory([]string{"alice", "bob", "carol"}, settings)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"developers", "loop-a"}, directory.UserGroups["alice"])
+	assert.ElementsMatch(t, []string{"staff", "ops"}, directory.UserGroups["bob"])
+	assert.NotContains(t, directory.UserGroups, "carol")
+	assert.ElementsMatch(t, []string{"developers", "engineering", "staff", "ops", "loop-a", "loop-b"}, directory.Groups)
+
+	t.Run("search errors are returned", func(t *testing.T) {
+		// the base DN of the users no longer exists
+		server.SetEntries(testDirectory()[:1])
+
+		_, err := service.ReadDirectory([]string{"alice"}, settings)
+		require.Error(t, err)
+	})
+}
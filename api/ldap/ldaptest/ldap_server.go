@@ -0,0 +1,438 @@
+package ldaptest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// MatchingRuleInChain is the OID of the Active Directory LDAP_MATCHING_RULE_IN_CHAIN matching rule
+const MatchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+const (
+	resultSuccess             = 0
+	resultProtocolError       = 2
+	resultNoSuchObject        = 32
+	resultInvalidCredentials  = 49
+	resultUnwillingToPerform  = 53
+	maxMatchingRuleChainDepth = 32
+)
+
+// Entry is an entry of the directory served by the test LDAP server, the userPassword attribute is used to check the
+// simple binds
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Server is an in-process LDAP server supporting the simple bind and the search operations, it is only meant to be
+// used by tests
+type Server struct {
+	listener net.Listener
+	mu       sync.Mutex
+	entries  []Entry
+	wg       sync.WaitGroup
+}
+
+// RunLDAPServer starts an LDAP server serving the entries on a random local port
+func RunLDAPServer(entries []Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{listener: listener, entries: entries}
+
+	server.wg.Add(1)
+	go server.serve()
+
+	return server, nil
+}
+
+// URL returns the host:port the server listens on
+func (server *Server) URL() string {
+	return server.listener.Addr().String()
+}
+
+// SetEntries replaces the entries of the directory
+func (server *Server) SetEntries(entries []Entry) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.entries = entries
+}
+
+// Close stops the server
+func (server *Server) Close() {
+	server.listener.Close()
+	server.wg.Wait()
+}
+
+func (server *Server) serve() {
+	defer server.wg.Done()
+
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		server.wg.Add(1)
+		go server.handle(conn)
+	}
+}
+
+func (server *Server) handle(conn net.Conn) {
+	defer server.wg.Done()
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		packet, err := ber.ReadPacket(reader)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		messageID := packet.Children[0].Value
+		request := packet.Children[1]
+
+		if request.ClassType != ber.ClassApplication {
+			return
+		}
+
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			code := server.bind(request)
+			if _, err := conn.Write(response(messageID, ldap.ApplicationBindResponse, code).Bytes()); err != nil {
+				return
+			}
+
+		case ldap.ApplicationSearchRequest:
+			for _, entry := range server.search(messageID, request) {
+				if _, err := conn.Write(entry.Bytes()); err != nil {
+					return
+				}
+			}
+
+		case ldap.ApplicationUnbindRequest:
+			return
+
+		default:
+			if _, err := conn.Write(response(messageID, ldap.ApplicationExtendedResponse, resultUnwillingToPerform).Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (server *Server) bind(request *ber.Packet) int64 {
+	if len(request.Children) < 3 {
+		return resultProtocolError
+	}
+
+	dn := packetString(request.Children[1])
+	password := packetString(request.Children[2])
+
+	if dn == "" && password == "" {
+		return resultSuccess
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for _, entry := range server.entries {
+		if strings.EqualFold(entry.DN, dn) {
+			for _, value := range attributeValues(entry, "userPassword") {
+				if value == password && password != "" {
+					return resultSuccess
+				}
+			}
+		}
+	}
+
+	return resultInvalidCredentials
+}
+
+func (server *Server) search(messageID any, request *ber.Packet) []*ber.Packet {
+	if len(request.Children) < 8 {
+		return []*ber.Packet{response(messageID, ldap.ApplicationSearchResultDone, resultProtocolError)}
+	}
+
+	baseDN := packetString(request.Children[0])
+	scope := packetInt(request.Children[1])
+	filter := request.Children[6]
+
+	var attributes []string
+	for _, attribute := range request.Children[7].Children {
+		attributes = append(attributes, packetString(attribute))
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	var results []*ber.Packet
+	baseFound := false
+
+	for _, entry := range server.entries {
+		if strings.EqualFold(entry.DN, baseDN) {
+			baseFound = true
+		}
+
+		if !inScope(entry.DN, baseDN, scope) || !server.match(entry, filter) {
+			continue
+		}
+
+		results = append(results, searchResultEntry(messageID, entry, attributes))
+	}
+
+	code := int64(resultSuccess)
+	if !baseFound {
+		code = resultNoSuchObject
+	}
+
+	return append(results, response(messageID, ldap.ApplicationSearchResultDone, code))
+}
+
+func (server *Server) match(entry Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !server.match(entry, child) {
+				return false
+			}
+		}
+
+		return true
+
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if server.match(entry, child) {
+				return true
+			}
+		}
+
+		return false
+
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !server.match(entry, filter.Children[0])
+
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+
+		return hasValue(entry, packetString(filter.Children[0]), packetString(filter.Children[1]))
+
+	case ldap.FilterPresent:
+		return strings.EqualFold(filter.Data.String(), "objectClass") || len(attributeValues(entry, filter.Data.String())) > 0
+
+	case ldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+
+		for _, value := range attributeValues(entry, packetString(filter.Children[0])) {
+			if matchSubstrings(strings.ToLower(value), filter.Children[1].Children) {
+				return true
+			}
+		}
+
+		return false
+
+	case ldap.FilterExtensibleMatch:
+		var rule, attribute, value string
+		for _, child := range filter.Children {
+			switch child.Tag {
+			case ldap.MatchingRuleAssertionMatchingRule:
+				rule = child.Data.String()
+			case ldap.MatchingRuleAssertionType:
+				attribute = child.Data.String()
+			case ldap.MatchingRuleAssertionMatchValue:
+				value = child.Data.String()
+			}
+		}
+
+		if rule == MatchingRuleInChain {
+			return server.memberInChain(entry, attribute, value, map[string]bool{}, 0)
+		}
+
+		return rule == "" && hasValue(entry, attribute, value)
+	}
+
+	return false
+}
+
+// memberInChain returns true when value is a value of the attribute of the entry, or of the attribute of an entry
+// referenced by the attribute of the entry, recursively
+func (server *Server) memberInChain(entry Entry, attribute, value string, visited map[string]bool, depth int) bool {
+	if depth > maxMatchingRuleChainDepth || visited[strings.ToLower(entry.DN)] {
+		return false
+	}
+
+	visited[strings.ToLower(entry.DN)] = true
+
+	for _, member := range attributeValues(entry, attribute) {
+		if strings.EqualFold(member, value) {
+			return true
+		}
+
+		for _, nested := range server.entries {
+			if strings.EqualFold(nested.DN, member) && server.memberInChain(nested, attribute, value, visited, depth+1) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchSubstrings(value string, substrings []*ber.Packet) bool {
+	for i, substring := range substrings {
+		part := strings.ToLower(substring.Data.String())
+
+		switch substring.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, part) {
+				return false
+			}
+
+			value = value[len(part):]
+		case ldap.FilterSubstringsAny:
+			index := strings.Index(value, part)
+			if index < 0 {
+				return false
+			}
+
+			value = value[index+len(part):]
+		case ldap.FilterSubstringsFinal:
+			if i != len(substrings)-1 || !strings.HasSuffix(value, part) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func inScope(dn, baseDN string, scope int64) bool {
+	dn = strings.ToLower(dn)
+	baseDN = strings.ToLower(baseDN)
+
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == baseDN
+	case ldap.ScopeSingleLevel:
+		parent, _, _ := strings.Cut(dn, ",")
+		return dn != baseDN && strings.TrimPrefix(dn, parent+",") == baseDN
+	default:
+		return dn == baseDN || baseDN == "" || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
+
+func hasValue(entry Entry, attribute, value string) bool {
+	for _, v := range attributeValues(entry, attribute) {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func attributeValues(entry Entry, attribute string) []string {
+	for name, values := range entry.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+
+	return nil
+}
+
+func searchResultEntry(messageID any, entry Entry, attributes []string) *ber.Packet {
+	packet := envelope(messageID)
+
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range entry.Attributes {
+		if strings.EqualFold(name, "userPassword") || !requested(name, attributes) {
+			continue
+		}
+
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+
+	result.AppendChild(list)
+	packet.AppendChild(result)
+
+	return packet
+}
+
+func requested(name string, attributes []string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+
+	for _, attribute := range attributes {
+		if attribute == "*" || strings.EqualFold(attribute, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func response(messageID any, tag ber.Tag, code int64) *ber.Packet {
+	packet := envelope(messageID)
+
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	packet.AppendChild(result)
+
+	return packet
+}
+
+func envelope(messageID any) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+
+	return packet
+}
+
+func packetString(packet *ber.Packet) string {
+	if s, ok := packet.Value.(string); ok {
+		return s
+	}
+
+	if packet.Data != nil {
+		return packet.Data.String()
+	}
+
+	return ""
+}
+
+func packetInt(packet *ber.Packet) int64 {
+	if i, ok := packet.Value.(int64); ok {
+		return i
+	}
+
+	return -1
+}
@@ -0,0 +1,269 @@
+package ldap
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/scheduler"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrLDAPAuthenticationDisabled is returned when a synchronization is requested while LDAP is not the authentication method
+var ErrLDAPAuthenticationDisabled = errors.New("LDAP authentication is not enabled")
+
+// SyncService periodically reconciles the users and team memberships with the LDAP/AD. The users managed by the
+// directory are the users without a Portainer password, except the initial administrator. Users no longer found are
+// disabled and, when group search settings are defined, the memberships of the teams named after a directory group
+// follow the groups of the users.
+type SyncService struct {
+	dataStore   dataservices.DataStore
+	ldapService portainer.LDAPService
+	scheduler   *scheduler.Scheduler
+	jobID       string
+	jobMu       sync.Mutex
+	syncMu      sync.Mutex
+}
+
+// NewSyncService returns a new SyncService, the synchronization is scheduled with the interval of the LDAP settings
+func NewSyncService(dataStore dataservices.DataStore, ldapService portainer.LDAPService, scheduler *scheduler.Scheduler) (*SyncService, error) {
+	service := &SyncService{
+		dataStore:   dataStore,
+		ldapService: ldapService,
+		scheduler:   scheduler,
+	}
+
+	settings, err := dataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.SetSyncInterval(settings.LDAPSettings.SyncInterval); err != nil {
+		log.Warn().Err(err).Str("interval", settings.LDAPSettings.SyncInterval).Msg("invalid LDAP synchronization interval, the synchronization is disabled")
+	}
+
+	return service, nil
+}
+
+// ValidateSyncInterval returns an error when the interval is neither empty nor a positive duration
+func ValidateSyncInterval(interval string) error {
+	if interval == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return err
+	}
+
+	if d < time.Minute {
+		return errors.New("the synchronization interval must be at least one minute")
+	}
+
+	return nil
+}
+
+// SetSyncInterval reschedules the synchronization, an empty interval disables it
+func (service *SyncService) SetSyncInterval(interval string) error {
+	if err := ValidateSyncInterval(interval); err != nil {
+		return err
+	}
+
+	service.jobMu.Lock()
+	defer service.jobMu.Unlock()
+
+	if service.jobID != "" {
+		if err := service.scheduler.StopJob(service.jobID); err != nil {
+			return err
+		}
+
+		service.jobID = ""
+	}
+
+	if interval == "" {
+		return nil
+	}
+
+	d, _ := time.ParseDuration(interval)
+
+	service.jobID = service.scheduler.StartJobEvery(d, func() error {
+		report, err := service.Sync(false)
+		if errors.Is(err, ErrLDAPAuthenticationDisabled) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		log.Info().
+			Int("disabled_users", len(report.DisabledUsers)).
+			Int("enabled_users", len(report.EnabledUsers)).
+			Int("added_memberships", len(report.AddedMemberships)).
+			Int("removed_memberships", len(report.RemovedMemberships)).
+			Msg("LDAP synchronization completed")
+
+		return nil
+	})
+
+	return nil
+}
+
+// Sync reconciles the users and team memberships with the directory. With dryRun, the changes are only reported.
+func (service *SyncService) Sync(dryRun bool) (*portainer.LDAPSyncReport, error) {
+	service.syncMu.Lock()
+	defer service.syncMu.Unlock()
+
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationLDAP {
+		return nil, ErrLDAPAuthenticationDisabled
+	}
+
+	users, err := service.dataStore.User().ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// the OAuth, SAML and certificate users do not have a password either, only the LDAP ones are synchronized
+	users = slices.DeleteFunc(users, func(user portainer.User) bool {
+		return user.ID == 1 || user.Origin != portainer.UserOriginLDAP
+	})
+
+	usernames := make([]string, 0, len(users))
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+
+	directory, err := service.ldapService.ReadDirectory(usernames, &settings.LDAPSettings)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the LDAP directory")
+	}
+
+	report := &portainer.LDAPSyncReport{
+		Time:               time.Now().Unix(),
+		DryRun:             dryRun,
+		DisabledUsers:      []string{},
+		EnabledUsers:       []string{},
+		AddedMemberships:   []portainer.LDAPSyncMembership{},
+		RemovedMemberships: []portainer.LDAPSyncMembership{},
+	}
+
+	syncGroups := len(settings.LDAPSettings.GroupSearchSettings) > 0 && settings.LDAPSettings.GroupSearchSettings[0].GroupBaseDN != ""
+
+	reconcile := func(tx dataservices.DataStoreTx) error {
+		teams, err := tx.Team().ReadAll()
+		if err != nil {
+			return err
+		}
+
+		managedTeams := make([]portainer.Team, 0)
+		for _, team := range teams {
+			if containsFold(directory.Groups, team.Name) {
+				managedTeams = append(managedTeams, team)
+			}
+		}
+
+		for _, u := range users {
+			// the user is read again in the transaction so that the changes made since the directory was read are kept
+			user, err := tx.User().Read(u.ID)
+			if tx.IsErrObjectNotFound(err) || (err == nil && user.Origin != portainer.UserOriginLDAP) {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			groups, found := directory.UserGroups[user.Username]
+			if found == user.Disabled {
+				user.Disabled = !found
+
+				if found {
+					report.EnabledUsers = append(report.EnabledUsers, user.Username)
+				} else {
+					report.DisabledUsers = append(report.DisabledUsers, user.Username)
+				}
+
+				if !dryRun {
+					if err := tx.User().Update(user.ID, user); err != nil {
+						return err
+					}
+				}
+			}
+
+			if !found || !syncGroups {
+				continue
+			}
+
+			if err := syncUserMemberships(tx, user, groups, managedTeams, report, dryRun); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if dryRun {
+		err = service.dataStore.ViewTx(reconcile)
+	} else {
+		err = service.dataStore.UpdateTx(reconcile)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func syncUserMemberships(tx dataservices.DataStoreTx, user *portainer.User, groups []string, managedTeams []portainer.Team, report *portainer.LDAPSyncReport, dryRun bool) error {
+	memberships, err := tx.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, team := range managedTeams {
+		isMember := slices.ContainsFunc(memberships, func(membership portainer.TeamMembership) bool {
+			return membership.TeamID == team.ID
+		})
+
+		inGroup := containsFold(groups, team.Name)
+
+		switch {
+		case inGroup && !isMember:
+			report.AddedMemberships = append(report.AddedMemberships, portainer.LDAPSyncMembership{Username: user.Username, Team: team.Name})
+
+			if dryRun {
+				continue
+			}
+
+			if err := tx.TeamMembership().Create(&portainer.TeamMembership{UserID: user.ID, TeamID: team.ID, Role: portainer.TeamMember}); err != nil {
+				return err
+			}
+
+		case !inGroup && isMember:
+			report.RemovedMemberships = append(report.RemovedMemberships, portainer.LDAPSyncMembership{Username: user.Username, Team: team.Name})
+
+			if dryRun {
+				continue
+			}
+
+			if err := tx.TeamMembership().DeleteTeamMembershipByTeamIDAndUserID(team.ID, user.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+}
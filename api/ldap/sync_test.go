@@ -0,0 +1,153 @@
+package ldap
+
+import (
+	"context"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/scheduler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSyncService(t *testing.T) (*SyncService, *datastore.Store) {
+	_, ldapSettings := runTestServer(t)
+
+	_, store := datastore.MustNewTestStore(t, true, true)
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+
+	settings.AuthenticationMethod = portainer.AuthenticationLDAP
+	settings.LDAPSettings = *ldapSettings
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	users := []*portainer.User{
+		{Username: "admin", Password: "hash", Role: portainer.AdministratorRole, Origin: portainer.UserOriginInternal},
+		{Username: "alice", Role: portainer.StandardUserRole, Origin: portainer.UserOriginLDAP},
+		{Username: "bob", Role: portainer.StandardUserRole, Disabled: true, Origin: portainer.UserOriginLDAP},
+		{Username: "carol", Role: portainer.StandardUserRole, Origin: portainer.UserOriginLDAP},
+		{Username: "dave", Password: "hash", Role: portainer.StandardUserRole, Origin: portainer.UserOriginInternal},
+		{Username: "erin", Role: portainer.StandardUserRole, Origin: portainer.UserOriginOAuth},
+		{Username: "frank", Role: portainer.StandardUserRole, Origin: portainer.UserOriginCertificate},
+	}
+	for _, user := range users {
+		require.NoError(t, store.User().Create(user))
+	}
+
+	for _, name := range []string{"developers", "ops", "local"} {
+		require.NoError(t, store.Team().Create(&portainer.Team{Name: name}))
+	}
+
+	// alice left ops and is in a team unknown to the directory
+	require.NoError(t, store.TeamMembership().Create(&portainer.TeamMembership{UserID: 2, TeamID: 2, Role: portainer.TeamMember}))
+	require.NoError(t, store.TeamMembership().Create(&portainer.TeamMembership{UserID: 2, TeamID: 3, Role: portainer.TeamMember}))
+
+	s := scheduler.NewScheduler(context.Background())
+	t.Cleanup(func() { _ = s.Shutdown() })
+
+	service, err := NewSyncService(store, &Service{}, s)
+	require.NoError(t, err)
+
+	return service, store
+}
+
+func teamIDs(t *testing.T, store *datastore.Store, userID portainer.UserID) []portainer.TeamID {
+	memberships, err := store.TeamMembership().TeamMembershipsByUserID(userID)
+	require.NoError(t, err)
+
+	ids := []portainer.TeamID{}
+	for _, membership := range memberships {
+		ids = append(ids, membership.TeamID)
+	}
+
+	return ids
+}
+
+func TestSync(t *testing.T) {
+	service, store := setupSyncService(t)
+
+	expected := &portainer.LDAPSyncReport{
+		DryRun:        true,
+		DisabledUsers: []string{"carol"},
+		EnabledUsers:  []string{"bob"},
+		AddedMemberships: []portainer.LDAPSyncMembership{
+			{Username: "alice", Team: "developers"},
+			{Username: "bob", Team: "ops"},
+		},
+		RemovedMemberships: []portainer.LDAPSyncMembership{
+			{Username: "alice", Team: "ops"},
+		},
+	}
+
+	report, err := service.Sync(true)
+	require.NoError(t, err)
+	report.Time = 0
+	assert.Equal(t, expected, report)
+
+	// a dry run does not change anything
+	carol, err := store.User().Read(4)
+	require.NoError(t, err)
+	assert.False(t, carol.Disabled)
+	assert.ElementsMatch(t, []portainer.TeamID{2, 3}, teamIDs(t, store, 2))
+
+	report, err = service.Sync(false)
+	require.NoError(t, err)
+	report.Time = 0
+	expected.DryRun = false
+	assert.Equal(t, expected, report)
+
+	carol, err = store.User().Read(4)
+	require.NoError(t, err)
+	assert.True(t, carol.Disabled)
+
+	bob, err := store.User().Read(3)
+	require.NoError(t, err)
+	assert.False(t, bob.Disabled)
+
+	assert.ElementsMatch(t, []portainer.TeamID{1, 3}, teamIDs(t, store, 2))
+	assert.ElementsMatch(t, []portainer.TeamID{2}, teamIDs(t, store, 3))
+
+	// the users that do not log in through LDAP are not managed by the directory, with or without a password
+	for _, id := range []portainer.UserID{5, 6, 7} {
+		user, err := store.User().Read(id)
+		require.NoError(t, err)
+		assert.False(t, user.Disabled, user.Username)
+	}
+
+	// the synchronization is idempotent
+	report, err = service.Sync(false)
+	require.NoError(t, err)
+	assert.Empty(t, report.DisabledUsers)
+	assert.Empty(t, report.EnabledUsers)
+	assert.Empty(t, report.AddedMemberships)
+	assert.Empty(t, report.RemovedMemberships)
+}
+
+func TestSyncRequiresLDAPAuthentication(t *testing.T) {
+	service, store := setupSyncService(t)
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+
+	settings.AuthenticationMethod = portainer.AuthenticationInternal
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	_, err = service.Sync(true)
+	require.ErrorIs(t, err, ErrLDAPAuthenticationDisabled)
+}
+
+func TestSetSyncInterval(t *testing.T) {
+	service, _ := setupSyncService(t)
+
+	require.NoError(t, service.SetSyncInterval("1h"))
+	assert.NotEmpty(t, service.jobID)
+
+	require.NoError(t, service.SetSyncInterval(""))
+	assert.Empty(t, service.jobID)
+
+	require.Error(t, service.SetSyncInterval("10s"))
+	require.Error(t, service.SetSyncInterval("soon"))
+}
@@ -54,6 +54,17 @@ type (
 		ForceUpdate bool `example:"false"`
 		// Pull latest image
 		ForcePullImage bool `example:"false"`
+		// Only redeploy when the new commits change a file matching one of these globs, ** matches any number of folders
+		PathFilters []string `json:",omitempty" example:"services/api/**"`
+		// Only redeploy when the head commit is signed by one of the trusted signing keys, the stacks deployed from the
+		// environment (relative path stacks) are refused as they clone the reference again
+		RequireSignedCommits bool `json:",omitempty" example:"false"`
+		// Armored GPG public keys or SSH public keys in the authorized_keys format trusted to sign the commits
+		TrustedSigningKeys []string `json:",omitempty"`
+		// Git provider sending the push events to the webhook, one of github, gitlab, gitea or bitbucket. Leave empty for a webhook triggered by any request
+		WebhookProvider GitWebhookProvider `json:",omitempty" example:"github"`
+		// Secret used by the provider to sign the push events or the token sent along with them, required with a WebhookProvider
+		WebhookSecret string `json:",omitempty" secret:"true"`
 	}
 
 	// AzureCredentials represents the credentials used to connect to an Azure
@@ -64,7 +75,7 @@ type (
 		// Azure tenant ID
 		TenantID string `json:"TenantID" example:"34ddc78d-4fel-2358-8cc1-df84c8o839f5"`
 		// Azure authentication key
-		AuthenticationKey string `json:"AuthenticationKey" example:"cOrXoK/1D35w8YQ8nH1/8ZGwzz45JIYD5jxHKXEQknk="`
+		AuthenticationKey string `json:"AuthenticationKey" example:"cOrXoK/1D35w8YQ8nH1/8ZGwzz45JIYD5jxHKXEQknk=" secret:"true"`
 	}
 
 	// OpenAMTConfiguration represents the credentials and configurations used to connect to an OpenAMT MPS server
@@ -72,11 +83,11 @@ type (
 		Enabled          bool   `json:"enabled"`
 		MPSServer        string `json:"mpsServer"`
 		MPSUser          string `json:"mpsUser"`
-		MPSPassword      string `json:"mpsPassword"`
+		MPSPassword      string `json:"mpsPassword" secret:"true"`
 		MPSToken         string `json:"mpsToken"` // retrieved from API
 		CertFileName     string `json:"certFileName"`
 		CertFileContent  string `json:"certFileContent"`
-		CertFilePassword string `json:"certFilePassword"`
+		CertFilePassword string `json:"certFilePassword" secret:"true"`
 		DomainName       string `json:"domainName"`
 	}
 
@@ -136,11 +147,15 @@ type (
 		MaxBatchSize              *int
 		MaxBatchDelay             *time.Duration
 		SecretKeyName             *string
+		SecretsKey                *string
+		SecretsPreviousKeys       *[]string
+		RotateSecretsKey          *bool
 		LogLevel                  *string
 		LogMode                   *string
 		KubectlShellImage         *string
 		PullLimitCheckDisabled    *bool
 		TrustedOrigins            *string
+		TrustedProxies            *string
 	}
 
 	// CustomTemplateVariableDefinition
@@ -192,6 +207,51 @@ type (
 	// CustomTemplatePlatform represents a custom template platform
 	CustomTemplatePlatform int
 
+	// DeploymentGroup represents a set of stacks that are deployed in the order of their dependencies and torn down
+	// in the reverse order
+	DeploymentGroup struct {
+		// Deployment group identifier
+		ID DeploymentGroupID `json:"Id" example:"1"`
+		// Deployment group name
+		Name string `json:"Name" example:"shop"`
+		// Stacks of the group along with their dependencies
+		Stacks []DeploymentGroupStack `json:"Stacks"`
+		// Result of the last deployment or teardown of the group
+		LastRun *DeploymentGroupRun `json:"LastRun,omitempty"`
+	}
+
+	// DeploymentGroupID represents a deployment group identifier
+	DeploymentGroupID int
+
+	// DeploymentGroupStack represents a stack of a deployment group
+	DeploymentGroupStack struct {
+		// Stack identifier
+		StackID StackID `json:"StackId" example:"1"`
+		// Stacks of the group that must be running before this stack is deployed
+		DependsOn []StackID `json:"DependsOn" example:"2"`
+	}
+
+	// DeploymentGroupRun represents the result of a deployment or a teardown of a deployment group
+	DeploymentGroupRun struct {
+		// Action of the run, deploy or teardown
+		Action DeploymentGroupAction `json:"Action" example:"deploy"`
+		// Unix timestamp of the start of the run
+		Date int64 `json:"Date" example:"1587399600"`
+		// Whether the run is still in progress
+		Running bool `json:"Running,omitempty" example:"false"`
+		// Whether every stack of the group was processed
+		Success bool `json:"Success" example:"true"`
+		// Stacks processed by the run, in order
+		Stacks []StackID `json:"Stacks" example:"2"`
+		// Stack that made the run abort
+		FailedStackID StackID `json:"FailedStackId,omitempty" example:"1"`
+		// Error that made the run abort
+		Error string `json:"Error,omitempty"`
+	}
+
+	// DeploymentGroupAction represents the action of a deployment group run
+	DeploymentGroupAction string
+
 	// DiagnosticsData represents the diagnostics data for an environment
 	// this contains the logs, telnet, traceroute, dns and proxy information
 	// which will be part of the DockerSnapshot and KubernetesSnapshot structs
@@ -210,7 +270,7 @@ type (
 		// Username used to authenticate against the DockerHub
 		Username string `json:"Username" example:"user"`
 		// Password used to authenticate against the DockerHub
-		Password string `json:"Password,omitempty" example:"passwd"`
+		Password string `json:"Password,omitempty" example:"passwd" secret:"true"`
 	}
 
 	// DockerSnapshot represents a snapshot of a specific Docker environment(endpoint) at a specific time
@@ -271,11 +331,34 @@ type (
 		TagIDs       []TagID      `json:"TagIds"`
 		Endpoints    []EndpointID `json:"Endpoints"`
 		PartialMatch bool         `json:"PartialMatch"`
+		// Selector restricts the members of a dynamic Edge group, in addition to its tags
+		Selector *EdgeGroupSelector `json:"Selector,omitempty"`
 	}
 
 	// EdgeGroupID represents an Edge group identifier
 	EdgeGroupID int
 
+	// EdgeGroupSelector represents an expression matching environments(endpoints) for a dynamic Edge group.
+	// A selector either combines its nested selectors with an operator or matches a single field.
+	EdgeGroupSelector struct {
+		// Operator used to combine the nested selectors, empty for a field selector
+		Operator EdgeGroupSelectorOperator `json:"Operator,omitempty" example:"and" enums:"and,or,not"`
+		// Nested selectors, "not" accepts exactly one
+		Selectors []EdgeGroupSelector `json:"Selectors,omitempty"`
+		// Field matched by this selector
+		Field EdgeGroupSelectorField `json:"Field,omitempty" example:"name" enums:"name,groupId,agentVersion,type,platform,containerEngine,lastCheckInAge,label"`
+		// Label key, only used by the label field
+		Key string `json:"Key,omitempty" example:"site"`
+		// Value to match, its format depends on the field
+		Value string `json:"Value,omitempty" example:"paris-*"`
+	}
+
+	// EdgeGroupSelectorOperator represents the logical operator of an Edge group selector
+	EdgeGroupSelectorOperator string
+
+	// EdgeGroupSelectorField represents the environment(endpoint) field matched by an Edge group selector
+	EdgeGroupSelectorField string
+
 	// EdgeJob represents a job that can run on Edge environments(endpoints).
 	EdgeJob struct {
 		// EdgeJob Identifier
@@ -344,6 +427,18 @@ type (
 		DeploymentType EdgeStackDeploymentType `json:"DeploymentType"`
 		// Uses the manifest's namespaces instead of the default one
 		UseManifestNamespaces bool
+		// RollbackPolicy defines how environments react to a failed deployment of a new version
+		RollbackPolicy EdgeStackRollbackPolicy `json:"RollbackPolicy"`
+		// PreviousVersion is the version whose files are kept to roll back to, 0 when none is available
+		PreviousVersion int `json:"PreviousVersion,omitempty"`
+		// GitReferenceName is the reference the files were cloned from, the deployed tag when a tag constraint was given
+		GitReferenceName string `json:"GitReferenceName,omitempty"`
+	}
+
+	// EdgeStackRollbackPolicy represents the automatic rollback policy of an edge stack
+	EdgeStackRollbackPolicy struct {
+		// Enabled instructs environments that fail to deploy a new version to redeploy the previous one
+		Enabled bool `json:"Enabled" example:"true"`
 	}
 
 	EdgeStackStatusForEnv struct {
@@ -422,6 +517,8 @@ type (
 		AzureCredentials AzureCredentials `json:"AzureCredentials,omitempty"`
 		// List of tag identifiers to which this environment(endpoint) is associated
 		TagIDs []TagID `json:"TagIds"`
+		// List of key/value labels associated to this environment(endpoint)
+		Labels []Pair `json:"Labels,omitempty"`
 		// The status of the environment(endpoint) (1 - up, 2 - down)
 		Status EndpointStatus `json:"Status" example:"1"`
 		// List of snapshots
@@ -511,6 +608,8 @@ type (
 		TeamAccessPolicies TeamAccessPolicies `json:"TeamAccessPolicies"`
 		// List of tags associated to this environment(endpoint) group
 		TagIDs []TagID `json:"TagIds"`
+		// List of key/value labels inherited by the environments(endpoints) of this group
+		EnvironmentLabels []Pair `json:"EnvironmentLabels"`
 
 		// Deprecated fields
 		Labels []Pair `json:"Labels"`
@@ -726,6 +825,25 @@ type (
 	// InternalAuthSettings represents settings used for the default 'internal' authentication
 	InternalAuthSettings struct {
 		RequiredPasswordLength int
+		// Whether internal users must enrol a TOTP second factor to log in
+		EnforceTwoFactor bool `json:"EnforceTwoFactor" example:"false"`
+		// Rules applied to the passwords of internal users on top of the required length
+		PasswordPolicy PasswordPolicy `json:"PasswordPolicy"`
+	}
+
+	// PasswordPolicy represents the rules a password of an internal user must follow
+	PasswordPolicy struct {
+		RequireUppercase bool `json:"RequireUppercase" example:"true"`
+		RequireLowercase bool `json:"RequireLowercase" example:"true"`
+		RequireDigit     bool `json:"RequireDigit" example:"true"`
+		// Require a character that is neither a letter nor a digit
+		RequireSpecial bool `json:"RequireSpecial" example:"false"`
+		// Reject the passwords found in the embedded list of common passwords
+		DenyCommonPasswords bool `json:"DenyCommonPasswords" example:"true"`
+		// Number of previous passwords that cannot be reused, 0 to allow reuse
+		HistorySize int `json:"HistorySize" example:"5"`
+		// Number of days after which users must change their password at next login, 0 to disable
+		MaxAgeDays int `json:"MaxAgeDays" example:"90"`
 	}
 
 	// LDAPGroupSearchSettings represents settings used to search for groups in a LDAP server
@@ -736,6 +854,10 @@ type (
 		GroupFilter string `json:"GroupFilter" example:"(objectClass=account"`
 		// LDAP attribute which denotes the group membership
 		GroupAttribute string `json:"GroupAttribute" example:"member"`
+		// Whether the groups which are members of the groups of the user are resolved too
+		NestedGroups bool `json:"NestedGroups,omitempty" example:"true"`
+		// Whether the nested groups are resolved by the server with the Active Directory LDAP_MATCHING_RULE_IN_CHAIN rule
+		MatchingRuleInChain bool `json:"MatchingRuleInChain,omitempty" example:"false"`
 	}
 
 	// LDAPSearchSettings represents settings used to search for users in a LDAP server
@@ -755,7 +877,7 @@ type (
 		// Account that will be used to search for users
 		ReaderDN string `json:"ReaderDN" example:"cn=readonly-account,dc=ldap,dc=domain,dc=tld" validate:"required_if=AnonymousMode false"`
 		// Password of the account that will be used to search users
-		Password string `json:"Password,omitempty" example:"readonly-password" validate:"required_if=AnonymousMode false"`
+		Password string `json:"Password,omitempty" example:"readonly-password" validate:"required_if=AnonymousMode false" secret:"true"`
 		// URL or IP address of the LDAP server
 		URL       string           `json:"URL" example:"myldap.domain.tld:389" validate:"hostname_port"`
 		TLSConfig TLSConfiguration `json:"TLSConfig"`
@@ -765,6 +887,8 @@ type (
 		GroupSearchSettings []LDAPGroupSearchSettings `json:"GroupSearchSettings"`
 		// Automatically provision users and assign them to matching LDAP group names
 		AutoCreateUsers bool `json:"AutoCreateUsers" example:"true"`
+		// Interval of the synchronization of the users and team memberships with the directory, empty to disable it
+		SyncInterval string `json:"SyncInterval,omitempty" example:"1h"`
 	}
 
 	// LDAPUser represents a LDAP user
@@ -773,6 +897,34 @@ type (
 		Groups []string
 	}
 
+	// LDAPDirectory represents the users and groups read from a LDAP server
+	LDAPDirectory struct {
+		// Groups of the users found in the directory, indexed by username
+		UserGroups map[string][]string
+		// Names of all the groups of the directory
+		Groups []string
+	}
+
+	// LDAPSyncMembership represents a team membership changed by a LDAP synchronization
+	LDAPSyncMembership struct {
+		Username string `json:"Username" example:"bob"`
+		Team     string `json:"Team" example:"developers"`
+	}
+
+	// LDAPSyncReport represents the changes made, or that would be made, by a LDAP synchronization
+	LDAPSyncReport struct {
+		// Unix timestamp (UTC) of the synchronization
+		Time int64 `json:"Time" example:"1700000000"`
+		// Whether the changes were only computed and not applied
+		DryRun bool `json:"DryRun" example:"true"`
+		// Users no longer found in the directory
+		DisabledUsers []string `json:"DisabledUsers"`
+		// Disabled users found again in the directory
+		EnabledUsers       []string             `json:"EnabledUsers"`
+		AddedMemberships   []LDAPSyncMembership `json:"AddedMemberships"`
+		RemovedMemberships []LDAPSyncMembership `json:"RemovedMemberships"`
+	}
+
 	// ExtensionLicenseInformation represents information about an extension license
 	ExtensionLicenseInformation struct {
 		LicenseKey string `json:"LicenseKey,omitempty"`
@@ -787,7 +939,7 @@ type (
 	// OAuthSettings represents the settings used to authorize with an authorization server
 	OAuthSettings struct {
 		ClientID             string           `json:"ClientID"`
-		ClientSecret         string           `json:"ClientSecret,omitempty"`
+		ClientSecret         string           `json:"ClientSecret,omitempty" secret:"true"`
 		AccessTokenURI       string           `json:"AccessTokenURI"`
 		AuthorizationURI     string           `json:"AuthorizationURI"`
 		ResourceURI          string           `json:"ResourceURI"`
@@ -826,7 +978,7 @@ type (
 		// Username or AccessKeyID used to authenticate against this registry
 		Username string `json:"Username" example:"registry user"`
 		// Password or SecretAccessKey used to authenticate against this registry
-		Password                string                           `json:"Password,omitempty" example:"registry_password"`
+		Password                string                           `json:"Password,omitempty" example:"registry_password" secret:"true"`
 		ManagementConfiguration *RegistryManagementConfiguration `json:"ManagementConfiguration"`
 		Gitlab                  GitlabRegistryData               `json:"Gitlab"`
 		Github                  GithubRegistryData               `json:"Github"`
@@ -846,7 +998,7 @@ type (
 		AuthorizedTeams []TeamID `json:"AuthorizedTeams"`
 
 		// Stores temporary access token
-		AccessToken       string `json:"AccessToken,omitempty"`
+		AccessToken       string `json:"AccessToken,omitempty" secret:"true"`
 		AccessTokenExpiry int64  `json:"AccessTokenExpiry,omitempty"`
 	}
 
@@ -867,10 +1019,10 @@ type (
 		Type              RegistryType     `json:"Type"`
 		Authentication    bool             `json:"Authentication"`
 		Username          string           `json:"Username"`
-		Password          string           `json:"Password"`
+		Password          string           `json:"Password" secret:"true"`
 		TLSConfig         TLSConfiguration `json:"TLSConfig"`
 		Ecr               EcrData          `json:"Ecr"`
-		AccessToken       string           `json:"AccessToken,omitempty"`
+		AccessToken       string           `json:"AccessToken,omitempty" secret:"true"`
 		AccessTokenExpiry int64            `json:"AccessTokenExpiry,omitempty"`
 	}
 
@@ -941,8 +1093,78 @@ type (
 		DateCreated int64    `json:"dateCreated"`      // Unix timestamp (UTC) when the API key was created
 		LastUsed    int64    `json:"lastUsed"`         // Unix timestamp (UTC) when the API key was last used
 		Digest      string   `json:"digest,omitempty"` // Digest represents SHA256 hash of the raw API key
+		// Unix timestamp (UTC) after which the API key is rejected, 0 when it never expires
+		ExpiresAt int64 `json:"expiresAt,omitempty" example:"1700000000"`
+		// Restrictions applied on top of the permissions of the user, nil for an unrestricted key
+		Scope *APIKeyScope `json:"scope,omitempty"`
+	}
+
+	// APIKeyScope restricts the requests that can be authenticated with an API key
+	APIKeyScope struct {
+		// Only allow the read operations (GET, HEAD and OPTIONS)
+		ReadOnly bool `json:"readOnly" example:"false"`
+		// Environments the key can access, all the environments of the user when empty
+		EndpointIDs []EndpointID `json:"endpointIds,omitempty"`
+		// Route groups the key can access, all the routes when empty
+		RouteGroups []APIKeyRouteGroup `json:"routeGroups,omitempty" example:"stacks"`
+	}
+
+	// APIKeyRouteGroup represents a group of API routes an API key can be restricted to
+	APIKeyRouteGroup string
+
+	// SAMLSettings represents the settings used to authenticate users against a SAML 2.0 identity provider
+	SAMLSettings struct {
+		// Entity ID of the identity provider, expected as issuer of the assertions
+		IdPEntityID string `json:"IdPEntityID" example:"https://idp.mydomain.tld/saml"`
+		// URL of the single sign-on service of the identity provider, using the HTTP-Redirect binding
+		IdPSSOURL string `json:"IdPSSOURL" example:"https://idp.mydomain.tld/saml/sso"`
+		// PEM encoded certificates used by the identity provider to sign its responses
+		IdPCertificate string `json:"IdPCertificate"`
+		// Public URL of Portainer, used to build the entity ID and the assertion consumer service URL of Portainer
+		ServiceProviderURL string `json:"ServiceProviderURL" example:"https://portainer.mydomain.tld"`
+		// Name of the attribute holding the username, the subject name ID is used when empty
+		UsernameAttribute string `json:"UsernameAttribute" example:"email"`
+		// Name of the attribute holding the groups of the user
+		GroupsAttribute string `json:"GroupsAttribute" example:"groups"`
+		// Whether the users unknown to Portainer are created on their first login
+		AutoCreateUsers bool `json:"AutoCreateUsers" example:"true"`
+		// Team the users created on their first login are added to
+		DefaultTeamID TeamID `json:"DefaultTeamID" example:"1"`
+		// Teams the users are added to according to their groups
+		GroupTeamMappings []SAMLGroupTeamMapping `json:"GroupTeamMappings"`
 	}
 
+	// SAMLGroupTeamMapping maps a group of the identity provider to a Portainer team
+	SAMLGroupTeamMapping struct {
+		Group  string `json:"Group" example:"developers"`
+		TeamID TeamID `json:"TeamID" example:"1"`
+	}
+
+	// SAMLIdentity represents a user authenticated by a SAML identity provider
+	SAMLIdentity struct {
+		Username string
+		Groups   []string
+	}
+
+	// ClientCertificateSettings represents the settings used to authenticate users with TLS client certificates
+	ClientCertificateSettings struct {
+		// Whether users can authenticate with a client certificate
+		Enabled bool `json:"Enabled" example:"true"`
+		// PEM encoded certificates of the authorities issuing the client certificates
+		CACertificates string `json:"CACertificates,omitempty"`
+		// Field of the certificate holding the username, the subject common name when empty
+		UsernameSource ClientCertificateUsernameSource `json:"UsernameSource,omitempty" example:"email"`
+		// Whether the users unknown to Portainer are created on their first login
+		AutoCreateUsers bool `json:"AutoCreateUsers,omitempty" example:"true"`
+		// Regular expression the whole username must match to be created on their first login, required when AutoCreateUsers is set
+		AutoCreateUsernamePattern string `json:"AutoCreateUsernamePattern,omitempty" example:".+@mydomain\\.tld"`
+		// Team the users created on their first login are added to
+		DefaultTeamID TeamID `json:"DefaultTeamID,omitempty" example:"1"`
+	}
+
+	// ClientCertificateUsernameSource represents the field of a client certificate holding the username
+	ClientCertificateUsernameSource string
+
 	// Schedule represents a scheduled job.
 	// It only contains a pointer to one of the JobRunner implementations
 	// based on the JobType.
@@ -994,13 +1216,16 @@ type (
 		LogoURL string `json:"LogoURL" example:"https://mycompany.mydomain.tld/logo.png"`
 		// A list of label name & value that will be used to hide containers when querying containers
 		BlackListedLabels []Pair `json:"BlackListedLabels"`
-		// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, or 3 for oauth
-		AuthenticationMethod AuthenticationMethod          `json:"AuthenticationMethod" example:"1"`
-		InternalAuthSettings InternalAuthSettings          `json:"InternalAuthSettings"`
-		LDAPSettings         LDAPSettings                  `json:"LDAPSettings"`
-		OAuthSettings        OAuthSettings                 `json:"OAuthSettings"`
-		OpenAMTConfiguration OpenAMTConfiguration          `json:"openAMTConfiguration"`
-		FeatureFlagSettings  map[featureflags.Feature]bool `json:"FeatureFlagSettings"`
+		// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, 3 for oauth or 4 for SAML
+		AuthenticationMethod AuthenticationMethod `json:"AuthenticationMethod" example:"1"`
+		InternalAuthSettings InternalAuthSettings `json:"InternalAuthSettings"`
+		LDAPSettings         LDAPSettings         `json:"LDAPSettings"`
+		OAuthSettings        OAuthSettings        `json:"OAuthSettings"`
+		SAMLSettings         SAMLSettings         `json:"SAMLSettings"`
+		// Authentication with TLS client certificates, available whatever the authentication method
+		ClientCertificateSettings ClientCertificateSettings     `json:"ClientCertificateSettings"`
+		OpenAMTConfiguration      OpenAMTConfiguration          `json:"openAMTConfiguration"`
+		FeatureFlagSettings       map[featureflags.Feature]bool `json:"FeatureFlagSettings"`
 		// The interval in which environment(endpoint) snapshots are created
 		SnapshotInterval string `json:"SnapshotInterval" example:"5m"`
 		// URL to the templates that will be displayed in the UI when navigating to App Templates
@@ -1061,7 +1286,37 @@ type (
 		KeyPath     string `json:"keyPath"`
 		SelfSigned  bool   `json:"selfSigned"`
 		HTTPEnabled bool   `json:"httpEnabled"`
-	}
+		// ACME configuration, used to request and renew the certificate from an ACME server such as Let's Encrypt
+		ACME *ACMESettings `json:"acme,omitempty"`
+	}
+
+	// ACMESettings represents the configuration of the ACME client
+	ACMESettings struct {
+		Enabled bool `json:"enabled" example:"true"`
+		// URL of the ACME directory, defaults to Let's Encrypt production
+		DirectoryURL string `json:"directoryURL" example:"https://acme-v02.api.letsencrypt.org/directory"`
+		// Contact email of the ACME account
+		Email string `json:"email" example:"admin@example.com"`
+		// Domains of the certificate, the first one is used as the common name
+		Domains []string `json:"domains" example:"portainer.example.com"`
+		// Challenge used to prove the control of the domains
+		ChallengeType ACMEChallengeType `json:"challengeType" example:"http-01"`
+		// Name of the DNS provider used for the dns-01 challenge
+		DNSProvider string `json:"dnsProvider,omitempty" example:"cloudflare"`
+		// Configuration of the DNS provider
+		DNSProviderConfig map[string]string `json:"dnsProviderConfig,omitempty" secret:"true"`
+		// Number of days before the expiration of the certificate when it is renewed
+		RenewBeforeDays int `json:"renewBeforeDays" example:"30"`
+		// Expiration of the current certificate, unix timestamp
+		NotAfter int64 `json:"notAfter,omitempty" example:"1700000000"`
+		// Last renewal, unix timestamp
+		LastRenewal int64 `json:"lastRenewal,omitempty" example:"1700000000"`
+		// Error of the last failed renewal attempt
+		LastError string `json:"lastError,omitempty"`
+	}
+
+	// ACMEChallengeType represents the type of an ACME challenge
+	ACMEChallengeType string
 
 	// Stack represents a Docker stack created via docker stack deploy
 	Stack struct {
@@ -1105,6 +1360,41 @@ type (
 		FromAppTemplate bool `example:"false"`
 		// Kubernetes namespace if stack is a kube application
 		Namespace string `example:"default"`
+		// Identifiers of the variable sets whose variables are inherited by the stack, the variables of Env override them
+		VariableSetIDs []VariableSetID `json:"VariableSetIds" example:"1"`
+		// Output of the last build of the images of a Compose stack
+		BuildLog *StackBuildLog `json:"BuildLog,omitempty"`
+		// Last push events received by the webhook of the stack, the most recent last
+		WebhookDeliveries []StackWebhookDelivery `json:"WebhookDeliveries,omitempty"`
+	}
+
+	// StackWebhookDelivery represents a push event received from a git provider by the webhook of a stack
+	StackWebhookDelivery struct {
+		// Identifier of the delivery given by the provider
+		ID string `example:"72d3162e-cc78-11e3-81ab-4c9367dc0958"`
+		// Git provider which sent the push event
+		Provider GitWebhookProvider `example:"github"`
+		// Unix timestamp of the reception of the push event
+		Date int64 `example:"1587399600"`
+		// Pushed reference
+		Ref string `example:"refs/heads/main"`
+		// Commit the reference was pushed to
+		CommitSHA string `example:"a5c9e5d3f2e7c3b04d8d6a7d5e4f3c2b1a0f9e8d"`
+		// Whether the pushed reference is the one deployed by the stack and redeployed it, a redeployment skipped by the
+		// path filters or the signature requirement or which failed is recorded as not triggered once it ends
+		Triggered bool `example:"true"`
+		// Reason of the failure of the redeployment
+		Error string `json:",omitempty"`
+	}
+
+	// StackBuildLog represents the output of the build of the images of a Compose stack
+	StackBuildLog struct {
+		// Unix timestamp of the end of the build
+		Date int64 `example:"1587399600"`
+		// Whether the deployment of the stack succeeded
+		Success bool `example:"true"`
+		// Output of the build, only its last part is kept for the long builds
+		Output string `example:"Step 1/2 : FROM alpine"`
 	}
 
 	// StackOption represents the options for stack deployment
@@ -1113,12 +1403,43 @@ type (
 		Prune bool `example:"false"`
 	}
 
+	// StackHealth represents the computed status of a deployed stack and the status of each of its services
+	StackHealth struct {
+		// Overall status of the stack, one of running, starting, degraded, error, completed, stopped, removing, removed or unknown
+		Status string `json:"Status" example:"running"`
+		// Status of each service of the stack
+		Services []StackServiceHealth `json:"Services"`
+	}
+
+	// StackServiceHealth represents the status of a service of a deployed stack
+	StackServiceHealth struct {
+		// Service name
+		Name string `json:"Name" example:"web"`
+		// Status of the service, see StackHealth.Status
+		Status string `json:"Status" example:"running"`
+		// Number of replicas desired by the service
+		DesiredReplicas int `json:"DesiredReplicas" example:"2"`
+		// Number of replicas running
+		RunningReplicas int `json:"RunningReplicas" example:"2"`
+		// Worst health check state of the replicas, empty when the service has no health check
+		Health string `json:"Health" example:"healthy"`
+		// Number of times the replicas of the service were restarted
+		RestartCount int `json:"RestartCount" example:"0"`
+		// Exit code of the last replica that exited
+		ExitCode int `json:"ExitCode" example:"0"`
+		// Last lines of logs of a failing service
+		LogsTail string `json:"LogsTail,omitempty"`
+	}
+
 	// StackID represents a stack identifier (it must be composed of Name + "_" + SwarmID to create a unique identifier)
 	StackID int
 
 	// StackStatus represent a status for a stack
 	StackStatus int
 
+	// GitWebhookProvider represents the git provider sending the push events to a GitOps webhook
+	GitWebhookProvider string
+
 	// StackType represents the type of the stack (compose v2, stack deploy v3)
 	StackType int
 
@@ -1323,6 +1644,8 @@ type (
 		Role                UserRole
 		ForceChangePassword bool
 		Token               string
+		// Scope of the API key used to authenticate the request, if any
+		APIKeyScope *APIKeyScope
 	}
 
 	// TunnelDetails represents information associated to a tunnel
@@ -1349,6 +1672,17 @@ type (
 		TokenIssueAt  int64             `json:"TokenIssueAt" example:"1"`
 		ThemeSettings UserThemeSettings `json:"ThemeSettings"`
 		UseCache      bool              `json:"UseCache" example:"true"`
+		// Second authentication factor of the user
+		TwoFactor UserTwoFactor `json:"TwoFactor"`
+		// Unix timestamp (UTC) of the last password change, 0 when unknown
+		PasswordChangedAt int64 `json:"PasswordChangedAt,omitempty" example:"1700000000"`
+		// Hashes of the previous passwords, most recent first
+		PasswordHistory []string `json:"PasswordHistory,omitempty" swaggerignore:"true"`
+		// Whether the user is no longer allowed to log in, set when it is no longer found in the LDAP directory
+		Disabled bool `json:"Disabled,omitempty" example:"false"`
+		// Authentication the user logs in with, empty for the users created before it was recorded until their next
+		// LDAP login
+		Origin UserOrigin `json:"Origin,omitempty" example:"ldap"`
 
 		// Deprecated fields
 
@@ -1360,6 +1694,21 @@ type (
 		EndpointAuthorizations EndpointAuthorizations
 	}
 
+	// UserOrigin represents the authentication a user logs in with
+	UserOrigin string
+
+	// UserTwoFactor represents the TOTP second factor of an internal user
+	UserTwoFactor struct {
+		// Whether a code is required at login. The secret is pending confirmation while it is false
+		Enabled bool `json:"Enabled" example:"true"`
+		// Base32 encoded TOTP secret
+		Secret string `json:"Secret,omitempty" swaggerignore:"true" secret:"true"`
+		// Hashes of the unused recovery codes
+		RecoveryCodes []string `json:"RecoveryCodes,omitempty" swaggerignore:"true"`
+		// Last accepted TOTP time step, a code cannot be used twice
+		LastUsedStep int64 `json:"LastUsedStep,omitempty" swaggerignore:"true"`
+	}
+
 	// UserAccessPolicies represent the association of an access policy and a user
 	UserAccessPolicies map[UserID]AccessPolicy
 
@@ -1376,12 +1725,63 @@ type (
 	// or a regular user
 	UserRole int
 
+	// UserSessionID represents a user session identifier
+	UserSessionID int
+
+	// UserSession represents a JWT issued to a user, recorded when the token is first used
+	UserSession struct {
+		// Session Identifier
+		ID     UserSessionID `json:"Id" example:"1"`
+		UserID UserID        `json:"UserId" example:"1"`
+		// Identifier (jti) of the JWT
+		TokenID string `json:"TokenId,omitempty" swaggerignore:"true"`
+		// Unix timestamp (UTC) when the JWT was issued
+		IssuedAt int64 `json:"IssuedAt" example:"1700000000"`
+		// Unix timestamp (UTC) when the JWT expires, 0 when it never expires
+		ExpiresAt int64 `json:"ExpiresAt" example:"1700028800"`
+		// Unix timestamp (UTC) of the last request made with the JWT
+		LastSeen  int64  `json:"LastSeen" example:"1700000600"`
+		ClientIP  string `json:"ClientIP" example:"10.0.0.10"`
+		UserAgent string `json:"UserAgent" example:"Mozilla/5.0"`
+		// Whether the JWT has been revoked, revoked sessions are kept until they expire
+		Revoked bool `json:"Revoked" example:"false"`
+	}
+
 	// UserThemeSettings represents the theme settings for a user
 	UserThemeSettings struct {
 		// Color represents the color theme of the UI
 		Color string `json:"color" example:"dark" enums:"dark,light,highcontrast,auto"`
 	}
 
+	// VariableSet represents a named set of variables that the stacks deployed on an environment group, an
+	// environment, or by a team can inherit
+	VariableSet struct {
+		// Variable set identifier
+		ID VariableSetID `json:"Id" example:"1"`
+		// Variable set name
+		Name string `json:"Name" example:"production"`
+		// Scope of the variable set, 1 for an environment group, 2 for an environment and 3 for a team
+		ScopeType VariableSetScopeType `json:"ScopeType" example:"1"`
+		// Identifier of the environment group, environment or team the variable set is scoped to
+		ScopeID int `json:"ScopeId" example:"1"`
+		// Variables of the set
+		Variables []StackVariable `json:"Variables"`
+	}
+
+	// VariableSetID represents a variable set identifier
+	VariableSetID int
+
+	// VariableSetScopeType represents the kind of object a variable set is scoped to
+	VariableSetScopeType int
+
+	// StackVariable represents a variable of a variable set, the values are encrypted at rest when a secrets key is
+	// loaded and the values of the secret variables are masked in the API responses
+	StackVariable struct {
+		Name   string `json:"Name" example:"DB_HOST"`
+		Value  string `json:"Value" example:"db.internal" secret:"true"`
+		Secret bool   `json:"Secret" example:"false"`
+	}
+
 	// Webhook represents a url webhook that can be used to update a service
 	Webhook struct {
 		// Webhook Identifier
@@ -1453,6 +1853,13 @@ type (
 		Detached bool
 	}
 
+	ComposeRecreateServiceOptions struct {
+		ComposeOptions
+
+		// PullImage pulls the image of the service before recreating its containers
+		PullImage bool
+	}
+
 	// ComposeStackManager represents a service to manage Compose stacks
 	ComposeStackManager interface {
 		ComposeSyntaxMaxVersion() string
@@ -1461,6 +1868,14 @@ type (
 		Up(ctx context.Context, stack *Stack, endpoint *Endpoint, options ComposeUpOptions) error
 		Down(ctx context.Context, stack *Stack, endpoint *Endpoint) error
 		Pull(ctx context.Context, stack *Stack, endpoint *Endpoint, options ComposeOptions) error
+		StartService(ctx context.Context, stack *Stack, endpoint *Endpoint, serviceName string) error
+		StopService(ctx context.Context, stack *Stack, endpoint *Endpoint, serviceName string) error
+		RestartService(ctx context.Context, stack *Stack, endpoint *Endpoint, serviceName string) error
+		ScaleService(ctx context.Context, stack *Stack, endpoint *Endpoint, serviceName string, replicas int) error
+		RecreateService(ctx context.Context, stack *Stack, endpoint *Endpoint, serviceName string, options ComposeRecreateServiceOptions) error
+		Status(ctx context.Context, stack *Stack, endpoint *Endpoint) (*StackHealth, error)
+		// WaitForRunning waits for the services of the stack to run with their health checks passing
+		WaitForRunning(ctx context.Context, stack *Stack, endpoint *Endpoint) error
 	}
 
 	// CryptoService represents a service for encrypting/hashing data
@@ -1534,6 +1949,8 @@ type (
 		GetMTLSCertificates() (string, string, string, error)
 		GetDefaultChiselPrivateKeyPath() string
 		StoreChiselPrivateKey(privateKey []byte) error
+		GetDefaultACMEAccountKeyPath() string
+		StoreACMEAccountKey(privateKey []byte) error
 	}
 
 	// GitService represents a service for managing Git
@@ -1542,6 +1959,14 @@ type (
 		LatestCommitID(repositoryURL, referenceName, username, password string, tlsSkipVerify bool) (string, error)
 		ListRefs(repositoryURL, username, password string, hardRefresh bool, tlsSkipVerify bool) ([]string, error)
 		ListFiles(repositoryURL, referenceName, username, password string, dirOnly, hardRefresh bool, includeExts []string, tlsSkipVerify bool) ([]string, error)
+		CloneCommit(destination, repositoryURL, referenceName, commitID, username, password string, tlsSkipVerify bool) error
+		FetchHistory(repositoryURL, referenceName, username, password string, tlsSkipVerify bool, depth int) (GitHistory, error)
+	}
+
+	// GitHistory represents the last commits fetched from a git reference
+	GitHistory interface {
+		ChangedFiles(fromCommitID, toCommitID string) ([]string, error)
+		VerifyCommitSignature(commitID string, trustedKeys []string) error
 	}
 
 	// OpenAMTService represents a service for managing OpenAMT
@@ -1703,6 +2128,20 @@ type (
 		GetUserGroups(username string, settings *LDAPSettings) ([]string, error)
 		SearchGroups(settings *LDAPSettings) ([]LDAPUser, error)
 		SearchUsers(settings *LDAPSettings) ([]string, error)
+		ReadDirectory(usernames []string, settings *LDAPSettings) (*LDAPDirectory, error)
+	}
+
+	// LDAPSyncService represents a service used to synchronize the users and team memberships with a LDAP/AD
+	LDAPSyncService interface {
+		SetSyncInterval(interval string) error
+		Sync(dryRun bool) (*LDAPSyncReport, error)
+	}
+
+	// ClientCertificateService represents a service used to authenticate users with the TLS client certificate of the requests
+	ClientCertificateService interface {
+		SetSettings(settings *ClientCertificateSettings) error
+		Username(r *http.Request) (string, error)
+		CanCreateUser(username string) bool
 	}
 
 	// OAuthService represents a service used to authenticate users using OAuth
@@ -1710,6 +2149,13 @@ type (
 		Authenticate(code string, configuration *OAuthSettings) (string, error)
 	}
 
+	// SAMLService represents a service used to authenticate users using SAML 2.0
+	SAMLService interface {
+		AuthnRequestURL(settings *SAMLSettings) (string, error)
+		Authenticate(samlResponse string, settings *SAMLSettings) (*SAMLIdentity, error)
+		Metadata(settings *SAMLSettings) ([]byte, error)
+	}
+
 	// ReverseTunnelService represents a service used to manage reverse tunnel connections.
 	ReverseTunnelService interface {
 		StartTunnelServer(addr, port string, snapshotService SnapshotService) error
@@ -1737,10 +2183,11 @@ type (
 
 	// SwarmStackManager represents a service to manage Swarm stacks
 	SwarmStackManager interface {
-		Login(registries []Registry, endpoint *Endpoint) error
-		Logout(endpoint *Endpoint) error
-		Deploy(stack *Stack, prune bool, pullImage bool, endpoint *Endpoint) error
+		Deploy(stack *Stack, prune bool, pullImage bool, endpoint *Endpoint, registries []Registry) error
 		Remove(stack *Stack, endpoint *Endpoint) error
+		Status(stack *Stack, endpoint *Endpoint) (*StackHealth, error)
+		// WaitForRunning waits for the services of the stack to converge to their running replicas
+		WaitForRunning(ctx context.Context, stack *Stack, endpoint *Endpoint) error
 		NormalizeStackName(name string) string
 	}
 )
@@ -1772,6 +2219,8 @@ const (
 	PortainerAgentSignatureHeader = "X-PortainerAgent-Signature"
 	// PortainerAgentPublicKeyHeader represent the name of the header containing the public key
 	PortainerAgentPublicKeyHeader = "X-PortainerAgent-PublicKey"
+	// PortainerAgentManagerOperationHeader represent the name of the header forcing an agent to forward a request to a Swarm manager node
+	PortainerAgentManagerOperationHeader = "X-PortainerAgent-ManagerOperation"
 	// PortainerAgentKubernetesSATokenHeader represent the name of the header containing a Kubernetes SA token
 	PortainerAgentKubernetesSATokenHeader = "X-PortainerAgent-SA-Token"
 	// PortainerAgentSignatureMessage represents the message used to create a digital signature
@@ -1808,6 +2257,10 @@ const (
 	LicenseCheckInURL = LicenseServerBaseURL + "/licenses/checkin"
 	// TrustedOriginsEnvVar is the environment variable used to set the trusted origins for CSRF protection
 	TrustedOriginsEnvVar = "TRUSTED_ORIGINS"
+	// TrustedProxiesEnvVar is the environment variable used to set the reverse proxies allowed to set X-Forwarded-For
+	TrustedProxiesEnvVar = "TRUSTED_PROXIES"
+	// SecretsKeyEnvVar is the environment variable used to set the master key of the secret fields encryption
+	SecretsKeyEnvVar = "SECRETS_KEY"
 	// CSPEnvVar is the environment variable used to enable/disable the Content Security Policy
 	CSPEnvVar = "CSP"
 )
@@ -1823,6 +2276,30 @@ const (
 	AuthenticationLDAP
 	// AuthenticationOAuth represents the OAuth authentication method (authentication against a authorization server)
 	AuthenticationOAuth
+	// AuthenticationSAML represents the SAML authentication method (authentication against a SAML 2.0 identity provider)
+	AuthenticationSAML
+)
+
+const (
+	// UserOriginInternal represents the users logging in with a password stored by Portainer
+	UserOriginInternal UserOrigin = "internal"
+	// UserOriginLDAP represents the users authenticated against the LDAP directory, managed by the LDAP synchronization
+	UserOriginLDAP UserOrigin = "ldap"
+	// UserOriginOAuth represents the users authenticated by the OAuth authorization server
+	UserOriginOAuth UserOrigin = "oauth"
+	// UserOriginSAML represents the users authenticated by the SAML identity provider
+	UserOriginSAML UserOrigin = "saml"
+	// UserOriginCertificate represents the users created from a TLS client certificate
+	UserOriginCertificate UserOrigin = "certificate"
+)
+
+const (
+	// ClientCertificateUsernameCommonName uses the common name of the subject of the certificate as username
+	ClientCertificateUsernameCommonName ClientCertificateUsernameSource = "commonName"
+	// ClientCertificateUsernameEmail uses the first email address of the subject alternative names as username
+	ClientCertificateUsernameEmail ClientCertificateUsernameSource = "email"
+	// ClientCertificateUsernameDNSName uses the first DNS name of the subject alternative names as username
+	ClientCertificateUsernameDNSName ClientCertificateUsernameSource = "dnsName"
 )
 
 const (
@@ -1851,6 +2328,34 @@ const (
 	CustomTemplatePlatformWindows
 )
 
+const (
+	// EdgeGroupSelectorAnd matches environments matched by all the nested selectors
+	EdgeGroupSelectorAnd EdgeGroupSelectorOperator = "and"
+	// EdgeGroupSelectorOr matches environments matched by at least one of the nested selectors
+	EdgeGroupSelectorOr EdgeGroupSelectorOperator = "or"
+	// EdgeGroupSelectorNot matches environments not matched by the nested selector
+	EdgeGroupSelectorNot EdgeGroupSelectorOperator = "not"
+)
+
+const (
+	// EdgeGroupSelectorName matches the environment name against a glob pattern
+	EdgeGroupSelectorName EdgeGroupSelectorField = "name"
+	// EdgeGroupSelectorGroupID matches the environment group identifier
+	EdgeGroupSelectorGroupID EdgeGroupSelectorField = "groupId"
+	// EdgeGroupSelectorAgentVersion matches the agent version against a semver constraint
+	EdgeGroupSelectorAgentVersion EdgeGroupSelectorField = "agentVersion"
+	// EdgeGroupSelectorType matches the environment type identifier
+	EdgeGroupSelectorType EdgeGroupSelectorField = "type"
+	// EdgeGroupSelectorPlatform matches the environment platform, either "docker" or "kubernetes"
+	EdgeGroupSelectorPlatform EdgeGroupSelectorField = "platform"
+	// EdgeGroupSelectorContainerEngine matches the container engine, such as "docker" or "podman"
+	EdgeGroupSelectorContainerEngine EdgeGroupSelectorField = "containerEngine"
+	// EdgeGroupSelectorLastCheckInAge matches environments which checked in within the given duration
+	EdgeGroupSelectorLastCheckInAge EdgeGroupSelectorField = "lastCheckInAge"
+	// EdgeGroupSelectorLabel matches the value of an environment label against a glob pattern
+	EdgeGroupSelectorLabel EdgeGroupSelectorField = "label"
+)
+
 const (
 	// EdgeStackDeploymentCompose represent an edge stack deployed using a compose file
 	EdgeStackDeploymentCompose EdgeStackDeploymentType = iota
@@ -2028,6 +2533,30 @@ const (
 	StackStatusInactive
 )
 
+const (
+	GitWebhookProviderGitHub    GitWebhookProvider = "github"
+	GitWebhookProviderGitLab    GitWebhookProvider = "gitlab"
+	GitWebhookProviderGitea     GitWebhookProvider = "gitea"
+	GitWebhookProviderBitbucket GitWebhookProvider = "bitbucket"
+)
+
+const (
+	_ VariableSetScopeType = iota
+	// EndpointGroupVariableSetScope represents a variable set inherited by the stacks of the environments of a group
+	EndpointGroupVariableSetScope
+	// EndpointVariableSetScope represents a variable set inherited by the stacks of an environment
+	EndpointVariableSetScope
+	// TeamVariableSetScope represents a variable set inherited by the stacks referencing it on behalf of a team
+	TeamVariableSetScope
+)
+
+const (
+	// DeploymentGroupDeploy represents the deployment of the stacks of a deployment group
+	DeploymentGroupDeploy DeploymentGroupAction = "deploy"
+	// DeploymentGroupTeardown represents the removal of the stacks of a deployment group
+	DeploymentGroupTeardown DeploymentGroupAction = "teardown"
+)
+
 const (
 	_ TemplateType = iota
 	// ContainerTemplate represents a container template
@@ -2316,3 +2845,27 @@ const (
 	ContainerEngineDocker = "docker"
 	ContainerEnginePodman = "podman"
 )
+
+const (
+	// ACMEChallengeHTTP01 proves the control of a domain by serving a token on the HTTP listener
+	ACMEChallengeHTTP01 ACMEChallengeType = "http-01"
+	// ACMEChallengeDNS01 proves the control of a domain with a TXT record created by a DNS provider
+	ACMEChallengeDNS01 ACMEChallengeType = "dns-01"
+)
+
+const (
+	// APIKeyRouteGroupEnvironments covers the environments, their groups and the Docker and Kubernetes proxies
+	APIKeyRouteGroupEnvironments APIKeyRouteGroup = "environments"
+	// APIKeyRouteGroupStacks covers the stacks
+	APIKeyRouteGroupStacks APIKeyRouteGroup = "stacks"
+	// APIKeyRouteGroupWebhooks covers the service webhooks
+	APIKeyRouteGroupWebhooks APIKeyRouteGroup = "webhooks"
+	// APIKeyRouteGroupEdge covers the edge groups, stacks, jobs and update schedules
+	APIKeyRouteGroupEdge APIKeyRouteGroup = "edge"
+	// APIKeyRouteGroupRegistries covers the registries
+	APIKeyRouteGroupRegistries APIKeyRouteGroup = "registries"
+	// APIKeyRouteGroupTemplates covers the application and custom templates
+	APIKeyRouteGroupTemplates APIKeyRouteGroup = "templates"
+	// APIKeyRouteGroupUsers covers the users, teams and memberships
+	APIKeyRouteGroupUsers APIKeyRouteGroup = "users"
+)
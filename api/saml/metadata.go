@@ -0,0 +1,187 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	spEntityDescriptor struct {
+		XMLName         xml.Name           `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+		EntityID        string             `xml:"entityID,attr"`
+		SPSSODescriptor spSSODescriptorXML `xml:"SPSSODescriptor"`
+	}
+
+	spSSODescriptorXML struct {
+		AuthnRequestsSigned        bool        `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned       bool        `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupportEnumeration string      `xml:"protocolSupportEnumeration,attr"`
+		NameIDFormat               string      `xml:"NameIDFormat"`
+		AssertionConsumerService   endpointXML `xml:"AssertionConsumerService"`
+	}
+
+	endpointXML struct {
+		Binding  string `xml:"Binding,attr"`
+		Location string `xml:"Location,attr"`
+		Index    *int   `xml:"index,attr,omitempty"`
+	}
+
+	idpEntitiesDescriptor struct {
+		EntityDescriptors []idpEntityDescriptor `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	}
+
+	idpEntityDescriptor struct {
+		EntityID          string                `xml:"entityID,attr"`
+		IDPSSODescriptors []idpSSODescriptorXML `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
+	}
+
+	idpSSODescriptorXML struct {
+		KeyDescriptors      []keyDescriptorXML `xml:"urn:oasis:names:tc:SAML:2.0:metadata KeyDescriptor"`
+		SingleSignOnService []endpointXML      `xml:"urn:oasis:names:tc:SAML:2.0:metadata SingleSignOnService"`
+	}
+
+	keyDescriptorXML struct {
+		Use     string     `xml:"use,attr"`
+		KeyInfo keyInfoXML `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo"`
+	}
+
+	keyInfoXML struct {
+		X509Data []x509DataXML `xml:"http://www.w3.org/2000/09/xmldsig# X509Data"`
+	}
+
+	x509DataXML struct {
+		X509Certificates []string `xml:"http://www.w3.org/2000/09/xmldsig# X509Certificate"`
+	}
+)
+
+// IdPMetadata represents the settings of an identity provider found in its metadata
+type IdPMetadata struct {
+	EntityID string
+	// URL of the single sign-on service using the HTTP-Redirect binding
+	SSOURL string
+	// PEM encoded signing certificates
+	Certificate string
+}
+
+// Metadata returns the metadata describing Portainer to the identity provider
+func (service *Service) Metadata(settings *portainer.SAMLSettings) ([]byte, error) {
+	if settings.ServiceProviderURL == "" {
+		return nil, errors.Wrap(ErrSAMLNotConfigured, "the Portainer URL is required")
+	}
+
+	index := 0
+	descriptor := spEntityDescriptor{
+		EntityID: EntityID(settings),
+		SPSSODescriptor: spSSODescriptorXML{
+			AuthnRequestsSigned:        false,
+			WantAssertionsSigned:       true,
+			ProtocolSupportEnumeration: nsProtocol,
+			NameIDFormat:               nameIDUnspecified,
+			AssertionConsumerService: endpointXML{
+				Binding:  bindingHTTPPost,
+				Location: AssertionConsumerServiceURL(settings),
+				Index:    &index,
+			},
+		},
+	}
+
+	data, err := xml.MarshalIndent(descriptor, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+// ParseIdPMetadata extracts the entity ID, single sign-on URL and signing certificates from the metadata of an identity
+// provider, the metadata can either be an EntityDescriptor or an EntitiesDescriptor
+func ParseIdPMetadata(data []byte) (*IdPMetadata, error) {
+	// parseDocument rejects the DTDs and checks the document is well formed before it is unmarshalled
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptors []idpEntityDescriptor
+
+	switch {
+	case root.is(nsMetadata, "EntityDescriptor"):
+		var descriptor idpEntityDescriptor
+		if err := xml.Unmarshal(data, &descriptor); err != nil {
+			return nil, errors.Wrap(err, "invalid identity provider metadata")
+		}
+
+		descriptors = append(descriptors, descriptor)
+	case root.is(nsMetadata, "EntitiesDescriptor"):
+		var entities idpEntitiesDescriptor
+		if err := xml.Unmarshal(data, &entities); err != nil {
+			return nil, errors.Wrap(err, "invalid identity provider metadata")
+		}
+
+		descriptors = entities.EntityDescriptors
+	default:
+		return nil, errors.New("the document is not a SAML metadata document")
+	}
+
+	for _, descriptor := range descriptors {
+		if len(descriptor.IDPSSODescriptors) > 0 {
+			return idpMetadata(descriptor.EntityID, descriptor.IDPSSODescriptors[0])
+		}
+	}
+
+	return nil, errors.New("the metadata does not describe an identity provider")
+}
+
+func idpMetadata(entityID string, idp idpSSODescriptorXML) (*IdPMetadata, error) {
+	metadata := &IdPMetadata{EntityID: entityID}
+	if metadata.EntityID == "" {
+		return nil, errors.New("the identity provider metadata has no entity ID")
+	}
+
+	for _, service := range idp.SingleSignOnService {
+		if service.Binding == bindingHTTPRedirect {
+			metadata.SSOURL = service.Location
+
+			break
+		}
+	}
+
+	if metadata.SSOURL == "" {
+		return nil, errors.New("the identity provider does not support the HTTP-Redirect binding")
+	}
+
+	var certificates bytes.Buffer
+	for _, key := range idp.KeyDescriptors {
+		if key.Use != "" && key.Use != "signing" {
+			continue
+		}
+
+		for _, x509Data := range key.KeyInfo.X509Data {
+			for _, certificate := range x509Data.X509Certificates {
+				der, err := base64.StdEncoding.DecodeString(removeWhitespace(certificate))
+				if err != nil {
+					return nil, errors.Wrap(err, "invalid identity provider certificate")
+				}
+
+				if err := pem.Encode(&certificates, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	metadata.Certificate = strings.TrimSpace(certificates.String())
+
+	if _, err := ParseCertificates(metadata.Certificate); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,64 @@
+package saml
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIdPMetadata(t *testing.T) {
+	data, err := os.ReadFile("testdata/idp_metadata.xml")
+	require.NoError(t, err)
+
+	certificate, err := os.ReadFile("testdata/idp.crt")
+	require.NoError(t, err)
+
+	metadata, err := ParseIdPMetadata(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://idp.example.com/saml", metadata.EntityID)
+	assert.Equal(t, "https://idp.example.com/saml/sso", metadata.SSOURL)
+	assert.Equal(t, strings.TrimSpace(string(certificate)), metadata.Certificate)
+
+	t.Run("entities descriptor", func(t *testing.T) {
+		entities := `<md:EntitiesDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata">` + strings.Replace(string(data), `<?xml version="1.0" encoding="UTF-8"?>`, "", 1) + `</md:EntitiesDescriptor>`
+
+		metadata, err := ParseIdPMetadata([]byte(entities))
+		require.NoError(t, err)
+		assert.Equal(t, "https://idp.example.com/saml", metadata.EntityID)
+	})
+
+	t.Run("without redirect binding", func(t *testing.T) {
+		_, err := ParseIdPMetadata([]byte(strings.Replace(string(data), "bindings:HTTP-Redirect", "bindings:SOAP", 1)))
+		assert.Error(t, err)
+	})
+
+	t.Run("not a metadata document", func(t *testing.T) {
+		_, err := ParseIdPMetadata([]byte(`<EntityDescriptor entityID="idp"/>`))
+		assert.Error(t, err)
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	data, err := NewService().Metadata(&portainer.SAMLSettings{ServiceProviderURL: "https://portainer.example.com"})
+	require.NoError(t, err)
+
+	root, err := parseDocument(data)
+	require.NoError(t, err)
+	require.True(t, root.is(nsMetadata, "EntityDescriptor"))
+	assert.Equal(t, "https://portainer.example.com/api/auth/saml/metadata", root.attr("entityID"))
+
+	descriptor := root.child(nsMetadata, "SPSSODescriptor")
+	require.NotNil(t, descriptor)
+	assert.Equal(t, "true", descriptor.attr("WantAssertionsSigned"))
+
+	acs := descriptor.child(nsMetadata, "AssertionConsumerService")
+	require.NotNil(t, acs)
+	assert.Equal(t, "https://portainer.example.com/api/auth/saml/acs", acs.attr("Location"))
+	assert.Equal(t, bindingHTTPPost, acs.attr("Binding"))
+}
@@ -0,0 +1,504 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"container/list"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MetadataPath is the path of the metadata of Portainer, also used as its entity ID
+	MetadataPath = "/api/auth/saml/metadata"
+	// AssertionConsumerServicePath is the path the identity provider posts its responses to
+	AssertionConsumerServicePath = "/api/auth/saml/acs"
+	// LoginPath is the path starting a login through the identity provider
+	LoginPath = "/api/auth/saml/login"
+
+	bindingHTTPPost      = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	bindingHTTPRedirect  = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	nameIDUnspecified    = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	statusSuccess        = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	confirmationBearer   = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	requestValidity      = 10 * time.Minute
+	clockSkew            = 3 * time.Minute
+	maxResponseSize      = 1 << 20
+	maxPendingRequests   = 10000
+	maxAssertionValidity = 24 * time.Hour
+)
+
+var (
+	ErrSAMLNotConfigured = errors.New("SAML authentication is not configured")
+	ErrInvalidResponse   = errors.New("invalid SAML response")
+)
+
+// Service represents a SAML 2.0 service provider authenticating users against an identity provider
+type Service struct {
+	mu sync.Mutex
+	// pendingRequests indexes the authentication requests waiting for a response in pendingOrder, the oldest first
+	pendingRequests map[string]*list.Element
+	pendingOrder    *list.List
+	// usedAssertions holds the IDs of the consumed assertions until they expire, to prevent their replay
+	usedAssertions map[string]time.Time
+	now            func() time.Time
+}
+
+// NewService returns a pointer to a new instance of this service
+func NewService() *Service {
+	return &Service{
+		pendingRequests: make(map[string]*list.Element),
+		pendingOrder:    list.New(),
+		usedAssertions:  make(map[string]time.Time),
+		now:             time.Now,
+	}
+}
+
+// EntityID returns the entity ID of Portainer
+func EntityID(settings *portainer.SAMLSettings) string {
+	return strings.TrimSuffix(settings.ServiceProviderURL, "/") + MetadataPath
+}
+
+// AssertionConsumerServiceURL returns the URL the identity provider posts its responses to
+func AssertionConsumerServiceURL(settings *portainer.SAMLSettings) string {
+	return strings.TrimSuffix(settings.ServiceProviderURL, "/") + AssertionConsumerServicePath
+}
+
+// ValidateSettings returns an error when the settings are not enough to authenticate users
+func ValidateSettings(settings *portainer.SAMLSettings) error {
+	if settings.IdPEntityID == "" || settings.IdPSSOURL == "" || settings.ServiceProviderURL == "" {
+		return errors.Wrap(ErrSAMLNotConfigured, "the identity provider entity ID, single sign-on URL and the Portainer URL are required")
+	}
+
+	if _, err := ParseCertificates(settings.IdPCertificate); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AuthnRequestURL creates an authentication request and returns the URL of the identity provider to redirect the user to
+func (service *Service) AuthnRequestURL(settings *portainer.SAMLSettings) (string, error) {
+	if err := ValidateSettings(settings); err != nil {
+		return "", err
+	}
+
+	ssoURL, err := url.Parse(settings.IdPSSOURL)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid identity provider single sign-on URL")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	now := service.now().UTC()
+
+	var request strings.Builder
+	request.WriteString(`<samlp:AuthnRequest xmlns:samlp="` + nsProtocol + `" xmlns:saml="` + nsAssertion + `"`)
+	request.WriteString(` ID="` + id + `" Version="2.0" IssueInstant="` + now.Format(time.RFC3339) + `"`)
+	request.WriteString(` Destination="` + escapeAttr(settings.IdPSSOURL) + `"`)
+	request.WriteString(` AssertionConsumerServiceURL="` + escapeAttr(AssertionConsumerServiceURL(settings)) + `"`)
+	request.WriteString(` ProtocolBinding="` + bindingHTTPPost + `">`)
+	request.WriteString(`<saml:Issuer>` + escapeAttr(EntityID(settings)) + `</saml:Issuer>`)
+	request.WriteString(`<samlp:NameIDPolicy Format="` + nameIDUnspecified + `" AllowCreate="true"/>`)
+	request.WriteString(`</samlp:AuthnRequest>`)
+
+	var deflated bytes.Buffer
+	writer, err := flate.NewWriter(&deflated, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := writer.Write([]byte(request.String())); err != nil {
+		return "", err
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	service.addPendingRequest(id, now)
+
+	query := ssoURL.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	ssoURL.RawQuery = query.Encode()
+
+	return ssoURL.String(), nil
+}
+
+// Authenticate validates a base64 encoded SAML response received through the HTTP-POST binding and returns the
+// identity of the user. The response must answer a pending authentication request and its assertion must be signed
+// by the identity provider, either directly or through the signature of the response.
+func (service *Service) Authenticate(samlResponse string, settings *portainer.SAMLSettings) (*portainer.SAMLIdentity, error) {
+	if err := ValidateSettings(settings); err != nil {
+		return nil, err
+	}
+
+	certificates, err := ParseCertificates(settings.IdPCertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(removeWhitespace(samlResponse))
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, "the response is not base64 encoded")
+	}
+
+	if len(data) > maxResponseSize {
+		return nil, errors.Wrap(ErrInvalidResponse, "the response is too large")
+	}
+
+	// the DTDs and the deeply nested documents are rejected before the signatures are looked at
+	response, err := parseDocument(data)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+
+	if !response.is(nsProtocol, "Response") {
+		return nil, errors.Wrap(ErrInvalidResponse, "the document is not a SAML response")
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+
+	validationContext := newValidationContext(certificates)
+
+	signedResponse := doc.Root()
+	responseSigned := false
+	if signed, err := verifySignature(validationContext, signedResponse); err == nil {
+		signedResponse, responseSigned = signed, true
+
+		if response, err = toElement(signed); err != nil {
+			return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+		}
+	} else if !errors.Is(err, errSignatureMissing) {
+		return nil, errors.Wrap(err, "unable to verify the signature of the response")
+	}
+
+	now := service.now()
+
+	if err := validateResponse(response, settings); err != nil {
+		return nil, err
+	}
+
+	if response.child(nsAssertion, "EncryptedAssertion") != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, "encrypted assertions are not supported")
+	}
+
+	assertions := response.childrenNamed(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, errors.Wrap(ErrInvalidResponse, "the response must contain exactly one assertion")
+	}
+
+	assertion := assertions[0]
+
+	var assertionElement *etree.Element
+	for _, el := range signedResponse.ChildElements() {
+		if el.Tag == "Assertion" && el.NamespaceURI() == nsAssertion {
+			assertionElement = el
+		}
+	}
+
+	if assertionElement == nil {
+		return nil, errors.Wrap(ErrInvalidResponse, "the response must contain exactly one assertion")
+	}
+
+	if signed, err := verifySignature(validationContext, assertionElement); err == nil {
+		if assertion, err = toElement(signed); err != nil {
+			return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+		}
+	} else if !errors.Is(err, errSignatureMissing) || !responseSigned {
+		return nil, errors.Wrap(err, "unable to verify the signature of the assertion")
+	}
+
+	requestID := response.attr("InResponseTo")
+
+	expiresAt, err := validateAssertion(assertion, requestID, settings, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if !service.consumeRequest(requestID, now) {
+		return nil, errors.Wrap(ErrInvalidResponse, "the response does not answer a pending authentication request")
+	}
+
+	if !service.useAssertion(assertion.attr("ID"), expiresAt, now) {
+		return nil, errors.Wrap(ErrInvalidResponse, "the assertion has already been used")
+	}
+
+	return identity(assertion, settings)
+}
+
+func validateResponse(response *element, settings *portainer.SAMLSettings) error {
+	if response.attr("Version") != "2.0" {
+		return errors.Wrap(ErrInvalidResponse, "unsupported SAML version")
+	}
+
+	if destination := response.attr("Destination"); destination != "" && destination != AssertionConsumerServiceURL(settings) {
+		return errors.Wrapf(ErrInvalidResponse, "unexpected destination %q", destination)
+	}
+
+	if issuer := response.child(nsAssertion, "Issuer"); issuer != nil && issuer.text() != settings.IdPEntityID {
+		return errors.Wrapf(ErrInvalidResponse, "unexpected issuer %q", issuer.text())
+	}
+
+	status := response.child(nsProtocol, "Status")
+	if status == nil {
+		return errors.Wrap(ErrInvalidResponse, "missing status")
+	}
+
+	statusCode := status.child(nsProtocol, "StatusCode")
+	if statusCode == nil || statusCode.attr("Value") != statusSuccess {
+		message := childText(status, nsProtocol, "StatusMessage")
+		if statusCode != nil {
+			message = strings.TrimSpace(statusCode.attr("Value") + " " + message)
+		}
+
+		return errors.Wrapf(ErrInvalidResponse, "the identity provider rejected the authentication: %s", message)
+	}
+
+	return nil
+}
+
+// validateAssertion checks the issuer, subject confirmation and conditions of the assertion and returns the time after
+// which the assertion cannot be used anymore
+func validateAssertion(assertion *element, requestID string, settings *portainer.SAMLSettings, now time.Time) (time.Time, error) {
+	if assertion.attr("ID") == "" || assertion.attr("Version") != "2.0" {
+		return time.Time{}, errors.Wrap(ErrInvalidResponse, "invalid assertion")
+	}
+
+	if issuer := childText(assertion, nsAssertion, "Issuer"); issuer != settings.IdPEntityID {
+		return time.Time{}, errors.Wrapf(ErrInvalidResponse, "unexpected assertion issuer %q", issuer)
+	}
+
+	subject := assertion.child(nsAssertion, "Subject")
+	if subject == nil {
+		return time.Time{}, errors.Wrap(ErrInvalidResponse, "missing subject")
+	}
+
+	expiresAt, err := validateSubjectConfirmation(subject, requestID, settings, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	conditions := assertion.child(nsAssertion, "Conditions")
+	if conditions == nil {
+		return time.Time{}, errors.Wrap(ErrInvalidResponse, "missing conditions")
+	}
+
+	if notBefore, ok, err := parseTime(conditions.attr("NotBefore")); err != nil {
+		return time.Time{}, err
+	} else if ok && now.Add(clockSkew).Before(notBefore) {
+		return time.Time{}, errors.Wrap(ErrInvalidResponse, "the assertion is not valid yet")
+	}
+
+	if notOnOrAfter, ok, err := parseTime(conditions.attr("NotOnOrAfter")); err != nil {
+		return time.Time{}, err
+	} else if ok {
+		if !now.Add(-clockSkew).Before(notOnOrAfter) {
+			return time.Time{}, errors.Wrap(ErrInvalidResponse, "the assertion has expired")
+		}
+
+		expiresAt = notOnOrAfter
+	}
+
+	entityID := EntityID(settings)
+	for _, restriction := range conditions.childrenNamed(nsAssertion, "AudienceRestriction") {
+		allowed := false
+		for _, audience := range restriction.childrenNamed(nsAssertion, "Audience") {
+			if audience.text() == entityID {
+				allowed = true
+			}
+		}
+
+		if !allowed {
+			return time.Time{}, errors.Wrap(ErrInvalidResponse, "the assertion is not intended for Portainer")
+		}
+	}
+
+	return expiresAt, nil
+}
+
+// validateSubjectConfirmation looks for a bearer subject confirmation addressed to Portainer and returns its expiry
+func validateSubjectConfirmation(subject *element, requestID string, settings *portainer.SAMLSettings, now time.Time) (time.Time, error) {
+	acsURL := AssertionConsumerServiceURL(settings)
+
+	for _, confirmation := range subject.childrenNamed(nsAssertion, "SubjectConfirmation") {
+		if confirmation.attr("Method") != confirmationBearer {
+			continue
+		}
+
+		data := confirmation.child(nsAssertion, "SubjectConfirmationData")
+		if data == nil || data.attr("Recipient") != acsURL || data.attr("InResponseTo") != requestID {
+			continue
+		}
+
+		notOnOrAfter, ok, err := parseTime(data.attr("NotOnOrAfter"))
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if !ok || !now.Add(-clockSkew).Before(notOnOrAfter) {
+			continue
+		}
+
+		return notOnOrAfter, nil
+	}
+
+	return time.Time{}, errors.Wrap(ErrInvalidResponse, "no valid bearer subject confirmation for Portainer")
+}
+
+// identity extracts the username and groups of the user from the assertion
+func identity(assertion *element, settings *portainer.SAMLSettings) (*portainer.SAMLIdentity, error) {
+	identity := &portainer.SAMLIdentity{}
+
+	if settings.UsernameAttribute == "" {
+		if subject := assertion.child(nsAssertion, "Subject"); subject != nil {
+			identity.Username = childText(subject, nsAssertion, "NameID")
+		}
+	} else if values := attributeValues(assertion, settings.UsernameAttribute); len(values) > 0 {
+		identity.Username = values[0]
+	}
+
+	if identity.Username == "" {
+		return nil, errors.Wrap(ErrInvalidResponse, "unable to find the username in the assertion")
+	}
+
+	if settings.GroupsAttribute != "" {
+		identity.Groups = attributeValues(assertion, settings.GroupsAttribute)
+	}
+
+	return identity, nil
+}
+
+// attributeValues returns the values of the attribute matching name, either by name or by friendly name
+func attributeValues(assertion *element, name string) []string {
+	var values []string
+
+	for _, statement := range assertion.childrenNamed(nsAssertion, "AttributeStatement") {
+		for _, attribute := range statement.childrenNamed(nsAssertion, "Attribute") {
+			if attribute.attr("Name") != name && attribute.attr("FriendlyName") != name {
+				continue
+			}
+
+			for _, value := range attribute.childrenNamed(nsAssertion, "AttributeValue") {
+				if text := value.text(); text != "" {
+					values = append(values, text)
+				}
+			}
+		}
+	}
+
+	return values
+}
+
+type pendingRequest struct {
+	id       string
+	issuedAt time.Time
+}
+
+// addPendingRequest records a request waiting for a response. The expired requests are dropped and, when too many
+// are pending, the oldest one is dropped so that flooding the login route cannot block the new logins.
+func (service *Service) addPendingRequest(id string, now time.Time) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	for front := service.pendingOrder.Front(); front != nil; front = service.pendingOrder.Front() {
+		request := front.Value.(pendingRequest)
+		if now.Sub(request.issuedAt) <= requestValidity && service.pendingOrder.Len() < maxPendingRequests {
+			break
+		}
+
+		service.pendingOrder.Remove(front)
+		delete(service.pendingRequests, request.id)
+	}
+
+	service.pendingRequests[id] = service.pendingOrder.PushBack(pendingRequest{id: id, issuedAt: now})
+}
+
+// consumeRequest removes a pending request, it returns false when the request is unknown or has expired
+func (service *Service) consumeRequest(id string, now time.Time) bool {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	el, ok := service.pendingRequests[id]
+	if !ok {
+		return false
+	}
+
+	service.pendingOrder.Remove(el)
+	delete(service.pendingRequests, id)
+
+	return now.Sub(el.Value.(pendingRequest).issuedAt) <= requestValidity
+}
+
+// useAssertion records the use of an assertion, it returns false when the assertion has already been used
+func (service *Service) useAssertion(id string, expiresAt time.Time, now time.Time) bool {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	for assertionID, expiry := range service.usedAssertions {
+		if now.After(expiry) {
+			delete(service.usedAssertions, assertionID)
+		}
+	}
+
+	if _, ok := service.usedAssertions[id]; ok {
+		return false
+	}
+
+	service.usedAssertions[id] = minTime(expiresAt.Add(clockSkew), now.Add(maxAssertionValidity))
+
+	return true
+}
+
+func parseTime(value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false, errors.Wrapf(ErrInvalidResponse, "invalid time %q", value)
+	}
+
+	return t, true, nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+// newID returns a random identifier, starting with a letter as required for XML IDs
+func newID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "unable to generate a request ID")
+	}
+
+	return "id-" + hex.EncodeToString(b), nil
+}
+
+var attrReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func escapeAttr(s string) string {
+	return attrReplacer.Replace(s)
+}
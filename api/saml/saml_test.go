@@ -0,0 +1,277 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The responses of testdata are signed with testdata/idp.key, a key pair generated for the tests. They were
+// canonicalized with the C14N implementation of the Python standard library and signed with openssl.
+
+const testRequestID = "id-test-request"
+
+var testNow = time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC)
+
+func testSettings(t *testing.T) *portainer.SAMLSettings {
+	t.Helper()
+
+	certificate, err := os.ReadFile("testdata/idp.crt")
+	require.NoError(t, err)
+
+	return &portainer.SAMLSettings{
+		IdPEntityID:        "https://idp.example.com/saml",
+		IdPSSOURL:          "https://idp.example.com/saml/sso",
+		IdPCertificate:     string(certificate),
+		ServiceProviderURL: "https://portainer.example.com/",
+		GroupsAttribute:    "groups",
+	}
+}
+
+func testService(now time.Time) *Service {
+	service := NewService()
+	service.now = func() time.Time { return now }
+	service.addPendingRequest(testRequestID, now)
+
+	return service
+}
+
+func readResponse(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func encode(response string) string {
+	return base64.StdEncoding.EncodeToString([]byte(response))
+}
+
+func TestAuthenticate(t *testing.T) {
+	for _, name := range []string{"response_signed_assertion.xml", "response_signed_response.xml"} {
+		t.Run(name, func(t *testing.T) {
+			identity, err := testService(testNow).Authenticate(encode(readResponse(t, name)), testSettings(t))
+			require.NoError(t, err)
+
+			assert.Equal(t, "alice@example.com", identity.Username)
+			assert.Equal(t, []string{"developers", "R&D <ops>"}, identity.Groups)
+		})
+	}
+
+	t.Run("uses the username attribute, by name or friendly name", func(t *testing.T) {
+		for _, attribute := range []string{"uid", "urn:oid:0.9.2342.19200300.100.1.1"} {
+			settings := testSettings(t)
+			settings.UsernameAttribute = attribute
+
+			identity, err := testService(testNow).Authenticate(encode(readResponse(t, "response_signed_assertion.xml")), settings)
+			require.NoError(t, err)
+			assert.Equal(t, "alice", identity.Username)
+		}
+	})
+
+	t.Run("fails when the username attribute is missing", func(t *testing.T) {
+		settings := testSettings(t)
+		settings.UsernameAttribute = "missing"
+
+		_, err := testService(testNow).Authenticate(encode(readResponse(t, "response_signed_assertion.xml")), settings)
+		assert.ErrorIs(t, err, ErrInvalidResponse)
+	})
+}
+
+func TestAuthenticateRejectsInvalidResponses(t *testing.T) {
+	signedAssertion := readResponse(t, "response_signed_assertion.xml")
+	signedResponse := readResponse(t, "response_signed_response.xml")
+
+	start := strings.Index(signedAssertion, "<saml:Assertion")
+	end := strings.Index(signedAssertion, "</saml:Assertion>") + len("</saml:Assertion>")
+	assertion := signedAssertion[start:end]
+	unsignedAssertion := assertion[:strings.Index(assertion, "<ds:Signature")] + assertion[strings.Index(assertion, "</ds:Signature>")+len("</ds:Signature>"):]
+
+	tests := []struct {
+		name     string
+		response string
+		settings func(*portainer.SAMLSettings)
+		now      time.Time
+	}{
+		{
+			name:     "tampered signed assertion",
+			response: strings.Replace(signedAssertion, "alice@example.com", "admin", 1),
+		},
+		{
+			name:     "tampered signed response",
+			response: strings.Replace(signedResponse, "alice@example.com", "admin", 1),
+		},
+		{
+			name:     "unsigned assertion",
+			response: strings.Replace(signedAssertion, assertion, unsignedAssertion, 1),
+		},
+		{
+			name:     "additional unsigned assertion",
+			response: strings.Replace(signedAssertion, assertion, assertion+strings.Replace(unsignedAssertion, "id-test-assertion", "id-evil", 1), 1),
+		},
+		{
+			name:     "signed assertion wrapped in an extension",
+			response: strings.Replace(signedAssertion, assertion, "<samlp:Extensions>"+assertion+"</samlp:Extensions>"+strings.Replace(unsignedAssertion, "alice@example.com", "admin", 1), 1),
+		},
+		{
+			name:     "signature of another element",
+			response: strings.Replace(signedAssertion, `ID="id-test-assertion"`, `ID="id-other"`, 1),
+		},
+		{
+			name:     "unknown identity provider certificate",
+			response: signedAssertion,
+			settings: func(settings *portainer.SAMLSettings) {
+				settings.IdPCertificate = otherCertificate(t)
+			},
+		},
+		{
+			name:     "unexpected issuer",
+			response: signedAssertion,
+			settings: func(settings *portainer.SAMLSettings) {
+				settings.IdPEntityID = "https://other-idp.example.com"
+			},
+		},
+		{
+			name:     "unexpected audience and recipient",
+			response: signedAssertion,
+			settings: func(settings *portainer.SAMLSettings) {
+				settings.ServiceProviderURL = "https://other.example.com"
+			},
+		},
+		{
+			name:     "expired assertion",
+			response: signedAssertion,
+			now:      testNow.Add(time.Hour),
+		},
+		{
+			name:     "assertion not valid yet",
+			response: signedAssertion,
+			now:      testNow.Add(-time.Hour),
+		},
+		{
+			name:     "not base64 encoded",
+			response: "not base64",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := testSettings(t)
+			if test.settings != nil {
+				test.settings(settings)
+			}
+
+			now := testNow
+			if !test.now.IsZero() {
+				now = test.now
+			}
+
+			response := test.response
+			if response != "not base64" {
+				response = encode(response)
+			}
+
+			_, err := testService(now).Authenticate(response, settings)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAuthenticateRejectsUnsolicitedAndReplayedResponses(t *testing.T) {
+	response := encode(readResponse(t, "response_signed_assertion.xml"))
+	settings := testSettings(t)
+
+	service := NewService()
+	service.now = func() time.Time { return testNow }
+
+	_, err := service.Authenticate(response, settings)
+	require.ErrorIs(t, err, ErrInvalidResponse, "a response to an unknown request must be rejected")
+
+	service.addPendingRequest(testRequestID, testNow)
+	_, err = service.Authenticate(response, settings)
+	require.NoError(t, err)
+
+	service.addPendingRequest(testRequestID, testNow)
+	_, err = service.Authenticate(response, settings)
+	require.ErrorIs(t, err, ErrInvalidResponse, "a replayed assertion must be rejected")
+}
+
+func TestAuthnRequestURL(t *testing.T) {
+	service := NewService()
+	settings := testSettings(t)
+	settings.IdPSSOURL = "https://idp.example.com/saml/sso?tenant=1"
+
+	location, err := service.AuthnRequestURL(settings)
+	require.NoError(t, err)
+
+	u, err := url.Parse(location)
+	require.NoError(t, err)
+	assert.Equal(t, "idp.example.com", u.Host)
+	assert.Equal(t, "1", u.Query().Get("tenant"))
+
+	deflated, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	require.NoError(t, err)
+
+	request, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	require.NoError(t, err)
+
+	root, err := parseDocument(request)
+	require.NoError(t, err)
+	require.True(t, root.is(nsProtocol, "AuthnRequest"))
+
+	assert.Equal(t, "https://portainer.example.com/api/auth/saml/acs", root.attr("AssertionConsumerServiceURL"))
+	assert.Equal(t, "https://portainer.example.com/api/auth/saml/metadata", childText(root, nsAssertion, "Issuer"))
+	assert.Contains(t, service.pendingRequests, root.attr("ID"))
+}
+
+func TestAuthnRequestURLRequiresSettings(t *testing.T) {
+	_, err := NewService().AuthnRequestURL(&portainer.SAMLSettings{})
+	assert.ErrorIs(t, err, ErrSAMLNotConfigured)
+}
+
+func TestParseDocumentRejectsDTD(t *testing.T) {
+	_, err := parseDocument([]byte(`<!DOCTYPE root [<!ENTITY e "entity">]><root>&e;</root>`))
+	assert.Error(t, err)
+}
+
+func otherCertificate(t *testing.T) string {
+	t.Helper()
+
+	certificate, err := os.ReadFile("testdata/other.crt")
+	require.NoError(t, err)
+
+	return string(certificate)
+}
+
+func TestPendingRequestsDropTheOldest(t *testing.T) {
+	service := NewService()
+
+	for i := range maxPendingRequests + 1 {
+		service.addPendingRequest(fmt.Sprintf("id-%d", i), testNow)
+	}
+
+	require.Len(t, service.pendingRequests, maxPendingRequests)
+	require.Equal(t, maxPendingRequests, service.pendingOrder.Len())
+
+	assert.False(t, service.consumeRequest("id-0", testNow), "the oldest request is dropped")
+	assert.True(t, service.consumeRequest(fmt.Sprintf("id-%d", maxPendingRequests), testNow), "the new request is kept")
+
+	// the expired requests are dropped first
+	service.addPendingRequest("id-new", testNow.Add(requestValidity+time.Second))
+	require.Len(t, service.pendingRequests, 1)
+	assert.True(t, service.consumeRequest("id-new", testNow.Add(requestValidity+time.Second)))
+}
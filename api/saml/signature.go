@@ -0,0 +1,109 @@
+package saml
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+var (
+	errSignatureMissing = errors.New("the element is not signed")
+	errInvalidSignature = errors.New("invalid signature")
+)
+
+// ParseCertificates parses the PEM encoded certificates used by an identity provider to sign its messages
+func ParseCertificates(data string) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid identity provider certificate")
+		}
+
+		if _, ok := certificate.PublicKey.(*rsa.PublicKey); !ok {
+			return nil, errors.New("only identity provider certificates with an RSA key are supported")
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, errors.New("no identity provider certificate found")
+	}
+
+	return certificates, nil
+}
+
+// newValidationContext returns the context verifying the signatures made with the identity provider certificates
+func newValidationContext(certificates []*x509.Certificate) *dsig.ValidationContext {
+	return dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certificates})
+}
+
+// verifySignature verifies the enveloped XML signature of el and returns the signed content. The signature must
+// reference el itself and only the returned element must be read, so that the unsigned parts of the document cannot
+// be mistaken for signed ones.
+func verifySignature(ctx *dsig.ValidationContext, el *etree.Element) (*etree.Element, error) {
+	// the namespaces declared by the ancestors are needed to canonicalize the element on its own
+	nsContext, err := etreeutils.NSBuildParentContext(el)
+	if err != nil {
+		return nil, errors.Wrap(errInvalidSignature, err.Error())
+	}
+
+	detached, err := etreeutils.NSDetatch(nsContext, el)
+	if err != nil {
+		return nil, errors.Wrap(errInvalidSignature, err.Error())
+	}
+
+	signed, err := ctx.Validate(detached)
+	if errors.Is(err, dsig.ErrMissingSignature) {
+		return nil, errSignatureMissing
+	} else if err != nil {
+		return nil, errors.Wrap(errInvalidSignature, err.Error())
+	}
+
+	return signed, nil
+}
+
+// toElement converts an element verified by verifySignature to the representation read by this package
+func toElement(el *etree.Element) (*element, error) {
+	doc := etree.NewDocument()
+	doc.SetRoot(el)
+
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDocument(data)
+}
+
+func childText(el *element, namespace, local string) string {
+	child := el.child(namespace, local)
+	if child == nil {
+		return ""
+	}
+
+	return child.text()
+}
+
+func removeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
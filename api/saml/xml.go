@@ -0,0 +1,195 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	nsXML       = "http://www.w3.org/XML/1998/namespace"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+)
+
+// maxDocumentDepth limits the nesting of the XML documents received from the identity provider
+const maxDocumentDepth = 64
+
+type (
+	// element is an XML element which keeps the namespace prefixes as written in the document, they are resolved
+	// against the declarations of the element and its ancestors
+	element struct {
+		prefix   string
+		local    string
+		attrs    []attribute
+		nsDecls  []attribute
+		parent   *element
+		children []any
+	}
+
+	attribute struct {
+		prefix string
+		local  string
+		value  string
+	}
+
+	charData string
+)
+
+// parseDocument parses an XML document and returns its root element, DTDs are rejected
+func parseDocument(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, current *element
+	depth := 0
+
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid XML document")
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth > maxDocumentDepth {
+				return nil, errors.New("the XML document is too deeply nested")
+			}
+
+			el := &element{prefix: t.Name.Space, local: t.Name.Local, parent: current}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					el.nsDecls = append(el.nsDecls, attribute{value: attr.Value})
+				case attr.Name.Space == "xmlns":
+					el.nsDecls = append(el.nsDecls, attribute{local: attr.Name.Local, value: attr.Value})
+				default:
+					el.attrs = append(el.attrs, attribute{prefix: attr.Name.Space, local: attr.Name.Local, value: attr.Value})
+				}
+			}
+
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("the XML document has more than one root element")
+				}
+
+				root = el
+			} else {
+				current.children = append(current.children, el)
+			}
+
+			current = el
+
+		case xml.EndElement:
+			if current == nil || current.prefix != t.Name.Space || current.local != t.Name.Local {
+				return nil, errors.New("invalid XML document: mismatched end element")
+			}
+
+			depth--
+			current = current.parent
+
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, charData(t))
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("invalid XML document: text outside of the root element")
+			}
+
+		case xml.Directive:
+			return nil, errors.New("XML documents with a DTD are not supported")
+		}
+	}
+
+	if root == nil || current != nil {
+		return nil, errors.New("invalid XML document: unexpected end of document")
+	}
+
+	return root, nil
+}
+
+// namespace returns the namespace bound to prefix in the scope of the element
+func (el *element) namespace(prefix string) string {
+	if prefix == "xml" {
+		return nsXML
+	}
+
+	for e := el; e != nil; e = e.parent {
+		for _, decl := range e.nsDecls {
+			if decl.local == prefix {
+				return decl.value
+			}
+		}
+	}
+
+	return ""
+}
+
+func (el *element) is(namespace, local string) bool {
+	return el.local == local && el.namespace(el.prefix) == namespace
+}
+
+// attr returns the value of an attribute without namespace
+func (el *element) attr(local string) string {
+	for _, attr := range el.attrs {
+		if attr.prefix == "" && attr.local == local {
+			return attr.value
+		}
+	}
+
+	return ""
+}
+
+func (el *element) childElements() []*element {
+	var elements []*element
+	for _, child := range el.children {
+		if e, ok := child.(*element); ok {
+			elements = append(elements, e)
+		}
+	}
+
+	return elements
+}
+
+// child returns the first child element with the given name
+func (el *element) child(namespace, local string) *element {
+	for _, e := range el.childElements() {
+		if e.is(namespace, local) {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// childrenNamed returns the child elements with the given name
+func (el *element) childrenNamed(namespace, local string) []*element {
+	var elements []*element
+	for _, e := range el.childElements() {
+		if e.is(namespace, local) {
+			elements = append(elements, e)
+		}
+	}
+
+	return elements
+}
+
+// text returns the character data of the element, without the one of its descendants
+func (el *element) text() string {
+	var sb strings.Builder
+	for _, child := range el.children {
+		if data, ok := child.(charData); ok {
+			sb.WriteString(string(data))
+		}
+	}
+
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,312 @@
+package secrets
+
+import (
+	"reflect"
+	"sync"
+)
+
+// secretTag marks the string fields, or maps of strings, holding a secret, such as `secret:"true"`
+const secretTag = "secret"
+
+var (
+	secretTypesMu sync.Mutex
+	secretTypes   = map[reflect.Type]bool{}
+)
+
+// HasSecretFields returns true when the type of the object holds a field tagged as secret, directly or through its
+// fields, pointers, slices and maps
+func HasSecretFields(object any) bool {
+	if object == nil {
+		return false
+	}
+
+	return typeHasSecretFields(reflect.TypeOf(object))
+}
+
+func typeHasSecretFields(t reflect.Type) bool {
+	secretTypesMu.Lock()
+	defer secretTypesMu.Unlock()
+
+	result, ok := secretTypes[t]
+	if !ok {
+		result = hasSecretFields(t, map[reflect.Type]bool{})
+		secretTypes[t] = result
+	}
+
+	return result
+}
+
+func hasSecretFields(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	// the fields of recursive types are already being looked at
+	if visiting[t] {
+		return false
+	}
+
+	visiting[t] = true
+
+	result := false
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		result = hasSecretFields(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if isSecretField(field) || hasSecretFields(field.Type, visiting) {
+				result = true
+
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+func isSecretField(field reflect.StructField) bool {
+	if field.Tag.Get(secretTag) != "true" {
+		return false
+	}
+
+	t := field.Type
+
+	return t.Kind() == reflect.String || (t.Kind() == reflect.Map && t.Elem().Kind() == reflect.String)
+}
+
+// transformSecretField transforms a secret string field, or every value of a secret map field
+func transformSecretField(field reflect.Value, transform func(string) (string, error)) error {
+	if field.Kind() == reflect.String {
+		value, err := transform(field.String())
+		if err != nil {
+			return err
+		}
+
+		field.SetString(value)
+
+		return nil
+	}
+
+	if field.IsNil() {
+		return nil
+	}
+
+	// the map can be shared with the caller, the transformed values are stored in a new one
+	values := reflect.MakeMapWithSize(field.Type(), field.Len())
+
+	iter := field.MapRange()
+	for iter.Next() {
+		value, err := transform(iter.Value().String())
+		if err != nil {
+			return err
+		}
+
+		values.SetMapIndex(iter.Key(), reflect.ValueOf(value).Convert(field.Type().Elem()))
+	}
+
+	field.Set(values)
+
+	return nil
+}
+
+// EncryptFields encrypts in place the secret fields of the object, which must be a pointer
+func (service *Service) EncryptFields(object any) error {
+	return transformFields(reflect.ValueOf(object), service.Encrypt)
+}
+
+// EncryptedCopy returns a copy of the object with its secret fields encrypted, the object itself is left untouched.
+// Only the values leading to secret fields are copied, the rest is shared with the object.
+func (service *Service) EncryptedCopy(object any) (any, error) {
+	if object == nil {
+		return nil, nil
+	}
+
+	clone, err := copyFields(reflect.ValueOf(object), service.Encrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	return clone.Interface(), nil
+}
+
+// DecryptFields decrypts in place the secret fields of the object, which must be a pointer. A nil service fails to
+// decrypt any encrypted field.
+func (service *Service) DecryptFields(object any) error {
+	return transformFields(reflect.ValueOf(object), service.Decrypt)
+}
+
+func transformFields(v reflect.Value, transform func(string) (string, error)) error {
+	if !v.IsValid() || !typeHasSecretFields(v.Type()) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+
+		return transformFields(v.Elem(), transform)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := transformFields(v.Index(i), transform); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// map values are not addressable, a copy is transformed and stored back
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+
+			if err := transformFields(value, transform); err != nil {
+				return err
+			}
+
+			v.SetMapIndex(iter.Key(), value)
+		}
+
+	case reflect.Struct:
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if !isSecretField(field) {
+				if err := transformFields(v.Field(i), transform); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if !v.Field(i).CanSet() {
+				continue
+			}
+
+			if err := transformSecretField(v.Field(i), transform); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyFields returns a copy of the value with its secret fields transformed, the values without secret fields are
+// returned as they are
+func copyFields(v reflect.Value, transform func(string) (string, error)) (reflect.Value, error) {
+	if !typeHasSecretFields(v.Type()) {
+		return v, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, nil
+		}
+
+		elem, err := copyFields(v.Elem(), transform)
+		if err != nil {
+			return v, err
+		}
+
+		clone := reflect.New(v.Type().Elem())
+		clone.Elem().Set(elem)
+
+		return clone, nil
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+
+		clone := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		if err := copyElements(clone, v, transform); err != nil {
+			return v, err
+		}
+
+		return clone, nil
+
+	case reflect.Array:
+		clone := reflect.New(v.Type()).Elem()
+		if err := copyElements(clone, v, transform); err != nil {
+			return v, err
+		}
+
+		return clone, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+
+		clone := reflect.MakeMapWithSize(v.Type(), v.Len())
+
+		iter := v.MapRange()
+		for iter.Next() {
+			value, err := copyFields(iter.Value(), transform)
+			if err != nil {
+				return v, err
+			}
+
+			clone.SetMapIndex(iter.Key(), value)
+		}
+
+		return clone, nil
+
+	case reflect.Struct:
+		clone := reflect.New(v.Type()).Elem()
+		clone.Set(v)
+
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if isSecretField(field) {
+				if err := transformSecretField(clone.Field(i), transform); err != nil {
+					return v, err
+				}
+
+				continue
+			}
+
+			value, err := copyFields(v.Field(i), transform)
+			if err != nil {
+				return v, err
+			}
+
+			clone.Field(i).Set(value)
+		}
+
+		return clone, nil
+	}
+
+	return v, nil
+}
+
+func copyElements(dst, src reflect.Value, transform func(string) (string, error)) error {
+	for i := 0; i < src.Len(); i++ {
+		value, err := copyFields(src.Index(i), transform)
+		if err != nil {
+			return err
+		}
+
+		dst.Index(i).Set(value)
+	}
+
+	return nil
+}
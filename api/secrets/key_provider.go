@@ -0,0 +1,189 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// KeyProvider holds a master key, it wraps the data keys used to encrypt the secret fields so that the master key
+// itself never has to be loaded in memory when it is managed by a KMS
+type KeyProvider interface {
+	// KeyID identifies the master key, it is stored along with each encrypted value
+	KeyID() string
+	// WrapKey encrypts a data key with the master key
+	WrapKey(dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey
+	UnwrapKey(wrappedKey []byte) ([]byte, error)
+}
+
+// KeyProviderFactory creates a key provider from the configuration given on the command line
+type KeyProviderFactory func(config string) (KeyProvider, error)
+
+var (
+	keyProvidersMu sync.RWMutex
+	keyProviders   = map[string]KeyProviderFactory{
+		"file": newFileKeyProvider,
+		"exec": newExecKeyProvider,
+	}
+)
+
+// RegisterKeyProvider makes a key provider available under name
+func RegisterKeyProvider(name string, factory KeyProviderFactory) {
+	keyProvidersMu.Lock()
+	defer keyProvidersMu.Unlock()
+
+	keyProviders[name] = factory
+}
+
+// KeyProviders returns the names of the registered key providers
+func KeyProviders() []string {
+	keyProvidersMu.RLock()
+	defer keyProvidersMu.RUnlock()
+
+	names := make([]string, 0, len(keyProviders))
+	for name := range keyProviders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NewKeyProvider creates a key provider from a "<provider>:<config>" specification, such as
+// "file:/run/secrets/portainer-fields" or "exec:/usr/local/bin/portainer-kms". A specification without a known
+// provider is the path of a key file.
+func NewKeyProvider(spec string) (KeyProvider, error) {
+	name, config, found := strings.Cut(spec, ":")
+
+	keyProvidersMu.RLock()
+	factory, ok := keyProviders[name]
+	keyProvidersMu.RUnlock()
+
+	if !found || !ok {
+		return newFileKeyProvider(spec)
+	}
+
+	return factory(config)
+}
+
+// fileKeyProvider derives the master key from the content of a file
+type fileKeyProvider struct {
+	key   []byte
+	keyID string
+}
+
+func newFileKeyProvider(path string) (KeyProvider, error) {
+	if path == "" {
+		return nil, errors.New("the file key provider requires a path")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the secrets key file")
+	}
+
+	if len(content) == 0 {
+		return nil, fmt.Errorf("the secrets key file %q is empty", path)
+	}
+
+	// a 32 byte hash of the content is required for AES-256
+	key := sha256.Sum256(content)
+	id := sha256.Sum256(key[:])
+
+	return &fileKeyProvider{
+		key:   key[:],
+		keyID: "file-" + hex.EncodeToString(id[:8]),
+	}, nil
+}
+
+func (provider *fileKeyProvider) KeyID() string {
+	return provider.keyID
+}
+
+func (provider *fileKeyProvider) WrapKey(dataKey []byte) ([]byte, error) {
+	return seal(provider.key, dataKey)
+}
+
+func (provider *fileKeyProvider) UnwrapKey(wrappedKey []byte) ([]byte, error) {
+	return open(provider.key, wrappedKey)
+}
+
+// execKeyProvider delegates the master key to an external program, such as a KMS client. The program is called with
+// "key-id" and prints the identifier of the master key, or with "wrap" or "unwrap" and reads a base64 encoded key on
+// its standard input and prints the base64 encoded result.
+type execKeyProvider struct {
+	command string
+	keyID   string
+}
+
+func newExecKeyProvider(command string) (KeyProvider, error) {
+	if command == "" {
+		return nil, errors.New("the exec key provider requires a command")
+	}
+
+	provider := &execKeyProvider{command: command}
+
+	output, err := provider.run("key-id", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.keyID = strings.TrimSpace(string(output))
+	if provider.keyID == "" || strings.Contains(provider.keyID, ":") {
+		return nil, fmt.Errorf("invalid key identifier %q returned by the key provider command", provider.keyID)
+	}
+
+	return provider, nil
+}
+
+func (provider *execKeyProvider) KeyID() string {
+	return provider.keyID
+}
+
+func (provider *execKeyProvider) WrapKey(dataKey []byte) ([]byte, error) {
+	return provider.transform("wrap", dataKey)
+}
+
+func (provider *execKeyProvider) UnwrapKey(wrappedKey []byte) ([]byte, error) {
+	return provider.transform("unwrap", wrappedKey)
+}
+
+func (provider *execKeyProvider) transform(action string, key []byte) ([]byte, error) {
+	output, err := provider.run(action, []byte(base64.StdEncoding.EncodeToString(key)))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(output)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid output of the key provider command for %s", action)
+	}
+
+	return result, nil
+}
+
+func (provider *execKeyProvider) run(action string, input []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(provider.command, action)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "key provider command failed: %s", stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,214 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// envelopePrefix starts the encrypted values, they are formatted as
+// enc:v1:<key id>:<base64 wrapped data key>:<base64 nonce and ciphertext>
+const envelopePrefix = "enc:v1:"
+
+var (
+	// ErrNoSecretsKey is returned when an encrypted value is read while no secrets key is loaded
+	ErrNoSecretsKey = errors.New("the value is encrypted but no secrets key was loaded")
+	// ErrUnknownSecretsKey is returned when a value is encrypted with a key that is neither the current nor a previous key
+	ErrUnknownSecretsKey = errors.New("the value is encrypted with an unknown secrets key")
+	// ErrInvalidEncryptedValue is returned when an encrypted value cannot be parsed
+	ErrInvalidEncryptedValue = errors.New("invalid encrypted value")
+)
+
+// Service encrypts the secret fields with envelope encryption. A data key is generated when the service is created
+// and wrapped once with the current master key, the values encrypted with previous master keys remain readable until
+// they are encrypted again.
+type Service struct {
+	current        KeyProvider
+	providers      map[string]KeyProvider
+	dataKey        []byte
+	wrappedDataKey string
+
+	mu       sync.Mutex
+	dataKeys map[string][]byte
+}
+
+// NewService returns a service encrypting with the current key provider and decrypting with any of the providers
+func NewService(current KeyProvider, previous ...KeyProvider) (*Service, error) {
+	service := &Service{
+		current:   current,
+		providers: make(map[string]KeyProvider),
+		dataKey:   make([]byte, 32),
+		dataKeys:  make(map[string][]byte),
+	}
+
+	for _, provider := range append([]KeyProvider{current}, previous...) {
+		if strings.Contains(provider.KeyID(), ":") {
+			return nil, fmt.Errorf("invalid key identifier %q", provider.KeyID())
+		}
+
+		if _, ok := service.providers[provider.KeyID()]; !ok {
+			service.providers[provider.KeyID()] = provider
+		}
+	}
+
+	if _, err := io.ReadFull(rand.Reader, service.dataKey); err != nil {
+		return nil, err
+	}
+
+	wrappedDataKey, err := current.WrapKey(service.dataKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to wrap the data key")
+	}
+
+	service.wrappedDataKey = base64.StdEncoding.EncodeToString(wrappedDataKey)
+	service.dataKeys[current.KeyID()+":"+service.wrappedDataKey] = service.dataKey
+
+	return service, nil
+}
+
+// KeyID returns the identifier of the current master key
+func (service *Service) KeyID() string {
+	return service.current.KeyID()
+}
+
+// IsEncrypted returns true when the value was encrypted by a Service
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// KeyIDOf returns the identifier of the master key used to encrypt the value
+func KeyIDOf(value string) (string, bool) {
+	if !IsEncrypted(value) {
+		return "", false
+	}
+
+	keyID, _, found := strings.Cut(strings.TrimPrefix(value, envelopePrefix), ":")
+
+	return keyID, found
+}
+
+// Encrypt encrypts the value with the current master key, empty values are kept as they are
+func (service *Service) Encrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	ciphertext, err := seal(service.dataKey, []byte(value))
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + service.current.KeyID() + ":" + service.wrappedDataKey + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts a value encrypted by Encrypt, the values that are not encrypted are returned as they are. A nil
+// service fails to decrypt any encrypted value.
+func (service *Service) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	if service == nil {
+		return "", ErrNoSecretsKey
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", ErrInvalidEncryptedValue
+	}
+
+	dataKey, err := service.unwrapDataKey(parts[0], parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.Wrap(ErrInvalidEncryptedValue, err.Error())
+	}
+
+	plaintext, err := open(dataKey, ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func (service *Service) unwrapDataKey(keyID, wrappedDataKey string) ([]byte, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if dataKey, ok := service.dataKeys[keyID+":"+wrappedDataKey]; ok {
+		return dataKey, nil
+	}
+
+	provider, ok := service.providers[keyID]
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownSecretsKey, keyID)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(wrappedDataKey)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidEncryptedValue, err.Error())
+	}
+
+	dataKey, err := provider.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to unwrap the data key")
+	}
+
+	service.dataKeys[keyID+":"+wrappedDataKey] = dataKey
+
+	return dataKey, nil
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrInvalidEncryptedValue
+	}
+
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decrypt the value")
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
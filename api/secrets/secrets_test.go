@@ -0,0 +1,233 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type credentials struct {
+	Username string
+	Password string `secret:"true"`
+}
+
+type config struct {
+	Name          string
+	Credentials   credentials
+	Authorization *credentials
+	Accounts      []credentials
+	ByName        map[string]credentials
+	Token         string            `secret:"true"`
+	Options       map[string]string `secret:"true"`
+}
+
+func newTestKeyProvider(t *testing.T, content string) KeyProvider {
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	provider, err := NewKeyProvider("file:" + path)
+	require.NoError(t, err)
+
+	return provider
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	service, err := NewService(newTestKeyProvider(t, "first key"))
+	require.NoError(t, err)
+
+	encrypted, err := service.Encrypt("passwd")
+	require.NoError(t, err)
+	require.True(t, IsEncrypted(encrypted))
+	require.NotContains(t, encrypted, "passwd")
+
+	keyID, ok := KeyIDOf(encrypted)
+	require.True(t, ok)
+	assert.Equal(t, service.KeyID(), keyID)
+
+	decrypted, err := service.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "passwd", decrypted)
+
+	// the values stored before the encryption was enabled are read as they are
+	decrypted, err = service.Decrypt("plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", decrypted)
+
+	encrypted, err = service.Encrypt("")
+	require.NoError(t, err)
+	assert.Empty(t, encrypted)
+
+	t.Run("previous key", func(t *testing.T) {
+		rotated, err := NewService(newTestKeyProvider(t, "second key"), newTestKeyProvider(t, "first key"))
+		require.NoError(t, err)
+		require.NotEqual(t, service.KeyID(), rotated.KeyID())
+
+		value, err := service.Encrypt("passwd")
+		require.NoError(t, err)
+
+		decrypted, err := rotated.Decrypt(value)
+		require.NoError(t, err)
+		assert.Equal(t, "passwd", decrypted)
+
+		// the previous key is unknown to the first service
+		value, err = rotated.Encrypt("passwd")
+		require.NoError(t, err)
+
+		_, err = service.Decrypt(value)
+		require.ErrorIs(t, err, ErrUnknownSecretsKey)
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		var noKey *Service
+
+		value, err := service.Encrypt("passwd")
+		require.NoError(t, err)
+
+		_, err = noKey.Decrypt(value)
+		require.ErrorIs(t, err, ErrNoSecretsKey)
+	})
+
+	t.Run("tampered value", func(t *testing.T) {
+		value, err := service.Encrypt("passwd")
+		require.NoError(t, err)
+
+		_, err = service.Decrypt(value[:len(value)-4] + "AAAA")
+		require.Error(t, err)
+
+		_, err = service.Decrypt(envelopePrefix + "garbage")
+		require.ErrorIs(t, err, ErrInvalidEncryptedValue)
+	})
+}
+
+func TestEncryptDecryptFields(t *testing.T) {
+	service, err := NewService(newTestKeyProvider(t, "key"))
+	require.NoError(t, err)
+
+	require.True(t, HasSecretFields(&config{}))
+	require.True(t, HasSecretFields([]config{}))
+	require.False(t, HasSecretFields(&struct{ Password string }{}))
+	require.False(t, HasSecretFields("value"))
+
+	object := &config{
+		Name:          "name",
+		Credentials:   credentials{Username: "user", Password: "p1"},
+		Authorization: &credentials{Password: "p2"},
+		Accounts:      []credentials{{Password: "p3"}},
+		ByName:        map[string]credentials{"a": {Password: "p4"}},
+		Token:         "p5",
+		Options:       map[string]string{"apiToken": "p6"},
+	}
+
+	options := object.Options
+
+	require.NoError(t, service.EncryptFields(object))
+
+	for _, value := range []string{object.Credentials.Password, object.Authorization.Password, object.Accounts[0].Password, object.ByName["a"].Password, object.Token, object.Options["apiToken"]} {
+		require.True(t, IsEncrypted(value), value)
+	}
+
+	assert.Equal(t, "name", object.Name)
+	assert.Equal(t, "user", object.Credentials.Username)
+	assert.Equal(t, "p6", options["apiToken"], "the maps of the caller are left untouched")
+
+	require.NoError(t, service.DecryptFields(object))
+
+	assert.Equal(t, &config{
+		Name:          "name",
+		Credentials:   credentials{Username: "user", Password: "p1"},
+		Authorization: &credentials{Password: "p2"},
+		Accounts:      []credentials{{Password: "p3"}},
+		ByName:        map[string]credentials{"a": {Password: "p4"}},
+		Token:         "p5",
+		Options:       map[string]string{"apiToken": "p6"},
+	}, object)
+}
+
+func TestEncryptedCopy(t *testing.T) {
+	service, err := NewService(newTestKeyProvider(t, "key"))
+	require.NoError(t, err)
+
+	newObject := func() *config {
+		return &config{
+			Name:          "name",
+			Credentials:   credentials{Username: "user", Password: "p1"},
+			Authorization: &credentials{Password: "p2"},
+			Accounts:      []credentials{{Password: "p3"}},
+			ByName:        map[string]credentials{"a": {Password: "p4"}},
+			Token:         "p5",
+			Options:       map[string]string{"apiToken": "p6"},
+		}
+	}
+
+	object := newObject()
+
+	clone, err := service.EncryptedCopy(object)
+	require.NoError(t, err)
+
+	// the object of the caller is left untouched
+	assert.Equal(t, newObject(), object)
+
+	encrypted, ok := clone.(*config)
+	require.True(t, ok)
+
+	for _, value := range []string{encrypted.Credentials.Password, encrypted.Authorization.Password, encrypted.Accounts[0].Password, encrypted.ByName["a"].Password, encrypted.Token, encrypted.Options["apiToken"]} {
+		require.True(t, IsEncrypted(value), value)
+	}
+
+	assert.Equal(t, "name", encrypted.Name)
+	assert.Equal(t, "user", encrypted.Credentials.Username)
+
+	require.NoError(t, service.DecryptFields(encrypted))
+	assert.Equal(t, newObject(), encrypted)
+
+	// the values without secret fields are not copied
+	clone, err = service.EncryptedCopy(credentials{Username: "user"})
+	require.NoError(t, err)
+	assert.Equal(t, credentials{Username: "user"}, clone)
+
+	names := []string{"a"}
+	clone, err = service.EncryptedCopy(names)
+	require.NoError(t, err)
+	assert.Equal(t, names, clone)
+}
+
+func TestExecKeyProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test key provider is a shell script")
+	}
+
+	// the test KMS "wraps" the keys by prepending three zero bytes
+	script := filepath.Join(t.TempDir(), "kms")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+case "$1" in
+  key-id) echo "kms-test" ;;
+  wrap) read key; echo "AAAA$key" ;;
+  unwrap) read key; echo "${key#AAAA}" ;;
+  *) exit 1 ;;
+esac
+`), 0700))
+
+	provider, err := NewKeyProvider("exec:" + script)
+	require.NoError(t, err)
+	assert.Equal(t, "kms-test", provider.KeyID())
+
+	service, err := NewService(provider)
+	require.NoError(t, err)
+
+	value, err := service.Encrypt("passwd")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(value, envelopePrefix+"kms-test:"))
+
+	// a new service unwraps the data key with the command
+	service, err = NewService(provider)
+	require.NoError(t, err)
+
+	decrypted, err := service.Decrypt(value)
+	require.NoError(t, err)
+	assert.Equal(t, "passwd", decrypted)
+}
@@ -0,0 +1,141 @@
+package buildlogs
+
+import (
+	"context"
+	"sync"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// MaxStoredOutputSize is the maximum size of the build output kept with a stack, the beginning of the longer outputs
+// is dropped
+const MaxStoredOutputSize = 64 * 1024
+
+// maxBufferedOutputSize is the maximum size of the output kept in memory while a build is in progress, the oldest half
+// is dropped once it is reached
+const maxBufferedOutputSize = 1024 * 1024
+
+// Service keeps track of the builds in progress so that their output can be followed while the stacks are deployed
+type Service struct {
+	mu     sync.Mutex
+	builds map[portainer.StackID]*Build
+}
+
+// NewService returns a new build logs service
+func NewService() *Service {
+	return &Service{
+		builds: make(map[portainer.StackID]*Build),
+	}
+}
+
+// Start registers a new build for the stack, it replaces the build previously registered for the stack
+func (service *Service) Start(stackID portainer.StackID) *Build {
+	build := &Build{changed: make(chan struct{})}
+
+	service.mu.Lock()
+	service.builds[stackID] = build
+	service.mu.Unlock()
+
+	return build
+}
+
+// Finish marks the build as ended and unregisters it
+func (service *Service) Finish(stackID portainer.StackID, build *Build) {
+	service.mu.Lock()
+	if service.builds[stackID] == build {
+		delete(service.builds, stackID)
+	}
+	service.mu.Unlock()
+
+	build.mu.Lock()
+	if !build.done {
+		build.done = true
+		close(build.changed)
+	}
+	build.mu.Unlock()
+}
+
+// Running returns the build in progress of the stack, or nil when the stack is not being built
+func (service *Service) Running(stackID portainer.StackID) *Build {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	return service.builds[stackID]
+}
+
+// Build records the output of a build and notifies its followers
+type Build struct {
+	mu     sync.Mutex
+	output []byte
+	// dropped is the size of the beginning of the output dropped from memory
+	dropped int
+	done    bool
+	changed chan struct{}
+}
+
+// Write appends p to the output of the build
+func (build *Build) Write(p []byte) (int, error) {
+	build.mu.Lock()
+	defer build.mu.Unlock()
+
+	build.output = append(build.output, p...)
+
+	if len(build.output) > maxBufferedOutputSize {
+		trimmed := len(build.output) - maxBufferedOutputSize/2
+		build.output = append([]byte(nil), build.output[trimmed:]...)
+		build.dropped += trimmed
+	}
+
+	if !build.done {
+		close(build.changed)
+		build.changed = make(chan struct{})
+	}
+
+	return len(p), nil
+}
+
+// Output returns the output of the build, truncated to its last MaxStoredOutputSize bytes
+func (build *Build) Output() string {
+	build.mu.Lock()
+	defer build.mu.Unlock()
+
+	output := build.output
+	if len(output) > MaxStoredOutputSize {
+		output = output[len(output)-MaxStoredOutputSize:]
+	}
+
+	return string(output)
+}
+
+// Next returns the output written after offset and the offset of its end, it waits for more output while the build
+// is in progress. The output dropped from memory is skipped. done is true once the build ended and its whole output
+// was returned.
+func (build *Build) Next(ctx context.Context, offset int) (output []byte, next int, done bool, err error) {
+	for {
+		build.mu.Lock()
+
+		start := max(offset-build.dropped, 0)
+		if start < len(build.output) {
+			output = append([]byte(nil), build.output[start:]...)
+			next = build.dropped + len(build.output)
+			build.mu.Unlock()
+
+			return output, next, false, nil
+		}
+
+		if build.done {
+			build.mu.Unlock()
+
+			return nil, offset, true, nil
+		}
+
+		changed := build.changed
+		build.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, offset, false, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package buildlogs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildFollow(t *testing.T) {
+	service := NewService()
+
+	build := service.Start(1)
+	require.Same(t, build, service.Running(1))
+	require.Nil(t, service.Running(2))
+
+	_, err := build.Write([]byte("Step 1/2 : FROM alpine\n"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	output, offset, done, err := build.Next(ctx, 0)
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, "Step 1/2 : FROM alpine\n", string(output))
+	require.Equal(t, len(output), offset)
+
+	go func() {
+		_, _ = build.Write([]byte("Step 2/2 : RUN true\n"))
+		service.Finish(1, build)
+	}()
+
+	output, offset, done, err = build.Next(ctx, offset)
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, "Step 2/2 : RUN true\n", string(output))
+
+	_, _, done, err = build.Next(ctx, offset)
+	require.NoError(t, err)
+	require.True(t, done)
+
+	require.Nil(t, service.Running(1))
+	require.Equal(t, "Step 1/2 : FROM alpine\nStep 2/2 : RUN true\n", build.Output())
+}
+
+func TestBuildNextCanceled(t *testing.T) {
+	build := NewService().Start(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, _, err := build.Next(ctx, 0)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBuildOutputTruncated(t *testing.T) {
+	build := NewService().Start(1)
+
+	_, err := build.Write([]byte(strings.Repeat("a", MaxStoredOutputSize) + "end"))
+	require.NoError(t, err)
+
+	output := build.Output()
+	require.Len(t, output, MaxStoredOutputSize)
+	require.True(t, strings.HasSuffix(output, "end"))
+}
+
+func TestBuildOutputBuffered(t *testing.T) {
+	build := NewService().Start(1)
+
+	line := strings.Repeat("a", 1023) + "\n"
+	for i := 0; i < 2*maxBufferedOutputSize/len(line); i++ {
+		_, err := build.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	_, err := build.Write([]byte("end"))
+	require.NoError(t, err)
+
+	require.LessOrEqual(t, len(build.output), maxBufferedOutputSize)
+	require.True(t, strings.HasSuffix(build.Output(), "end"))
+
+	// the followers skip the output dropped from memory
+	output, next, done, err := build.Next(context.Background(), 0)
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, 2*maxBufferedOutputSize+len("end"), next)
+	require.Equal(t, string(build.output), string(output))
+}
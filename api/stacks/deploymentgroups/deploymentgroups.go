@@ -0,0 +1,401 @@
+package deploymentgroups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/scheduler"
+	"github.com/portainer/portainer/api/stacks/deployments"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultWaitTimeout is the time given to each stack of a group to run before the deployment is aborted
+const DefaultWaitTimeout = 5 * time.Minute
+
+var (
+	ErrDependencyCycle = errors.New("the dependencies of the stacks form a cycle")
+	ErrGroupRunning    = errors.New("the deployment group is already being deployed or torn down")
+)
+
+// Order returns the stacks of a group sorted so that every stack comes after the stacks it depends on. The stacks
+// without dependency between them keep the order of the group.
+func Order(stacks []portainer.DeploymentGroupStack) ([]portainer.StackID, error) {
+	remaining := make(map[portainer.StackID]int, len(stacks))
+	for _, stack := range stacks {
+		if _, ok := remaining[stack.StackID]; ok {
+			return nil, fmt.Errorf("the stack %d is referenced more than once", stack.StackID)
+		}
+
+		remaining[stack.StackID] = len(stack.DependsOn)
+	}
+
+	for _, stack := range stacks {
+		for _, dependency := range stack.DependsOn {
+			if _, ok := remaining[dependency]; !ok {
+				return nil, fmt.Errorf("the stack %d depends on the stack %d which is not part of the group", stack.StackID, dependency)
+			}
+
+			if dependency == stack.StackID {
+				return nil, fmt.Errorf("%w: the stack %d depends on itself", ErrDependencyCycle, stack.StackID)
+			}
+		}
+	}
+
+	order := make([]portainer.StackID, 0, len(stacks))
+	for len(order) < len(stacks) {
+		progressed := false
+
+		for _, stack := range stacks {
+			if remaining[stack.StackID] != 0 {
+				continue
+			}
+
+			order = append(order, stack.StackID)
+			remaining[stack.StackID] = -1
+			progressed = true
+
+			for _, dependent := range stacks {
+				if slices.Contains(dependent.DependsOn, stack.StackID) {
+					remaining[dependent.StackID]--
+				}
+			}
+		}
+
+		if !progressed {
+			return nil, ErrDependencyCycle
+		}
+	}
+
+	return order, nil
+}
+
+// GroupOfStack returns the deployment group holding the stack, or nil when the stack is not part of any group
+func GroupOfStack(tx dataservices.DataStoreTx, stackID portainer.StackID) (*portainer.DeploymentGroup, error) {
+	groups, err := tx.DeploymentGroup().ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		if slices.ContainsFunc(group.Stacks, func(stack portainer.DeploymentGroupStack) bool { return stack.StackID == stackID }) {
+			return &group, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// RemoveStacks removes the stacks from the deployment groups along with the dependencies on them, the groups left
+// without any stack are deleted
+func RemoveStacks(tx dataservices.DataStoreTx, stackIDs ...portainer.StackID) error {
+	groups, err := tx.DeploymentGroup().ReadAll()
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		stacks := slices.DeleteFunc(slices.Clone(group.Stacks), func(stack portainer.DeploymentGroupStack) bool {
+			return slices.Contains(stackIDs, stack.StackID)
+		})
+
+		if len(stacks) == len(group.Stacks) {
+			continue
+		}
+
+		if len(stacks) == 0 {
+			if err := tx.DeploymentGroup().Delete(group.ID); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		for i := range stacks {
+			stacks[i].DependsOn = slices.DeleteFunc(slices.Clone(stacks[i].DependsOn), func(stackID portainer.StackID) bool {
+				return slices.Contains(stackIDs, stackID)
+			})
+		}
+
+		group.Stacks = stacks
+
+		if err := tx.DeploymentGroup().Update(group.ID, &group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResetUnfinishedRuns marks the runs still in progress as failed, they were interrupted by a restart of the server
+func ResetUnfinishedRuns(dataStore dataservices.DataStore) error {
+	return dataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		groups, err := tx.DeploymentGroup().ReadAll()
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			if group.LastRun == nil || !group.LastRun.Running {
+				continue
+			}
+
+			group.LastRun.Running = false
+			group.LastRun.Success = false
+			group.LastRun.Error = "the run was interrupted by a restart of the server"
+
+			if err := tx.DeploymentGroup().Update(group.ID, &group); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Service deploys and tears down the stacks of the deployment groups
+type Service struct {
+	dataStore     dataservices.DataStore
+	fileService   portainer.FileService
+	stackDeployer deployments.StackDeployer
+	scheduler     *scheduler.Scheduler
+	gitService    portainer.GitService
+	// WaitTimeout is the time given to each stack to run
+	WaitTimeout time.Duration
+
+	mu      sync.Mutex
+	running map[portainer.DeploymentGroupID]bool
+}
+
+// NewService creates a service to deploy and tear down the deployment groups
+func NewService(dataStore dataservices.DataStore, fileService portainer.FileService, stackDeployer deployments.StackDeployer, scheduler *scheduler.Scheduler, gitService portainer.GitService) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		fileService:   fileService,
+		stackDeployer: stackDeployer,
+		scheduler:     scheduler,
+		gitService:    gitService,
+		WaitTimeout:   DefaultWaitTimeout,
+		running:       make(map[portainer.DeploymentGroupID]bool),
+	}
+}
+
+// StartDeploy starts the deployment of the group in the background and returns the run in progress, which is stored
+// as the last run of the group until it is replaced by the result of the deployment. The stacks are deployed in the
+// order of their dependencies, each stack must run with its health checks passing before the stacks depending on it
+// are deployed. The deployment is aborted at the first failure.
+func (service *Service) StartDeploy(group *portainer.DeploymentGroup, securityContext *security.RestrictedRequestContext) (*portainer.DeploymentGroupRun, error) {
+	order, err := Order(group.Stacks)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.reserve(group.ID); err != nil {
+		return nil, err
+	}
+
+	run := newRun(portainer.DeploymentGroupDeploy)
+	run.Running = true
+
+	if err := service.saveRun(group.ID, run); err != nil {
+		service.release(group.ID)
+
+		return nil, err
+	}
+
+	go func() {
+		defer service.release(group.ID)
+
+		if _, err := service.execute(group, portainer.DeploymentGroupDeploy, order, service.deployFunc(context.Background(), securityContext)); err != nil {
+			log.Error().Err(err).Int("deployment_group_id", int(group.ID)).Msg("unable to deploy the deployment group")
+		}
+	}()
+
+	return run, nil
+}
+
+// Teardown stops the stacks of the group in the reverse order of their dependencies, the stacks are kept along
+// with their files so that the group can be deployed again. The teardown is aborted at the first failure.
+func (service *Service) Teardown(group *portainer.DeploymentGroup) (*portainer.DeploymentGroupRun, error) {
+	order, err := Order(group.Stacks)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(order)
+
+	if err := service.reserve(group.ID); err != nil {
+		return nil, err
+	}
+	defer service.release(group.ID)
+
+	return service.execute(group, portainer.DeploymentGroupTeardown, order, service.stopStack)
+}
+
+func (service *Service) deployFunc(ctx context.Context, securityContext *security.RestrictedRequestContext) func(*portainer.Stack, *portainer.Endpoint) error {
+	return func(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+		return service.deployStack(ctx, stack, endpoint, securityContext)
+	}
+}
+
+// reserve marks the group as running, only one run of a group can be in progress at a time
+func (service *Service) reserve(groupID portainer.DeploymentGroupID) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.running[groupID] {
+		return ErrGroupRunning
+	}
+
+	service.running[groupID] = true
+
+	return nil
+}
+
+func (service *Service) release(groupID portainer.DeploymentGroupID) {
+	service.mu.Lock()
+	delete(service.running, groupID)
+	service.mu.Unlock()
+}
+
+func newRun(action portainer.DeploymentGroupAction) *portainer.DeploymentGroupRun {
+	return &portainer.DeploymentGroupRun{
+		Action: action,
+		Date:   time.Now().Unix(),
+		Stacks: []portainer.StackID{},
+	}
+}
+
+func (service *Service) execute(group *portainer.DeploymentGroup, action portainer.DeploymentGroupAction, order []portainer.StackID, fn func(*portainer.Stack, *portainer.Endpoint) error) (*portainer.DeploymentGroupRun, error) {
+	run := newRun(action)
+
+	for _, stackID := range order {
+		if err := service.runStack(stackID, fn); err != nil {
+			log.Warn().
+				Err(err).
+				Int("deployment_group_id", int(group.ID)).
+				Int("stack_id", int(stackID)).
+				Str("action", string(action)).
+				Msg("deployment group run aborted")
+
+			run.FailedStackID = stackID
+			run.Error = err.Error()
+
+			break
+		}
+
+		run.Stacks = append(run.Stacks, stackID)
+	}
+
+	run.Success = run.Error == ""
+
+	if err := service.saveRun(group.ID, run); err != nil {
+		return nil, err
+	}
+
+	group.LastRun = run
+
+	return run, nil
+}
+
+// saveRun stores the run as the last run of the group
+func (service *Service) saveRun(groupID portainer.DeploymentGroupID, run *portainer.DeploymentGroupRun) error {
+	err := service.dataStore.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		storedGroup, err := tx.DeploymentGroup().Read(groupID)
+		if err != nil {
+			return err
+		}
+
+		storedGroup.LastRun = run
+
+		return tx.DeploymentGroup().Update(groupID, storedGroup)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to persist the deployment group run: %w", err)
+	}
+
+	return nil
+}
+
+func (service *Service) runStack(stackID portainer.StackID, fn func(*portainer.Stack, *portainer.Endpoint) error) error {
+	stack, err := service.dataStore.Stack().Read(stackID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the stack: %w", err)
+	}
+
+	endpoint, err := service.dataStore.Endpoint().Endpoint(stack.EndpointID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the environment of the stack %s: %w", stack.Name, err)
+	}
+
+	return fn(stack, endpoint)
+}
+
+func (service *Service) deployStack(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, securityContext *security.RestrictedRequestContext) error {
+	var deploymentConfig deployments.StackDeploymentConfiger
+	var err error
+
+	switch stack.Type {
+	case portainer.DockerComposeStack:
+		deploymentConfig, err = deployments.CreateComposeStackDeploymentConfig(securityContext, stack, endpoint, service.dataStore, service.fileService, service.stackDeployer, false, false)
+	case portainer.DockerSwarmStack:
+		prune := stack.Option != nil && stack.Option.Prune
+		deploymentConfig, err = deployments.CreateSwarmStackDeploymentConfig(securityContext, stack, endpoint, service.dataStore, service.fileService, service.stackDeployer, prune, false)
+	default:
+		return fmt.Errorf("the stack %s is neither a Compose nor a Swarm stack", stack.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := deploymentConfig.Deploy(); err != nil {
+		return fmt.Errorf("unable to deploy the stack %s: %w", stack.Name, err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, service.WaitTimeout)
+	defer cancel()
+
+	if err := service.stackDeployer.WaitForRunning(waitCtx, stack, endpoint); err != nil {
+		return err
+	}
+
+	if stack.AutoUpdate != nil && stack.AutoUpdate.Interval != "" && stack.AutoUpdate.JobID == "" {
+		jobID, httpErr := deployments.StartAutoupdate(stack.ID, stack.AutoUpdate.Interval, service.scheduler, service.stackDeployer, service.dataStore, service.gitService)
+		if httpErr != nil {
+			return httpErr.Err
+		}
+
+		stack.AutoUpdate.JobID = jobID
+	}
+
+	stack.Status = portainer.StackStatusActive
+	stack.UpdatedBy = deploymentConfig.GetUsername()
+	stack.UpdateDate = time.Now().Unix()
+
+	return service.dataStore.Stack().Update(stack.ID, stack)
+}
+
+func (service *Service) stopStack(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	// stop scheduler updates of the stack so that it is not deployed again
+	if stack.AutoUpdate != nil && stack.AutoUpdate.JobID != "" {
+		deployments.StopAutoupdate(stack.ID, stack.AutoUpdate.JobID, service.scheduler)
+		stack.AutoUpdate.JobID = ""
+	}
+
+	if stack.Status != portainer.StackStatusInactive {
+		if err := service.stackDeployer.StopStack(stack, endpoint); err != nil {
+			return fmt.Errorf("unable to stop the stack %s: %w", stack.Name, err)
+		}
+	}
+
+	stack.Status = portainer.StackStatusInactive
+
+	return service.dataStore.Stack().Update(stack.ID, stack)
+}
@@ -0,0 +1,249 @@
+package deploymentgroups
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/deployments"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrder(t *testing.T) {
+	order, err := Order([]portainer.DeploymentGroupStack{
+		{StackID: 3, DependsOn: []portainer.StackID{2}},
+		{StackID: 4},
+		{StackID: 2, DependsOn: []portainer.StackID{1}},
+		{StackID: 1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []portainer.StackID{4, 1, 2, 3}, order)
+
+	_, err = Order([]portainer.DeploymentGroupStack{
+		{StackID: 1, DependsOn: []portainer.StackID{2}},
+		{StackID: 2, DependsOn: []portainer.StackID{1}},
+	})
+	require.ErrorIs(t, err, ErrDependencyCycle)
+
+	_, err = Order([]portainer.DeploymentGroupStack{{StackID: 1, DependsOn: []portainer.StackID{1}}})
+	require.ErrorIs(t, err, ErrDependencyCycle)
+
+	_, err = Order([]portainer.DeploymentGroupStack{{StackID: 1, DependsOn: []portainer.StackID{5}}})
+	require.ErrorContains(t, err, "not part of the group")
+
+	_, err = Order([]portainer.DeploymentGroupStack{{StackID: 1}, {StackID: 1}})
+	require.ErrorContains(t, err, "more than once")
+}
+
+type recordingDeployer struct {
+	deployments.StackDeployer
+	calls     []string
+	unhealthy string
+}
+
+func (d *recordingDeployer) DeployComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage, forceRecreate bool) error {
+	d.calls = append(d.calls, "deploy "+stack.Name)
+
+	return nil
+}
+
+func (d *recordingDeployer) WaitForRunning(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	d.calls = append(d.calls, "wait "+stack.Name)
+
+	if stack.Name == d.unhealthy {
+		return errors.New("service web is unhealthy")
+	}
+
+	return nil
+}
+
+func (d *recordingDeployer) StopStack(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	d.calls = append(d.calls, "stop "+stack.Name)
+
+	return nil
+}
+
+func TestDeployTeardown(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	require.NoError(t, store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}))
+
+	for i, name := range []string{"database", "backend", "frontend"} {
+		require.NoError(t, store.Stack().Create(&portainer.Stack{
+			ID:         portainer.StackID(i + 1),
+			Name:       name,
+			EndpointID: 1,
+			Type:       portainer.DockerComposeStack,
+			Status:     portainer.StackStatusInactive,
+		}))
+	}
+
+	group := &portainer.DeploymentGroup{
+		Name: "shop",
+		Stacks: []portainer.DeploymentGroupStack{
+			{StackID: 3, DependsOn: []portainer.StackID{2}},
+			{StackID: 2, DependsOn: []portainer.StackID{1}},
+			{StackID: 1},
+		},
+	}
+	require.NoError(t, store.DeploymentGroup().Create(group))
+
+	fs, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	deployer := &recordingDeployer{}
+	service := NewService(store, fs, deployer, nil, nil)
+	securityContext := &security.RestrictedRequestContext{UserID: 1, IsAdmin: true}
+
+	// deploy starts the deployment of the group and waits for its end
+	deploy := func(t *testing.T) *portainer.DeploymentGroupRun {
+		run, err := service.StartDeploy(group, securityContext)
+		require.NoError(t, err)
+		require.True(t, run.Running)
+
+		require.Eventually(t, func() bool {
+			service.mu.Lock()
+			defer service.mu.Unlock()
+
+			return !service.running[group.ID]
+		}, 5*time.Second, 10*time.Millisecond)
+
+		storedGroup, err := store.DeploymentGroup().Read(group.ID)
+		require.NoError(t, err)
+		require.False(t, storedGroup.LastRun.Running)
+		require.Equal(t, portainer.DeploymentGroupDeploy, storedGroup.LastRun.Action)
+
+		return storedGroup.LastRun
+	}
+
+	t.Run("deploy", func(t *testing.T) {
+		run := deploy(t)
+		require.True(t, run.Success)
+		require.Equal(t, []portainer.StackID{1, 2, 3}, run.Stacks)
+		require.Equal(t, []string{"deploy database", "wait database", "deploy backend", "wait backend", "deploy frontend", "wait frontend"}, deployer.calls)
+
+		stack, err := store.Stack().Read(2)
+		require.NoError(t, err)
+		require.Equal(t, portainer.StackStatusActive, stack.Status)
+		require.Equal(t, "admin", stack.UpdatedBy)
+	})
+
+	t.Run("teardown", func(t *testing.T) {
+		deployer.calls = nil
+
+		run, err := service.Teardown(group)
+		require.NoError(t, err)
+		require.True(t, run.Success)
+		require.Equal(t, []portainer.StackID{3, 2, 1}, run.Stacks)
+		require.Equal(t, []string{"stop frontend", "stop backend", "stop database"}, deployer.calls)
+
+		stack, err := store.Stack().Read(1)
+		require.NoError(t, err)
+		require.Equal(t, portainer.StackStatusInactive, stack.Status)
+
+		storedGroup, err := store.DeploymentGroup().Read(group.ID)
+		require.NoError(t, err)
+		require.Equal(t, run, storedGroup.LastRun)
+	})
+
+	t.Run("abort on failure", func(t *testing.T) {
+		deployer.calls = nil
+		deployer.unhealthy = "backend"
+
+		run := deploy(t)
+		require.False(t, run.Success)
+		require.Equal(t, []portainer.StackID{1}, run.Stacks)
+		require.Equal(t, portainer.StackID(2), run.FailedStackID)
+		require.Equal(t, "service web is unhealthy", run.Error)
+		require.Equal(t, []string{"deploy database", "wait database", "deploy backend", "wait backend"}, deployer.calls)
+
+		stack, err := store.Stack().Read(3)
+		require.NoError(t, err)
+		require.Equal(t, portainer.StackStatusInactive, stack.Status)
+	})
+
+	t.Run("concurrent run", func(t *testing.T) {
+		service.running[group.ID] = true
+		defer delete(service.running, group.ID)
+
+		_, err := service.Teardown(group)
+		require.ErrorIs(t, err, ErrGroupRunning)
+
+		_, err = service.StartDeploy(group, securityContext)
+		require.ErrorIs(t, err, ErrGroupRunning)
+	})
+}
+
+func TestGroupOfStack(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	require.NoError(t, store.DeploymentGroup().Create(&portainer.DeploymentGroup{
+		Name:   "shop",
+		Stacks: []portainer.DeploymentGroupStack{{StackID: 2, DependsOn: []portainer.StackID{1}}, {StackID: 1}},
+	}))
+
+	group, err := GroupOfStack(store, 1)
+	require.NoError(t, err)
+	require.NotNil(t, group)
+	require.Equal(t, "shop", group.Name)
+
+	group, err = GroupOfStack(store, 3)
+	require.NoError(t, err)
+	require.Nil(t, group)
+}
+
+func TestRemoveStacks(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	require.NoError(t, store.DeploymentGroup().Create(&portainer.DeploymentGroup{
+		Name:   "shop",
+		Stacks: []portainer.DeploymentGroupStack{{StackID: 3, DependsOn: []portainer.StackID{1, 2}}, {StackID: 2}, {StackID: 1}},
+	}))
+	require.NoError(t, store.DeploymentGroup().Create(&portainer.DeploymentGroup{
+		Name:   "blog",
+		Stacks: []portainer.DeploymentGroupStack{{StackID: 1}},
+	}))
+
+	require.NoError(t, RemoveStacks(store, 1))
+
+	groups, err := store.DeploymentGroup().ReadAll()
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, []portainer.DeploymentGroupStack{{StackID: 3, DependsOn: []portainer.StackID{2}}, {StackID: 2}}, groups[0].Stacks)
+}
+
+func TestResetUnfinishedRuns(t *testing.T) {
+	_, store := datastore.MustNewTestStore(t, true, false)
+
+	finished := &portainer.DeploymentGroupRun{Action: portainer.DeploymentGroupDeploy, Success: true, Stacks: []portainer.StackID{1}}
+
+	require.NoError(t, store.DeploymentGroup().Create(&portainer.DeploymentGroup{
+		Name:    "shop",
+		Stacks:  []portainer.DeploymentGroupStack{{StackID: 1}},
+		LastRun: &portainer.DeploymentGroupRun{Action: portainer.DeploymentGroupDeploy, Running: true, Stacks: []portainer.StackID{}},
+	}))
+	require.NoError(t, store.DeploymentGroup().Create(&portainer.DeploymentGroup{
+		Name:    "blog",
+		Stacks:  []portainer.DeploymentGroupStack{{StackID: 1}},
+		LastRun: finished,
+	}))
+
+	require.NoError(t, ResetUnfinishedRuns(store))
+
+	interrupted, err := store.DeploymentGroup().Read(1)
+	require.NoError(t, err)
+	require.False(t, interrupted.LastRun.Running)
+	require.False(t, interrupted.LastRun.Success)
+	require.NotEmpty(t, interrupted.LastRun.Error)
+
+	unchanged, err := store.DeploymentGroup().Read(2)
+	require.NoError(t, err)
+	require.Equal(t, finished, unchanged.LastRun)
+}
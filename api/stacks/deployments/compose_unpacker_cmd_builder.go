@@ -2,6 +2,7 @@ package deployments
 
 import (
 	"fmt"
+	"slices"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
@@ -34,6 +35,8 @@ type unpackerCmdBuilderOptions struct {
 	forceRecreate      bool
 	composeDestination string
 	registries         []portainer.Registry
+	// env vars inherited from the variable sets of the stack, overridden by the env vars of the stack
+	inheritedEnv []portainer.Pair
 }
 
 type buildCmdFunc func(stack *portainer.Stack, opts unpackerCmdBuilderOptions, registries []string, env []string) []string
@@ -57,7 +60,7 @@ func (d *stackDeployer) buildUnpackerCmdForStack(stack *portainer.Stack, operati
 	}
 
 	registriesStrings := generateRegistriesStrings(opts.registries, d.dataStore)
-	envStrings := getEnv(stack.Env)
+	envStrings := getEnv(append(slices.Clip(opts.inheritedEnv), stack.Env...))
 
 	return fn(stack, opts, registriesStrings, envStrings), nil
 }
@@ -32,6 +32,8 @@ func (e *StackAuthorMissingErr) Error() string {
 
 var singleflightGroup = &singleflight.Group{}
 
+var errEnvironmentOffline = errors.New("the environment of the stack is offline")
+
 // RedeployWhenChanged pull and redeploy the stack when git repo changed
 // Stack will always be redeployed if force deployment is set to true
 func RedeployWhenChanged(stackID portainer.StackID, deployer StackDeployer, datastore dataservices.DataStore, gitService portainer.GitService) error {
@@ -44,21 +46,46 @@ func RedeployWhenChanged(stackID portainer.StackID, deployer StackDeployer, data
 
 	// Webhook
 	if stack.AutoUpdate != nil && stack.AutoUpdate.Webhook != "" {
-		return redeployWhenChanged(stack, deployer, datastore, gitService, true)
+		return redeployWhenChanged(stack, deployer, datastore, gitService, func(_ bool, err error) {
+			if err != nil {
+				log.Error().Err(err).
+					Int("stack_id", int(stack.ID)).
+					Str("stack", stack.Name).
+					Int("endpoint_id", int(stack.EndpointID)).
+					Msg("webhook failed to redeploy a stack")
+			}
+		})
 	}
 
 	// Polling
 	_, err, _ = singleflightGroup.Do(strconv.Itoa(int(stackID)), func() (any, error) {
-		return nil, redeployWhenChanged(stack, deployer, datastore, gitService, false)
+		return nil, redeployWhenChanged(stack, deployer, datastore, gitService, nil)
 	})
 
 	return err
 }
 
-func redeployWhenChanged(stack *portainer.Stack, deployer StackDeployer, datastore dataservices.DataStore, gitService portainer.GitService, webhook bool) error {
+// RedeployPushedStack redeploys in the background a stack whose webhook received a push event, done is called with
+// the outcome once the redeployment ends. The returned error is the one preventing the redeployment from starting.
+func RedeployPushedStack(stackID portainer.StackID, deployer StackDeployer, datastore dataservices.DataStore, gitService portainer.GitService, done func(deployed bool, err error)) error {
+	stack, err := datastore.Stack().Read(stackID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get the stack %v", stackID)
+	}
+
+	return redeployWhenChanged(stack, deployer, datastore, gitService, done)
+}
+
+// redeployWhenChanged redeploys the stack when its git repository changed, the redeployment runs in the background
+// when done is set and done is then called with its outcome
+func redeployWhenChanged(stack *portainer.Stack, deployer StackDeployer, datastore dataservices.DataStore, gitService portainer.GitService, done func(deployed bool, err error)) error {
 	log.Debug().Int("stack_id", int(stack.ID)).Msg("redeploying stack")
 
 	if stack.GitConfig == nil {
+		if done != nil {
+			done(false, nil)
+		}
+
 		return nil // do nothing if it isn't a git-based stack
 	}
 
@@ -90,25 +117,24 @@ func redeployWhenChanged(stack *portainer.Stack, deployer StackDeployer, datasto
 	}
 
 	if !isEnvironmentOnline(endpoint) {
+		if done != nil {
+			done(false, errEnvironmentOffline)
+		}
+
 		return nil
 	}
 
-	if webhook {
+	if done != nil {
 		go func() {
-			if err := redeployWhenChangedSecondStage(stack, deployer, datastore, gitService, user, endpoint); err != nil {
-				log.Error().Err(err).
-					Int("stack_id", int(stack.ID)).
-					Str("stack", stack.Name).
-					Str("author", author).
-					Int("endpoint_id", int(stack.EndpointID)).
-					Msg("webhook failed to redeploy a stack")
-			}
+			done(redeployWhenChangedSecondStage(stack, deployer, datastore, gitService, user, endpoint))
 		}()
 
 		return nil
 	}
 
-	return redeployWhenChangedSecondStage(stack, deployer, datastore, gitService, user, endpoint)
+	_, err = redeployWhenChangedSecondStage(stack, deployer, datastore, gitService, user, endpoint)
+
+	return err
 }
 
 func redeployWhenChangedSecondStage(
@@ -118,13 +144,13 @@ func redeployWhenChangedSecondStage(
 	gitService portainer.GitService,
 	user *portainer.User,
 	endpoint *portainer.Endpoint,
-) error {
+) (bool, error) {
 	var gitCommitChangedOrForceUpdate bool
 
 	if !stack.FromAppTemplate {
-		updated, newHash, err := update.UpdateGitObject(gitService, fmt.Sprintf("stack:%d", stack.ID), stack.GitConfig, false, false, stack.ProjectPath)
+		updated, newHash, err := update.UpdateGitObject(gitService, fmt.Sprintf("stack:%d", stack.ID), stack.GitConfig, stack.AutoUpdate, false, false, stack.ProjectPath)
 		if err != nil {
-			return err
+			return false, err
 		}
 
 		if updated {
@@ -135,14 +161,14 @@ func redeployWhenChangedSecondStage(
 	}
 
 	if !gitCommitChangedOrForceUpdate {
-		return nil
+		return false, nil
 	}
 
 	registries, err := getUserRegistries(datastore, user, endpoint.ID)
 	if dataservices.IsErrObjectNotFound(err) {
-		return scheduler.NewPermanentError(err)
+		return false, scheduler.NewPermanentError(err)
 	} else if err != nil {
-		return err
+		return false, err
 	}
 
 	switch stack.Type {
@@ -154,7 +180,7 @@ func redeployWhenChangedSecondStage(
 		}
 
 		if err != nil {
-			return errors.WithMessagef(err, "failed to deploy a docker compose stack %v", stack.ID)
+			return false, errors.WithMessagef(err, "failed to deploy a docker compose stack %v", stack.ID)
 		}
 	case portainer.DockerSwarmStack:
 		if stackutils.IsRelativePathStack(stack) {
@@ -163,25 +189,25 @@ func redeployWhenChangedSecondStage(
 			err = deployer.DeploySwarmStack(stack, endpoint, registries, true, true)
 		}
 		if err != nil {
-			return errors.WithMessagef(err, "failed to deploy a docker compose stack %v", stack.ID)
+			return false, errors.WithMessagef(err, "failed to deploy a docker compose stack %v", stack.ID)
 		}
 	case portainer.KubernetesStack:
 		log.Debug().Int("stack_id", int(stack.ID)).Msg("deploying a kube app")
 
 		if err := deployer.DeployKubernetesStack(stack, endpoint, user); err != nil {
-			return errors.WithMessagef(err, "failed to deploy a kubernetes app stack %v", stack.ID)
+			return false, errors.WithMessagef(err, "failed to deploy a kubernetes app stack %v", stack.ID)
 		}
 	default:
-		return errors.Errorf("cannot update stack, type %v is unsupported", stack.Type)
+		return false, errors.Errorf("cannot update stack, type %v is unsupported", stack.Type)
 	}
 
 	stack.Status = portainer.StackStatusActive
 
 	if err := datastore.Stack().Update(stack.ID, stack); err != nil {
-		return errors.WithMessagef(err, "failed to update the stack %v", stack.ID)
+		return false, errors.WithMessagef(err, "failed to update the stack %v", stack.ID)
 	}
 
-	return nil
+	return true, nil
 }
 
 func getUserRegistries(datastore dataservices.DataStore, user *portainer.User, endpointID portainer.EndpointID) ([]portainer.Registry, error) {
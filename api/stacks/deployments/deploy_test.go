@@ -88,6 +88,14 @@ func (s *noopDeployer) DeployKubernetesStack(stack *portainer.Stack, endpoint *p
 	return nil
 }
 
+func (s *noopDeployer) WaitForRunning(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	return nil
+}
+
+func (s *noopDeployer) StopStack(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	return nil
+}
+
 // with unpacker
 func (s *noopDeployer) DeployRemoteComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage, forceRecreate bool) error {
 	return nil
@@ -363,3 +371,14 @@ func Test_getUserRegistries(t *testing.T) {
 		assert.ElementsMatch(t, []portainer.Registry{registryReachableByUser, registryReachableByTeam}, registries)
 	})
 }
+
+func Test_DeployRemoteStack_RefusesSignedCommits(t *testing.T) {
+	deployer := &stackDeployer{}
+	stack := &portainer.Stack{AutoUpdate: &portainer.AutoUpdateSettings{RequireSignedCommits: true}}
+
+	err := deployer.DeployRemoteComposeStack(stack, &portainer.Endpoint{}, nil, false, false)
+	require.ErrorIs(t, err, ErrRemoteSignedCommits)
+
+	err = deployer.DeployRemoteSwarmStack(stack, &portainer.Endpoint{}, nil, false, false)
+	require.ErrorIs(t, err, ErrRemoteSignedCommits)
+}
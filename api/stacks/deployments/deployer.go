@@ -8,6 +8,7 @@ import (
 	"github.com/portainer/portainer/api/dataservices"
 	dockerclient "github.com/portainer/portainer/api/docker/client"
 	k "github.com/portainer/portainer/api/kubernetes"
+	"github.com/portainer/portainer/api/stacks/stackutils"
 
 	"github.com/pkg/errors"
 )
@@ -16,6 +17,10 @@ type BaseStackDeployer interface {
 	DeploySwarmStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, prune, pullImage bool) error
 	DeployComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage, forceRecreate bool) error
 	DeployKubernetesStack(stack *portainer.Stack, endpoint *portainer.Endpoint, user *portainer.User) error
+	// WaitForRunning waits for the services of a Compose or Swarm stack to run
+	WaitForRunning(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) error
+	// StopStack removes the containers or services of a Compose or Swarm stack, the stack files are kept
+	StopStack(stack *portainer.Stack, endpoint *portainer.Endpoint) error
 }
 
 type StackDeployer interface {
@@ -48,10 +53,7 @@ func (d *stackDeployer) DeploySwarmStack(stack *portainer.Stack, endpoint *porta
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	d.swarmStackManager.Login(registries, endpoint)
-	defer d.swarmStackManager.Logout(endpoint)
-
-	return d.swarmStackManager.Deploy(stack, prune, pullImage, endpoint)
+	return d.swarmStackManager.Deploy(stack, prune, pullImage, endpoint, registries)
 }
 
 func (d *stackDeployer) DeployComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage, forceRecreate bool) error {
@@ -106,3 +108,39 @@ func (d *stackDeployer) DeployKubernetesStack(stack *portainer.Stack, endpoint *
 
 	return nil
 }
+
+func (d *stackDeployer) WaitForRunning(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	switch stack.Type {
+	case portainer.DockerComposeStack:
+		return d.composeStackManager.WaitForRunning(ctx, stack, endpoint)
+	case portainer.DockerSwarmStack:
+		return d.swarmStackManager.WaitForRunning(ctx, stack, endpoint)
+	}
+
+	return errors.Errorf("unable to wait for the stack %s, only Compose and Swarm stacks are supported", stack.Name)
+}
+
+func (d *stackDeployer) StopStack(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	switch stack.Type {
+	case portainer.DockerComposeStack:
+		if stackutils.IsRelativePathStack(stack) {
+			return d.StopRemoteComposeStack(stack, endpoint)
+		}
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+
+		return d.composeStackManager.Down(context.TODO(), stack, endpoint)
+	case portainer.DockerSwarmStack:
+		if stackutils.IsRelativePathStack(stack) {
+			return d.StopRemoteSwarmStack(stack, endpoint)
+		}
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+
+		return d.swarmStackManager.Remove(stack, endpoint)
+	}
+
+	return errors.Errorf("unable to stop the stack %s, only Compose and Swarm stacks are supported", stack.Name)
+}
@@ -12,6 +12,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/stacks/stackutils"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -31,6 +32,10 @@ const (
 	composePathPrefix          = "portainer-compose-unpacker"
 )
 
+// ErrRemoteSignedCommits is returned when a stack requiring signed commits would be deployed by the compose-unpacker,
+// which clones the git reference again so the deployed commit cannot be the verified one
+var ErrRemoteSignedCommits = errors.New("the stacks requiring signed commits cannot be deployed from the environment")
+
 type RemoteStackDeployer interface {
 	// compose
 	DeployRemoteComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage bool, forceRecreate bool) error
@@ -52,12 +57,13 @@ func (d *stackDeployer) DeployRemoteComposeStack(
 	forcePullImage bool,
 	forceRecreate bool,
 ) error {
+	if stack.AutoUpdate != nil && stack.AutoUpdate.RequireSignedCommits {
+		return ErrRemoteSignedCommits
+	}
+
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	d.swarmStackManager.Login(registries, endpoint)
-	defer d.swarmStackManager.Logout(endpoint)
-
 	// --force-recreate doesn't pull updated images
 	if forcePullImage {
 		if err := d.composeStackManager.Pull(context.TODO(), stack, endpoint, portainer.ComposeOptions{}); err != nil {
@@ -113,12 +119,13 @@ func (d *stackDeployer) DeployRemoteSwarmStack(
 	prune bool,
 	pullImage bool,
 ) error {
+	if stack.AutoUpdate != nil && stack.AutoUpdate.RequireSignedCommits {
+		return ErrRemoteSignedCommits
+	}
+
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	d.swarmStackManager.Login(registries, endpoint)
-	defer d.swarmStackManager.Logout(endpoint)
-
 	return d.remoteStack(stack, endpoint, OperationSwarmDeploy, unpackerCmdBuilderOptions{
 		pullImage:     pullImage,
 		prune:         prune,
@@ -191,6 +198,7 @@ func (d *stackDeployer) remoteStack(stack *portainer.Stack, endpoint *portainer.
 	composeDestination := filesystem.JoinPaths(stack.ProjectPath, composePathPrefix)
 
 	opts.composeDestination = composeDestination
+	opts.inheritedEnv = stackutils.VariableSetsEnv(d.dataStore, stack, endpoint)
 
 	cmd, err := d.buildUnpackerCmdForStack(stack, operation, opts)
 	if err != nil {
@@ -68,6 +68,7 @@ func (b *GitMethodStackBuilder) SetGitRepository(payload *StackPayload) GitMetho
 
 	repoConfig.URL = payload.URL
 	repoConfig.ReferenceName = payload.ReferenceName
+	repoConfig.TagConstraint = payload.TagConstraint
 	repoConfig.TLSSkipVerify = payload.TLSSkipVerify
 
 	repoConfig.ConfigFilePath = payload.ComposeFile
@@ -89,7 +90,7 @@ func (b *GitMethodStackBuilder) SetGitRepository(payload *StackPayload) GitMetho
 		return b.fileService.GetStackProjectPath(stackFolder)
 	}
 
-	commitHash, err := stackutils.DownloadGitRepository(repoConfig, b.gitService, getProjectPath)
+	commitHash, err := stackutils.DownloadGitRepository(&repoConfig, b.gitService, getProjectPath)
 	if err != nil {
 		b.err = httperror.InternalServerError(err.Error(), err)
 		return b
@@ -42,6 +42,8 @@ type RepositoryConfigPayload struct {
 	URL string `example:"https://github.com/openfaas/faas" validate:"required"`
 	// Reference name of a Git repository hosting the Stack file
 	ReferenceName string `example:"refs/heads/master"`
+	// Semver constraint of the tags to track instead of the reference name
+	TagConstraint string `example:"~1.4"`
 	// Use basic authentication to clone the Git repository
 	Authentication bool `example:"true"`
 	// Username used in basic authentication. Required when RepositoryAuthentication is true
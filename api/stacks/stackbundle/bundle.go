@@ -0,0 +1,228 @@
+package stackbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	gittypes "github.com/portainer/portainer/api/git/types"
+)
+
+const (
+	// Version is the version of the bundle format
+	Version = 1
+	// ManifestFileName is the name of the manifest inside the bundle archive
+	ManifestFileName = "stack.json"
+	// filesDirectory holds the stack files inside the bundle archive
+	filesDirectory = "files/"
+	// maxBundleSize is the maximum size of the uncompressed content of a bundle
+	maxBundleSize = 32 * 1024 * 1024
+)
+
+var ErrInvalidBundle = errors.New("invalid stack bundle")
+
+// Manifest describes a stack independently of the Portainer instance it was exported from
+type Manifest struct {
+	// Version of the bundle format
+	Version int `example:"1"`
+	// Name of the stack
+	Name string `example:"myStack"`
+	// Type of the stack, 1 for a Swarm stack and 2 for a Compose stack
+	Type portainer.StackType `example:"2"`
+	// Path to the stack file, relative to the files of the bundle
+	EntryPoint string `example:"docker-compose.yml"`
+	// Additional stack files, relative to the files of the bundle
+	AdditionalFiles []string `example:"[override.yml]"`
+	// Environment variables of the stack, the secret ones are left out when the bundle is exported without secrets
+	Env []portainer.Pair
+	// Deployment options of the stack
+	Option *portainer.StackOption
+	// Git repository of the stack, the stack is cloned from it on import
+	GitConfig *gittypes.RepoConfig
+	// GitOps update settings of the stack
+	AutoUpdate *portainer.AutoUpdateSettings
+	// Access control of the stack, the users and teams are referenced by name
+	ResourceControl *ResourceControl
+}
+
+// ResourceControl describes the access control of a stack with the users and teams referenced by name
+type ResourceControl struct {
+	Public             bool     `example:"false"`
+	AdministratorsOnly bool     `example:"false"`
+	Users              []string `example:"[alice]"`
+	Teams              []string `example:"[developers]"`
+}
+
+// Bundle is a stack along with its files
+type Bundle struct {
+	Manifest Manifest
+	// Content of the stack files, indexed by their path relative to the project of the stack
+	Files map[string][]byte
+}
+
+// Write writes the bundle as a tar.gz archive
+func Write(w io.Writer, bundle *Bundle) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifest, err := json.MarshalIndent(bundle.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(tarWriter, ManifestFileName, manifest); err != nil {
+		return err
+	}
+
+	filePaths := make([]string, 0, len(bundle.Files))
+	for filePath := range bundle.Files {
+		filePaths = append(filePaths, filePath)
+	}
+	slices.Sort(filePaths)
+
+	for _, filePath := range filePaths {
+		if err := writeFile(tarWriter, filesDirectory+filePath, bundle.Files[filePath]); err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+
+	return gzipWriter.Close()
+}
+
+func writeFile(tarWriter *tar.Writer, name string, content []byte) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: 0600,
+		Size: int64(len(content)),
+	}); err != nil {
+		return err
+	}
+
+	_, err := tarWriter.Write(content)
+
+	return err
+}
+
+// Read reads a bundle from a tar.gz archive and validates that it holds the files of the stack
+func Read(r io.Reader) (*Bundle, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+	defer gzipReader.Close()
+
+	bundle := &Bundle{Files: make(map[string][]byte)}
+
+	var manifest []byte
+	remaining := int64(maxBundleSize)
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Size > remaining {
+			return nil, fmt.Errorf("%w: the bundle exceeds %d bytes", ErrInvalidBundle, maxBundleSize)
+		}
+		remaining -= header.Size
+
+		content, err := io.ReadAll(io.LimitReader(tarReader, header.Size))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+		}
+
+		if header.Name == ManifestFileName {
+			manifest = content
+
+			continue
+		}
+
+		filePath, ok := strings.CutPrefix(header.Name, filesDirectory)
+		if !ok {
+			continue
+		}
+
+		if !isLocalPath(filePath) {
+			return nil, fmt.Errorf("%w: illegal file path %s", ErrInvalidBundle, header.Name)
+		}
+
+		bundle.Files[filePath] = content
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidBundle, ManifestFileName)
+	}
+
+	if err := json.Unmarshal(manifest, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+
+	if err := bundle.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+
+	return bundle, nil
+}
+
+func (bundle *Bundle) validate() error {
+	manifest := bundle.Manifest
+
+	if manifest.Version != Version {
+		return fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+
+	if manifest.Name == "" {
+		return errors.New("missing stack name")
+	}
+
+	if manifest.Type != portainer.DockerComposeStack && manifest.Type != portainer.DockerSwarmStack {
+		return errors.New("only Compose and Swarm stacks can be imported")
+	}
+
+	if manifest.EntryPoint == "" {
+		return errors.New("missing stack entry point")
+	}
+
+	// the files of the git stacks are cloned from their repository
+	if manifest.GitConfig != nil {
+		if manifest.GitConfig.URL == "" {
+			return errors.New("missing git repository URL")
+		}
+
+		return nil
+	}
+
+	for _, filePath := range append([]string{manifest.EntryPoint}, manifest.AdditionalFiles...) {
+		if _, ok := bundle.Files[filePath]; !ok {
+			return fmt.Errorf("missing stack file %s", filePath)
+		}
+	}
+
+	return nil
+}
+
+// isLocalPath returns true when the path stays inside the project of the stack
+func isLocalPath(filePath string) bool {
+	cleaned := path.Clean(filePath)
+
+	return filePath != "" && !path.IsAbs(cleaned) && cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
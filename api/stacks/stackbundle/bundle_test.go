@@ -0,0 +1,132 @@
+package stackbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	gittypes "github.com/portainer/portainer/api/git/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRead(t *testing.T) {
+	bundle := &Bundle{
+		Manifest: Manifest{
+			Version:         Version,
+			Name:            "app",
+			Type:            portainer.DockerComposeStack,
+			EntryPoint:      "docker-compose.yml",
+			AdditionalFiles: []string{"overrides/prod.yml"},
+			Env:             []portainer.Pair{{Name: "TAG", Value: "1.0"}},
+			ResourceControl: &ResourceControl{Users: []string{"alice"}, Teams: []string{"developers"}},
+		},
+		Files: map[string][]byte{
+			"docker-compose.yml": []byte("services:\n  web:\n    image: nginx"),
+			"overrides/prod.yml": []byte("services:\n  web:\n    restart: always"),
+		},
+	}
+
+	var archive bytes.Buffer
+	require.NoError(t, Write(&archive, bundle))
+
+	read, err := Read(&archive)
+	require.NoError(t, err)
+	require.Equal(t, bundle, read)
+}
+
+func TestReadGitBundleWithoutFiles(t *testing.T) {
+	bundle := &Bundle{
+		Manifest: Manifest{
+			Version:    Version,
+			Name:       "app",
+			Type:       portainer.DockerSwarmStack,
+			EntryPoint: "deploy/stack.yml",
+			GitConfig:  &gittypes.RepoConfig{URL: "https://github.com/portainer/app", ConfigFilePath: "deploy/stack.yml"},
+		},
+	}
+
+	var archive bytes.Buffer
+	require.NoError(t, Write(&archive, bundle))
+
+	read, err := Read(&archive)
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/portainer/app", read.Manifest.GitConfig.URL)
+	require.Empty(t, read.Files)
+}
+
+func TestReadInvalidBundles(t *testing.T) {
+	manifest := []byte(`{"Version": 1, "Name": "app", "Type": 2, "EntryPoint": "docker-compose.yml"}`)
+
+	tests := []struct {
+		name        string
+		files       map[string][]byte
+		expectedErr string
+	}{
+		{
+			name:        "missing manifest",
+			files:       map[string][]byte{"files/docker-compose.yml": {}},
+			expectedErr: "missing stack.json",
+		},
+		{
+			name:        "missing entry point",
+			files:       map[string][]byte{ManifestFileName: manifest},
+			expectedErr: "missing stack file docker-compose.yml",
+		},
+		{
+			name: "path traversal",
+			files: map[string][]byte{
+				ManifestFileName:           manifest,
+				"files/docker-compose.yml": {},
+				"files/../../etc/passwd":   {},
+			},
+			expectedErr: "illegal file path",
+		},
+		{
+			name: "kubernetes stack",
+			files: map[string][]byte{
+				ManifestFileName:           []byte(`{"Version": 1, "Name": "app", "Type": 3, "EntryPoint": "app.yml"}`),
+				"files/docker-compose.yml": {},
+			},
+			expectedErr: "only Compose and Swarm stacks can be imported",
+		},
+		{
+			name: "unsupported version",
+			files: map[string][]byte{
+				ManifestFileName: []byte(`{"Version": 2, "Name": "app", "Type": 2, "EntryPoint": "docker-compose.yml"}`),
+			},
+			expectedErr: "unsupported bundle version 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(createArchive(t, tt.files))
+			require.ErrorIs(t, err, ErrInvalidBundle)
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+
+	_, err := Read(bytes.NewReader([]byte("not an archive")))
+	require.ErrorIs(t, err, ErrInvalidBundle)
+}
+
+func createArchive(t *testing.T, files map[string][]byte) *bytes.Buffer {
+	var archive bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for name, content := range files {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tarWriter.Write(content)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	return &archive
+}
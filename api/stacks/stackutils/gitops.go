@@ -15,8 +15,9 @@ var (
 )
 
 // DownloadGitRepository downloads the target git repository on the disk
+// The reference of a config tracking a tag constraint is first pointed to the highest matching tag
 // The first return value represents the commit hash of the downloaded git repository
-func DownloadGitRepository(config gittypes.RepoConfig, gitService portainer.GitService, getProjectPath func() string) (string, error) {
+func DownloadGitRepository(config *gittypes.RepoConfig, gitService portainer.GitService, getProjectPath func() string) (string, error) {
 	username := ""
 	password := ""
 	if config.Authentication != nil {
@@ -24,6 +25,10 @@ func DownloadGitRepository(config gittypes.RepoConfig, gitService portainer.GitS
 		password = config.Authentication.Password
 	}
 
+	if _, err := git.ResolveTagConstraint(gitService, config, username, password); err != nil {
+		return "", fmt.Errorf("unable to resolve the tag constraint: %w", err)
+	}
+
 	projectPath := getProjectPath()
 	err := gitService.CloneRepository(projectPath, config.URL, config.ReferenceName, username, password, config.TLSSkipVerify)
 	if err != nil {
@@ -43,3 +48,13 @@ func DownloadGitRepository(config gittypes.RepoConfig, gitService portainer.GitS
 	}
 	return commitID, nil
 }
+
+// KeepWebhookSecret returns the new auto update settings, they keep the saved webhook secret when the secret is left
+// empty for the same provider since the secret is never sent back to the clients
+func KeepWebhookSecret(current, updated *portainer.AutoUpdateSettings) *portainer.AutoUpdateSettings {
+	if current != nil && updated != nil && updated.WebhookSecret == "" && updated.WebhookProvider == current.WebhookProvider {
+		updated.WebhookSecret = current.WebhookSecret
+	}
+
+	return updated
+}
@@ -1,6 +1,10 @@
 package stackutils
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/docker/cli/cli/compose/loader"
 	"github.com/docker/cli/cli/compose/types"
 	"github.com/pkg/errors"
@@ -78,3 +82,53 @@ func ValidateStackFiles(stack *portainer.Stack, securitySettings *portainer.Endp
 	}
 	return nil
 }
+
+// ValidateComposeSyntaxVersion returns an error when the stack file is not valid YAML or when it declares a version
+// of the compose syntax above maxVersion, the files without version follow the compose specification
+func ValidateComposeSyntaxVersion(stackFileContent []byte, maxVersion string) error {
+	composeConfigYAML, err := loader.ParseYAML(stackFileContent)
+	if err != nil {
+		return err
+	}
+
+	version, ok := composeConfigYAML["version"]
+	if !ok {
+		return nil
+	}
+
+	fileVersion, err := parseComposeSyntaxVersion(fmt.Sprint(version))
+	if err != nil {
+		return err
+	}
+
+	supportedVersion, err := parseComposeSyntaxVersion(maxVersion)
+	if err != nil {
+		return err
+	}
+
+	if fileVersion[0] > supportedVersion[0] || (fileVersion[0] == supportedVersion[0] && fileVersion[1] > supportedVersion[1]) {
+		return errors.Errorf("the compose syntax version %v is not supported, the maximum supported version is %s", version, maxVersion)
+	}
+
+	return nil
+}
+
+// parseComposeSyntaxVersion returns the major and minor numbers of a compose syntax version such as 3 or 3.9
+func parseComposeSyntaxVersion(version string) ([2]int, error) {
+	var parsed [2]int
+
+	major, minor, hasMinor := strings.Cut(version, ".")
+
+	var err error
+	if parsed[0], err = strconv.Atoi(major); err != nil {
+		return parsed, errors.Errorf("invalid compose syntax version %s", version)
+	}
+
+	if hasMinor {
+		if parsed[1], err = strconv.Atoi(minor); err != nil {
+			return parsed, errors.Errorf("invalid compose syntax version %s", version)
+		}
+	}
+
+	return parsed, nil
+}
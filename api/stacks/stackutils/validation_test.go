@@ -0,0 +1,44 @@
+package stackutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ValidateComposeSyntaxVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr string
+	}{
+		{name: "compose specification", content: "services:\n  web:\n    image: nginx"},
+		{name: "supported version", content: "version: \"3.9\"\nservices:\n  web:\n    image: nginx"},
+		{name: "major version only", content: "version: '2'\nservices:\n  web:\n    image: nginx"},
+		{name: "unquoted version", content: "version: 3.4\nservices:\n  web:\n    image: nginx"},
+		{
+			name:        "unsupported version",
+			content:     "version: \"4.0\"\nservices:\n  web:\n    image: nginx",
+			expectedErr: "the compose syntax version 4.0 is not supported, the maximum supported version is 3.9",
+		},
+		{
+			name:        "invalid version",
+			content:     "version: latest\nservices:\n  web:\n    image: nginx",
+			expectedErr: "invalid compose syntax version latest",
+		},
+		{name: "invalid YAML", content: "services: [", expectedErr: "yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateComposeSyntaxVersion([]byte(tt.content), "3.9")
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
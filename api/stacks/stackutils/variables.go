@@ -0,0 +1,229 @@
+package stackutils
+
+import (
+	"fmt"
+	"slices"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MaskedVariableValue replaces the values of the secret variables in the API responses
+const MaskedVariableValue = "********"
+
+// ResolvedVariable represents a variable fed to the deployment of a stack along with the origin of its value
+type ResolvedVariable struct {
+	Name   string `json:"Name" example:"DB_HOST"`
+	Value  string `json:"Value" example:"db.internal"`
+	Secret bool   `json:"Secret" example:"false"`
+	// Origin of the value: the environment labels, a variable set or the stack itself
+	Source string `json:"Source" example:"variable set production"`
+}
+
+// StackVariableSets returns the variable sets referenced by the stack that apply to its environment, ordered from the
+// broadest to the narrowest scope: environment group, environment then team. The variable sets that no longer exist
+// or that are scoped to another environment or group are skipped.
+func StackVariableSets(tx dataservices.DataStoreTx, stack *portainer.Stack, endpoint *portainer.Endpoint) []portainer.VariableSet {
+	variableSets := make([]portainer.VariableSet, 0, len(stack.VariableSetIDs))
+
+	for _, variableSetID := range stack.VariableSetIDs {
+		variableSet, err := tx.VariableSet().Read(variableSetID)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Int("stack_id", int(stack.ID)).
+				Int("variable_set_id", int(variableSetID)).
+				Msg("unable to retrieve the variable set, its variables won't be exposed to the stack")
+
+			continue
+		}
+
+		if !VariableSetAppliesTo(variableSet, endpoint) {
+			log.Warn().
+				Int("stack_id", int(stack.ID)).
+				Int("variable_set_id", int(variableSetID)).
+				Msg("the variable set is scoped to another environment, its variables won't be exposed to the stack")
+
+			continue
+		}
+
+		variableSets = append(variableSets, *variableSet)
+	}
+
+	// the sort is stable so that the sets of a same scope keep the order in which the stack references them
+	slices.SortStableFunc(variableSets, func(a, b portainer.VariableSet) int {
+		return int(a.ScopeType) - int(b.ScopeType)
+	})
+
+	return variableSets
+}
+
+// VariableSetAppliesTo returns true when the variable set can be inherited by the stacks of the environment, the
+// team variable sets apply to any environment
+func VariableSetAppliesTo(variableSet *portainer.VariableSet, endpoint *portainer.Endpoint) bool {
+	switch variableSet.ScopeType {
+	case portainer.EndpointGroupVariableSetScope:
+		return endpoint != nil && int(endpoint.GroupID) == variableSet.ScopeID
+	case portainer.EndpointVariableSetScope:
+		return endpoint != nil && int(endpoint.ID) == variableSet.ScopeID
+	case portainer.TeamVariableSetScope:
+		return true
+	}
+
+	return false
+}
+
+// VariableSetsEnv returns the variables inherited by the stack from its variable sets as env vars, the variables of
+// the narrowest scopes come last so that they override the others
+func VariableSetsEnv(tx dataservices.DataStoreTx, stack *portainer.Stack, endpoint *portainer.Endpoint) []portainer.Pair {
+	var env []portainer.Pair
+
+	for _, variableSet := range StackVariableSets(tx, stack, endpoint) {
+		for _, variable := range variableSet.Variables {
+			env = append(env, portainer.Pair{Name: variable.Name, Value: variable.Value})
+		}
+	}
+
+	return env
+}
+
+// ResolveStackVariables returns the final variables of the stack, in the order they are fed to the deployment: the
+// environment labels are overridden by the variable sets, which are overridden by the env vars of the stack. The
+// values of the secret variables are masked.
+func ResolveStackVariables(tx dataservices.DataStoreTx, stack *portainer.Stack, endpoint *portainer.Endpoint, labelsEnv []portainer.Pair) []ResolvedVariable {
+	var variables []ResolvedVariable
+
+	set := func(variable ResolvedVariable) {
+		index := slices.IndexFunc(variables, func(v ResolvedVariable) bool { return v.Name == variable.Name })
+		if index == -1 {
+			variables = append(variables, variable)
+
+			return
+		}
+
+		// an override keeps the variable secret
+		variable.Secret = variable.Secret || variables[index].Secret
+		variables[index] = variable
+	}
+
+	for _, label := range labelsEnv {
+		set(ResolvedVariable{Name: label.Name, Value: label.Value, Source: "environment labels"})
+	}
+
+	for _, variableSet := range StackVariableSets(tx, stack, endpoint) {
+		for _, variable := range variableSet.Variables {
+			set(ResolvedVariable{
+				Name:   variable.Name,
+				Value:  variable.Value,
+				Secret: variable.Secret,
+				Source: fmt.Sprintf("variable set %s", variableSet.Name),
+			})
+		}
+	}
+
+	for _, env := range stack.Env {
+		set(ResolvedVariable{Name: env.Name, Value: env.Value, Source: "stack"})
+	}
+
+	for i := range variables {
+		if variables[i].Secret {
+			variables[i].Value = MaskedVariableValue
+		}
+	}
+
+	return variables
+}
+
+// MaskStackEnv masks the values of the env vars of the stack that override a secret variable of one of its variable
+// sets
+func MaskStackEnv(tx dataservices.DataStoreTx, stack *portainer.Stack) {
+	if len(stack.VariableSetIDs) == 0 || len(stack.Env) == 0 {
+		return
+	}
+
+	secretNames := SecretVariableNames(tx, stack)
+
+	env := make([]portainer.Pair, len(stack.Env))
+	for i, pair := range stack.Env {
+		if secretNames[pair.Name] {
+			pair.Value = MaskedVariableValue
+		}
+
+		env[i] = pair
+	}
+
+	stack.Env = env
+}
+
+// SecretVariableNames returns the names of the secret variables of the variable sets of the stack
+func SecretVariableNames(tx dataservices.DataStoreTx, stack *portainer.Stack) map[string]bool {
+	secretNames := make(map[string]bool)
+
+	for _, variableSetID := range stack.VariableSetIDs {
+		variableSet, err := tx.VariableSet().Read(variableSetID)
+		if err != nil {
+			continue
+		}
+
+		for _, variable := range variableSet.Variables {
+			if variable.Secret {
+				secretNames[variable.Name] = true
+			}
+		}
+	}
+
+	return secretNames
+}
+
+// RestoreMaskedEnv returns the env vars with the masked values replaced by the previous values of the same variables,
+// so that the masked values sent back by the clients do not overwrite the secrets
+func RestoreMaskedEnv(previous []portainer.Pair, env []portainer.Pair) []portainer.Pair {
+	restored := make([]portainer.Pair, 0, len(env))
+
+	for _, pair := range env {
+		if pair.Value == MaskedVariableValue {
+			if index := slices.IndexFunc(previous, func(p portainer.Pair) bool { return p.Name == pair.Name }); index != -1 {
+				pair.Value = previous[index].Value
+			}
+		}
+
+		restored = append(restored, pair)
+	}
+
+	return restored
+}
+
+// RestoreMaskedVariables returns the variables with the masked values of the secret variables replaced by their
+// previous values
+func RestoreMaskedVariables(previous []portainer.StackVariable, variables []portainer.StackVariable) []portainer.StackVariable {
+	restored := make([]portainer.StackVariable, 0, len(variables))
+
+	for _, variable := range variables {
+		if variable.Secret && variable.Value == MaskedVariableValue {
+			if index := slices.IndexFunc(previous, func(v portainer.StackVariable) bool { return v.Name == variable.Name }); index != -1 {
+				variable.Value = previous[index].Value
+			}
+		}
+
+		restored = append(restored, variable)
+	}
+
+	return restored
+}
+
+// MaskVariables returns the variables with the values of the secret variables masked
+func MaskVariables(variables []portainer.StackVariable) []portainer.StackVariable {
+	masked := make([]portainer.StackVariable, len(variables))
+
+	for i, variable := range variables {
+		if variable.Secret {
+			variable.Value = MaskedVariableValue
+		}
+
+		masked[i] = variable
+	}
+
+	return masked
+}
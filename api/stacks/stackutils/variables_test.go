@@ -0,0 +1,93 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResolveStackVariables(t *testing.T) {
+	store := testhelpers.NewDatastore(testhelpers.WithVariableSets([]portainer.VariableSet{
+		{
+			ID: 1, Name: "team", ScopeType: portainer.TeamVariableSetScope, ScopeID: 1,
+			Variables: []portainer.StackVariable{{Name: "LOG_LEVEL", Value: "debug"}},
+		},
+		{
+			ID: 2, Name: "production", ScopeType: portainer.EndpointGroupVariableSetScope, ScopeID: 2,
+			Variables: []portainer.StackVariable{
+				{Name: "DB_HOST", Value: "db.internal"},
+				{Name: "DB_PASSWORD", Value: "s3cr3t", Secret: true},
+				{Name: "LOG_LEVEL", Value: "info"},
+			},
+		},
+		{
+			ID: 3, Name: "edge", ScopeType: portainer.EndpointVariableSetScope, ScopeID: 5,
+			Variables: []portainer.StackVariable{{Name: "DB_HOST", Value: "db.edge"}},
+		},
+		{
+			ID: 4, Name: "node", ScopeType: portainer.EndpointVariableSetScope, ScopeID: 1,
+			Variables: []portainer.StackVariable{{Name: "DB_HOST", Value: "db.node"}},
+		},
+	}))
+
+	endpoint := &portainer.Endpoint{ID: 1, GroupID: 2}
+	stack := &portainer.Stack{
+		ID:             1,
+		VariableSetIDs: []portainer.VariableSetID{1, 3, 4, 2, 9},
+		Env:            []portainer.Pair{{Name: "DB_PASSWORD", Value: "override"}, {Name: "PORT", Value: "80"}},
+	}
+
+	// the sets are ordered by scope, the set scoped to another environment and the missing set are skipped
+	variableSets := StackVariableSets(store, stack, endpoint)
+	require.Len(t, variableSets, 3)
+	require.Equal(t, "production", variableSets[0].Name)
+	require.Equal(t, "node", variableSets[1].Name)
+	require.Equal(t, "team", variableSets[2].Name)
+
+	require.Equal(t, []portainer.Pair{
+		{Name: "DB_HOST", Value: "db.internal"},
+		{Name: "DB_PASSWORD", Value: "s3cr3t"},
+		{Name: "LOG_LEVEL", Value: "info"},
+		{Name: "DB_HOST", Value: "db.node"},
+		{Name: "LOG_LEVEL", Value: "debug"},
+	}, VariableSetsEnv(store, stack, endpoint))
+
+	variables := ResolveStackVariables(store, stack, endpoint, []portainer.Pair{{Name: "REGION", Value: "eu"}, {Name: "DB_HOST", Value: "label"}})
+	require.Equal(t, []ResolvedVariable{
+		{Name: "REGION", Value: "eu", Source: "environment labels"},
+		{Name: "DB_HOST", Value: "db.node", Source: "variable set node"},
+		{Name: "DB_PASSWORD", Value: MaskedVariableValue, Secret: true, Source: "stack"},
+		{Name: "LOG_LEVEL", Value: "debug", Source: "variable set team"},
+		{Name: "PORT", Value: "80", Source: "stack"},
+	}, variables)
+
+	MaskStackEnv(store, stack)
+	require.Equal(t, []portainer.Pair{{Name: "DB_PASSWORD", Value: MaskedVariableValue}, {Name: "PORT", Value: "80"}}, stack.Env)
+}
+
+func Test_RestoreMaskedEnv(t *testing.T) {
+	previous := []portainer.Pair{{Name: "DB_PASSWORD", Value: "s3cr3t"}, {Name: "PORT", Value: "80"}}
+
+	env := RestoreMaskedEnv(previous, []portainer.Pair{
+		{Name: "DB_PASSWORD", Value: MaskedVariableValue},
+		{Name: "PORT", Value: "8080"},
+		{Name: "TOKEN", Value: MaskedVariableValue},
+	})
+
+	require.Equal(t, []portainer.Pair{
+		{Name: "DB_PASSWORD", Value: "s3cr3t"},
+		{Name: "PORT", Value: "8080"},
+		{Name: "TOKEN", Value: MaskedVariableValue},
+	}, env)
+
+	variables := RestoreMaskedVariables(
+		[]portainer.StackVariable{{Name: "DB_PASSWORD", Value: "s3cr3t", Secret: true}},
+		[]portainer.StackVariable{{Name: "DB_PASSWORD", Value: MaskedVariableValue, Secret: true}, {Name: "PORT", Value: MaskedVariableValue}},
+	)
+
+	require.Equal(t, "s3cr3t", variables[0].Value)
+	require.Equal(t, MaskedVariableValue, variables[1].Value)
+}
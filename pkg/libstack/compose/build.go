@@ -0,0 +1,108 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/portainer/portainer/pkg/libstack"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/rs/zerolog/log"
+)
+
+const buildKitRequiredNotice = "BuildKit is required by the build of the stack, its output is only available in the Portainer logs\n"
+
+// classicBuilderCli makes compose build the images with the builder of the Docker daemon, whose output is written to
+// the streams of the client unlike the BuildKit output
+type classicBuilderCli struct {
+	command.Cli
+}
+
+func (classicBuilderCli) BuildKitEnabled() (bool, error) {
+	return false, nil
+}
+
+// build builds the images of the services with a build section, the build context is resolved from the project
+// directory and the registries of the options are used to pull the base images
+func (c *ComposeDeployer) build(ctx context.Context, composeService api.Service, project *types.Project, options libstack.DeployOptions) error {
+	addBuildArgs(project, options.BuildArgs)
+
+	var buildOptions api.BuildOptions
+
+	if options.BuildOutput == nil || !hasBuildSection(project) {
+		return composeService.Build(ctx, project, buildOptions)
+	}
+
+	buildOptions.Progress = "plain"
+
+	return withCliOutput(ctx, options.Options, io.MultiWriter(log.Logger, options.BuildOutput), func(ctx context.Context, cli *command.DockerCli) error {
+		var buildCli command.Cli = classicBuilderCli{Cli: cli}
+
+		if requiresBuildKit(project) {
+			buildCli = cli
+
+			if _, err := fmt.Fprint(options.BuildOutput, buildKitRequiredNotice); err != nil {
+				return err
+			}
+		}
+
+		return c.createComposeServiceFn(buildCli).Build(ctx, project, buildOptions)
+	})
+}
+
+// addBuildArgs adds the build args to the build sections of the services, the args defined by the build sections
+// take precedence
+func addBuildArgs(project *types.Project, buildArgs []string) {
+	args := types.NewMappingWithEquals(buildArgs)
+
+	for name, service := range project.Services {
+		if service.Build == nil {
+			continue
+		}
+
+		if service.Build.Args == nil {
+			service.Build.Args = make(types.MappingWithEquals)
+		}
+
+		for key, value := range args {
+			if _, ok := service.Build.Args[key]; !ok {
+				service.Build.Args[key] = value
+			}
+		}
+
+		project.Services[name] = service
+	}
+}
+
+func hasBuildSection(project *types.Project) bool {
+	for _, service := range project.Services {
+		if service.Build != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requiresBuildKit returns true when a service relies on a build feature that the builder of the Docker daemon
+// does not support
+func requiresBuildKit(project *types.Project) bool {
+	for _, service := range project.Services {
+		if service.Build == nil {
+			continue
+		}
+
+		if len(service.Build.Platforms) > 1 ||
+			service.Build.Privileged ||
+			len(service.Build.AdditionalContexts) > 0 ||
+			len(service.Build.SSH) > 0 ||
+			len(service.Build.Secrets) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
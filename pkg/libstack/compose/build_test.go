@@ -0,0 +1,117 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/portainer/portainer/pkg/libstack"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/stretchr/testify/require"
+)
+
+type buildRecordingComposeService struct {
+	api.Service
+	clis     []command.Cli
+	builds   []api.BuildOptions
+	project  *types.Project
+	buildKit bool
+}
+
+func (s *buildRecordingComposeService) Build(ctx context.Context, project *types.Project, options api.BuildOptions) error {
+	s.builds = append(s.builds, options)
+	s.project = project
+
+	// the builds whose output is captured run with a dedicated client
+	if len(s.clis) > 1 {
+		cli := s.clis[len(s.clis)-1]
+		s.buildKit, _ = cli.BuildKitEnabled()
+		fmt.Fprintln(cli.Out(), "Step 1/2 : FROM alpine")
+	}
+
+	return nil
+}
+
+func (s *buildRecordingComposeService) Up(ctx context.Context, project *types.Project, options api.UpOptions) error {
+	return nil
+}
+
+func Test_DeployBuild(t *testing.T) {
+	tests := []struct {
+		name               string
+		composeFileContent string
+		expectedArgs       types.MappingWithEquals
+		expectedOutput     string
+		expectedBuildKit   bool
+	}{
+		{
+			name: "no build section",
+			composeFileContent: `services:
+  web:
+    image: nginx:alpine`,
+		},
+		{
+			name: "build section",
+			composeFileContent: `services:
+  web:
+    build:
+      context: .
+      args:
+        VERSION: "2.0"`,
+			expectedArgs:   types.MappingWithEquals{"VERSION": ptr("2.0"), "REGION": ptr("eu")},
+			expectedOutput: "Step 1/2 : FROM alpine\n",
+		},
+		{
+			name: "build section relying on BuildKit",
+			composeFileContent: `services:
+  web:
+    build:
+      context: .
+      ssh:
+        - default`,
+			expectedArgs:     types.MappingWithEquals{"VERSION": ptr("1.0"), "REGION": ptr("eu")},
+			expectedOutput:   buildKitRequiredNotice + "Step 1/2 : FROM alpine\n",
+			expectedBuildKit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &buildRecordingComposeService{}
+
+			w := ComposeDeployer{
+				createComposeServiceFn: func(cli command.Cli) api.Service {
+					service.clis = append(service.clis, cli)
+
+					return service
+				},
+			}
+
+			dir := t.TempDir()
+			filePaths := []string{createFile(t, dir, "docker-compose.yml", tt.composeFileContent)}
+
+			var output bytes.Buffer
+			err := w.Deploy(context.Background(), filePaths, libstack.DeployOptions{
+				Options:     libstack.Options{ProjectName: "deploy_build_test"},
+				BuildArgs:   []string{"VERSION=1.0", "REGION=eu"},
+				BuildOutput: &output,
+			})
+			require.NoError(t, err)
+
+			require.Len(t, service.builds, 1)
+			if tt.expectedArgs != nil {
+				require.Equal(t, tt.expectedArgs, service.project.Services["web"].Build.Args)
+			}
+			require.Equal(t, tt.expectedOutput, output.String())
+			require.Equal(t, tt.expectedBuildKit, service.buildKit)
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"maps"
 	"os"
 	"path/filepath"
@@ -45,10 +46,20 @@ func withCli(
 	ctx context.Context,
 	options libstack.Options,
 	cliFn func(context.Context, *command.DockerCli) error,
+) error {
+	return withCliOutput(ctx, options, log.Logger, cliFn)
+}
+
+// withCliOutput is like withCli but the output of the Docker client is written to out
+func withCliOutput(
+	ctx context.Context,
+	options libstack.Options,
+	out io.Writer,
+	cliFn func(context.Context, *command.DockerCli) error,
 ) error {
 	ctx = context.Background()
 
-	cli, err := command.NewDockerCli(command.WithCombinedStreams(log.Logger))
+	cli, err := command.NewDockerCli(command.WithCombinedStreams(out))
 	if err != nil {
 		return fmt.Errorf("unable to create a Docker client: %w", err)
 	}
@@ -136,7 +147,7 @@ func (c *ComposeDeployer) Deploy(ctx context.Context, filePaths []string, option
 			opts.Start.OnExit = api.CascadeStop
 		}
 
-		if err := composeService.Build(ctx, project, api.BuildOptions{}); err != nil {
+		if err := c.build(ctx, composeService, project, options); err != nil {
 			return fmt.Errorf("compose build operation failed: %w", err)
 		}
 
@@ -0,0 +1,149 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/portainer/portainer/pkg/libstack"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/rs/zerolog/log"
+)
+
+// withService runs serviceFn with the project of the stack files once it is checked that it defines the service
+func (c *ComposeDeployer) withService(
+	ctx context.Context,
+	filePaths []string,
+	serviceName string,
+	options libstack.Options,
+	serviceFn func(api.Service, *types.Project) error,
+) error {
+	return c.withComposeService(ctx, filePaths, options, func(composeService api.Service, project *types.Project) error {
+		if project == nil {
+			return fmt.Errorf("%w: %s", libstack.ErrServiceNotFound, serviceName)
+		}
+
+		if _, err := project.GetService(serviceName); err != nil {
+			return fmt.Errorf("%w: %s", libstack.ErrServiceNotFound, serviceName)
+		}
+
+		addServiceLabels(project, false, 0)
+
+		return serviceFn(composeService, project)
+	})
+}
+
+// StartService starts the existing containers of a service
+func (c *ComposeDeployer) StartService(ctx context.Context, filePaths []string, serviceName string, options libstack.Options) error {
+	if err := c.withService(ctx, filePaths, serviceName, options, func(composeService api.Service, project *types.Project) error {
+		return composeService.Start(ctx, project.Name, api.StartOptions{
+			Project:  project,
+			Services: []string{serviceName},
+		})
+	}); err != nil {
+		return fmt.Errorf("compose start operation failed: %w", err)
+	}
+
+	log.Info().Str("service", serviceName).Msg("Stack service start successful")
+
+	return nil
+}
+
+// StopService stops the containers of a service without removing them
+func (c *ComposeDeployer) StopService(ctx context.Context, filePaths []string, serviceName string, options libstack.Options) error {
+	if err := c.withService(ctx, filePaths, serviceName, options, func(composeService api.Service, project *types.Project) error {
+		return composeService.Stop(ctx, project.Name, api.StopOptions{
+			Project:  project,
+			Services: []string{serviceName},
+		})
+	}); err != nil {
+		return fmt.Errorf("compose stop operation failed: %w", err)
+	}
+
+	log.Info().Str("service", serviceName).Msg("Stack service stop successful")
+
+	return nil
+}
+
+// RestartService restarts the containers of a service, its dependencies are left untouched
+func (c *ComposeDeployer) RestartService(ctx context.Context, filePaths []string, serviceName string, options libstack.Options) error {
+	if err := c.withService(ctx, filePaths, serviceName, options, func(composeService api.Service, project *types.Project) error {
+		return composeService.Restart(ctx, project.Name, api.RestartOptions{
+			Project:  project,
+			Services: []string{serviceName},
+			NoDeps:   true,
+		})
+	}); err != nil {
+		return fmt.Errorf("compose restart operation failed: %w", err)
+	}
+
+	log.Info().Str("service", serviceName).Msg("Stack service restart successful")
+
+	return nil
+}
+
+// ScaleService creates or removes containers so that the service runs the given number of replicas
+func (c *ComposeDeployer) ScaleService(ctx context.Context, filePaths []string, serviceName string, replicas int, options libstack.Options) error {
+	if replicas < 0 {
+		return fmt.Errorf("invalid number of replicas: %d", replicas)
+	}
+
+	if err := c.withService(ctx, filePaths, serviceName, options, func(composeService api.Service, project *types.Project) error {
+		service := project.Services[serviceName]
+		service.SetScale(replicas)
+		project.Services[serviceName] = service
+
+		return composeService.Scale(ctx, project, api.ScaleOptions{Services: []string{serviceName}})
+	}); err != nil {
+		return fmt.Errorf("compose scale operation failed: %w", err)
+	}
+
+	log.Info().Str("service", serviceName).Int("replicas", replicas).Msg("Stack service scale successful")
+
+	return nil
+}
+
+// RecreateService recreates the containers of a service, its dependencies are left untouched
+func (c *ComposeDeployer) RecreateService(ctx context.Context, filePaths []string, serviceName string, options libstack.RecreateServiceOptions) error {
+	if err := c.withService(ctx, filePaths, serviceName, options.Options, func(composeService api.Service, project *types.Project) error {
+		services := []string{serviceName}
+
+		project, err := project.WithSelectedServices(services)
+		if err != nil {
+			return err
+		}
+
+		project = project.WithoutUnnecessaryResources()
+
+		if options.PullImage {
+			pullProject, err := project.WithSelectedServices(services, types.IgnoreDependencies)
+			if err != nil {
+				return err
+			}
+
+			if err := composeService.Pull(ctx, pullProject, api.PullOptions{}); err != nil {
+				return fmt.Errorf("compose pull operation failed: %w", err)
+			}
+		}
+
+		return composeService.Up(ctx, project, api.UpOptions{
+			Create: api.CreateOptions{
+				Services:             services,
+				Recreate:             api.RecreateForce,
+				RecreateDependencies: api.RecreateNever,
+				Inherit:              true,
+			},
+			Start: api.StartOptions{
+				Project:  project,
+				Services: services,
+			},
+		})
+	}); err != nil {
+		return fmt.Errorf("compose recreate operation failed: %w", err)
+	}
+
+	log.Info().Str("service", serviceName).Msg("Stack service recreate successful")
+
+	return nil
+}
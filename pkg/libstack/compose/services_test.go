@@ -0,0 +1,111 @@
+package compose
+
+import (
+	"context"
+	"testing"
+
+	"github.com/portainer/portainer/pkg/libstack"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingComposeService struct {
+	api.Service
+	calls   []string
+	project *types.Project
+	up      api.UpOptions
+	stop    api.StopOptions
+	restart api.RestartOptions
+}
+
+func (s *recordingComposeService) Start(ctx context.Context, projectName string, options api.StartOptions) error {
+	s.calls = append(s.calls, "start")
+	s.project = options.Project
+
+	return nil
+}
+
+func (s *recordingComposeService) Stop(ctx context.Context, projectName string, options api.StopOptions) error {
+	s.calls = append(s.calls, "stop")
+	s.stop = options
+
+	return nil
+}
+
+func (s *recordingComposeService) Restart(ctx context.Context, projectName string, options api.RestartOptions) error {
+	s.calls = append(s.calls, "restart")
+	s.restart = options
+
+	return nil
+}
+
+func (s *recordingComposeService) Scale(ctx context.Context, project *types.Project, options api.ScaleOptions) error {
+	s.calls = append(s.calls, "scale")
+	s.project = project
+
+	return nil
+}
+
+func (s *recordingComposeService) Pull(ctx context.Context, project *types.Project, options api.PullOptions) error {
+	s.calls = append(s.calls, "pull")
+	s.project = project
+
+	return nil
+}
+
+func (s *recordingComposeService) Up(ctx context.Context, project *types.Project, options api.UpOptions) error {
+	s.calls = append(s.calls, "up")
+	s.up = options
+
+	return nil
+}
+
+func Test_ServiceOperations(t *testing.T) {
+	const composeFileContent = `services:
+  web:
+    image: nginx:alpine
+    depends_on:
+      - db
+  db:
+    image: postgres:alpine`
+
+	service := &recordingComposeService{}
+
+	w := ComposeDeployer{
+		createComposeServiceFn: func(command.Cli) api.Service { return service },
+	}
+
+	filePaths := []string{createFile(t, t.TempDir(), "docker-compose.yml", composeFileContent)}
+	options := libstack.Options{ProjectName: "service_operations_test"}
+
+	ctx := context.Background()
+
+	require.NoError(t, w.StartService(ctx, filePaths, "web", options))
+	require.Equal(t, "service_operations_test", service.project.Name)
+
+	require.NoError(t, w.StopService(ctx, filePaths, "web", options))
+	require.Equal(t, []string{"web"}, service.stop.Services)
+
+	require.NoError(t, w.RestartService(ctx, filePaths, "web", options))
+	require.True(t, service.restart.NoDeps)
+
+	require.NoError(t, w.ScaleService(ctx, filePaths, "web", 3, options))
+	require.Equal(t, 3, *service.project.Services["web"].Scale)
+	require.Equal(t, "web", service.project.Services["web"].CustomLabels[api.ServiceLabel])
+
+	require.Error(t, w.ScaleService(ctx, filePaths, "web", -1, options))
+
+	require.NoError(t, w.RecreateService(ctx, filePaths, "web", libstack.RecreateServiceOptions{Options: options, PullImage: true}))
+	require.Len(t, service.project.Services, 1, "only the image of the service is pulled")
+	require.Equal(t, []string{"web"}, service.up.Create.Services)
+	require.Equal(t, api.RecreateForce, service.up.Create.Recreate)
+	require.Equal(t, api.RecreateNever, service.up.Create.RecreateDependencies)
+
+	require.Equal(t, []string{"start", "stop", "restart", "scale", "pull", "up"}, service.calls)
+
+	err := w.StartService(ctx, filePaths, "cache", options)
+	require.ErrorIs(t, err, libstack.ErrServiceNotFound)
+}
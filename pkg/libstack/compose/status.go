@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/portainer/portainer/pkg/libstack"
@@ -37,6 +39,8 @@ type service struct {
 	Health     string
 	ExitCode   int
 	Publishers []publisher
+	// host is the Docker host running the container, the default host is used when empty
+	host string
 }
 
 // docker container state can be one of "created", "running", "paused", "restarting", "removing", "exited", or "dead"
@@ -92,7 +96,7 @@ func getServiceStatus(ctx context.Context, service service) (libstack.Status, st
 func getContainerLogsTail(ctx context.Context, service service) (string, error) {
 	var combinedOutput bytes.Buffer
 
-	if err := withCli(ctx, libstack.Options{ProjectName: service.Project}, func(ctx context.Context, cli *command.DockerCli) error {
+	if err := withCli(ctx, libstack.Options{ProjectName: service.Project, Host: service.host}, func(ctx context.Context, cli *command.DockerCli) error {
 		out, err := cli.Client().ContainerLogs(ctx, service.Name, container.LogsOptions{
 			ShowStdout: true,
 			ShowStderr: true,
@@ -165,7 +169,13 @@ func aggregateStatuses(ctx context.Context, services []service) (libstack.Status
 }
 
 func (c *ComposeDeployer) WaitForStatus(ctx context.Context, name string, status libstack.Status) libstack.WaitResult {
+	return c.WaitForStatusWithOptions(ctx, status, libstack.WaitOptions{Options: libstack.Options{ProjectName: name}})
+}
+
+// WaitForStatusWithOptions waits for the containers of the stack to reach the given status on the host of the options
+func (c *ComposeDeployer) WaitForStatusWithOptions(ctx context.Context, status libstack.Status, options libstack.WaitOptions) libstack.WaitResult {
 	waitResult := libstack.WaitResult{Status: status}
+	name := options.ProjectName
 
 	for {
 		if ctx.Err() != nil {
@@ -178,7 +188,7 @@ func (c *ComposeDeployer) WaitForStatus(ctx context.Context, name string, status
 
 		var containerSummaries []api.ContainerSummary
 
-		if err := c.withComposeService(ctx, nil, libstack.Options{ProjectName: name}, func(composeService api.Service, project *types.Project) error {
+		if err := c.withComposeService(ctx, nil, libstack.Options{ProjectName: name, Host: options.Host}, func(composeService api.Service, project *types.Project) error {
 			var err error
 
 			psCtx, cancelFunc := context.WithTimeout(context.Background(), time.Minute)
@@ -196,12 +206,18 @@ func (c *ComposeDeployer) WaitForStatus(ctx context.Context, name string, status
 		}
 
 		services := serviceListFromContainerSummary(containerSummaries)
+		for i := range services {
+			services[i].host = options.Host
+		}
 
 		if len(services) == 0 && status == libstack.StatusRemoved {
 			return waitResult
 		}
 
 		aggregateStatus, errorMessage := aggregateStatuses(ctx, services)
+		if options.RequireHealthy && aggregateStatus == libstack.StatusRunning {
+			aggregateStatus, errorMessage = aggregateHealth(services)
+		}
 		if aggregateStatus == status {
 			return waitResult
 		}
@@ -226,6 +242,27 @@ func (c *ComposeDeployer) WaitForStatus(ctx context.Context, name string, status
 	}
 }
 
+// aggregateHealth computes the status of running containers from their health checks, the containers without
+// health check are considered running
+func aggregateHealth(services []service) (libstack.Status, string) {
+	status := libstack.StatusRunning
+
+	for _, service := range services {
+		if service.State != "running" {
+			continue
+		}
+
+		switch service.Health {
+		case container.Unhealthy:
+			return libstack.StatusError, fmt.Sprintf("service %s is unhealthy", service.Service)
+		case container.Starting:
+			status = libstack.StatusStarting
+		}
+	}
+
+	return status, ""
+}
+
 func serviceListFromContainerSummary(containerSummaries []api.ContainerSummary) []service {
 	var services []service
 
@@ -259,3 +296,132 @@ func serviceListFromContainerSummary(containerSummaries []api.ContainerSummary)
 
 	return services
 }
+
+var healthSeverity = map[string]int{
+	"":                      0,
+	container.NoHealthcheck: 0,
+	container.Healthy:       1,
+	container.Starting:      2,
+	container.Unhealthy:     3,
+}
+
+// GetStatus returns the status of the services of the stack, computed from the state of their containers
+func (c *ComposeDeployer) GetStatus(ctx context.Context, filePaths []string, options libstack.Options) (libstack.StackStatus, error) {
+	var stackStatus libstack.StackStatus
+
+	if err := withCli(ctx, options, func(ctx context.Context, cli *command.DockerCli) error {
+		composeService := c.createComposeServiceFn(cli)
+
+		desiredReplicas := make(map[string]int)
+		if len(filePaths) > 0 {
+			project, err := createProject(ctx, filePaths, options)
+			if err != nil {
+				return fmt.Errorf("failed to create compose project: %w", err)
+			}
+
+			for name, service := range project.Services {
+				desiredReplicas[name] = service.GetScale()
+			}
+		}
+
+		containerSummaries, err := composeService.Ps(ctx, options.ProjectName, api.PsOptions{All: true})
+		if err != nil {
+			return err
+		}
+
+		containersByService := make(map[string][]api.ContainerSummary)
+		for _, cs := range containerSummaries {
+			containersByService[cs.Service] = append(containersByService[cs.Service], cs)
+		}
+
+		serviceNames := slices.Sorted(maps.Keys(desiredReplicas))
+		for name := range containersByService {
+			if _, ok := desiredReplicas[name]; !ok {
+				serviceNames = append(serviceNames, name)
+			}
+		}
+		slices.Sort(serviceNames)
+
+		for _, name := range serviceNames {
+			desired, ok := desiredReplicas[name]
+			if !ok {
+				desired = len(containersByService[name])
+			}
+
+			stackStatus.Services = append(stackStatus.Services, getComposeServiceStatus(ctx, cli, options.Host, name, desired, containersByService[name]))
+		}
+
+		return nil
+	}); err != nil {
+		return stackStatus, fmt.Errorf("compose status operation failed: %w", err)
+	}
+
+	stackStatus.Status = libstack.AggregateServicesStatus(stackStatus.Services)
+
+	return stackStatus, nil
+}
+
+// getComposeServiceStatus returns the status of a compose service from the state of its containers
+func getComposeServiceStatus(ctx context.Context, cli command.Cli, host string, name string, desired int, containerSummaries []api.ContainerSummary) libstack.ServiceStatus {
+	serviceStatus := libstack.ServiceStatus{
+		Name:            name,
+		DesiredReplicas: desired,
+	}
+
+	services := serviceListFromContainerSummary(containerSummaries)
+
+	var unhealthy *service
+	for i := range services {
+		services[i].host = host
+
+		if services[i].State == "running" {
+			serviceStatus.RunningReplicas++
+		}
+
+		if healthSeverity[services[i].Health] > healthSeverity[serviceStatus.Health] {
+			serviceStatus.Health = services[i].Health
+		}
+
+		if services[i].Health == container.Unhealthy {
+			unhealthy = &services[i]
+		}
+
+		if services[i].State == "exited" && serviceStatus.ExitCode == 0 {
+			serviceStatus.ExitCode = services[i].ExitCode
+		}
+
+		inspect, err := cli.Client().ContainerInspect(ctx, services[i].ID)
+		if err != nil {
+			log.Debug().
+				Err(err).
+				Str("container", services[i].Name).
+				Msg("unable to inspect the container, its restart count is not reported")
+
+			continue
+		}
+
+		serviceStatus.RestartCount += inspect.RestartCount
+	}
+
+	status, errorMessage := aggregateStatuses(ctx, services)
+	serviceStatus.Status = status
+	serviceStatus.LogsTail = errorMessage
+
+	if status == libstack.StatusRunning && (serviceStatus.RunningReplicas < desired || unhealthy != nil) {
+		serviceStatus.Status = libstack.StatusDegraded
+
+		if unhealthy != nil {
+			logsTail, err := getContainerLogsTail(ctx, *unhealthy)
+			if err != nil {
+				log.Debug().
+					Err(err).
+					Str("container", unhealthy.Name).
+					Msg("failed to get logs from container")
+			}
+
+			serviceStatus.LogsTail = logsTail
+		}
+	}
+
+	return serviceStatus
+}
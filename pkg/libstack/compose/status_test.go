@@ -0,0 +1,101 @@
+package compose
+
+import (
+	"context"
+	"testing"
+
+	"github.com/portainer/portainer/pkg/libstack"
+
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/stretchr/testify/require"
+)
+
+type psComposeService struct {
+	api.Service
+	containers []api.ContainerSummary
+}
+
+func (s *psComposeService) Ps(ctx context.Context, projectName string, options api.PsOptions) ([]api.ContainerSummary, error) {
+	return s.containers, nil
+}
+
+func Test_GetStatus(t *testing.T) {
+	const composeFileContent = `services:
+  web:
+    image: nginx:alpine
+    deploy:
+      replicas: 2
+  worker:
+    image: alpine
+  db:
+    image: postgres:alpine`
+
+	service := &psComposeService{
+		containers: []api.ContainerSummary{
+			{ID: "1", Name: "app-web-1", Project: "app", Service: "web", State: "running", Health: "healthy"},
+			{ID: "2", Name: "app-web-2", Project: "app", Service: "web", State: "running", Health: "unhealthy"},
+			{ID: "3", Name: "app-worker-1", Project: "app", Service: "worker", State: "running"},
+			{ID: "4", Name: "app-db-1", Project: "app", Service: "db", State: "exited", ExitCode: 3},
+		},
+	}
+
+	w := ComposeDeployer{
+		createComposeServiceFn: func(command.Cli) api.Service { return service },
+	}
+
+	filePaths := []string{createFile(t, t.TempDir(), "docker-compose.yml", composeFileContent)}
+
+	status, err := w.GetStatus(context.Background(), filePaths, libstack.Options{ProjectName: "app"})
+	require.NoError(t, err)
+	require.Equal(t, libstack.StatusError, status.Status)
+	require.Len(t, status.Services, 3)
+
+	db := status.Services[0]
+	require.Equal(t, "db", db.Name)
+	require.Equal(t, libstack.StatusError, db.Status)
+	require.Equal(t, 3, db.ExitCode)
+	require.NotEmpty(t, db.LogsTail)
+
+	web := status.Services[1]
+	require.Equal(t, "web", web.Name)
+	require.Equal(t, libstack.StatusDegraded, web.Status)
+	require.Equal(t, 2, web.DesiredReplicas)
+	require.Equal(t, 2, web.RunningReplicas)
+	require.Equal(t, "unhealthy", web.Health)
+
+	worker := status.Services[2]
+	require.Equal(t, libstack.StatusRunning, worker.Status)
+	require.Equal(t, 1, worker.RunningReplicas)
+	require.Empty(t, worker.Health)
+}
+
+func Test_WaitForStatusWithOptions(t *testing.T) {
+	service := &psComposeService{}
+
+	w := ComposeDeployer{
+		createComposeServiceFn: func(command.Cli) api.Service { return service },
+	}
+
+	options := libstack.WaitOptions{Options: libstack.Options{ProjectName: "app"}, RequireHealthy: true}
+
+	service.containers = []api.ContainerSummary{
+		{ID: "1", Name: "app-web-1", Project: "app", Service: "web", State: "running", Health: "healthy"},
+		{ID: "2", Name: "app-worker-1", Project: "app", Service: "worker", State: "running"},
+	}
+
+	result := w.WaitForStatusWithOptions(context.Background(), libstack.StatusRunning, options)
+	require.Equal(t, libstack.WaitResult{Status: libstack.StatusRunning}, result)
+
+	service.containers = []api.ContainerSummary{
+		{ID: "1", Name: "app-web-1", Project: "app", Service: "web", State: "running", Health: "unhealthy"},
+	}
+
+	result = w.WaitForStatusWithOptions(context.Background(), libstack.StatusRunning, options)
+	require.Equal(t, "service web is unhealthy", result.ErrorMsg)
+
+	options.RequireHealthy = false
+
+	result = w.WaitForStatusWithOptions(context.Background(), libstack.StatusRunning, options)
+	require.Empty(t, result.ErrorMsg)
+}
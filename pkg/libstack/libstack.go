@@ -2,6 +2,8 @@ package libstack
 
 import (
 	"context"
+	"errors"
+	"io"
 
 	portainer "github.com/portainer/portainer/api"
 
@@ -19,10 +21,33 @@ type Deployer interface {
 	Run(ctx context.Context, filePaths []string, serviceName string, options RunOptions) error
 	Validate(ctx context.Context, filePaths []string, options Options) error
 	WaitForStatus(ctx context.Context, name string, status Status) WaitResult
+	// WaitForStatusWithOptions waits for the stack named by options.ProjectName to reach the status on the host of the
+	// options
+	WaitForStatusWithOptions(ctx context.Context, status Status, options WaitOptions) WaitResult
 	Config(ctx context.Context, filePaths []string, options Options) ([]byte, error)
 	GetExistingEdgeStacks(ctx context.Context) ([]EdgeStack, error)
+	// StartService starts the existing containers of a service
+	StartService(ctx context.Context, filePaths []string, serviceName string, options Options) error
+	// StopService stops the containers of a service without removing them
+	StopService(ctx context.Context, filePaths []string, serviceName string, options Options) error
+	// RestartService restarts the containers of a service, its dependencies are left untouched
+	RestartService(ctx context.Context, filePaths []string, serviceName string, options Options) error
+	// ScaleService creates or removes containers so that the service runs the given number of replicas
+	ScaleService(ctx context.Context, filePaths []string, serviceName string, replicas int, options Options) error
+	// RecreateService recreates the containers of a service, its dependencies are left untouched
+	RecreateService(ctx context.Context, filePaths []string, serviceName string, options RecreateServiceOptions) error
+	// GetStatus returns the status of every service of the stack along with the overall status of the stack
+	//
+	// filePaths are optional, they are used to know the number of replicas desired by each service
+	GetStatus(ctx context.Context, filePaths []string, options Options) (StackStatus, error)
 }
 
+// ErrServiceNotFound is returned by the service operations when the service is not defined by the stack files
+var ErrServiceNotFound = errors.New("service not found")
+
+// ErrOperationNotSupported is returned by the deployers for the operations that do not apply to their kind of stacks
+var ErrOperationNotSupported = errors.New("operation not supported")
+
 type Status string
 
 const (
@@ -34,6 +59,8 @@ const (
 	StatusRemoving  Status = "removing"
 	StatusRemoved   Status = "removed"
 	StatusCompleted Status = "completed"
+	// StatusDegraded is reported by GetStatus for the services that run with missing or unhealthy replicas
+	StatusDegraded Status = "degraded"
 )
 
 type WaitResult struct {
@@ -41,6 +68,68 @@ type WaitResult struct {
 	ErrorMsg string
 }
 
+type WaitOptions struct {
+	Options
+	// RequireHealthy makes the running status wait for the health checks of the services to pass, an unhealthy
+	// service is reported as an error
+	RequireHealthy bool
+}
+
+type ServiceStatus struct {
+	Name            string
+	Status          Status
+	DesiredReplicas int
+	RunningReplicas int
+	// Health is the worst health check state of the replicas, it is empty when the service has no health check
+	Health       string
+	RestartCount int
+	// ExitCode is the exit code of the last replica of the service that exited
+	ExitCode int
+	// LogsTail holds the last lines of logs of the failing services
+	LogsTail string
+}
+
+type StackStatus struct {
+	Status   Status
+	Services []ServiceStatus
+}
+
+// AggregateServicesStatus computes the overall status of a stack from the status of its services
+func AggregateServicesStatus(services []ServiceStatus) Status {
+	if len(services) == 0 {
+		return StatusRemoved
+	}
+
+	statusCounts := make(map[Status]int)
+	for _, service := range services {
+		statusCounts[service.Status]++
+	}
+
+	switch {
+	case statusCounts[StatusError] > 0:
+		return StatusError
+	case statusCounts[StatusDegraded] > 0:
+		return StatusDegraded
+	case statusCounts[StatusStarting] > 0:
+		return StatusStarting
+	case statusCounts[StatusRemoving] > 0:
+		return StatusRemoving
+	case statusCounts[StatusCompleted] == len(services):
+		return StatusCompleted
+	case statusCounts[StatusRunning]+statusCounts[StatusCompleted] == len(services):
+		return StatusRunning
+	case statusCounts[StatusStopped] == len(services):
+		return StatusStopped
+	case statusCounts[StatusRemoved] == len(services):
+		return StatusRemoved
+	case statusCounts[StatusRunning] > 0:
+		// Some services are running while others are stopped or removed
+		return StatusDegraded
+	default:
+		return StatusUnknown
+	}
+}
+
 type Options struct {
 	// WorkingDir is the working directory for the command execution
 	WorkingDir  string
@@ -57,6 +146,8 @@ type Options struct {
 	// ConfigOptions is a list of options to pass to the docker-compose config command
 	ConfigOptions []string
 	Registries    []configtypes.AuthConfig
+	// HTTPHeaders are sent with every request to the Docker API, they are only used by the swarm deployer
+	HTTPHeaders map[string]string
 }
 
 type DeployOptions struct {
@@ -67,10 +158,26 @@ type DeployOptions struct {
 	//
 	// When this is set, docker compose will output its logs to stdout
 	AbortOnContainerExit bool
-	RemoveOrphans        bool
-	EdgeStackID          portainer.EdgeStackID
+	// RemoveOrphans removes the services that are no longer defined by the stack files, for swarm stacks this is a prune
+	RemoveOrphans bool
+	EdgeStackID   portainer.EdgeStackID
+	// ResolveImage sets when the image digests are resolved against the registry for swarm stacks,
+	// one of ResolveImageAlways (the default), ResolveImageChanged or ResolveImageNever
+	ResolveImage string
+	// BuildArgs are passed to the builds of the services with a build section, the args defined by the build sections
+	// take precedence, example: "VERSION=1.0"
+	BuildArgs []string
+	// BuildOutput receives the output of the builds of the services with a build section, it is only used by the
+	// compose deployer
+	BuildOutput io.Writer
 }
 
+const (
+	ResolveImageAlways  = "always"
+	ResolveImageChanged = "changed"
+	ResolveImageNever   = "never"
+)
+
 type RunOptions struct {
 	Options
 	// Automatically remove the container when it exits
@@ -81,6 +188,12 @@ type RunOptions struct {
 	Detached bool
 }
 
+type RecreateServiceOptions struct {
+	Options
+	// PullImage pulls the image of the service before recreating its containers
+	PullImage bool
+}
+
 type RemoveOptions struct {
 	Options
 
@@ -0,0 +1,137 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/portainer/portainer/pkg/libstack"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/compose/convert"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/rs/zerolog/log"
+)
+
+// withService runs serviceFn with the swarm service of the stack once it is checked that it exists
+func (d *SwarmDeployer) withService(
+	ctx context.Context,
+	serviceName string,
+	options libstack.Options,
+	serviceFn func(*command.DockerCli, swarm.Service) error,
+) error {
+	return d.withCli(ctx, options, func(ctx context.Context, cli *command.DockerCli) error {
+		name := convert.NewNamespace(options.ProjectName).Scope(serviceName)
+
+		service, _, err := cli.Client().ServiceInspectWithRaw(ctx, name, swarm.ServiceInspectOptions{})
+		if cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", libstack.ErrServiceNotFound, serviceName)
+		} else if err != nil {
+			return err
+		}
+
+		if service.Spec.Labels[convert.LabelNamespace] != options.ProjectName {
+			return fmt.Errorf("%w: %s", libstack.ErrServiceNotFound, serviceName)
+		}
+
+		return serviceFn(cli, service)
+	})
+}
+
+// updateService updates the spec of a swarm service and logs the warnings returned by the daemon
+func updateService(ctx context.Context, cli *command.DockerCli, service swarm.Service, updateOpts swarm.ServiceUpdateOptions) error {
+	response, err := cli.Client().ServiceUpdate(ctx, service.ID, service.Version, service.Spec, updateOpts)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range response.Warnings {
+		log.Warn().Str("service", service.Spec.Name).Msg(warning)
+	}
+
+	return nil
+}
+
+// StartService is not supported by swarm stacks, services are scaled instead
+func (d *SwarmDeployer) StartService(ctx context.Context, filePaths []string, serviceName string, options libstack.Options) error {
+	return fmt.Errorf("swarm start operation failed: %w", libstack.ErrOperationNotSupported)
+}
+
+// StopService is not supported by swarm stacks, services are scaled instead
+func (d *SwarmDeployer) StopService(ctx context.Context, filePaths []string, serviceName string, options libstack.Options) error {
+	return fmt.Errorf("swarm stop operation failed: %w", libstack.ErrOperationNotSupported)
+}
+
+// RestartService replaces the tasks of a service by forcing its update
+func (d *SwarmDeployer) RestartService(ctx context.Context, filePaths []string, serviceName string, options libstack.Options) error {
+	if err := d.withService(ctx, serviceName, options, func(cli *command.DockerCli, service swarm.Service) error {
+		service.Spec.TaskTemplate.ForceUpdate++
+
+		return updateService(ctx, cli, service, swarm.ServiceUpdateOptions{})
+	}); err != nil {
+		return fmt.Errorf("swarm restart operation failed: %w", err)
+	}
+
+	log.Info().Str("service", serviceName).Msg("Stack service restart successful")
+
+	return nil
+}
+
+// ScaleService sets the number of replicas of a replicated service
+func (d *SwarmDeployer) ScaleService(ctx context.Context, filePaths []string, serviceName string, replicas int, options libstack.Options) error {
+	if replicas < 0 {
+		return fmt.Errorf("invalid number of replicas: %d", replicas)
+	}
+
+	if err := d.withService(ctx, serviceName, options, func(cli *command.DockerCli, service swarm.Service) error {
+		if service.Spec.Mode.Replicated == nil {
+			return fmt.Errorf("only replicated services can be scaled")
+		}
+
+		count := uint64(replicas)
+		service.Spec.Mode.Replicated.Replicas = &count
+
+		return updateService(ctx, cli, service, swarm.ServiceUpdateOptions{})
+	}); err != nil {
+		return fmt.Errorf("swarm scale operation failed: %w", err)
+	}
+
+	log.Info().Str("service", serviceName).Int("replicas", replicas).Msg("Stack service scale successful")
+
+	return nil
+}
+
+// RecreateService replaces the tasks of a service, the digest of its image is resolved again against the registry
+// when PullImage is set
+func (d *SwarmDeployer) RecreateService(ctx context.Context, filePaths []string, serviceName string, options libstack.RecreateServiceOptions) error {
+	if err := d.withService(ctx, serviceName, options.Options, func(cli *command.DockerCli, service swarm.Service) error {
+		service.Spec.TaskTemplate.ForceUpdate++
+
+		var updateOpts swarm.ServiceUpdateOptions
+
+		if options.PullImage {
+			// The image of the spec is pinned to the digest resolved by the previous deployment
+			image := service.Spec.TaskTemplate.ContainerSpec.Image
+			if labelImage := service.Spec.Labels[convert.LabelImage]; labelImage != "" {
+				image = labelImage
+			}
+
+			encodedAuth, err := command.RetrieveAuthTokenFromImage(cli.ConfigFile(), image)
+			if err != nil {
+				return err
+			}
+
+			service.Spec.TaskTemplate.ContainerSpec.Image = image
+			updateOpts.EncodedRegistryAuth = encodedAuth
+			updateOpts.QueryRegistry = true
+		}
+
+		return updateService(ctx, cli, service, updateOpts)
+	}); err != nil {
+		return fmt.Errorf("swarm recreate operation failed: %w", err)
+	}
+
+	log.Info().Str("service", serviceName).Msg("Stack service recreate successful")
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer/pkg/libstack"
+
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/compose/convert"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/rs/zerolog/log"
+)
+
+// getServiceStatus returns the convergence status of a swarm service from its task counts and its most recent task
+func getServiceStatus(service swarm.Service, tasks []swarm.Task) (libstack.Status, string) {
+	if service.ServiceStatus == nil {
+		return libstack.StatusUnknown, ""
+	}
+
+	running := service.ServiceStatus.RunningTasks
+	desired := service.ServiceStatus.DesiredTasks
+
+	var latest *swarm.Task
+	for i, task := range tasks {
+		if task.ServiceID != service.ID {
+			continue
+		}
+
+		if latest == nil || task.Meta.CreatedAt.After(latest.Meta.CreatedAt) {
+			latest = &tasks[i]
+		}
+	}
+
+	log.Debug().
+		Str("service", service.Spec.Name).
+		Uint64("running", running).
+		Uint64("desired", desired).
+		Msg("getServiceStatus")
+
+	isJob := service.Spec.Mode.ReplicatedJob != nil || service.Spec.Mode.GlobalJob != nil
+
+	switch {
+	case isJob && desired == 0 && service.ServiceStatus.CompletedTasks > 0:
+		return libstack.StatusCompleted, ""
+	case running < desired && latest != nil && (latest.Status.State == swarm.TaskStateFailed || latest.Status.State == swarm.TaskStateRejected):
+		errorMessage := latest.Status.Err
+		if errorMessage == "" {
+			errorMessage = string(latest.Status.State)
+		}
+
+		return libstack.StatusError, fmt.Sprintf("service %s: %s", service.Spec.Name, errorMessage)
+	case desired == 0 && !isJob:
+		return libstack.StatusStopped, ""
+	case running >= desired && !isJob:
+		return libstack.StatusRunning, ""
+	default:
+		return libstack.StatusStarting, ""
+	}
+}
+
+func aggregateStatuses(services []swarm.Service, tasks []swarm.Task) (libstack.Status, string) {
+	servicesCount := len(services)
+
+	if servicesCount == 0 {
+		log.Debug().
+			Int("tasks", len(tasks)).
+			Msg("no services found")
+
+		if len(tasks) > 0 {
+			return libstack.StatusRemoving, ""
+		}
+
+		return libstack.StatusRemoved, ""
+	}
+
+	statusCounts := make(map[libstack.Status]int)
+	var errorMessages []string
+	for _, service := range services {
+		status, serviceError := getServiceStatus(service, tasks)
+		if serviceError != "" {
+			errorMessages = append(errorMessages, serviceError)
+		}
+		statusCounts[status]++
+	}
+
+	sort.Strings(errorMessages)
+
+	log.Debug().
+		Interface("statusCounts", statusCounts).
+		Strs("errorMessages", errorMessages).
+		Msg("check_status")
+
+	switch {
+	case len(errorMessages) > 0:
+		return libstack.StatusError, strings.Join(errorMessages, "\n")
+	case statusCounts[libstack.StatusStarting] > 0:
+		return libstack.StatusStarting, ""
+	case statusCounts[libstack.StatusCompleted] == servicesCount:
+		return libstack.StatusCompleted, ""
+	case statusCounts[libstack.StatusRunning]+statusCounts[libstack.StatusCompleted] == servicesCount:
+		return libstack.StatusRunning, ""
+	case statusCounts[libstack.StatusStopped] == servicesCount:
+		return libstack.StatusStopped, ""
+	default:
+		return libstack.StatusUnknown, ""
+	}
+}
+
+// WaitForStatus waits for every service of the stack to converge to the given status, the error message lists
+// the services whose tasks fail
+func (d *SwarmDeployer) WaitForStatus(ctx context.Context, name string, status libstack.Status) libstack.WaitResult {
+	return d.waitForStatus(ctx, libstack.Options{}, name, status)
+}
+
+// WaitForStatusWithOptions waits for the services of the stack to reach the given status on the host of the options.
+// The tasks whose health checks fail are replaced by swarm, a running service is therefore considered healthy
+func (d *SwarmDeployer) WaitForStatusWithOptions(ctx context.Context, status libstack.Status, options libstack.WaitOptions) libstack.WaitResult {
+	return d.waitForStatus(ctx, options.Options, options.ProjectName, status)
+}
+
+func (d *SwarmDeployer) waitForStatus(ctx context.Context, options libstack.Options, name string, status libstack.Status) libstack.WaitResult {
+	waitResult := libstack.WaitResult{Status: status}
+
+	for {
+		if ctx.Err() != nil {
+			waitResult.ErrorMsg = "failed to wait for status: " + ctx.Err().Error()
+
+			return waitResult
+		}
+
+		time.Sleep(1 * time.Second)
+
+		var services []swarm.Service
+		var tasks []swarm.Task
+
+		if err := d.withCli(ctx, options, func(ctx context.Context, cli *command.DockerCli) error {
+			listCtx, cancelFunc := context.WithTimeout(ctx, time.Minute)
+			defer cancelFunc()
+
+			stackFilter := filters.NewArgs(filters.Arg("label", convert.LabelNamespace+"="+name))
+
+			var err error
+			if services, err = cli.Client().ServiceList(listCtx, swarm.ServiceListOptions{Filters: stackFilter, Status: true}); err != nil {
+				return err
+			}
+
+			tasks, err = cli.Client().TaskList(listCtx, swarm.TaskListOptions{Filters: stackFilter})
+
+			return err
+		}); err != nil {
+			log.Debug().
+				Str("project_name", name).
+				Err(err).
+				Msg("error from the swarm services list")
+
+			continue
+		}
+
+		aggregateStatus, errorMessage := aggregateStatuses(services, tasks)
+		if aggregateStatus == status {
+			return waitResult
+		}
+
+		if status == libstack.StatusRunning && aggregateStatus == libstack.StatusCompleted {
+			waitResult.Status = libstack.StatusCompleted
+
+			return waitResult
+		}
+
+		if errorMessage != "" {
+			waitResult.ErrorMsg = errorMessage
+
+			return waitResult
+		}
+
+		log.Debug().
+			Str("project_name", name).
+			Str("required_status", string(status)).
+			Str("status", string(aggregateStatus)).
+			Msg("waiting for status")
+	}
+}
+
+// GetStatus returns the status of the services of the stack, computed from the swarm services and their tasks
+func (d *SwarmDeployer) GetStatus(ctx context.Context, filePaths []string, options libstack.Options) (libstack.StackStatus, error) {
+	var stackStatus libstack.StackStatus
+
+	if err := d.withCli(ctx, options, func(ctx context.Context, cli *command.DockerCli) error {
+		namespace := convert.NewNamespace(options.ProjectName)
+		stackFilter := filters.NewArgs(filters.Arg("label", convert.LabelNamespace+"="+namespace.Name()))
+
+		services, err := cli.Client().ServiceList(ctx, swarm.ServiceListOptions{Filters: stackFilter, Status: true})
+		if err != nil {
+			return err
+		}
+
+		tasks, err := cli.Client().TaskList(ctx, swarm.TaskListOptions{Filters: stackFilter})
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(services, func(i, j int) bool {
+			return services[i].Spec.Name < services[j].Spec.Name
+		})
+
+		for _, service := range services {
+			serviceStatus := getSwarmServiceStatus(service, tasks)
+			serviceStatus.Name = namespace.Descope(service.Spec.Name)
+
+			if serviceStatus.Status == libstack.StatusError {
+				logsTail, err := getServiceLogsTail(ctx, cli, service.ID)
+				if err != nil {
+					log.Debug().
+						Err(err).
+						Str("service", service.Spec.Name).
+						Msg("failed to get logs from service")
+				}
+
+				serviceStatus.LogsTail = strings.TrimSpace(serviceStatus.LogsTail + "\n" + logsTail)
+			}
+
+			stackStatus.Services = append(stackStatus.Services, serviceStatus)
+		}
+
+		return nil
+	}); err != nil {
+		return stackStatus, fmt.Errorf("swarm status operation failed: %w", err)
+	}
+
+	stackStatus.Status = libstack.AggregateServicesStatus(stackStatus.Services)
+
+	return stackStatus, nil
+}
+
+// getSwarmServiceStatus returns the replicas, the restarts and the last exit code of a swarm service, the failed
+// tasks of the service are counted as restarts since swarm replaces them
+func getSwarmServiceStatus(service swarm.Service, tasks []swarm.Task) libstack.ServiceStatus {
+	status, errorMessage := getServiceStatus(service, tasks)
+
+	serviceStatus := libstack.ServiceStatus{
+		Status:   status,
+		LogsTail: errorMessage,
+	}
+
+	if service.ServiceStatus != nil {
+		serviceStatus.DesiredReplicas = int(service.ServiceStatus.DesiredTasks)
+		serviceStatus.RunningReplicas = int(service.ServiceStatus.RunningTasks)
+	}
+
+	var lastExited *swarm.Task
+	for i, task := range tasks {
+		if task.ServiceID != service.ID {
+			continue
+		}
+
+		switch task.Status.State {
+		case swarm.TaskStateFailed, swarm.TaskStateRejected:
+			serviceStatus.RestartCount++
+		case swarm.TaskStateComplete:
+		default:
+			continue
+		}
+
+		if task.Status.ContainerStatus != nil && (lastExited == nil || task.Meta.CreatedAt.After(lastExited.Meta.CreatedAt)) {
+			lastExited = &tasks[i]
+		}
+	}
+
+	if lastExited != nil {
+		serviceStatus.ExitCode = lastExited.Status.ContainerStatus.ExitCode
+	}
+
+	return serviceStatus
+}
+
+func getServiceLogsTail(ctx context.Context, cli *command.DockerCli, serviceID string) (string, error) {
+	out, err := cli.Client().ServiceLogs(ctx, serviceID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+		Tail:       "20",
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to get logs from service: %w", err)
+	}
+	defer out.Close()
+
+	var combinedOutput bytes.Buffer
+	if _, err := io.Copy(&combinedOutput, out); err != nil {
+		return "", fmt.Errorf("unable to read service logs: %w", err)
+	}
+
+	return combinedOutput.String(), nil
+}
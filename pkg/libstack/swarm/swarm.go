@@ -0,0 +1,324 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/portainer/portainer/pkg/libstack"
+	"github.com/portainer/portainer/pkg/libstack/compose"
+
+	"github.com/compose-spec/compose-go/v2/dotenv"
+	"github.com/distribution/reference"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/command/stack/loader"
+	stackoptions "github.com/docker/cli/cli/command/stack/options"
+	stackswarm "github.com/docker/cli/cli/command/stack/swarm"
+	"github.com/docker/cli/cli/compose/convert"
+	composeloader "github.com/docker/cli/cli/compose/loader"
+	composetypes "github.com/docker/cli/cli/compose/types"
+	"github.com/docker/cli/cli/flags"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/registry"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const portainerEnvVarsPrefix = "PORTAINER_"
+
+var mu sync.Mutex
+
+// SwarmDeployer deploys stacks on a Swarm cluster through the Docker API. The stack files are converted to Swarm
+// services, networks, secrets and configs the same way `docker stack deploy` does, without relying on the docker binary
+type SwarmDeployer struct {
+	createAPIClientFn func(libstack.Options) (client.APIClient, error)
+}
+
+// NewSwarmDeployer creates a new swarm deployer
+func NewSwarmDeployer() *SwarmDeployer {
+	return &SwarmDeployer{
+		createAPIClientFn: newAPIClient,
+	}
+}
+
+func newAPIClient(options libstack.Options) (client.APIClient, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+
+	if options.Host != "" {
+		opts = append(opts, client.WithHost(options.Host))
+	}
+
+	if len(options.HTTPHeaders) > 0 {
+		opts = append(opts, client.WithHTTPHeaders(options.HTTPHeaders))
+	}
+
+	return client.NewClientWithOpts(opts...)
+}
+
+func (d *SwarmDeployer) withCli(
+	ctx context.Context,
+	options libstack.Options,
+	cliFn func(context.Context, *command.DockerCli) error,
+) error {
+	apiClient, err := d.createAPIClientFn(options)
+	if err != nil {
+		return fmt.Errorf("unable to create a Docker client: %w", err)
+	}
+	defer apiClient.Close()
+
+	cli, err := command.NewDockerCli(command.WithCombinedStreams(log.Logger))
+	if err != nil {
+		return fmt.Errorf("unable to create a Docker client: %w", err)
+	}
+
+	mu.Lock()
+	if err := cli.Initialize(flags.NewClientOptions(), command.WithAPIClient(apiClient)); err != nil {
+		mu.Unlock()
+		return fmt.Errorf("unable to initialize the Docker client: %w", err)
+	}
+	mu.Unlock()
+
+	for _, r := range options.Registries {
+		if r.ServerAddress == "" || r.ServerAddress == registry.DefaultNamespace {
+			r.ServerAddress = registry.IndexServer
+		}
+
+		cli.ConfigFile().AuthConfigs[r.ServerAddress] = r
+	}
+
+	return cliFn(ctx, cli)
+}
+
+// Deploy creates or updates the services of the stack, along with its networks, secrets and configs
+func (d *SwarmDeployer) Deploy(ctx context.Context, filePaths []string, options libstack.DeployOptions) error {
+	if options.ProjectName == "" {
+		return fmt.Errorf("swarm deploy operation failed: the stack name is required")
+	}
+
+	config, err := loadConfig(filePaths, options.Options)
+	if err != nil {
+		return fmt.Errorf("swarm deploy operation failed: %w", err)
+	}
+
+	if options.EdgeStackID > 0 {
+		addEdgeStackLabel(config, int(options.EdgeStackID))
+	}
+
+	resolveImage := options.ResolveImage
+	if resolveImage == "" {
+		resolveImage = libstack.ResolveImageAlways
+	}
+
+	// The detach flag is marked as set so that the deployment doesn't warn about tasks being created in the background,
+	// the convergence of the services is reported by WaitForStatus
+	deployFlags := pflag.NewFlagSet("deploy", pflag.ContinueOnError)
+	deployFlags.Bool("detach", true, "")
+	if err := deployFlags.Set("detach", "true"); err != nil {
+		return err
+	}
+
+	if err := d.withCli(ctx, options.Options, func(ctx context.Context, cli *command.DockerCli) error {
+		return stackswarm.RunDeploy(ctx, cli, deployFlags, &stackoptions.Deploy{
+			Namespace:        options.ProjectName,
+			ResolveImage:     resolveImage,
+			SendRegistryAuth: true,
+			Prune:            options.RemoveOrphans,
+			Detach:           true,
+			Quiet:            true,
+		}, config)
+	}); err != nil {
+		return fmt.Errorf("swarm deploy operation failed: %w", err)
+	}
+
+	log.Info().Msg("Stack deployment successful")
+
+	return nil
+}
+
+// Remove removes the services, networks, secrets and configs of the stack and waits for its tasks to be gone
+func (d *SwarmDeployer) Remove(ctx context.Context, projectName string, filePaths []string, options libstack.RemoveOptions) error {
+	if projectName == "" {
+		return fmt.Errorf("swarm remove operation failed: the stack name is required")
+	}
+
+	if err := d.withCli(ctx, options.Options, func(ctx context.Context, cli *command.DockerCli) error {
+		return stackswarm.RunRemove(ctx, cli, stackoptions.Remove{Namespaces: []string{projectName}, Detach: true})
+	}); err != nil {
+		return fmt.Errorf("swarm remove operation failed: %w", err)
+	}
+
+	if result := d.waitForStatus(ctx, options.Options, projectName, libstack.StatusRemoved); result.ErrorMsg != "" {
+		return fmt.Errorf("swarm remove operation failed: %s", result.ErrorMsg)
+	}
+
+	log.Info().Msg("Stack removal successful")
+
+	return nil
+}
+
+// Pull is not supported by swarm stacks, the images are pulled by the nodes running the tasks of the services
+func (d *SwarmDeployer) Pull(ctx context.Context, filePaths []string, options libstack.Options) error {
+	return fmt.Errorf("swarm pull operation failed: %w", libstack.ErrOperationNotSupported)
+}
+
+// Run is not supported by swarm stacks
+func (d *SwarmDeployer) Run(ctx context.Context, filePaths []string, serviceName string, options libstack.RunOptions) error {
+	return fmt.Errorf("swarm run operation failed: %w", libstack.ErrOperationNotSupported)
+}
+
+// Validate validates stack file
+func (d *SwarmDeployer) Validate(ctx context.Context, filePaths []string, options libstack.Options) error {
+	_, err := loadConfig(filePaths, options)
+
+	return err
+}
+
+// Config returns the merged stack files once interpolated
+func (d *SwarmDeployer) Config(ctx context.Context, filePaths []string, options libstack.Options) ([]byte, error) {
+	config, err := loadConfig(filePaths, options)
+	if err != nil {
+		return nil, fmt.Errorf("swarm config operation failed: %w", err)
+	}
+
+	payload, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal as YAML: %w", err)
+	}
+
+	return payload, nil
+}
+
+func (d *SwarmDeployer) GetExistingEdgeStacks(ctx context.Context) ([]libstack.EdgeStack, error) {
+	m := make(map[int]libstack.EdgeStack)
+
+	if err := d.withCli(ctx, libstack.Options{}, func(ctx context.Context, cli *command.DockerCli) error {
+		services, err := cli.Client().ServiceList(ctx, swarm.ServiceListOptions{
+			Filters: filters.NewArgs(filters.Arg("label", compose.PortainerEdgeStackLabel)),
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, service := range services {
+			id, err := strconv.Atoi(service.Spec.Labels[compose.PortainerEdgeStackLabel])
+			if err != nil {
+				return err
+			}
+
+			namespace := service.Spec.Labels[convert.LabelNamespace]
+			if namespace == "" {
+				return fmt.Errorf("invalid stack namespace label")
+			}
+
+			m[id] = libstack.EdgeStack{
+				ID:   id,
+				Name: namespace,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return slices.Collect(maps.Values(m)), nil
+}
+
+func addEdgeStackLabel(config *composetypes.Config, edgeStackID int) {
+	for i, service := range config.Services {
+		if service.Deploy.Labels == nil {
+			service.Deploy.Labels = make(composetypes.Labels)
+		}
+
+		service.Deploy.Labels[compose.PortainerEdgeStackLabel] = strconv.Itoa(edgeStackID)
+
+		config.Services[i] = service
+	}
+}
+
+// loadConfig loads and interpolates the stack files, the same way `docker stack deploy` does
+func loadConfig(filePaths []string, options libstack.Options) (*composetypes.Config, error) {
+	details, err := loader.GetConfigDetails(filePaths, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the stack files: %w", err)
+	}
+
+	if options.ProjectDir != "" {
+		// When relative paths are used in the stack file, the project directory is used as the base path
+		details.WorkingDir = options.ProjectDir
+	}
+
+	details.Environment, err = buildEnvironment(options)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := composeloader.Load(details)
+	if err != nil {
+		if fpe, ok := err.(*composeloader.ForbiddenPropertiesError); ok {
+			properties := slices.Sorted(maps.Keys(fpe.Properties))
+
+			return nil, fmt.Errorf("the stack file contains unsupported options: %s", strings.Join(properties, ", "))
+		}
+
+		return nil, fmt.Errorf("failed to load the stack files: %w", err)
+	}
+
+	dicts := make([]map[string]any, 0, len(details.ConfigFiles))
+	for _, configFile := range details.ConfigFiles {
+		dicts = append(dicts, configFile.Config)
+	}
+
+	if unsupported := composeloader.GetUnsupportedProperties(dicts...); len(unsupported) > 0 {
+		log.Warn().Strs("options", unsupported).Msg("ignoring the options unsupported by swarm stacks")
+	}
+
+	for _, service := range config.Services {
+		if service.Image == "" {
+			return nil, fmt.Errorf("invalid image reference for service %s: no image specified", service.Name)
+		}
+
+		if _, err := reference.ParseAnyReference(service.Image); err != nil {
+			return nil, fmt.Errorf("invalid image reference for service %s: %w", service.Name, err)
+		}
+	}
+
+	return config, nil
+}
+
+// buildEnvironment returns the variables used to interpolate the stack files, the Portainer variables of the process
+// are overridden by the env file which is overridden by the variables of the options
+func buildEnvironment(options libstack.Options) (map[string]string, error) {
+	env := make(map[string]string)
+
+	for _, ev := range os.Environ() {
+		if k, v, ok := strings.Cut(ev, "="); ok && strings.HasPrefix(k, portainerEnvVarsPrefix) {
+			env[k] = v
+		}
+	}
+
+	if options.EnvFilePath != "" {
+		fileEnv, err := dotenv.GetEnvFromFile(env, []string{options.EnvFilePath})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the env file: %w", err)
+		}
+
+		maps.Copy(env, fileEnv)
+	}
+
+	for _, ev := range options.Env {
+		if k, v, ok := strings.Cut(ev, "="); ok {
+			env[k] = v
+		}
+	}
+
+	return env, nil
+}
@@ -0,0 +1,277 @@
+package swarm
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/portainer/portainer/pkg/libstack"
+	"github.com/portainer/portainer/pkg/libstack/compose"
+
+	"github.com/docker/cli/cli/compose/convert"
+	configtypes "github.com/docker/cli/cli/config/types"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPIClient struct {
+	client.APIClient
+	networks       []string
+	services       map[string]swarm.ServiceSpec
+	listedServices []swarm.Service
+	tasks          []swarm.Task
+	createOptions  swarm.ServiceCreateOptions
+	updatedSpec    swarm.ServiceSpec
+	updatedOptions swarm.ServiceUpdateOptions
+}
+
+func (c *fakeAPIClient) ClientVersion() string {
+	return "1.47"
+}
+
+func (c *fakeAPIClient) Close() error {
+	return nil
+}
+
+func (c *fakeAPIClient) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{APIVersion: "1.47"}, nil
+}
+
+func (c *fakeAPIClient) NegotiateAPIVersionPing(types.Ping) {}
+
+func (c *fakeAPIClient) Info(ctx context.Context) (system.Info, error) {
+	return system.Info{Swarm: swarm.Info{ControlAvailable: true}}, nil
+}
+
+func (c *fakeAPIClient) NetworkList(ctx context.Context, options network.ListOptions) ([]network.Summary, error) {
+	return nil, nil
+}
+
+func (c *fakeAPIClient) NetworkCreate(ctx context.Context, name string, options network.CreateOptions) (network.CreateResponse, error) {
+	c.networks = append(c.networks, name)
+
+	return network.CreateResponse{ID: name}, nil
+}
+
+func (c *fakeAPIClient) ServiceList(ctx context.Context, options swarm.ServiceListOptions) ([]swarm.Service, error) {
+	return c.listedServices, nil
+}
+
+func (c *fakeAPIClient) TaskList(ctx context.Context, options swarm.TaskListOptions) ([]swarm.Task, error) {
+	return c.tasks, nil
+}
+
+func (c *fakeAPIClient) ServiceLogs(ctx context.Context, serviceID string, options container.LogsOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("connection refused")), nil
+}
+
+func (c *fakeAPIClient) ServiceCreate(ctx context.Context, spec swarm.ServiceSpec, options swarm.ServiceCreateOptions) (swarm.ServiceCreateResponse, error) {
+	c.services[spec.Name] = spec
+	c.createOptions = options
+
+	return swarm.ServiceCreateResponse{ID: spec.Name}, nil
+}
+
+func (c *fakeAPIClient) ServiceInspectWithRaw(ctx context.Context, serviceID string, options swarm.ServiceInspectOptions) (swarm.Service, []byte, error) {
+	spec, ok := c.services[serviceID]
+	if !ok {
+		return swarm.Service{}, nil, notFoundError{}
+	}
+
+	return swarm.Service{ID: serviceID, Spec: spec}, nil, nil
+}
+
+func (c *fakeAPIClient) ServiceUpdate(ctx context.Context, serviceID string, version swarm.Version, spec swarm.ServiceSpec, options swarm.ServiceUpdateOptions) (swarm.ServiceUpdateResponse, error) {
+	c.updatedSpec = spec
+	c.updatedOptions = options
+
+	return swarm.ServiceUpdateResponse{}, nil
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "not found" }
+
+func (notFoundError) NotFound() {}
+
+func createFile(t *testing.T, dir, fileName, content string) string {
+	filePath := filepath.Join(dir, fileName)
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0o644))
+
+	return filePath
+}
+
+func Test_DeployAndServiceOperations(t *testing.T) {
+	const stackFileContent = `version: "3.8"
+services:
+  web:
+    image: registry.example.com/web:${TAG}
+    deploy:
+      replicas: 2`
+
+	apiClient := &fakeAPIClient{services: make(map[string]swarm.ServiceSpec)}
+
+	d := SwarmDeployer{
+		createAPIClientFn: func(libstack.Options) (client.APIClient, error) { return apiClient, nil },
+	}
+
+	filePaths := []string{createFile(t, t.TempDir(), "docker-compose.yml", stackFileContent)}
+	options := libstack.Options{
+		ProjectName: "app",
+		Env:         []string{"TAG=1.0"},
+		Registries:  []configtypes.AuthConfig{{ServerAddress: "registry.example.com", Username: "user", Password: "pass"}},
+	}
+
+	ctx := context.Background()
+
+	require.NoError(t, d.Deploy(ctx, filePaths, libstack.DeployOptions{
+		Options:      options,
+		EdgeStackID:  4,
+		ResolveImage: libstack.ResolveImageNever,
+	}))
+
+	require.Equal(t, []string{"app_default"}, apiClient.networks)
+	require.Contains(t, apiClient.services, "app_web")
+
+	spec := apiClient.services["app_web"]
+	require.Equal(t, "registry.example.com/web:1.0", spec.TaskTemplate.ContainerSpec.Image)
+	require.Equal(t, uint64(2), *spec.Mode.Replicated.Replicas)
+	require.Equal(t, "app", spec.Labels[convert.LabelNamespace])
+	require.Equal(t, "4", spec.Labels[compose.PortainerEdgeStackLabel])
+	require.NotEmpty(t, apiClient.createOptions.EncodedRegistryAuth)
+	require.False(t, apiClient.createOptions.QueryRegistry)
+
+	require.NoError(t, d.ScaleService(ctx, filePaths, "web", 5, options))
+	require.Equal(t, uint64(5), *apiClient.updatedSpec.Mode.Replicated.Replicas)
+
+	require.NoError(t, d.RestartService(ctx, filePaths, "web", options))
+	require.Equal(t, uint64(1), apiClient.updatedSpec.TaskTemplate.ForceUpdate)
+
+	require.NoError(t, d.RecreateService(ctx, filePaths, "web", libstack.RecreateServiceOptions{Options: options, PullImage: true}))
+	require.True(t, apiClient.updatedOptions.QueryRegistry)
+	require.NotEmpty(t, apiClient.updatedOptions.EncodedRegistryAuth)
+
+	require.ErrorIs(t, d.RestartService(ctx, filePaths, "db", options), libstack.ErrServiceNotFound)
+	require.ErrorIs(t, d.StopService(ctx, filePaths, "web", options), libstack.ErrOperationNotSupported)
+}
+
+func Test_aggregateStatuses(t *testing.T) {
+	replicas := uint64(2)
+
+	service := func(id string, running, desired uint64) swarm.Service {
+		return swarm.Service{
+			ID: id,
+			Spec: swarm.ServiceSpec{
+				Annotations: swarm.Annotations{Name: "app_" + id},
+				Mode:        swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+			},
+			ServiceStatus: &swarm.ServiceStatus{RunningTasks: running, DesiredTasks: desired},
+		}
+	}
+
+	failedTask := swarm.Task{ServiceID: "db", Status: swarm.TaskStatus{State: swarm.TaskStateRejected, Err: "No such image: postgres:missing"}}
+
+	tests := []struct {
+		name          string
+		services      []swarm.Service
+		tasks         []swarm.Task
+		expected      libstack.Status
+		expectedError string
+	}{
+		{
+			name:     "no services nor tasks",
+			expected: libstack.StatusRemoved,
+		},
+		{
+			name:     "tasks still shutting down",
+			tasks:    []swarm.Task{{ServiceID: "web"}},
+			expected: libstack.StatusRemoving,
+		},
+		{
+			name:     "converged services",
+			services: []swarm.Service{service("web", 2, 2), service("db", 1, 1)},
+			expected: libstack.StatusRunning,
+		},
+		{
+			name:     "converging services",
+			services: []swarm.Service{service("web", 1, 2), service("db", 1, 1)},
+			expected: libstack.StatusStarting,
+		},
+		{
+			name:     "services scaled down to zero",
+			services: []swarm.Service{service("web", 0, 0)},
+			expected: libstack.StatusStopped,
+		},
+		{
+			name:          "failing service",
+			services:      []swarm.Service{service("web", 2, 2), service("db", 0, 1)},
+			tasks:         []swarm.Task{failedTask},
+			expected:      libstack.StatusError,
+			expectedError: "service app_db: No such image: postgres:missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, errorMessage := aggregateStatuses(tt.services, tt.tasks)
+			require.Equal(t, tt.expected, status)
+			require.Equal(t, tt.expectedError, errorMessage)
+		})
+	}
+}
+
+func Test_GetStatus(t *testing.T) {
+	replicas := uint64(2)
+
+	apiClient := &fakeAPIClient{
+		listedServices: []swarm.Service{
+			{
+				ID:            "web",
+				Spec:          swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "app_web"}, Mode: swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}}},
+				ServiceStatus: &swarm.ServiceStatus{RunningTasks: 2, DesiredTasks: 2},
+			},
+			{
+				ID:            "api",
+				Spec:          swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "app_api"}, Mode: swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}}},
+				ServiceStatus: &swarm.ServiceStatus{RunningTasks: 0, DesiredTasks: 2},
+			},
+		},
+		tasks: []swarm.Task{
+			{ServiceID: "api", Status: swarm.TaskStatus{State: swarm.TaskStateFailed, Err: "task: non-zero exit (1)", ContainerStatus: &swarm.ContainerStatus{ExitCode: 1}}},
+			{ServiceID: "api", Status: swarm.TaskStatus{State: swarm.TaskStateFailed, Err: "task: non-zero exit (1)", ContainerStatus: &swarm.ContainerStatus{ExitCode: 1}}},
+			{ServiceID: "web", Status: swarm.TaskStatus{State: swarm.TaskStateRunning}},
+		},
+	}
+
+	d := SwarmDeployer{
+		createAPIClientFn: func(libstack.Options) (client.APIClient, error) { return apiClient, nil },
+	}
+
+	status, err := d.GetStatus(context.Background(), nil, libstack.Options{ProjectName: "app"})
+	require.NoError(t, err)
+	require.Equal(t, libstack.StatusError, status.Status)
+	require.Len(t, status.Services, 2)
+
+	api := status.Services[0]
+	require.Equal(t, "api", api.Name)
+	require.Equal(t, libstack.StatusError, api.Status)
+	require.Equal(t, 2, api.DesiredReplicas)
+	require.Equal(t, 0, api.RunningReplicas)
+	require.Equal(t, 2, api.RestartCount)
+	require.Equal(t, 1, api.ExitCode)
+	require.Contains(t, api.LogsTail, "non-zero exit")
+	require.Contains(t, api.LogsTail, "connection refused")
+
+	web := status.Services[1]
+	require.Equal(t, "web", web.Name)
+	require.Equal(t, libstack.StatusRunning, web.Status)
+	require.Empty(t, web.LogsTail)
+}
@@ -0,0 +1,217 @@
+package networking
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// CheckStatus is the outcome of a connectivity check
+type CheckStatus string
+
+const (
+	CheckStatusPassed  CheckStatus = "passed"
+	CheckStatusWarning CheckStatus = "warning"
+	CheckStatusFailed  CheckStatus = "failed"
+	CheckStatusSkipped CheckStatus = "skipped"
+)
+
+// CertificateExpiryWarningThreshold is how close to its expiry date a certificate has to be to raise a warning
+const CertificateExpiryWarningThreshold = 30 * 24 * time.Hour
+
+// CheckResult is the structured result of a single connectivity check
+type CheckResult struct {
+	// Name of the check, e.g. dns or tcp
+	Name string `json:"Name" example:"tcp"`
+	// Outcome of the check
+	Status CheckStatus `json:"Status" example:"passed"`
+	// Time spent running the check, in milliseconds
+	DurationMs int64 `json:"DurationMs" example:"12"`
+	// Human readable summary of the outcome
+	Message string `json:"Message" example:"connected to 10.0.0.1:9001"`
+	// Additional data collected by the check
+	Details map[string]any `json:"Details,omitempty"`
+	// Suggested fix when the check did not pass
+	Remediation string `json:"Remediation,omitempty"`
+}
+
+// CertificateInfo describes a certificate presented during a TLS handshake
+type CertificateInfo struct {
+	Subject   string    `json:"Subject"`
+	Issuer    string    `json:"Issuer"`
+	DNSNames  []string  `json:"DNSNames,omitempty"`
+	NotBefore time.Time `json:"NotBefore"`
+	NotAfter  time.Time `json:"NotAfter"`
+	IsCA      bool      `json:"IsCA"`
+}
+
+// SkippedCheck returns the result of a check that was not run
+func SkippedCheck(name, reason string) CheckResult {
+	return CheckResult{
+		Name:    name,
+		Status:  CheckStatusSkipped,
+		Message: reason,
+	}
+}
+
+// RunCheck times fn and turns its outcome into a CheckResult, the remediation is only kept when fn fails
+func RunCheck(name, remediation string, fn func() (string, map[string]any, error)) CheckResult {
+	start := time.Now()
+	message, details, err := fn()
+
+	return probeResult(name, time.Since(start), message, details, err, remediation)
+}
+
+// probeResult turns the outcome of a probe into a CheckResult, the remediation is only kept when the probe failed
+func probeResult(name string, duration time.Duration, message string, details map[string]any, err error, remediation string) CheckResult {
+	result := CheckResult{
+		Name:       name,
+		Status:     CheckStatusPassed,
+		DurationMs: duration.Milliseconds(),
+		Message:    message,
+		Details:    details,
+	}
+
+	if err != nil {
+		result.Status = CheckStatusFailed
+		result.Message = err.Error()
+		result.Remediation = remediation
+	}
+
+	return result
+}
+
+// CheckDNS resolves the host of url with ProbeDNS and reports the resolved addresses
+func CheckDNS(url string) CheckResult {
+	_, host, _ := parseURL(url)
+	if net.ParseIP(host) != nil {
+		return SkippedCheck("dns", host+" is an IP address")
+	}
+
+	probe := ProbeDNS(url)
+
+	var err error
+	if probe.Err != nil {
+		err = fmt.Errorf("unable to resolve %s: %w", host, probe.Err)
+	}
+
+	return probeResult("dns", probe.Duration, "resolved "+host, map[string]any{"Addresses": probe.ResolvedIPs}, err,
+		fmt.Sprintf("Make sure %s can be resolved by the DNS servers configured on the Portainer host", host))
+}
+
+// CheckTCP connects to the address of url with ProbeTelnet and reports the local and remote addresses
+func CheckTCP(url string) CheckResult {
+	probe := ProbeTelnet(url)
+
+	var err error
+	if probe.Err != nil {
+		err = fmt.Errorf("unable to connect to %s: %w", probe.Address, probe.Err)
+	}
+
+	return probeResult("tcp", probe.Duration, "connected to "+probe.Address, map[string]any{
+		"LocalAddress":  probe.LocalAddress,
+		"RemoteAddress": probe.RemoteAddress,
+	}, err, fmt.Sprintf("Make sure the service is listening on %s and that no firewall blocks the traffic from the Portainer host", probe.Address))
+}
+
+// CheckTLS reports the TLS connection established by a ProbeProxy request sent to an https URL. The check fails when
+// the handshake fails or a certificate is expired, and raises a warning when one expires soon.
+func CheckTLS(probe ProxyProbe) CheckResult {
+	if probe.Err != nil {
+		return probeResult("tls", probe.Duration, "", nil, fmt.Errorf("TLS handshake failed: %w", probe.Err), tlsRemediation(probe.Err))
+	}
+
+	if probe.TLS == nil {
+		return probeResult("tls", probe.Duration, "", nil, errors.New("the connection is not encrypted"), "Check the TLS configuration of the environment")
+	}
+
+	result := probeResult("tls", probe.Duration, "TLS handshake succeeded", map[string]any{
+		"Version":          probe.TLS.Version,
+		"CipherSuite":      probe.TLS.CipherSuite,
+		"CertificateChain": probe.TLS.CertificateChain,
+	}, nil, "")
+
+	now := time.Now()
+
+	for _, cert := range probe.TLS.CertificateChain {
+		switch {
+		case now.After(cert.NotAfter):
+			result.Status = CheckStatusFailed
+			result.Message = fmt.Sprintf("certificate %q expired on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+			result.Remediation = "Renew the expired certificate on the environment"
+
+			return result
+		case cert.NotAfter.Sub(now) < CertificateExpiryWarningThreshold:
+			result.Status = CheckStatusWarning
+			result.Message = fmt.Sprintf("certificate %q expires on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+			result.Remediation = "Renew the certificate on the environment before it expires"
+		}
+	}
+
+	return result
+}
+
+// CheckProxy reports whether a ProbeProxy request went through a proxy, which raises a warning since the proxy can
+// alter or block the requests sent to the environment
+func CheckProxy(probe ProxyProbe) CheckResult {
+	result := CheckResult{
+		Name:       "proxy",
+		Status:     CheckStatusPassed,
+		DurationMs: probe.Duration.Milliseconds(),
+		Message:    probe.Status,
+	}
+
+	switch {
+	case probe.Err != nil:
+		result.Status = CheckStatusWarning
+		result.Message = fmt.Sprintf("unable to detect a proxy: %s", probe.Err)
+	case probe.Detected:
+		result.Status = CheckStatusWarning
+		result.Remediation = "Make sure the proxy between Portainer and the environment allows the Docker and Kubernetes API requests, or exclude the environment with NO_PROXY"
+	}
+
+	return result
+}
+
+func tlsRemediation(err error) string {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostnameErr      x509.HostnameError
+		recordHeaderErr  tls.RecordHeaderError
+		alertErr         tls.AlertError
+	)
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "The server certificate is not signed by the configured CA, upload the right CA certificate or skip the server verification"
+	case errors.As(err, &hostnameErr):
+		return "The server certificate does not match the environment URL, use a hostname listed in the certificate or reissue it"
+	// the http client replaces the record header error when the server answers with plain HTTP
+	case errors.As(err, &recordHeaderErr), strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return "The server does not speak TLS on this port, disable TLS for this environment or check the port"
+	case errors.As(err, &alertErr):
+		return "The server rejected the handshake, upload a client certificate and key trusted by the environment"
+	}
+
+	return "Check the TLS configuration of the environment and that the server is reachable"
+}
+
+// OverallStatus returns the worst status of results, skipped checks are ignored
+func OverallStatus(results []CheckResult) CheckStatus {
+	status := CheckStatusPassed
+
+	for _, result := range results {
+		switch result.Status {
+		case CheckStatusFailed:
+			return CheckStatusFailed
+		case CheckStatusWarning:
+			status = CheckStatusWarning
+		}
+	}
+
+	return status
+}
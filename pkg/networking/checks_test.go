@@ -0,0 +1,142 @@
+package networking
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckDNS(t *testing.T) {
+	result := CheckDNS("tcp://127.0.0.1:2375")
+	if result.Status != CheckStatusSkipped {
+		t.Fatalf("expected an IP address to skip the check, got %s", result.Status)
+	}
+
+	result = CheckDNS("tcp://nonexistent.domain.invalid:2375")
+	if result.Status != CheckStatusFailed {
+		t.Fatalf("expected the check to fail, got %s", result.Status)
+	}
+
+	if result.Remediation == "" {
+		t.Fatal("expected a remediation hint for a failed check")
+	}
+}
+
+func TestCheckTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := listener.Addr().String()
+
+	result := CheckTCP("tcp://" + address)
+	if result.Status != CheckStatusPassed {
+		t.Fatalf("expected the check to pass, got %s: %s", result.Status, result.Message)
+	}
+
+	if result.Remediation != "" {
+		t.Fatalf("expected no remediation for a passed check, got %q", result.Remediation)
+	}
+
+	listener.Close()
+
+	result = CheckTCP("tcp://" + address)
+	if result.Status != CheckStatusFailed {
+		t.Fatalf("expected the check to fail once the listener is closed, got %s", result.Status)
+	}
+
+	if !strings.Contains(result.Remediation, address) {
+		t.Fatalf("expected the remediation to mention %s, got %q", address, result.Remediation)
+	}
+}
+
+func TestCheckTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	result := CheckTLS(ProbeProxy(server.URL, &tls.Config{InsecureSkipVerify: true}))
+	if result.Status != CheckStatusPassed {
+		t.Fatalf("expected the check to pass, got %s: %s", result.Status, result.Message)
+	}
+
+	chain, ok := result.Details["CertificateChain"].([]CertificateInfo)
+	if !ok || len(chain) == 0 {
+		t.Fatal("expected the certificate chain to be reported")
+	}
+
+	// The test server certificate is self-signed, it cannot be verified with the system roots
+	result = CheckTLS(ProbeProxy(server.URL, &tls.Config{}))
+	if result.Status != CheckStatusFailed {
+		t.Fatalf("expected the check to fail, got %s", result.Status)
+	}
+
+	if !strings.Contains(result.Remediation, "CA certificate") {
+		t.Fatalf("expected a CA remediation hint, got %q", result.Remediation)
+	}
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+
+	result = CheckTLS(ProbeProxy("https://"+plain.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}))
+	if result.Status != CheckStatusFailed {
+		t.Fatalf("expected the check to fail against a plain HTTP server, got %s", result.Status)
+	}
+
+	if !strings.Contains(result.Remediation, "does not speak TLS") {
+		t.Fatalf("expected a TLS port remediation hint, got %q", result.Remediation)
+	}
+}
+
+func TestCheckProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	result := CheckProxy(ProbeProxy(server.URL, nil))
+	if result.Status != CheckStatusPassed {
+		t.Fatalf("expected the check to pass, got %s: %s", result.Status, result.Message)
+	}
+
+	proxied := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Via", "1.1 squid")
+	}))
+	defer proxied.Close()
+
+	result = CheckProxy(ProbeProxy(proxied.URL, nil))
+	if result.Status != CheckStatusWarning {
+		t.Fatalf("expected a warning when a proxy is detected, got %s", result.Status)
+	}
+
+	if result.Remediation == "" {
+		t.Fatal("expected a remediation hint when a proxy is detected")
+	}
+}
+
+func TestOverallStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []CheckStatus
+		expected CheckStatus
+	}{
+		{"no checks", nil, CheckStatusPassed},
+		{"skipped checks are ignored", []CheckStatus{CheckStatusPassed, CheckStatusSkipped}, CheckStatusPassed},
+		{"warning", []CheckStatus{CheckStatusPassed, CheckStatusWarning}, CheckStatusWarning},
+		{"failure wins", []CheckStatus{CheckStatusWarning, CheckStatusFailed, CheckStatusPassed}, CheckStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []CheckResult
+			for _, status := range tt.statuses {
+				results = append(results, CheckResult{Status: status})
+			}
+
+			if status := OverallStatus(results); status != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, status)
+			}
+		})
+	}
+}
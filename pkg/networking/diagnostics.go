@@ -12,106 +12,237 @@ import (
 	"github.com/segmentio/encoding/json"
 )
 
+const (
+	telnetTimeout = 5 * time.Second
+	proxyTimeout  = 10 * time.Second
+)
+
+// DNSProbe is the outcome of the DNS lookup of the host of a URL
+type DNSProbe struct {
+	Host        string
+	ResolvedIPs []net.IP
+	ConnectedAt time.Time
+	Duration    time.Duration
+	Err         error
+}
+
+// TelnetProbe is the outcome of a connection to the address of a URL
+type TelnetProbe struct {
+	Network       string
+	Address       string
+	LocalAddress  string
+	RemoteAddress string
+	ConnectedAt   time.Time
+	Duration      time.Duration
+	Err           error
+}
+
+// ProxyProbe is the outcome of a request sent to a URL to detect a proxy between the client and the server
+type ProxyProbe struct {
+	LocalAddress  string
+	RemoteAddress string
+	// Status describes how a proxy was detected, if any
+	Status      string
+	Detected    bool
+	TLS         *TLSInfo
+	ConnectedAt time.Time
+	Duration    time.Duration
+	Err         error
+}
+
+// TLSInfo describes the TLS connection established with a server
+type TLSInfo struct {
+	Version          string
+	CipherSuite      string
+	CertificateChain []CertificateInfo
+}
+
+// ProbeDNS resolves the host of url
+func ProbeDNS(url string) DNSProbe {
+	_, host, _ := parseURL(url)
+
+	probe := DNSProbe{Host: host, ConnectedAt: time.Now()}
+	probe.ResolvedIPs, probe.Err = net.LookupIP(host)
+	probe.Duration = time.Since(probe.ConnectedAt)
+
+	return probe
+}
+
 // ProbeDNSConnection probes a DNS connection and returns a JSON string with the DNS lookup status and IP addresses.
 // ignores errors for the dns lookup since we want to know if the host is reachable
 func ProbeDNSConnection(url string) string {
-	_, host, _ := parseURL(url)
+	probe := ProbeDNS(url)
+
 	result := map[string]interface{}{
 		"operation":      "dns lookup",
-		"remote_address": host,
-		"connected_at":   time.Now().Format(time.RFC3339),
+		"remote_address": probe.Host,
+		"connected_at":   probe.ConnectedAt.Format(time.RFC3339),
+		"duration_ms":    probe.Duration.Milliseconds(),
 		"status":         "dns lookup successful",
 		"resolved_ips":   []net.IP{},
 	}
 
-	ipAddresses, err := net.LookupIP(host)
-	if err != nil {
-		result["status"] = fmt.Sprintf("dns lookup failed: %s", err)
+	if probe.Err != nil {
+		result["status"] = fmt.Sprintf("dns lookup failed: %s", probe.Err)
 	} else {
-		result["resolved_ips"] = ipAddresses
+		result["resolved_ips"] = probe.ResolvedIPs
 	}
 
 	jsonData, _ := json.Marshal(result)
 	return string(jsonData)
 }
 
-// ProbeTelnetConnection probes a telnet connection and returns a JSON string with the telnet connection status, local and remote addresses.
-// ignores errors for the telnet connection since we want to know if the host is reachable
-func ProbeTelnetConnection(url string) string {
+// ProbeTelnet opens a connection to the address of url, the path of the socket for a unix URL
+func ProbeTelnet(url string) TelnetProbe {
 	network, host, port := parseURL(url)
 	if network == "https" || network == "http" {
 		network = "tcp"
 	}
 
-	address := fmt.Sprintf("%s:%s", host, port)
-	result := map[string]string{
+	probe := TelnetProbe{Network: network, Address: net.JoinHostPort(host, port), ConnectedAt: time.Now()}
+	if network == "unix" {
+		probe.Address = socketPath(url)
+	}
+
+	connection, err := net.DialTimeout(network, probe.Address, telnetTimeout)
+	probe.Duration = time.Since(probe.ConnectedAt)
+
+	if err != nil {
+		probe.Err = err
+
+		return probe
+	}
+	defer connection.Close()
+
+	probe.LocalAddress = connection.LocalAddr().String()
+	probe.RemoteAddress = connection.RemoteAddr().String()
+
+	return probe
+}
+
+// ProbeTelnetConnection probes a telnet connection and returns a JSON string with the telnet connection status, local and remote addresses.
+// ignores errors for the telnet connection since we want to know if the host is reachable
+func ProbeTelnetConnection(url string) string {
+	probe := ProbeTelnet(url)
+
+	result := map[string]interface{}{
 		"operation":      "telnet connection",
 		"local_address":  "unknown",
 		"remote_address": "unknown",
-		"network":        network,
-		"status":         "connected to " + address,
-		"connected_at":   time.Now().Format(time.RFC3339),
+		"network":        probe.Network,
+		"status":         "connected to " + probe.Address,
+		"connected_at":   probe.ConnectedAt.Format(time.RFC3339),
+		"duration_ms":    probe.Duration.Milliseconds(),
 	}
 
-	connection, err := net.DialTimeout(network, address, 5*time.Second)
-	if err != nil {
-		result["status"] = fmt.Sprintf("failed to connect to %s: %s", address, err)
+	if probe.Err != nil {
+		result["status"] = fmt.Sprintf("failed to connect to %s: %s", probe.Address, probe.Err)
 	} else {
-		defer connection.Close()
-		result["local_address"] = connection.LocalAddr().String()
-		result["remote_address"] = connection.RemoteAddr().String()
+		result["local_address"] = probe.LocalAddress
+		result["remote_address"] = probe.RemoteAddress
 	}
 
 	jsonData, _ := json.Marshal(result)
 	return string(jsonData)
 }
 
-// DetectProxy probes a target URL and returns a JSON string with the proxy detection status, local and remote addresses.
-// ignores errors for the http request since we want to know if the host is reachable
-func DetectProxy(url string) string {
+// ProbeProxy sends a request to url using tlsConfig for the https URLs and looks for the traces of a proxy in the
+// response headers and the certificate presented by the server
+func ProbeProxy(url string, tlsConfig *tls.Config) ProxyProbe {
 	client := &http.Client{
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
+			TLSClientConfig: tlsConfig,
 		},
-		Timeout: 10 * time.Second,
+		Timeout: proxyTimeout,
 	}
 
-	result := map[string]string{
+	probe := ProxyProbe{Status: "no proxy detected", ConnectedAt: time.Now()}
+
+	resp, err := client.Get(url)
+	probe.Duration = time.Since(probe.ConnectedAt)
+
+	if err != nil {
+		probe.Err = err
+
+		return probe
+	}
+	defer resp.Body.Close()
+
+	if resp.Request != nil {
+		probe.LocalAddress = resp.Request.Host
+		probe.RemoteAddress = resp.Request.RemoteAddr
+	}
+
+	if resp.TLS != nil {
+		probe.TLS = tlsInfo(resp.TLS)
+	}
+
+	if resp.Header.Get("Via") != "" || resp.Header.Get("X-Forwarded-For") != "" || resp.Header.Get("Proxy-Connection") != "" {
+		probe.Status = "proxy detected via headers"
+		probe.Detected = true
+	} else if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		if cert.IsCA || strings.Contains(strings.ToLower(cert.Issuer.CommonName), "proxy") {
+			probe.Status = "proxy detected via certificate"
+			probe.Detected = true
+		}
+	}
+
+	return probe
+}
+
+// DetectProxy probes a target URL and returns a JSON string with the proxy detection status, local and remote addresses.
+// ignores errors for the http request since we want to know if the host is reachable
+func DetectProxy(url string) string {
+	probe := ProbeProxy(url, &tls.Config{InsecureSkipVerify: true})
+
+	result := map[string]interface{}{
 		"operation":      "proxy detection",
 		"local_address":  "unknown",
 		"remote_address": "unknown",
 		"network":        "https",
-		"status":         "no proxy detected",
-		"connected_at":   time.Now().Format(time.RFC3339),
+		"status":         probe.Status,
+		"connected_at":   probe.ConnectedAt.Format(time.RFC3339),
+		"duration_ms":    probe.Duration.Milliseconds(),
 	}
 
-	resp, err := client.Get(url)
-	if err != nil {
-		result["status"] = fmt.Sprintf("failed to make request: %s", err)
+	if probe.Err != nil {
+		result["status"] = fmt.Sprintf("failed to make request: %s", probe.Err)
 	} else {
-		defer resp.Body.Close()
-
-		if resp.Request != nil {
-			result["local_address"] = resp.Request.Host
-			result["remote_address"] = resp.Request.RemoteAddr
-		}
+		result["local_address"] = probe.LocalAddress
+		result["remote_address"] = probe.RemoteAddress
+	}
 
-		if resp.Header.Get("Via") != "" || resp.Header.Get("X-Forwarded-For") != "" || resp.Header.Get("Proxy-Connection") != "" {
-			result["status"] = "proxy detected via headers"
-		} else if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
-			cert := resp.TLS.PeerCertificates[0]
-			if cert.IsCA || strings.Contains(strings.ToLower(cert.Issuer.CommonName), "proxy") {
-				result["status"] = "proxy detected via certificate"
-			}
-		}
+	if probe.TLS != nil {
+		result["tls_version"] = probe.TLS.Version
+		result["cipher_suite"] = probe.TLS.CipherSuite
 	}
 
 	jsonData, _ := json.Marshal(result)
 	return string(jsonData)
 }
 
+func tlsInfo(state *tls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{
+		Version:          tls.VersionName(state.Version),
+		CipherSuite:      tls.CipherSuiteName(state.CipherSuite),
+		CertificateChain: make([]CertificateInfo, 0, len(state.PeerCertificates)),
+	}
+
+	for _, cert := range state.PeerCertificates {
+		info.CertificateChain = append(info.CertificateChain, CertificateInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			DNSNames:  cert.DNSNames,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			IsCA:      cert.IsCA,
+		})
+	}
+
+	return info
+}
+
 // parseURL parses a raw URL and returns the network, host and port
 // it also ensures the network is tcp and the port is set to the default for the network
 func parseURL(rawURL string) (network, host, port string) {
@@ -134,3 +265,13 @@ func parseURL(rawURL string) (network, host, port string) {
 
 	return network, host, port
 }
+
+// socketPath returns the path of the socket of a unix URL
+func socketPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Path
+}